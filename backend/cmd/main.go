@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
+	_ "time/tzdata" // Embedded zone database so the quota reset timezone loads in minimal containers
 
 	"finone-search-system/config"
 	"finone-search-system/database"
+	"finone-search-system/grpcapi"
 	"finone-search-system/handlers"
 	"finone-search-system/middleware"
+	"finone-search-system/models"
 	"finone-search-system/services"
 	"finone-search-system/utils"
 
@@ -19,14 +24,46 @@ import (
 func main() {
 	// Initialize logger
 	utils.InitLogger()
+
+	// Schema migrations can be managed without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
+
 	utils.LogInfo("Starting Finone Search System...")
 
 	// Load configuration
 	if err := config.LoadConfig(); err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	warnings, err := config.Validate(config.Get())
+	if err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+	for _, warning := range warnings {
+		utils.LogWarning("Insecure configuration: " + warning)
+	}
 	utils.LogInfo("Configuration loaded successfully")
 
+	// Locate logins and sessions when GeoIP databases are configured
+	if err := utils.InitGeoIP(config.Get().GeoIP); err != nil {
+		log.Fatalf("Failed to load GeoIP databases: %v", err)
+	}
+
+	// Export traces of requests and database queries when tracing is enabled
+	shutdownTracing, err := utils.InitTracing(config.Get().Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			utils.LogError("Failed to flush traces", err)
+		}
+	}()
+
 	// Initialize PostgreSQL connection
 	if err := database.InitPostgres(); err != nil {
 		log.Fatalf("Failed to initialize PostgreSQL: %v", err)
@@ -49,18 +86,43 @@ func main() {
 		log.Fatalf("Failed to run ClickHouse migrations: %v", err)
 	}
 
+	// Use the people table selected by the latest switchover, if any
+	if err := services.NewPeopleTableService().LoadActiveTable(); err != nil {
+		log.Fatalf("Failed to load active people table: %v", err)
+	}
+
 	// Start the daily reset scheduler
 	utils.LogInfo("Starting background schedulers...")
 	schedulerService := services.NewSchedulerService()
 	schedulerService.StartDailyResetScheduler()
-	schedulerService.StartWeeklyCleanup()
+	schedulerService.StartRetentionPurge()
+	schedulerService.StartExportCleanup()
+	schedulerService.StartTableMaintenance()
+	services.NewWebhookService().ResumePendingDeliveries()
+	services.NewClientAnalyticsService().StartFlusher()
+	services.NewPeopleTableService().StartRefresher()
+	services.NewConfigReloadService().StartWatcher()
 	utils.LogInfo("Background schedulers started successfully")
 
+	// Serve the gRPC search API for internal services alongside the JSON API
+	if config.Get().GRPC.Enabled {
+		grpcAddr := fmt.Sprintf("%s:%d", config.Get().Server.Host, config.Get().GRPC.Port)
+		grpcServer, err := grpcapi.Serve(grpcAddr)
+		if err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+		defer grpcServer.GracefulStop()
+		utils.LogInfo(fmt.Sprintf("gRPC server listening on %s", grpcAddr))
+	}
+
 	// Setup Gin router
 	router := setupRouter()
 
+	// Run queued background jobs once every job handler is registered
+	services.NewJobQueueService().StartWorkers()
+
 	// Start server
-	serverAddr := fmt.Sprintf("%s:%d", config.AppConfig.Server.Host, config.AppConfig.Server.Port)
+	serverAddr := fmt.Sprintf("%s:%d", config.Get().Server.Host, config.Get().Server.Port)
 	utils.LogInfo(fmt.Sprintf("Server starting on %s", serverAddr))
 
 	if err := router.Run(serverAddr); err != nil {
@@ -76,6 +138,12 @@ func setupRouter() *gin.Engine {
 
 	router := gin.New()
 
+	// Forwarded client IPs are only believed from the configured proxies, so per-IP limits such as the
+	// registration and login throttles cannot be dodged with a forged X-Forwarded-For
+	if err := router.SetTrustedProxies(config.Get().Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
+
 	// Global middleware
 	router.Use(utils.GinLogger())
 	router.Use(utils.GinRecovery())
@@ -88,13 +156,49 @@ func setupRouter() *gin.Engine {
 	})
 
 	// router.Use(middleware.CORSMiddleware()) // Disabled - nginx handles CORS
-	router.Use(middleware.RateLimitMiddleware())
+	router.Use(middleware.RequestIDMiddleware())
+	router.Use(middleware.TracingMiddleware())
+	router.Use(middleware.ErrorMiddleware())
+	router.Use(middleware.ClientTrackingMiddleware())
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler()
 	searchHandler := handlers.NewSearchHandler()
 	registrationHandler := handlers.NewRegistrationHandler()
 	passwordChangeHandler := handlers.NewPasswordChangeHandler()
+	simulationHandler := handlers.NewSimulationHandler()
+	fieldPolicyHandler := handlers.NewFieldPolicyHandler()
+	clickHouseIndexHandler := handlers.NewClickHouseIndexHandler()
+	permissionHandler := handlers.NewPermissionHandler()
+	configHandler := handlers.NewConfigHandler()
+	webhookHandler := handlers.NewWebhookHandler()
+	clientAnalyticsHandler := handlers.NewClientAnalyticsHandler()
+	peopleTableHandler := handlers.NewPeopleTableHandler()
+	pincodeHandler := handlers.NewPincodeHandler()
+	circleHandler := handlers.NewCircleHandler()
+	systemMaintenanceHandler := handlers.NewSystemMaintenanceHandler()
+	importChangesHandler := handlers.NewImportChangesHandler()
+	dataQualityHandler := handlers.NewDataQualityHandler()
+	cacheHandler := handlers.NewCacheHandler()
+	retentionHandler := handlers.NewRetentionHandler()
+	storageHandler := handlers.NewStorageHandler()
+	peopleRecordHandler := handlers.NewPeopleRecordHandler()
+	datasetHandler := handlers.NewDatasetHandler()
+	searchCorrelationHandler := handlers.NewSearchCorrelationHandler()
+	searchAnalyticsHandler := handlers.NewSearchAnalyticsHandler()
+	dashboardHandler := handlers.NewDashboardHandler()
+	clickHouseSystemHandler := handlers.NewClickHouseSystemHandler()
+	tableMaintenanceHandler := handlers.NewTableMaintenanceHandler()
+	organizationHandler := handlers.NewOrganizationHandler()
+	jobProgressHandler := handlers.NewJobProgressHandler()
+	jwtKeyHandler := handlers.NewJWTKeyHandler()
+	adminExportHandler := handlers.NewAdminExportHandler()
+	oidcHandler := handlers.NewOIDCHandler()
+	searchTemplateHandler := handlers.NewSearchTemplateHandler()
+	jobQueueHandler := handlers.NewJobQueueHandler()
+
+	// Async imports queued on the job queue go through the search handler's import pipeline
+	services.RegisterJobHandler(services.JobTypeImport, searchHandler.RunImportJob)
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -114,28 +218,76 @@ func setupRouter() *gin.Engine {
 		})
 	})
 
+	// Readiness for load balancers: not ready without PostgreSQL, degraded while ClickHouse is down.
+	// A degraded server stays in rotation, answering searches with 503 and a Retry-After.
+	router.GET("/health/ready", func(c *gin.Context) {
+		pgErr := database.PostgresHealthCheck()
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		chErr := database.ClickHouseDB.Ping(ctx)
+		circuitOpen := database.ClickHouseCircuitOpen()
+
+		state, status := "ready", http.StatusOK
+		switch {
+		case pgErr != nil:
+			state, status = "not_ready", http.StatusServiceUnavailable
+		case chErr != nil || circuitOpen:
+			state = "degraded"
+		}
+
+		body := gin.H{
+			"status":                  state,
+			"degraded":                state == "degraded",
+			"postgresql":              pgErr == nil,
+			"clickhouse":              chErr == nil,
+			"clickhouse_circuit_open": circuitOpen,
+		}
+		if circuitOpen {
+			middleware.SetRetryAfter(c, database.ClickHouseRetryAfter())
+		}
+		c.JSON(status, body)
+	})
+
+	// Connection pool metrics for Prometheus
+	router.GET("/metrics", storageHandler.Metrics)
+
+	// Requests are limited per user once authenticated, per client IP before
+	rateLimit := middleware.RateLimitMiddleware()
+
 	// API routes
 	api := router.Group("/api/v1")
 	{
 		// Public routes (no authentication required)
-		auth := api.Group("/auth")
+		auth := api.Group("/auth", rateLimit)
 		{
 			auth.POST("/login", userHandler.Login)
+			auth.GET("/jwks", jwtKeyHandler.GetJWKS)
+			auth.GET("/oidc/login", oidcHandler.Login)
+			auth.GET("/oidc/callback", oidcHandler.Callback)
 		}
 
 		// Public registration endpoint
-		api.POST("/register", registrationHandler.CreateRegistrationRequest)
+		// Registration is closed while maintenance mode is on
+		maintenanceMode := middleware.MaintenanceMode()
+		api.POST("/register", rateLimit, maintenanceMode, registrationHandler.CreateRegistrationRequest)
+		api.GET("/register/verify", rateLimit, maintenanceMode, registrationHandler.VerifyRegistrationEmail)
 
-		// Protected routes (authentication required)
+		// Protected routes (authentication required); only admins are served while maintenance mode is on
 		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(), maintenanceMode, middleware.AuthorizationMiddleware(), rateLimit)
 		{
 			// User routes
 			users := protected.Group("/users")
 			{
 				users.GET("/profile", userHandler.GetProfile)
 				users.GET("/analytics", userHandler.GetMyAnalytics)
+				users.GET("/quota", userHandler.GetMyQuota)
+				users.GET("/exports", searchHandler.GetMyExports)
+				users.POST("/exports/:id/regenerate", searchHandler.RegenerateExport)
 				users.POST("/logout", userHandler.Logout)
+				users.GET("/sessions", userHandler.GetMySessions)
+				users.DELETE("/sessions/:session_id", userHandler.InvalidateMySession)
 			}
 
 			// Password change request routes (user)
@@ -145,28 +297,60 @@ func setupRouter() *gin.Engine {
 				passwordChange.GET("/my", passwordChangeHandler.GetUserPasswordChangeRequests)
 			}
 
+			// Live import and export progress over a WebSocket
+			protected.GET("/ws", jobProgressHandler.StreamJobProgress)
+
+			// Organization admin routes, limited to the members of the caller's own organization
+			organization := protected.Group("/organization")
+			{
+				organization.GET("", organizationHandler.GetMyOrganization)
+				organization.GET("/members", organizationHandler.GetMyOrganizationMembers)
+				organization.POST("/members", organizationHandler.CreateMyOrganizationMember)
+				organization.PUT("/members/:user_id", organizationHandler.UpdateMyOrganizationMember)
+				organization.DELETE("/members/:user_id", organizationHandler.RemoveMyOrganizationMember)
+			}
+
 			// Search routes
+			// Routes reading ClickHouse fail fast with 503 while it is unavailable
 			search := protected.Group("/search")
+			requireClickHouse := middleware.RequireClickHouse()
 			{
-				search.POST("/", searchHandler.Search)
-				search.POST("/within", searchHandler.SearchWithin)
-				search.POST("/mobile/enhanced", searchHandler.EnhancedMobileSearch)
-				search.GET("/person/:id", searchHandler.GetPerson)
-				search.GET("/stats", searchHandler.GetStats)
-				search.POST("/export", searchHandler.ExportSearchResults)
+				search.POST("/", requireClickHouse, searchHandler.Search)
+				search.POST("/within", requireClickHouse, searchHandler.SearchWithin)
+				search.GET("/:search_id/results", requireClickHouse, searchHandler.GetSearchResults)
+				search.POST("/mobile/enhanced", requireClickHouse, searchHandler.EnhancedMobileSearch)
+				search.POST("/enhanced-mobile", requireClickHouse, searchHandler.EnhancedMobileSearch) // Alias of /mobile/enhanced
+				search.POST("/enhanced", requireClickHouse, searchHandler.EnhancedSearch)
+				search.GET("/person/:id", requireClickHouse, searchHandler.GetPerson)
+				search.GET("/person/:id/graph", requireClickHouse, searchHandler.GetPersonGraph)
+				search.GET("/person/:id/history", requireClickHouse, searchHandler.GetPersonHistory)
+				search.GET("/stats", requireClickHouse, searchHandler.GetStats)
+				search.GET("/stats/pincode/:pincode", requireClickHouse, searchHandler.GetPincodeStats)
+				search.GET("/datasets", datasetHandler.GetMyDatasets)
+				search.GET("/templates", searchTemplateHandler.GetMySearchTemplates)
+				search.POST("/templates/:id/run", requireClickHouse, searchHandler.RunSearchTemplate)
+				search.POST("/export", requireClickHouse, searchHandler.ExportSearchResults)
 			}
 
-			// Admin only routes
+			// Admin only routes (access is enforced by the route permission table)
 			admin := protected.Group("/admin")
-			admin.Use(middleware.AdminMiddleware())
 			{
+				// Home screen overview
+				admin.GET("/dashboard", dashboardHandler.GetDashboard)
+
 				// User management
 				admin.POST("/users", userHandler.CreateUser)
+				admin.POST("/users/bulk", userHandler.BulkCreateUsers)
 				admin.GET("/users", userHandler.GetUsers)
 				admin.GET("/users/:id", userHandler.GetUser)
 				admin.PUT("/users/:id", userHandler.UpdateUser)
 				admin.DELETE("/users/:id", userHandler.DeleteUser)
 				admin.GET("/analytics", userHandler.GetUserAnalytics)
+				admin.GET("/analytics/clients", clientAnalyticsHandler.GetClientAnalytics)
+				admin.GET("/analytics/search-performance", searchAnalyticsHandler.GetSearchPerformance)
+				admin.GET("/analytics/slow-queries", searchAnalyticsHandler.GetSlowQueries)
+				admin.GET("/analytics/top-queries", searchAnalyticsHandler.GetTopQueries)
+				admin.GET("/analytics/zero-result-queries", searchAnalyticsHandler.GetZeroResultQueries)
 
 				// Registration request management
 				admin.GET("/registration-requests", registrationHandler.GetRegistrationRequests)
@@ -184,6 +368,7 @@ func setupRouter() *gin.Engine {
 				admin.GET("/sessions", userHandler.GetAllActiveSessions)
 				admin.GET("/users/:id/sessions", userHandler.GetUserSessions)
 				admin.DELETE("/users/:id/sessions", userHandler.InvalidateUserSessions)
+				admin.DELETE("/sessions/:session_id", userHandler.InvalidateSession)
 				admin.POST("/sessions/cleanup", userHandler.CleanupExpiredSessions)
 
 				// User search history
@@ -194,9 +379,155 @@ func setupRouter() *gin.Engine {
 				admin.POST("/users/:id/reset-daily-search-count", userHandler.ResetUserDailySearchCount)
 				admin.GET("/reset/next-reset-time", userHandler.GetNextResetTime)
 
+				// One-off search credits on top of the daily limit
+				admin.GET("/users/:id/search-credits", userHandler.GetSearchCredits)
+				admin.POST("/users/:id/search-credits", userHandler.GrantSearchCredits)
+				admin.POST("/users/:id/quota-adjustment", userHandler.AdjustQuota)
+				admin.POST("/users/:id/upgrade", userHandler.UpgradeUser)
+				admin.GET("/users/:id/upgrades", userHandler.GetUserUpgrades)
+
+				// Support sessions acting as a user
+				admin.POST("/users/:id/impersonate", userHandler.ImpersonateUser)
+				admin.GET("/impersonations", userHandler.GetImpersonations)
+				admin.GET("/logins", userHandler.GetLogins)
+
 				// CSV import
 				admin.POST("/import/csv", searchHandler.ImportCSV)
 				admin.POST("/import/csv-path", searchHandler.ImportCSVFromPath)
+				admin.POST("/import/url", searchHandler.ImportCSVFromURL)
+				admin.POST("/import/uploads", searchHandler.CreateUpload)
+				admin.GET("/import/uploads/:upload_id", searchHandler.GetUpload)
+				admin.PUT("/import/uploads/:upload_id/chunks/:index", searchHandler.UploadChunk)
+				admin.POST("/import/uploads/:upload_id/complete", searchHandler.CompleteUpload)
+				admin.DELETE("/import/uploads/:upload_id", searchHandler.AbortUpload)
+				admin.POST("/import/profile", searchHandler.ProfileCSV)
+				admin.POST("/import/profile-path", searchHandler.ProfileCSVFromPath)
+				admin.GET("/import/audit", searchHandler.GetImportAudit)
+				admin.GET("/import/jobs/:job_id/rows", searchHandler.GetImportRows)
+				admin.DELETE("/import/jobs/:job_id/rows", searchHandler.DeleteImportRows)
+				admin.GET("/import/jobs/:job_id/errors", searchHandler.GetImportErrors)
+				admin.GET("/import/jobs/:job_id/changes", importChangesHandler.GetImportChanges)
+				admin.POST("/import/jobs/:job_id/changes/apply", importChangesHandler.ApplyImportChanges)
+				admin.DELETE("/import/jobs/:job_id/changes", importChangesHandler.DiscardImportChanges)
+
+				// Pincode directory for the derived locality, city and state columns
+				admin.GET("/import/pincodes", pincodeHandler.GetPincodeDirectory)
+				admin.POST("/import/pincodes", pincodeHandler.ImportPincodes)
+
+				// Circle normalization map applied by imports and circle searches
+				admin.GET("/import/circles", circleHandler.GetCircleAliases)
+				admin.POST("/import/circles", circleHandler.SetCircleAlias)
+				admin.DELETE("/import/circles/:alias", circleHandler.DeleteCircleAlias)
+				admin.GET("/import/circles/unmapped", circleHandler.GetUnmappedCircles)
+
+				// Cross-field consistency flags
+				admin.POST("/quality/validate", dataQualityHandler.ValidateRecords)
+				admin.POST("/quality/revalidate", dataQualityHandler.RevalidatePeople)
+				admin.GET("/quality/summary", dataQualityHandler.GetQualitySummary)
+
+				// Quota and access decision simulation
+				admin.POST("/simulate", simulationHandler.Simulate)
+
+				// Field visibility policies
+				admin.GET("/field-policies", fieldPolicyHandler.GetPolicies)
+				admin.PUT("/field-policies", fieldPolicyHandler.UpsertPolicy)
+				admin.DELETE("/field-policies/:id", fieldPolicyHandler.DeletePolicy)
+
+				// ClickHouse index management
+				admin.GET("/clickhouse/indexes", clickHouseIndexHandler.GetIndexes)
+				admin.POST("/clickhouse/indexes/materialize", clickHouseIndexHandler.MaterializeIndexes)
+
+				// People table versions and switchover
+				admin.GET("/clickhouse/people-tables", peopleTableHandler.GetPeopleTables)
+				admin.POST("/clickhouse/people-tables", peopleTableHandler.CreatePeopleTable)
+				admin.POST("/clickhouse/people-tables/switch", peopleTableHandler.SwitchPeopleTable)
+
+				// ClickHouse storage, parts and replication
+				admin.GET("/system/clickhouse", clickHouseSystemHandler.GetClickHouseStatus)
+
+				// Maintenance mode, which shuts users out during large imports and migrations
+				admin.GET("/system/maintenance", systemMaintenanceHandler.GetMaintenance)
+				admin.PUT("/system/maintenance", systemMaintenanceHandler.SetMaintenance)
+
+				// Merging the parts imports leave behind
+				admin.GET("/maintenance/optimize", tableMaintenanceHandler.GetMaintenanceStatus)
+				admin.POST("/maintenance/optimize", tableMaintenanceHandler.OptimizeTable)
+
+				// Access auditing
+				admin.GET("/permissions/matrix", permissionHandler.GetPermissionMatrix)
+				admin.GET("/config", configHandler.GetEffectiveConfig)
+				admin.POST("/config/reload", configHandler.ReloadConfig)
+
+				// Session token signing keys
+				admin.GET("/jwt-keys", jwtKeyHandler.GetKeys)
+				admin.POST("/jwt-keys/rotate", jwtKeyHandler.RotateKey)
+				admin.POST("/jwt-keys/:kid/retire", jwtKeyHandler.RetireKey)
+				admin.GET("/searches/:search_id", searchCorrelationHandler.GetSearchCorrelation)
+				admin.POST("/exports/trace", searchHandler.TraceExport)
+
+				// Table exports for compliance reviews and billing reconciliation
+				admin.GET("/export/users", adminExportHandler.ExportUsers)
+				admin.GET("/export/usage", adminExportHandler.ExportUsage)
+				admin.GET("/export/searches", adminExportHandler.ExportSearches)
+
+				// Manual fixes to individual people rows
+				admin.POST("/people", peopleRecordHandler.CreatePerson)
+				admin.PUT("/people/:id", peopleRecordHandler.UpdatePerson)
+				admin.DELETE("/people/:id", peopleRecordHandler.DeletePerson)
+				admin.GET("/people/:id/edits", peopleRecordHandler.GetPersonEdits)
+
+				// Datasets
+				admin.GET("/datasets", datasetHandler.GetDatasets)
+				admin.POST("/datasets", datasetHandler.CreateDataset)
+				admin.PUT("/datasets/:id", datasetHandler.UpdateDataset)
+				admin.DELETE("/datasets/:id", datasetHandler.DeleteDataset)
+				admin.GET("/datasets/:id/stats", datasetHandler.GetDatasetStats)
+				admin.GET("/datasets/:id/access", datasetHandler.GetDatasetAccess)
+				admin.POST("/datasets/:id/access", datasetHandler.GrantDatasetAccess)
+				admin.DELETE("/datasets/:id/access/:user_id", datasetHandler.RevokeDatasetAccess)
+
+				// Search templates
+				admin.GET("/search-templates", searchTemplateHandler.GetSearchTemplates)
+				admin.POST("/search-templates", searchTemplateHandler.CreateSearchTemplate)
+				admin.PUT("/search-templates/:id", searchTemplateHandler.UpdateSearchTemplate)
+				admin.DELETE("/search-templates/:id", searchTemplateHandler.DeleteSearchTemplate)
+
+				// Background job queue
+				admin.GET("/jobs", jobQueueHandler.GetJobs)
+				admin.GET("/jobs/:id", jobQueueHandler.GetJob)
+				admin.POST("/jobs/:id/retry", jobQueueHandler.RetryJob)
+
+				// Organizations and their shared quotas
+				admin.GET("/organizations", organizationHandler.GetOrganizations)
+				admin.POST("/organizations", organizationHandler.CreateOrganization)
+				admin.GET("/organizations/:id", organizationHandler.GetOrganization)
+				admin.PUT("/organizations/:id", organizationHandler.UpdateOrganization)
+				admin.DELETE("/organizations/:id", organizationHandler.DeleteOrganization)
+				admin.PUT("/organizations/:id/members/:user_id", organizationHandler.AddOrganizationMember)
+				admin.DELETE("/organizations/:id/members/:user_id", organizationHandler.RemoveOrganizationMember)
+
+				// Database connection pools
+				admin.GET("/storage", storageHandler.GetStorageStats)
+
+				// In-process caches
+				admin.GET("/cache/stats", cacheHandler.GetCacheStats)
+				admin.POST("/cache/flush", cacheHandler.FlushCache)
+
+				// Log retention
+				admin.GET("/retention", retentionHandler.GetRetentionStatus)
+				admin.POST("/retention/purge", retentionHandler.PurgeRetention)
+				admin.POST("/retention/anonymize", retentionHandler.AnonymizeSearches)
+				admin.PUT("/retention/policies/:name", retentionHandler.SaveRetentionPolicy)
+				admin.DELETE("/retention/policies/:name", retentionHandler.DeleteRetentionPolicy)
+				admin.POST("/retention/policies/:name/run", retentionHandler.RunRetentionPolicy)
+
+				// Webhooks
+				admin.GET("/webhooks", webhookHandler.GetWebhooks)
+				admin.POST("/webhooks", webhookHandler.CreateWebhook)
+				admin.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+				admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+				admin.GET("/webhooks/:id/deliveries", webhookHandler.GetDeliveries)
+				admin.POST("/webhooks/:id/deliveries/:deliveryId/retry", webhookHandler.RetryDelivery)
 			}
 		}
 	}
@@ -207,11 +538,9 @@ func setupRouter() *gin.Engine {
 	// Debug: catch-all route to see what paths are being requested
 	router.NoRoute(func(c *gin.Context) {
 		utils.LogInfo(fmt.Sprintf("No route found for: %s %s", c.Request.Method, c.Request.URL.Path))
-		c.JSON(http.StatusNotFound, gin.H{
-			"error":  "Route not found",
-			"method": c.Request.Method,
-			"path":   c.Request.URL.Path,
-		})
+		middleware.AbortWithError(c, models.NewAPIError(http.StatusNotFound, models.ErrorCodeNotFound, "Route not found").
+			With("method", c.Request.Method).
+			With("path", c.Request.URL.Path))
 	})
 
 	return router
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"finone-search-system/config"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+)
+
+func TestEnhancedMobileSearchRoutes(t *testing.T) {
+	utils.InitLogger()
+	if err := config.LoadConfig(); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	router := setupRouter()
+	authorization := services.NewAuthorizationService()
+
+	handlers := make(map[string]string)
+	for _, route := range router.Routes() {
+		handlers[route.Method+" "+route.Path] = route.Handler
+	}
+
+	// The alias and the original route both reach EnhancedMobileSearch and both need the search permission
+	for _, path := range []string{"/api/v1/search/enhanced-mobile", "/api/v1/search/mobile/enhanced"} {
+		handler, ok := handlers["POST "+path]
+		if !ok {
+			t.Errorf("POST %s is not routed", path)
+			continue
+		}
+		if !strings.HasSuffix(handler, ".(*SearchHandler).EnhancedMobileSearch-fm") {
+			t.Errorf("POST %s is handled by %s, want SearchHandler.EnhancedMobileSearch", path, handler)
+		}
+
+		permission, ok := authorization.RequiredPermission(http.MethodPost, path)
+		if !ok || permission != services.PermissionSearch {
+			t.Errorf("POST %s requires permission %q (%v), want %q", path, permission, ok, services.PermissionSearch)
+		}
+
+		// The route sits behind authentication, so a request without a token is refused rather than not found
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"mobile":"9876543210"}`)))
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("POST %s without a token returned %d, want %d", path, recorder.Code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+)
+
+// runMigrateCommand handles `finone-search migrate [flags] up|down|status`, which manages
+// schema migrations without starting the server.
+func runMigrateCommand(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	target := flags.String("db", "all", "database to migrate: postgres, clickhouse or all")
+	steps := flags.Int("steps", 0, "number of migrations to apply or roll back (up: 0 = all pending, down: 0 = one)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: finone-search migrate [-db postgres|clickhouse|all] [-steps N] up|down|status")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	action := flags.Arg(0)
+	if action != "up" && action != "down" && action != "status" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *target != "all" && *target != "postgres" && *target != "clickhouse" {
+		log.Fatalf("Unknown database: %s", *target)
+	}
+	if action == "down" && *target == "all" {
+		log.Fatalf("Rollback requires -db postgres or -db clickhouse")
+	}
+
+	if err := config.LoadConfig(); err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if *target == "all" || *target == "postgres" {
+		if err := database.InitPostgres(); err != nil {
+			log.Fatalf("Failed to initialize PostgreSQL: %v", err)
+		}
+		defer database.ClosePostgres()
+
+		runMigrateAction("PostgreSQL", database.PostgresMigrator{}, database.PostgresMigrationsDir, action, *steps)
+	}
+
+	if *target == "all" || *target == "clickhouse" {
+		if err := database.InitClickHouse(); err != nil {
+			log.Fatalf("Failed to initialize ClickHouse: %v", err)
+		}
+		defer database.CloseClickHouse()
+
+		runMigrateAction("ClickHouse", database.ClickHouseMigrator{}, database.ClickHouseMigrationsDir, action, *steps)
+	}
+}
+
+func runMigrateAction(name string, m database.Migrator, dir, action string, steps int) {
+	switch action {
+	case "up":
+		count, err := database.MigrateUp(m, dir, steps)
+		if err != nil {
+			log.Fatalf("%s migration failed after %d applied: %v", name, count, err)
+		}
+		log.Printf("%s: applied %d migration(s)", name, count)
+	case "down":
+		count, err := database.MigrateDown(m, dir, steps)
+		if err != nil {
+			log.Fatalf("%s rollback failed after %d rolled back: %v", name, count, err)
+		}
+		log.Printf("%s: rolled back %d migration(s)", name, count)
+	case "status":
+		statuses, err := database.GetMigrationStatus(m, dir)
+		if err != nil {
+			log.Fatalf("Failed to get %s migration status: %v", name, err)
+		}
+		fmt.Printf("%s migrations:\n", name)
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("  %03d_%s  %s\n", status.Version, status.Name, state)
+		}
+	}
+}
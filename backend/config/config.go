@@ -4,23 +4,73 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	Limits   LimitsConfig   `yaml:"limits"`
-	CSV      CSVConfig      `yaml:"csv"`
+	Server    ServerConfig    `yaml:"server"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	GeoIP     GeoIPConfig     `yaml:"geoip"`
+	Database  DatabaseConfig  `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	CSV       CSVConfig       `yaml:"csv"`
+	Export    ExportConfig    `yaml:"export"`
+	S3        S3Config        `yaml:"s3"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Quota     QuotaConfig     `yaml:"quota"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Search    SearchConfig    `yaml:"search"`
+	Response  ResponseConfig  `yaml:"response"`
+
+	Retention   RetentionConfig   `yaml:"retention"`
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+	Jobs        JobsConfig        `yaml:"jobs"`
+
+	Notifications NotificationConfig `yaml:"notifications"`
+	Webhooks      WebhookConfig      `yaml:"webhooks"`
+	Registration  RegistrationConfig `yaml:"registration"`
+	OIDC          OIDCConfig         `yaml:"oidc"`
 }
 
 type ServerConfig struct {
-	Port    int           `yaml:"port"`
-	Host    string        `yaml:"host"`
-	Timeout time.Duration `yaml:"timeout"`
+	Port        int           `yaml:"port"`
+	Host        string        `yaml:"host"`
+	Timeout     time.Duration `yaml:"timeout"`
+	CORSOrigins []string      `yaml:"cors_origins"` // Frontend origins allowed by the CORS middleware
+	// Header a trusted proxy sets to the client's ISO country code, such as CF-IPCountry. It is
+	// preferred over the GeoIP country.
+	CountryHeader string `yaml:"country_header"`
+	// Addresses or CIDRs of the reverse proxies whose X-Forwarded-For and X-Real-IP headers are
+	// believed for the client IP. Empty trusts none, so the client IP is the connection's address.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// GRPCConfig controls the gRPC search API for internal services
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// GeoIPConfig names the MaxMind databases logins and sessions are located with; leave a path empty to
+// go without it
+type GeoIPConfig struct {
+	CityDB string `yaml:"city_db"` // GeoLite2 or GeoIP2 City (or Country) .mmdb: country and city
+	ASNDB  string `yaml:"asn_db"`  // GeoLite2 or GeoIP2 ASN .mmdb: network number and owner
+}
+
+// TracingConfig controls OpenTelemetry tracing and the OTLP exporter spans are sent to
+type TracingConfig struct {
+	Enabled     bool    `yaml:"enabled"`
+	Endpoint    string  `yaml:"endpoint"`     // OTLP collector, host:port for grpc or a URL for http
+	Protocol    string  `yaml:"protocol"`     // grpc or http
+	Insecure    bool    `yaml:"insecure"`     // Send without TLS, e.g. to a local collector
+	ServiceName string  `yaml:"service_name"` // Reported as service.name
+	SampleRatio float64 `yaml:"sample_ratio"` // Fraction of new traces recorded; incoming sampled traces are always kept
 }
 
 type DatabaseConfig struct {
@@ -29,42 +79,274 @@ type DatabaseConfig struct {
 }
 
 type PostgresConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	DBName   string `yaml:"dbname"`
-	SSLMode  string `yaml:"sslmode"`
+	Host            string        `yaml:"host"`
+	Port            int           `yaml:"port"`
+	User            string        `yaml:"user"`
+	Password        string        `yaml:"password"`
+	DBName          string        `yaml:"dbname"`
+	SSLMode         string        `yaml:"sslmode"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"` // 0 keeps idle connections until their lifetime ends
 }
 
 type ClickHouseConfig struct {
-	Host     string `yaml:"host"`
-	Port     int    `yaml:"port"`
-	User     string `yaml:"user"`
-	Password string `yaml:"password"`
-	Database string `yaml:"database"`
+	Host                    string        `yaml:"host"`
+	Port                    int           `yaml:"port"`
+	User                    string        `yaml:"user"`
+	Password                string        `yaml:"password"`
+	Database                string        `yaml:"database"`
+	HTTPPort                int           `yaml:"http_port"` // HTTP interface, used to stream large exports
+	MaxOpenConns            int           `yaml:"max_open_conns"`
+	MaxIdleConns            int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime         time.Duration `yaml:"conn_max_lifetime"`
+	DialTimeout             time.Duration `yaml:"dial_timeout"`
+	ReadTimeout             time.Duration `yaml:"read_timeout"`              // Longest wait for a server response
+	ConnectRetries          int           `yaml:"connect_retries"`           // Attempts at startup before giving up
+	ConnectBackoff          time.Duration `yaml:"connect_backoff"`           // Initial delay between attempts, doubled each time
+	CircuitBreakerThreshold int           `yaml:"circuit_breaker_threshold"` // Consecutive connection failures that open the circuit
+	CircuitBreakerCooldown  time.Duration `yaml:"circuit_breaker_cooldown"`  // Time to fail fast before probing again
+	PeopleTable             string        `yaml:"people_table"`              // Table searched and imported into until an admin switchover picks another
+	// Storage monitoring warns above these thresholds
+	PartsWarningPerPartition int           `yaml:"parts_warning_per_partition"` // Active parts in one partition; inserts slow down and then fail as this grows
+	PartsWarningPerTable     int           `yaml:"parts_warning_per_table"`
+	ReplicaLagWarning        time.Duration `yaml:"replica_lag_warning"`
+	DiskFreeWarningPercent   int           `yaml:"disk_free_warning_percent"`
 }
 
 type JWTConfig struct {
-	Secret string        `yaml:"secret"`
-	Expiry time.Duration `yaml:"expiry"`
+	Secret       string        `yaml:"secret"`
+	Expiry       time.Duration `yaml:"expiry"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`  // Sessions without a request for this long are invalidated; 0 disables
+	ExtendActive bool          `yaml:"extend_active"` // Issue a fresh token to sessions still in use once half their lifetime has passed
+	// Lifetime of the sessions admins get to act as a user for support; they are never extended
+	ImpersonationExpiry time.Duration `yaml:"impersonation_expiry"`
 }
 
 type LimitsConfig struct {
 	MaxSearchesPerDay int    `yaml:"max_searches_per_day"`
 	MaxExportsPerDay  int    `yaml:"max_exports_per_day"`
 	MaxRowsPerSearch  int    `yaml:"max_rows_per_search"`
+	MaxExportRows     int    `yaml:"max_export_rows"` // Rows an export may contain; 0 is unlimited
 	MaxUploadSize     string `yaml:"max_upload_size"`
+	// Rows an export may contain by user type (DEMO, PERMANENT), in place of MaxExportRows
+	MaxExportRowsByUserType map[string]int `yaml:"max_export_rows_by_user_type"`
 }
 
 type CSVConfig struct {
-	BatchSize int    `yaml:"batch_size"`
-	TempDir   string `yaml:"temp_dir"`
+	BatchSize    int           `yaml:"batch_size"`
+	TempDir      string        `yaml:"temp_dir"`
+	ImportDir    string        `yaml:"import_dir"`    // Only files under this directory can be imported by server path
+	ErrorDir     string        `yaml:"error_dir"`     // Each import writes the rows it could not import to a CSV file here
+	MaxRetries   int           `yaml:"max_retries"`   // Retries for a batch that fails with a transient ClickHouse error
+	RetryBackoff time.Duration `yaml:"retry_backoff"` // Initial delay between retries, doubled each time
+	// URL imports may fetch from loopback, private and link-local addresses; off so an import cannot
+	// reach internal services
+	URLAllowPrivate bool `yaml:"url_allow_private"`
+	// Largest chunk a chunked upload accepts per request; the whole upload is bounded by limits.max_upload_size
+	MaxChunkSizeMB int `yaml:"max_chunk_size_mb"`
+}
+
+// S3Config holds the default credentials for s3:// URLs. Endpoint and PathStyle point them at an
+// S3-compatible store such as MinIO; AWS is used when Endpoint is empty.
+type S3Config struct {
+	Region          string `yaml:"region"`
+	Endpoint        string `yaml:"endpoint"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	PathStyle       bool   `yaml:"path_style"`
+}
+
+type ExportConfig struct {
+	Dir               string        `yaml:"dir"`                 // Export files are written here and served under /downloads
+	FastPathThreshold int           `yaml:"fast_path_threshold"` // Row count at which exports stream straight from ClickHouse
+	Expiry            time.Duration `yaml:"expiry"`              // How long export download links stay valid
+	RegenerateFree    bool          `yaml:"regenerate_free"`     // Regenerating an expired export does not use the export quota
+	// Where finished export files are kept: local (Dir, served under /downloads) or s3, uploaded to
+	// S3Bucket with the s3 credentials and downloaded through presigned URLs
+	Storage         string        `yaml:"storage"`
+	S3Bucket        string        `yaml:"s3_bucket"`
+	S3Prefix        string        `yaml:"s3_prefix"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval"` // How often expired export files are removed
+	// How exports are marked for leak tracing: column adds an export_watermark column, order sorts rows
+	// in an order unique to the export, both does both and off disables it
+	Watermark string `yaml:"watermark"`
+	// What happens to an export matching more rows than the user's export row limit: reject fails it,
+	// truncate writes the first rows up to the limit and flags the export as truncated
+	RowLimitAction string `yaml:"row_limit_action"`
+}
+
+type CacheConfig struct {
+	AuthTTL time.Duration `yaml:"auth_ttl"` // How long validated sessions are cached by the auth middleware; 0 disables
+}
+
+type QuotaConfig struct {
+	ResetTimezone string `yaml:"reset_timezone"` // IANA zone name, e.g. Asia/Kolkata
+	ResetTime     string `yaml:"reset_time"`     // Time of day (HH:MM) at which daily quotas reset
+}
+
+// RateLimitConfig limits API requests per user, or per client IP before login. Limits are requests per
+// minute, with bursts of up to Burst requests. A user's rate_limit_per_minute overrides the limit for
+// their user type, which overrides RequestsPerMinute; route limits apply on top.
+type RateLimitConfig struct {
+	Enabled           bool                   `yaml:"enabled"`
+	RequestsPerMinute int                    `yaml:"requests_per_minute"`
+	Burst             int                    `yaml:"burst"`      // Defaults to the per-minute limit
+	UserTypes         map[string]int         `yaml:"user_types"` // Per-minute limit by user type (DEMO, PERMANENT)
+	Routes            []RouteRateLimitConfig `yaml:"routes"`
+}
+
+// RouteRateLimitConfig is a stricter per-user limit on one route
+type RouteRateLimitConfig struct {
+	Route             string `yaml:"route"` // Method and path as registered, e.g. "POST /api/v1/search/export"
+	RequestsPerMinute int    `yaml:"requests_per_minute"`
+	Burst             int    `yaml:"burst"`
+}
+
+type SearchConfig struct {
+	Backend        string `yaml:"backend"`          // Registered search backend name; clickhouse by default
+	PincodeGeoFile string `yaml:"pincode_geo_file"` // CSV of pincode,latitude,longitude for nearby searches; bundled Delhi data if empty
+
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"` // Searches slower than this have their query plan captured; 0 disables
+
+	EnhancedMobileMaxLinked int `yaml:"enhanced_mobile_max_linked"` // Most records the second hop of a depth 2 enhanced mobile search adds
+
+	CountMode       string `yaml:"count_mode"`        // window counts matches in the page query, query runs a separate count()
+	ExactCountLimit int    `yaml:"exact_count_limit"` // Match counts reaching this are estimated from a sample
+
+	// Timeout and ClickHouse read limits of searches by user type (DEMO, PERMANENT); "default" covers
+	// the other users and fills the timeout of types that leave it out
+	Budgets map[string]QueryBudgetConfig `yaml:"budgets"`
+}
+
+// QueryBudgetConfig limits the searches of one user type
+type QueryBudgetConfig struct {
+	Timeout        time.Duration `yaml:"timeout"`
+	MaxRowsToRead  uint64        `yaml:"max_rows_to_read"` // Rows a query may read; 0 is unlimited
+	MaxResultBytes uint64        `yaml:"max_result_bytes"` // Bytes a query may return; 0 is unlimited
+}
+
+// ResponseConfig sets the default shape of person rows in search responses; clients override it per
+// request with the omit_empty, timestamps and casing query parameters
+type ResponseConfig struct {
+	OmitEmpty  bool   `yaml:"omit_empty"` // Leave out empty fields
+	Timestamps string `yaml:"timestamps"` // rfc3339 or unix
+	Casing     string `yaml:"casing"`     // snake or camel
+}
+
+// RetentionConfig sets how many days of each table are kept; 0 keeps rows forever. The day settings
+// feed the built-in retention policies, which Policies can add to or override by name.
+type RetentionConfig struct {
+	SearchesDays          int                     `yaml:"searches_days"`
+	LoginsDays            int                     `yaml:"logins_days"`
+	SystemLogsDays        int                     `yaml:"system_logs_days"`
+	SearchPerformanceDays int                     `yaml:"search_performance_days"` // Applied as a ClickHouse TTL
+	AnonymizeSearchesDays int                     `yaml:"anonymize_searches_days"` // Searches older than this keep only structural metadata; 0 disables
+	DailyUsageDays        int                     `yaml:"daily_usage_days"`
+	SessionsDays          int                     `yaml:"sessions_days"` // Days ended sessions are kept after logout or expiry
+	ExportsDays           int                     `yaml:"exports_days"`  // Export records and their files
+	UploadsDays           int                     `yaml:"uploads_days"`  // Unfinished chunked uploads and their partial files
+	Policies              []RetentionPolicyConfig `yaml:"policies"`
+}
+
+// RetentionPolicyConfig declares a retention policy in config.yaml
+type RetentionPolicyConfig struct {
+	Name     string `yaml:"name"`
+	Table    string `yaml:"table"`
+	Criteria string `yaml:"criteria"` // Named row filter of the table; all when empty
+	AgeDays  int    `yaml:"age_days"`
+	Action   string `yaml:"action"`  // delete, archive, anonymize or ttl
+	Enabled  *bool  `yaml:"enabled"` // Enabled when omitted
+}
+
+// MaintenanceConfig schedules the nightly OPTIMIZE ... FINAL of the active people table, which merges
+// the parts left behind by imports one partition at a time
+type MaintenanceConfig struct {
+	OptimizeEnabled bool          `yaml:"optimize_enabled"`
+	OptimizeTime    string        `yaml:"optimize_time"`   // HH:MM in the quota reset timezone
+	OptimizeWindow  time.Duration `yaml:"optimize_window"` // No partition is started once this much time has passed
+}
+
+// JobsConfig sets up the Postgres-backed queue background work such as async imports and exports,
+// retention purges and notification emails runs on
+type JobsConfig struct {
+	Workers      int           `yaml:"workers"`       // Jobs run at once by this instance
+	PollInterval time.Duration `yaml:"poll_interval"` // How often idle workers look for queued jobs
+	MaxAttempts  int           `yaml:"max_attempts"`  // Attempts before a failing job is dead-lettered
+	RetryBackoff time.Duration `yaml:"retry_backoff"` // Wait before the first retry, doubled for each later one
+	StaleAfter   time.Duration `yaml:"stale_after"`   // Running jobs without a heartbeat for this long died with their instance
+}
+
+// RegistrationConfig protects the public registration endpoint from spam
+type RegistrationConfig struct {
+	CaptchaProvider    string        `yaml:"captcha_provider"` // hcaptcha or turnstile; empty to not require a CAPTCHA
+	CaptchaSecret      string        `yaml:"captcha_secret"`
+	MaxPerIPPerHour    int           `yaml:"max_per_ip_per_hour"`   // 0 for no limit
+	MaxPerEmailPerDay  int           `yaml:"max_per_email_per_day"` // 0 for no limit
+	VerifyEmail        bool          `yaml:"verify_email"`          // Requests reach PENDING only once the emailed link is followed
+	VerificationExpiry time.Duration `yaml:"verification_expiry"`   // How long the emailed link works
+}
+
+// OIDCConfig enables single sign-on through OpenID Connect providers such as Azure AD or Google Workspace
+type OIDCConfig struct {
+	Providers         []OIDCProviderConfig `yaml:"providers"`
+	RedirectURL       string               `yaml:"redirect_url"`        // This server's /api/v1/auth/oidc/callback, as registered with the providers
+	PostLoginRedirect string               `yaml:"post_login_redirect"` // Frontend page the session is handed to in the URL fragment; the callback returns JSON when empty
+	StateExpiry       time.Duration        `yaml:"state_expiry"`        // How long a login may take at the provider
+}
+
+// OIDCProviderConfig is one OpenID Connect provider, and the accounts created for its users
+type OIDCProviderConfig struct {
+	Name           string   `yaml:"name"`   // Chosen with ?provider= on /auth/oidc/login, e.g. azure or google
+	Issuer         string   `yaml:"issuer"` // e.g. https://login.microsoftonline.com/<tenant>/v2.0 or https://accounts.google.com
+	ClientID       string   `yaml:"client_id"`
+	ClientSecret   string   `yaml:"client_secret"`
+	Scopes         []string `yaml:"scopes"`          // openid email profile when empty
+	AllowedDomains []string `yaml:"allowed_domains"` // Email domains that may sign in; any when empty
+	TrustEmail     bool     `yaml:"trust_email"`     // Treat emails as verified when the provider sends no email_verified claim, as Azure AD does
+
+	AutoProvision     bool   `yaml:"auto_provision"` // Create accounts for users without one on their first login
+	DefaultRole       string `yaml:"default_role"`
+	DefaultUserType   string `yaml:"default_user_type"`
+	MaxSearchesPerDay int    `yaml:"max_searches_per_day"` // Quotas of created accounts; 0 uses the limits defaults
+	MaxExportsPerDay  int    `yaml:"max_exports_per_day"`
+}
+
+type NotificationConfig struct {
+	Enabled        bool            `yaml:"enabled"`
+	Provider       string          `yaml:"provider"` // smtp, or log to only write emails to the application log
+	From           string          `yaml:"from"`
+	AdminEmail     string          `yaml:"admin_email"`     // Receives new registration request alerts
+	BaseURL        string          `yaml:"base_url"`        // Public URL used to build links in emails
+	QuotaThreshold float64         `yaml:"quota_threshold"` // Fraction of a daily quota at which users are warned
+	Events         map[string]bool `yaml:"events"`          // Per-event switch; events not listed are enabled
+	SMTP           SMTPConfig      `yaml:"smtp"`
 }
 
-var AppConfig *Config
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
 
+type WebhookConfig struct {
+	MaxAttempts  int           `yaml:"max_attempts"`  // Delivery attempts before an event is marked failed
+	RetryBackoff time.Duration `yaml:"retry_backoff"` // Initial delay between attempts, doubled each time
+	Timeout      time.Duration `yaml:"timeout"`       // Per-request timeout
+}
+
+// LoadConfig loads the configuration the server starts with; see Get
 func LoadConfig() error {
+	current.Store(load())
+	return nil
+}
+
+// load reads the configuration from config/config.yaml, or the environment when the file cannot be
+// read, with environment overrides and defaults applied
+func load() *Config {
 	config := &Config{}
 
 	// Try to load from YAML file first
@@ -76,12 +358,14 @@ func LoadConfig() error {
 	// Override with environment variables if they exist
 	overrideWithEnv(config)
 
-	AppConfig = config
-	return nil
+	// Fill in settings missing from older config files
+	applyDefaults(config)
+
+	return config
 }
 
 func loadFromYAML(config *Config) error {
-	file, err := os.Open("config/config.yaml")
+	file, err := os.Open(FilePath)
 	if err != nil {
 		return err
 	}
@@ -94,31 +378,176 @@ func loadFromYAML(config *Config) error {
 func loadFromEnv(config *Config) {
 	config.Server.Port = getEnvAsInt("SERVER_PORT", 8080)
 	config.Server.Host = getEnv("SERVER_HOST", "0.0.0.0")
+	config.Server.CORSOrigins = splitList(os.Getenv("FRONTEND_URL"))
+	config.Server.CountryHeader = getEnv("COUNTRY_HEADER", "")
+	config.Server.TrustedProxies = splitList(os.Getenv("TRUSTED_PROXIES"))
+	config.GeoIP.CityDB = getEnv("GEOIP_CITY_DB", "")
+	config.GeoIP.ASNDB = getEnv("GEOIP_ASN_DB", "")
+	config.GRPC.Enabled = getEnvAsBool("GRPC_ENABLED", false)
+	config.GRPC.Port = getEnvAsInt("GRPC_PORT", 9090)
+	config.Tracing.Enabled = getEnvAsBool("TRACING_ENABLED", false)
+	config.Tracing.Endpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "localhost:4317")
+	config.Tracing.Protocol = getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	config.Tracing.Insecure = getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", true)
+	config.Tracing.ServiceName = getEnv("OTEL_SERVICE_NAME", "finone-search-system")
+	config.Tracing.SampleRatio = getEnvAsFloat("TRACING_SAMPLE_RATIO", 1)
 	config.Server.Timeout = time.Duration(getEnvAsInt("SERVER_TIMEOUT", 30)) * time.Second
 
 	config.Database.Postgres.Host = getEnv("POSTGRES_HOST", "localhost")
 	config.Database.Postgres.Port = getEnvAsInt("POSTGRES_PORT", 5432)
 	config.Database.Postgres.User = getEnv("POSTGRES_USER", "postgres")
-	config.Database.Postgres.Password = getEnv("POSTGRES_PASSWORD", "secret")
+	config.Database.Postgres.Password = getEnv("POSTGRES_PASSWORD", defaultPostgresPassword)
 	config.Database.Postgres.DBName = getEnv("POSTGRES_DB", "finone_search")
 	config.Database.Postgres.SSLMode = getEnv("POSTGRES_SSLMODE", "disable")
+	config.Database.Postgres.MaxOpenConns = getEnvAsInt("POSTGRES_MAX_OPEN_CONNS", 25)
+	config.Database.Postgres.MaxIdleConns = getEnvAsInt("POSTGRES_MAX_IDLE_CONNS", 5)
+	config.Database.Postgres.ConnMaxLifetime = time.Duration(getEnvAsInt("POSTGRES_CONN_MAX_LIFETIME_SECONDS", 300)) * time.Second
+	config.Database.Postgres.ConnMaxIdleTime = time.Duration(getEnvAsInt("POSTGRES_CONN_MAX_IDLE_TIME_SECONDS", 0)) * time.Second
 
 	config.Database.ClickHouse.Host = getEnv("CLICKHOUSE_HOST", "localhost")
 	config.Database.ClickHouse.Port = getEnvAsInt("CLICKHOUSE_PORT", 9000)
 	config.Database.ClickHouse.User = getEnv("CLICKHOUSE_USER", "default")
 	config.Database.ClickHouse.Password = getEnv("CLICKHOUSE_PASSWORD", "")
 	config.Database.ClickHouse.Database = getEnv("CLICKHOUSE_DB", "finone_search")
+	config.Database.ClickHouse.HTTPPort = getEnvAsInt("CLICKHOUSE_HTTP_PORT", 8123)
+	config.Database.ClickHouse.MaxOpenConns = getEnvAsInt("CLICKHOUSE_MAX_OPEN_CONNS", 10)
+	config.Database.ClickHouse.MaxIdleConns = getEnvAsInt("CLICKHOUSE_MAX_IDLE_CONNS", 5)
+	config.Database.ClickHouse.ConnMaxLifetime = time.Duration(getEnvAsInt("CLICKHOUSE_CONN_MAX_LIFETIME_SECONDS", 3600)) * time.Second
+	config.Database.ClickHouse.DialTimeout = time.Duration(getEnvAsInt("CLICKHOUSE_DIAL_TIMEOUT_SECONDS", 10)) * time.Second
+	config.Database.ClickHouse.ReadTimeout = time.Duration(getEnvAsInt("CLICKHOUSE_READ_TIMEOUT_SECONDS", 300)) * time.Second
+	config.Database.ClickHouse.ConnectRetries = getEnvAsInt("CLICKHOUSE_CONNECT_RETRIES", 5)
+	config.Database.ClickHouse.PeopleTable = getEnv("CLICKHOUSE_PEOPLE_TABLE", "people")
 
-	config.JWT.Secret = getEnv("JWT_SECRET", "your-super-secret-key-change-in-production")
+	config.JWT.Secret = getEnv("JWT_SECRET", DefaultJWTSecret)
 	config.JWT.Expiry = time.Duration(getEnvAsInt("JWT_EXPIRY_HOURS", 24)) * time.Hour
+	config.JWT.IdleTimeout = time.Duration(getEnvAsInt("SESSION_IDLE_TIMEOUT_MINUTES", 0)) * time.Minute
+	config.JWT.ExtendActive = getEnvAsBool("SESSION_EXTEND_ACTIVE", false)
+	config.JWT.ImpersonationExpiry = time.Duration(getEnvAsInt("IMPERSONATION_EXPIRY_MINUTES", 30)) * time.Minute
 
 	config.Limits.MaxSearchesPerDay = getEnvAsInt("MAX_SEARCHES_PER_DAY", 500)
 	config.Limits.MaxExportsPerDay = getEnvAsInt("MAX_EXPORTS_PER_DAY", 3)
 	config.Limits.MaxRowsPerSearch = getEnvAsInt("MAX_ROWS_PER_SEARCH", 10000)
+	config.Limits.MaxExportRows = getEnvAsInt("MAX_EXPORT_ROWS", 1000000)
 	config.Limits.MaxUploadSize = getEnv("MAX_UPLOAD_SIZE", "2GB")
+	config.Limits.MaxExportRowsByUserType = map[string]int{"DEMO": getEnvAsInt("MAX_EXPORT_ROWS_DEMO", 10000)}
 
 	config.CSV.BatchSize = getEnvAsInt("CSV_BATCH_SIZE", 100000)
 	config.CSV.TempDir = getEnv("CSV_TEMP_DIR", "/tmp/csv_uploads")
+	config.CSV.MaxRetries = getEnvAsInt("CSV_MAX_RETRIES", 3)
+	config.CSV.ImportDir = getEnv("CSV_IMPORT_DIR", "./imports")
+	config.CSV.ErrorDir = getEnv("CSV_ERROR_DIR", "./import_errors")
+	config.CSV.URLAllowPrivate = getEnvAsBool("CSV_URL_ALLOW_PRIVATE", false)
+	config.CSV.MaxChunkSizeMB = getEnvAsInt("CSV_MAX_CHUNK_SIZE_MB", 64)
+
+	config.S3.Region = getEnv("S3_REGION", getEnv("AWS_REGION", "us-east-1"))
+	config.S3.Endpoint = getEnv("S3_ENDPOINT", "")
+	config.S3.AccessKeyID = getEnv("S3_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID"))
+	config.S3.SecretAccessKey = getEnv("S3_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	config.S3.SessionToken = getEnv("S3_SESSION_TOKEN", os.Getenv("AWS_SESSION_TOKEN"))
+	config.S3.PathStyle = getEnvAsBool("S3_PATH_STYLE", false)
+
+	config.Export.Dir = getEnv("EXPORT_DIR", "./downloads/exports")
+	config.Export.FastPathThreshold = getEnvAsInt("EXPORT_FAST_PATH_THRESHOLD", 100000)
+	config.Export.RegenerateFree = getEnvAsBool("EXPORT_REGENERATE_FREE", false)
+	config.Export.Storage = getEnv("EXPORT_STORAGE", "local")
+	config.Export.S3Bucket = getEnv("EXPORT_S3_BUCKET", "")
+	config.Export.S3Prefix = getEnv("EXPORT_S3_PREFIX", "exports/")
+	config.Export.CleanupInterval = time.Duration(getEnvAsInt("EXPORT_CLEANUP_INTERVAL_MINUTES", 60)) * time.Minute
+	config.Export.Watermark = getEnv("EXPORT_WATERMARK", "column")
+	config.Export.RowLimitAction = getEnv("EXPORT_ROW_LIMIT_ACTION", "reject")
+
+	config.Cache.AuthTTL = time.Duration(getEnvAsInt("AUTH_CACHE_TTL_SECONDS", 30)) * time.Second
+
+	config.Quota.ResetTimezone = getEnv("QUOTA_RESET_TIMEZONE", "Asia/Kolkata")
+	config.Quota.ResetTime = getEnv("QUOTA_RESET_TIME", "00:00")
+
+	config.RateLimit.Enabled = getEnvAsBool("RATE_LIMIT_ENABLED", true)
+	config.RateLimit.RequestsPerMinute = getEnvAsInt("RATE_LIMIT_PER_MINUTE", 300)
+	config.RateLimit.Burst = getEnvAsInt("RATE_LIMIT_BURST", 0)
+	config.RateLimit.UserTypes = map[string]int{"DEMO": getEnvAsInt("RATE_LIMIT_DEMO_PER_MINUTE", 60)}
+	config.RateLimit.Routes = []RouteRateLimitConfig{
+		{Route: "POST /api/v1/search/export", RequestsPerMinute: getEnvAsInt("RATE_LIMIT_EXPORT_PER_MINUTE", 10)},
+	}
+
+	config.Search.Backend = getEnv("SEARCH_BACKEND", "clickhouse")
+	config.Search.PincodeGeoFile = getEnv("SEARCH_PINCODE_GEO_FILE", "")
+	config.Search.SlowQueryThreshold = time.Duration(getEnvAsInt("SEARCH_SLOW_QUERY_MS", 2000)) * time.Millisecond
+	config.Search.EnhancedMobileMaxLinked = getEnvAsInt("SEARCH_ENHANCED_MOBILE_MAX_LINKED", 500)
+	config.Search.CountMode = getEnv("SEARCH_COUNT_MODE", "window")
+	config.Search.ExactCountLimit = getEnvAsInt("SEARCH_EXACT_COUNT_LIMIT", 100000)
+	config.Search.Budgets = map[string]QueryBudgetConfig{
+		"default": {Timeout: time.Duration(getEnvAsInt("SEARCH_TIMEOUT_SECONDS", 30)) * time.Second},
+		"DEMO": {
+			Timeout:        time.Duration(getEnvAsInt("SEARCH_DEMO_TIMEOUT_SECONDS", 10)) * time.Second,
+			MaxRowsToRead:  uint64(getEnvAsInt("SEARCH_DEMO_MAX_ROWS_TO_READ", 50000000)),
+			MaxResultBytes: uint64(getEnvAsInt("SEARCH_DEMO_MAX_RESULT_BYTES", 104857600)),
+		},
+	}
+
+	config.Response.OmitEmpty = getEnvAsBool("RESPONSE_OMIT_EMPTY", false)
+	config.Response.Timestamps = getEnv("RESPONSE_TIMESTAMPS", "rfc3339")
+	config.Response.Casing = getEnv("RESPONSE_CASING", "snake")
+
+	config.Retention.SearchesDays = getEnvAsInt("RETENTION_SEARCHES_DAYS", 365)
+	config.Retention.LoginsDays = getEnvAsInt("RETENTION_LOGINS_DAYS", 180)
+	config.Retention.SystemLogsDays = getEnvAsInt("RETENTION_SYSTEM_LOGS_DAYS", 180)
+	config.Retention.SearchPerformanceDays = getEnvAsInt("RETENTION_SEARCH_PERFORMANCE_DAYS", 90)
+	config.Retention.AnonymizeSearchesDays = getEnvAsInt("RETENTION_ANONYMIZE_SEARCHES_DAYS", 90)
+	config.Retention.DailyUsageDays = getEnvAsInt("RETENTION_DAILY_USAGE_DAYS", 90)
+	config.Retention.SessionsDays = getEnvAsInt("RETENTION_SESSIONS_DAYS", 7)
+	config.Retention.ExportsDays = getEnvAsInt("RETENTION_EXPORTS_DAYS", 0)
+	config.Retention.UploadsDays = getEnvAsInt("RETENTION_UPLOADS_DAYS", 7)
+
+	config.Maintenance.OptimizeEnabled = getEnvAsBool("MAINTENANCE_OPTIMIZE_ENABLED", true)
+	config.Maintenance.OptimizeTime = getEnv("MAINTENANCE_OPTIMIZE_TIME", "01:00")
+	config.Maintenance.OptimizeWindow = time.Duration(getEnvAsInt("MAINTENANCE_OPTIMIZE_WINDOW_MINUTES", 120)) * time.Minute
+
+	config.Jobs.Workers = getEnvAsInt("JOB_WORKERS", 4)
+	config.Jobs.PollInterval = time.Duration(getEnvAsInt("JOB_POLL_INTERVAL_SECONDS", 2)) * time.Second
+	config.Jobs.MaxAttempts = getEnvAsInt("JOB_MAX_ATTEMPTS", 3)
+	config.Jobs.RetryBackoff = time.Duration(getEnvAsInt("JOB_RETRY_BACKOFF_SECONDS", 30)) * time.Second
+	config.Jobs.StaleAfter = time.Duration(getEnvAsInt("JOB_STALE_AFTER_MINUTES", 5)) * time.Minute
+
+	config.Registration.CaptchaProvider = getEnv("REGISTRATION_CAPTCHA_PROVIDER", "")
+	config.Registration.CaptchaSecret = getEnv("REGISTRATION_CAPTCHA_SECRET", "")
+	config.Registration.MaxPerIPPerHour = getEnvAsInt("REGISTRATION_MAX_PER_IP_PER_HOUR", 5)
+	config.Registration.MaxPerEmailPerDay = getEnvAsInt("REGISTRATION_MAX_PER_EMAIL_PER_DAY", 3)
+	config.Registration.VerifyEmail = getEnvAsBool("REGISTRATION_VERIFY_EMAIL", false)
+	config.Registration.VerificationExpiry = time.Duration(getEnvAsInt("REGISTRATION_VERIFICATION_EXPIRY_HOURS", 24)) * time.Hour
+
+	// One OIDC provider can be configured from the environment; more need config.yaml
+	if issuer := getEnv("OIDC_ISSUER", ""); issuer != "" {
+		config.OIDC.Providers = []OIDCProviderConfig{{
+			Name:              getEnv("OIDC_PROVIDER_NAME", "sso"),
+			Issuer:            issuer,
+			ClientID:          getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:      getEnv("OIDC_CLIENT_SECRET", ""),
+			AllowedDomains:    splitList(os.Getenv("OIDC_ALLOWED_DOMAINS")),
+			TrustEmail:        getEnvAsBool("OIDC_TRUST_EMAIL", false),
+			AutoProvision:     getEnvAsBool("OIDC_AUTO_PROVISION", false),
+			DefaultRole:       getEnv("OIDC_DEFAULT_ROLE", "USER"),
+			DefaultUserType:   getEnv("OIDC_DEFAULT_USER_TYPE", "PERMANENT"),
+			MaxSearchesPerDay: getEnvAsInt("OIDC_MAX_SEARCHES_PER_DAY", 0),
+			MaxExportsPerDay:  getEnvAsInt("OIDC_MAX_EXPORTS_PER_DAY", 0),
+		}}
+	}
+	config.OIDC.RedirectURL = getEnv("OIDC_REDIRECT_URL", "")
+	config.OIDC.PostLoginRedirect = getEnv("OIDC_POST_LOGIN_REDIRECT", "")
+	config.OIDC.StateExpiry = time.Duration(getEnvAsInt("OIDC_STATE_EXPIRY_MINUTES", 10)) * time.Minute
+
+	config.Notifications.Enabled = getEnvAsBool("NOTIFICATIONS_ENABLED", false)
+	config.Notifications.Provider = getEnv("NOTIFICATION_PROVIDER", "smtp")
+	config.Notifications.From = getEnv("NOTIFICATION_FROM", "")
+	config.Notifications.AdminEmail = getEnv("NOTIFICATION_ADMIN_EMAIL", "")
+	config.Notifications.BaseURL = getEnv("APP_BASE_URL", "")
+	config.Notifications.SMTP.Host = getEnv("SMTP_HOST", "")
+	config.Notifications.SMTP.Port = getEnvAsInt("SMTP_PORT", 587)
+	config.Notifications.SMTP.Username = getEnv("SMTP_USERNAME", "")
+	config.Notifications.SMTP.Password = getEnv("SMTP_PASSWORD", "")
+
+	config.Webhooks.MaxAttempts = getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5)
+	config.Webhooks.RetryBackoff = time.Duration(getEnvAsInt("WEBHOOK_RETRY_BACKOFF_SECONDS", 10)) * time.Second
+	config.Webhooks.Timeout = time.Duration(getEnvAsInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second
 }
 
 func overrideWithEnv(config *Config) {
@@ -127,9 +556,313 @@ func overrideWithEnv(config *Config) {
 			config.Server.Port = p
 		}
 	}
+	if origins := splitList(os.Getenv("FRONTEND_URL")); len(origins) > 0 {
+		config.Server.CORSOrigins = origins
+	}
+	config.Server.CountryHeader = getEnv("COUNTRY_HEADER", config.Server.CountryHeader)
+	if proxies := splitList(os.Getenv("TRUSTED_PROXIES")); len(proxies) > 0 {
+		config.Server.TrustedProxies = proxies
+	}
+	config.GeoIP.CityDB = getEnv("GEOIP_CITY_DB", config.GeoIP.CityDB)
+	config.GeoIP.ASNDB = getEnv("GEOIP_ASN_DB", config.GeoIP.ASNDB)
+	if port := os.Getenv("GRPC_PORT"); port != "" {
+		if p, err := strconv.Atoi(port); err == nil {
+			config.GRPC.Port = p
+		}
+	}
+	if enabled := os.Getenv("TRACING_ENABLED"); enabled != "" {
+		config.Tracing.Enabled = getEnvAsBool("TRACING_ENABLED", config.Tracing.Enabled)
+	}
+	config.Tracing.Endpoint = getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", config.Tracing.Endpoint)
+	if insecure := os.Getenv("OTEL_EXPORTER_OTLP_INSECURE"); insecure != "" {
+		config.Tracing.Insecure = getEnvAsBool("OTEL_EXPORTER_OTLP_INSECURE", config.Tracing.Insecure)
+	}
+	config.Tracing.Protocol = getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", config.Tracing.Protocol)
+	config.Tracing.ServiceName = getEnv("OTEL_SERVICE_NAME", config.Tracing.ServiceName)
+	config.Tracing.SampleRatio = getEnvAsFloat("TRACING_SAMPLE_RATIO", config.Tracing.SampleRatio)
+	if enabled := os.Getenv("RATE_LIMIT_ENABLED"); enabled != "" {
+		config.RateLimit.Enabled = getEnvAsBool("RATE_LIMIT_ENABLED", config.RateLimit.Enabled)
+	}
+	config.RateLimit.RequestsPerMinute = getEnvAsInt("RATE_LIMIT_PER_MINUTE", config.RateLimit.RequestsPerMinute)
+	// Secrets should come from the environment rather than config files
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		config.JWT.Secret = secret
+	}
+	if password := os.Getenv("POSTGRES_PASSWORD"); password != "" {
+		config.Database.Postgres.Password = password
+	}
+	if password := os.Getenv("CLICKHOUSE_PASSWORD"); password != "" {
+		config.Database.ClickHouse.Password = password
+	}
+	if secret := os.Getenv("REGISTRATION_CAPTCHA_SECRET"); secret != "" {
+		config.Registration.CaptchaSecret = secret
+	}
+	// OIDC client secrets come from OIDC_<NAME>_CLIENT_SECRET, e.g. OIDC_AZURE_CLIENT_SECRET
+	for i := range config.OIDC.Providers {
+		provider := &config.OIDC.Providers[i]
+		name := strings.ToUpper(strings.ReplaceAll(provider.Name, "-", "_"))
+		if secret := os.Getenv("OIDC_" + name + "_CLIENT_SECRET"); secret != "" {
+			provider.ClientSecret = secret
+		}
+	}
+	config.OIDC.RedirectURL = getEnv("OIDC_REDIRECT_URL", config.OIDC.RedirectURL)
+	// Keep the SMTP password out of config files
+	if password := os.Getenv("SMTP_PASSWORD"); password != "" {
+		config.Notifications.SMTP.Password = password
+	}
+	// Likewise the S3 credentials
+	if accessKey := getEnv("S3_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID")); accessKey != "" {
+		config.S3.AccessKeyID = accessKey
+		config.S3.SecretAccessKey = getEnv("S3_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+		config.S3.SessionToken = getEnv("S3_SESSION_TOKEN", os.Getenv("AWS_SESSION_TOKEN"))
+	}
 	// Add more overrides as needed
 }
 
+// applyDefaults sets defaults for settings that are zero, since a YAML config skips loadFromEnv
+func applyDefaults(config *Config) {
+	if len(config.Server.CORSOrigins) == 0 {
+		config.Server.CORSOrigins = []string{
+			"http://localhost:3000",
+			"https://finoneweb.nikhilsahni.xyz",
+			"https://finone.nikhilsahni.xyz",
+		}
+	}
+	if config.GRPC.Port <= 0 {
+		config.GRPC.Port = 9090
+	}
+	if config.RateLimit.RequestsPerMinute <= 0 {
+		config.RateLimit.RequestsPerMinute = 300
+	}
+
+	if config.Tracing.Endpoint == "" {
+		config.Tracing.Endpoint = "localhost:4317"
+	}
+	if config.Tracing.Protocol == "" {
+		config.Tracing.Protocol = "grpc"
+	}
+	if config.Tracing.ServiceName == "" {
+		config.Tracing.ServiceName = "finone-search-system"
+	}
+	if config.Tracing.SampleRatio <= 0 || config.Tracing.SampleRatio > 1 {
+		config.Tracing.SampleRatio = 1
+	}
+
+	pg := &config.Database.Postgres
+	if pg.MaxOpenConns <= 0 {
+		pg.MaxOpenConns = 25
+	}
+	if pg.MaxIdleConns <= 0 {
+		pg.MaxIdleConns = 5
+	}
+	if pg.MaxIdleConns > pg.MaxOpenConns {
+		pg.MaxIdleConns = pg.MaxOpenConns
+	}
+	if pg.ConnMaxLifetime <= 0 {
+		pg.ConnMaxLifetime = 5 * time.Minute
+	}
+
+	ch := &config.Database.ClickHouse
+	if ch.HTTPPort <= 0 {
+		ch.HTTPPort = 8123
+	}
+	if ch.MaxOpenConns <= 0 {
+		ch.MaxOpenConns = 10
+	}
+	if ch.MaxIdleConns <= 0 {
+		ch.MaxIdleConns = 5
+	}
+	if ch.MaxIdleConns > ch.MaxOpenConns {
+		ch.MaxIdleConns = ch.MaxOpenConns
+	}
+	if ch.ConnMaxLifetime <= 0 {
+		ch.ConnMaxLifetime = time.Hour
+	}
+	if ch.DialTimeout <= 0 {
+		ch.DialTimeout = 10 * time.Second
+	}
+	if ch.ReadTimeout <= 0 {
+		ch.ReadTimeout = 5 * time.Minute
+	}
+	if ch.ConnectRetries <= 0 {
+		ch.ConnectRetries = 5
+	}
+	if ch.ConnectBackoff <= 0 {
+		ch.ConnectBackoff = time.Second
+	}
+	if ch.CircuitBreakerThreshold <= 0 {
+		ch.CircuitBreakerThreshold = 5
+	}
+	if ch.CircuitBreakerCooldown <= 0 {
+		ch.CircuitBreakerCooldown = 30 * time.Second
+	}
+	if ch.PeopleTable == "" {
+		ch.PeopleTable = "people"
+	}
+	if ch.PartsWarningPerPartition <= 0 {
+		ch.PartsWarningPerPartition = 300
+	}
+	if ch.PartsWarningPerTable <= 0 {
+		ch.PartsWarningPerTable = 3000
+	}
+	if ch.ReplicaLagWarning <= 0 {
+		ch.ReplicaLagWarning = 5 * time.Minute
+	}
+	if ch.DiskFreeWarningPercent <= 0 {
+		ch.DiskFreeWarningPercent = 15
+	}
+
+	if config.CSV.MaxRetries <= 0 {
+		config.CSV.MaxRetries = 3
+	}
+	if config.CSV.RetryBackoff <= 0 {
+		config.CSV.RetryBackoff = 2 * time.Second
+	}
+	if config.CSV.ImportDir == "" {
+		config.CSV.ImportDir = "./imports"
+	}
+	if config.CSV.ErrorDir == "" {
+		config.CSV.ErrorDir = "./import_errors"
+	}
+	if config.CSV.MaxChunkSizeMB <= 0 {
+		config.CSV.MaxChunkSizeMB = 64
+	}
+	if config.S3.Region == "" {
+		config.S3.Region = "us-east-1"
+	}
+
+	if config.Export.Dir == "" {
+		config.Export.Dir = "./downloads/exports"
+	}
+	if config.Export.FastPathThreshold <= 0 {
+		config.Export.FastPathThreshold = 100000
+	}
+	if config.Export.Expiry <= 0 {
+		config.Export.Expiry = 24 * time.Hour
+	}
+	if config.Export.Storage == "" {
+		config.Export.Storage = "local"
+	}
+	if config.Export.CleanupInterval <= 0 {
+		config.Export.CleanupInterval = time.Hour
+	}
+	if config.Export.Watermark == "" {
+		config.Export.Watermark = "column"
+	}
+	if config.Export.RowLimitAction != "truncate" {
+		config.Export.RowLimitAction = "reject"
+	}
+
+	if config.JWT.ImpersonationExpiry <= 0 {
+		config.JWT.ImpersonationExpiry = 30 * time.Minute
+	}
+
+	if config.Quota.ResetTimezone == "" {
+		config.Quota.ResetTimezone = "Asia/Kolkata"
+	}
+	if config.Quota.ResetTime == "" {
+		config.Quota.ResetTime = "00:00"
+	}
+
+	if config.Search.Backend == "" {
+		config.Search.Backend = "clickhouse"
+	}
+	if config.Search.EnhancedMobileMaxLinked <= 0 {
+		config.Search.EnhancedMobileMaxLinked = 500
+	}
+	if config.Search.CountMode != "query" {
+		config.Search.CountMode = "window"
+	}
+	if config.Search.ExactCountLimit <= 0 {
+		config.Search.ExactCountLimit = 100000
+	}
+	if config.Search.Budgets == nil {
+		config.Search.Budgets = make(map[string]QueryBudgetConfig)
+	}
+	if config.Search.Budgets["default"].Timeout <= 0 {
+		budget := config.Search.Budgets["default"]
+		budget.Timeout = 30 * time.Second
+		config.Search.Budgets["default"] = budget
+	}
+	for userType, budget := range config.Search.Budgets {
+		if budget.Timeout <= 0 {
+			budget.Timeout = config.Search.Budgets["default"].Timeout
+			config.Search.Budgets[userType] = budget
+		}
+	}
+
+	if config.Response.Timestamps == "" {
+		config.Response.Timestamps = "rfc3339"
+	}
+	if config.Response.Casing == "" {
+		config.Response.Casing = "snake"
+	}
+
+	if config.Maintenance.OptimizeTime == "" {
+		config.Maintenance.OptimizeTime = "01:00"
+	}
+	if config.Maintenance.OptimizeWindow <= 0 {
+		config.Maintenance.OptimizeWindow = 2 * time.Hour
+	}
+
+	if config.Jobs.Workers <= 0 {
+		config.Jobs.Workers = 4
+	}
+	if config.Jobs.PollInterval <= 0 {
+		config.Jobs.PollInterval = 2 * time.Second
+	}
+	if config.Jobs.MaxAttempts <= 0 {
+		config.Jobs.MaxAttempts = 3
+	}
+	if config.Jobs.RetryBackoff <= 0 {
+		config.Jobs.RetryBackoff = 30 * time.Second
+	}
+	if config.Jobs.StaleAfter <= 0 {
+		config.Jobs.StaleAfter = 5 * time.Minute
+	}
+
+	if config.Registration.VerificationExpiry <= 0 {
+		config.Registration.VerificationExpiry = 24 * time.Hour
+	}
+
+	if config.OIDC.StateExpiry <= 0 {
+		config.OIDC.StateExpiry = 10 * time.Minute
+	}
+	for i := range config.OIDC.Providers {
+		provider := &config.OIDC.Providers[i]
+		provider.Issuer = strings.TrimSuffix(provider.Issuer, "/")
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+		if provider.DefaultRole == "" {
+			provider.DefaultRole = "USER"
+		}
+		if provider.DefaultUserType == "" {
+			provider.DefaultUserType = "PERMANENT"
+		}
+	}
+
+	if config.Notifications.Provider == "" {
+		config.Notifications.Provider = "smtp"
+	}
+	if config.Notifications.SMTP.Port <= 0 {
+		config.Notifications.SMTP.Port = 587
+	}
+	if config.Notifications.QuotaThreshold <= 0 || config.Notifications.QuotaThreshold > 1 {
+		config.Notifications.QuotaThreshold = 0.9
+	}
+
+	if config.Webhooks.MaxAttempts <= 0 {
+		config.Webhooks.MaxAttempts = 5
+	}
+	if config.Webhooks.RetryBackoff <= 0 {
+		config.Webhooks.RetryBackoff = 10 * time.Second
+	}
+	if config.Webhooks.Timeout <= 0 {
+		config.Webhooks.Timeout = 10 * time.Second
+	}
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -146,6 +879,24 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func (c *Config) GetPostgresConnectionString() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Database.Postgres.Host,
@@ -171,3 +922,14 @@ func (c *Config) GetClickHouseConnectionString() string {
 
 	return connectionStr
 }
+
+// splitList splits a comma-separated list, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// FilePath is the configuration file read at startup and on every reload
+const FilePath = "config/config.yaml"
+
+var current atomic.Pointer[Config]
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func(previous, next *Config)
+)
+
+// Get returns the configuration in effect. A reload swaps it for a new one rather than changing it,
+// so callers that need several settings to agree should call Get once and keep the result.
+func Get() *Config {
+	return current.Load()
+}
+
+// OnReload registers a hook run after each reload with the replaced and the new configuration, e.g.
+// to rebuild middleware from settings it copies at startup
+func OnReload(hook func(previous, next *Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, hook)
+}
+
+// Reload reads the configuration again and swaps it in, then runs the reload hooks. A configuration
+// that fails validation is rejected and the current one kept. Returns the sections that changed but
+// only take effect after a restart.
+func Reload() ([]string, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	next := load()
+	if _, err := Validate(next); err != nil {
+		return nil, err
+	}
+	previous := current.Swap(next)
+	for _, hook := range reloadHooks {
+		hook(previous, next)
+	}
+	return restartRequired(previous, next), nil
+}
+
+// restartRequired lists the sections read only at startup, such as listen addresses and connection
+// settings, that differ between two configurations
+func restartRequired(previous, next *Config) []string {
+	if previous == nil {
+		return nil
+	}
+	var sections []string
+	if previous.Server.Host != next.Server.Host || previous.Server.Port != next.Server.Port || previous.Server.Timeout != next.Server.Timeout {
+		sections = append(sections, "server")
+	}
+	if previous.GRPC != next.GRPC {
+		sections = append(sections, "grpc")
+	}
+	if previous.Database != next.Database {
+		sections = append(sections, "database")
+	}
+	return sections
+}
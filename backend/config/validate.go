@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultJWTSecret is the placeholder secret shipped in config.yaml and used when JWT_SECRET is unset
+const DefaultJWTSecret = "your-super-secret-key-change-in-production"
+
+// defaultPostgresPassword is used when neither config.yaml nor POSTGRES_PASSWORD sets one
+const defaultPostgresPassword = "secret"
+
+// minJWTSecretLength is the shortest JWT secret accepted in release mode
+const minJWTSecretLength = 32
+
+// ReleaseMode reports whether the server runs in Gin's release mode, the default unless GIN_MODE
+// says otherwise
+func ReleaseMode() bool {
+	mode := os.Getenv("GIN_MODE")
+	return mode == "" || mode == "release"
+}
+
+// Problems lists required secrets that are empty or left at their placeholder values
+func (c *Config) Problems() []string {
+	var problems []string
+	switch {
+	case c.JWT.Secret == "":
+		problems = append(problems, "jwt.secret (JWT_SECRET) is empty")
+	case c.JWT.Secret == DefaultJWTSecret:
+		problems = append(problems, "jwt.secret (JWT_SECRET) is the default placeholder")
+	case len(c.JWT.Secret) < minJWTSecretLength:
+		problems = append(problems, fmt.Sprintf("jwt.secret (JWT_SECRET) is shorter than %d characters", minJWTSecretLength))
+	}
+	switch c.Database.Postgres.Password {
+	case "":
+		problems = append(problems, "database.postgres.password (POSTGRES_PASSWORD) is empty")
+	case defaultPostgresPassword:
+		problems = append(problems, "database.postgres.password (POSTGRES_PASSWORD) is the default placeholder")
+	}
+	if c.Registration.CaptchaProvider != "" && c.Registration.CaptchaSecret == "" {
+		problems = append(problems, "registration.captcha_secret (REGISTRATION_CAPTCHA_SECRET) is empty but a CAPTCHA provider is set")
+	}
+	if c.Notifications.Enabled && c.Notifications.Provider == "smtp" &&
+		c.Notifications.SMTP.Username != "" && c.Notifications.SMTP.Password == "" {
+		problems = append(problems, "notifications.smtp.password (SMTP_PASSWORD) is empty but an SMTP username is set")
+	}
+	if len(c.OIDC.Providers) > 0 && c.OIDC.RedirectURL == "" {
+		problems = append(problems, "oidc.redirect_url (OIDC_REDIRECT_URL) is empty but OIDC providers are set")
+	}
+	for _, provider := range c.OIDC.Providers {
+		if provider.Name == "" || provider.Issuer == "" || provider.ClientID == "" {
+			problems = append(problems, fmt.Sprintf("oidc provider %q needs a name, issuer and client_id", provider.Name))
+		}
+	}
+	return problems
+}
+
+// Validate fails in release mode when required secrets are missing or left at their defaults, so
+// a misconfigured deployment does not start with insecure settings. Outside release mode the
+// problems are only returned as warnings.
+func Validate(c *Config) (warnings []string, err error) {
+	problems := c.Problems()
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	if ReleaseMode() {
+		return nil, fmt.Errorf("invalid configuration for release mode (set GIN_MODE=debug to run anyway):\n  - %s",
+			strings.Join(problems, "\n  - "))
+	}
+	return problems, nil
+}
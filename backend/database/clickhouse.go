@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"finone-search-system/config"
@@ -14,15 +15,44 @@ import (
 
 var ClickHouseDB driver.Conn
 
+// InitClickHouse connects to ClickHouse, retrying with exponential backoff so a ClickHouse
+// restart during deployment does not stop the server from starting
 func InitClickHouse() error {
+	cfg := config.Get().Database.ClickHouse
+	backoff := cfg.ConnectBackoff
+
+	var err error
+	for attempt := 1; attempt <= cfg.ConnectRetries; attempt++ {
+		var conn driver.Conn
+		conn, err = openClickHouse()
+		if err == nil {
+			ClickHouseDB = newResilientConn(conn, cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)
+			log.Println("Successfully connected to ClickHouse")
+			return nil
+		}
+
+		if attempt < cfg.ConnectRetries {
+			log.Printf("ClickHouse connection attempt %d/%d failed: %v (retrying in %s)", attempt, cfg.ConnectRetries, err, backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+		}
+	}
+
+	return err
+}
+
+func openClickHouse() (driver.Conn, error) {
+	cfg := config.Get().Database.ClickHouse
+
 	conn, err := clickhouse.Open(&clickhouse.Options{
-		Addr: []string{fmt.Sprintf("%s:%d",
-			config.AppConfig.Database.ClickHouse.Host,
-			config.AppConfig.Database.ClickHouse.Port)},
+		Addr: []string{fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)},
 		Auth: clickhouse.Auth{
-			Database: config.AppConfig.Database.ClickHouse.Database,
-			Username: config.AppConfig.Database.ClickHouse.User,
-			Password: config.AppConfig.Database.ClickHouse.Password,
+			Database: cfg.Database,
+			Username: cfg.User,
+			Password: cfg.Password,
 		},
 		Settings: clickhouse.Settings{
 			"max_execution_time":          60,
@@ -30,22 +60,25 @@ func InitClickHouse() error {
 			"optimize_move_to_prewhere":   1,
 			"use_uncompressed_cache":      0,
 		},
-		Compression: &clickhouse.Compression{Method: clickhouse.CompressionLZ4},
-		DialTimeout: time.Duration(10) * time.Second,
+		Compression:     &clickhouse.Compression{Method: clickhouse.CompressionLZ4},
+		DialTimeout:     cfg.DialTimeout,
+		ReadTimeout:     cfg.ReadTimeout,
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to connect to ClickHouse: %w", err)
+		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
 	}
 
 	// Test the connection
 	if err := conn.Ping(context.Background()); err != nil {
-		return fmt.Errorf("failed to ping ClickHouse: %w", err)
+		conn.Close()
+		return nil, fmt.Errorf("failed to ping ClickHouse: %w", err)
 	}
 
-	ClickHouseDB = conn
-	log.Println("Successfully connected to ClickHouse")
-	return nil
+	return conn, nil
 }
 
 func CloseClickHouse() error {
@@ -55,71 +88,14 @@ func CloseClickHouse() error {
 	return nil
 }
 
-// RunClickHouseMigrations executes the ClickHouse schema migrations
+// RunClickHouseMigrations applies any pending versioned ClickHouse migrations
 func RunClickHouseMigrations() error {
-	migrationQueries := []string{
-		`CREATE DATABASE IF NOT EXISTS finone_search`,
-
-		`CREATE TABLE IF NOT EXISTS finone_search.people
-		(
-			id UUID DEFAULT generateUUIDv4(),
-			master_id String,
-			mobile String,
-			name String,
-			fname String,
-			address String,
-			alt String,
-			circle String,
-			email String,
-			-- Materialized pincode extracted from address for fast filtering (first 6-digit token)
-			pincode String MATERIALIZED arrayFirst(x -> length(x) = 6, extractAll(address, '\\d+')),
-			created_at DateTime DEFAULT now(),
-			updated_at DateTime DEFAULT now(),
-			-- Secondary indexes for accelerating LIKE/ILIKE searches
-			INDEX idx_name_ngram name TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4,
-			INDEX idx_fname_ngram fname TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4,
-			INDEX idx_address_ngram address TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4,
-			INDEX idx_email_token email TYPE tokenbf_v1(1024) GRANULARITY 4,
-			INDEX idx_circle_token circle TYPE tokenbf_v1(1024) GRANULARITY 4,
-			INDEX idx_mobile_token mobile TYPE tokenbf_v1(1024) GRANULARITY 4,
-			INDEX idx_alt_token alt TYPE tokenbf_v1(1024) GRANULARITY 4,
-			INDEX idx_master_id_token master_id TYPE tokenbf_v1(1024) GRANULARITY 4,
-			-- Bloom filter index for exact pincode matches
-			INDEX idx_pincode_bf pincode TYPE bloom_filter GRANULARITY 4
-		)
-		ENGINE = MergeTree()
-		ORDER BY (mobile, name, master_id)
-		SETTINGS index_granularity = 8192,
-		         max_compress_block_size = 1048576,
-		         min_compress_block_size = 65536`,
-
-		`CREATE TABLE IF NOT EXISTS finone_search.search_performance
-		(
-			query_id String,
-			user_id String,
-			query_text String,
-			execution_time_ms UInt32,
-			result_count UInt32,
-			timestamp DateTime DEFAULT now()
-		)
-		ENGINE = MergeTree()
-		ORDER BY timestamp`,
-
-		// Ensure schema upgrades on existing deployments (idempotent)
-		`ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS pincode String MATERIALIZED arrayFirst(x -> length(x) = 6, extractAll(address, '\\d+'))`,
-		`ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_pincode_bf pincode TYPE bloom_filter GRANULARITY 4`,
-		`ALTER TABLE finone_search.people MATERIALIZE COLUMN pincode`,
-		`ALTER TABLE finone_search.people MATERIALIZE INDEX idx_pincode_bf`,
-	}
-
-	for i, query := range migrationQueries {
-		log.Printf("Running ClickHouse migration %d", i+1)
-		if err := ClickHouseDB.Exec(context.Background(), query); err != nil {
-			return fmt.Errorf("failed to run ClickHouse migration %d: %w", i+1, err)
-		}
+	count, err := MigrateUp(ClickHouseMigrator{}, ClickHouseMigrationsDir, 0)
+	if err != nil {
+		return err
 	}
 
-	log.Println("All ClickHouse migrations completed successfully")
+	log.Printf("ClickHouse migrations up to date (%d applied)", count)
 	return nil
 }
 
@@ -132,6 +108,21 @@ func ClickHouseHealthCheck() error {
 	return ClickHouseDB.Ping(context.Background())
 }
 
+// ClickHouseCircuitOpen reports whether ClickHouse calls are currently failing fast
+func ClickHouseCircuitOpen() bool {
+	conn, ok := ClickHouseDB.(*resilientConn)
+	return ok && conn.CircuitOpen()
+}
+
+// ClickHouseRetryAfter returns how long clients should wait before retrying a call that failed
+// because ClickHouse is unavailable
+func ClickHouseRetryAfter() time.Duration {
+	if conn, ok := ClickHouseDB.(*resilientConn); ok {
+		return conn.RetryAfter()
+	}
+	return config.Get().Database.ClickHouse.CircuitBreakerCooldown
+}
+
 // Utility function to execute queries with timeout
 func ExecuteClickHouseQuery(query string, args ...interface{}) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -147,3 +138,15 @@ func SelectClickHouseData(dest interface{}, query string, args ...interface{}) e
 
 	return ClickHouseDB.Select(ctx, dest, query, args...)
 }
+
+// InList returns "(?, ...)" with a placeholder per value, and the values as arguments. A slice bound to
+// a single placeholder is sent as an array literal, which IN does not accept as a list of values.
+func InList(values []string) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "?"
+		args[i] = value
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")", args
+}
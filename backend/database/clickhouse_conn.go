@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	chdriver "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// ErrClickHouseUnavailable is returned without contacting ClickHouse while the circuit is open
+var ErrClickHouseUnavailable = errors.New("clickhouse is temporarily unavailable")
+
+// transientExceptionCodes are ClickHouse server errors that are expected to succeed on retry
+var transientExceptionCodes = map[int32]bool{
+	159: true, // TIMEOUT_EXCEEDED
+	202: true, // TOO_MANY_SIMULTANEOUS_QUERIES
+	209: true, // SOCKET_TIMEOUT
+	210: true, // NETWORK_ERROR
+	241: true, // MEMORY_LIMIT_EXCEEDED
+	252: true, // TOO_MANY_PARTS
+	319: true, // UNKNOWN_STATUS_OF_INSERT
+}
+
+// IsConnectionError reports whether err means ClickHouse could not be reached, as opposed to
+// the server rejecting a query. A query outliving its context, including a read or write timing out
+// at the context's deadline, is not a connection failure.
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrClickHouseUnavailable) || errors.Is(err, clickhouse.ErrAcquireConnTimeout) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		// Only a timeout while dialing means ClickHouse is unreachable
+		var opErr *net.OpError
+		return !netErr.Timeout() || (errors.As(err, &opErr) && opErr.Op == "dial")
+	}
+
+	message := err.Error()
+	return strings.Contains(message, "connection refused") || strings.Contains(message, "broken pipe") ||
+		strings.Contains(message, "connection reset")
+}
+
+// IsTransientError reports whether a failed ClickHouse call is worth retrying
+func IsTransientError(err error) bool {
+	if IsConnectionError(err) {
+		return true
+	}
+
+	var exception *clickhouse.Exception
+	if errors.As(err, &exception) {
+		return transientExceptionCodes[exception.Code]
+	}
+	return false
+}
+
+// resilientConn wraps the ClickHouse pool with a circuit breaker, traces its queries and adds the
+// query budget of their context (see WithQueryBudget). After threshold consecutive
+// connection failures, calls fail fast with ErrClickHouseUnavailable until the cooldown elapses;
+// a single call then probes ClickHouse while the others keep failing fast, and the circuit closes if
+// it succeeds or opens for another cooldown if it fails. The driver pool redials dropped connections
+// on its own, so no explicit reconnect is needed.
+type resilientConn struct {
+	chdriver.Conn
+
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool // A call is probing ClickHouse after the cooldown
+}
+
+func newResilientConn(conn chdriver.Conn, threshold int, cooldown time.Duration) *resilientConn {
+	return &resilientConn{
+		Conn:      conn,
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether a call may be attempted. Once the cooldown of an open circuit elapses, only
+// the first call is let through to probe ClickHouse.
+func (c *resilientConn) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.openUntil.IsZero() {
+		return true
+	}
+	if time.Now().Before(c.openUntil) || c.probing {
+		return false
+	}
+	c.probing = true
+	return true
+}
+
+// record updates the breaker with the outcome of a call. A call whose own context ended says nothing
+// about ClickHouse, so it is not counted; if it was the probe, the next call probes instead.
+func (c *resilientConn) record(ctx context.Context, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil && ctx.Err() != nil {
+		c.probing = false
+		return
+	}
+	if !IsConnectionError(err) {
+		c.failures = 0
+		c.openUntil = time.Time{}
+		c.probing = false
+		return
+	}
+
+	c.failures++
+	if c.probing || c.failures >= c.threshold {
+		c.openUntil = time.Now().Add(c.cooldown)
+	}
+	c.probing = false
+}
+
+// CircuitOpen reports whether calls are currently failing fast
+func (c *resilientConn) CircuitOpen() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.openUntil.IsZero() && (time.Now().Before(c.openUntil) || c.probing)
+}
+
+// RetryAfter returns how long until an open circuit lets a call probe ClickHouse again, or the full
+// cooldown when the circuit is closed
+func (c *resilientConn) RetryAfter() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if wait := time.Until(c.openUntil); wait > 0 {
+		return wait
+	}
+	return c.cooldown
+}
+
+func (c *resilientConn) Select(ctx context.Context, dest any, query string, args ...any) error {
+	if !c.allow() {
+		return ErrClickHouseUnavailable
+	}
+	ctx, span := startQuerySpan(ctx, "clickhouse", "select", query)
+	query = budgetedQuery(ctx, query)
+	err := c.Conn.Select(ctx, dest, query, args...)
+	endQuerySpan(span, err)
+	c.record(ctx, err)
+	return err
+}
+
+func (c *resilientConn) Query(ctx context.Context, query string, args ...any) (chdriver.Rows, error) {
+	if !c.allow() {
+		return nil, ErrClickHouseUnavailable
+	}
+	ctx, span := startQuerySpan(ctx, "clickhouse", "query", query)
+	query = budgetedQuery(ctx, query)
+	rows, err := c.Conn.Query(ctx, query, args...)
+	c.record(ctx, err)
+	if err != nil || span == nil {
+		endQuerySpan(span, err)
+		return rows, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *resilientConn) QueryRow(ctx context.Context, query string, args ...any) chdriver.Row {
+	if !c.allow() {
+		return unavailableRow{}
+	}
+	ctx, span := startQuerySpan(ctx, "clickhouse", "query", query)
+	query = budgetedQuery(ctx, query)
+	row := c.Conn.QueryRow(ctx, query, args...)
+	endQuerySpan(span, row.Err())
+	c.record(ctx, row.Err())
+	return row
+}
+
+func (c *resilientConn) PrepareBatch(ctx context.Context, query string, opts ...chdriver.PrepareBatchOption) (chdriver.Batch, error) {
+	if !c.allow() {
+		return nil, ErrClickHouseUnavailable
+	}
+	ctx, span := startQuerySpan(ctx, "clickhouse", "prepare_batch", query)
+	batch, err := c.Conn.PrepareBatch(ctx, query, opts...)
+	endQuerySpan(span, err)
+	c.record(ctx, err)
+	return batch, err
+}
+
+func (c *resilientConn) Exec(ctx context.Context, query string, args ...any) error {
+	if !c.allow() {
+		return ErrClickHouseUnavailable
+	}
+	ctx, span := startQuerySpan(ctx, "clickhouse", "exec", query)
+	err := c.Conn.Exec(ctx, query, args...)
+	endQuerySpan(span, err)
+	c.record(ctx, err)
+	return err
+}
+
+func (c *resilientConn) AsyncInsert(ctx context.Context, query string, wait bool, args ...any) error {
+	if !c.allow() {
+		return ErrClickHouseUnavailable
+	}
+	ctx, span := startQuerySpan(ctx, "clickhouse", "async_insert", query)
+	err := c.Conn.AsyncInsert(ctx, query, wait, args...)
+	endQuerySpan(span, err)
+	c.record(ctx, err)
+	return err
+}
+
+// Ping always reaches ClickHouse so health checks can close an open circuit
+func (c *resilientConn) Ping(ctx context.Context) error {
+	err := c.Conn.Ping(ctx)
+	c.record(ctx, err)
+	return err
+}
+
+// unavailableRow is returned by QueryRow while the circuit is open
+type unavailableRow struct{}
+
+func (unavailableRow) Err() error           { return ErrClickHouseUnavailable }
+func (unavailableRow) Scan(...any) error    { return ErrClickHouseUnavailable }
+func (unavailableRow) ScanStruct(any) error { return ErrClickHouseUnavailable }
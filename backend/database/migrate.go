@@ -0,0 +1,377 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration directories, relative to the working directory like the rest of the runtime files
+const (
+	PostgresMigrationsDir   = "migrations/postgres"
+	ClickHouseMigrationsDir = "migrations/clickhouse"
+)
+
+// migrationFilePattern matches versioned files such as 003_user_sessions.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// statementSeparator splits ClickHouse scripts, which only accept one statement per query
+var statementSeparator = regexp.MustCompile(`;\s*(\n|$)`)
+
+// peopleAlterPattern matches ClickHouse statements that change the schema of the people table
+var peopleAlterPattern = regexp.MustCompile(`(?m)^\s*ALTER TABLE finone_search\.people\s`)
+
+// Migration represents a versioned schema change with its up and down scripts
+type Migration struct {
+	Version int
+	Name    string
+	UpSQL   string
+	DownSQL string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Migrator applies migrations to one database and records them in its schema_migrations table
+type Migrator interface {
+	ensureMigrationsTable() error
+	appliedVersions() (map[int]time.Time, error)
+	apply(migration Migration, up bool) error
+}
+
+// LoadMigrations reads and orders the versioned migration files in a directory
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory %s: %w", dir, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("conflicting migrations for version %d: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.UpSQL = string(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.UpSQL == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// MigrateUp applies up to steps pending migrations in version order; steps <= 0 applies all of them
+func MigrateUp(m Migrator, dir string, steps int) (int, error) {
+	migrations, applied, err := loadMigrationState(m, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if steps > 0 && count >= steps {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		log.Printf("Applying migration %d_%s", migration.Version, migration.Name)
+		if err := m.apply(migration, true); err != nil {
+			return count, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown rolls back up to steps applied migrations, newest first; steps <= 0 rolls back one
+func MigrateDown(m Migrator, dir string, steps int) (int, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	migrations, applied, err := loadMigrationState(m, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.DownSQL == "" {
+			return count, fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+
+		log.Printf("Rolling back migration %d_%s", migration.Version, migration.Name)
+		if err := m.apply(migration, false); err != nil {
+			return count, fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// GetMigrationStatus lists every migration in a directory and whether it has been applied
+func GetMigrationStatus(m Migrator, dir string) ([]MigrationStatus, error) {
+	migrations, applied, err := loadMigrationState(m, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+func loadMigrationState(m Migrator, dir string) ([]Migration, map[int]time.Time, error) {
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.ensureMigrationsTable(); err != nil {
+		return nil, nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return migrations, applied, nil
+}
+
+// isEmptyScript reports whether a script contains nothing but comments and whitespace
+func isEmptyScript(script string) bool {
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+// PostgresMigrator applies migrations to PostgreSQL, each inside its own transaction
+type PostgresMigrator struct{}
+
+func (PostgresMigrator) ensureMigrationsTable() error {
+	_, err := PostgresDB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT now()
+	)`)
+	return err
+}
+
+func (PostgresMigrator) appliedVersions() (map[int]time.Time, error) {
+	var rows []struct {
+		Version   int       `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := PostgresDB.Select(&rows, `SELECT version, applied_at FROM schema_migrations`); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+func (PostgresMigrator) apply(migration Migration, up bool) error {
+	tx, err := PostgresDB.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	script := migration.UpSQL
+	if !up {
+		script = migration.DownSQL
+	}
+	if !isEmptyScript(script) {
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+	}
+
+	if up {
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+	} else {
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ClickHouseMigrator applies migrations to ClickHouse. ClickHouse has no transactional DDL, so
+// schema_migrations is an append-only log of up/down events and scripts should stay idempotent.
+type ClickHouseMigrator struct{}
+
+func (ClickHouseMigrator) ensureMigrationsTable() error {
+	ctx := context.Background()
+	if err := ClickHouseDB.Exec(ctx, `CREATE DATABASE IF NOT EXISTS finone_search`); err != nil {
+		return err
+	}
+	return ClickHouseDB.Exec(ctx, `CREATE TABLE IF NOT EXISTS finone_search.schema_migrations
+		(
+			version UInt32,
+			name String,
+			direction Enum8('up' = 1, 'down' = 2),
+			applied_at DateTime64(3) DEFAULT now64(3)
+		)
+		ENGINE = MergeTree()
+		ORDER BY (version, applied_at)`)
+}
+
+func (ClickHouseMigrator) appliedVersions() (map[int]time.Time, error) {
+	var rows []struct {
+		Version   uint32    `ch:"version"`
+		Direction string    `ch:"direction"`
+		AppliedAt time.Time `ch:"applied_at"`
+	}
+	query := `SELECT version, argMax(direction, applied_at) AS direction, max(applied_at) AS applied_at
+	          FROM finone_search.schema_migrations
+	          GROUP BY version`
+	if err := ClickHouseDB.Select(context.Background(), &rows, query); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		if row.Direction == "up" {
+			applied[int(row.Version)] = row.AppliedAt
+		}
+	}
+	return applied, nil
+}
+
+func (ClickHouseMigrator) apply(migration Migration, up bool) error {
+	ctx := context.Background()
+
+	script, direction := migration.UpSQL, "up"
+	if !up {
+		script, direction = migration.DownSQL, "down"
+	}
+
+	for _, statement := range statementSeparator.Split(script, -1) {
+		if isEmptyScript(statement) {
+			continue
+		}
+		if err := ClickHouseDB.Exec(ctx, statement); err != nil {
+			return err
+		}
+		if err := alterOtherPeopleTables(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return ClickHouseDB.Exec(ctx,
+		`INSERT INTO finone_search.schema_migrations (version, name, direction) VALUES (?, ?, ?)`,
+		uint32(migration.Version), migration.Name, direction)
+}
+
+// alterOtherPeopleTables repeats a statement that changes the people table on every other table
+// holding people rows, such as tables created for a switchover, so whichever table is switched to
+// has the current schema
+func alterOtherPeopleTables(ctx context.Context, statement string) error {
+	match := peopleAlterPattern.FindStringIndex(statement)
+	if match == nil {
+		return nil
+	}
+
+	tables, err := otherPeopleTables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list people tables: %w", err)
+	}
+	for _, table := range tables {
+		altered := statement[:match[0]] + strings.Replace(statement[match[0]:], "finone_search.people", QualifiedTable(table), 1)
+		log.Printf("Applying people schema change to %s", QualifiedTable(table))
+		if err := ClickHouseDB.Exec(ctx, altered); err != nil {
+			return fmt.Errorf("failed to apply people schema change to %s: %w", QualifiedTable(table), err)
+		}
+	}
+	return nil
+}
+
+// otherPeopleTables returns the MergeTree tables in the people database, other than people, that
+// hold people rows. History and staging tables, which record the people table of each row, are left
+// out.
+func otherPeopleTables(ctx context.Context) ([]string, error) {
+	rows, err := ClickHouseDB.Query(ctx, `SELECT name FROM system.tables
+		WHERE database = ? AND name != 'people' AND engine LIKE '%MergeTree'
+		  AND name IN (SELECT table FROM system.columns WHERE database = ? AND name = 'mobile')
+		  AND name NOT IN (SELECT table FROM system.columns WHERE database = ? AND name = 'people_table')
+		ORDER BY name`, PeopleDatabase, PeopleDatabase, PeopleDatabase)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// PeopleHistoryTable holds the prior versions of people rows in every people table
+const PeopleHistoryTable = PeopleDatabase + ".people_history"
+
+// peopleHistoryColumns are the people columns copied into the history table
+const peopleHistoryColumns = `id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at,
+	confidence, quality_flags, source_file, import_job_id, imported_at, mobile_raw, alt_raw`
+
+// ArchivePeopleRows copies the rows of table with the given IDs into the history table before they
+// are replaced or deleted. Rows written by exceptJobID are skipped, so an import that reuses the IDs
+// of the rows it replaces does not archive its own rows; pass "" to archive every matching row.
+func ArchivePeopleRows(ctx context.Context, table string, ids []string, change, supersededByJobID, exceptJobID string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	idList, args := InList(ids)
+	where := `id IN ` + idList
+	if exceptJobID != "" {
+		where += ` AND import_job_id != ?`
+		args = append(args, exceptJobID)
+	}
+	return archivePeople(ctx, table, where, args, change, supersededByJobID)
+}
+
+// archivePeople copies the rows of table matching a condition into the history table
+func archivePeople(ctx context.Context, table, where string, args []interface{}, change, supersededByJobID string) error {
+	query := `INSERT INTO ` + PeopleHistoryTable + ` (` + peopleHistoryColumns + `, people_table, change, superseded_by_job_id)
+		SELECT ` + peopleHistoryColumns + `, ?, ?, ? FROM ` + table + ` WHERE ` + where
+	args = append([]interface{}{table, change, supersededByJobID}, args...)
+
+	if err := ClickHouseDB.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to archive people rows: %w", err)
+	}
+	return nil
+}
+
+// RestorePeopleRows puts back the rows of table that the import jobID replaced, e.g. when the import
+// is rolled back, and returns how many were restored. The history entries are kept.
+func RestorePeopleRows(ctx context.Context, table, jobID string) (uint64, error) {
+	var count uint64
+	countQuery := `SELECT count() FROM ` + PeopleHistoryTable + ` WHERE superseded_by_job_id = ? AND people_table = ?`
+	if err := ClickHouseDB.QueryRow(ctx, countQuery, jobID, table).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count replaced people rows: %w", err)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	query := `INSERT INTO ` + table + ` (` + peopleHistoryColumns + `)
+		SELECT ` + peopleHistoryColumns + ` FROM ` + PeopleHistoryTable + ` WHERE superseded_by_job_id = ? AND people_table = ?`
+	if err := ClickHouseDB.Exec(ctx, query, jobID, table); err != nil {
+		return 0, fmt.Errorf("failed to restore replaced people rows: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// PeopleStagingTable holds the changed rows found by delta imports until they are applied or discarded
+const PeopleStagingTable = PeopleDatabase + ".people_staging"
+
+// stagedCondition selects the staged rows of an import, or only those with the given IDs
+func stagedCondition(jobID string, ids []string) (string, []interface{}) {
+	where := `import_job_id = ?`
+	args := []interface{}{jobID}
+	if len(ids) > 0 {
+		idList, idArgs := InList(ids)
+		where += ` AND id IN ` + idList
+		args = append(args, idArgs...)
+	}
+	return where, args
+}
+
+// stagedTables returns the people tables the selected staged rows of an import belong to, with the
+// number of rows for each
+func stagedTables(ctx context.Context, where string, args []interface{}) (map[string]uint64, error) {
+	rows, err := ClickHouseDB.Query(ctx, `SELECT people_table, count() FROM `+PeopleStagingTable+` WHERE `+where+` GROUP BY people_table`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count staged rows: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]uint64)
+	for rows.Next() {
+		var table string
+		var count uint64
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan staged rows: %w", err)
+		}
+		tables[table] = count
+	}
+	return tables, rows.Err()
+}
+
+// ApplyStagedPeopleRows writes the staged rows of an import, or only those with the given IDs, over
+// the rows they replace and returns how many were applied. The replaced rows are archived with change
+// as replaced by the import, so rolling it back restores them. Where a row was staged more than once,
+// one of its versions is applied.
+func ApplyStagedPeopleRows(ctx context.Context, jobID string, ids []string, change string) (uint64, error) {
+	where, args := stagedCondition(jobID, ids)
+	tables, err := stagedTables(ctx, where, args)
+	if err != nil {
+		return 0, err
+	}
+
+	var applied uint64
+	for table, count := range tables {
+		tableWhere := where + ` AND people_table = ?`
+		tableArgs := append(append([]interface{}{}, args...), table)
+		replaced := `id IN (SELECT id FROM ` + PeopleStagingTable + ` WHERE ` + tableWhere + `) AND import_job_id != ?`
+		replacedArgs := append(append([]interface{}{}, tableArgs...), jobID)
+
+		if err := archivePeople(ctx, table, replaced, replacedArgs, change, jobID); err != nil {
+			return applied, err
+		}
+		insert := `INSERT INTO ` + table + ` (` + peopleHistoryColumns + `)
+			SELECT ` + peopleHistoryColumns + ` FROM ` + PeopleStagingTable + ` WHERE ` + tableWhere + ` LIMIT 1 BY id`
+		if err := ClickHouseDB.Exec(ctx, insert, tableArgs...); err != nil {
+			return applied, fmt.Errorf("failed to apply staged rows: %w", err)
+		}
+		if err := ClickHouseDB.Exec(ctx, `DELETE FROM `+table+` WHERE `+replaced, replacedArgs...); err != nil {
+			return applied, fmt.Errorf("failed to delete replaced rows: %w", err)
+		}
+		if err := ClickHouseDB.Exec(ctx, `DELETE FROM `+PeopleStagingTable+` WHERE `+tableWhere, tableArgs...); err != nil {
+			return applied, fmt.Errorf("failed to clear applied staged rows: %w", err)
+		}
+		applied += count
+	}
+	return applied, nil
+}
+
+// DiscardStagedPeopleRows drops the staged rows of an import, or only those with the given IDs, and
+// returns how many were discarded
+func DiscardStagedPeopleRows(ctx context.Context, jobID string, ids []string) (uint64, error) {
+	where, args := stagedCondition(jobID, ids)
+	tables, err := stagedTables(ctx, where, args)
+	if err != nil {
+		return 0, err
+	}
+
+	var discarded uint64
+	for _, count := range tables {
+		discarded += count
+	}
+	if discarded == 0 {
+		return 0, nil
+	}
+	if err := ClickHouseDB.Exec(ctx, `DELETE FROM `+PeopleStagingTable+` WHERE `+where, args...); err != nil {
+		return 0, fmt.Errorf("failed to discard staged rows: %w", err)
+	}
+	return discarded, nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"sync/atomic"
+
+	"finone-search-system/config"
+)
+
+// PeopleDatabase is the ClickHouse database holding the people tables
+const PeopleDatabase = "finone_search"
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// activePeopleTable holds the unqualified name of the people table that searches, exports and
+// imports use. Swapping it is atomic, so a switchover never mixes tables within a single query.
+var activePeopleTable atomic.Value
+
+// peopleTableKey carries a dataset's people table on a query context
+type peopleTableKey struct{}
+
+// ValidTableName reports whether name is a plain ClickHouse identifier that is safe to interpolate
+func ValidTableName(name string) bool {
+	return tableNamePattern.MatchString(name)
+}
+
+// PeopleTableName returns the unqualified name of the active people table
+func PeopleTableName() string {
+	if name, ok := activePeopleTable.Load().(string); ok && name != "" {
+		return name
+	}
+	if config.Get() != nil && ValidTableName(config.Get().Database.ClickHouse.PeopleTable) {
+		return config.Get().Database.ClickHouse.PeopleTable
+	}
+	return "people"
+}
+
+// PeopleTable returns the fully qualified active people table, e.g. finone_search.people
+func PeopleTable() string {
+	return QualifiedTable(PeopleTableName())
+}
+
+// QualifiedTable returns the fully qualified name of a table in the people database
+func QualifiedTable(name string) string {
+	return PeopleDatabase + "." + name
+}
+
+// SetPeopleTable switches the active people table; callers validate that the table exists first
+func SetPeopleTable(name string) {
+	activePeopleTable.Store(name)
+}
+
+// WithPeopleTable returns a context whose people queries run against table, a fully qualified
+// dataset table. An empty table keeps the active people table.
+func WithPeopleTable(ctx context.Context, table string) context.Context {
+	if table == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, peopleTableKey{}, table)
+}
+
+// PeopleTableFor returns the people table a query runs against: the dataset table carried by ctx,
+// or the active people table
+func PeopleTableFor(ctx context.Context) string {
+	if table, ok := ctx.Value(peopleTableKey{}).(string); ok && table != "" {
+		return table
+	}
+	return PeopleTable()
+}
@@ -3,29 +3,29 @@ package database
 import (
 	"fmt"
 	"log"
-	"os"
-	"time"
 
 	"finone-search-system/config"
 
 	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
 )
 
 var PostgresDB *sqlx.DB
 
 func InitPostgres() error {
-	connectionString := config.AppConfig.GetPostgresConnectionString()
+	connectionString := config.Get().GetPostgresConnectionString()
 
-	db, err := sqlx.Connect("postgres", connectionString)
+	sqlDB, err := openTracedPostgres(connectionString)
 	if err != nil {
 		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
+	db := sqlx.NewDb(sqlDB, "postgres")
 
 	// Configure connection pool
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	pool := config.Get().Database.Postgres
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
@@ -44,36 +44,15 @@ func ClosePostgres() error {
 	return nil
 }
 
-// RunPostgresMigrations executes the PostgreSQL schema migrations
+// RunPostgresMigrations applies any pending versioned PostgreSQL migrations
 func RunPostgresMigrations() error {
-	migrationFiles := []string{
-		"migrations/001_postgres_schema.sql",
-		"migrations/003_user_sessions.sql",
-		"migrations/004_system_logs.sql",
-		"migrations/005_user_registration_requests.sql",
-		"migrations/006_user_password_change_requests.sql",
-		"migrations/007_fix_password_change_status.sql",
-	}
-
-	for _, file := range migrationFiles {
-		log.Printf("Running migration: %s", file)
-		if err := runMigrationFile(file); err != nil {
-			return fmt.Errorf("failed to run migration %s: %w", file, err)
-		}
-	}
-
-	log.Println("All PostgreSQL migrations completed successfully")
-	return nil
-}
-
-func runMigrationFile(filePath string) error {
-	content, err := os.ReadFile(filePath)
+	count, err := MigrateUp(PostgresMigrator{}, PostgresMigrationsDir, 0)
 	if err != nil {
 		return err
 	}
 
-	_, err = PostgresDB.Exec(string(content))
-	return err
+	log.Printf("PostgreSQL migrations up to date (%d applied)", count)
+	return nil
 }
 
 // Utility functions for database operations
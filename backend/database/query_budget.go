@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// QueryBudget limits the ClickHouse work of the queries run on a context: how long each may run and how
+// many rows and result bytes it may read. Zero fields are unlimited.
+type QueryBudget struct {
+	Timeout        time.Duration
+	MaxRowsToRead  uint64
+	MaxResultBytes uint64
+}
+
+// queryBudgetKey carries a query budget on a query context
+type queryBudgetKey struct{}
+
+// budgetExceptionCodes are the ClickHouse errors of a query stopped by its budget
+var budgetExceptionCodes = map[int32]bool{
+	158: true, // TOO_MANY_ROWS
+	159: true, // TIMEOUT_EXCEEDED
+	307: true, // TOO_MANY_BYTES
+	396: true, // TOO_MANY_ROWS_OR_BYTES
+}
+
+// trailingSettings matches a SETTINGS clause ending a query, outside any subquery
+var trailingSettings = regexp.MustCompile(`(?is)\sSETTINGS\s[^()]*$`)
+
+// WithQueryBudget returns a context whose ClickHouse queries are limited by budget
+func WithQueryBudget(ctx context.Context, budget QueryBudget) context.Context {
+	return context.WithValue(ctx, queryBudgetKey{}, budget)
+}
+
+// QueryBudgetFor returns the query budget carried by ctx, if any
+func QueryBudgetFor(ctx context.Context) (QueryBudget, bool) {
+	budget, ok := ctx.Value(queryBudgetKey{}).(QueryBudget)
+	return budget, ok
+}
+
+// settings returns the budget as ClickHouse settings, e.g. "max_execution_time=10, max_rows_to_read=1000"
+func (b QueryBudget) settings() string {
+	var settings []string
+	if b.Timeout > 0 {
+		settings = append(settings, fmt.Sprintf("max_execution_time=%d", int(math.Ceil(b.Timeout.Seconds()))))
+	}
+	if b.MaxRowsToRead > 0 {
+		settings = append(settings, fmt.Sprintf("max_rows_to_read=%d", b.MaxRowsToRead))
+	}
+	if b.MaxResultBytes > 0 {
+		settings = append(settings, fmt.Sprintf("max_result_bytes=%d", b.MaxResultBytes))
+	}
+	return strings.Join(settings, ", ")
+}
+
+// budgetedQuery adds the settings of the budget carried by ctx to a query's SETTINGS clause, so the
+// server enforces them
+func budgetedQuery(ctx context.Context, query string) string {
+	budget, ok := QueryBudgetFor(ctx)
+	if !ok {
+		return query
+	}
+	settings := budget.settings()
+	if settings == "" {
+		return query
+	}
+	query = strings.TrimRight(query, " \t\r\n;")
+	if trailingSettings.MatchString(query) {
+		return query + ", " + settings
+	}
+	return query + " SETTINGS " + settings
+}
+
+// IsBudgetError reports whether a query failed because it ran out of its query budget
+func IsBudgetError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var exception *clickhouse.Exception
+	return errors.As(err, &exception) && budgetExceptionCodes[exception.Code]
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	chdriver "github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("finone-search-system/database")
+
+// startQuerySpan starts a client span for a database call with the SQL as an attribute. Spans are
+// only started under an existing span, such as an HTTP request's, so background work and calls made
+// without a context do not each start a trace of their own.
+func startQuerySpan(ctx context.Context, system, operation, query string) (context.Context, trace.Span) {
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return ctx, nil
+	}
+	return tracer.Start(ctx, system+" "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", system),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", query),
+		),
+	)
+}
+
+// endQuerySpan records the outcome of a call on a span from startQuerySpan
+func endQuerySpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedRows ends the span of a ClickHouse query once its rows are closed
+type tracedRows struct {
+	chdriver.Rows
+	span trace.Span
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if err == nil {
+		err = r.Rows.Err()
+	}
+	endQuerySpan(r.span, err)
+	return err
+}
+
+// openTracedPostgres opens a PostgreSQL pool whose queries are traced when run with a context
+// carrying a span (GetContext, ExecContext and the like)
+func openTracedPostgres(dsn string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(tracedConnector{connector}), nil
+}
+
+type tracedConnector struct {
+	driver.Connector
+}
+
+func (t tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := t.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedPostgresConn{conn}, nil
+}
+
+// tracedPostgresConn wraps a lib/pq connection, tracing queries and passing everything else through
+type tracedPostgresConn struct {
+	driver.Conn
+}
+
+func (c *tracedPostgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startQuerySpan(ctx, "postgresql", "query", query)
+	rows, err := c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+func (c *tracedPostgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startQuerySpan(ctx, "postgresql", "exec", query)
+	result, err := c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+	endQuerySpan(span, err)
+	return result, err
+}
+
+func (c *tracedPostgresConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *tracedPostgresConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *tracedPostgresConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c *tracedPostgresConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *tracedPostgresConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
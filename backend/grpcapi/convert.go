@@ -0,0 +1,132 @@
+package grpcapi
+
+import (
+	"time"
+
+	"finone-search-system/models"
+	finonev1 "finone-search-system/proto/finone/v1"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func searchRequestFromProto(in *finonev1.SearchRequest) *models.SearchRequest {
+	req := &models.SearchRequest{
+		Query:        in.GetQuery(),
+		Fields:       in.GetFields(),
+		FieldQueries: in.GetFieldQueries(),
+		Logic:        in.GetLogic(),
+		MatchType:    in.GetMatchType(),
+		Limit:        int(in.GetLimit()),
+		Offset:       int(in.GetOffset()),
+		Facets:       in.GetFacets(),
+		FacetLimit:   int(in.GetFacetLimit()),
+		SortBy:       in.GetSortBy(),
+		Quality:      in.GetQuality(),
+		Diagnostic:   in.GetDiagnostic(),
+		DatasetID:    in.GetDatasetId(),
+	}
+	if near := in.GetNear(); near != nil {
+		req.Near = &models.NearbyFilter{
+			Pincode:  near.GetPincode(),
+			RadiusKm: near.GetRadiusKm(),
+			Prefixes: near.GetPrefixes(),
+		}
+	}
+	return req
+}
+
+func searchResponseToProto(response *models.SearchResponse) *finonev1.SearchResponse {
+	out := &finonev1.SearchResponse{
+		Results:         peopleToProto(response.Results),
+		TotalCount:      int32(response.TotalCount),
+		ExecutionTimeMs: int32(response.ExecutionTime),
+		SearchId:        response.SearchID,
+		HasMore:         response.HasMore,
+		Quota:           searchQuotaToProto(response.Quota),
+	}
+	if len(response.Facets) > 0 {
+		out.Facets = make(map[string]*finonev1.FacetCounts, len(response.Facets))
+		for field, counts := range response.Facets {
+			facet := &finonev1.FacetCounts{Counts: make([]*finonev1.FacetCount, len(counts))}
+			for i, count := range counts {
+				facet.Counts[i] = &finonev1.FacetCount{Value: count.Value, Count: count.Count}
+			}
+			out.Facets[field] = facet
+		}
+	}
+	return out
+}
+
+func enhancedMobileSearchResponseToProto(response *models.EnhancedMobileSearchResponse) *finonev1.EnhancedMobileSearchResponse {
+	return &finonev1.EnhancedMobileSearchResponse{
+		DirectMatches:        peopleToProto(response.DirectMatches),
+		MasterIdMatches:      peopleToProto(response.MasterIDMatches),
+		TotalDirectMatches:   int32(response.TotalDirectMatches),
+		TotalMasterIdMatches: int32(response.TotalMasterIDMatches),
+		TotalCount:           int32(response.TotalCount),
+		ExecutionTimeMs:      int32(response.ExecutionTime),
+		SearchId:             response.SearchID,
+		HasMore:              response.HasMore,
+		MasterIds:            response.MasterIDs,
+		Quota:                searchQuotaToProto(response.Quota),
+	}
+}
+
+func searchQuotaToProto(quota *models.SearchQuota) *finonev1.SearchQuota {
+	if quota == nil {
+		return nil
+	}
+	return &finonev1.SearchQuota{
+		Charged:           quota.Charged,
+		FreeReason:        quota.FreeReason,
+		SearchesUsedToday: int32(quota.SearchesUsedToday),
+		SearchesLimit:     int32(quota.SearchesLimit),
+		SearchesRemaining: int32(quota.SearchesRemaining),
+		ResetsAt:          timestampToProto(quota.ResetsAt),
+	}
+}
+
+func peopleToProto(people []models.Person) []*finonev1.Person {
+	out := make([]*finonev1.Person, len(people))
+	for i := range people {
+		out[i] = personToProto(&people[i])
+	}
+	return out
+}
+
+func personToProto(person *models.Person) *finonev1.Person {
+	out := &finonev1.Person{
+		Id:           person.ID,
+		MasterId:     person.MasterID,
+		Mobile:       person.Mobile,
+		Name:         person.Name,
+		Fname:        person.FName,
+		Address:      person.Address,
+		Alt:          person.Alt,
+		Circle:       person.Circle,
+		Email:        person.Email,
+		CreatedAt:    timestampToProto(person.CreatedAt),
+		UpdatedAt:    timestampToProto(person.UpdatedAt),
+		Confidence:   uint32(person.Confidence),
+		QualityFlags: person.QualityFlags,
+	}
+	if len(person.Highlights) > 0 {
+		out.Highlights = make(map[string]*finonev1.Highlights, len(person.Highlights))
+		for field, highlights := range person.Highlights {
+			matches := &finonev1.Highlights{Highlights: make([]*finonev1.Highlight, len(highlights))}
+			for i, h := range highlights {
+				matches.Highlights[i] = &finonev1.Highlight{Start: int32(h.Start), End: int32(h.End), Snippet: h.Snippet}
+			}
+			out.Highlights[field] = matches
+		}
+	}
+	return out
+}
+
+// timestampToProto leaves zero times unset, as the JSON API's omit_empty option does
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,300 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"finone-search-system/database"
+	"finone-search-system/middleware"
+	"finone-search-system/models"
+	finonev1 "finone-search-system/proto/finone/v1"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// caller is the authenticated user of a call, set by the auth interceptor
+type caller struct {
+	userID         uuid.UUID
+	role           string
+	impersonatedBy string
+	clientID       string
+	requestID      string
+}
+
+type callerKey struct{}
+
+// Server implements finone.v1.SearchService over the same service layer as the JSON API
+type Server struct {
+	finonev1.UnimplementedSearchServiceServer
+	authService          *services.AuthService
+	authorizationService *services.AuthorizationService
+	searchService        *services.SearchService
+	datasetService       *services.DatasetService
+}
+
+func NewServer() *Server {
+	return &Server{
+		authService:          services.NewAuthService(),
+		authorizationService: services.NewAuthorizationService(),
+		searchService:        services.NewSearchService(),
+		datasetService:       services.NewDatasetService(),
+	}
+}
+
+// Serve listens on addr and serves the gRPC API until the returned server is stopped
+func Serve(addr string) (*grpc.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(server.authenticate))
+	finonev1.RegisterSearchServiceServer(grpcServer, server)
+
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			utils.LogError("gRPC server stopped", err)
+		}
+	}()
+	return grpcServer, nil
+}
+
+// authenticate validates the session token sent as "authorization: Bearer <token>" metadata, like
+// AuthMiddleware, checks the caller may search and applies their rate limit. A refreshed token is returned in the
+// x-session-token header when the session is extended.
+func (s *Server) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authHeader := firstMetadata(md, "authorization")
+	if authHeader == "" {
+		return nil, status.Error(codes.Unauthenticated, "Missing authorization metadata")
+	}
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		return nil, status.Error(codes.Unauthenticated, "Invalid authorization metadata format")
+	}
+
+	user, err := s.authService.ValidateSession(tokenString)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid session: %v", err)
+	}
+
+	// Every method searches people, so they share the search routes' permission
+	if !s.authorizationService.HasPermission(user.Role, services.PermissionSearch) {
+		return nil, status.Error(codes.PermissionDenied, "Insufficient permissions")
+	}
+
+	call := &caller{
+		userID:    user.ID,
+		role:      user.Role,
+		clientID:  services.NormalizeClientID(firstMetadata(md, strings.ToLower(services.ClientIDHeader))),
+		requestID: firstMetadata(md, "x-request-id"),
+	}
+	if adminID := s.authService.Impersonator(tokenString); adminID != nil {
+		if !services.AllowedWhileImpersonating(services.PermissionSearch) {
+			return nil, status.Error(codes.PermissionDenied, "Not allowed while impersonating a user")
+		}
+		call.impersonatedBy = adminID.String()
+	}
+
+	// Like MaintenanceMode, only admins are served while maintenance mode is on
+	if message, enabled := services.MaintenanceMessage(); enabled && user.Role != "ADMIN" && call.impersonatedBy == "" {
+		return nil, status.Error(codes.Unavailable, message)
+	}
+
+	// The same per-user limits as RateLimitMiddleware, sharing its buckets; route rules can name the method
+	if allowed, _, _, wait := middleware.AllowRequest(user, "", info.FullMethod); !allowed {
+		seconds := max(int(math.Ceil(wait.Seconds())), 1)
+		if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds))); err != nil {
+			utils.LogError("Failed to send retry-after", err)
+		}
+		return nil, status.Error(codes.ResourceExhausted, "Too many requests, please slow down")
+	}
+
+	if newToken, err := s.authService.RecordActivity(tokenString, user); err != nil {
+		utils.LogError("Failed to record session activity", err)
+	} else if newToken != "" {
+		if err := grpc.SetHeader(ctx, metadata.Pairs("x-session-token", newToken)); err != nil {
+			utils.LogError("Failed to send refreshed session token", err)
+		}
+	}
+
+	return handler(context.WithValue(ctx, callerKey{}, call), req)
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func callerFrom(ctx context.Context) (*caller, error) {
+	call, ok := ctx.Value(callerKey{}).(*caller)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "User not found in context")
+	}
+	return call, nil
+}
+
+// allowDiagnostic mirrors the JSON API: impersonation searches are always diagnostic, and only
+// administrators may ask for one
+func (s *Server) allowDiagnostic(call *caller, diagnostic *bool) error {
+	if call.impersonatedBy != "" {
+		*diagnostic = true
+		return nil
+	}
+	if *diagnostic && !s.authorizationService.HasPermission(call.role, services.PermissionSearchDiagnostic) {
+		return status.Error(codes.PermissionDenied, "Diagnostic searches are restricted to administrators")
+	}
+	return nil
+}
+
+// searchQuota completes the quota a search reports with the user's usage after it, leaving it out
+// when the usage is unavailable
+func (s *Server) searchQuota(userID uuid.UUID, quota *models.SearchQuota) *models.SearchQuota {
+	if quota == nil {
+		return nil
+	}
+	usage, err := s.authService.GetQuotaUsage(userID)
+	if err != nil {
+		utils.LogError("Failed to get quota usage for gRPC response", err)
+		return nil
+	}
+	quota.SetUsage(usage)
+	return quota
+}
+
+// searchError maps a search service error to a gRPC status
+func searchError(message string, err error) error {
+	switch {
+	case errors.Is(err, services.ErrSearchLimitExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, services.ErrSearchFieldNotAllowed), errors.Is(err, services.ErrDatasetAccessDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, services.ErrDatasetNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidEnhancedSearch), errors.Is(err, services.ErrInvalidSearchMode):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case database.IsBudgetError(err):
+		// Checked first: a query stopped by its time budget is not an outage
+		return status.Error(codes.ResourceExhausted, "Search exceeded the time or data limit of your account, please narrow it")
+	case database.IsConnectionError(err):
+		return status.Error(codes.Unavailable, "Search is temporarily unavailable, please retry later")
+	}
+	utils.LogError(message, err)
+	return status.Error(codes.Internal, message)
+}
+
+func (s *Server) Search(ctx context.Context, in *finonev1.SearchRequest) (*finonev1.SearchResponse, error) {
+	call, err := callerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := searchRequestFromProto(in)
+	req.ClientID = call.clientID
+	req.RequestID = call.requestID
+	req.Context = ctx
+	req.ImpersonatedBy = call.impersonatedBy
+
+	s.searchService.ApplyDefaults(req)
+	if !utils.IsValidQualityFilter(req.Quality) {
+		return nil, status.Error(codes.InvalidArgument, "Invalid quality filter: "+req.Quality)
+	}
+	if _, err := s.searchService.PrepareNearby(req); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.allowDiagnostic(call, &req.Diagnostic); err != nil {
+		return nil, err
+	}
+
+	response, err := s.searchService.Search(call.userID, req)
+	if err != nil {
+		return nil, searchError("Search failed", err)
+	}
+	response.Quota = s.searchQuota(call.userID, response.Quota)
+	return searchResponseToProto(response), nil
+}
+
+func (s *Server) EnhancedMobileSearch(ctx context.Context, in *finonev1.EnhancedMobileSearchRequest) (*finonev1.EnhancedMobileSearchResponse, error) {
+	call, err := callerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &models.EnhancedMobileSearchRequest{
+		MobileNumber:   in.GetMobileNumber(),
+		Limit:          int(in.GetLimit()),
+		Offset:         int(in.GetOffset()),
+		Diagnostic:     in.GetDiagnostic(),
+		DatasetID:      in.GetDatasetId(),
+		ClientID:       call.clientID,
+		RequestID:      call.requestID,
+		ImpersonatedBy: call.impersonatedBy,
+		Context:        ctx,
+	}
+	if err := s.allowDiagnostic(call, &req.Diagnostic); err != nil {
+		return nil, err
+	}
+	if req.MobileNumber == "" {
+		return nil, status.Error(codes.InvalidArgument, "Mobile number is required")
+	}
+	if req.Offset < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must be a non-negative integer")
+	}
+	if req.Limit <= 0 {
+		req.Limit = 1000
+	}
+	if req.Limit > 10000 {
+		req.Limit = 10000
+	}
+
+	response, err := s.searchService.EnhancedMobileSearch(call.userID, req)
+	if err != nil {
+		return nil, searchError("Enhanced mobile search failed", err)
+	}
+	response.Quota = s.searchQuota(call.userID, response.Quota)
+	return enhancedMobileSearchResponseToProto(response), nil
+}
+
+func (s *Server) GetPerson(ctx context.Context, in *finonev1.GetPersonRequest) (*finonev1.Person, error) {
+	call, err := callerFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "Person ID is required")
+	}
+
+	dataset, err := s.datasetService.ResolveDataset(call.userID, in.GetDatasetId())
+	if err != nil {
+		return nil, searchError("Failed to resolve dataset", err)
+	}
+	table := ""
+	if dataset != nil {
+		table = database.QualifiedTable(dataset.TableName)
+	}
+
+	person, err := s.searchService.GetPersonByID(in.GetId(), table)
+	if err != nil {
+		utils.LogError("Failed to get person", err)
+		return nil, status.Error(codes.NotFound, "Person not found")
+	}
+
+	// Apply the caller's field visibility policy
+	people := []models.Person{*person}
+	s.searchService.MaskResults(call.userID, people)
+	return personToProto(&people[0]), nil
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminExportHandler struct {
+	adminExportService *services.AdminExportService
+}
+
+func NewAdminExportHandler() *AdminExportHandler {
+	return &AdminExportHandler{
+		adminExportService: services.NewAdminExportService(),
+	}
+}
+
+// ExportUsers handles downloading the users table as CSV (admin only)
+func (h *AdminExportHandler) ExportUsers(c *gin.Context) {
+	h.writeCSV(c, "users", func(ctx context.Context, w io.Writer) error {
+		return h.adminExportService.ExportUsers(ctx, w)
+	})
+}
+
+// ExportUsage handles downloading daily usage as CSV, optionally for a period and one user (admin only)
+func (h *AdminExportHandler) ExportUsage(c *gin.Context) {
+	filter, err := adminExportFilter(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	h.writeCSV(c, "usage", func(ctx context.Context, w io.Writer) error {
+		return h.adminExportService.ExportUsage(ctx, w, filter)
+	})
+}
+
+// ExportSearches handles downloading the search log as CSV, optionally for a period and one user (admin only)
+func (h *AdminExportHandler) ExportSearches(c *gin.Context) {
+	filter, err := adminExportFilter(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	h.writeCSV(c, "searches", func(ctx context.Context, w io.Writer) error {
+		return h.adminExportService.ExportSearches(ctx, w, filter)
+	})
+}
+
+// writeCSV streams a CSV download. Once rows have been sent a failure can no longer change the
+// status, so it is logged and the download ends early.
+func (h *AdminExportHandler) writeCSV(c *gin.Context, name string, write func(context.Context, io.Writer) error) {
+	fileName := fmt.Sprintf("%s_%s.csv", name, time.Now().Format("20060102_150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Status(http.StatusOK)
+
+	if err := write(c.Request.Context(), c.Writer); err != nil {
+		utils.LogError("Failed to export "+name, err)
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to export "+name)
+		}
+		return
+	}
+	utils.LogInfo(fmt.Sprintf("Admin %s exported %s", c.GetString("user_id"), name))
+}
+
+// adminExportFilter reads the optional from and to (RFC 3339 times or YYYY-MM-DD dates, to inclusive)
+// and user_id query parameters
+func adminExportFilter(c *gin.Context) (services.AdminExportFilter, error) {
+	var filter services.AdminExportFilter
+	if value := c.Query("from"); value != "" {
+		from, _, err := parseAnalyticsTime(value)
+		if err != nil {
+			return filter, fmt.Errorf("from must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		filter.From = from
+	}
+	if value := c.Query("to"); value != "" {
+		to, dateOnly, err := parseAnalyticsTime(value)
+		if err != nil {
+			return filter, fmt.Errorf("to must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = to
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+	if value := c.Query("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid user ID")
+		}
+		filter.UserID = &userID
+	}
+	return filter, nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CacheHandler struct {
+	cacheAdminService *services.CacheAdminService
+}
+
+func NewCacheHandler() *CacheHandler {
+	return &CacheHandler{
+		cacheAdminService: services.NewCacheAdminService(),
+	}
+}
+
+// GetCacheStats handles reporting the size and hit rate of each cache (admin only)
+func (h *CacheHandler) GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cacheAdminService.GetStats())
+}
+
+// FlushCache handles clearing one cache namespace or all of them (admin only)
+func (h *CacheHandler) FlushCache(c *gin.Context) {
+	var req models.FlushCacheRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+			return
+		}
+	}
+
+	response, err := h.cacheAdminService.Flush(req.Namespace)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CircleHandler struct {
+	circleAliasService *services.CircleAliasService
+}
+
+func NewCircleHandler() *CircleHandler {
+	return &CircleHandler{
+		circleAliasService: services.NewCircleAliasService(),
+	}
+}
+
+// GetCircleAliases handles listing the circle normalization map (admin only)
+func (h *CircleHandler) GetCircleAliases(c *gin.Context) {
+	aliases, err := h.circleAliasService.ListAliases()
+	if err != nil {
+		utils.LogError("Failed to list circle aliases", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve circle aliases")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"aliases": aliases})
+}
+
+// SetCircleAlias handles adding an alias to the circle normalization map or changing its circle (admin only)
+func (h *CircleHandler) SetCircleAlias(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var req models.CircleAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	alias, err := h.circleAliasService.SetAlias(&req, adminID)
+	if errors.Is(err, services.ErrInvalidCircleAlias) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to save circle alias", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to save circle alias")
+		return
+	}
+
+	c.JSON(http.StatusOK, alias)
+}
+
+// DeleteCircleAlias handles removing an alias from the circle normalization map (admin only)
+func (h *CircleHandler) DeleteCircleAlias(c *gin.Context) {
+	err := h.circleAliasService.DeleteAlias(c.Param("alias"))
+	if errors.Is(err, services.ErrCircleAliasNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Circle alias not found")
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to delete circle alias", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete circle alias")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Circle alias deleted"})
+}
+
+// GetUnmappedCircles handles listing the circle values imports found that the map does not know (admin only)
+func (h *CircleHandler) GetUnmappedCircles(c *gin.Context) {
+	params, ok := pageParams(c, 50, 500)
+	if !ok {
+		return
+	}
+
+	response, err := h.circleAliasService.GetUnmapped(params)
+	if err != nil {
+		utils.LogError("Failed to get unmapped circles", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve unmapped circles")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
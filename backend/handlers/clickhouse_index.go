@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClickHouseIndexHandler struct {
+	indexService *services.ClickHouseIndexService
+}
+
+func NewClickHouseIndexHandler() *ClickHouseIndexHandler {
+	return &ClickHouseIndexHandler{
+		indexService: services.NewClickHouseIndexService(),
+	}
+}
+
+// GetIndexes handles listing ClickHouse indexes and their materialization state (admin only)
+func (h *ClickHouseIndexHandler) GetIndexes(c *gin.Context) {
+	indexes, err := h.indexService.GetIndexes()
+	if err != nil {
+		utils.LogError("Failed to get ClickHouse indexes", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve indexes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"indexes": indexes,
+		"count":   len(indexes),
+	})
+}
+
+// MaterializeIndexes handles starting index materialization over existing data (admin only)
+func (h *ClickHouseIndexHandler) MaterializeIndexes(c *gin.Context) {
+	var req models.MaterializeIndexRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+			return
+		}
+	}
+
+	indexes, err := h.indexService.MaterializeIndexes(req.Indexes)
+	if err != nil {
+		utils.LogError("Failed to materialize ClickHouse indexes", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Index materialization started",
+		"indexes": indexes,
+	})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClickHouseSystemHandler struct {
+	systemService *services.ClickHouseSystemService
+}
+
+func NewClickHouseSystemHandler() *ClickHouseSystemHandler {
+	return &ClickHouseSystemHandler{
+		systemService: services.NewClickHouseSystemService(),
+	}
+}
+
+// GetClickHouseStatus handles reporting ClickHouse storage, parts, merges and replication (admin only)
+func (h *ClickHouseSystemHandler) GetClickHouseStatus(c *gin.Context) {
+	status, err := h.systemService.GetStatus(c.Request.Context())
+	if err != nil {
+		utils.LogError("Failed to get ClickHouse status", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get ClickHouse status")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
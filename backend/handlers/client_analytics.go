@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClientAnalyticsHandler struct {
+	clientAnalyticsService *services.ClientAnalyticsService
+}
+
+func NewClientAnalyticsHandler() *ClientAnalyticsHandler {
+	return &ClientAnalyticsHandler{
+		clientAnalyticsService: services.NewClientAnalyticsService(),
+	}
+}
+
+// GetClientAnalytics handles retrieving API usage segmented by client application (admin only)
+func (h *ClientAnalyticsHandler) GetClientAnalytics(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if days < 1 || days > 90 {
+		days = 7
+	}
+
+	// Include the requests counted since the last periodic flush
+	h.clientAnalyticsService.Flush()
+
+	analytics, err := h.clientAnalyticsService.GetClientAnalytics(days)
+	if err != nil {
+		utils.LogError("Failed to get client analytics", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve client analytics")
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConfigHandler struct {
+	configReportService *services.ConfigReportService
+	configReloadService *services.ConfigReloadService
+}
+
+func NewConfigHandler() *ConfigHandler {
+	return &ConfigHandler{
+		configReportService: services.NewConfigReportService(),
+		configReloadService: services.NewConfigReloadService(),
+	}
+}
+
+// GetEffectiveConfig handles reporting the sanitized configuration of the running instance (admin only)
+func (h *ConfigHandler) GetEffectiveConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.configReportService.GetEffectiveConfig())
+}
+
+// ReloadConfig handles reloading the configuration of the instance that serves the request without
+// a restart (admin only)
+func (h *ConfigHandler) ReloadConfig(c *gin.Context) {
+	response, err := h.configReloadService.Reload()
+	if errors.Is(err, services.ErrConfigInvalid) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to reload configuration", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to reload configuration")
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
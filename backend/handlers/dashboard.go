@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DashboardHandler struct {
+	dashboardService *services.DashboardService
+}
+
+func NewDashboardHandler() *DashboardHandler {
+	return &DashboardHandler{
+		dashboardService: services.NewDashboardService(),
+	}
+}
+
+// GetDashboard handles getting the admin home screen figures in one call (admin only)
+func (h *DashboardHandler) GetDashboard(c *gin.Context) {
+	dashboard, err := h.dashboardService.GetDashboard(c.Request.Context())
+	if err != nil {
+		utils.LogError("Failed to get admin dashboard", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get dashboard")
+		return
+	}
+
+	c.JSON(http.StatusOK, dashboard)
+}
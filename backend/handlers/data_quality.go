@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DataQualityHandler struct {
+	dataQualityService *services.DataQualityService
+}
+
+func NewDataQualityHandler() *DataQualityHandler {
+	return &DataQualityHandler{
+		dataQualityService: services.NewDataQualityService(),
+	}
+}
+
+// ValidateRecords handles checking people records for inconsistent fields before they are imported (admin only)
+func (h *DataQualityHandler) ValidateRecords(c *gin.Context) {
+	var req models.ValidateRecordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	response, err := h.dataQualityService.ValidateRecords(req.Records)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevalidatePeople handles recomputing the quality flags of the rows already imported (admin only)
+func (h *DataQualityHandler) RevalidatePeople(c *gin.Context) {
+	if err := h.dataQualityService.Revalidate(); err != nil {
+		utils.LogError("Failed to revalidate people", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to start revalidation")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Revalidation started"})
+}
+
+// GetQualitySummary handles reporting how many people rows carry each quality flag (admin only)
+func (h *DataQualityHandler) GetQualitySummary(c *gin.Context) {
+	summary, err := h.dataQualityService.GetSummary()
+	if err != nil {
+		utils.LogError("Failed to get quality summary", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve quality summary")
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
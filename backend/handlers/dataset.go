@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DatasetHandler struct {
+	datasetService *services.DatasetService
+}
+
+func NewDatasetHandler() *DatasetHandler {
+	return &DatasetHandler{
+		datasetService: services.NewDatasetService(),
+	}
+}
+
+// GetMyDatasets handles listing the datasets the current user may search
+func (h *DatasetHandler) GetMyDatasets(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	datasets, err := h.datasetService.ListUserDatasets(userID, c.GetString("role"))
+	if err != nil {
+		utils.LogError("Failed to list user datasets", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list datasets")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"datasets": datasets})
+}
+
+// GetDatasets handles listing every registered dataset (admin only)
+func (h *DatasetHandler) GetDatasets(c *gin.Context) {
+	datasets, err := h.datasetService.ListDatasets()
+	if err != nil {
+		utils.LogError("Failed to list datasets", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list datasets")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"datasets": datasets})
+}
+
+// CreateDataset handles registering an existing people table as a dataset (admin only)
+func (h *DatasetHandler) CreateDataset(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var req models.CreateDatasetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	dataset, err := h.datasetService.CreateDataset(&req, adminID)
+	if err != nil {
+		h.writeError(c, "Failed to create dataset", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dataset)
+}
+
+// UpdateDataset handles renaming, repointing or deactivating a dataset (admin only)
+func (h *DatasetHandler) UpdateDataset(c *gin.Context) {
+	var req models.UpdateDatasetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	dataset, err := h.datasetService.UpdateDataset(c.Param("id"), &req)
+	if err != nil {
+		h.writeError(c, "Failed to update dataset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dataset)
+}
+
+// DeleteDataset handles unregistering a dataset; its ClickHouse table is kept (admin only)
+func (h *DatasetHandler) DeleteDataset(c *gin.Context) {
+	if err := h.datasetService.DeleteDataset(c.Param("id")); err != nil {
+		h.writeError(c, "Failed to delete dataset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dataset deleted"})
+}
+
+// GetDatasetAccess handles listing the users entitled to a dataset (admin only)
+func (h *DatasetHandler) GetDatasetAccess(c *gin.Context) {
+	access, err := h.datasetService.GetAccess(c.Param("id"))
+	if err != nil {
+		h.writeError(c, "Failed to get dataset access", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"access": access})
+}
+
+// GrantDatasetAccess handles entitling users to a dataset (admin only)
+func (h *DatasetHandler) GrantDatasetAccess(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var req models.GrantDatasetAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	access, err := h.datasetService.GrantAccess(c.Param("id"), req.UserIDs, adminID)
+	if err != nil {
+		h.writeError(c, "Failed to grant dataset access", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"access": access})
+}
+
+// RevokeDatasetAccess handles removing a user's entitlement to a dataset (admin only)
+func (h *DatasetHandler) RevokeDatasetAccess(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.datasetService.RevokeAccess(c.Param("id"), userID); err != nil {
+		h.writeError(c, "Failed to revoke dataset access", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dataset access revoked"})
+}
+
+// GetDatasetStats handles reporting a dataset's size and search volume (admin only)
+func (h *DatasetHandler) GetDatasetStats(c *gin.Context) {
+	stats, err := h.datasetService.GetStats(c.Param("id"))
+	if err != nil {
+		h.writeError(c, "Failed to get dataset stats", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// writeError maps dataset errors to their status codes, logging unexpected ones
+func (h *DatasetHandler) writeError(c *gin.Context, message string, err error) {
+	if writeDatasetError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrDatasetAccessNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidDataset):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrDatasetExists):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+	default:
+		utils.LogError(message, err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, message)
+	}
+}
+
+// writeDatasetError responds to a request naming a dataset that does not exist or that the user may
+// not use, reporting whether it did
+func writeDatasetError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrDatasetNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, services.ErrDatasetAccessDenied):
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeDatasetAccessDenied, err.Error())
+	default:
+		return false
+	}
+	return true
+}
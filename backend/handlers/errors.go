@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/database"
+	"finone-search-system/middleware"
+	"finone-search-system/models"
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortWithError responds with an API error carrying the given status, code and message
+func abortWithError(c *gin.Context, status int, code, message string) {
+	abortWithAPIError(c, models.NewAPIError(status, code, message))
+}
+
+// abortWithAPIError responds with an API error, e.g. one carrying extra fields
+func abortWithAPIError(c *gin.Context, err *models.APIError) {
+	middleware.AbortWithError(c, err)
+}
+
+// writeQuotaError responds to daily search and export limit errors, reporting whether err was one
+func writeQuotaError(c *gin.Context, err error) bool {
+	if errors.Is(err, services.ErrSearchLimitExceeded) || errors.Is(err, services.ErrExportLimitExceeded) {
+		abortWithError(c, http.StatusTooManyRequests, models.ErrorCodeQuotaExceeded, err.Error())
+		return true
+	}
+	return false
+}
+
+// writeBudgetError responds 422 when a search was stopped by the query budget of the user's type,
+// reporting whether it did. Call it before writeUnavailableError, so a query stopped by its budget is
+// not reported as an outage.
+func writeBudgetError(c *gin.Context, err error) bool {
+	if !database.IsBudgetError(err) {
+		return false
+	}
+	abortWithError(c, http.StatusUnprocessableEntity, models.ErrorCodeSearchTooExpensive,
+		"Search exceeded the time or data limit of your account, please narrow it")
+	return true
+}
+
+// writeUnavailableError responds 503 with a Retry-After header when err means ClickHouse could not be
+// reached, reporting whether it did, so outages are not reported as server bugs
+func writeUnavailableError(c *gin.Context, err error) bool {
+	if !database.IsConnectionError(err) {
+		return false
+	}
+	middleware.SetRetryAfter(c, database.ClickHouseRetryAfter())
+	abortWithError(c, http.StatusServiceUnavailable, models.ErrorCodeServiceUnavailable, "Search is temporarily unavailable, please retry later")
+	return true
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FieldPolicyHandler struct {
+	fieldMaskingService *services.FieldMaskingService
+}
+
+func NewFieldPolicyHandler() *FieldPolicyHandler {
+	return &FieldPolicyHandler{
+		fieldMaskingService: services.NewFieldMaskingService(),
+	}
+}
+
+// GetPolicies handles listing field visibility policies (admin only)
+func (h *FieldPolicyHandler) GetPolicies(c *gin.Context) {
+	policies, err := h.fieldMaskingService.GetPolicies()
+	if err != nil {
+		utils.LogError("Failed to get field visibility policies", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve field visibility policies")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policies": policies,
+		"count":    len(policies),
+	})
+}
+
+// UpsertPolicy handles creating or replacing a field visibility policy (admin only)
+func (h *FieldPolicyHandler) UpsertPolicy(c *gin.Context) {
+	var req models.UpsertFieldVisibilityPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	policy, err := h.fieldMaskingService.UpsertPolicy(&req)
+	if err != nil {
+		utils.LogError("Failed to save field visibility policy", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	utils.LogInfo("Field visibility policy saved: " + policy.ID.String())
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy handles deleting a field visibility policy (admin only)
+func (h *FieldPolicyHandler) DeletePolicy(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid policy ID")
+		return
+	}
+
+	err = h.fieldMaskingService.DeletePolicy(id)
+	if err != nil {
+		utils.LogError("Failed to delete field visibility policy", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	utils.LogInfo("Field visibility policy deleted: " + idStr)
+	c.JSON(http.StatusOK, gin.H{"message": "Field visibility policy deleted successfully"})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ImportChangesHandler struct {
+	importChangesService *services.ImportChangesService
+}
+
+func NewImportChangesHandler() *ImportChangesHandler {
+	return &ImportChangesHandler{
+		importChangesService: services.NewImportChangesService(),
+	}
+}
+
+// stagedChangesRequest selects staged changes by the IDs of the rows they replace; none selects all
+type stagedChangesRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// GetImportChanges handles listing the changed rows a delta import staged for review (admin only)
+func (h *ImportChangesHandler) GetImportChanges(c *gin.Context) {
+	jobID, ok := importJobID(c)
+	if !ok {
+		return
+	}
+	params, ok := pageParams(c, 50, 500)
+	if !ok {
+		return
+	}
+
+	changes, err := h.importChangesService.GetChanges(jobID, params)
+	if err != nil {
+		utils.LogError("Failed to get staged changes", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve staged changes")
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// ApplyImportChanges handles writing staged changes over the rows they replace (admin only)
+func (h *ImportChangesHandler) ApplyImportChanges(c *gin.Context) {
+	jobID, req, ok := h.stagedChangesTarget(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.importChangesService.ApplyChanges(jobID, req.IDs)
+	if err != nil {
+		h.writeStagedChangesError(c, "Failed to apply staged changes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DiscardImportChanges handles dropping staged changes without applying them (admin only)
+func (h *ImportChangesHandler) DiscardImportChanges(c *gin.Context) {
+	jobID, req, ok := h.stagedChangesTarget(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.importChangesService.DiscardChanges(jobID, req.IDs)
+	if err != nil {
+		h.writeStagedChangesError(c, "Failed to discard staged changes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// stagedChangesTarget parses the import job ID and the optional body selecting staged changes
+func (h *ImportChangesHandler) stagedChangesTarget(c *gin.Context) (uuid.UUID, stagedChangesRequest, bool) {
+	var req stagedChangesRequest
+	jobID, ok := importJobID(c)
+	if !ok {
+		return jobID, req, false
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+			return jobID, req, false
+		}
+	}
+	return jobID, req, true
+}
+
+// writeStagedChangesError maps staged change errors to their status codes
+func (h *ImportChangesHandler) writeStagedChangesError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidStagedChange):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrNoStagedChanges):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	default:
+		utils.LogError(message, err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, message)
+	}
+}
+
+// importJobID parses the import job ID of the route, responding and returning false when it is invalid
+func importJobID(c *gin.Context) (uuid.UUID, bool) {
+	jobID, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid import job ID")
+		return uuid.Nil, false
+	}
+	return jobID, true
+}
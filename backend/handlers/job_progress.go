@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+// jobProgressPingInterval keeps idle progress connections open through proxies
+const jobProgressPingInterval = 30 * time.Second
+
+type JobProgressHandler struct {
+	authorizationService *services.AuthorizationService
+}
+
+func NewJobProgressHandler() *JobProgressHandler {
+	return &JobProgressHandler{
+		authorizationService: services.NewAuthorizationService(),
+	}
+}
+
+// StreamJobProgress handles a WebSocket that streams import and export progress events as JSON
+// messages. Users who may import receive every job; other users receive their own exports.
+func (h *JobProgressHandler) StreamJobProgress(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+	allJobs := h.authorizationService.HasPermission(c.GetString("role"), services.PermissionImport)
+
+	server := websocket.Server{
+		// The session token authenticates the connection, so any origin may open it
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			h.stream(conn, userID, allJobs)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// stream sends progress events until the client disconnects
+func (h *JobProgressHandler) stream(conn *websocket.Conn, userID uuid.UUID, allJobs bool) {
+	defer conn.Close()
+
+	subscription := services.SubscribeJobProgress(userID, allJobs)
+	defer subscription.Unsubscribe()
+
+	// Clients send nothing, but reading notices when they go away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var message []byte
+		for {
+			if err := websocket.Message.Receive(conn, &message); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(jobProgressPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case event := <-subscription.Events:
+			if err := websocket.JSON.Send(conn, event); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.PayloadType = websocket.PingFrame
+			_, err := conn.Write(nil)
+			conn.PayloadType = websocket.TextFrame
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobQueueHandler struct {
+	jobQueueService *services.JobQueueService
+}
+
+func NewJobQueueHandler() *JobQueueHandler {
+	return &JobQueueHandler{
+		jobQueueService: services.NewJobQueueService(),
+	}
+}
+
+// GetJobs handles listing background jobs, newest first, optionally by status and type (admin only)
+func (h *JobQueueHandler) GetJobs(c *gin.Context) {
+	params, ok := pageParams(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	response, err := h.jobQueueService.ListJobs(c.Query("status"), c.Query("type"), params)
+	if err != nil {
+		writeJobQueueError(c, "Failed to list jobs", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetJob handles getting one background job with its result or last error (admin only)
+func (h *JobQueueHandler) GetJob(c *gin.Context) {
+	job, err := h.jobQueueService.GetJob(c.Param("id"))
+	if err != nil {
+		writeJobQueueError(c, "Failed to get job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryJob handles queueing a dead job again (admin only)
+func (h *JobQueueHandler) RetryJob(c *gin.Context) {
+	job, err := h.jobQueueService.RetryJob(c.Param("id"))
+	if err != nil {
+		writeJobQueueError(c, "Failed to retry job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func writeJobQueueError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidJobStatus):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrJobNotDead):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+	default:
+		utils.LogError(message, err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, message)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type JWTKeyHandler struct {
+	jwtKeyService *services.JWTKeyService
+}
+
+func NewJWTKeyHandler() *JWTKeyHandler {
+	return &JWTKeyHandler{
+		jwtKeyService: services.NewJWTKeyService(),
+	}
+}
+
+// GetKeys handles listing the keys that sign and verify session tokens, without their secrets (admin only)
+func (h *JWTKeyHandler) GetKeys(c *gin.Context) {
+	keys, err := h.jwtKeyService.ListKeys()
+	if err != nil {
+		utils.LogError("Failed to list signing keys", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list signing keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RotateKey handles starting to sign session tokens with a new key (admin only)
+func (h *JWTKeyHandler) RotateKey(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	// The body is optional; an empty one rotates to a new HS256 key
+	var req models.RotateJWTKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+			return
+		}
+	}
+
+	key, err := h.jwtKeyService.RotateKey(&req, adminID)
+	if errors.Is(err, services.ErrInvalidJWTAlgorithm) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to rotate signing key", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to rotate signing key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// RetireKey handles rejecting every token signed with a rotated-out key (admin only)
+func (h *JWTKeyHandler) RetireKey(c *gin.Context) {
+	key, err := h.jwtKeyService.RetireKey(c.Param("kid"))
+	switch {
+	case errors.Is(err, services.ErrJWTKeyNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrJWTKeyActive), errors.Is(err, services.ErrConfigJWTKey):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+		return
+	case err != nil:
+		utils.LogError("Failed to retire signing key", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retire signing key")
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// GetJWKS handles publishing the public keys of RS256 signing keys
+func (h *JWTKeyHandler) GetJWKS(c *gin.Context) {
+	c.JSON(http.StatusOK, h.jwtKeyService.JWKS())
+}
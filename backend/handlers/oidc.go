@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie binds a login's state to the browser that started it, so a callback URL from a
+// login started elsewhere cannot sign this browser in to someone else's account
+const oidcStateCookie = "finone_sso_state"
+
+type OIDCHandler struct {
+	oidcService *services.OIDCService
+}
+
+func NewOIDCHandler() *OIDCHandler {
+	return &OIDCHandler{
+		oidcService: services.NewOIDCService(),
+	}
+}
+
+// Login handles starting single sign-on by redirecting the browser to the provider
+func (h *OIDCHandler) Login(c *gin.Context) {
+	authURL, state, err := h.oidcService.StartLogin(c.Request.Context(), c.Query("provider"))
+	switch {
+	case errors.Is(err, services.ErrOIDCNotConfigured), errors.Is(err, services.ErrOIDCProviderNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrOIDCUnavailable):
+		utils.LogError("Failed to start SSO login", err)
+		abortWithError(c, http.StatusBadGateway, models.ErrorCodeUpstream, services.ErrOIDCUnavailable.Error())
+		return
+	case err != nil:
+		utils.LogError("Failed to start SSO login", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to start single sign-on")
+		return
+	}
+
+	setOIDCStateCookie(c, state, int(config.Get().OIDC.StateExpiry.Seconds()))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles the browser returning from the provider: it signs the user in and hands the
+// session to oidc.post_login_redirect, or returns it like /auth/login when none is configured
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		// e.g. access_denied when the user cancels at the provider
+		utils.LogWarning("SSO login refused by the provider: " + providerError + " " + c.Query("error_description"))
+		ssoError(c, http.StatusUnauthorized, models.ErrorCodeInvalidCredentials, services.ErrOIDCFailed.Error()+": "+providerError)
+		return
+	}
+
+	// Only the browser that started the login may finish it
+	state := c.Query("state")
+	cookie, cookieErr := c.Cookie(oidcStateCookie)
+	setOIDCStateCookie(c, "", -1)
+	if cookieErr != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		utils.LogWarning("SSO callback refused: its state does not match the login started by this browser")
+		ssoError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, services.ErrOIDCStateInvalid.Error())
+		return
+	}
+
+	response, err := h.oidcService.FinishLogin(c.Request.Context(), state, c.Query("code"), loginClient(c))
+	switch {
+	case errors.Is(err, services.ErrOIDCStateInvalid):
+		ssoError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrOIDCFailed):
+		utils.LogError("SSO login failed", err)
+		ssoError(c, http.StatusUnauthorized, models.ErrorCodeInvalidCredentials, services.ErrOIDCFailed.Error())
+		return
+	case errors.Is(err, services.ErrAccountExpired):
+		ssoError(c, http.StatusUnauthorized, models.ErrorCodeAccountExpired, err.Error())
+		return
+	case errors.Is(err, services.ErrOIDCEmailNotVerified), errors.Is(err, services.ErrOIDCDomainNotAllowed),
+		errors.Is(err, services.ErrOIDCNoAccount), errors.Is(err, services.ErrAccountDisabled):
+		ssoError(c, http.StatusForbidden, models.ErrorCodeForbidden, err.Error())
+		return
+	case errors.Is(err, services.ErrOIDCUnavailable):
+		utils.LogError("SSO login failed", err)
+		ssoError(c, http.StatusBadGateway, models.ErrorCodeUpstream, services.ErrOIDCUnavailable.Error())
+		return
+	case err != nil:
+		utils.LogError("SSO login failed", err)
+		ssoError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to sign in")
+		return
+	}
+
+	if redirect := config.Get().OIDC.PostLoginRedirect; redirect != "" {
+		// In the fragment, the token is not sent to the frontend's server or kept in its logs
+		fragment := url.Values{
+			"token":      {response.Token},
+			"session_id": {response.SessionID},
+			"expires_at": {response.ExpiresAt.Format(time.RFC3339)},
+		}
+		c.Redirect(http.StatusFound, redirect+"#"+fragment.Encode())
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ssoError reports a failed single sign-on. The browser arrives from the provider rather than the
+// frontend, so with oidc.post_login_redirect the error is handed to that page instead.
+func ssoError(c *gin.Context, status int, code, message string) {
+	if redirect := config.Get().OIDC.PostLoginRedirect; redirect != "" {
+		c.Redirect(http.StatusFound, redirect+"#"+url.Values{"error": {message}, "code": {code}}.Encode())
+		c.Abort()
+		return
+	}
+	abortWithError(c, status, code, message)
+}
+
+// setOIDCStateCookie sets, or with a negative maxAge clears, the cookie holding a login's state. It is
+// only sent to the callback, is not readable by scripts and is sent on the provider's top-level redirect.
+func setOIDCStateCookie(c *gin.Context, state string, maxAge int) {
+	path := "/"
+	if callback, err := url.Parse(config.Get().OIDC.RedirectURL); err == nil && callback.Path != "" {
+		path = callback.Path
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path,
+		MaxAge:   maxAge,
+		Secure:   strings.HasPrefix(config.Get().OIDC.RedirectURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OrganizationHandler struct {
+	organizationService *services.OrganizationService
+}
+
+func NewOrganizationHandler() *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: services.NewOrganizationService(),
+	}
+}
+
+// GetOrganizations handles listing every organization (admin only)
+func (h *OrganizationHandler) GetOrganizations(c *gin.Context) {
+	organizations, err := h.organizationService.ListOrganizations()
+	if err != nil {
+		utils.LogError("Failed to list organizations", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list organizations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"organizations": organizations})
+}
+
+// CreateOrganization handles creating an organization (admin only)
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var req models.CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	organization, err := h.organizationService.CreateOrganization(&req, adminID)
+	if err != nil {
+		h.writeError(c, "Failed to create organization", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, organization)
+}
+
+// GetOrganization handles showing an organization with its members and usage today (admin only)
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+
+	details, err := h.organizationService.GetOrganizationDetails(id)
+	if err != nil {
+		h.writeError(c, "Failed to get organization", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// UpdateOrganization handles changing an organization's name, seats or shared limits (admin only)
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	organization, err := h.organizationService.UpdateOrganization(id, &req)
+	if err != nil {
+		h.writeError(c, "Failed to update organization", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, organization)
+}
+
+// DeleteOrganization handles deleting an organization; its members keep their accounts (admin only)
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.organizationService.DeleteOrganization(id); err != nil {
+		h.writeError(c, "Failed to delete organization", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted"})
+}
+
+// AddOrganizationMember handles adding an existing user to an organization or changing their role (admin only)
+func (h *OrganizationHandler) AddOrganizationMember(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	if err := h.organizationService.AddMember(id, userID, req.Role, adminID); err != nil {
+		h.writeError(c, "Failed to add organization member", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member added"})
+}
+
+// RemoveOrganizationMember handles taking a user out of an organization; their account is kept (admin only)
+func (h *OrganizationHandler) RemoveOrganizationMember(c *gin.Context) {
+	id, ok := organizationID(c)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(id, userID, false); err != nil {
+		h.writeError(c, "Failed to remove organization member", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// GetMyOrganization handles showing the organization the current user administers with its members
+func (h *OrganizationHandler) GetMyOrganization(c *gin.Context) {
+	id, _, ok := h.administeredOrganization(c)
+	if !ok {
+		return
+	}
+
+	details, err := h.organizationService.GetOrganizationDetails(id)
+	if err != nil {
+		h.writeError(c, "Failed to get organization", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, details)
+}
+
+// GetMyOrganizationMembers handles listing the members of the organization the current user administers
+func (h *OrganizationHandler) GetMyOrganizationMembers(c *gin.Context) {
+	id, _, ok := h.administeredOrganization(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.organizationService.ListMembers(id)
+	if err != nil {
+		h.writeError(c, "Failed to list organization members", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// CreateMyOrganizationMember handles creating an account in the organization the current user administers
+func (h *OrganizationHandler) CreateMyOrganizationMember(c *gin.Context) {
+	id, actorID, ok := h.administeredOrganization(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	member, err := h.organizationService.CreateMember(id, &req, actorID)
+	if err != nil {
+		h.writeError(c, "Failed to create organization member", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// UpdateMyOrganizationMember handles changing a member of the organization the current user administers.
+// Organization admins cannot change their own role or limits, or deactivate themselves.
+func (h *OrganizationHandler) UpdateMyOrganizationMember(c *gin.Context) {
+	id, actorID, ok := h.administeredOrganization(c)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.UpdateOrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+	if userID == actorID && (req.Role != nil || (req.IsActive != nil && !*req.IsActive) ||
+		req.MaxSearchesPerDay != nil || req.MaxExportsPerDay != nil) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "You cannot change your own role or limits, or deactivate yourself")
+		return
+	}
+
+	if err := h.organizationService.UpdateMember(id, userID, actorID, &req); err != nil {
+		h.writeError(c, "Failed to update organization member", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member updated"})
+}
+
+// RemoveMyOrganizationMember handles removing a member from the organization the current user administers.
+// The member's account is deactivated and their seat is freed.
+func (h *OrganizationHandler) RemoveMyOrganizationMember(c *gin.Context) {
+	id, actorID, ok := h.administeredOrganization(c)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+	if userID == actorID {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "You cannot remove yourself")
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(id, userID, true); err != nil {
+		h.writeError(c, "Failed to remove organization member", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed and deactivated"})
+}
+
+// administeredOrganization returns the organization the current user is an organization admin of and
+// the user's ID, responding with 403 when they administer none
+func (h *OrganizationHandler) administeredOrganization(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := h.organizationService.AdministeredOrganization(userID)
+	if err != nil {
+		h.writeError(c, "Failed to get organization", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, userID, true
+}
+
+// writeError responds to an organization error
+func (h *OrganizationHandler) writeError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrOrganizationNotFound), errors.Is(err, services.ErrNotOrganizationMember),
+		errors.Is(err, services.ErrUserNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, services.ErrNotOrganizationAdmin):
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeForbidden, err.Error())
+	case errors.Is(err, services.ErrOrganizationExists), errors.Is(err, services.ErrOrganizationFull),
+		errors.Is(err, services.ErrInOtherOrganization), errors.Is(err, services.ErrMemberEmailExists):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidOrganization):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+	default:
+		utils.LogError(message, err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, message)
+	}
+}
+
+// organizationID parses the organization ID route parameter, responding with 400 when it is invalid
+func organizationID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid organization ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"finone-search-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageParams parses the page and limit query parameters of a list endpoint, or the cursor of a
+// previous page. A missing or invalid page is the first; a limit out of 1..maxLimit is defaultLimit.
+// It writes the error response itself for a bad cursor.
+func pageParams(c *gin.Context, defaultLimit, maxLimit int) (models.PageParams, bool) {
+	if cursor := c.Query("cursor"); cursor != "" {
+		params, err := models.ParseCursor(cursor)
+		if err != nil || params.Limit > maxLimit {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid cursor")
+			return models.PageParams{}, false
+		}
+		return params, true
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 || limit > maxLimit {
+		limit = defaultLimit
+	}
+
+	return models.PageParams{Page: page, Limit: limit}, true
+}
@@ -1,8 +1,8 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 
 	"finone-search-system/models"
 	"finone-search-system/services"
@@ -26,26 +26,26 @@ func NewPasswordChangeHandler() *PasswordChangeHandler {
 func (h *PasswordChangeHandler) CreatePasswordChangeRequest(c *gin.Context) {
 	var req models.CreatePasswordChangeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	// Validate required fields
 	if req.Reason == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Reason is required")
 		return
 	}
 
 	// Get user from context
 	userInterface, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User not found in context")
 		return
 	}
 
 	user, ok := userInterface.(*models.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type in context"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Invalid user type in context")
 		return
 	}
 
@@ -58,7 +58,7 @@ func (h *PasswordChangeHandler) CreatePasswordChangeRequest(c *gin.Context) {
 	)
 	if err != nil {
 		utils.LogError("Failed to create password change request", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
 
@@ -68,23 +68,17 @@ func (h *PasswordChangeHandler) CreatePasswordChangeRequest(c *gin.Context) {
 
 // GetPasswordChangeRequests gets paginated list of password change requests (admin only)
 func (h *PasswordChangeHandler) GetPasswordChangeRequests(c *gin.Context) {
-	// Parse query parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	status := c.Query("status")
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	params, ok := pageParams(c, 10, 100)
+	if !ok {
+		return
 	}
+	status := c.Query("status")
 
 	// Get password change requests
-	response, err := h.passwordChangeService.GetPasswordChangeRequests(page, limit, status)
+	response, err := h.passwordChangeService.GetPasswordChangeRequests(params, status)
 	if err != nil {
 		utils.LogError("Failed to get password change requests", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get password change requests"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get password change requests")
 		return
 	}
 
@@ -96,14 +90,14 @@ func (h *PasswordChangeHandler) GetPasswordChangeRequest(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password change request ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid password change request ID")
 		return
 	}
 
 	passwordChangeRequest, err := h.passwordChangeService.GetPasswordChangeRequest(id)
 	if err != nil {
 		utils.LogError("Failed to get password change request", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Password change request not found"})
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Password change request not found")
 		return
 	}
 
@@ -115,40 +109,55 @@ func (h *PasswordChangeHandler) UpdatePasswordChangeRequest(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password change request ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid password change request ID")
 		return
 	}
 
 	var req models.UpdatePasswordChangeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
-	// Validate status
+	// Validate status; COMPLETED is the name approval had before it was renamed to APPROVED
+	if req.Status == "COMPLETED" {
+		req.Status = "APPROVED"
+	}
 	if req.Status != "APPROVED" && req.Status != "REJECTED" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be either 'APPROVED' or 'REJECTED'"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Status must be either 'APPROVED' or 'REJECTED'")
 		return
 	}
 
 	// Get admin user from context
 	userInterface, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User not found in context")
 		return
 	}
 
 	user, ok := userInterface.(*models.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type in context"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Invalid user type in context")
 		return
 	}
 
 	// Update password change request
 	updatedRequest, err := h.passwordChangeService.UpdatePasswordChangeRequest(id, req, user.ID)
+	if errors.Is(err, services.ErrInvalidPasswordChange) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrPasswordChangeNotFound) || errors.Is(err, services.ErrUserNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrPasswordChangeProcessed) {
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+		return
+	}
 	if err != nil {
 		utils.LogError("Failed to update password change request", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
 
@@ -158,35 +167,29 @@ func (h *PasswordChangeHandler) UpdatePasswordChangeRequest(c *gin.Context) {
 
 // GetUserPasswordChangeRequests gets password change requests for the authenticated user
 func (h *PasswordChangeHandler) GetUserPasswordChangeRequests(c *gin.Context) {
-	// Parse query parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	params, ok := pageParams(c, 10, 100)
+	if !ok {
+		return
 	}
 
 	// Get user from context
 	userInterface, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User not found in context")
 		return
 	}
 
 	user, ok := userInterface.(*models.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type in context"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Invalid user type in context")
 		return
 	}
 
 	// Get user's password change requests
-	response, err := h.passwordChangeService.GetUserPasswordChangeRequests(user.ID, page, limit)
+	response, err := h.passwordChangeService.GetUserPasswordChangeRequests(user.ID, params)
 	if err != nil {
 		utils.LogError("Failed to get user password change requests", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get password change requests"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get password change requests")
 		return
 	}
 
@@ -198,27 +201,27 @@ func (h *PasswordChangeHandler) DeletePasswordChangeRequest(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid password change request ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid password change request ID")
 		return
 	}
 
 	// Get admin user from context
 	userInterface, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User not found in context")
 		return
 	}
 
 	user, ok := userInterface.(*models.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type in context"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Invalid user type in context")
 		return
 	}
 
 	err = h.passwordChangeService.DeletePasswordChangeRequest(id)
 	if err != nil {
 		utils.LogError("Failed to delete password change request", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
 
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PeopleRecordHandler struct {
+	peopleRecordService *services.PeopleRecordService
+}
+
+func NewPeopleRecordHandler() *PeopleRecordHandler {
+	return &PeopleRecordHandler{
+		peopleRecordService: services.NewPeopleRecordService(),
+	}
+}
+
+// CreatePerson handles adding a single person row (admin only)
+func (h *PeopleRecordHandler) CreatePerson(c *gin.Context) {
+	var req models.CreatePersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithAPIError(c, models.NewAPIError(http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format").
+			With("details", err.Error()))
+		return
+	}
+
+	person, err := h.peopleRecordService.Create(&req, editorID(c))
+	if err != nil {
+		utils.LogError("Failed to create person", err)
+		abortWithAPIError(c, models.NewAPIError(http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create person").
+			With("details", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusCreated, person)
+}
+
+// UpdatePerson handles correcting a single person row (admin only)
+func (h *PeopleRecordHandler) UpdatePerson(c *gin.Context) {
+	var req models.UpdatePersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	person, err := h.peopleRecordService.Update(c.Param("id"), &req, editorID(c))
+	if errors.Is(err, services.ErrPersonNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Person not found")
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to update person", err)
+		abortWithAPIError(c, models.NewAPIError(http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update person").
+			With("details", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
+
+// DeletePerson handles removing a single person row (admin only)
+func (h *PeopleRecordHandler) DeletePerson(c *gin.Context) {
+	var req models.DeletePersonRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+			return
+		}
+	}
+
+	err := h.peopleRecordService.Delete(c.Param("id"), req.Reason, editorID(c))
+	if errors.Is(err, services.ErrPersonNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Person not found")
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to delete person", err)
+		abortWithAPIError(c, models.NewAPIError(http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete person").
+			With("details", err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Person deleted successfully"})
+}
+
+// GetPersonEdits handles listing the manual edits of a person row (admin only)
+func (h *PeopleRecordHandler) GetPersonEdits(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	edits, err := h.peopleRecordService.GetEdits(c.Param("id"), limit)
+	if err != nil {
+		utils.LogError("Failed to get person edits", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get person edits")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"edits": edits})
+}
+
+// editorID returns the ID of the admin making a change, if known
+func editorID(c *gin.Context) *uuid.UUID {
+	id, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		return nil
+	}
+	return &id
+}
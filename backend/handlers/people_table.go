@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PeopleTableHandler struct {
+	peopleTableService *services.PeopleTableService
+}
+
+func NewPeopleTableHandler() *PeopleTableHandler {
+	return &PeopleTableHandler{
+		peopleTableService: services.NewPeopleTableService(),
+	}
+}
+
+// GetPeopleTables handles listing the people table versions and the active table (admin only)
+func (h *PeopleTableHandler) GetPeopleTables(c *gin.Context) {
+	status, err := h.peopleTableService.GetStatus()
+	if err != nil {
+		utils.LogError("Failed to get people tables", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve people tables")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// CreatePeopleTable handles creating an empty people table version to import into (admin only)
+func (h *PeopleTableHandler) CreatePeopleTable(c *gin.Context) {
+	var req models.CreatePeopleTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	table, err := h.peopleTableService.CreateTable(req.Name)
+	if err != nil {
+		utils.LogError("Failed to create people table", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, table)
+}
+
+// SwitchPeopleTable handles cutting searches, exports and imports over to another people table (admin only)
+func (h *PeopleTableHandler) SwitchPeopleTable(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.SwitchPeopleTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	switchover, err := h.peopleTableService.Switch(&req, userID)
+	if err != nil {
+		utils.LogError("Failed to switch people table", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "People table switched",
+		"switchover": switchover,
+	})
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PermissionHandler struct {
+	authorizationService *services.AuthorizationService
+}
+
+func NewPermissionHandler() *PermissionHandler {
+	return &PermissionHandler{
+		authorizationService: services.NewAuthorizationService(),
+	}
+}
+
+// GetPermissionMatrix handles documenting effective route access per role (admin only)
+func (h *PermissionHandler) GetPermissionMatrix(c *gin.Context) {
+	c.JSON(http.StatusOK, h.authorizationService.GetPermissionMatrix())
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PincodeHandler struct {
+	pincodeDirectoryService *services.PincodeDirectoryService
+}
+
+func NewPincodeHandler() *PincodeHandler {
+	return &PincodeHandler{
+		pincodeDirectoryService: services.NewPincodeDirectoryService(),
+	}
+}
+
+// ImportPincodes handles replacing the pincode directory used to derive locality, city and state (admin only)
+func (h *PincodeHandler) ImportPincodes(c *gin.Context) {
+	file, header, err := c.Request.FormFile("csv_file")
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "No file provided")
+		return
+	}
+	defer file.Close()
+
+	utils.LogInfo("Pincode directory import started: " + header.Filename)
+
+	response, err := h.pincodeDirectoryService.ImportCSV(file)
+	if err != nil {
+		utils.LogError("Failed to import pincode directory", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Rows already in the people table keep their old address components unless recomputed
+	if c.DefaultPostForm("rematerialize", "false") == "true" {
+		if err := h.pincodeDirectoryService.RematerializePeople(); err != nil {
+			utils.LogError("Failed to rematerialize address components", err)
+			abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Pincode directory imported but rematerializing people failed")
+			return
+		}
+		response.Rematerializing = true
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetPincodeDirectory handles reporting the size of the pincode directory (admin only)
+func (h *PincodeHandler) GetPincodeDirectory(c *gin.Context) {
+	status, err := h.pincodeDirectoryService.GetStatus()
+	if err != nil {
+		utils.LogError("Failed to get pincode directory", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve pincode directory")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"finone-search-system/models"
 	"finone-search-system/services"
@@ -12,6 +14,10 @@ import (
 	"github.com/google/uuid"
 )
 
+// registrationRetryAfter is suggested to clients whose registration was throttled; the per-IP limit
+// is counted over an hour
+const registrationRetryAfter = time.Hour
+
 type RegistrationHandler struct {
 	registrationService *services.RegistrationService
 }
@@ -26,61 +32,91 @@ func NewRegistrationHandler() *RegistrationHandler {
 func (h *RegistrationHandler) CreateRegistrationRequest(c *gin.Context) {
 	var req models.CreateRegistrationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	// Validate request
 	if req.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Name is required")
 		return
 	}
 	if req.Email == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email is required"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Email is required")
 		return
 	}
 	if req.PhoneNumber == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone number is required"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Phone number is required")
 		return
 	}
 	if req.RequestedSearches <= 0 || req.RequestedSearches > 10000 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Requested searches must be between 1 and 10000"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Requested searches must be between 1 and 10000")
 		return
 	}
 
-	registrationRequest, err := h.registrationService.CreateRegistrationRequest(req)
+	registrationRequest, err := h.registrationService.CreateRegistrationRequest(req, c.ClientIP())
 	if err != nil {
-		utils.LogError("Failed to create registration request", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, services.ErrCaptchaRequired), errors.Is(err, services.ErrCaptchaFailed):
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeCaptchaFailed, err.Error())
+		case errors.Is(err, services.ErrCaptchaUnavailable):
+			utils.LogError("Failed to verify registration CAPTCHA", err)
+			abortWithError(c, http.StatusBadGateway, models.ErrorCodeUpstream, "CAPTCHA verification is unavailable, please try again later")
+		case errors.Is(err, services.ErrRegistrationThrottled):
+			c.Header("Retry-After", strconv.Itoa(int(registrationRetryAfter.Seconds())))
+			abortWithError(c, http.StatusTooManyRequests, models.ErrorCodeRateLimited, err.Error())
+		case errors.Is(err, services.ErrRegistrationExists):
+			abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+		default:
+			utils.LogError("Failed to create registration request", err)
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		}
 		return
 	}
 
 	utils.LogInfo("Registration request created: " + req.Email)
+	message := "Registration request submitted successfully. You will be contacted by our admin team."
+	if registrationRequest.Status == "UNVERIFIED" {
+		message = "Registration request submitted. Please check your email and follow the link to verify your address."
+	}
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Registration request submitted successfully. You will be contacted by our admin team.",
+		"message": message,
 		"request": registrationRequest,
 	})
 }
 
-// GetRegistrationRequests handles getting paginated list of registration requests (admin only)
-func (h *RegistrationHandler) GetRegistrationRequests(c *gin.Context) {
-	// Parse pagination parameters
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		page = 1
+// VerifyRegistrationEmail handles the email verification link sent for a registration request (public endpoint)
+func (h *RegistrationHandler) VerifyRegistrationEmail(c *gin.Context) {
+	request, err := h.registrationService.VerifyRegistrationEmail(c.Query("token"))
+	if errors.Is(err, services.ErrInvalidVerificationToken) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to verify registration email", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to verify registration email")
+		return
 	}
 
-	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 20
+	utils.LogInfo("Registration request verified: " + request.Email)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified. Your registration request is now with our admin team.",
+	})
+}
+
+// GetRegistrationRequests handles getting paginated list of registration requests (admin only)
+func (h *RegistrationHandler) GetRegistrationRequests(c *gin.Context) {
+	params, ok := pageParams(c, 20, 100)
+	if !ok {
+		return
 	}
 
 	status := c.Query("status") // Optional filter by status
 
-	response, err := h.registrationService.GetRegistrationRequests(page, limit, status)
+	response, err := h.registrationService.GetRegistrationRequests(params, status)
 	if err != nil {
 		utils.LogError("Failed to get registration requests", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get registration requests"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get registration requests")
 		return
 	}
 
@@ -92,14 +128,14 @@ func (h *RegistrationHandler) GetRegistrationRequest(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request ID")
 		return
 	}
 
 	request, err := h.registrationService.GetRegistrationRequest(id)
 	if err != nil {
 		utils.LogError("Failed to get registration request", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Registration request not found"})
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Registration request not found")
 		return
 	}
 
@@ -111,39 +147,39 @@ func (h *RegistrationHandler) UpdateRegistrationRequest(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request ID")
 		return
 	}
 
 	var req models.UpdateRegistrationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	// Validate status
 	if req.Status != "APPROVED" && req.Status != "REJECTED" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be APPROVED or REJECTED"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Status must be APPROVED or REJECTED")
 		return
 	}
 
 	// Get admin user ID from context
 	adminUserInterface, exists := c.Get("user")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User not found in context")
 		return
 	}
 
 	adminUser, ok := adminUserInterface.(*models.User)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type in context"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Invalid user type in context")
 		return
 	}
 
 	updatedRequest, err := h.registrationService.UpdateRegistrationRequest(id, req, adminUser.ID)
 	if err != nil {
 		utils.LogError("Failed to update registration request", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -166,14 +202,14 @@ func (h *RegistrationHandler) DeleteRegistrationRequest(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request ID")
 		return
 	}
 
 	err = h.registrationService.DeleteRegistrationRequest(id)
 	if err != nil {
 		utils.LogError("Failed to delete registration request", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RetentionHandler struct {
+	retentionService *services.RetentionService
+}
+
+func NewRetentionHandler() *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: services.NewRetentionService(),
+	}
+}
+
+// GetRetentionStatus handles listing the retention policies, their tables and recent runs (admin only)
+func (h *RetentionHandler) GetRetentionStatus(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	status, err := h.retentionService.GetStatus(limit)
+	if err != nil {
+		utils.LogError("Failed to get retention status", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get retention status")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// PurgeRetention handles running the retention policies now instead of waiting for the nightly run (admin only)
+func (h *RetentionHandler) PurgeRetention(c *gin.Context) {
+	var req models.RetentionPurgeRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+			return
+		}
+	}
+
+	var triggeredBy *uuid.UUID
+	if adminID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		triggeredBy = &adminID
+	}
+
+	purges, err := h.retentionService.Purge(req.Table, triggeredBy)
+	if err != nil {
+		h.writeRunError(c, "Retention purge failed", err, purges)
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Retention purge triggered by admin %s", c.GetString("user_id")))
+	c.JSON(http.StatusOK, models.RetentionPurgeResponse{Purges: purges})
+}
+
+// AnonymizeSearches handles anonymizing old search logs now instead of waiting for the nightly run (admin only)
+func (h *RetentionHandler) AnonymizeSearches(c *gin.Context) {
+	var triggeredBy *uuid.UUID
+	if adminID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		triggeredBy = &adminID
+	}
+
+	runs, err := h.retentionService.AnonymizeSearches(triggeredBy)
+	if err != nil {
+		h.writeRunError(c, "Search anonymization failed", err, runs)
+		return
+	}
+	if len(runs) == 0 {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Search anonymization is disabled: no enabled anonymize policy has an age")
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Search anonymization triggered by admin %s", c.GetString("user_id")))
+	c.JSON(http.StatusOK, models.RetentionPurgeResponse{Purges: runs})
+}
+
+// RunRetentionPolicy handles running one retention policy now (admin only)
+func (h *RetentionHandler) RunRetentionPolicy(c *gin.Context) {
+	var triggeredBy *uuid.UUID
+	if adminID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		triggeredBy = &adminID
+	}
+
+	run, err := h.retentionService.RunPolicy(c.Param("name"), triggeredBy)
+	if err != nil {
+		var runs []models.RetentionPurge
+		if run != nil {
+			runs = append(runs, *run)
+		}
+		h.writeRunError(c, "Retention policy run failed", err, runs)
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Retention policy %s triggered by admin %s", c.Param("name"), c.GetString("user_id")))
+	if run == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Nothing to run: the policy keeps rows forever or only sets a ClickHouse TTL"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// SaveRetentionPolicy handles creating a retention policy or overriding one by name (admin only)
+func (h *RetentionHandler) SaveRetentionPolicy(c *gin.Context) {
+	var req models.RetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	var updatedBy *uuid.UUID
+	if adminID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		updatedBy = &adminID
+	}
+
+	policy, err := h.retentionService.SavePolicy(c.Param("name"), &req, updatedBy)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRetentionPolicy) {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		utils.LogError("Failed to save retention policy", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to save retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteRetentionPolicy handles removing a stored retention policy, restoring any built-in or config
+// policy of the same name (admin only)
+func (h *RetentionHandler) DeleteRetentionPolicy(c *gin.Context) {
+	if err := h.retentionService.DeletePolicy(c.Param("name")); err != nil {
+		if errors.Is(err, services.ErrRetentionPolicyNotFound) {
+			abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "No stored retention policy with this name")
+			return
+		}
+		utils.LogError("Failed to delete retention policy", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Retention policy deleted"})
+}
+
+// writeRunError maps retention run errors to their status codes, returning the runs that completed
+// before an unexpected failure
+func (h *RetentionHandler) writeRunError(c *gin.Context, message string, err error, runs []models.RetentionPurge) {
+	switch {
+	case errors.Is(err, services.ErrUnknownRetentionTable):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrRetentionPolicyNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, services.ErrPurgeRunning), errors.Is(err, services.ErrRetentionPolicyDisabled):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+	default:
+		utils.LogError(message, err)
+		abortWithAPIError(c, models.NewAPIError(http.StatusInternalServerError, models.ErrorCodeInternal, message).With("purges", runs))
+	}
+}
@@ -1,25 +1,59 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"finone-search-system/config"
+	"finone-search-system/database"
 	"finone-search-system/models"
 	"finone-search-system/services"
 	"finone-search-system/utils"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type SearchHandler struct {
-	searchService *services.SearchService
+	authService           *services.AuthService
+	authorizationService  *services.AuthorizationService
+	searchService         *services.SearchService
+	exportService         *services.ExportService
+	importAuditService    *services.ImportAuditService
+	peopleTableService    *services.PeopleTableService
+	webhookService        *services.WebhookService
+	datasetService        *services.DatasetService
+	remoteImportService   *services.RemoteImportService
+	chunkedUploadService  *services.ChunkedUploadService
+	searchTemplateService *services.SearchTemplateService
+	jobQueueService       *services.JobQueueService
+	circleAliasService    *services.CircleAliasService
 }
 
 func NewSearchHandler() *SearchHandler {
 	return &SearchHandler{
-		searchService: services.NewSearchService(),
+		authService:           services.NewAuthService(),
+		authorizationService:  services.NewAuthorizationService(),
+		searchService:         services.NewSearchService(),
+		exportService:         services.NewExportService(),
+		importAuditService:    services.NewImportAuditService(),
+		peopleTableService:    services.NewPeopleTableService(),
+		webhookService:        services.NewWebhookService(),
+		datasetService:        services.NewDatasetService(),
+		remoteImportService:   services.NewRemoteImportService(),
+		chunkedUploadService:  services.NewChunkedUploadService(),
+		searchTemplateService: services.NewSearchTemplateService(),
+		jobQueueService:       services.NewJobQueueService(),
+		circleAliasService:    services.NewCircleAliasService(),
 	}
 }
 
@@ -27,219 +61,1537 @@ func NewSearchHandler() *SearchHandler {
 func (h *SearchHandler) Search(c *gin.Context) {
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	var req models.SearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
+	req.TemplateID = "" // Only searches run through a template have one
 
 	// Debug logging to see what we received
 	utils.LogInfo(fmt.Sprintf("Raw request received - Query: %s, Fields: %v, FieldQueries: %v, Logic: %s",
 		req.Query, req.Fields, req.FieldQueries, req.Logic))
 
+	h.runSearch(c, userID, &req)
+}
+
+// RunSearchTemplate handles searching through a search template with just the values of its fields
+func (h *SearchHandler) RunSearchTemplate(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var run models.RunSearchTemplateRequest
+	if err := c.ShouldBindJSON(&run); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	req, err := h.searchTemplateService.BuildRequest(c.Param("id"), &run)
+	if err != nil {
+		writeSearchTemplateError(c, "Search failed", err)
+		return
+	}
+
+	h.runSearch(c, userID, req)
+}
+
+// runSearch validates and runs a search request and writes its results
+func (h *SearchHandler) runSearch(c *gin.Context, userID uuid.UUID, req *models.SearchRequest) {
+	req.ClientID = c.GetString("client_id")
+	req.RequestID = c.GetString("request_id")
+	req.Context = c.Request.Context()
+
+	shape, err := responseShape(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if req.Columns, err = models.NormalizePersonColumns(req.Columns); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Set defaults
+	h.searchService.ApplyDefaults(req)
+	if !utils.IsValidQualityFilter(req.Quality) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid quality filter: "+req.Quality)
+		return
+	}
+	if _, err := h.searchService.PrepareNearby(req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err := h.searchService.ValidateMode(req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if req.Debug && !h.authorizationService.HasPermission(c.GetString("role"), services.PermissionSearchDebug) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeForbidden, "Search debug traces are restricted to administrators")
+		return
+	}
+	req.ImpersonatedBy = c.GetString("impersonated_by")
+	if !h.allowDiagnostic(c, &req.Diagnostic) {
+		return
+	}
+
+	// Debug logging
+	utils.LogInfo(fmt.Sprintf("Search request - Query: %s, Logic: %s, Fields: %v, Limit: %d",
+		req.Query, req.Logic, req.Fields, req.Limit))
+
+	response, err := h.searchService.Search(userID, req)
+	quota := h.setSearchQuotaHeaders(c, userID)
+	if errors.Is(err, services.ErrInvalidSearchMode) || errors.Is(err, services.ErrInvalidEnhancedSearch) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrSearchFieldNotAllowed) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeFieldNotAllowed, err.Error())
+		return
+	}
+	if writeQuotaError(c, err) {
+		return
+	}
+	if writeDatasetError(c, err) || writeBudgetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Search failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Search failed")
+		return
+	}
+	models.ShapePeople(response.Results, shape.WithColumns(response.Columns))
+	c.Header(services.SearchIDHeader, response.SearchID)
+	response.Quota = searchQuota(response.Quota, quota)
+
+	// Add message if no results found
+	if response.TotalCount == 0 {
+		utils.LogInfo("Search completed successfully - No results found")
+		// Create response with no results message
+		responseWithMessage := gin.H{
+			"results":           response.Results,
+			"total_count":       response.TotalCount,
+			"execution_time_ms": response.ExecutionTime,
+			"search_id":         response.SearchID,
+			"quota":             response.Quota,
+			"has_more":          response.HasMore,
+			"mode":              response.Mode,
+			"message":           "No results found for your search criteria",
+		}
+		if len(response.Columns) > 0 {
+			responseWithMessage["columns"] = response.Columns
+		}
+		c.JSON(http.StatusOK, responseWithMessage)
+		return
+	}
+
+	utils.LogInfo("Search completed successfully")
+	c.JSON(http.StatusOK, response)
+}
+
+// GetSearchResults handles fetching another page of a logged search by re-running its stored query,
+// so clients can paginate without sending the search again. Admins may replay any user's search.
+func (h *SearchHandler) GetSearchResults(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+	searchID, err := uuid.Parse(c.Param("search_id"))
+	if err != nil {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, services.ErrSearchNotFound.Error())
+		return
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "offset must be a non-negative integer")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "limit must be a non-negative integer")
+		return
+	}
+
+	shape, err := responseShape(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	canViewAll := h.authorizationService.HasPermission(c.GetString("role"), services.PermissionAudit)
+	response, err := h.searchService.ReplaySearch(userID, canViewAll, searchID, offset, limit)
+	quota := h.setSearchQuotaHeaders(c, userID)
+	switch {
+	case errors.Is(err, services.ErrSearchNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrSearchAnonymized):
+		abortWithError(c, http.StatusGone, models.ErrorCodeSearchAnonymized, err.Error())
+		return
+	case errors.Is(err, services.ErrSearchNotReplayable):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if writeQuotaError(c, err) {
+		return
+	}
+	if writeDatasetError(c, err) || writeBudgetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Search replay failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Search failed")
+		return
+	}
+
+	models.ShapePeople(response.Results, shape.WithColumns(response.Columns))
+	c.Header(services.SearchIDHeader, response.SearchID)
+	response.Quota = searchQuota(response.Quota, quota)
+	c.JSON(http.StatusOK, response)
+}
+
+// allowDiagnostic rejects diagnostic searches from users who may not run them, reporting whether the
+// request can go ahead. Searches by an admin impersonating a user are always diagnostic, so they do
+// not use up the user's quota.
+func (h *SearchHandler) allowDiagnostic(c *gin.Context, diagnostic *bool) bool {
+	if c.GetString("impersonated_by") != "" {
+		*diagnostic = true
+		return true
+	}
+	if *diagnostic && !h.authorizationService.HasPermission(c.GetString("role"), services.PermissionSearchDiagnostic) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeForbidden, "Diagnostic searches are restricted to administrators")
+		return false
+	}
+	return true
+}
+
+// setSearchQuotaHeaders reports the user's remaining searches for today in the response headers,
+// so clients can warn before they hit the daily limit. Returns the usage, or nil when it is unavailable.
+func (h *SearchHandler) setSearchQuotaHeaders(c *gin.Context, userID uuid.UUID) *models.QuotaUsage {
+	quota, err := h.authService.GetQuotaUsage(userID)
+	if err != nil {
+		utils.LogError("Failed to get quota usage for response headers", err)
+		return nil
+	}
+
+	c.Header("X-Search-Quota-Limit", strconv.Itoa(quota.Searches.Limit))
+	c.Header("X-Search-Quota-Remaining", strconv.Itoa(quota.Searches.Remaining))
+	return quota
+}
+
+// searchQuota completes the quota a search reports with the user's usage after it, leaving it out of
+// the response when the usage is unavailable
+func searchQuota(quota *models.SearchQuota, usage *models.QuotaUsage) *models.SearchQuota {
+	if quota == nil || usage == nil {
+		return nil
+	}
+	quota.SetUsage(usage)
+	return quota
+}
+
+// responseShape returns how person rows are serialized: the configured defaults, overridden by the
+// omit_empty, timestamps and casing query parameters
+func responseShape(c *gin.Context) (*models.ResponseShape, error) {
+	shape := &models.ResponseShape{Timestamps: models.TimestampsRFC3339, Casing: models.CasingSnake}
+	if config.Get() != nil {
+		shape.OmitEmpty = config.Get().Response.OmitEmpty
+		shape.Timestamps = config.Get().Response.Timestamps
+		shape.Casing = config.Get().Response.Casing
+	}
+
+	if value := c.Query("omit_empty"); value != "" {
+		omitEmpty, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("omit_empty must be true or false")
+		}
+		shape.OmitEmpty = omitEmpty
+	}
+	shape.Timestamps = c.DefaultQuery("timestamps", shape.Timestamps)
+	if shape.Timestamps != models.TimestampsRFC3339 && shape.Timestamps != models.TimestampsUnix {
+		return nil, fmt.Errorf("timestamps must be rfc3339 or unix")
+	}
+	shape.Casing = c.DefaultQuery("casing", shape.Casing)
+	if shape.Casing != models.CasingSnake && shape.Casing != models.CasingCamel {
+		return nil, fmt.Errorf("casing must be snake or camel")
+	}
+	return shape, nil
+}
+
+// GetPerson handles retrieving a specific person by ID
+func (h *SearchHandler) GetPerson(c *gin.Context) {
+	personID := c.Param("id")
+	if personID == "" {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Person ID is required")
+		return
+	}
+
+	shape, err := responseShape(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	dataset, err := h.datasetService.ResolveDataset(userID, c.Query("dataset_id"))
+	if writeDatasetError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to resolve dataset", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to resolve dataset")
+		return
+	}
+	table := ""
+	if dataset != nil {
+		table = database.QualifiedTable(dataset.TableName)
+	}
+
+	person, err := h.searchService.GetPersonByID(personID, table)
+	if writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to get person", err)
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Person not found")
+		return
+	}
+
+	// Apply the caller's field visibility policy
+	people := []models.Person{*person}
+	h.searchService.MaskResults(userID, people)
+	models.ShapePeople(people, shape)
+
+	c.JSON(http.StatusOK, people[0])
+}
+
+// GetPersonGraph handles returning the people linked to a person by shared attributes, for a link chart
+func (h *SearchHandler) GetPersonGraph(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	depth, err := strconv.Atoi(c.DefaultQuery("depth", strconv.Itoa(services.DefaultPersonGraphDepth)))
+	if err != nil || depth < 1 || depth > services.MaxPersonGraphDepth {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest,
+			fmt.Sprintf("depth must be between 1 and %d", services.MaxPersonGraphDepth))
+		return
+	}
+	maxNodes, err := strconv.Atoi(c.DefaultQuery("max_nodes", strconv.Itoa(services.DefaultPersonGraphMaxNodes)))
+	if err != nil || maxNodes < 2 || maxNodes > services.MaxPersonGraphMaxNodes {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest,
+			fmt.Sprintf("max_nodes must be between 2 and %d", services.MaxPersonGraphMaxNodes))
+		return
+	}
+
+	shape, err := responseShape(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	dataset, err := h.datasetService.ResolveDataset(userID, c.Query("dataset_id"))
+	if writeDatasetError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to resolve dataset", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to resolve dataset")
+		return
+	}
+	table := ""
+	if dataset != nil {
+		table = database.QualifiedTable(dataset.TableName)
+	}
+
+	graph, err := h.searchService.GetPersonGraph(userID, c.Param("id"), table, depth, maxNodes)
+	if errors.Is(err, services.ErrSearchFieldNotAllowed) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeFieldNotAllowed, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrPersonNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Person not found")
+		return
+	}
+	if writeBudgetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to get person graph", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get person graph")
+		return
+	}
+
+	for i := range graph.Nodes {
+		people := []models.Person{graph.Nodes[i].Person}
+		models.ShapePeople(people, shape)
+		graph.Nodes[i].Person = people[0]
+	}
+	c.JSON(http.StatusOK, graph)
+}
+
+// GetPersonHistory handles returning the prior versions of a person, with the imports that wrote and
+// replaced each one
+func (h *SearchHandler) GetPersonHistory(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultPersonHistoryLimit)))
+	if err != nil || limit < 1 || limit > services.MaxPersonHistoryLimit {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest,
+			fmt.Sprintf("limit must be between 1 and %d", services.MaxPersonHistoryLimit))
+		return
+	}
+
+	shape, err := responseShape(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	dataset, err := h.datasetService.ResolveDataset(userID, c.Query("dataset_id"))
+	if writeDatasetError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to resolve dataset", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to resolve dataset")
+		return
+	}
+	table := ""
+	if dataset != nil {
+		table = database.QualifiedTable(dataset.TableName)
+	}
+
+	history, err := h.searchService.GetPersonHistory(userID, c.Param("id"), table, limit)
+	if errors.Is(err, services.ErrPersonNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Person not found")
+		return
+	}
+	if writeBudgetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to get person history", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get person history")
+		return
+	}
+
+	if history.Current != nil {
+		people := []models.Person{*history.Current}
+		models.ShapePeople(people, shape)
+		history.Current = &people[0]
+	}
+	for i := range history.Versions {
+		people := []models.Person{history.Versions[i].Person}
+		models.ShapePeople(people, shape)
+		history.Versions[i].Person = people[0]
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// GetStats handles retrieving search statistics
+func (h *SearchHandler) GetStats(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	// Stats of one dataset with ?dataset_id=, otherwise of the default people table
+	dataset, err := h.datasetService.ResolveDataset(userID, c.Query("dataset_id"))
+	if writeDatasetError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to resolve dataset", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to resolve dataset")
+		return
+	}
+
+	stats, err := h.searchService.GetSearchStats(dataset)
+	if writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to get search stats", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetPincodeStats handles returning record counts, top circles and top name tokens for a pincode
+func (h *SearchHandler) GetPincodeStats(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	top, err := strconv.Atoi(c.DefaultQuery("top", strconv.Itoa(services.DefaultPincodeStatsTop)))
+	if err != nil || top < 1 || top > services.MaxPincodeStatsTop {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest,
+			fmt.Sprintf("top must be between 1 and %d", services.MaxPincodeStatsTop))
+		return
+	}
+
+	dataset, err := h.datasetService.ResolveDataset(userID, c.Query("dataset_id"))
+	if writeDatasetError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to resolve dataset", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to resolve dataset")
+		return
+	}
+	table := ""
+	if dataset != nil {
+		table = database.QualifiedTable(dataset.TableName)
+	}
+
+	stats, err := h.searchService.GetPincodeStats(userID, c.Param("pincode"), table, top)
+	if errors.Is(err, services.ErrInvalidPincode) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrSearchFieldNotAllowed) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeFieldNotAllowed, err.Error())
+		return
+	}
+	if writeBudgetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to get pincode stats", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get pincode stats")
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// ImportCSV handles CSV file import (admin only)
+func (h *SearchHandler) ImportCSV(c *gin.Context) {
+	// Get file from form data
+	file, header, err := c.Request.FormFile("csv_file")
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "No file provided")
+		return
+	}
+	defer file.Close()
+
+	// Get batch size from form (optional)
+	batchSizeStr := c.DefaultPostForm("batch_size", "100000")
+	batchSize, err := strconv.Atoi(batchSizeStr)
+	if err != nil || batchSize < 1000 {
+		batchSize = 100000
+	}
+
+	// Get has_header flag
+	hasHeader := c.DefaultPostForm("has_header", "true") == "true"
+
+	utils.LogInfo("Starting CSV import: " + header.Filename)
+
+	// Save uploaded file temporarily
+	tempFilePath := "/tmp/" + header.Filename
+	if err := c.SaveUploadedFile(header, tempFilePath); err != nil {
+		utils.LogError("Failed to save uploaded file", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to save file")
+		return
+	}
+
+	// Ensure temp file cleanup regardless of success or failure
+	defer func() {
+		if err := os.Remove(tempFilePath); err != nil {
+			utils.LogError("Failed to cleanup temp file: "+tempFilePath, err)
+		} else {
+			utils.LogInfo("Cleaned up temp file: " + tempFilePath)
+		}
+	}()
+
+	// Process the CSV file
+	processor := utils.NewCSVProcessor(batchSize, "/tmp")
+	processor.SetSource(uuid.New().String(), header.Filename)
+	updateExisting, delta := c.PostForm("update_existing") == "true", c.PostForm("delta") == "true"
+	if updateExisting && delta {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, errDeltaWithUpdate.Error())
+		return
+	}
+	processor.SetUpdateExisting(updateExisting)
+	processor.SetDelta(delta)
+
+	// Optional field map confirmed from a profiling step, sent as a JSON object
+	var fieldMap map[string]int
+	if fieldMapStr := c.PostForm("field_map"); fieldMapStr != "" {
+		if err := json.Unmarshal([]byte(fieldMapStr), &fieldMap); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid field map")
+			return
+		}
+	}
+
+	// Delimiter and encoding are detected from the file unless given
+	if utils.IsWorkbookFile(header.Filename) {
+		processor.SetWorkbook(c.PostForm("sheet"))
+	}
+	if err := processor.SetFormat(c.PostForm("delimiter"), c.PostForm("encoding")); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// Optional target table, e.g. a rebuilt people table that is switched over to once loaded, or a dataset
+	if err := h.configureImport(processor, fieldMap, c.PostForm("table"), c.PostForm("dataset_id")); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	if c.PostForm("dry_run") == "true" {
+		sampleRows, _ := strconv.Atoi(c.PostForm("sample_rows"))
+		h.dryRunImport(c, processor, tempFilePath, hasHeader, sampleRows)
+		return
+	}
+
+	endImport, ok := h.beginImport(c)
+	if !ok {
+		return
+	}
+	defer endImport()
+
+	progress := trackImport(c, processor, header.Filename, header.Size)
+	response, err := processor.ProcessCSVFile(tempFilePath, hasHeader)
+	finishImportProgress(progress, response, err)
+	if err != nil {
+		utils.LogError("CSV processing failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "CSV processing failed")
+		return
+	}
+
+	utils.LogInfo("CSV import completed successfully")
+	h.recordUnmappedCircles(response)
+	h.dispatchImportCompleted(header.Filename, response)
+	c.JSON(http.StatusOK, response)
+}
+
+// ImportCSVFromPath handles CSV file import from a file in the import directory (admin only).
+// Every import is audited, and a file that was already imported is rejected unless forced. Async
+// imports are queued on the job queue and run by a worker.
+func (h *SearchHandler) ImportCSVFromPath(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req struct {
+		FilePath   string         `json:"file_path" validate:"required"`
+		BatchSize  int            `json:"batch_size"`
+		HasHeader  bool           `json:"has_header"`
+		FieldMap   map[string]int `json:"field_map"`
+		Delimiter  string         `json:"delimiter"`   // comma, tab or pipe; detected when empty
+		Encoding   string         `json:"encoding"`    // UTF-8, ISO-8859-1 or Windows-1252; detected when empty
+		Sheet      string         `json:"sheet"`       // Sheet of an .xlsx workbook; the first sheet when empty
+		Force      bool           `json:"force"`       // Import even if the same file was imported before
+		Table      string         `json:"table"`       // Target people table; defaults to the active table
+		DatasetID  string         `json:"dataset_id"`  // Or the dataset whose table to load
+		DryRun     bool           `json:"dry_run"`     // Validate the file and report on it without importing
+		SampleRows int            `json:"sample_rows"` // Rows a dry run reads; 0 reads the whole file
+		Async      bool           `json:"async"`       // Queue the import and return the job at once
+		// Replace rows with the same master ID and mobile, keeping the old versions in the person history
+		UpdateExisting bool `json:"update_existing"`
+		// Insert only new rows, skip unchanged ones and stage changed ones for review
+		Delta bool `json:"delta"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
 	// Set defaults
-	if req.Limit == 0 {
-		req.Limit = 1000
+	if req.BatchSize == 0 {
+		req.BatchSize = 200000 // Use larger batch for big files
+	}
+	if req.UpdateExisting && req.Delta {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, errDeltaWithUpdate.Error())
+		return
+	}
+
+	filePath, err := h.importAuditService.ResolveImportPath(req.FilePath)
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Rejected CSV import path %q: %v", req.FilePath, err))
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// A dry run inserts nothing, so it is neither audited nor checked against previous imports
+	if req.DryRun {
+		processor := utils.NewCSVProcessor(req.BatchSize, "/tmp")
+		if utils.IsWorkbookFile(filePath) {
+			processor.SetWorkbook(req.Sheet)
+		}
+		if err := processor.SetFormat(req.Delimiter, req.Encoding); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		if err := h.configureImport(processor, req.FieldMap, req.Table, req.DatasetID); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		h.dryRunImport(c, processor, filePath, req.HasHeader, req.SampleRows)
+		return
+	}
+
+	checksum, fileSize, err := h.importAuditService.FileChecksum(filePath)
+	if err != nil {
+		utils.LogError("Failed to checksum import file", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to read import file")
+		return
+	}
+
+	previous, err := h.importAuditService.FindPreviousImport(checksum)
+	if err != nil {
+		utils.LogError("Failed to check previous imports", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to check previous imports")
+		return
+	}
+	if previous != nil && !req.Force {
+		abortWithAPIError(c, models.NewAPIError(http.StatusConflict, models.ErrorCodeConflict,
+			"This file has already been imported; set force to import it again").With("previous_import", previous))
+		return
+	}
+
+	job := &pathImportJob{
+		UserID:         userID,
+		RequestedPath:  req.FilePath,
+		FilePath:       filePath,
+		Checksum:       checksum,
+		FileSize:       fileSize,
+		Reimport:       previous != nil,
+		BatchSize:      req.BatchSize,
+		HasHeader:      req.HasHeader,
+		FieldMap:       req.FieldMap,
+		Delimiter:      req.Delimiter,
+		Encoding:       req.Encoding,
+		Sheet:          req.Sheet,
+		Table:          req.Table,
+		DatasetID:      req.DatasetID,
+		UpdateExisting: req.UpdateExisting,
+		Delta:          req.Delta,
+	}
+
+	if req.Async {
+		// Reject a bad format, field map or target now rather than in the worker
+		if _, err := utils.ParseCSVFormat(req.Delimiter, req.Encoding); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		if err := h.configureImport(utils.NewCSVProcessor(req.BatchSize, "/tmp"), req.FieldMap, req.Table, req.DatasetID); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		// A single attempt: rerunning an import that failed partway would insert its rows twice
+		queued, err := h.jobQueueService.Enqueue(services.JobTypeImport, job, &userID, 1)
+		if err != nil {
+			utils.LogError("Failed to queue CSV import", err)
+			abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to queue import")
+			return
+		}
+		utils.LogInfo(fmt.Sprintf("Queued CSV import from path: %s as job %s", filePath, queued.ID))
+		c.JSON(http.StatusAccepted, queued)
+		return
+	}
+
+	endImport, ok := h.beginImport(c)
+	if !ok {
+		return
+	}
+	defer endImport()
+
+	response, err := h.importFromPath(job)
+	var configErr *importConfigError
+	switch {
+	case errors.As(err, &configErr):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	case errors.Is(err, errImportNotRecorded):
+		utils.LogError("Failed to record CSV import", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to record import")
+		return
+	case err != nil:
+		utils.LogError("CSV processing failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "CSV processing failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// pathImportJob is an audited import of a file in the import directory, run in the request or by a
+// job queue worker
+type pathImportJob struct {
+	UserID         uuid.UUID      `json:"user_id"`
+	RequestedPath  string         `json:"requested_path"`
+	FilePath       string         `json:"file_path"` // Resolved in the import directory
+	Checksum       string         `json:"checksum"`
+	FileSize       int64          `json:"file_size"`
+	Reimport       bool           `json:"reimport"`
+	BatchSize      int            `json:"batch_size"`
+	HasHeader      bool           `json:"has_header"`
+	FieldMap       map[string]int `json:"field_map,omitempty"`
+	Delimiter      string         `json:"delimiter,omitempty"`
+	Encoding       string         `json:"encoding,omitempty"`
+	Sheet          string         `json:"sheet,omitempty"`
+	Table          string         `json:"table,omitempty"`
+	DatasetID      string         `json:"dataset_id,omitempty"`
+	UpdateExisting bool           `json:"update_existing,omitempty"`
+	Delta          bool           `json:"delta,omitempty"`
+}
+
+// errDeltaWithUpdate is returned when an import asks for both update_existing and delta
+var errDeltaWithUpdate = errors.New("update_existing and delta cannot be combined")
+
+// errImportNotRecorded is returned when the audit record of an import cannot be written
+var errImportNotRecorded = errors.New("failed to record import")
+
+// importConfigError is returned when an import's field map or target table is invalid
+type importConfigError struct {
+	err error
+}
+
+func (e *importConfigError) Error() string { return e.err.Error() }
+
+func (e *importConfigError) Unwrap() error { return e.err }
+
+// importFromPath runs an audited import of a file in the import directory. The caller holds the
+// import slot from services.BeginImport.
+func (h *SearchHandler) importFromPath(job *pathImportJob) (*models.CSVImportResponse, error) {
+	auditID, err := h.importAuditService.RecordImportStart(job.UserID, job.RequestedPath, job.FilePath, job.Checksum, job.FileSize, job.Reimport)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errImportNotRecorded, err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Starting CSV import from path: %s (sha256 %s) by user %s", job.FilePath, job.Checksum, job.UserID))
+
+	// Process the CSV file directly (no temp file needed)
+	processor := utils.NewCSVProcessor(job.BatchSize, "/tmp")
+	processor.SetSource(auditID.String(), job.RequestedPath)
+	processor.SetUpdateExisting(job.UpdateExisting)
+	processor.SetDelta(job.Delta)
+	if utils.IsWorkbookFile(job.FilePath) {
+		processor.SetWorkbook(job.Sheet)
+	}
+	if err := processor.SetFormat(job.Delimiter, job.Encoding); err != nil {
+		h.recordImportResult(auditID, nil, err)
+		return nil, &importConfigError{err: err}
+	}
+	if err := h.configureImport(processor, job.FieldMap, job.Table, job.DatasetID); err != nil {
+		h.recordImportResult(auditID, nil, err)
+		return nil, &importConfigError{err: err}
+	}
+
+	progress := trackImportFor(job.UserID, processor, job.RequestedPath, job.FileSize)
+	response, err := processor.ProcessCSVFile(job.FilePath, job.HasHeader)
+	finishImportProgress(progress, response, err)
+	h.recordImportResult(auditID, response, err)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.LogInfo("CSV import completed successfully")
+	h.dispatchImportCompleted(job.FilePath, response)
+	return response, nil
+}
+
+// RunImportJob runs an import queued with async. It waits while table maintenance holds off imports
+// and fails for good if the file changed after it was queued.
+func (h *SearchHandler) RunImportJob(ctx context.Context, queued *models.Job) (interface{}, error) {
+	var job pathImportJob
+	if err := json.Unmarshal(queued.Payload, &job); err != nil {
+		return nil, &services.PermanentJobError{Err: fmt.Errorf("invalid import job payload: %w", err)}
+	}
+
+	checksum, _, err := h.importAuditService.FileChecksum(job.FilePath)
+	if err != nil {
+		return nil, &services.PermanentJobError{Err: fmt.Errorf("failed to read import file: %w", err)}
+	}
+	if checksum != job.Checksum {
+		return nil, &services.PermanentJobError{Err: fmt.Errorf("import file %s changed after the import was queued", job.RequestedPath)}
+	}
+
+	endImport, err := services.BeginImport()
+	if err != nil {
+		return nil, &services.DeferJobError{Err: err, After: time.Minute}
+	}
+	defer endImport()
+
+	response, err := h.importFromPath(&job)
+	var configErr *importConfigError
+	if errors.As(err, &configErr) {
+		return nil, &services.PermanentJobError{Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ImportCSVFromURL handles importing a CSV file streamed from an HTTPS or s3:// URL (admin only)
+func (h *SearchHandler) ImportCSVFromURL(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req struct {
+		URL            string         `json:"url" binding:"required"` // https:// (including presigned) or s3://bucket/key
+		BatchSize      int            `json:"batch_size"`
+		HasHeader      bool           `json:"has_header"`
+		FieldMap       map[string]int `json:"field_map"`
+		Delimiter      string         `json:"delimiter"`
+		Encoding       string         `json:"encoding"`
+		Table          string         `json:"table"`
+		DatasetID      string         `json:"dataset_id"`
+		DryRun         bool           `json:"dry_run"`
+		SampleRows     int            `json:"sample_rows"`
+		UpdateExisting bool           `json:"update_existing"`
+		Delta          bool           `json:"delta"`
+		// Credentials for s3:// URLs; the configured S3 credentials are used when omitted
+		AccessKeyID     string `json:"access_key_id"`
+		SecretAccessKey string `json:"secret_access_key"`
+		SessionToken    string `json:"session_token"`
+		Region          string `json:"region"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+	if req.BatchSize == 0 {
+		req.BatchSize = 200000
+	}
+	if req.UpdateExisting && req.Delta {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, errDeltaWithUpdate.Error())
+		return
+	}
+	if sourceURL, err := url.Parse(req.URL); err == nil && utils.IsWorkbookFile(sourceURL.Path) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest,
+			"Excel workbooks cannot be streamed from a URL; upload them or import them by path")
+		return
+	}
+
+	processor := utils.NewCSVProcessor(req.BatchSize, "/tmp")
+	if err := processor.SetFormat(req.Delimiter, req.Encoding); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err := h.configureImport(processor, req.FieldMap, req.Table, req.DatasetID); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	body, sourceURL, err := h.remoteImportService.Open(c.Request.Context(), req.URL, services.RemoteCredentials{
+		AccessKeyID:     req.AccessKeyID,
+		SecretAccessKey: req.SecretAccessKey,
+		SessionToken:    req.SessionToken,
+		Region:          req.Region,
+	})
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Failed to open CSV import URL %s: %v", sourceURL, err))
+		if errors.Is(err, services.ErrInvalidImportURL) {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		abortWithError(c, http.StatusBadGateway, models.ErrorCodeUpstream, err.Error())
+		return
+	}
+	defer body.Close()
+
+	if req.DryRun {
+		report, err := processor.DryRunReader(body, req.HasHeader, req.SampleRows)
+		if err != nil {
+			utils.LogError("CSV dry run failed", err)
+			abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "CSV validation failed")
+			return
+		}
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	endImport, ok := h.beginImport(c)
+	if !ok {
+		return
+	}
+	defer endImport()
+
+	// The checksum is only known once the stream is consumed, so URL imports are recorded but not
+	// checked against previous imports
+	auditID, err := h.importAuditService.RecordImportStart(userID, sourceURL, sourceURL, "", 0, false)
+	if err != nil {
+		utils.LogError("Failed to record CSV import", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to record import")
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Starting CSV import from URL: %s by user %s", sourceURL, userID))
+
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(body, hash)}
+	processor.SetSource(auditID.String(), sourceURL)
+	processor.SetUpdateExisting(req.UpdateExisting)
+	processor.SetDelta(req.Delta)
+
+	// The size of a remote source is not known, so only rows are reported until it completes
+	progress := trackImport(c, processor, sourceURL, 0)
+	response, err := processor.ProcessCSVReader(counter, req.HasHeader)
+	finishImportProgress(progress, response, err)
+	if srcErr := h.importAuditService.RecordImportSource(auditID, hex.EncodeToString(hash.Sum(nil)), counter.n); srcErr != nil {
+		utils.LogError("Failed to record CSV import checksum", srcErr)
+	}
+	h.recordImportResult(auditID, response, err)
+	if err != nil {
+		utils.LogError("CSV processing failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "CSV processing failed")
+		return
+	}
+
+	utils.LogInfo("CSV import from URL completed successfully")
+	h.dispatchImportCompleted(sourceURL, response)
+	c.JSON(http.StatusOK, response)
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// configureImport applies the circle normalization map, an optional field map and target table or
+// dataset to an import
+func (h *SearchHandler) configureImport(processor *utils.CSVProcessor, fieldMap map[string]int, table, datasetID string) error {
+	processor.SetCircleMap(h.circleAliasService.CircleMap())
+	if fieldMap != nil {
+		if err := processor.SetFieldMap(fieldMap); err != nil {
+			return err
+		}
+	}
+	table, err := h.importTable(table, datasetID)
+	if err != nil {
+		return err
+	}
+	if table != "" {
+		return h.setImportTable(processor, table)
+	}
+	return nil
+}
+
+// dryRunImport validates a CSV file with an import's settings and responds with the report
+func (h *SearchHandler) dryRunImport(c *gin.Context, processor *utils.CSVProcessor, filePath string, hasHeader bool, sampleRows int) {
+	report, err := processor.DryRun(filePath, hasHeader, sampleRows)
+	if err != nil {
+		utils.LogError("CSV dry run failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "CSV validation failed")
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// importTable returns the people table an import loads: the table of a registered dataset, an explicit
+// table, or "" for the active people table
+func (h *SearchHandler) importTable(table, datasetID string) (string, error) {
+	if datasetID == "" {
+		return table, nil
+	}
+	if table != "" {
+		return "", fmt.Errorf("set either table or dataset_id, not both")
+	}
+	dataset, err := h.datasetService.GetDataset(datasetID)
+	if err != nil {
+		return "", err
+	}
+	return dataset.TableName, nil
+}
+
+// setImportTable points an import at another people table after checking it has the people columns
+func (h *SearchHandler) setImportTable(processor *utils.CSVProcessor, table string) error {
+	if _, err := h.peopleTableService.ValidateTable(table); err != nil {
+		return err
+	}
+	return processor.SetTargetTable(table)
+}
+
+// beginImport holds off table maintenance for the duration of an import, responding with 409 while
+// an OPTIMIZE run is in progress
+func (h *SearchHandler) beginImport(c *gin.Context) (func(), bool) {
+	endImport, err := services.BeginImport()
+	if err != nil {
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+		return nil, false
+	}
+	return endImport, true
+}
+
+// trackImport reports the progress of an import to /ws subscribers. sourceSize is the size of the source
+// in bytes, from which the percentage done is estimated, or 0 when it is not known.
+func trackImport(c *gin.Context, processor *utils.CSVProcessor, name string, sourceSize int64) *services.JobProgressTracker {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	return trackImportFor(userID, processor, name, sourceSize)
+}
+
+// trackImportFor reports the progress of an import run for a user outside their request
+func trackImportFor(userID uuid.UUID, processor *utils.CSVProcessor, name string, sourceSize int64) *services.JobProgressTracker {
+	tracker := services.TrackJob(models.JobKindImport, processor.JobID(), userID, name, 0)
+	processor.SetProgressFunc(func(response *models.CSVImportResponse, bytesRead int64) {
+		fraction := 0.0
+		if sourceSize > 0 {
+			fraction = float64(bytesRead) / float64(sourceSize)
+		}
+		tracker.Update(response.ProcessedRows, response.ErrorRows, fraction, response.Errors)
+	})
+	return tracker
+}
+
+// finishImportProgress reports how a tracked import ended
+func finishImportProgress(tracker *services.JobProgressTracker, response *models.CSVImportResponse, err error) {
+	if err != nil {
+		tracker.Fail(err)
+		return
 	}
-	if req.Limit > 10000 {
-		req.Limit = 10000 // Max limit from config
+	tracker.Complete(response.ProcessedRows, response.ErrorRows, response.Errors)
+}
+
+// recordImportResult stores the outcome of an audited import; failures are only logged
+func (h *SearchHandler) recordImportResult(auditID uuid.UUID, response *models.CSVImportResponse, importErr error) {
+	if err := h.importAuditService.RecordImportResult(auditID, response, importErr); err != nil {
+		utils.LogError("Failed to record CSV import result", err)
 	}
-	if req.Logic == "" {
-		req.Logic = "AND"
+	h.recordUnmappedCircles(response)
+}
+
+// recordUnmappedCircles adds the circle values an import could not normalize to the unmapped circles
+// report; failures are only logged
+func (h *SearchHandler) recordUnmappedCircles(response *models.CSVImportResponse) {
+	if response == nil {
+		return
 	}
-	if req.MatchType == "" {
-		req.MatchType = "partial"
+	if err := h.circleAliasService.RecordUnmapped(response.JobID, response.UnmappedCircles); err != nil {
+		utils.LogError("Failed to record unmapped circles", err)
 	}
+}
 
-	// Debug logging
-	utils.LogInfo(fmt.Sprintf("Search request - Query: %s, Logic: %s, Fields: %v, Limit: %d",
-		req.Query, req.Logic, req.Fields, req.Limit))
+// dispatchImportCompleted notifies webhooks subscribed to completed imports
+func (h *SearchHandler) dispatchImportCompleted(fileName string, response *models.CSVImportResponse) {
+	h.webhookService.Dispatch(services.WebhookEventImportCompleted, gin.H{
+		"file":           fileName,
+		"job_id":         response.JobID,
+		"table":          response.Table,
+		"total_rows":     response.TotalRows,
+		"processed_rows": response.ProcessedRows,
+		"error_rows":     response.ErrorRows,
+	})
+}
 
-	response, err := h.searchService.Search(userID, &req)
-	if err != nil {
-		utils.LogError("Search failed", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed"})
+// GetImportAudit handles listing audited server-side CSV imports (admin only)
+func (h *SearchHandler) GetImportAudit(c *gin.Context) {
+	params, ok := pageParams(c, 20, 100)
+	if !ok {
 		return
 	}
 
-	// Add message if no results found
-	if response.TotalCount == 0 {
-		utils.LogInfo("Search completed successfully - No results found")
-		// Create response with no results message
-		responseWithMessage := gin.H{
-			"results":           response.Results,
-			"total_count":       response.TotalCount,
-			"execution_time_ms": response.ExecutionTime,
-			"search_id":         response.SearchID,
-			"has_more":          response.HasMore,
-			"message":           "No results found for your search criteria",
-		}
-		c.JSON(http.StatusOK, responseWithMessage)
+	response, err := h.importAuditService.GetImportAudit(params)
+	if err != nil {
+		utils.LogError("Failed to get import audit", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get import audit")
 		return
 	}
 
-	utils.LogInfo("Search completed successfully")
 	c.JSON(http.StatusOK, response)
 }
 
-// GetPerson handles retrieving a specific person by ID
-func (h *SearchHandler) GetPerson(c *gin.Context) {
-	personID := c.Param("id")
-	if personID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Person ID is required"})
+// GetImportRows handles counting the rows an import job left in a people table (admin only)
+func (h *SearchHandler) GetImportRows(c *gin.Context) {
+	jobID, table, ok := h.importRowsTarget(c)
+	if !ok {
 		return
 	}
 
-	person, err := h.searchService.GetPersonByID(personID)
+	rows, err := h.importAuditService.GetImportRows(jobID, table)
 	if err != nil {
-		utils.LogError("Failed to get person", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		h.writeImportRowsError(c, "Failed to count import rows", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, person)
+	c.JSON(http.StatusOK, rows)
 }
 
-// GetStats handles retrieving search statistics
-func (h *SearchHandler) GetStats(c *gin.Context) {
-	stats, err := h.searchService.GetSearchStats()
+// DeleteImportRows handles rolling back an import by deleting the rows it loaded (admin only)
+func (h *SearchHandler) DeleteImportRows(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
 	if err != nil {
-		utils.LogError("Failed to get search stats", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statistics"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
 		return
 	}
 
-	c.JSON(http.StatusOK, stats)
+	jobID, table, ok := h.importRowsTarget(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.importAuditService.DeleteImportRows(jobID, table, adminID)
+	if err != nil {
+		h.writeImportRowsError(c, "Failed to delete import rows", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rows)
 }
 
-// ImportCSV handles CSV file import (admin only)
-func (h *SearchHandler) ImportCSV(c *gin.Context) {
-	// Get file from form data
-	file, header, err := c.Request.FormFile("csv_file")
+// GetImportErrors handles downloading the rows an import job could not load as CSV (admin only)
+func (h *SearchHandler) GetImportErrors(c *gin.Context) {
+	jobID, ok := importJobID(c)
+	if !ok {
+		return
+	}
+
+	path, err := h.importAuditService.ImportErrorFile(jobID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file provided"})
+		if errors.Is(err, services.ErrImportErrorsNotFound) {
+			abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+			return
+		}
+		utils.LogError("Failed to open import error file", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to open import error file")
 		return
 	}
-	defer file.Close()
 
-	// Get batch size from form (optional)
-	batchSizeStr := c.DefaultPostForm("batch_size", "100000")
-	batchSize, err := strconv.Atoi(batchSizeStr)
-	if err != nil || batchSize < 1000 {
-		batchSize = 100000
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.FileAttachment(path, fmt.Sprintf("import_%s_errors.csv", jobID))
+}
+
+// importRowsTarget parses the import job ID and resolves the people table holding its rows: the table
+// or dataset named in the query, or else the table the import recorded. It responds and returns false
+// when either is invalid.
+func (h *SearchHandler) importRowsTarget(c *gin.Context) (uuid.UUID, string, bool) {
+	jobID, err := uuid.Parse(c.Param("job_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid import job ID")
+		return uuid.Nil, "", false
 	}
 
-	// Get has_header flag
-	hasHeader := c.DefaultPostForm("has_header", "true") == "true"
+	table, err := h.importTable(c.Query("table"), c.Query("dataset_id"))
+	if err != nil {
+		if !writeDatasetError(c, err) {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		}
+		return uuid.Nil, "", false
+	}
+	if table != "" {
+		if _, err := h.peopleTableService.ValidateTable(table); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return uuid.Nil, "", false
+		}
+		return jobID, database.QualifiedTable(table), true
+	}
 
-	utils.LogInfo("Starting CSV import: " + header.Filename)
+	table, err = h.importAuditService.ImportTable(jobID)
+	if err != nil {
+		utils.LogError("Failed to resolve import table", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to resolve import table")
+		return uuid.Nil, "", false
+	}
+	return jobID, table, true
+}
 
-	// Save uploaded file temporarily
-	tempFilePath := "/tmp/" + header.Filename
-	if err := c.SaveUploadedFile(header, tempFilePath); err != nil {
-		utils.LogError("Failed to save uploaded file", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+// writeImportRowsError maps import rollback errors to their status codes
+func (h *SearchHandler) writeImportRowsError(c *gin.Context, message string, err error) {
+	if errors.Is(err, services.ErrImportRowsNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
 		return
 	}
+	utils.LogError(message, err)
+	abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, message)
+}
 
-	// Ensure temp file cleanup regardless of success or failure
-	defer func() {
-		if err := os.Remove(tempFilePath); err != nil {
-			utils.LogError("Failed to cleanup temp file: "+tempFilePath, err)
-		} else {
-			utils.LogInfo("Cleaned up temp file: " + tempFilePath)
-		}
-	}()
+// ProfileCSV handles sampling an uploaded CSV file and suggesting a field map (admin only)
+func (h *SearchHandler) ProfileCSV(c *gin.Context) {
+	file, header, err := c.Request.FormFile("csv_file")
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "No file provided")
+		return
+	}
+	defer file.Close()
 
-	// Process the CSV file
-	processor := utils.NewCSVProcessor(batchSize, "/tmp")
-	response, err := processor.ProcessCSVFile(tempFilePath, hasHeader)
+	sampleSize, err := strconv.Atoi(c.DefaultPostForm("sample_size", "1000"))
+	if err != nil || sampleSize < 1 || sampleSize > 100000 {
+		sampleSize = 1000
+	}
+	hasHeader := c.DefaultPostForm("has_header", "true") == "true"
+	format, err := utils.ParseCSVFormat(c.PostForm("delimiter"), c.PostForm("encoding"))
 	if err != nil {
-		utils.LogError("CSV processing failed", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "CSV processing failed"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
 		return
 	}
 
-	utils.LogInfo("CSV import completed successfully")
-	c.JSON(http.StatusOK, response)
+	// Only the sampled rows are read, so the upload is profiled without saving it
+	profile, err := utils.ProfileCSV(file, hasHeader, sampleSize, format)
+	if err != nil {
+		utils.LogError("CSV profiling failed", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("CSV profiled: %s (%d rows sampled)", header.Filename, profile.SampledRows))
+	c.JSON(http.StatusOK, profile)
 }
 
-// ImportCSVFromPath handles CSV file import from direct file path (admin only)
-func (h *SearchHandler) ImportCSVFromPath(c *gin.Context) {
+// ProfileCSVFromPath handles sampling a CSV file on the server and suggesting a field map (admin only)
+func (h *SearchHandler) ProfileCSVFromPath(c *gin.Context) {
 	var req struct {
-		FilePath  string `json:"file_path" validate:"required"`
-		BatchSize int    `json:"batch_size"`
-		HasHeader bool   `json:"has_header"`
+		FilePath   string `json:"file_path" validate:"required"`
+		HasHeader  bool   `json:"has_header"`
+		SampleSize int    `json:"sample_size"`
+		Delimiter  string `json:"delimiter"`
+		Encoding   string `json:"encoding"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
-	// Set defaults
-	if req.BatchSize == 0 {
-		req.BatchSize = 200000 // Use larger batch for big files
+	if req.SampleSize < 1 || req.SampleSize > 100000 {
+		req.SampleSize = 1000
+	}
+	format, err := utils.ParseCSVFormat(req.Delimiter, req.Encoding)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
 	}
 
-	utils.LogInfo("Starting CSV import from path: " + req.FilePath)
+	filePath, err := h.importAuditService.ResolveImportPath(req.FilePath)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
 
-	// Check if file exists
-	if _, err := os.Stat(req.FilePath); os.IsNotExist(err) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File not found: " + req.FilePath})
+	file, err := os.Open(filePath)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "File not found: "+req.FilePath)
 		return
 	}
+	defer file.Close()
 
-	// Process the CSV file directly (no temp file needed)
-	processor := utils.NewCSVProcessor(req.BatchSize, "/tmp")
-	response, err := processor.ProcessCSVFile(req.FilePath, req.HasHeader)
+	profile, err := utils.ProfileCSV(file, req.HasHeader, req.SampleSize, format)
 	if err != nil {
-		utils.LogError("CSV processing failed", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "CSV processing failed"})
+		utils.LogError("CSV profiling failed", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
 		return
 	}
 
-	utils.LogInfo("CSV import completed successfully")
-	c.JSON(http.StatusOK, response)
+	utils.LogInfo(fmt.Sprintf("CSV profiled: %s (%d rows sampled)", req.FilePath, profile.SampledRows))
+	c.JSON(http.StatusOK, profile)
 }
 
 // ExportSearchResults handles exporting search results to CSV
 func (h *SearchHandler) ExportSearchResults(c *gin.Context) {
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	var req models.ExportRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	if req.Async {
+		h.queueExport(c, userID, &req)
+		return
+	}
+
+	response, err := h.exportService.Export(userID, &req)
+	if writeQuotaError(c, err) {
+		return
+	}
+	if errors.Is(err, services.ErrExportTooLarge) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeExportTooLarge, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrSearchFieldNotAllowed) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeFieldNotAllowed, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrInvalidExport) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrSearchNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrSearchAnonymized) {
+		abortWithError(c, http.StatusGone, models.ErrorCodeSearchAnonymized, err.Error())
+		return
+	}
+	if writeDatasetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
+	if err != nil {
+		utils.LogError("Export failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Export failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// queueExport queues an async export on the job queue. The password of an encrypted export is only
+// ever returned in the response, so encrypted exports cannot run in the background.
+func (h *SearchHandler) queueExport(c *gin.Context, userID uuid.UUID, req *models.ExportRequest) {
+	if req.Encrypt || req.Password != "" {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Encrypted exports cannot be async")
+		return
+	}
+
+	job, err := h.jobQueueService.Enqueue(services.JobTypeExport, services.ExportJobPayload{UserID: userID, Request: *req}, &userID, 0)
+	if err != nil {
+		utils.LogError("Failed to queue export", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to queue export")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetMyExports returns the current user's recent exports and whether each can still be downloaded
+func (h *SearchHandler) GetMyExports(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	exports, err := h.exportService.GetUserExports(userID, limit)
+	if err != nil {
+		utils.LogError("Failed to get user exports", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get exports")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+// RegenerateExport re-runs the query of an expired export and returns a fresh download
+func (h *SearchHandler) RegenerateExport(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid export ID")
+		return
+	}
+
+	response, err := h.exportService.Regenerate(userID, exportID)
+	if errors.Is(err, services.ErrExportStillAvailable) {
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, "Export file is still available; download it instead")
+		return
+	}
+	if err != nil {
+		utils.LogError("Export regeneration failed", err)
+		if err.Error() == "export not found" {
+			abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+			return
+		}
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// TraceExport handles finding the export a leaked file came from, by its watermark or the order of
+// its rows (admin only)
+func (h *SearchHandler) TraceExport(c *gin.Context) {
+	var req models.ExportTraceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
-	// TODO: Implement export functionality
-	// For now, return a placeholder response
-	response := models.ExportResponse{
-		DownloadURL: "/api/downloads/export_" + userID.String() + ".csv",
-		FileName:    "search_results.csv",
-		FileSize:    0,
-		RowCount:    0,
+	response, err := h.exportService.TraceExport(c.Request.Context(), &req)
+	if errors.Is(err, services.ErrInvalidTrace) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Export trace failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to trace export")
+		return
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -249,19 +1601,33 @@ func (h *SearchHandler) ExportSearchResults(c *gin.Context) {
 func (h *SearchHandler) SearchWithin(c *gin.Context) {
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	var req models.SearchWithinRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+	req.ClientID = c.GetString("client_id")
+	req.RequestID = c.GetString("request_id")
+	req.Context = c.Request.Context()
+
+	shape, err := responseShape(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	req.ImpersonatedBy = c.GetString("impersonated_by")
+	if !h.allowDiagnostic(c, &req.Diagnostic) {
 		return
 	}
 
@@ -277,11 +1643,29 @@ func (h *SearchHandler) SearchWithin(c *gin.Context) {
 	}
 
 	response, err := h.searchService.SearchWithin(userID, &req)
+	if errors.Is(err, services.ErrSearchAnonymized) {
+		abortWithError(c, http.StatusGone, models.ErrorCodeSearchAnonymized, err.Error())
+		return
+	}
+	quota := h.setSearchQuotaHeaders(c, userID)
+	if errors.Is(err, services.ErrSearchFieldNotAllowed) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeFieldNotAllowed, err.Error())
+		return
+	}
+	if writeQuotaError(c, err) {
+		return
+	}
+	if writeDatasetError(c, err) || writeBudgetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
 	if err != nil {
 		utils.LogError("Search within failed", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, err.Error())
 		return
 	}
+	models.ShapePeople(response.Results, shape)
+	c.Header(services.SearchIDHeader, response.SearchID)
+	response.Quota = searchQuota(response.Quota, quota)
 
 	// Add message if no results found
 	if response.TotalCount == 0 {
@@ -292,6 +1676,7 @@ func (h *SearchHandler) SearchWithin(c *gin.Context) {
 			"total_count":       response.TotalCount,
 			"execution_time_ms": response.ExecutionTime,
 			"search_id":         response.SearchID,
+			"quota":             response.Quota,
 			"has_more":          response.HasMore,
 			"message":           "No results found within previous search results",
 		}
@@ -307,25 +1692,74 @@ func (h *SearchHandler) SearchWithin(c *gin.Context) {
 func (h *SearchHandler) EnhancedMobileSearch(c *gin.Context) {
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	var req models.EnhancedMobileSearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	// Validate mobile number
 	if req.MobileNumber == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Mobile number is required"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Mobile number is required")
+		return
+	}
+
+	h.runEnhancedSearch(c, userID, &models.EnhancedSearchRequest{
+		KeyType:    models.EnhancedKeyMobile,
+		Value:      req.MobileNumber,
+		Limit:      req.Limit,
+		Offset:     req.Offset,
+		Depth:      req.Depth,
+		Diagnostic: req.Diagnostic,
+		DatasetID:  req.DatasetID,
+	})
+}
+
+// EnhancedSearch handles enhanced searches keyed on a mobile number, an alt number or an email
+func (h *SearchHandler) EnhancedSearch(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.EnhancedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+	if req.Value == "" {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "value is required")
+		return
+	}
+
+	h.runEnhancedSearch(c, userID, &req)
+}
+
+// runEnhancedSearch validates and runs an enhanced search and writes its matches
+func (h *SearchHandler) runEnhancedSearch(c *gin.Context, userID uuid.UUID, req *models.EnhancedSearchRequest) {
+	req.ClientID = c.GetString("client_id")
+	req.RequestID = c.GetString("request_id")
+	req.Context = c.Request.Context()
+
+	shape, err := responseShape(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	req.ImpersonatedBy = c.GetString("impersonated_by")
+	if !h.allowDiagnostic(c, &req.Diagnostic) {
 		return
 	}
 
@@ -336,22 +1770,50 @@ func (h *SearchHandler) EnhancedMobileSearch(c *gin.Context) {
 	if req.Limit > 10000 {
 		req.Limit = 10000
 	}
+	if req.Depth == 0 {
+		req.Depth = 1
+	}
+	if req.Depth != 1 && req.Depth != 2 {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "depth must be 1 or 2")
+		return
+	}
 
-	utils.LogInfo(fmt.Sprintf("Enhanced mobile search request - Mobile: %s, Limit: %d, Offset: %d",
-		req.MobileNumber, req.Limit, req.Offset))
+	utils.LogInfo(fmt.Sprintf("Enhanced search request - Key: %s %s, Limit: %d, Offset: %d, Depth: %d",
+		req.KeyType, req.Value, req.Limit, req.Offset, req.Depth))
 
-	response, err := h.searchService.EnhancedMobileSearch(userID, &req)
+	response, err := h.searchService.EnhancedSearch(userID, req)
+	quota := h.setSearchQuotaHeaders(c, userID)
+	if errors.Is(err, services.ErrInvalidEnhancedSearch) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrSearchFieldNotAllowed) {
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeFieldNotAllowed, err.Error())
+		return
+	}
+	if writeQuotaError(c, err) {
+		return
+	}
+	if writeDatasetError(c, err) || writeBudgetError(c, err) || writeUnavailableError(c, err) {
+		return
+	}
 	if err != nil {
-		utils.LogError("Enhanced mobile search failed", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Enhanced mobile search failed"})
+		utils.LogError("Enhanced search failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Enhanced search failed")
 		return
 	}
+	models.ShapePeople(response.DirectMatches, shape)
+	models.ShapePeople(response.MasterIDMatches, shape)
+	models.ShapePeople(response.SecondHopMatches, shape)
+	c.Header(services.SearchIDHeader, response.SearchID)
+	response.Quota = searchQuota(response.Quota, quota)
 
 	// Add message if no results found
 	if response.TotalCount == 0 {
-		utils.LogInfo("Enhanced mobile search completed successfully - No results found")
+		utils.LogInfo("Enhanced search completed successfully - No results found")
 		// Create response with no results message
 		responseWithMessage := gin.H{
+			"key_type":                response.KeyType,
 			"direct_matches":          response.DirectMatches,
 			"master_id_matches":       response.MasterIDMatches,
 			"total_direct_matches":    response.TotalDirectMatches,
@@ -359,15 +1821,16 @@ func (h *SearchHandler) EnhancedMobileSearch(c *gin.Context) {
 			"total_count":             response.TotalCount,
 			"execution_time_ms":       response.ExecutionTime,
 			"search_id":               response.SearchID,
+			"quota":                   response.Quota,
 			"has_more":                response.HasMore,
 			"master_ids":              response.MasterIDs,
-			"message":                 fmt.Sprintf("No results found for mobile number: %s", req.MobileNumber),
+			"message":                 fmt.Sprintf("No results found for %s: %s", response.KeyType, req.Value),
 		}
 		c.JSON(http.StatusOK, responseWithMessage)
 		return
 	}
 
-	utils.LogInfo(fmt.Sprintf("Enhanced mobile search completed successfully - Direct: %d, Master ID: %d",
+	utils.LogInfo(fmt.Sprintf("Enhanced search completed successfully - Direct: %d, Master ID: %d",
 		len(response.DirectMatches), len(response.MasterIDMatches)))
 	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAnalyticsRange bounds analytics periods; the performance log keeps 90 days by default
+const maxAnalyticsRange = 90 * 24 * time.Hour
+
+type SearchAnalyticsHandler struct {
+	searchAnalyticsService *services.SearchAnalyticsService
+}
+
+func NewSearchAnalyticsHandler() *SearchAnalyticsHandler {
+	return &SearchAnalyticsHandler{
+		searchAnalyticsService: services.NewSearchAnalyticsService(),
+	}
+}
+
+// GetSearchPerformance handles reporting search latency and volume over a period (admin only)
+func (h *SearchAnalyticsHandler) GetSearchPerformance(c *gin.Context) {
+	from, to, err := analyticsRange(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	interval := c.Query("interval")
+	if interval == "" {
+		interval = services.AnalyticsIntervalHour
+		if to.Sub(from) > 3*24*time.Hour {
+			interval = services.AnalyticsIntervalDay
+		}
+	}
+	if interval != services.AnalyticsIntervalHour && interval != services.AnalyticsIntervalDay {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "interval must be hour or day")
+		return
+	}
+
+	analytics, err := h.searchAnalyticsService.GetSearchPerformance(c.Request.Context(), from, to, interval, analyticsLimit(c))
+	if err != nil {
+		utils.LogError("Failed to get search performance analytics", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve search performance analytics")
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
+
+// GetSlowQueries handles listing recent slow searches with their captured query plans (admin only)
+func (h *SearchAnalyticsHandler) GetSlowQueries(c *gin.Context) {
+	from, to, err := analyticsRange(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	report, err := h.searchAnalyticsService.GetSlowQueries(c.Request.Context(), from, to, analyticsLimit(c))
+	if err != nil {
+		utils.LogError("Failed to get slow queries", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve slow queries")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetTopQueries handles reporting the most searched terms and field combinations over a period (admin only)
+func (h *SearchAnalyticsHandler) GetTopQueries(c *gin.Context) {
+	from, to, err := analyticsRange(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	report, err := h.searchAnalyticsService.GetTopQueries(from, to, analyticsLimit(c))
+	if err != nil {
+		utils.LogError("Failed to get top queries", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve top queries")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetZeroResultQueries handles reporting the searches that found nothing over a period (admin only)
+func (h *SearchAnalyticsHandler) GetZeroResultQueries(c *gin.Context) {
+	from, to, err := analyticsRange(c)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	report, err := h.searchAnalyticsService.GetZeroResultQueries(from, to, analyticsLimit(c))
+	if err != nil {
+		utils.LogError("Failed to get zero result queries", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve zero result queries")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// analyticsLimit returns how many rows a report lists, 20 by default and at most 100
+func analyticsLimit(c *gin.Context) int {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return limit
+}
+
+// analyticsRange returns the period in the from and to query parameters, as RFC 3339 times or dates.
+// A date in to includes that whole day. The period defaults to the last 7 days.
+func analyticsRange(c *gin.Context) (time.Time, time.Time, error) {
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		parsed, dateOnly, err := parseAnalyticsTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		if dateOnly {
+			parsed = parsed.AddDate(0, 0, 1)
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -7)
+	if value := c.Query("from"); value != "" {
+		parsed, _, err := parseAnalyticsTime(value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be an RFC 3339 time or a YYYY-MM-DD date")
+		}
+		from = parsed
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from) > maxAnalyticsRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("the period can be at most 90 days")
+	}
+	return from, to, nil
+}
+
+// parseAnalyticsTime parses an RFC 3339 time or a date, reporting whether it was a date
+func parseAnalyticsTime(value string) (time.Time, bool, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, false, nil
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", value, services.QuotaLocation())
+	return parsed, true, err
+}
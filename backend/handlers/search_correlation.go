@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchCorrelationHandler struct {
+	searchCorrelationService *services.SearchCorrelationService
+}
+
+func NewSearchCorrelationHandler() *SearchCorrelationHandler {
+	return &SearchCorrelationHandler{
+		searchCorrelationService: services.NewSearchCorrelationService(),
+	}
+}
+
+// GetSearchCorrelation handles looking up everything recorded about a search ID (admin only)
+func (h *SearchCorrelationHandler) GetSearchCorrelation(c *gin.Context) {
+	correlation, err := h.searchCorrelationService.GetSearchCorrelation(c.Param("search_id"))
+	if errors.Is(err, services.ErrSearchNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "Search not found")
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to look up search", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to look up search")
+		return
+	}
+
+	c.JSON(http.StatusOK, correlation)
+}
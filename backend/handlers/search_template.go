@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SearchTemplateHandler struct {
+	searchTemplateService *services.SearchTemplateService
+}
+
+func NewSearchTemplateHandler() *SearchTemplateHandler {
+	return &SearchTemplateHandler{
+		searchTemplateService: services.NewSearchTemplateService(),
+	}
+}
+
+// GetMySearchTemplates handles listing the search templates users can run
+func (h *SearchTemplateHandler) GetMySearchTemplates(c *gin.Context) {
+	templates, err := h.searchTemplateService.ListTemplates(false)
+	if err != nil {
+		writeSearchTemplateError(c, "Failed to list search templates", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// GetSearchTemplates handles listing every search template, active or not (admin only)
+func (h *SearchTemplateHandler) GetSearchTemplates(c *gin.Context) {
+	templates, err := h.searchTemplateService.ListTemplates(true)
+	if err != nil {
+		writeSearchTemplateError(c, "Failed to list search templates", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
+}
+
+// CreateSearchTemplate handles creating a search template (admin only)
+func (h *SearchTemplateHandler) CreateSearchTemplate(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var req models.CreateSearchTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	template, err := h.searchTemplateService.CreateTemplate(&req, adminID)
+	if err != nil {
+		writeSearchTemplateError(c, "Failed to create search template", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// UpdateSearchTemplate handles changing or deactivating a search template (admin only)
+func (h *SearchTemplateHandler) UpdateSearchTemplate(c *gin.Context) {
+	var req models.UpdateSearchTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	template, err := h.searchTemplateService.UpdateTemplate(c.Param("id"), &req)
+	if err != nil {
+		writeSearchTemplateError(c, "Failed to update search template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteSearchTemplate handles deleting a search template (admin only)
+func (h *SearchTemplateHandler) DeleteSearchTemplate(c *gin.Context) {
+	if err := h.searchTemplateService.DeleteTemplate(c.Param("id")); err != nil {
+		writeSearchTemplateError(c, "Failed to delete search template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Search template deleted"})
+}
+
+// writeSearchTemplateError maps search template errors to their status codes, logging unexpected ones
+func writeSearchTemplateError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrSearchTemplateNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidSearchTemplate):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrSearchTemplateExists):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+	default:
+		utils.LogError(message, err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, message)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SimulationHandler struct {
+	simulationService *services.SimulationService
+}
+
+func NewSimulationHandler() *SimulationHandler {
+	return &SimulationHandler{
+		simulationService: services.NewSimulationService(),
+	}
+}
+
+// Simulate reports whether a hypothetical search or export would be allowed for a user (admin only)
+func (h *SimulationHandler) Simulate(c *gin.Context) {
+	var req models.SimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	if req.UserID == "" {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "User ID is required")
+		return
+	}
+
+	response, err := h.simulationService.Simulate(&req)
+	if errors.Is(err, services.ErrInvalidSimulation) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrUserNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
+		return
+	}
+	if err != nil {
+		utils.LogError("Simulation failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Simulation failed")
+		return
+	}
+
+	utils.LogInfo("Simulation completed for user: " + response.UserID)
+	c.JSON(http.StatusOK, response)
+}
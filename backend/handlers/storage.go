@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StorageHandler struct {
+	storageService *services.StorageService
+}
+
+func NewStorageHandler() *StorageHandler {
+	return &StorageHandler{
+		storageService: services.NewStorageService(),
+	}
+}
+
+// GetStorageStats handles reporting the database connection pools of this instance (admin only)
+func (h *StorageHandler) GetStorageStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.storageService.GetPoolStats())
+}
+
+// Metrics handles exposing the connection pool stats in the Prometheus text format
+func (h *StorageHandler) Metrics(c *gin.Context) {
+	stats := h.storageService.GetPoolStats()
+	circuitOpen := 0
+	if stats.ClickHouse.CircuitOpen {
+		circuitOpen = 1
+	}
+
+	var b strings.Builder
+	writeMetric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	writeMetric("finone_postgres_pool_max_open", "gauge", "Maximum open PostgreSQL connections.", stats.Postgres.MaxOpenConns)
+	writeMetric("finone_postgres_pool_open", "gauge", "Open PostgreSQL connections.", stats.Postgres.OpenConns)
+	writeMetric("finone_postgres_pool_in_use", "gauge", "PostgreSQL connections in use.", stats.Postgres.InUse)
+	writeMetric("finone_postgres_pool_idle", "gauge", "Idle PostgreSQL connections.", stats.Postgres.Idle)
+	writeMetric("finone_postgres_pool_wait_count_total", "counter", "Queries that waited for a PostgreSQL connection.", stats.Postgres.WaitCount)
+	writeMetric("finone_postgres_pool_wait_seconds_total", "counter", "Time spent waiting for a PostgreSQL connection.", float64(stats.Postgres.WaitDurationMs)/1000)
+	writeMetric("finone_postgres_pool_max_idle_closed_total", "counter", "PostgreSQL connections closed because of max_idle_conns.", stats.Postgres.MaxIdleClosed)
+	writeMetric("finone_postgres_pool_max_idle_time_closed_total", "counter", "PostgreSQL connections closed because of conn_max_idle_time.", stats.Postgres.MaxIdleTimeClosed)
+	writeMetric("finone_postgres_pool_max_lifetime_closed_total", "counter", "PostgreSQL connections closed because of conn_max_lifetime.", stats.Postgres.MaxLifetimeClosed)
+	writeMetric("finone_clickhouse_pool_max_open", "gauge", "Maximum open ClickHouse connections.", stats.ClickHouse.MaxOpenConns)
+	writeMetric("finone_clickhouse_pool_max_idle", "gauge", "Maximum idle ClickHouse connections.", stats.ClickHouse.MaxIdleConns)
+	writeMetric("finone_clickhouse_pool_open", "gauge", "Open ClickHouse connections.", stats.ClickHouse.OpenConns)
+	writeMetric("finone_clickhouse_pool_idle", "gauge", "Idle ClickHouse connections.", stats.ClickHouse.Idle)
+	writeMetric("finone_clickhouse_circuit_open", "gauge", "1 while ClickHouse calls are failing fast.", circuitOpen)
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
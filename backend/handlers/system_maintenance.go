@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SystemMaintenanceHandler struct {
+	systemMaintenanceService *services.SystemMaintenanceService
+}
+
+func NewSystemMaintenanceHandler() *SystemMaintenanceHandler {
+	return &SystemMaintenanceHandler{
+		systemMaintenanceService: services.NewSystemMaintenanceService(),
+	}
+}
+
+// GetMaintenance handles reporting whether maintenance mode is on (admin only)
+func (h *SystemMaintenanceHandler) GetMaintenance(c *gin.Context) {
+	maintenance, err := h.systemMaintenanceService.Get()
+	if err != nil {
+		utils.LogError("Failed to get maintenance mode", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve maintenance mode")
+		return
+	}
+
+	c.JSON(http.StatusOK, maintenance)
+}
+
+// SetMaintenance handles turning maintenance mode on or off (admin only)
+func (h *SystemMaintenanceHandler) SetMaintenance(c *gin.Context) {
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var req models.SystemMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	maintenance, err := h.systemMaintenanceService.Set(&req, adminID)
+	if errors.Is(err, services.ErrInvalidMaintenanceMessage) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to save maintenance mode", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to save maintenance mode")
+		return
+	}
+
+	c.JSON(http.StatusOK, maintenance)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TableMaintenanceHandler struct {
+	maintenanceService *services.TableMaintenanceService
+	schedulerService   *services.SchedulerService
+}
+
+func NewTableMaintenanceHandler() *TableMaintenanceHandler {
+	return &TableMaintenanceHandler{
+		maintenanceService: services.NewTableMaintenanceService(),
+		schedulerService:   services.NewSchedulerService(),
+	}
+}
+
+// OptimizeTable handles starting an OPTIMIZE of a people table now instead of waiting for the
+// nightly run; it runs in the background without a time window (admin only)
+func (h *TableMaintenanceHandler) OptimizeTable(c *gin.Context) {
+	var req models.OptimizeTableRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+			return
+		}
+	}
+
+	var triggeredBy *uuid.UUID
+	if adminID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		triggeredBy = &adminID
+	}
+
+	run, err := h.maintenanceService.StartOptimize(req.Table, triggeredBy, time.Time{})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTable):
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		case errors.Is(err, services.ErrMaintenanceRunning), errors.Is(err, services.ErrImportRunning):
+			abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+		default:
+			utils.LogError("Failed to start table maintenance", err)
+			abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to start table maintenance")
+		}
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Table maintenance of %s triggered by admin %s", run.Table, c.GetString("user_id")))
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetMaintenanceStatus handles reporting the current or last OPTIMIZE run of this instance (admin only)
+func (h *TableMaintenanceHandler) GetMaintenanceStatus(c *gin.Context) {
+	running, run := h.maintenanceService.GetStatus()
+	c.JSON(http.StatusOK, models.TableMaintenanceStatus{
+		Running: running,
+		Run:     run,
+		NextRun: h.schedulerService.GetNextTableMaintenanceTime(),
+	})
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateUpload handles starting a chunked CSV upload (admin only)
+func (h *SearchHandler) CreateUpload(c *gin.Context) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	upload, err := h.chunkedUploadService.CreateUpload(userID, &req)
+	if err != nil {
+		h.writeUploadError(c, "Failed to create upload", err)
+		return
+	}
+	c.JSON(http.StatusCreated, upload)
+}
+
+// GetUpload handles getting the state of a chunked upload, e.g. to resume it (admin only)
+func (h *SearchHandler) GetUpload(c *gin.Context) {
+	userID, uploadID, ok := h.uploadParams(c)
+	if !ok {
+		return
+	}
+
+	upload, err := h.chunkedUploadService.GetUpload(uploadID, userID)
+	if err != nil {
+		h.writeUploadError(c, "Failed to get upload", err)
+		return
+	}
+	c.JSON(http.StatusOK, upload)
+}
+
+// UploadChunk handles appending one chunk to a chunked upload. The body is the raw chunk and the
+// X-Chunk-SHA256 header its SHA-256 checksum (admin only).
+func (h *SearchHandler) UploadChunk(c *gin.Context) {
+	userID, uploadID, ok := h.uploadParams(c)
+	if !ok {
+		return
+	}
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil || index < 0 {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid chunk index")
+		return
+	}
+
+	response, err := h.chunkedUploadService.AppendChunk(uploadID, userID, index, c.GetHeader("X-Chunk-SHA256"), c.Request.Body)
+	if err != nil {
+		h.writeUploadError(c, "Failed to store chunk", err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CompleteUpload handles importing a fully uploaded file. It takes the options of a server path
+// import; an import that is rejected, fails or is only a dry run leaves the upload open so it can be
+// completed again without re-uploading (admin only).
+func (h *SearchHandler) CompleteUpload(c *gin.Context) {
+	userID, uploadID, ok := h.uploadParams(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		BatchSize      int            `json:"batch_size"`
+		HasHeader      bool           `json:"has_header"`
+		FieldMap       map[string]int `json:"field_map"`
+		Delimiter      string         `json:"delimiter"`
+		Encoding       string         `json:"encoding"`
+		Sheet          string         `json:"sheet"` // Sheet of an .xlsx workbook; the first sheet when empty
+		Force          bool           `json:"force"`
+		Table          string         `json:"table"`
+		DatasetID      string         `json:"dataset_id"`
+		DryRun         bool           `json:"dry_run"`
+		SampleRows     int            `json:"sample_rows"`
+		UpdateExisting bool           `json:"update_existing"`
+		Delta          bool           `json:"delta"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+	if req.BatchSize == 0 {
+		req.BatchSize = 200000
+	}
+	if req.UpdateExisting && req.Delta {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, errDeltaWithUpdate.Error())
+		return
+	}
+
+	upload, filePath, checksum, err := h.chunkedUploadService.CompleteUpload(uploadID, userID)
+	if err != nil {
+		h.writeUploadError(c, "Failed to complete upload", err)
+		return
+	}
+
+	var jobID *uuid.UUID
+	defer func() {
+		if err := h.chunkedUploadService.FinishUpload(uploadID, jobID); err != nil {
+			utils.LogError("Failed to finish upload", err)
+		}
+	}()
+
+	processor := utils.NewCSVProcessor(req.BatchSize, "/tmp")
+	if utils.IsWorkbookFile(upload.FileName) {
+		processor.SetWorkbook(req.Sheet)
+	}
+	if err := processor.SetFormat(req.Delimiter, req.Encoding); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err := h.configureImport(processor, req.FieldMap, req.Table, req.DatasetID); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	if req.DryRun {
+		h.dryRunImport(c, processor, filePath, req.HasHeader, req.SampleRows)
+		return
+	}
+
+	previous, err := h.importAuditService.FindPreviousImport(checksum)
+	if err != nil {
+		utils.LogError("Failed to check previous imports", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to check previous imports")
+		return
+	}
+	if previous != nil && !req.Force {
+		abortWithAPIError(c, models.NewAPIError(http.StatusConflict, models.ErrorCodeConflict,
+			"This file has already been imported; set force to import it again").With("previous_import", previous))
+		return
+	}
+
+	endImport, ok := h.beginImport(c)
+	if !ok {
+		return
+	}
+	defer endImport()
+
+	auditID, err := h.importAuditService.RecordImportStart(userID, upload.FileName, filePath, checksum, upload.ReceivedBytes, previous != nil)
+	if err != nil {
+		utils.LogError("Failed to record CSV import", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to record import")
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Starting CSV import of upload %s: %s (sha256 %s) by user %s", uploadID, upload.FileName, checksum, userID))
+
+	processor.SetSource(auditID.String(), upload.FileName)
+	processor.SetUpdateExisting(req.UpdateExisting)
+	processor.SetDelta(req.Delta)
+	progress := trackImport(c, processor, upload.FileName, upload.ReceivedBytes)
+	response, err := processor.ProcessCSVFile(filePath, req.HasHeader)
+	finishImportProgress(progress, response, err)
+	h.recordImportResult(auditID, response, err)
+	if err != nil {
+		utils.LogError("CSV processing failed", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "CSV processing failed")
+		return
+	}
+	jobID = &auditID
+
+	utils.LogInfo("CSV import of upload completed successfully")
+	h.dispatchImportCompleted(upload.FileName, response)
+	c.JSON(http.StatusOK, response)
+}
+
+// AbortUpload handles abandoning a chunked upload and removing its partial file (admin only)
+func (h *SearchHandler) AbortUpload(c *gin.Context) {
+	userID, uploadID, ok := h.uploadParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chunkedUploadService.AbortUpload(uploadID, userID); err != nil {
+		h.writeUploadError(c, "Failed to abort upload", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Upload aborted"})
+}
+
+// uploadParams returns the caller and the upload named in the path, responding if either is invalid
+func (h *SearchHandler) uploadParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	uploadID, err := uuid.Parse(c.Param("upload_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid upload ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, uploadID, true
+}
+
+// writeUploadError maps chunked upload errors to responses
+func (h *SearchHandler) writeUploadError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+	case errors.Is(err, services.ErrInvalidUpload), errors.Is(err, services.ErrInvalidChunk),
+		errors.Is(err, services.ErrChunkChecksumMismatch):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+	case errors.Is(err, services.ErrChunkTooLarge), errors.Is(err, services.ErrUploadTooLarge):
+		abortWithError(c, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, err.Error())
+	case errors.Is(err, services.ErrUploadNotOpen), errors.Is(err, services.ErrChunkOutOfOrder),
+		errors.Is(err, services.ErrChunkConflict), errors.Is(err, services.ErrUploadIncomplete),
+		errors.Is(err, services.ErrUploadChecksumMismatch):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+	default:
+		utils.LogError(message, err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, message)
+	}
+}
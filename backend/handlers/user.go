@@ -1,11 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"finone-search-system/config"
 	"finone-search-system/models"
 	"finone-search-system/services"
 	"finone-search-system/utils"
@@ -15,12 +18,16 @@ import (
 )
 
 type UserHandler struct {
-	authService *services.AuthService
+	authService         *services.AuthService
+	searchCreditService *services.SearchCreditService
+	quotaAdjustments    *services.QuotaAdjustmentService
 }
 
 func NewUserHandler() *UserHandler {
 	return &UserHandler{
-		authService: services.NewAuthService(),
+		authService:         services.NewAuthService(),
+		searchCreditService: services.NewSearchCreditService(),
+		quotaAdjustments:    services.NewQuotaAdjustmentService(),
 	}
 }
 
@@ -31,16 +38,20 @@ func (h *UserHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.LogError("Invalid login request format", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	utils.LogInfo(fmt.Sprintf("Login attempt for email: %s", req.Email))
 
-	response, err := h.authService.Login(req.Email, req.Password)
+	response, err := h.authService.Login(req.Email, req.Password, loginClient(c))
 	if err != nil {
 		utils.LogError("Login failed", err)
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		code := models.ErrorCodeInvalidCredentials
+		if errors.Is(err, services.ErrAccountExpired) {
+			code = models.ErrorCodeAccountExpired
+		}
+		abortWithError(c, http.StatusUnauthorized, code, err.Error())
 		return
 	}
 
@@ -48,18 +59,35 @@ func (h *UserHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// loginClient describes the client of a login request, with its country when a trusted proxy sets
+// server.country_header. Codes other than two letters, and XX for unknown, are ignored.
+func loginClient(c *gin.Context) services.LoginClient {
+	client := services.LoginClient{IPAddress: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+	if header := config.Get().Server.CountryHeader; header != "" {
+		country := strings.ToUpper(strings.TrimSpace(c.GetHeader(header)))
+		if len(country) == 2 && country != "XX" && strings.Trim(country, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "" {
+			client.Location.Country = country
+		}
+	}
+	return client
+}
+
 // CreateUser handles user creation (admin only)
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req models.CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
 		return
 	}
 
 	user, err := h.authService.CreateUser(&req)
+	if errors.Is(err, services.ErrInvalidSearchField) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidField, err.Error())
+		return
+	}
 	if err != nil {
 		utils.LogError("Failed to create user", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create user")
 		return
 	}
 
@@ -67,22 +95,58 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, user)
 }
 
-// GetUsers handles retrieving paginated list of users (admin only)
-func (h *UserHandler) GetUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+// BulkCreateUsers handles creating many users from a JSON body or an uploaded CSV (admin only).
+// Either every user is created or, when any row is invalid, none are and each invalid row is reported.
+func (h *UserHandler) BulkCreateUsers(c *gin.Context) {
+	var req models.BulkCreateUsersRequest
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, _, err := c.Request.FormFile("csv_file")
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "No file provided")
+			return
+		}
+		defer file.Close()
+
+		if req.Users, err = services.ParseBulkUsersCSV(file); err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+			return
+		}
+		req.SendWelcomeEmail = c.DefaultPostForm("send_welcome_email", "false") == "true"
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
 
-	if page < 1 {
-		page = 1
+	response, err := h.authService.BulkCreateUsers(&req)
+	var rowsErr *services.BulkUsersError
+	switch {
+	case errors.As(err, &rowsErr):
+		abortWithAPIError(c, models.NewAPIError(http.StatusBadRequest, models.ErrorCodeInvalidRequest, "No users were created: "+err.Error()).
+			With("errors", rowsErr.Rows))
+		return
+	case errors.Is(err, services.ErrBulkUsersEmpty), errors.Is(err, services.ErrBulkUsersTooMany):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	case err != nil:
+		utils.LogError("Failed to bulk create users", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create users")
+		return
 	}
-	if limit < 1 || limit > 100 {
-		limit = 20
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetUsers handles retrieving paginated list of users (admin only)
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	params, ok := pageParams(c, 20, 100)
+	if !ok {
+		return
 	}
 
-	response, err := h.authService.GetUsers(page, limit)
+	response, err := h.authService.GetUsers(params)
 	if err != nil {
 		utils.LogError("Failed to get users", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve users")
 		return
 	}
 
@@ -94,14 +158,14 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	user, err := h.authService.GetUserByID(userID)
 	if err != nil {
 		utils.LogError("Failed to get user", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
 		return
 	}
 
@@ -113,20 +177,32 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	var req models.UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+	if req.RateLimitPerMinute != nil && *req.RateLimitPerMinute < 0 {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "rate_limit_per_minute must be 0 or more")
+		return
+	}
+	if req.MaxExportRows != nil && *req.MaxExportRows < 0 {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "max_export_rows must be 0 or more")
 		return
 	}
 
 	user, err := h.authService.UpdateUser(userID, &req)
+	if errors.Is(err, services.ErrInvalidSearchField) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidField, err.Error())
+		return
+	}
 	if err != nil {
 		utils.LogError("Failed to update user", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update user")
 		return
 	}
 
@@ -138,20 +214,20 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	user, err := h.authService.GetUserByID(userID)
 	if err != nil {
 		utils.LogError("Failed to get user profile", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
 		return
 	}
 
@@ -162,19 +238,19 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 func (h *UserHandler) Logout(c *gin.Context) {
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User not authenticated")
 		return
 	}
 
 	tokenString, tokenExists := c.Get("token")
 	if !tokenExists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Token not found in context"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Token not found in context")
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
@@ -182,7 +258,8 @@ func (h *UserHandler) Logout(c *gin.Context) {
 	err = h.authService.InvalidateSession(tokenString.(string), userID)
 	if err != nil {
 		utils.LogError("Failed to invalidate session", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout", "details": err.Error()})
+		abortWithAPIError(c, models.NewAPIError(http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to logout").
+			With("details", err.Error()))
 		return
 	}
 
@@ -198,7 +275,7 @@ func (h *UserHandler) GetUserAnalytics(c *gin.Context) {
 	analytics, err := h.authService.GetUserAnalytics()
 	if err != nil {
 		utils.LogError("Failed to get user analytics", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve analytics"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve analytics")
 		return
 	}
 
@@ -210,79 +287,112 @@ func (h *UserHandler) GetUserSearchHistory(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
-	limitStr := c.DefaultQuery("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 || limit > 50 {
-		limit = 10
+	params, ok := pageParams(c, 10, 50)
+	if !ok {
+		return
 	}
 
-	searches, err := h.authService.GetUserRecentSearches(userID, limit)
+	searches, err := h.authService.GetUserSearchHistory(userID, params)
 	if err != nil {
 		utils.LogError("Failed to get user search history", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve search history"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve search history")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"searches": searches})
+	c.JSON(http.StatusOK, searches)
 }
 
 // GetMyAnalytics handles retrieving current user's analytics
 func (h *UserHandler) GetMyAnalytics(c *gin.Context) {
 	userIDStr, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
 		return
 	}
 
 	userID, err := uuid.Parse(userIDStr.(string))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	analytics, err := h.authService.GetUserAnalyticsByID(userID)
 	if err != nil {
 		utils.LogError("Failed to get user analytics", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve analytics"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve analytics")
 		return
 	}
 
 	c.JSON(http.StatusOK, analytics)
 }
 
+// GetMyQuota handles retrieving the current user's search and export usage for today
+func (h *UserHandler) GetMyQuota(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	quota, err := h.authService.GetQuotaUsage(userID)
+	if err != nil {
+		utils.LogError("Failed to get quota usage", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve quota usage")
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
+
 // GetUserSessions handles retrieving user sessions (admin only)
 func (h *UserHandler) GetUserSessions(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	params, ok := pageParams(c, 20, 100)
+	if !ok {
 		return
 	}
 
-	sessions, err := h.authService.GetUserSessions(userID)
+	sessions, err := h.authService.GetUserSessions(userID, params)
 	if err != nil {
 		utils.LogError("Failed to get user sessions", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sessions"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve sessions")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+	c.JSON(http.StatusOK, sessions)
 }
 
 // GetAllActiveSessions handles retrieving all active sessions (admin only)
 func (h *UserHandler) GetAllActiveSessions(c *gin.Context) {
-	sessions, err := h.authService.GetAllActiveSessions()
+	params, ok := pageParams(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.GetAllActiveSessions(params)
 	if err != nil {
 		utils.LogError("Failed to get active sessions", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve sessions"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve sessions")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+	c.JSON(http.StatusOK, sessions)
 }
 
 // InvalidateUserSessions handles invalidating all sessions for a user (admin only)
@@ -290,14 +400,14 @@ func (h *UserHandler) InvalidateUserSessions(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	err = h.authService.InvalidateAllUserSessions(userID)
 	if err != nil {
 		utils.LogError("Failed to invalidate user sessions", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invalidate sessions"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to invalidate sessions")
 		return
 	}
 
@@ -305,12 +415,99 @@ func (h *UserHandler) InvalidateUserSessions(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "All user sessions invalidated successfully"})
 }
 
+// InvalidateSession handles invalidating a single session of any user (admin only)
+func (h *UserHandler) InvalidateSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid session ID")
+		return
+	}
+
+	err = h.authService.InvalidateSessionByID(sessionID, nil)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to invalidate session", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to invalidate session")
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("Admin invalidated session: %s", sessionID.String()))
+	c.JSON(http.StatusOK, gin.H{"message": "Session invalidated successfully"})
+}
+
+// GetMySessions handles listing the current user's sessions
+func (h *UserHandler) GetMySessions(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	params, ok := pageParams(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.GetUserSessions(userID, params)
+	if err != nil {
+		utils.LogError("Failed to get user sessions", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// InvalidateMySession handles logging out one of the current user's sessions, e.g. on a stolen device
+func (h *UserHandler) InvalidateMySession(c *gin.Context) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	userID, err := uuid.Parse(userIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid session ID")
+		return
+	}
+
+	err = h.authService.InvalidateSessionByID(sessionID, &userID)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to invalidate session", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to invalidate session")
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("User %s invalidated session: %s", userID.String(), sessionID.String()))
+	c.JSON(http.StatusOK, gin.H{"message": "Session invalidated successfully"})
+}
+
 // CleanupExpiredSessions handles cleanup of expired sessions (admin only)
 func (h *UserHandler) CleanupExpiredSessions(c *gin.Context) {
 	err := h.authService.CleanupExpiredSessions()
 	if err != nil {
 		utils.LogError("Failed to cleanup expired sessions", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cleanup sessions"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to cleanup sessions")
 		return
 	}
 
@@ -324,7 +521,7 @@ func (h *UserHandler) ResetDailySearchCounts(c *gin.Context) {
 	err := schedulerService.ManualReset()
 	if err != nil {
 		utils.LogError("Failed to reset daily search counts", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset daily search counts"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to reset daily search counts")
 		return
 	}
 
@@ -339,7 +536,7 @@ func (h *UserHandler) ResetUserDailySearchCount(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
@@ -347,7 +544,7 @@ func (h *UserHandler) ResetUserDailySearchCount(c *gin.Context) {
 	user, err := h.authService.GetUserByID(userID)
 	if err != nil {
 		utils.LogError("Failed to get user", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
 		return
 	}
 
@@ -355,7 +552,7 @@ func (h *UserHandler) ResetUserDailySearchCount(c *gin.Context) {
 	err = h.authService.ResetUserDailySearchCount(userID)
 	if err != nil {
 		utils.LogError("Failed to reset user daily search count", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset user daily search count"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to reset user daily search count")
 		return
 	}
 
@@ -371,15 +568,259 @@ func (h *UserHandler) ResetUserDailySearchCount(c *gin.Context) {
 	})
 }
 
+// GrantSearchCredits handles granting a user extra searches for today (admin only)
+func (h *UserHandler) GrantSearchCredits(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	adminIDStr, exists := c.Get("user_id")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+	adminID, err := uuid.Parse(adminIDStr.(string))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.GrantSearchCreditsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	if _, err := h.authService.GetUserByID(userID); err != nil {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
+		return
+	}
+
+	credit, err := h.searchCreditService.GrantSearchCredits(userID, adminID, &req)
+	if err != nil {
+		utils.LogError("Failed to grant search credits", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, credit)
+}
+
+// AdjustQuota handles giving a user extra searches and exports for today only (admin only)
+func (h *UserHandler) AdjustQuota(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User ID not found in context")
+		return
+	}
+
+	var req models.QuotaAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	if _, err := h.authService.GetUserByID(userID); err != nil {
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
+		return
+	}
+
+	adjustment, err := h.quotaAdjustments.AdjustQuota(userID, adminID, &req)
+	if errors.Is(err, services.ErrInvalidQuotaAdjustment) {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		utils.LogError("Failed to adjust quota", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to adjust quota")
+		return
+	}
+
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+// UpgradeUser handles upgrading a DEMO user to PERMANENT (admin only)
+func (h *UserHandler) UpgradeUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.UpgradeUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	var upgradedBy *uuid.UUID
+	if adminID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		upgradedBy = &adminID
+	}
+
+	result, err := h.authService.UpgradeUser(userID, &req, upgradedBy)
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
+		return
+	case errors.Is(err, services.ErrUserNotDemo):
+		abortWithError(c, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+		return
+	case errors.Is(err, services.ErrInvalidUpgrade):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	case err != nil:
+		utils.LogError("Failed to upgrade user", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to upgrade user")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetUserUpgrades handles listing a user's upgrades (admin only)
+func (h *UserHandler) GetUserUpgrades(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	upgrades, err := h.authService.GetUserUpgrades(userID)
+	if err != nil {
+		utils.LogError("Failed to get user upgrades", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get user upgrades")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"upgrades": upgrades})
+}
+
+// ImpersonateUser handles issuing a short-lived session acting as a user, for support (admin only)
+func (h *UserHandler) ImpersonateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	adminID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Invalid user ID")
+		return
+	}
+
+	result, err := h.authService.Impersonate(adminID, userID, req.Reason, c.ClientIP(), c.GetHeader("User-Agent"))
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
+		return
+	case errors.Is(err, services.ErrCannotImpersonate):
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeForbidden, err.Error())
+		return
+	case errors.Is(err, services.ErrImpersonationReason):
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	case err != nil:
+		utils.LogError("Failed to impersonate user", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to impersonate user")
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// GetImpersonations handles listing impersonation sessions, optionally for one user (admin only)
+func (h *UserHandler) GetImpersonations(c *gin.Context) {
+	var userID *uuid.UUID
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+			return
+		}
+		userID = &id
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	impersonations, err := h.authService.GetImpersonations(userID, limit)
+	if err != nil {
+		utils.LogError("Failed to get impersonations", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get impersonations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"impersonations": impersonations})
+}
+
+// GetLogins handles the login audit, optionally for one user or only logins from a new location (admin only)
+func (h *UserHandler) GetLogins(c *gin.Context) {
+	var userID *uuid.UUID
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+			return
+		}
+		userID = &id
+	}
+	newLocationOnly := c.Query("new_location") == "true"
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	logins, err := h.authService.GetLogins(userID, newLocationOnly, limit)
+	if err != nil {
+		utils.LogError("Failed to get logins", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get logins")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logins": logins})
+}
+
+// GetSearchCredits handles retrieving a user's search credits for today (admin only)
+func (h *UserHandler) GetSearchCredits(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
+		return
+	}
+
+	credits, err := h.searchCreditService.GetSearchCredits(userID)
+	if err != nil {
+		utils.LogError("Failed to get search credits", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve search credits")
+		return
+	}
+
+	c.JSON(http.StatusOK, credits)
+}
+
 // GetNextResetTime returns when the next automatic reset will occur (admin only)
 func (h *UserHandler) GetNextResetTime(c *gin.Context) {
 	schedulerService := services.NewSchedulerService()
 	nextReset := schedulerService.GetNextResetTime()
+	timezone, resetTime := schedulerService.GetResetSchedule()
 
 	c.JSON(http.StatusOK, gin.H{
-		"next_reset_time":  nextReset.Format("2006-01-02 15:04:05 IST"),
+		"next_reset_time":  nextReset.Format("2006-01-02 15:04:05 MST"),
 		"next_reset_unix":  nextReset.Unix(),
 		"time_until_reset": time.Until(nextReset).String(),
+		"reset_timezone":   timezone,
+		"reset_time":       resetTime,
 	})
 }
 
@@ -388,20 +829,20 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	userIDStr := c.Param("id")
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid user ID")
 		return
 	}
 
 	// Check if user exists and get their info for logging
 	user, err := h.authService.GetUserByID(userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		abortWithError(c, http.StatusNotFound, models.ErrorCodeNotFound, "User not found")
 		return
 	}
 
 	// Prevent deletion of admin users for safety
 	if user.Role == "ADMIN" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot delete admin users"})
+		abortWithError(c, http.StatusForbidden, models.ErrorCodeForbidden, "Cannot delete admin users")
 		return
 	}
 
@@ -409,7 +850,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	err = h.authService.DeleteUser(userID)
 	if err != nil {
 		utils.LogError("Failed to delete user", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete user")
 		return
 	}
 
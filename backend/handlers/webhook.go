@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+}
+
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: services.NewWebhookService(),
+	}
+}
+
+// GetWebhooks handles listing registered webhooks (admin only)
+func (h *WebhookHandler) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookService.GetWebhooks()
+	if err != nil {
+		utils.LogError("Failed to get webhooks", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+		"count":    len(webhooks),
+	})
+}
+
+// CreateWebhook handles registering a webhook; the signing secret is only returned here (admin only)
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	adminUserInterface, exists := c.Get("user")
+	if !exists {
+		abortWithError(c, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "User not found in context")
+		return
+	}
+
+	adminUser, ok := adminUserInterface.(*models.User)
+	if !ok {
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Invalid user type in context")
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(&req, adminUser.ID)
+	if err != nil {
+		utils.LogError("Failed to create webhook", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	utils.LogInfo("Webhook created: " + webhook.ID.String())
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// UpdateWebhook handles changing a webhook's URL, secret, events or active state (admin only)
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid webhook ID")
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid request format")
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(id, &req)
+	if err != nil {
+		utils.LogError("Failed to update webhook", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles removing a webhook and its delivery history (admin only)
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid webhook ID")
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(id); err != nil {
+		utils.LogError("Failed to delete webhook", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	utils.LogInfo("Webhook deleted: " + idStr)
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetDeliveries handles listing recent deliveries of a webhook (admin only)
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid webhook ID")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	deliveries, err := h.webhookService.GetDeliveries(id, limit)
+	if err != nil {
+		utils.LogError("Failed to get webhook deliveries", err)
+		abortWithError(c, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"count":      len(deliveries),
+	})
+}
+
+// RetryDelivery handles sending a pending or failed delivery again (admin only)
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	webhookID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid webhook ID")
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.webhookService.RetryDelivery(webhookID, deliveryID)
+	if err != nil {
+		utils.LogError("Failed to retry webhook delivery", err)
+		abortWithError(c, http.StatusBadRequest, models.ErrorCodeInvalidRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
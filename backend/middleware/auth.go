@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"finone-search-system/models"
 	"finone-search-system/services"
+	"finone-search-system/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,24 +16,25 @@ import (
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		// Browsers cannot set headers on WebSocket connections, so those may pass the token as ?token=
+		if authHeader == "" && isWebSocketUpgrade(c) && c.Query("token") != "" {
+			authHeader = "Bearer " + c.Query("token")
+		}
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization header"})
-			c.Abort()
+			AbortWithError(c, models.NewAPIError(http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Missing authorization header"))
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+			AbortWithError(c, models.NewAPIError(http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Invalid authorization header format"))
 			return
 		}
 
 		authService := services.NewAuthService()
 		user, err := authService.ValidateSession(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid session", "details": err.Error()})
-			c.Abort()
+			AbortWithError(c, sessionError(err))
 			return
 		}
 
@@ -41,17 +45,48 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Set("user", user)         // Store full user object for convenience
 		c.Set("token", tokenString) // Store token for logout
 
+		// Flag impersonation sessions, so handlers and logs know the real admin
+		if adminID := authService.Impersonator(tokenString); adminID != nil {
+			c.Set("impersonated_by", adminID.String())
+			c.Header("X-Impersonated-By", adminID.String())
+		}
+
+		// Keep the session alive for the idle timeout and hand out a fresh token when it is extended
+		if newToken, err := authService.RecordActivity(tokenString, user); err != nil {
+			utils.LogError("Failed to record session activity", err)
+		} else if newToken != "" {
+			c.Header("X-Session-Token", newToken)
+		}
+
 		c.Next()
 	}
 }
 
+// isWebSocketUpgrade reports whether the request opens a WebSocket
+func isWebSocketUpgrade(c *gin.Context) bool {
+	return strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+}
+
+// sessionError maps why a session was rejected to an error code, keeping the reason in details
+func sessionError(err error) *models.APIError {
+	code := models.ErrorCodeUnauthorized
+	switch {
+	case errors.Is(err, services.ErrSessionExpired):
+		code = models.ErrorCodeSessionExpired
+	case errors.Is(err, services.ErrAccountExpired):
+		code = models.ErrorCodeAccountExpired
+	case errors.Is(err, services.ErrInvalidToken):
+		code = models.ErrorCodeInvalidToken
+	}
+	return models.NewAPIError(http.StatusUnauthorized, code, "Invalid session").With("details", err.Error())
+}
+
 // AdminMiddleware ensures the user has admin role
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists || role != "ADMIN" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
+			AbortWithError(c, models.NewAPIError(http.StatusForbidden, models.ErrorCodeForbidden, "Admin access required"))
 			return
 		}
 
@@ -59,11 +94,33 @@ func AdminMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware implements basic rate limiting (simplified version)
-func RateLimitMiddleware() gin.HandlerFunc {
+// AuthorizationMiddleware enforces the route permission table for the authenticated user's role.
+// Routes without an entry in the table are denied.
+func AuthorizationMiddleware() gin.HandlerFunc {
+	authorizationService := services.NewAuthorizationService()
+
 	return func(c *gin.Context) {
-		// TODO: Implement proper rate limiting with Redis or in-memory store
-		// For now, this is a placeholder
+		permission, ok := authorizationService.RequiredPermission(c.Request.Method, c.FullPath())
+		if !ok {
+			AbortWithError(c, models.NewAPIError(http.StatusForbidden, models.ErrorCodeForbidden, "Access to this route is not configured"))
+			return
+		}
+
+		role, _ := c.Get("role")
+		roleStr, _ := role.(string)
+		if !authorizationService.HasPermission(roleStr, permission) {
+			AbortWithError(c, models.NewAPIError(http.StatusForbidden, models.ErrorCodeForbidden, "Insufficient permissions").
+				With("required_permission", permission))
+			return
+		}
+
+		// Impersonation sessions only see what the user sees
+		if c.GetString("impersonated_by") != "" && !services.AllowedWhileImpersonating(permission) {
+			AbortWithError(c, models.NewAPIError(http.StatusForbidden, models.ErrorCodeForbidden, "Not allowed while impersonating a user").
+				With("required_permission", permission))
+			return
+		}
+
 		c.Next()
 	}
 }
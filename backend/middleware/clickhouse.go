@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireClickHouse fails requests fast with 503 and a Retry-After header while the ClickHouse circuit
+// breaker is open, instead of letting them wait on a database that is known to be down
+func RequireClickHouse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if database.ClickHouseCircuitOpen() {
+			SetRetryAfter(c, database.ClickHouseRetryAfter())
+			AbortWithError(c, models.NewAPIError(http.StatusServiceUnavailable, models.ErrorCodeServiceUnavailable,
+				"Search is temporarily unavailable, please retry later"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetRetryAfter sets the Retry-After header to wait, rounded up to whole seconds
+func SetRetryAfter(c *gin.Context, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
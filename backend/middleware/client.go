@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"time"
+
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientTrackingMiddleware identifies the calling application from the X-Client-Id header, stores it
+// in the context as "client_id" and records the request in the per-client usage analytics
+func ClientTrackingMiddleware() gin.HandlerFunc {
+	clientAnalyticsService := services.NewClientAnalyticsService()
+
+	return func(c *gin.Context) {
+		clientID := services.NormalizeClientID(c.GetHeader(services.ClientIDHeader))
+		c.Set("client_id", clientID)
+
+		start := time.Now()
+		c.Next()
+
+		clientAnalyticsService.RecordRequest(clientID, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -1,40 +1,48 @@
 package middleware
 
 import (
-	"os"
-	"strings"
+	"sync/atomic"
+
+	"finone-search-system/config"
+	"finone-search-system/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// CORSMiddleware allows the frontend origins in server.cors_origins (or FRONTEND_URL). The handler is
+// rebuilt when the configuration is reloaded, so origin changes apply without a restart.
 func CORSMiddleware() gin.HandlerFunc {
-	config := cors.DefaultConfig()
-
-	// Get frontend URL from environment variable
-	frontendURL := os.Getenv("FRONTEND_URL")
-	if frontendURL == "" {
-		// Default for development
-		frontendURL = "http://localhost:3000"
+	var handler atomic.Pointer[gin.HandlerFunc]
+	build := func(cfg *config.Config) {
+		corsConfig := newCORSConfig(cfg.Server.CORSOrigins)
+		if err := corsConfig.Validate(); err != nil {
+			utils.LogError("Invalid CORS origins, keeping the previous ones", err)
+			return
+		}
+		h := cors.New(corsConfig)
+		handler.Store(&h)
 	}
 
-	// Support multiple frontend URLs (comma-separated)
-	allowedOrigins := strings.Split(frontendURL, ",")
-	for i, origin := range allowedOrigins {
-		allowedOrigins[i] = strings.TrimSpace(origin)
+	build(config.Get())
+	if handler.Load() == nil {
+		h := cors.New(newCORSConfig(nil))
+		handler.Store(&h)
 	}
+	config.OnReload(func(_, next *config.Config) { build(next) })
 
-	// Add common production domains if not explicitly set
-	if frontendURL == "http://localhost:3000" {
-		// If only default is set, add production domains
-		allowedOrigins = append(allowedOrigins,
-			
-		"https://finoneweb.nikhilsahni.xyz",
-			"https://finone.nikhilsahni.xyz",
-		)
+	return func(c *gin.Context) {
+		(*handler.Load())(c)
 	}
+}
 
-	config.AllowOrigins = allowedOrigins
+func newCORSConfig(origins []string) cors.Config {
+	config := cors.DefaultConfig()
+	config.AllowOrigins = origins
+	if len(origins) == 0 {
+		// Default for development
+		config.AllowOrigins = []string{"http://localhost:3000"}
+	}
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 	config.AllowHeaders = []string{
 		"Origin",
@@ -42,6 +50,8 @@ func CORSMiddleware() gin.HandlerFunc {
 		"Accept",
 		"Authorization",
 		"X-Requested-With",
+		"X-Client-Id",
+		"X-Request-Id",
 		"Access-Control-Allow-Headers",
 		"Access-Control-Allow-Origin",
 		"Access-Control-Allow-Methods",
@@ -51,7 +61,14 @@ func CORSMiddleware() gin.HandlerFunc {
 		"Content-Length",
 		"Content-Type",
 		"Content-Disposition",
+		"X-Search-Quota-Limit",
+		"X-Search-Quota-Remaining",
+		"X-Session-Token",
+		"X-Request-Id",
+		"X-Search-Id",
+		"X-RateLimit-Limit",
+		"X-RateLimit-Remaining",
+		"Retry-After",
 	}
-
-	return cors.New(config)
+	return config
 }
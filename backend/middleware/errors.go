@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"errors"
+
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMiddleware renders the error a handler or middleware recorded with AbortWithError once the
+// chain returns, as {"error": message, "code": code}. Other recorded errors are logged and rendered
+// as internal errors, without their details.
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var apiErr *models.APIError
+		if !errors.As(err, &apiErr) {
+			utils.LogError("Unhandled request error", err)
+			apiErr = models.InternalError()
+		}
+		c.JSON(apiErr.Status, apiErr.Body())
+	}
+}
+
+// AbortWithError stops the handler chain and records err for ErrorMiddleware to render
+func AbortWithError(c *gin.Context, err *models.APIError) {
+	c.Error(err)
+	c.Abort()
+}
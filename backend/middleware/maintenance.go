@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"finone-search-system/models"
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceMode answers requests with 503 and the maintenance message while maintenance mode is on.
+// Admins, including admins impersonating a user, keep access, so it must run after AuthMiddleware on
+// authenticated routes; on public routes every request is turned away.
+func MaintenanceMode() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		message, enabled := services.MaintenanceMessage()
+		if !enabled || c.GetString("role") == "ADMIN" || c.GetString("impersonated_by") != "" {
+			c.Next()
+			return
+		}
+
+		AbortWithError(c, models.NewAPIError(http.StatusServiceUnavailable, models.ErrorCodeMaintenance, message))
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitSweepInterval is how often buckets that have refilled are dropped
+const rateLimitSweepInterval = time.Minute
+
+// requestLimiter holds the request buckets of every transport, so a user's JSON and gRPC calls share
+// their limits
+var requestLimiter = newRateLimiter()
+
+// RateLimitMiddleware limits requests per user, or per client IP before login, to rate_limit in the
+// configuration (see AllowRequest). It must run after AuthMiddleware to see the user.
+func RateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user *models.User
+		if value, ok := c.Get("user"); ok {
+			user, _ = value.(*models.User)
+		}
+
+		allowed, limit, remaining, wait := AllowRequest(user, c.ClientIP(), c.Request.Method+" "+c.FullPath())
+		if limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+		if !allowed {
+			abortRateLimited(c, wait)
+			return
+		}
+		c.Next()
+	}
+}
+
+// AllowRequest applies rate_limit to a request by user, or by client IP when user is nil, to route:
+// "METHOD /path" for the JSON API or the full method name for gRPC. The limit comes from the user's
+// rate_limit_per_minute, else the limit for their user type, else the default; a configured route limit
+// must also allow the request. A refused request spends no tokens. Reports whether the request is
+// allowed, the limit and the requests left (0 when rate limiting is off) and, when refused, how long
+// until it would be allowed. Limits are kept in memory, so each server instance enforces them on its own.
+func AllowRequest(user *models.User, ip, route string) (bool, int, int, time.Duration) {
+	cfg := config.Get().RateLimit
+	if !cfg.Enabled {
+		return true, 0, 0, 0
+	}
+
+	subject := "ip:" + ip
+	perMinute := cfg.RequestsPerMinute
+	if user != nil {
+		subject = "user:" + user.ID.String()
+		if limit, ok := cfg.UserTypes[user.UserType]; ok && limit > 0 {
+			perMinute = limit
+		}
+		if user.RateLimitPerMinute != nil && *user.RateLimitPerMinute > 0 {
+			perMinute = *user.RateLimitPerMinute
+		}
+	}
+
+	limits := []bucketLimit{{key: subject, perMinute: perMinute, burst: burstOrLimit(cfg.Burst, perMinute)}}
+	for _, rule := range cfg.Routes {
+		if rule.Route == route && rule.RequestsPerMinute > 0 {
+			limits = append(limits, bucketLimit{key: route + "|" + subject, perMinute: rule.RequestsPerMinute,
+				burst: burstOrLimit(rule.Burst, rule.RequestsPerMinute)})
+		}
+	}
+
+	allowed, remaining, wait := requestLimiter.take(time.Now(), limits...)
+	return allowed, perMinute, remaining, wait
+}
+
+func burstOrLimit(burst, perMinute int) int {
+	if burst > 0 {
+		return burst
+	}
+	return perMinute
+}
+
+func abortRateLimited(c *gin.Context, wait time.Duration) {
+	SetRetryAfter(c, wait)
+	AbortWithError(c, models.NewAPIError(http.StatusTooManyRequests, models.ErrorCodeRateLimited, "Too many requests, please slow down"))
+}
+
+// rateLimiter holds a token bucket per key
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// bucketLimit is the bucket a request spends a token from and the rate it refills at
+type bucketLimit struct {
+	key       string
+	perMinute int
+	burst     int
+}
+
+type tokenBucket struct {
+	tokens   float64
+	capacity float64
+	rate     float64 // Tokens per second
+	updated  time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket), lastSweep: time.Now()}
+}
+
+// take spends a token from each of the buckets, which refill at perMinute tokens per minute up to
+// burst, or from none of them when any is empty. It reports whether the tokens were available, how
+// many whole tokens the first bucket has left and, when they were not, how long until they are.
+func (l *rateLimiter) take(now time.Time, limits ...bucketLimit) (bool, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	buckets := make([]*tokenBucket, len(limits))
+	var wait time.Duration
+	for i, limit := range limits {
+		bucket, ok := l.buckets[limit.key]
+		if !ok {
+			bucket = &tokenBucket{tokens: float64(limit.burst), updated: now}
+			l.buckets[limit.key] = bucket
+		}
+		// Limits can change on a config reload or user update; the bucket follows the current one
+		bucket.capacity = float64(limit.burst)
+		bucket.rate = float64(limit.perMinute) / 60
+		bucket.refill(now)
+		buckets[i] = bucket
+
+		if bucket.tokens < 1 {
+			wait = max(wait, time.Duration((1-bucket.tokens)/bucket.rate*float64(time.Second)))
+		}
+	}
+	if wait > 0 {
+		return false, 0, wait
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true, int(math.Floor(buckets[0].tokens)), 0
+}
+
+// sweep drops buckets that have refilled completely, since a new bucket starts full
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+	}
+	b.updated = now
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"finone-search-system/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDMiddleware takes the request ID from the X-Request-Id header, or generates one, stores it
+// in the context as "request_id" and echoes it in the response, so a frontend can correlate its call
+// with the search log and performance log
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := services.NormalizeRequestID(c.GetHeader(services.RequestIDHeader))
+		c.Set("request_id", requestID)
+		c.Header(services.RequestIDHeader, requestID)
+		c.Next()
+	}
+}
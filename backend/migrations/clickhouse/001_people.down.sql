@@ -0,0 +1 @@
+DROP TABLE IF EXISTS finone_search.people;
@@ -0,0 +1,33 @@
+-- Main people table optimized for search
+CREATE TABLE IF NOT EXISTS finone_search.people
+(
+    id UUID DEFAULT generateUUIDv4(),
+    master_id String,
+    mobile String,
+    name String,
+    fname String,
+    address String,
+    alt String,
+    circle String,
+    email String,
+    -- Materialized pincode extracted from address for fast filtering (first 6-digit token)
+    pincode String MATERIALIZED arrayFirst(x -> length(x) = 6, extractAll(address, '\\d+')),
+    created_at DateTime DEFAULT now(),
+    updated_at DateTime DEFAULT now(),
+    -- Secondary indexes for accelerating LIKE/ILIKE searches
+    INDEX idx_name_ngram name TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4,
+    INDEX idx_fname_ngram fname TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4,
+    INDEX idx_address_ngram address TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4,
+    INDEX idx_email_token email TYPE tokenbf_v1(1024) GRANULARITY 4,
+    INDEX idx_circle_token circle TYPE tokenbf_v1(1024) GRANULARITY 4,
+    INDEX idx_mobile_token mobile TYPE tokenbf_v1(1024) GRANULARITY 4,
+    INDEX idx_alt_token alt TYPE tokenbf_v1(1024) GRANULARITY 4,
+    INDEX idx_master_id_token master_id TYPE tokenbf_v1(1024) GRANULARITY 4,
+    -- Bloom filter index for exact pincode matches
+    INDEX idx_pincode_bf pincode TYPE bloom_filter GRANULARITY 4
+)
+ENGINE = MergeTree()
+ORDER BY (mobile, name, master_id)
+SETTINGS index_granularity = 8192,
+         max_compress_block_size = 1048576,
+         min_compress_block_size = 65536;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS finone_search.search_performance
+(
+    query_id String,
+    user_id String,
+    query_text String,
+    execution_time_ms UInt32,
+    result_count UInt32,
+    timestamp DateTime DEFAULT now()
+)
+ENGINE = MergeTree()
+ORDER BY timestamp;
@@ -0,0 +1 @@
+-- The pincode column and index are part of the table definition in 001, so there is nothing to revert
@@ -0,0 +1,5 @@
+-- Add the materialized pincode column to tables created before it existed
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS pincode String MATERIALIZED arrayFirst(x -> length(x) = 6, extractAll(address, '\\d+'));
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_pincode_bf pincode TYPE bloom_filter GRANULARITY 4;
+ALTER TABLE finone_search.people MATERIALIZE COLUMN pincode;
+ALTER TABLE finone_search.people MATERIALIZE INDEX idx_pincode_bf;
@@ -0,0 +1 @@
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS confidence;
@@ -0,0 +1,10 @@
+-- Data quality score (0-100) computed by the importer, see utils.ConfidenceScore
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS confidence UInt8 DEFAULT 0;
+-- Backfill records imported before the column existed using the same weights as the importer:
+-- completeness 40, valid mobile 25, valid master ID 20, recency 15 (7 within three years)
+ALTER TABLE finone_search.people UPDATE confidence =
+    intDiv(40 * ((name != '') + (fname != '') + (address != '') + (alt != '') + (circle != '') + (email != '')), 6)
+    + if(match(replaceRegexpOne(replaceRegexpAll(mobile, '\\D', ''), '^(91(\\d{10})|0(\\d{10}))$', '\\2\\3'), '^[6-9]\\d{9}$'), 25, 0)
+    + if(positionCaseInsensitive(master_id, 'x') = 0 AND length(master_id) >= 8 AND match(master_id, '^\\d+[A-Za-z]*$'), 20, 0)
+    + multiIf(updated_at >= now() - INTERVAL 1 YEAR, 15, updated_at >= now() - INTERVAL 3 YEAR, 7, 0)
+WHERE confidence = 0;
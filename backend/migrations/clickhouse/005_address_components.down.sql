@@ -0,0 +1,6 @@
+ALTER TABLE finone_search.people DROP INDEX IF EXISTS idx_state_ngram;
+ALTER TABLE finone_search.people DROP INDEX IF EXISTS idx_city_ngram;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS state;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS city;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS locality;
+DROP TABLE IF EXISTS finone_search.pincode_directory;
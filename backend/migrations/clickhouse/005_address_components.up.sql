@@ -0,0 +1,18 @@
+-- Pincode directory used to derive locality, city and state from the pincode in the address.
+-- A Join table is an in-memory key-value dictionary that joinGet can read without credentials.
+-- Loaded with POST /api/v1/admin/import/pincodes, which replaces its contents.
+CREATE TABLE IF NOT EXISTS finone_search.pincode_directory
+(
+    pincode String,
+    locality String,
+    city String,
+    state String
+)
+ENGINE = Join(ANY, LEFT, pincode);
+
+-- Address components are computed at insert time from the materialized pincode
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS locality String MATERIALIZED joinGet('finone_search.pincode_directory', 'locality', pincode);
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS city String MATERIALIZED joinGet('finone_search.pincode_directory', 'city', pincode);
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS state String MATERIALIZED joinGet('finone_search.pincode_directory', 'state', pincode);
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_city_ngram city TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4;
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_state_ngram state TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4;
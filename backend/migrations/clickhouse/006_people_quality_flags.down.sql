@@ -0,0 +1,2 @@
+ALTER TABLE finone_search.people DROP INDEX IF EXISTS idx_quality_flags_bf;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS quality_flags;
@@ -0,0 +1,4 @@
+-- Consistency flags raised by the importer, see utils.CheckConsistency. Rows imported before the
+-- column existed stay unflagged until POST /api/v1/admin/quality/revalidate runs.
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS quality_flags Array(LowCardinality(String)) DEFAULT [];
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_quality_flags_bf quality_flags TYPE bloom_filter GRANULARITY 4;
@@ -0,0 +1 @@
+ALTER TABLE finone_search.search_performance REMOVE TTL;
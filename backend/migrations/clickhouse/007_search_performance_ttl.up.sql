@@ -0,0 +1,3 @@
+-- Expire search timings after the default retention; the retention service keeps the TTL in line with
+-- retention.search_performance_days at startup
+ALTER TABLE finone_search.search_performance MODIFY TTL timestamp + INTERVAL 90 DAY;
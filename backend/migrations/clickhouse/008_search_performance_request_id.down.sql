@@ -0,0 +1 @@
+ALTER TABLE finone_search.search_performance DROP COLUMN IF EXISTS request_id;
@@ -0,0 +1,2 @@
+-- Request ID of the API call, matching searches.request_id in PostgreSQL
+ALTER TABLE finone_search.search_performance ADD COLUMN IF NOT EXISTS request_id String DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE finone_search.people DROP INDEX IF EXISTS idx_import_job_id_bf;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS imported_at;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS import_job_id;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS source_file;
@@ -0,0 +1,6 @@
+-- Provenance of imported rows, set by the CSV importer so a bad import can be found and rolled back.
+-- Rows created by admins, or imported before the columns existed, have an empty import_job_id.
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS source_file String DEFAULT '';
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS import_job_id String DEFAULT '';
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS imported_at Nullable(DateTime);
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_import_job_id_bf import_job_id TYPE bloom_filter GRANULARITY 4;
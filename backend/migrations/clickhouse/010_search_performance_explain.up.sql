@@ -0,0 +1,2 @@
+-- EXPLAIN indexes = 1 output captured for searches slower than search.slow_query_threshold
+ALTER TABLE finone_search.search_performance ADD COLUMN IF NOT EXISTS explain_plan String DEFAULT '';
@@ -0,0 +1,30 @@
+-- Prior versions of people rows. A row is copied here before an import with update_existing or a
+-- manual edit replaces it, or a manual delete removes it, so GET /api/v1/search/person/:id/history
+-- can show what a person looked like before and which import wrote each version.
+CREATE TABLE IF NOT EXISTS finone_search.people_history
+(
+    id UUID,
+    master_id String,
+    mobile String,
+    name String,
+    fname String,
+    address String,
+    alt String,
+    circle String,
+    email String,
+    created_at DateTime,
+    updated_at DateTime,
+    confidence UInt8 DEFAULT 0,
+    quality_flags Array(LowCardinality(String)) DEFAULT [],
+    source_file String DEFAULT '',
+    import_job_id String DEFAULT '',
+    imported_at Nullable(DateTime),
+    -- The people table the row was in, what replaced it and when
+    people_table LowCardinality(String),
+    change LowCardinality(String),
+    superseded_at DateTime DEFAULT now(),
+    -- The import that replaced the row; empty for manual edits and deletes
+    superseded_by_job_id String DEFAULT ''
+)
+ENGINE = MergeTree()
+ORDER BY (id, superseded_at);
@@ -0,0 +1,4 @@
+ALTER TABLE finone_search.people_history DROP COLUMN IF EXISTS alt_raw;
+ALTER TABLE finone_search.people_history DROP COLUMN IF EXISTS mobile_raw;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS alt_raw;
+ALTER TABLE finone_search.people DROP COLUMN IF EXISTS mobile_raw;
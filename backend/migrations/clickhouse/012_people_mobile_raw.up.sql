@@ -0,0 +1,7 @@
+-- Imports store mobile and alt numbers in their canonical 10 digit form (see utils.NormalizeMobile) and
+-- keep the values as they appeared in the file here. Rows imported before the columns existed keep
+-- their numbers as imported, which searches still match through utils.MobileVariants.
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS mobile_raw String DEFAULT '';
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS alt_raw String DEFAULT '';
+ALTER TABLE finone_search.people_history ADD COLUMN IF NOT EXISTS mobile_raw String DEFAULT '';
+ALTER TABLE finone_search.people_history ADD COLUMN IF NOT EXISTS alt_raw String DEFAULT '';
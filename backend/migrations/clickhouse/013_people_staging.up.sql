@@ -0,0 +1,31 @@
+-- Changed rows found by delta imports, held for review. A delta import inserts rows whose mobile and
+-- master ID are new, skips rows identical to the existing row and stages the rest here with the ID and
+-- created_at of the row they would replace, until an admin applies or discards them at
+-- /api/v1/admin/import/jobs/:job_id/changes.
+CREATE TABLE IF NOT EXISTS finone_search.people_staging
+(
+    id UUID,
+    master_id String,
+    mobile String,
+    name String,
+    fname String,
+    address String,
+    alt String,
+    circle String,
+    email String,
+    created_at DateTime,
+    updated_at DateTime,
+    confidence UInt8 DEFAULT 0,
+    quality_flags Array(LowCardinality(String)) DEFAULT [],
+    source_file String DEFAULT '',
+    import_job_id String DEFAULT '',
+    imported_at Nullable(DateTime),
+    mobile_raw String DEFAULT '',
+    alt_raw String DEFAULT '',
+    -- The people table the row would be applied to, and the fields that differ from its current row
+    people_table LowCardinality(String),
+    changed_fields Array(LowCardinality(String)),
+    staged_at DateTime DEFAULT now()
+)
+ENGINE = MergeTree()
+ORDER BY (import_job_id, id);
@@ -0,0 +1 @@
+-- The columns and indexes belong to the migrations that introduced them; nothing to roll back
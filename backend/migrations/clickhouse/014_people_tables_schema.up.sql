@@ -0,0 +1,19 @@
+-- Bring people tables created for a switchover before people schema changes were applied to every
+-- people table up to date. The migrator repeats each statement on those tables; on people itself
+-- they change nothing.
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS pincode String MATERIALIZED arrayFirst(x -> length(x) = 6, extractAll(address, '\\d+'));
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_pincode_bf pincode TYPE bloom_filter GRANULARITY 4;
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS confidence UInt8 DEFAULT 0;
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS locality String MATERIALIZED joinGet('finone_search.pincode_directory', 'locality', pincode);
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS city String MATERIALIZED joinGet('finone_search.pincode_directory', 'city', pincode);
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS state String MATERIALIZED joinGet('finone_search.pincode_directory', 'state', pincode);
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_city_ngram city TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4;
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_state_ngram state TYPE ngrambf_v1(3, 256, 2) GRANULARITY 4;
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS quality_flags Array(LowCardinality(String)) DEFAULT [];
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_quality_flags_bf quality_flags TYPE bloom_filter GRANULARITY 4;
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS source_file String DEFAULT '';
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS import_job_id String DEFAULT '';
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS imported_at Nullable(DateTime);
+ALTER TABLE finone_search.people ADD INDEX IF NOT EXISTS idx_import_job_id_bf import_job_id TYPE bloom_filter GRANULARITY 4;
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS mobile_raw String DEFAULT '';
+ALTER TABLE finone_search.people ADD COLUMN IF NOT EXISTS alt_raw String DEFAULT '';
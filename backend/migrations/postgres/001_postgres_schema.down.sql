@@ -0,0 +1,6 @@
+-- Drop the core schema (removes all users and their history)
+DROP TABLE IF EXISTS daily_usage;
+DROP TABLE IF EXISTS exports;
+DROP TABLE IF EXISTS searches;
+DROP TABLE IF EXISTS logins;
+DROP TABLE IF EXISTS users CASCADE;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trigger_update_user_registration_requests_updated_at ON user_registration_requests;
+DROP FUNCTION IF EXISTS update_user_registration_requests_updated_at();
+DROP TABLE IF EXISTS user_registration_requests;
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trigger_update_user_password_change_requests_updated_at ON user_password_change_requests;
+DROP FUNCTION IF EXISTS update_user_password_change_requests_updated_at();
+DROP TABLE IF EXISTS user_password_change_requests;
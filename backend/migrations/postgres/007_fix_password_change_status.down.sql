@@ -0,0 +1 @@
+-- The constraint restored by this migration matches the one created in 006, so there is nothing to revert
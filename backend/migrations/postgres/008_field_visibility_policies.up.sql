@@ -0,0 +1,15 @@
+-- Field visibility policies: columns masked in search results and exports for a user or user type
+CREATE TABLE IF NOT EXISTS field_visibility_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    user_type TEXT CHECK (user_type IN ('DEMO', 'PERMANENT')),
+    masked_fields TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT now(),
+    updated_at TIMESTAMP DEFAULT now(),
+    -- A policy targets exactly one user or one user type
+    CHECK ((user_id IS NOT NULL) <> (user_type IS NOT NULL))
+);
+
+-- One policy per user and one per user type; user policies take precedence
+CREATE UNIQUE INDEX IF NOT EXISTS idx_field_visibility_policies_user ON field_visibility_policies(user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_field_visibility_policies_user_type ON field_visibility_policies(user_type) WHERE user_type IS NOT NULL;
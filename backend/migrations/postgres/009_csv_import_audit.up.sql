@@ -0,0 +1,20 @@
+-- Audit trail of server-side CSV imports: who imported which file, and its checksum for replay detection
+CREATE TABLE IF NOT EXISTS csv_import_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    requested_path TEXT NOT NULL,
+    resolved_path TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    file_size BIGINT NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'STARTED' CHECK (status IN ('STARTED', 'COMPLETED', 'FAILED')),
+    forced BOOLEAN NOT NULL DEFAULT false,
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    error_rows INTEGER NOT NULL DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMP DEFAULT now(),
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_csv_import_audit_checksum ON csv_import_audit(checksum);
+CREATE INDEX IF NOT EXISTS idx_csv_import_audit_started_at ON csv_import_audit(started_at DESC);
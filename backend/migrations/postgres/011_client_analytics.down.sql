@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS client_request_stats;
+DROP INDEX IF EXISTS idx_searches_client_id;
+ALTER TABLE searches DROP COLUMN IF EXISTS client_id;
@@ -0,0 +1,16 @@
+-- Client application (X-Client-Id header) that issued each search
+ALTER TABLE searches ADD COLUMN IF NOT EXISTS client_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_searches_client_id ON searches(client_id, search_time);
+
+-- Daily API request counters per client application
+CREATE TABLE IF NOT EXISTS client_request_stats (
+    client_id TEXT NOT NULL,
+    date DATE NOT NULL,
+    request_count BIGINT NOT NULL DEFAULT 0,
+    error_count BIGINT NOT NULL DEFAULT 0,
+    total_duration_ms BIGINT NOT NULL DEFAULT 0,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (client_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_client_request_stats_date ON client_request_stats(date);
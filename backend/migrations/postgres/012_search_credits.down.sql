@@ -0,0 +1,2 @@
+ALTER TABLE daily_usage DROP COLUMN IF EXISTS credits_used;
+DROP TABLE IF EXISTS search_credits;
@@ -0,0 +1,15 @@
+-- One-off extra searches granted to a user for a single quota day, on top of max_searches_per_day
+CREATE TABLE IF NOT EXISTS search_credits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    credits INTEGER NOT NULL CHECK (credits > 0),
+    reason TEXT,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_credits_user_date ON search_credits(user_id, date);
+
+-- Searches beyond max_searches_per_day that were paid for with credits
+ALTER TABLE daily_usage ADD COLUMN IF NOT EXISTS credits_used INTEGER NOT NULL DEFAULT 0;
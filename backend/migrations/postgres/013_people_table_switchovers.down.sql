@@ -0,0 +1 @@
+DROP TABLE IF EXISTS people_table_switchovers;
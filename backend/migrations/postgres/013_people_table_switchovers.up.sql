@@ -0,0 +1,11 @@
+-- History of ClickHouse people table switchovers; the latest row names the active table
+CREATE TABLE IF NOT EXISTS people_table_switchovers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    from_table TEXT NOT NULL,
+    to_table TEXT NOT NULL,
+    row_count BIGINT NOT NULL DEFAULT 0,
+    switched_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    switched_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_people_table_switchovers_time ON people_table_switchovers(switched_at DESC);
@@ -0,0 +1,5 @@
+ALTER TABLE exports DROP COLUMN IF EXISTS regenerated_from;
+ALTER TABLE exports DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE exports DROP COLUMN IF EXISTS search_query;
+ALTER TABLE exports DROP COLUMN IF EXISTS format;
+ALTER TABLE exports DROP COLUMN IF EXISTS file_name;
@@ -0,0 +1,6 @@
+-- Keep what is needed to regenerate an export once its file has expired or been cleaned up
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS file_name TEXT;
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS format TEXT;
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS search_query JSONB;
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS regenerated_from UUID REFERENCES exports(id) ON DELETE SET NULL;
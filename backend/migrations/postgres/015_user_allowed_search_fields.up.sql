@@ -0,0 +1,2 @@
+-- Fields a user may search on; empty allows every field
+ALTER TABLE users ADD COLUMN IF NOT EXISTS allowed_search_fields TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE searches DROP COLUMN IF EXISTS is_diagnostic;
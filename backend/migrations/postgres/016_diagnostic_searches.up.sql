@@ -0,0 +1,2 @@
+-- Admin diagnostic searches are logged but never count against the daily search quota
+ALTER TABLE searches ADD COLUMN IF NOT EXISTS is_diagnostic BOOLEAN NOT NULL DEFAULT false;
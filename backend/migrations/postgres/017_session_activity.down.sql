@@ -0,0 +1 @@
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS last_activity_at;
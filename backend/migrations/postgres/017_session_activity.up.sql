@@ -0,0 +1,4 @@
+-- Last time a session made a request, for the idle timeout
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS last_activity_at TIMESTAMP;
+UPDATE user_sessions SET last_activity_at = created_at WHERE last_activity_at IS NULL;
+ALTER TABLE user_sessions ALTER COLUMN last_activity_at SET DEFAULT now();
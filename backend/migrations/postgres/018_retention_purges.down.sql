@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_searches_search_time;
+DROP INDEX IF EXISTS idx_logins_login_time;
+DROP TABLE IF EXISTS retention_purges;
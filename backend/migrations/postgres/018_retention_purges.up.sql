@@ -0,0 +1,19 @@
+-- History of retention purges of the log tables
+CREATE TABLE IF NOT EXISTS retention_purges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    table_name TEXT NOT NULL,
+    retention_days INTEGER NOT NULL,
+    cutoff TIMESTAMP,
+    rows_deleted BIGINT NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'RUNNING' CHECK (status IN ('RUNNING', 'COMPLETED', 'FAILED')),
+    error TEXT,
+    triggered_by UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL for scheduled purges
+    started_at TIMESTAMP NOT NULL DEFAULT now(),
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_purges_started ON retention_purges(started_at DESC);
+
+-- Purges delete by age
+CREATE INDEX IF NOT EXISTS idx_logins_login_time ON logins(login_time);
+CREATE INDEX IF NOT EXISTS idx_searches_search_time ON searches(search_time);
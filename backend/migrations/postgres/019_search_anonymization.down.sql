@@ -0,0 +1,4 @@
+ALTER TABLE retention_purges DROP COLUMN IF EXISTS rows_anonymized;
+ALTER TABLE retention_purges DROP COLUMN IF EXISTS action;
+DROP INDEX IF EXISTS idx_searches_pending_anonymization;
+ALTER TABLE searches DROP COLUMN IF EXISTS anonymized_at;
@@ -0,0 +1,7 @@
+-- Old search logs keep only structural metadata once anonymized
+ALTER TABLE searches ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_searches_pending_anonymization ON searches(search_time) WHERE anonymized_at IS NULL;
+
+-- Retention runs either purge rows or anonymize them
+ALTER TABLE retention_purges ADD COLUMN IF NOT EXISTS action TEXT NOT NULL DEFAULT 'purge' CHECK (action IN ('purge', 'anonymize'));
+ALTER TABLE retention_purges ADD COLUMN IF NOT EXISTS rows_anonymized BIGINT NOT NULL DEFAULT 0;
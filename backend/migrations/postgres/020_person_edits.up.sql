@@ -0,0 +1,15 @@
+-- Audit trail of manual edits to ClickHouse people rows
+CREATE TABLE IF NOT EXISTS person_edits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    person_id TEXT NOT NULL,
+    people_table TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('CREATE', 'UPDATE', 'DELETE')),
+    before JSONB, -- Row before the edit; NULL for creates
+    after JSONB,  -- Row after the edit; NULL for deletes
+    reason TEXT,
+    edited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    edited_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_edits_person ON person_edits(person_id, edited_at DESC);
+CREATE INDEX IF NOT EXISTS idx_person_edits_time ON person_edits(edited_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_searches_request_id;
+ALTER TABLE searches DROP COLUMN IF EXISTS request_id;
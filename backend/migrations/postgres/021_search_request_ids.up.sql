@@ -0,0 +1,3 @@
+-- Request ID of the API call that ran each search, shared with search_performance and the X-Request-Id header
+ALTER TABLE searches ADD COLUMN IF NOT EXISTS request_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_searches_request_id ON searches(request_id) WHERE request_id IS NOT NULL;
@@ -0,0 +1,19 @@
+-- Record of DEMO accounts upgraded to PERMANENT, with the settings they had before
+CREATE TABLE IF NOT EXISTS user_upgrades (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    previous_user_type VARCHAR(20) NOT NULL,
+    new_user_type VARCHAR(20) NOT NULL,
+    previous_expires_at TIMESTAMP,
+    previous_max_searches_per_day INTEGER NOT NULL,
+    previous_max_exports_per_day INTEGER NOT NULL,
+    max_searches_per_day INTEGER NOT NULL,
+    max_exports_per_day INTEGER NOT NULL,
+    history_preserved BOOLEAN NOT NULL DEFAULT true,
+    searches_removed INTEGER NOT NULL DEFAULT 0,
+    notes TEXT,
+    upgraded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    upgraded_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_upgrades_user ON user_upgrades(user_id, upgraded_at DESC);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_searches_dataset;
+ALTER TABLE searches DROP COLUMN IF EXISTS dataset_id;
+DROP TABLE IF EXISTS user_dataset_access;
+DROP TABLE IF EXISTS datasets;
@@ -0,0 +1,27 @@
+-- Registry of datasets hosted alongside the default people table, each backed by its own ClickHouse table
+CREATE TABLE IF NOT EXISTS datasets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    table_name VARCHAR(63) NOT NULL, -- Unqualified table in the finone_search ClickHouse database
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Users entitled to search and export a dataset; admins can use every dataset
+CREATE TABLE IF NOT EXISTS user_dataset_access (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    dataset_id UUID NOT NULL REFERENCES datasets(id) ON DELETE CASCADE,
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    granted_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, dataset_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_dataset_access_dataset ON user_dataset_access(dataset_id);
+
+-- Dataset each search ran against; NULL for the default people table
+ALTER TABLE searches ADD COLUMN IF NOT EXISTS dataset_id UUID REFERENCES datasets(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_searches_dataset ON searches(dataset_id, search_time) WHERE dataset_id IS NOT NULL;
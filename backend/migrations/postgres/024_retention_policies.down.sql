@@ -0,0 +1,9 @@
+ALTER TABLE retention_purges DROP CONSTRAINT IF EXISTS retention_purges_action_check;
+DELETE FROM retention_purges WHERE action = 'archive';
+UPDATE retention_purges SET action = 'purge' WHERE action = 'delete';
+ALTER TABLE retention_purges ALTER COLUMN action SET DEFAULT 'purge';
+ALTER TABLE retention_purges ADD CONSTRAINT retention_purges_action_check CHECK (action IN ('purge', 'anonymize'));
+ALTER TABLE retention_purges DROP COLUMN IF EXISTS rows_archived;
+ALTER TABLE retention_purges DROP COLUMN IF EXISTS policy_name;
+DROP TABLE IF EXISTS retention_archive;
+DROP TABLE IF EXISTS retention_policies;
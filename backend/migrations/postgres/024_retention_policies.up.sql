@@ -0,0 +1,31 @@
+-- Retention policies managed through the admin API; they override the built-in and config.yaml
+-- policies of the same name
+CREATE TABLE IF NOT EXISTS retention_policies (
+    name TEXT PRIMARY KEY,
+    table_name TEXT NOT NULL,
+    criteria TEXT NOT NULL DEFAULT 'all',
+    age_days INTEGER NOT NULL CHECK (age_days >= 0),
+    action TEXT NOT NULL CHECK (action IN ('delete', 'archive', 'anonymize', 'ttl')),
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- Rows removed by archive policies, kept as JSON
+CREATE TABLE IF NOT EXISTS retention_archive (
+    id BIGSERIAL PRIMARY KEY,
+    policy_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    row_data JSONB NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_archive_table ON retention_archive(table_name, archived_at);
+
+-- Runs are now per policy and can archive rows
+ALTER TABLE retention_purges ADD COLUMN IF NOT EXISTS policy_name TEXT;
+ALTER TABLE retention_purges ADD COLUMN IF NOT EXISTS rows_archived BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE retention_purges DROP CONSTRAINT IF EXISTS retention_purges_action_check;
+UPDATE retention_purges SET action = 'delete' WHERE action = 'purge';
+ALTER TABLE retention_purges ALTER COLUMN action SET DEFAULT 'delete';
+ALTER TABLE retention_purges ADD CONSTRAINT retention_purges_action_check CHECK (action IN ('delete', 'archive', 'anonymize'));
@@ -0,0 +1,7 @@
+ALTER TABLE csv_import_audit DROP CONSTRAINT IF EXISTS csv_import_audit_status_check;
+UPDATE csv_import_audit SET status = 'COMPLETED' WHERE status = 'ROLLED_BACK';
+ALTER TABLE csv_import_audit ADD CONSTRAINT csv_import_audit_status_check CHECK (status IN ('STARTED', 'COMPLETED', 'FAILED'));
+ALTER TABLE csv_import_audit DROP COLUMN IF EXISTS rolled_back_at;
+ALTER TABLE csv_import_audit DROP COLUMN IF EXISTS rolled_back_by;
+ALTER TABLE csv_import_audit DROP COLUMN IF EXISTS rows_rolled_back;
+ALTER TABLE csv_import_audit DROP COLUMN IF EXISTS people_table;
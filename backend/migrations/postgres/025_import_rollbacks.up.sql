@@ -0,0 +1,7 @@
+-- Audited imports remember their target table and can be rolled back
+ALTER TABLE csv_import_audit ADD COLUMN IF NOT EXISTS people_table TEXT;
+ALTER TABLE csv_import_audit ADD COLUMN IF NOT EXISTS rows_rolled_back BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE csv_import_audit ADD COLUMN IF NOT EXISTS rolled_back_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE csv_import_audit ADD COLUMN IF NOT EXISTS rolled_back_at TIMESTAMP;
+ALTER TABLE csv_import_audit DROP CONSTRAINT IF EXISTS csv_import_audit_status_check;
+ALTER TABLE csv_import_audit ADD CONSTRAINT csv_import_audit_status_check CHECK (status IN ('STARTED', 'COMPLETED', 'FAILED', 'ROLLED_BACK'));
@@ -0,0 +1,29 @@
+-- Chunked uploads: a large CSV is sent in checksummed chunks appended to a partial file, so an
+-- interrupted upload resumes from the last confirmed chunk
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    file_name TEXT NOT NULL,
+    upload_file TEXT NOT NULL, -- Partial file under the CSV temp directory
+    total_size BIGINT,
+    checksum TEXT, -- Expected SHA-256 of the whole file, checked on completion when given
+    received_bytes BIGINT NOT NULL DEFAULT 0,
+    chunks_received INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'UPLOADING' CHECK (status IN ('UPLOADING', 'COMPLETING', 'COMPLETED', 'ABORTED')),
+    import_job_id UUID,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS upload_chunks (
+    upload_id UUID NOT NULL REFERENCES upload_sessions(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    byte_offset BIGINT NOT NULL,
+    size BIGINT NOT NULL,
+    checksum TEXT NOT NULL, -- SHA-256 of the chunk
+    received_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (upload_id, chunk_index)
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_user_id ON upload_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_updated_at ON upload_sessions(updated_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_exports_unexpired;
+ALTER TABLE exports DROP COLUMN IF EXISTS expired;
+ALTER TABLE exports DROP COLUMN IF EXISTS storage;
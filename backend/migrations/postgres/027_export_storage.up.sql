@@ -0,0 +1,5 @@
+-- Exports remember where their file is stored, and are marked expired once the cleanup job has removed it
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS storage TEXT NOT NULL DEFAULT 'local';
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS expired BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_exports_unexpired ON exports(expires_at) WHERE NOT expired;
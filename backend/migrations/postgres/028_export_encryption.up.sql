@@ -0,0 +1,2 @@
+-- Exports delivered as password-protected ZIPs; the password itself is never stored
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS encrypted BOOLEAN NOT NULL DEFAULT false;
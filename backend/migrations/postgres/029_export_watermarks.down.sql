@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_exports_watermark;
+ALTER TABLE exports DROP COLUMN IF EXISTS watermark_mode;
+ALTER TABLE exports DROP COLUMN IF EXISTS watermark;
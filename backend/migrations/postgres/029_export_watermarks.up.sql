@@ -0,0 +1,5 @@
+-- Leak tracing: each export records the watermark embedded in its file and how it was embedded
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS watermark TEXT;
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS watermark_mode TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exports_watermark ON exports(watermark) WHERE watermark IS NOT NULL;
@@ -0,0 +1,2 @@
+-- Accounts whose searches are logged but never checked against or counted toward the daily search limit
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quota_exempt BOOLEAN NOT NULL DEFAULT false;
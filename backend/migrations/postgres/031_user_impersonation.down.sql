@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_impersonations;
+ALTER TABLE searches DROP COLUMN IF EXISTS impersonated_by;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS impersonated_by;
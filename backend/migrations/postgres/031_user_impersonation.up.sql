@@ -0,0 +1,19 @@
+-- Support sessions acting as a user: the session and the searches made in it keep the real admin
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS impersonated_by UUID REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE searches ADD COLUMN IF NOT EXISTS impersonated_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Audit of every impersonation session issued
+CREATE TABLE IF NOT EXISTS user_impersonations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id UUID REFERENCES user_sessions(id) ON DELETE SET NULL,
+    reason TEXT NOT NULL,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    started_at TIMESTAMP NOT NULL DEFAULT now(),
+    expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_impersonations_started ON user_impersonations(started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_impersonations_user ON user_impersonations(user_id, started_at DESC);
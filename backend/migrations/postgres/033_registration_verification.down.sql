@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_user_registration_requests_verification;
+DROP INDEX IF EXISTS idx_user_registration_requests_ip;
+DROP INDEX IF EXISTS idx_user_registration_requests_open_email;
+
+DELETE FROM user_registration_requests WHERE status = 'UNVERIFIED';
+ALTER TABLE user_registration_requests DROP CONSTRAINT IF EXISTS user_registration_requests_status_check;
+ALTER TABLE user_registration_requests ADD CONSTRAINT user_registration_requests_status_check
+CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED'));
+
+ALTER TABLE user_registration_requests DROP COLUMN IF EXISTS verified_at;
+ALTER TABLE user_registration_requests DROP COLUMN IF EXISTS verification_expires_at;
+ALTER TABLE user_registration_requests DROP COLUMN IF EXISTS verification_token_hash;
+ALTER TABLE user_registration_requests DROP COLUMN IF EXISTS ip_address;
+
+-- The email unique constraint is not restored, since applicants may have registered again after a rejection
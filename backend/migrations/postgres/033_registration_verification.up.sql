@@ -0,0 +1,19 @@
+-- Registration requests can wait for the applicant to verify their email before they are reviewed
+ALTER TABLE user_registration_requests DROP CONSTRAINT IF EXISTS user_registration_requests_status_check;
+ALTER TABLE user_registration_requests ADD CONSTRAINT user_registration_requests_status_check
+CHECK (status IN ('UNVERIFIED', 'PENDING', 'APPROVED', 'REJECTED'));
+
+ALTER TABLE user_registration_requests ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45);
+ALTER TABLE user_registration_requests ADD COLUMN IF NOT EXISTS verification_token_hash VARCHAR(64);
+ALTER TABLE user_registration_requests ADD COLUMN IF NOT EXISTS verification_expires_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE user_registration_requests ADD COLUMN IF NOT EXISTS verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Only one open request per email, so rejected applicants can apply again
+ALTER TABLE user_registration_requests DROP CONSTRAINT IF EXISTS user_registration_requests_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_registration_requests_open_email
+    ON user_registration_requests(email) WHERE status IN ('UNVERIFIED', 'PENDING');
+
+-- Per-IP throttling and verification lookups
+CREATE INDEX IF NOT EXISTS idx_user_registration_requests_ip ON user_registration_requests(ip_address, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_registration_requests_verification
+    ON user_registration_requests(verification_token_hash) WHERE verification_token_hash IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Keys that sign session tokens, identified by the kid header. The newest key that has not been
+-- rotated out signs new tokens; rotated keys keep verifying the tokens they signed until verify_until,
+-- unless retired sooner. Tokens without a kid are signed with jwt.secret from the configuration.
+CREATE TABLE IF NOT EXISTS jwt_signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(10) NOT NULL CHECK (algorithm IN ('HS256', 'RS256')),
+    secret TEXT NOT NULL, -- Base64 HMAC secret, or the PEM private key for RS256
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    rotated_at TIMESTAMP,   -- When a newer key took over signing
+    verify_until TIMESTAMP, -- When the last token the key signed expires
+    retired_at TIMESTAMP    -- Tokens signed with the key are rejected from then on
+);
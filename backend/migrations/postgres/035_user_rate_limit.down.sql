@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS rate_limit_per_minute;
@@ -0,0 +1,2 @@
+-- Per-user API rate limit in requests per minute; NULL uses the limit for the user type
+ALTER TABLE users ADD COLUMN IF NOT EXISTS rate_limit_per_minute INTEGER CHECK (rate_limit_per_minute > 0);
@@ -0,0 +1,2 @@
+-- Rows an export run from a query may contain for this user; NULL uses limits.max_export_rows
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_export_rows INTEGER CHECK (max_export_rows > 0);
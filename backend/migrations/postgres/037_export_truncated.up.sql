@@ -0,0 +1,2 @@
+-- The export stopped at the user's export row limit, so the file holds fewer rows than matched
+ALTER TABLE exports ADD COLUMN IF NOT EXISTS truncated BOOLEAN NOT NULL DEFAULT FALSE;
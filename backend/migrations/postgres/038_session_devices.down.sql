@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS user_login_origins;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS country;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS device_fingerprint;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS device_type;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS os;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS browser;
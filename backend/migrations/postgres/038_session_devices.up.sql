@@ -0,0 +1,18 @@
+-- Device a session was created from, parsed from its User-Agent, and the client's country when known
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS browser TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS os TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS device_type TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS device_fingerprint TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS country TEXT;
+
+-- Devices and countries each account has logged in from, so logins from new ones can be reported.
+-- Unlike sessions they are kept after the sessions expire.
+CREATE TABLE IF NOT EXISTS user_login_origins (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('device', 'country')),
+    value TEXT NOT NULL, -- Device fingerprint or country code
+    description TEXT,    -- e.g. "Chrome on Windows (desktop)"
+    first_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, kind, value)
+);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_logins_new_location;
+ALTER TABLE logins DROP COLUMN IF EXISTS new_location;
+ALTER TABLE logins DROP COLUMN IF EXISTS as_org;
+ALTER TABLE logins DROP COLUMN IF EXISTS asn;
+ALTER TABLE logins DROP COLUMN IF EXISTS city;
+ALTER TABLE logins DROP COLUMN IF EXISTS country;
+ALTER TABLE logins DROP COLUMN IF EXISTS session_id;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS new_location;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS as_org;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS asn;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS city;
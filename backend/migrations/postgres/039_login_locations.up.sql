@@ -0,0 +1,15 @@
+-- Where logins and sessions came from, located by GeoIP (the country may come from a trusted proxy).
+-- new_location marks a login from a country the account had not logged in from before.
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS city TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS asn BIGINT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS as_org TEXT;
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS new_location BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE logins ADD COLUMN IF NOT EXISTS session_id UUID REFERENCES user_sessions(id) ON DELETE SET NULL;
+ALTER TABLE logins ADD COLUMN IF NOT EXISTS country TEXT;
+ALTER TABLE logins ADD COLUMN IF NOT EXISTS city TEXT;
+ALTER TABLE logins ADD COLUMN IF NOT EXISTS asn BIGINT;
+ALTER TABLE logins ADD COLUMN IF NOT EXISTS as_org TEXT;
+ALTER TABLE logins ADD COLUMN IF NOT EXISTS new_location BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_logins_new_location ON logins(login_time DESC) WHERE new_location;
@@ -0,0 +1,14 @@
+-- Standard searches for customer use cases, run by users with just the values of their fields
+CREATE TABLE IF NOT EXISTS search_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL UNIQUE,
+    description TEXT,
+    fields TEXT[] NOT NULL,
+    logic VARCHAR(3) NOT NULL DEFAULT 'AND',
+    match_type VARCHAR(10) NOT NULL DEFAULT 'partial',
+    output_columns TEXT[] NOT NULL DEFAULT '{}', -- Person columns returned; all when empty
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,15 @@
+-- Extra searches and exports given to a user for a single quota day, on top of their daily limits,
+-- with the reason they were given
+CREATE TABLE IF NOT EXISTS quota_adjustments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    extra_searches INTEGER NOT NULL DEFAULT 0 CHECK (extra_searches >= 0),
+    extra_exports INTEGER NOT NULL DEFAULT 0 CHECK (extra_exports >= 0),
+    reason TEXT NOT NULL,
+    adjusted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (extra_searches > 0 OR extra_exports > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_adjustments_user_date ON quota_adjustments(user_id, date);
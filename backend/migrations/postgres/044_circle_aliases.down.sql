@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS unmapped_circles;
+DROP TABLE IF EXISTS circle_aliases;
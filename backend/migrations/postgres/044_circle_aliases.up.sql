@@ -0,0 +1,35 @@
+-- Circle normalization map: imports store the circle an alias maps to, and circle searches also
+-- match every alias of a circle. Aliases and circles are upper case with single spaces.
+CREATE TABLE IF NOT EXISTS circle_aliases (
+    alias VARCHAR(100) PRIMARY KEY,
+    circle VARCHAR(100) NOT NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (alias <> circle)
+);
+
+CREATE INDEX IF NOT EXISTS idx_circle_aliases_circle ON circle_aliases(circle);
+
+INSERT INTO circle_aliases (alias, circle) VALUES
+    ('DL', 'DELHI'),
+    ('NCR', 'DELHI'),
+    ('DELHI NCR', 'DELHI'),
+    ('MUM', 'MUMBAI'),
+    ('KOL', 'KOLKATA'),
+    ('MH', 'MAHARASHTRA'),
+    ('TN', 'TAMIL NADU'),
+    ('KA', 'KARNATAKA'),
+    ('AP', 'ANDHRA PRADESH'),
+    ('UPE', 'UP EAST'),
+    ('UPW', 'UP WEST')
+ON CONFLICT (alias) DO NOTHING;
+
+-- Circle values imports found that are neither an alias nor a circle of the map, so admins can map them
+CREATE TABLE IF NOT EXISTS unmapped_circles (
+    value VARCHAR(255) PRIMARY KEY,
+    occurrences BIGINT NOT NULL DEFAULT 0,
+    last_import_job_id VARCHAR(64),
+    first_seen_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT now()
+);
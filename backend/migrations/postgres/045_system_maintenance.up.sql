@@ -0,0 +1,11 @@
+-- System maintenance mode: while enabled, every endpoint but the admin's answers 503 with the message,
+-- so users do not see half-loaded data during large imports and migrations. The table holds one row.
+CREATE TABLE IF NOT EXISTS system_maintenance (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    message TEXT NOT NULL DEFAULT '',
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+INSERT INTO system_maintenance (id) VALUES (TRUE) ON CONFLICT (id) DO NOTHING;
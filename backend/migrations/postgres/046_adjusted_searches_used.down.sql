@@ -0,0 +1 @@
+ALTER TABLE daily_usage DROP COLUMN IF EXISTS adjusted_searches_used;
@@ -0,0 +1,3 @@
+-- Searches beyond the daily limit covered by quota adjustments, counted apart from credits_used so
+-- search credit consumption is not charged for them
+ALTER TABLE daily_usage ADD COLUMN IF NOT EXISTS adjusted_searches_used INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,73 @@
+package models
+
+import "net/http"
+
+// Error codes sent as "code" with every API error, so clients can tell failures apart without
+// matching on messages
+const (
+	ErrorCodeInvalidRequest      = "INVALID_REQUEST"
+	ErrorCodeInvalidField        = "INVALID_FIELD"
+	ErrorCodeUnauthorized        = "UNAUTHORIZED"
+	ErrorCodeInvalidCredentials  = "INVALID_CREDENTIALS"
+	ErrorCodeInvalidToken        = "INVALID_TOKEN"
+	ErrorCodeSessionExpired      = "SESSION_EXPIRED"
+	ErrorCodeAccountExpired      = "ACCOUNT_EXPIRED"
+	ErrorCodeForbidden           = "FORBIDDEN"
+	ErrorCodeFieldNotAllowed     = "FIELD_NOT_ALLOWED"
+	ErrorCodeDatasetAccessDenied = "DATASET_ACCESS_DENIED"
+	ErrorCodeNotFound            = "NOT_FOUND"
+	ErrorCodeConflict            = "CONFLICT"
+	ErrorCodeSearchAnonymized    = "SEARCH_ANONYMIZED"
+	ErrorCodePayloadTooLarge     = "PAYLOAD_TOO_LARGE"
+	ErrorCodeQuotaExceeded       = "QUOTA_EXCEEDED"
+	ErrorCodeExportTooLarge      = "EXPORT_TOO_LARGE"
+	ErrorCodeSearchTooExpensive  = "SEARCH_TOO_EXPENSIVE"
+	ErrorCodeRateLimited         = "RATE_LIMITED"
+	ErrorCodeCaptchaFailed       = "CAPTCHA_FAILED"
+	ErrorCodeInternal            = "INTERNAL_ERROR"
+	ErrorCodeUpstream            = "UPSTREAM_ERROR"
+	ErrorCodeServiceUnavailable  = "SERVICE_UNAVAILABLE"
+	ErrorCodeMaintenance         = "MAINTENANCE"
+)
+
+// APIError is an error response: the HTTP status, a machine-readable code and a message for people,
+// sent as {"error": message, "code": code} plus any extra fields
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  map[string]interface{} // Extra response fields, e.g. the record a request conflicts with
+}
+
+func NewAPIError(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// InternalError is the response for failures whose details are not shown to clients
+func InternalError() *APIError {
+	return NewAPIError(http.StatusInternalServerError, ErrorCodeInternal, "Internal server error")
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// With adds a field to the response body
+func (e *APIError) With(key string, value interface{}) *APIError {
+	if e.Fields == nil {
+		e.Fields = map[string]interface{}{}
+	}
+	e.Fields[key] = value
+	return e
+}
+
+// Body returns the JSON response body
+func (e *APIError) Body() map[string]interface{} {
+	body := make(map[string]interface{}, len(e.Fields)+2)
+	for key, value := range e.Fields {
+		body[key] = value
+	}
+	body["error"] = e.Message
+	body["code"] = e.Code
+	return body
+}
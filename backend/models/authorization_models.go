@@ -0,0 +1,16 @@
+package models
+
+// RouteAccess describes the permission a route requires and which roles hold it
+type RouteAccess struct {
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	Permission string          `json:"permission"`
+	Roles      map[string]bool `json:"roles"` // role -> allowed
+}
+
+// PermissionMatrix documents effective access per role for every protected route
+type PermissionMatrix struct {
+	Roles           []string            `json:"roles"`
+	RolePermissions map[string][]string `json:"role_permissions"`
+	Routes          []RouteAccess       `json:"routes"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// CacheStats represents one in-process cache namespace
+type CacheStats struct {
+	Namespace   string   `json:"namespace"`
+	Description string   `json:"description"`
+	Entries     int      `json:"entries"`
+	ApproxBytes int      `json:"approx_bytes"`       // Rough estimate of the memory held by the entries
+	Hits        *uint64  `json:"hits,omitempty"`     // Lookups served from the cache since startup, where tracked
+	Misses      *uint64  `json:"misses,omitempty"`   // Lookups that went to the database since startup, where tracked
+	HitRate     *float64 `json:"hit_rate,omitempty"` // Hits as a fraction of all lookups
+	TTL         string   `json:"ttl,omitempty"`      // How long entries live; empty when they live until flushed
+}
+
+// CacheStatsResponse represents the caches held by the instance that served the request
+type CacheStatsResponse struct {
+	Namespaces []CacheStats `json:"namespaces"`
+}
+
+// FlushCacheRequest represents a cache flush; an empty namespace flushes every cache
+type FlushCacheRequest struct {
+	Namespace string `json:"namespace"`
+}
+
+// FlushCacheResponse reports what a flush cleared
+type FlushCacheResponse struct {
+	Flushed map[string]int `json:"flushed"` // Entries dropped per namespace
+}
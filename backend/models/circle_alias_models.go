@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CircleAlias maps a circle value found in imports to the circle it stands for
+type CircleAlias struct {
+	Alias     string     `json:"alias" db:"alias"`
+	Circle    string     `json:"circle" db:"circle"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CircleAliasRequest adds an alias to the circle normalization map or changes its circle
+type CircleAliasRequest struct {
+	Alias  string `json:"alias" binding:"required"`
+	Circle string `json:"circle" binding:"required"`
+}
+
+// UnmappedCircle is a circle value imports found that the circle normalization map does not know
+type UnmappedCircle struct {
+	Value           string    `json:"value" db:"value"`
+	Occurrences     int64     `json:"occurrences" db:"occurrences"`
+	LastImportJobID *string   `json:"last_import_job_id,omitempty" db:"last_import_job_id"`
+	FirstSeenAt     time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt      time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
@@ -1,12 +1,15 @@
 package models
 
 import (
+	"context"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Person represents a person record in ClickHouse
-// Note: A materialized `pincode` column exists in the table for performance,
-// but is intentionally not part of this struct to keep API responses stable.
+// Note: Materialized `pincode`, `locality`, `city` and `state` columns exist in the table for
+// filtering, but are intentionally not part of this struct to keep API responses stable.
 type Person struct {
 	ID        string    `json:"id" ch:"id"`
 	MasterID  string    `json:"master_id" ch:"master_id"`
@@ -19,6 +22,44 @@ type Person struct {
 	Email     string    `json:"email" ch:"email"`
 	CreatedAt time.Time `json:"created_at" ch:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" ch:"updated_at"`
+	// Confidence is a 0-100 data quality score computed at import (see utils.ConfidenceScore)
+	Confidence uint8 `json:"confidence" ch:"confidence"`
+	// QualityFlags lists internal inconsistencies found at import (see utils.CheckConsistency)
+	QualityFlags []string `json:"quality_flags" ch:"quality_flags"`
+	// Provenance set by the CSV importer; only loaded by the admin people endpoints
+	SourceFile  string     `json:"source_file,omitempty" ch:"source_file"`
+	ImportJobID string     `json:"import_job_id,omitempty" ch:"import_job_id"`
+	ImportedAt  *time.Time `json:"imported_at,omitempty" ch:"imported_at"`
+	// Numbers as they appeared in the import, before mobile and alt were normalized to 10 digits
+	MobileRaw string `json:"mobile_raw,omitempty" ch:"mobile_raw"`
+	AltRaw    string `json:"alt_raw,omitempty" ch:"alt_raw"`
+	// Highlights maps each matched field to where the search terms occur in it; set on search results only
+	Highlights map[string][]Highlight `json:"highlights,omitempty" ch:"-"`
+	// LinkPath is how an enhanced search reached the record from the searched key
+	LinkPath []LinkStep `json:"link_path,omitempty" ch:"-"`
+
+	shape *ResponseShape // Serialization options set by ShapePeople
+}
+
+// How a step of an enhanced search's link path reached a record: the first step by the searched
+// key's type (EnhancedKeyMobile, EnhancedKeyAlt or EnhancedKeyEmail), later ones by one of these
+const (
+	LinkViaMasterID  = "master_id"  // It shares a master ID with the previous record
+	LinkViaAltNumber = "linked_alt" // Its mobile or alt number matches the previous record's alt number
+)
+
+// LinkStep is one step of a link path: the record reached and the value linking it
+type LinkStep struct {
+	Via      string `json:"via"`
+	Value    string `json:"value"` // Number or master ID shared with the previous step
+	PersonID string `json:"person_id"`
+}
+
+// Highlight represents one occurrence of a search term in a field
+type Highlight struct {
+	Start   int    `json:"start"`   // Character offset of the match
+	End     int    `json:"end"`     // Character offset just past the match
+	Snippet string `json:"snippet"` // HTML-escaped text around the match with the match wrapped in <em>
 }
 
 // SearchRequest represents a search request payload
@@ -31,36 +72,252 @@ type SearchRequest struct {
 	MatchType      string            `json:"match_type" validate:"oneof=partial full"` // partial or full match
 	Limit          int               `json:"limit" validate:"min=1,max=10000"`         // Max results
 	Offset         int               `json:"offset" validate:"min=0"`                  // Pagination
-	EnhancedMobile bool              `json:"enhanced_mobile"`                          // Enhanced mobile search with master_id lookup
+	EnhancedMobile bool              `json:"enhanced_mobile"`                          // Enhanced mobile search with master_id lookup, in auto mode
+	Mode           string            `json:"mode,omitempty"`                           // How to search: auto (default), standard or enhanced_mobile
+	Facets         []string          `json:"facets,omitempty"`                         // Fields to return top value counts for (circle, pincode, city, state)
+	FacetLimit     int               `json:"facet_limit,omitempty"`                    // Max values returned per facet
+	SortBy         string            `json:"sort_by,omitempty"`                        // Result order: default (mobile, name) or confidence
+	Quality        string            `json:"quality,omitempty"`                        // Quality filter: clean, flagged or a single quality flag
+	Near           *NearbyFilter     `json:"near,omitempty"`                           // Restrict results to pincodes near a pincode or matching prefixes
+	NearPincodes   []string          `json:"near_pincodes,omitempty"`                  // Pincodes within Near's radius, set by the server
+	Debug          bool              `json:"debug,omitempty"`                          // Return an execution trace (admins only)
+	Diagnostic     bool              `json:"diagnostic,omitempty"`                     // Exempt from quota and marked as diagnostic (admins only)
+	DatasetID      string            `json:"dataset_id,omitempty"`                     // Dataset ID or slug to search; empty searches the default people table
+	Columns        []string          `json:"columns,omitempty"`                        // Person columns to return; all when empty
+	TemplateID     string            `json:"template_id,omitempty"`                    // Search template the request was built from, set by the server
+	ClientID       string            `json:"-"`                                        // Set from the X-Client-Id header
+	RequestID      string            `json:"-"`                                        // Set from the X-Request-Id header
+	Context        context.Context   `json:"-"`                                        // Context of the API call, carrying its trace span
+	ImpersonatedBy string            `json:"-"`                                        // Admin acting as the user in an impersonation session
+}
+
+// FacetCount represents the number of matching records sharing a facet value
+type FacetCount struct {
+	Value string `json:"value" ch:"value"`
+	Count uint64 `json:"count" ch:"count"`
+}
+
+// PincodeStats summarizes the records of one pincode. The breakdowns are left out (null) when the
+// caller's field visibility policy masks the field.
+type PincodeStats struct {
+	Pincode         string       `json:"pincode"`
+	Table           string       `json:"table"`
+	TotalRecords    uint64       `json:"total_records"`
+	UniqueMasterIDs uint64       `json:"unique_master_ids"`
+	Locality        string       `json:"locality,omitempty"` // From the pincode directory
+	City            string       `json:"city,omitempty"`
+	State           string       `json:"state,omitempty"`
+	TopCircles      []FacetCount `json:"top_circles"`
+	TopNameTokens   []FacetCount `json:"top_name_tokens"`
 }
 
 // EnhancedMobileSearchRequest represents an enhanced mobile search request
 type EnhancedMobileSearchRequest struct {
-	MobileNumber string `json:"mobile_number" validate:"required"`
-	Limit        int    `json:"limit" validate:"min=1,max=10000"`
-	Offset       int    `json:"offset" validate:"min=0"`
+	MobileNumber   string          `json:"mobile_number" validate:"required"`
+	Limit          int             `json:"limit" validate:"min=1,max=10000"`
+	Offset         int             `json:"offset" validate:"min=0"`
+	Depth          int             `json:"depth,omitempty"`      // Master ID hops to expand: 1 (default) or 2, which also follows alt numbers
+	Diagnostic     bool            `json:"diagnostic,omitempty"` // Exempt from quota and marked as diagnostic (admins only)
+	DatasetID      string          `json:"dataset_id,omitempty"` // Dataset ID or slug to search; empty searches the default people table
+	ClientID       string          `json:"-"`                    // Set from the X-Client-Id header
+	RequestID      string          `json:"-"`                    // Set from the X-Request-Id header
+	Context        context.Context `json:"-"`                    // Context of the API call, carrying its trace span
+	ImpersonatedBy string          `json:"-"`                    // Admin acting as the user in an impersonation session
 }
 
-// EnhancedMobileSearchResponse represents an enhanced mobile search response
+// Keys an enhanced search can start from
+const (
+	EnhancedKeyMobile = "mobile" // Mobile or alt number equal to, starting or ending with the value
+	EnhancedKeyAlt    = "alt"    // Alt number only, matched like mobile
+	EnhancedKeyEmail  = "email"  // Email equal to the value, or to it once both are normalized
+)
+
+// EnhancedSearchKey is what an enhanced search finds its direct matches by
+type EnhancedSearchKey struct {
+	Type  string
+	Value string // Digits of a number, or a lower-case email
+}
+
+// EnhancedSearchRequest represents an enhanced search from a mobile number, alt number or email: its
+// direct matches, then every record sharing their master IDs
+type EnhancedSearchRequest struct {
+	KeyType        string          `json:"key_type"` // mobile (default), alt or email
+	Value          string          `json:"value" validate:"required"`
+	Limit          int             `json:"limit" validate:"min=1,max=10000"`
+	Offset         int             `json:"offset" validate:"min=0"`
+	Depth          int             `json:"depth,omitempty"`      // Master ID hops to expand: 1 (default) or 2, which also follows alt numbers
+	Diagnostic     bool            `json:"diagnostic,omitempty"` // Exempt from quota and marked as diagnostic (admins only)
+	DatasetID      string          `json:"dataset_id,omitempty"` // Dataset ID or slug to search; empty searches the default people table
+	ClientID       string          `json:"-"`                    // Set from the X-Client-Id header
+	RequestID      string          `json:"-"`                    // Set from the X-Request-Id header
+	Context        context.Context `json:"-"`                    // Context of the API call, carrying its trace span
+	ImpersonatedBy string          `json:"-"`                    // Admin acting as the user in an impersonation session
+}
+
+// EnhancedMobileSearchResponse represents an enhanced search response
 type EnhancedMobileSearchResponse struct {
-	DirectMatches        []Person `json:"direct_matches"`    // Direct mobile number matches
-	MasterIDMatches      []Person `json:"master_id_matches"` // Additional records with same master_ids
-	TotalDirectMatches   int      `json:"total_direct_matches"`
-	TotalMasterIDMatches int      `json:"total_master_id_matches"`
-	TotalCount           int      `json:"total_count"`
-	ExecutionTime        int      `json:"execution_time_ms"`
-	SearchID             string   `json:"search_id"`
-	HasMore              bool     `json:"has_more"`
-	MasterIDs            []string `json:"master_ids"` // List of unique master_ids found
+	KeyType              string       `json:"key_type"`
+	DirectMatches        []Person     `json:"direct_matches"`               // Direct matches of the key
+	MasterIDMatches      []Person     `json:"master_id_matches"`            // Additional records with same master_ids
+	SecondHopMatches     []Person     `json:"second_hop_matches,omitempty"` // Records linked through alt numbers, at depth 2
+	TotalDirectMatches   int          `json:"total_direct_matches"`
+	TotalMasterIDMatches int          `json:"total_master_id_matches"`
+	TotalSecondHop       int          `json:"total_second_hop_matches,omitempty"`
+	SecondHopTruncated   bool         `json:"second_hop_truncated,omitempty"` // The second hop stopped at search.enhanced_mobile_max_linked
+	TotalCount           int          `json:"total_count"`
+	ExecutionTime        int          `json:"execution_time_ms"`
+	SearchID             string       `json:"search_id"`
+	HasMore              bool         `json:"has_more"`
+	MasterIDs            []string     `json:"master_ids"` // List of unique master_ids found
+	Quota                *SearchQuota `json:"quota,omitempty"`
+}
+
+// PersonGraph represents the people linked to a person through shared attributes, for a link chart
+type PersonGraph struct {
+	RootID    string            `json:"root_id"`
+	Nodes     []PersonGraphNode `json:"nodes"`
+	Edges     []PersonGraphEdge `json:"edges"`
+	Depth     int               `json:"depth"`     // Hops expanded from the root
+	MaxNodes  int               `json:"max_nodes"` // Node limit applied
+	Truncated bool              `json:"truncated"` // More linked people were left out at the node limit
+}
+
+// PersonGraphNode represents one person in a graph, found Depth hops from the root
+type PersonGraphNode struct {
+	ID     string `json:"id"`
+	Depth  int    `json:"depth"`
+	Person Person `json:"person"`
+}
+
+// PersonGraphEdge represents two people sharing an attribute: master_id, email, mobile, alt, or
+// mobile_alt when one's mobile is the other's alt number
+type PersonGraphEdge struct {
+	Source    string `json:"source"`
+	Target    string `json:"target"`
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
 }
 
 // SearchResponse represents a search response
 type SearchResponse struct {
-	Results       []Person `json:"results"`
-	TotalCount    int      `json:"total_count"`
-	ExecutionTime int      `json:"execution_time_ms"`
-	SearchID      string   `json:"search_id"`
-	HasMore       bool     `json:"has_more"`
+	Results       []Person                `json:"results"`
+	TotalCount    int                     `json:"total_count"`
+	Estimated     bool                    `json:"total_count_estimated,omitempty"` // total_count is estimated from a sample of the matches
+	ExecutionTime int                     `json:"execution_time_ms"`
+	SearchID      string                  `json:"search_id"`
+	HasMore       bool                    `json:"has_more"`
+	Facets        map[string][]FacetCount `json:"facets,omitempty"`
+	Nearby        *NearbySummary          `json:"nearby,omitempty"`
+	Debug         *SearchTrace            `json:"debug,omitempty"`
+	Quota         *SearchQuota            `json:"quota,omitempty"`
+	Columns       []string                `json:"columns,omitempty"` // Person columns returned, when the search restricted them
+	Mode          string                  `json:"mode"`              // Mode the search ran in: standard or enhanced_mobile
+}
+
+// Search modes. Auto runs an enhanced mobile search when the query is a mobile number and a standard
+// search otherwise; the other modes always run the one search.
+const (
+	SearchModeAuto           = "auto"
+	SearchModeStandard       = "standard"
+	SearchModeEnhancedMobile = "enhanced_mobile"
+)
+
+// Reasons a search was not counted against the daily search quota
+const (
+	SearchFreeDiagnostic  = "diagnostic"   // Admin diagnostic or impersonation search
+	SearchFreeDuplicate   = "duplicate"    // The same search was already made today
+	SearchFreeNoResults   = "no_results"   // The search found nothing
+	SearchFreeQuotaExempt = "quota_exempt" // The user's searches are not counted
+	SearchFreeReplay      = "replay"       // An admin viewing another user's or a diagnostic search
+)
+
+// SearchQuota reports what a search cost and the user's daily search quota after it, so clients need
+// no second request to show it
+type SearchQuota struct {
+	Charged           bool      `json:"charged"`
+	FreeReason        string    `json:"free_reason,omitempty"` // Why the search was not counted
+	SearchesUsedToday int       `json:"searches_used_today"`
+	SearchesLimit     int       `json:"searches_limit"`     // Includes today's search credits
+	SearchesRemaining int       `json:"searches_remaining"` // Also bounded by the organization's shared limit
+	ResetsAt          time.Time `json:"resets_at"`
+}
+
+// NewSearchQuota reports a search that was counted, or not for freeReason
+func NewSearchQuota(freeReason string) *SearchQuota {
+	return &SearchQuota{Charged: freeReason == "", FreeReason: freeReason}
+}
+
+// SetUsage fills in the user's quota usage after the search
+func (q *SearchQuota) SetUsage(usage *QuotaUsage) {
+	q.SearchesUsedToday = usage.Searches.Used
+	q.SearchesLimit = usage.Searches.Limit
+	q.SearchesRemaining = usage.Searches.Remaining
+	if org := usage.Organization; org != nil && org.Searches.Limit > 0 && org.Searches.Remaining < q.SearchesRemaining {
+		q.SearchesRemaining = org.Searches.Remaining
+	}
+	q.ResetsAt = usage.NextReset
+	if usage.SearchesExempt && q.Charged {
+		q.Charged = false
+		q.FreeReason = SearchFreeQuotaExempt
+	}
+}
+
+// SearchTrace represents how a search was executed, returned to admins who set debug
+type SearchTrace struct {
+	SQL          string        `json:"sql"`
+	Parameters   []string      `json:"parameters"` // Bound values with the searched text redacted
+	Indexes      []string      `json:"indexes"`    // Indexes ClickHouse used, from EXPLAIN indexes = 1
+	Plan         []string      `json:"plan,omitempty"`
+	CostEstimate *CostEstimate `json:"cost_estimate,omitempty"`
+	Decisions    []string      `json:"decisions"` // Routing, quota and duplicate decisions in order
+	Timings      []TraceTiming `json:"timings"`
+}
+
+// TraceTiming represents the time spent in one step of a search
+type TraceTiming struct {
+	Step       string  `json:"step"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// NearbyFilter restricts a search to the pincodes within a radius of a pincode and/or to pincode prefixes
+type NearbyFilter struct {
+	Pincode  string   `json:"pincode,omitempty"`
+	RadiusKm float64  `json:"radius_km,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"` // e.g. ["1100", "1220"]; matched in addition to the radius
+}
+
+// NearbyPincode represents a pincode within a nearby search's radius
+type NearbyPincode struct {
+	Pincode    string  `json:"pincode"`
+	DistanceKm float64 `json:"distance_km"`
+	Bucket     string  `json:"bucket"`
+}
+
+// DistanceBucket represents the matches of a nearby search within a distance range
+type DistanceBucket struct {
+	Label    string  `json:"label"`
+	MinKm    float64 `json:"min_km"`
+	MaxKm    float64 `json:"max_km"`
+	Pincodes int     `json:"pincodes"` // Known pincodes in the range
+	Count    uint64  `json:"count"`    // Matching records in the range
+}
+
+// NearbyMatch represents where a returned record lies relative to a nearby search's pincode
+type NearbyMatch struct {
+	PersonID   string  `json:"person_id"`
+	Pincode    string  `json:"pincode"`
+	DistanceKm float64 `json:"distance_km"` // -1 when the pincode is outside the radius, e.g. a prefix match
+	Bucket     string  `json:"bucket,omitempty"`
+}
+
+// NearbySummary represents the expansion and distance buckets of a nearby search
+type NearbySummary struct {
+	Pincode  string           `json:"pincode,omitempty"`
+	RadiusKm float64          `json:"radius_km,omitempty"`
+	Prefixes []string         `json:"prefixes,omitempty"`
+	Pincodes []NearbyPincode  `json:"pincodes"`
+	Buckets  []DistanceBucket `json:"buckets,omitempty"`
+	Matches  []NearbyMatch    `json:"matches"`
 }
 
 // CSVImportRequest represents a CSV import request
@@ -68,7 +325,9 @@ type CSVImportRequest struct {
 	FilePath  string         `json:"file_path" validate:"required"`
 	BatchSize int            `json:"batch_size" validate:"min=1000,max=1000000"`
 	HasHeader bool           `json:"has_header"`
-	Delimiter string         `json:"delimiter"`
+	Delimiter string         `json:"delimiter"` // comma, tab or pipe; detected when empty
+	Encoding  string         `json:"encoding"`  // UTF-8, ISO-8859-1 or Windows-1252; detected when empty
+	Sheet     string         `json:"sheet"`     // Sheet of an .xlsx workbook; the first sheet when empty
 	FieldMap  map[string]int `json:"field_map"` // Maps CSV column names to field positions
 }
 
@@ -76,12 +335,124 @@ type CSVImportRequest struct {
 type CSVImportResponse struct {
 	JobID         string     `json:"job_id"`
 	Status        string     `json:"status"`
+	Table         string     `json:"table"`               // ClickHouse table the rows were inserted into
+	Delimiter     string     `json:"delimiter,omitempty"` // Delimiter and encoding a CSV file was read with, as set or detected
+	Encoding      string     `json:"encoding,omitempty"`
+	Sheet         string     `json:"sheet,omitempty"` // Sheet a workbook was read from
 	TotalRows     int        `json:"total_rows"`
 	ProcessedRows int        `json:"processed_rows"`
+	UpdatedRows   int        `json:"updated_rows,omitempty"` // Processed rows that replaced an existing row
 	ErrorRows     int        `json:"error_rows"`
 	StartTime     time.Time  `json:"start_time"`
 	EndTime       *time.Time `json:"end_time,omitempty"`
 	Errors        []string   `json:"errors,omitempty"`
+	// Set when the failed rows were written to the job's error file, served at /admin/import/jobs/:job_id/errors
+	ErrorFile bool `json:"error_file,omitempty"`
+	// Circle values the circle normalization map does not know, most frequent first
+	UnmappedCircles []FacetCount `json:"unmapped_circles,omitempty"`
+	// What a delta import did with the processed rows
+	Delta *DeltaImportStats `json:"delta,omitempty"`
+}
+
+// DeltaImportStats counts the rows of a delta import by what happened to them
+type DeltaImportStats struct {
+	Added     int `json:"added"`     // New mobile and master ID, inserted
+	Changed   int `json:"changed"`   // Differ from the existing row, staged for review
+	Unchanged int `json:"unchanged"` // Identical to the existing row, skipped
+}
+
+// StagedChange is a row a delta import found changed, held for review with the row it would replace
+type StagedChange struct {
+	Incoming      Person    `json:"incoming"` // Carries the ID and created_at of the row it replaces
+	Current       *Person   `json:"current"`  // Nil when the row has been removed since
+	PeopleTable   string    `json:"people_table"`
+	ChangedFields []string  `json:"changed_fields"`
+	StagedAt      time.Time `json:"staged_at"`
+}
+
+// StagedChangesResult reports the staged changes of an import that were applied or discarded
+type StagedChangesResult struct {
+	JobID     string `json:"job_id"`
+	Applied   uint64 `json:"applied,omitempty"`
+	Discarded uint64 `json:"discarded,omitempty"`
+}
+
+// ImportRowsResponse represents the rows one import job left in a people table
+type ImportRowsResponse struct {
+	JobID      string     `json:"job_id"`
+	Table      string     `json:"table"`
+	SourceFile string     `json:"source_file"`
+	ImportedAt *time.Time `json:"imported_at,omitempty"`
+	RowCount   uint64     `json:"row_count"`
+	Deleted    bool       `json:"deleted"` // Set when the rows were just deleted
+	// Rows the import replaced with update_existing that were put back when it was rolled back
+	RestoredRows uint64 `json:"restored_rows,omitempty"`
+	// Changes a delta import staged that were discarded when it was rolled back
+	DiscardedChanges uint64 `json:"discarded_changes,omitempty"`
+}
+
+// CSVColumnProfile represents statistics and inferred field types for one CSV column
+type CSVColumnProfile struct {
+	Index          int                `json:"index"`
+	Header         string             `json:"header,omitempty"`
+	FilledCount    int                `json:"filled_count"`
+	DistinctCount  int                `json:"distinct_count"`
+	AvgLength      float64            `json:"avg_length"`
+	SampleValues   []string           `json:"sample_values"`
+	TypeScores     map[string]float64 `json:"type_scores"` // Share of sampled values that look like each type
+	SuggestedField string             `json:"suggested_field,omitempty"`
+}
+
+// CSVProfile represents the result of profiling a sample of a CSV file
+type CSVProfile struct {
+	SampledRows       int                `json:"sampled_rows"`
+	ColumnCount       int                `json:"column_count"`
+	HasHeader         bool               `json:"has_header"`
+	Delimiter         string             `json:"delimiter"` // Delimiter and encoding the sample was read with, as set or detected
+	Encoding          string             `json:"encoding"`
+	Columns           []CSVColumnProfile `json:"columns"`
+	SuggestedFieldMap map[string]int     `json:"suggested_field_map"` // Pass back as field_map to import with this layout
+	UnmappedFields    []string           `json:"unmapped_fields,omitempty"`
+}
+
+// CSVFieldValidation represents the statistics of one mapped field in a dry-run import
+type CSVFieldValidation struct {
+	Field             string   `json:"field"`
+	Column            int      `json:"column"`
+	FilledCount       int64    `json:"filled_count"`
+	EmptyCount        int64    `json:"empty_count"`
+	MalformedCount    int64    `json:"malformed_count"` // Mobiles and emails failing their format check
+	MalformedSamples  []string `json:"malformed_samples,omitempty"`
+	MinLength         int      `json:"min_length"`
+	MaxLength         int      `json:"max_length"`
+	AvgLength         float64  `json:"avg_length"`
+	MaxExpectedLength int      `json:"max_expected_length"`
+	LongCount         int64    `json:"long_count"`      // Values longer than max_expected_length
+	DuplicateCount    int64    `json:"duplicate_count"` // Filled values already seen earlier in the file
+	DuplicateRatio    float64  `json:"duplicate_ratio"` // Duplicates per filled value
+	DistinctCapped    bool     `json:"distinct_capped,omitempty"`
+}
+
+// CSVValidationReport represents the result of a dry-run import: what the file would load, without
+// inserting anything
+type CSVValidationReport struct {
+	DryRun        bool                 `json:"dry_run"`
+	Table         string               `json:"table"`               // ClickHouse table the rows would be inserted into
+	Delimiter     string               `json:"delimiter,omitempty"` // Delimiter and encoding a CSV file was read with, as set or detected
+	Encoding      string               `json:"encoding,omitempty"`
+	Sheet         string               `json:"sheet,omitempty"` // Sheet a workbook was read from
+	TotalRows     int64                `json:"total_rows"`
+	ValidRows     int64                `json:"valid_rows"` // Rows the import would insert
+	ErrorRows     int64                `json:"error_rows"` // Unreadable rows and rows with too few columns
+	Sampled       bool                 `json:"sampled"`    // The file has more rows than were read
+	FieldMap      map[string]int       `json:"field_map"`
+	Fields        []CSVFieldValidation `json:"fields"`
+	QualityFlags  map[string]int64     `json:"quality_flags"` // Rows per consistency flag, see utils.CheckConsistency
+	AvgConfidence float64              `json:"avg_confidence"`
+	Errors        []string             `json:"errors,omitempty"`
+	Duration      string               `json:"duration"`
+	// Circle values the circle normalization map does not know, most frequent first
+	UnmappedCircles []FacetCount `json:"unmapped_circles,omitempty"`
 }
 
 // SearchPerformance represents search performance metrics in ClickHouse
@@ -98,17 +469,33 @@ type SearchPerformance struct {
 type ExportRequest struct {
 	SearchID *string        `json:"search_id,omitempty"` // Export specific search results
 	Query    *SearchRequest `json:"query,omitempty"`     // Or provide new search query
-	Format   string         `json:"format" validate:"oneof=csv json"`
+	Format   string         `json:"format" validate:"oneof=csv json parquet"`
 	FileName string         `json:"file_name"`
+	// Deliver the file in an AES-256 encrypted ZIP. Password, when set, implies it; otherwise one is
+	// generated and returned once in the response.
+	Encrypt  bool   `json:"encrypt"`
+	Password string `json:"password,omitempty"`
+	// Write the first rows up to the user's export row limit instead of failing when more match, as
+	// export.row_limit_action truncate does for every export
+	Truncate bool `json:"truncate"`
+	// Queue the export on the job queue and return the job at once; the file is listed with the
+	// user's exports when it is written
+	Async bool `json:"async"`
 }
 
 // ExportResponse represents an export response
 type ExportResponse struct {
-	DownloadURL string    `json:"download_url"`
-	FileName    string    `json:"file_name"`
-	FileSize    int64     `json:"file_size"`
-	RowCount    int       `json:"row_count"`
-	ExpiresAt   time.Time `json:"expires_at"`
+	ExportID        *uuid.UUID `json:"export_id,omitempty"`
+	DownloadURL     string     `json:"download_url"`
+	FileName        string     `json:"file_name"`
+	FileSize        int64      `json:"file_size"`
+	RowCount        int        `json:"row_count"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	Method          string     `json:"method"` // standard or fast_path
+	RegeneratedFrom *uuid.UUID `json:"regenerated_from,omitempty"`
+	Encrypted       bool       `json:"encrypted"`
+	Truncated       bool       `json:"truncated"`          // More rows matched than the user's export row limit; the file has the first ones
+	Password        string     `json:"password,omitempty"` // Generated ZIP password; it is not stored and cannot be shown again
 }
 
 // BatchInsertResult represents the result of a batch insert operation
@@ -118,3 +505,212 @@ type BatchInsertResult struct {
 	Errors       []string      `json:"errors,omitempty"`
 	Duration     time.Duration `json:"duration"`
 }
+
+// ClickHouseIndex represents a data skipping index defined on the people table
+type ClickHouseIndex struct {
+	Name              string         `json:"name" ch:"name"`
+	Type              string         `json:"type" ch:"type"`
+	Expression        string         `json:"expression" ch:"expr"`
+	Granularity       uint64         `json:"granularity" ch:"granularity"`
+	CompressedBytes   uint64         `json:"compressed_bytes" ch:"data_compressed_bytes"`
+	UncompressedBytes uint64         `json:"uncompressed_bytes" ch:"data_uncompressed_bytes"`
+	Marks             uint64         `json:"marks" ch:"marks"`
+	State             string         `json:"state" ch:"-"` // materialized, materializing, not_materialized
+	Mutation          *IndexMutation `json:"mutation,omitempty" ch:"-"`
+}
+
+// IndexMutation represents the progress of a MATERIALIZE INDEX mutation
+type IndexMutation struct {
+	MutationID       string    `json:"mutation_id" ch:"mutation_id"`
+	Command          string    `json:"command" ch:"command"`
+	CreateTime       time.Time `json:"create_time" ch:"create_time"`
+	PartsToDo        int64     `json:"parts_to_do" ch:"parts_to_do"`
+	IsDone           uint8     `json:"is_done" ch:"is_done"`
+	LatestFailReason string    `json:"latest_fail_reason,omitempty" ch:"latest_fail_reason"`
+}
+
+// MaterializeIndexRequest represents a request to materialize indexes over existing data
+type MaterializeIndexRequest struct {
+	Indexes []string `json:"indexes"` // Index names; empty materializes every index
+}
+
+// PeopleTableInfo represents a people table version in ClickHouse
+type PeopleTableInfo struct {
+	Name           string   `json:"name" ch:"name"`
+	Engine         string   `json:"engine" ch:"engine"`
+	TotalRows      uint64   `json:"total_rows" ch:"total_rows"`
+	TotalBytes     uint64   `json:"total_bytes" ch:"total_bytes"`
+	Active         bool     `json:"active" ch:"-"`
+	MissingColumns []string `json:"missing_columns,omitempty" ch:"-"` // People columns the table lacks; it cannot be switched to
+}
+
+// PeopleTableSwitchover represents a change of the active people table
+type PeopleTableSwitchover struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	FromTable  string     `json:"from_table" db:"from_table"`
+	ToTable    string     `json:"to_table" db:"to_table"`
+	RowCount   int64      `json:"row_count" db:"row_count"`
+	SwitchedBy *uuid.UUID `json:"switched_by" db:"switched_by"`
+	SwitchedAt time.Time  `json:"switched_at" db:"switched_at"`
+}
+
+// PeopleTableStatus represents the active people table and the available versions
+type PeopleTableStatus struct {
+	ActiveTable  string                  `json:"active_table"`
+	DefaultTable string                  `json:"default_table"` // From configuration, used until the first switchover
+	Tables       []PeopleTableInfo       `json:"tables"`
+	History      []PeopleTableSwitchover `json:"history"`
+}
+
+// CreatePeopleTableRequest represents a request to create an empty people table version
+type CreatePeopleTableRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// SwitchPeopleTableRequest represents a request to make another people table active
+type SwitchPeopleTableRequest struct {
+	Table      string `json:"table" validate:"required"`
+	AllowEmpty bool   `json:"allow_empty"` // Switch even if the table has no rows
+}
+
+// ClickHouseTableStorage represents the active parts of one table in the people database
+type ClickHouseTableStorage struct {
+	Table             string  `json:"table" ch:"table"`
+	Rows              uint64  `json:"rows" ch:"rows"`
+	Parts             uint64  `json:"parts" ch:"parts"`
+	Partitions        uint64  `json:"partitions" ch:"partitions"`
+	MaxPartitionParts uint64  `json:"max_partition_parts" ch:"max_partition_parts"` // Parts in the most fragmented partition
+	BytesOnDisk       uint64  `json:"bytes_on_disk" ch:"bytes_on_disk"`
+	CompressedBytes   uint64  `json:"compressed_bytes" ch:"compressed_bytes"`
+	UncompressedBytes uint64  `json:"uncompressed_bytes" ch:"uncompressed_bytes"`
+	CompressionRatio  float64 `json:"compression_ratio" ch:"-"`
+}
+
+// ClickHouseMerge represents a merge or mutation in progress
+type ClickHouseMerge struct {
+	Table           string  `json:"table" ch:"table"`
+	ResultPart      string  `json:"result_part" ch:"result_part_name"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds" ch:"elapsed"`
+	Progress        float64 `json:"progress" ch:"progress"`
+	NumParts        uint64  `json:"num_parts" ch:"num_parts"`
+	CompressedBytes uint64  `json:"compressed_bytes" ch:"total_size_bytes_compressed"`
+	RowsRead        uint64  `json:"rows_read" ch:"rows_read"`
+	IsMutation      bool    `json:"is_mutation" ch:"is_mutation"`
+}
+
+// ClickHouseReplica represents the replication state of a replicated table
+type ClickHouseReplica struct {
+	Table                string `json:"table" ch:"table"`
+	IsReadonly           bool   `json:"is_readonly" ch:"is_readonly"`
+	IsSessionExpired     bool   `json:"is_session_expired" ch:"is_session_expired"`
+	AbsoluteDelaySeconds uint64 `json:"absolute_delay_seconds" ch:"absolute_delay"`
+	QueueSize            uint64 `json:"queue_size" ch:"queue_size"`
+	ActiveReplicas       uint64 `json:"active_replicas" ch:"active_replicas"`
+	TotalReplicas        uint64 `json:"total_replicas" ch:"total_replicas"`
+}
+
+// ClickHouseDisk represents the space on a ClickHouse disk
+type ClickHouseDisk struct {
+	Name       string `json:"name" ch:"name"`
+	Path       string `json:"path" ch:"path"`
+	FreeBytes  uint64 `json:"free_bytes" ch:"free_space"`
+	TotalBytes uint64 `json:"total_bytes" ch:"total_space"`
+}
+
+// ClickHouseWarning flags a table, replica or disk that needs attention
+type ClickHouseWarning struct {
+	Kind    string `json:"kind"` // parts, replica_lag, replica_readonly, disk_space
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+// ClickHouseSystemStatus represents the storage, merges and replication of the people database
+type ClickHouseSystemStatus struct {
+	Database string                   `json:"database"`
+	Tables   []ClickHouseTableStorage `json:"tables"`
+	Merges   []ClickHouseMerge        `json:"merges"`
+	Replicas []ClickHouseReplica      `json:"replicas"` // Empty unless tables use a Replicated engine
+	Disks    []ClickHouseDisk         `json:"disks"`
+	Warnings []ClickHouseWarning      `json:"warnings"`
+}
+
+// OptimizeTableRequest represents a request to merge the parts of a people table now
+type OptimizeTableRequest struct {
+	Table string `json:"table"` // Defaults to the active people table
+}
+
+// TableMaintenanceRun represents a partition-by-partition OPTIMIZE ... FINAL of a people table
+type TableMaintenanceRun struct {
+	Table            string     `json:"table"`
+	Status           string     `json:"status"` // running, completed, stopped (window ended), failed
+	Scheduled        bool       `json:"scheduled"`
+	TriggeredBy      *uuid.UUID `json:"triggered_by,omitempty"`
+	Partitions       int        `json:"partitions"` // Partitions with more than one active part when the run started
+	PartitionsDone   int        `json:"partitions_done"`
+	CurrentPartition string     `json:"current_partition,omitempty"`
+	StartedAt        time.Time  `json:"started_at"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	Error            string     `json:"error,omitempty"`
+}
+
+// TableMaintenanceStatus represents the current or last OPTIMIZE run of this instance and the next scheduled one
+type TableMaintenanceStatus struct {
+	Running bool                 `json:"running"`
+	Run     *TableMaintenanceRun `json:"run"`      // Null until the first run
+	NextRun *time.Time           `json:"next_run"` // Null when the nightly run is disabled
+}
+
+// AddressComponents represents the location a pincode belongs to
+type AddressComponents struct {
+	Pincode  string `json:"pincode"`
+	Locality string `json:"locality"`
+	City     string `json:"city"`
+	State    string `json:"state"`
+}
+
+// PincodeImportResponse represents the result of replacing the pincode directory
+type PincodeImportResponse struct {
+	Entries         int      `json:"entries"`
+	SkippedRows     int      `json:"skipped_rows"`
+	Errors          []string `json:"errors,omitempty"`
+	Rematerializing bool     `json:"rematerializing"` // Existing people rows are being recomputed
+}
+
+// PincodeDirectoryStatus represents the pincode directory used for address components
+type PincodeDirectoryStatus struct {
+	Entries uint64   `json:"entries"`
+	Fields  []string `json:"fields"` // People columns derived from the directory
+}
+
+// ValidateRecordsRequest represents people records to check for internal inconsistencies
+type ValidateRecordsRequest struct {
+	Records []Person `json:"records" binding:"required"`
+}
+
+// RecordValidation represents the quality flags raised by one validated record
+type RecordValidation struct {
+	Index        int      `json:"index"`
+	Mobile       string   `json:"mobile"`
+	QualityFlags []string `json:"quality_flags"`
+}
+
+// ValidateRecordsResponse represents the result of validating people records
+type ValidateRecordsResponse struct {
+	Total   int                `json:"total"`
+	Flagged int                `json:"flagged"`
+	Results []RecordValidation `json:"results"`
+}
+
+// QualityFlagCount represents how many people rows carry a quality flag
+type QualityFlagCount struct {
+	Flag  string `json:"flag" ch:"flag"`
+	Count uint64 `json:"count" ch:"count"`
+}
+
+// QualitySummary represents the quality flags across the active people table
+type QualitySummary struct {
+	Table       string             `json:"table"`
+	TotalRows   uint64             `json:"total_rows"`
+	FlaggedRows uint64             `json:"flagged_rows"`
+	Flags       []QualityFlagCount `json:"flags"`
+}
@@ -0,0 +1,138 @@
+package models
+
+import "time"
+
+// EffectiveConfig is the sanitized configuration a running instance is using. Durations are
+// rendered as Go duration strings and secrets are redacted.
+type EffectiveConfig struct {
+	Server        ServerSettings       `json:"server"`
+	Postgres      PostgresSettings     `json:"postgres"`
+	ClickHouse    ClickHouseSettings   `json:"clickhouse"`
+	Auth          AuthSettings         `json:"auth"`
+	Limits        LimitSettings        `json:"limits"`
+	Import        ImportSettings       `json:"import"`
+	Export        ExportSettings       `json:"export"`
+	Scheduler     SchedulerSettings    `json:"scheduler"`
+	Storage       StorageSettings      `json:"storage"`
+	Notifications NotificationSettings `json:"notifications"`
+	Features      map[string]bool      `json:"features"`
+	// Warnings lists insecure settings, such as placeholder secrets, that only debug mode tolerates
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type ServerSettings struct {
+	Host           string   `json:"host"`
+	Port           int      `json:"port"`
+	Timeout        string   `json:"timeout"`
+	CORSOrigins    []string `json:"cors_origins"`
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+type PostgresSettings struct {
+	Host            string `json:"host"`
+	Port            int    `json:"port"`
+	User            string `json:"user"`
+	Password        string `json:"password"`
+	DBName          string `json:"dbname"`
+	SSLMode         string `json:"sslmode"`
+	MaxOpenConns    int    `json:"max_open_conns"`
+	MaxIdleConns    int    `json:"max_idle_conns"`
+	ConnMaxLifetime string `json:"conn_max_lifetime"`
+	ConnMaxIdleTime string `json:"conn_max_idle_time"`
+}
+
+type ClickHouseSettings struct {
+	Host                    string `json:"host"`
+	Port                    int    `json:"port"`
+	HTTPPort                int    `json:"http_port"`
+	User                    string `json:"user"`
+	Password                string `json:"password"`
+	Database                string `json:"database"`
+	MaxOpenConns            int    `json:"max_open_conns"`
+	MaxIdleConns            int    `json:"max_idle_conns"`
+	ConnMaxLifetime         string `json:"conn_max_lifetime"`
+	DialTimeout             string `json:"dial_timeout"`
+	ReadTimeout             string `json:"read_timeout"`
+	ConnectRetries          int    `json:"connect_retries"`
+	ConnectBackoff          string `json:"connect_backoff"`
+	CircuitBreakerThreshold int    `json:"circuit_breaker_threshold"`
+	CircuitBreakerCooldown  string `json:"circuit_breaker_cooldown"`
+	PeopleTable             string `json:"people_table"`        // Configured default
+	ActivePeopleTable       string `json:"active_people_table"` // After any switchover
+}
+
+type AuthSettings struct {
+	JWTSecret    string   `json:"jwt_secret"`
+	JWTExpiry    string   `json:"jwt_expiry"`
+	SessionCache string   `json:"session_cache_ttl"`
+	SSOProviders []string `json:"sso_providers"` // Names of the OIDC providers users can sign in with
+}
+
+type LimitSettings struct {
+	MaxSearchesPerDay int    `json:"max_searches_per_day"`
+	MaxExportsPerDay  int    `json:"max_exports_per_day"`
+	MaxRowsPerSearch  int    `json:"max_rows_per_search"`
+	MaxExportRows     int    `json:"max_export_rows"`
+	MaxUploadSize     string `json:"max_upload_size"`
+
+	MaxExportRowsByUserType map[string]int                  `json:"max_export_rows_by_user_type"`
+	SearchBudgets           map[string]SearchBudgetSettings `json:"search_budgets"` // By user type, plus "default"
+}
+
+type SearchBudgetSettings struct {
+	Timeout        string `json:"timeout"`
+	MaxRowsToRead  uint64 `json:"max_rows_to_read"`
+	MaxResultBytes uint64 `json:"max_result_bytes"`
+}
+
+type ImportSettings struct {
+	BatchSize    int    `json:"batch_size"`
+	MaxRetries   int    `json:"max_retries"`
+	RetryBackoff string `json:"retry_backoff"`
+}
+
+type ExportSettings struct {
+	FastPathThreshold int      `json:"fast_path_threshold"`
+	Expiry            string   `json:"expiry"`
+	Formats           []string `json:"formats"`
+	RowLimitAction    string   `json:"row_limit_action"`
+}
+
+type SchedulerSettings struct {
+	QuotaResetTimezone string `json:"quota_reset_timezone"`
+	QuotaResetTime     string `json:"quota_reset_time"`
+	NextQuotaReset     string `json:"next_quota_reset"`
+	UsageCleanup       string `json:"usage_cleanup"`
+	JobWorkers         int    `json:"job_workers"`
+	JobPollInterval    string `json:"job_poll_interval"`
+	JobMaxAttempts     int    `json:"job_max_attempts"`
+	JobRetryBackoff    string `json:"job_retry_backoff"`
+	JobStaleAfter      string `json:"job_stale_after"`
+}
+
+type StorageSettings struct {
+	SearchBackend string `json:"search_backend"`
+	ExportBackend string `json:"export_backend"`
+	ExportDir     string `json:"export_dir"`
+	ExportBucket  string `json:"export_bucket,omitempty"`
+	ImportTempDir string `json:"import_temp_dir"`
+}
+
+type NotificationSettings struct {
+	Provider       string          `json:"provider"`
+	From           string          `json:"from"`
+	SMTPHost       string          `json:"smtp_host"`
+	SMTPPort       int             `json:"smtp_port"`
+	SMTPUsername   string          `json:"smtp_username"`
+	SMTPPassword   string          `json:"smtp_password"`
+	QuotaThreshold float64         `json:"quota_threshold"`
+	Events         map[string]bool `json:"events"`
+}
+
+// ConfigReloadResponse reports a configuration reload
+type ConfigReloadResponse struct {
+	ReloadedAt time.Time `json:"reloaded_at"`
+	// RestartRequired lists changed sections read only at startup: server, grpc or database
+	RestartRequired []string `json:"restart_required,omitempty"`
+	Warnings        []string `json:"warnings,omitempty"`
+}
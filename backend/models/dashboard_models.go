@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// DashboardUsers counts user accounts
+type DashboardUsers struct {
+	Total  int `json:"total" db:"total"`
+	Active int `json:"active" db:"active"` // Enabled and not expired
+}
+
+// DashboardUsage compares a usage count for the current and the previous quota day
+type DashboardUsage struct {
+	Today     int `json:"today"`
+	Yesterday int `json:"yesterday"`
+}
+
+// DashboardPending counts requests waiting for an admin
+type DashboardPending struct {
+	Registrations   int `json:"registrations"`
+	PasswordChanges int `json:"password_changes"`
+}
+
+// DashboardComponentHealth is the health of one database
+type DashboardComponentHealth struct {
+	Healthy   bool   `json:"healthy"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DashboardHealth is the health of the databases
+type DashboardHealth struct {
+	PostgreSQL            DashboardComponentHealth `json:"postgresql"`
+	ClickHouse            DashboardComponentHealth `json:"clickhouse"`
+	ClickHouseCircuitOpen bool                     `json:"clickhouse_circuit_open"`
+}
+
+// DashboardPeopleTable is the size of the active people table
+type DashboardPeopleTable struct {
+	Table       string `json:"table"`
+	Rows        uint64 `json:"rows"`
+	BytesOnDisk uint64 `json:"bytes_on_disk"`
+	Error       string `json:"error,omitempty"`
+}
+
+// AdminDashboardResponse gathers what the admin home screen shows in one response
+type AdminDashboardResponse struct {
+	QuotaDate      string               `json:"quota_date"`
+	Users          DashboardUsers       `json:"users"`
+	Searches       DashboardUsage       `json:"searches"`
+	Exports        DashboardUsage       `json:"exports"`
+	Pending        DashboardPending     `json:"pending_requests"`
+	ActiveSessions int                  `json:"active_sessions"`
+	Health         DashboardHealth      `json:"health"`
+	PeopleTable    DashboardPeopleTable `json:"people_table"`
+	LastImport     *CSVImportAudit      `json:"last_import"` // Null before the first import
+	GeneratedAt    time.Time            `json:"generated_at"`
+}
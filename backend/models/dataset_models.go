@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dataset represents a dataset hosted in its own ClickHouse people table, e.g. one telecom circle or vintage
+type Dataset struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Slug        string     `json:"slug" db:"slug"`
+	Name        string     `json:"name" db:"name"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	TableName   string     `json:"table" db:"table_name"` // Unqualified table in the people database
+	IsActive    bool       `json:"is_active" db:"is_active"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// CreateDatasetRequest represents an admin request to register a dataset
+type CreateDatasetRequest struct {
+	Slug        string `json:"slug" validate:"required"`
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+	Table       string `json:"table" validate:"required"` // Existing table with every people column
+}
+
+// UpdateDatasetRequest represents an admin request to update a dataset; deactivated datasets cannot be used
+type UpdateDatasetRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Table       *string `json:"table"`
+	IsActive    *bool   `json:"is_active"`
+}
+
+// DatasetAccess represents a user's entitlement to a dataset
+type DatasetAccess struct {
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	UserEmail string     `json:"user_email" db:"user_email"`
+	DatasetID uuid.UUID  `json:"dataset_id" db:"dataset_id"`
+	GrantedBy *uuid.UUID `json:"granted_by,omitempty" db:"granted_by"`
+	GrantedAt time.Time  `json:"granted_at" db:"granted_at"`
+}
+
+// GrantDatasetAccessRequest represents an admin request to entitle users to a dataset
+type GrantDatasetAccessRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" validate:"required"`
+}
+
+// DatasetStats represents the size and usage of a dataset
+type DatasetStats struct {
+	Dataset         Dataset `json:"dataset"`
+	TotalRows       uint64  `json:"total_rows"`
+	TotalBytes      uint64  `json:"total_bytes"`
+	SearchesLast24h int     `json:"searches_last_24h"`
+	SearchesLast30d int     `json:"searches_last_30d"`
+	AvgSearchTimeMs float64 `json:"avg_search_time_ms"` // Over the last 24 hours
+	EntitledUsers   int     `json:"entitled_users"`
+}
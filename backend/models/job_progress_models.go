@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of jobs that report progress
+const (
+	JobKindImport = "import"
+	JobKindExport = "export"
+)
+
+// Job progress statuses
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+)
+
+// JobProgressEvent reports the progress of a running import or export, streamed to clients over /ws
+type JobProgressEvent struct {
+	JobID         string    `json:"job_id"`
+	Kind          string    `json:"kind"`   // import, export
+	Status        string    `json:"status"` // running, completed, failed
+	UserID        uuid.UUID `json:"user_id"`
+	Name          string    `json:"name,omitempty"` // File imported or exported
+	RowsProcessed int       `json:"rows_processed"`
+	ErrorRows     int       `json:"error_rows"`
+	TotalRows     int       `json:"total_rows,omitempty"` // 0 when not known in advance, e.g. imports
+	Percent       float64   `json:"percent"`
+	ETASeconds    *int      `json:"eta_seconds,omitempty"` // Until the job completes, once it can be estimated
+	Errors        []string  `json:"errors,omitempty"`      // Latest row errors
+	Error         string    `json:"error,omitempty"`       // Why the job failed
+	StartedAt     time.Time `json:"started_at"`
+	Timestamp     time.Time `json:"timestamp"`
+}
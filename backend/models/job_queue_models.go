@@ -0,0 +1,27 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job is a unit of background work in the persistent job queue, such as an async import or export
+type Job struct {
+	ID          uuid.UUID        `json:"id" db:"id"`
+	Type        string           `json:"type" db:"type"`
+	Payload     json.RawMessage  `json:"payload" db:"payload"`
+	Status      string           `json:"status" db:"status"` // queued, running, succeeded or dead
+	Attempts    int              `json:"attempts" db:"attempts"`
+	MaxAttempts int              `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time        `json:"run_at" db:"run_at"`
+	LockedBy    *string          `json:"locked_by,omitempty" db:"locked_by"`
+	LockedAt    *time.Time       `json:"locked_at,omitempty" db:"locked_at"`
+	LastError   *string          `json:"last_error,omitempty" db:"last_error"`
+	Result      *json.RawMessage `json:"result,omitempty" db:"result"`
+	CreatedBy   *uuid.UUID       `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
+	FinishedAt  *time.Time       `json:"finished_at,omitempty" db:"finished_at"`
+}
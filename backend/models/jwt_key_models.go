@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JWT signing key statuses
+const (
+	JWTKeyActive    = "active"    // Signs new tokens
+	JWTKeyVerifying = "verifying" // Rotated out; still accepts the tokens it signed
+	JWTKeyExpired   = "expired"   // Rotated out and every token it signed has expired
+	JWTKeyRetired   = "retired"   // Retired by an admin; its tokens are rejected
+)
+
+// ConfigJWTKeyID identifies jwt.secret from the configuration, which signs tokens without a kid header
+// until the first rotation
+const ConfigJWTKeyID = "config"
+
+// JWTSigningKey represents a key that signs or verifies session tokens. The secret is never returned.
+type JWTSigningKey struct {
+	KID         string     `json:"kid" db:"kid"`
+	Algorithm   string     `json:"algorithm" db:"algorithm"` // HS256 or RS256
+	Secret      string     `json:"-" db:"secret"`
+	Status      string     `json:"status" db:"-"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	RotatedAt   *time.Time `json:"rotated_at,omitempty" db:"rotated_at"`
+	VerifyUntil *time.Time `json:"verify_until,omitempty" db:"verify_until"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty" db:"retired_at"`
+}
+
+// ConfigJWTKey reports whether tokens signed with jwt.secret, which carry no kid, are still accepted
+type ConfigJWTKey struct {
+	KID         string     `json:"kid"`
+	Algorithm   string     `json:"algorithm"`
+	Status      string     `json:"status"`
+	RotatedAt   *time.Time `json:"rotated_at,omitempty"`
+	VerifyUntil *time.Time `json:"verify_until,omitempty"`
+}
+
+// JWTKeyListResponse lists the signing keys, newest first
+type JWTKeyListResponse struct {
+	Keys      []JWTSigningKey `json:"keys"`
+	ConfigKey ConfigJWTKey    `json:"config_key"`
+}
+
+// RotateJWTKeyRequest represents a request to start signing tokens with a new key
+type RotateJWTKeyRequest struct {
+	Algorithm string `json:"algorithm"` // HS256 (default) or RS256
+}
+
+// JSONWebKey is the public half of an RS256 signing key, as published in the JWKS
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KID       string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// JSONWebKeySet lists the public keys that verify RS256 session tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
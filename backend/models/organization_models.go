@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization roles of members
+const (
+	OrganizationRoleMember = "MEMBER"
+	OrganizationRoleAdmin  = "ADMIN" // Manages the organization's members
+)
+
+// Organization represents a customer account, e.g. an agency, holding seats for its members
+type Organization struct {
+	ID                uuid.UUID  `json:"id" db:"id"`
+	Name              string     `json:"name" db:"name"`
+	MaxMembers        int        `json:"max_members" db:"max_members"`                   // 0 for no limit
+	MaxSearchesPerDay int        `json:"max_searches_per_day" db:"max_searches_per_day"` // Shared by all members; 0 for no limit
+	MaxExportsPerDay  int        `json:"max_exports_per_day" db:"max_exports_per_day"`   // Shared by all members; 0 for no limit
+	CreatedBy         *uuid.UUID `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	Members           int        `json:"members" db:"members"`
+}
+
+// CreateOrganizationRequest represents an admin request to create an organization
+type CreateOrganizationRequest struct {
+	Name              string `json:"name" validate:"required"`
+	MaxMembers        int    `json:"max_members"`
+	MaxSearchesPerDay int    `json:"max_searches_per_day"`
+	MaxExportsPerDay  int    `json:"max_exports_per_day"`
+}
+
+// UpdateOrganizationRequest represents an admin request to update an organization
+type UpdateOrganizationRequest struct {
+	Name              *string `json:"name"`
+	MaxMembers        *int    `json:"max_members"`
+	MaxSearchesPerDay *int    `json:"max_searches_per_day"`
+	MaxExportsPerDay  *int    `json:"max_exports_per_day"`
+}
+
+// OrganizationMember represents a user's membership of an organization with their usage today
+type OrganizationMember struct {
+	UserID            uuid.UUID  `json:"user_id" db:"user_id"`
+	Name              string     `json:"name" db:"name"`
+	Email             string     `json:"email" db:"email"`
+	Role              string     `json:"role" db:"role"` // MEMBER, ADMIN
+	IsActive          bool       `json:"is_active" db:"is_active"`
+	ExpiresAt         *time.Time `json:"expires_at" db:"expires_at"`
+	MaxSearchesPerDay int        `json:"max_searches_per_day" db:"max_searches_per_day"`
+	MaxExportsPerDay  int        `json:"max_exports_per_day" db:"max_exports_per_day"`
+	TodaySearches     int        `json:"today_searches" db:"today_searches"`
+	TodayExports      int        `json:"today_exports" db:"today_exports"`
+	AddedAt           time.Time  `json:"added_at" db:"added_at"`
+}
+
+// AddOrganizationMemberRequest represents an admin request to add an existing user to an organization
+type AddOrganizationMemberRequest struct {
+	Role string `json:"role"` // MEMBER (default) or ADMIN
+}
+
+// CreateOrganizationMemberRequest represents an organization admin's request to create a member account
+type CreateOrganizationMemberRequest struct {
+	Name              string     `json:"name" binding:"required"`
+	Email             string     `json:"email" binding:"required"`
+	Password          string     `json:"password"` // Generated when empty
+	Role              string     `json:"role"`     // Organization role: MEMBER (default) or ADMIN
+	ExpiresAt         *time.Time `json:"expires_at"`
+	MaxSearchesPerDay int        `json:"max_searches_per_day"`
+	MaxExportsPerDay  int        `json:"max_exports_per_day"`
+}
+
+// UpdateOrganizationMemberRequest represents an organization admin's request to update a member
+type UpdateOrganizationMemberRequest struct {
+	Role              *string `json:"role"`
+	IsActive          *bool   `json:"is_active"`
+	MaxSearchesPerDay *int    `json:"max_searches_per_day"`
+	MaxExportsPerDay  *int    `json:"max_exports_per_day"`
+}
+
+// CreatedOrganizationMember is a member account created by an organization admin
+type CreatedOrganizationMember struct {
+	User     User   `json:"user"`
+	Role     string `json:"role"`
+	Password string `json:"password,omitempty"` // Generated password, when none was given
+}
+
+// OrganizationDetails represents an organization with its members and shared usage today
+type OrganizationDetails struct {
+	Organization Organization         `json:"organization"`
+	Searches     QuotaStatus          `json:"searches"` // Limit 0 means no shared limit
+	Exports      QuotaStatus          `json:"exports"`
+	Members      []OrganizationMember `json:"members"`
+}
+
+// OrganizationQuota reports an organization's shared quotas on a member's quota usage
+type OrganizationQuota struct {
+	ID       uuid.UUID   `json:"id"`
+	Name     string      `json:"name"`
+	Searches QuotaStatus `json:"searches"` // Limit 0 means no shared limit
+	Exports  QuotaStatus `json:"exports"`
+}
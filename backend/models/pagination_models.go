@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCursor is returned for a cursor not made by PageParams.Cursor
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageParams selects one page of a list endpoint
+type PageParams struct {
+	Page  int // From 1
+	Limit int
+}
+
+// Offset returns the number of items before the page
+func (p PageParams) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// Cursor returns the opaque cursor of the page, accepted by list endpoints in place of page and limit
+func (p PageParams) Cursor() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", p.Page, p.Limit)))
+}
+
+// ParseCursor decodes a cursor made by PageParams.Cursor
+func ParseCursor(cursor string) (PageParams, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return PageParams{}, ErrInvalidCursor
+	}
+	var params PageParams
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &params.Page, &params.Limit); err != nil || params.Page < 1 || params.Limit < 1 {
+		return PageParams{}, ErrInvalidCursor
+	}
+	return params, nil
+}
+
+// Paginated is the response of every paged list endpoint
+type Paginated[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"` // Cursor of the next page, when there is one
+}
+
+// NewPaginated returns one page of items out of total
+func NewPaginated[T any](items []T, total int, params PageParams) *Paginated[T] {
+	if items == nil {
+		items = []T{}
+	}
+	page := &Paginated[T]{
+		Items:   items,
+		Total:   total,
+		Page:    params.Page,
+		Limit:   params.Limit,
+		HasMore: params.Offset()+len(items) < total,
+	}
+	if page.HasMore {
+		page.NextCursor = PageParams{Page: params.Page + 1, Limit: params.Limit}.Cursor()
+	}
+	return page
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreatePersonRequest represents a person row added by an admin
+type CreatePersonRequest struct {
+	MasterID string `json:"master_id"`
+	Mobile   string `json:"mobile" binding:"required"`
+	Name     string `json:"name"`
+	FName    string `json:"fname"`
+	Address  string `json:"address"`
+	Alt      string `json:"alt"`
+	Circle   string `json:"circle"`
+	Email    string `json:"email"`
+	Reason   string `json:"reason"` // Recorded in the edit history
+}
+
+// UpdatePersonRequest represents a correction to a person row; fields left out are unchanged
+type UpdatePersonRequest struct {
+	MasterID *string `json:"master_id"`
+	Mobile   *string `json:"mobile"`
+	Name     *string `json:"name"`
+	FName    *string `json:"fname"`
+	Address  *string `json:"address"`
+	Alt      *string `json:"alt"`
+	Circle   *string `json:"circle"`
+	Email    *string `json:"email"`
+	Reason   string  `json:"reason"`
+}
+
+// DeletePersonRequest represents removing a person row
+type DeletePersonRequest struct {
+	Reason string `json:"reason"`
+}
+
+// PersonEdit represents one manual edit of a person row
+type PersonEdit struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	PersonID    string          `json:"person_id" db:"person_id"`
+	PeopleTable string          `json:"people_table" db:"people_table"`
+	Action      string          `json:"action" db:"action"` // CREATE, UPDATE or DELETE
+	Before      json.RawMessage `json:"before" db:"before"` // null for creates
+	After       json.RawMessage `json:"after" db:"after"`   // null for deletes
+	Reason      *string         `json:"reason,omitempty" db:"reason"`
+	EditedBy    *uuid.UUID      `json:"edited_by,omitempty" db:"edited_by"`
+	EditedAt    time.Time       `json:"edited_at" db:"edited_at"`
+}
+
+// What replaced a prior version of a person row in the people history
+const (
+	PersonChangeImport = "import" // An import with update_existing
+	PersonChangeEdit   = "edit"   // A manual edit
+	PersonChangeDelete = "delete" // A manual delete
+)
+
+// PersonVersion is a prior version of a person row. Person.ImportJobID is the import that wrote the
+// version; SupersededByJobID is the import that replaced it.
+type PersonVersion struct {
+	Person            Person    `json:"person"`
+	PeopleTable       string    `json:"people_table"`
+	Change            string    `json:"change"`
+	SupersededAt      time.Time `json:"superseded_at"`
+	SupersededByJobID string    `json:"superseded_by_job_id,omitempty"`
+}
+
+// PersonHistory is the current version of a person row and its prior versions, newest first
+type PersonHistory struct {
+	PersonID string          `json:"person_id"`
+	Current  *Person         `json:"current"` // null when the row was deleted
+	Versions []PersonVersion `json:"versions"`
+}
@@ -1,9 +1,11 @@
 package models
 
 import (
+	"context"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // User represents a user in the PostgreSQL database
@@ -20,15 +22,37 @@ type User struct {
 	MaxExportsPerDay  int        `json:"max_exports_per_day" db:"max_exports_per_day"`
 	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
+	// AllowedSearchFields restricts the fields the user may search on; empty allows every field
+	AllowedSearchFields pq.StringArray `json:"allowed_search_fields" db:"allowed_search_fields"`
+	// QuotaExempt searches are logged but not checked against or counted toward the daily search limit
+	QuotaExempt bool `json:"quota_exempt" db:"quota_exempt"`
+	// RateLimitPerMinute overrides the API rate limit for the user type; nil uses the configured limit
+	RateLimitPerMinute *int `json:"rate_limit_per_minute" db:"rate_limit_per_minute"`
+	// MaxExportRows overrides the export row limit for the user type; nil uses the configured limit
+	MaxExportRows *int `json:"max_export_rows" db:"max_export_rows"`
 }
 
 // Login represents a login record
 type Login struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	UserID    uuid.UUID `json:"user_id" db:"user_id"`
-	LoginTime time.Time `json:"login_time" db:"login_time"`
-	IPAddress string    `json:"ip_address" db:"ip_address"`
-	UserAgent string    `json:"user_agent" db:"user_agent"`
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	LoginTime time.Time  `json:"login_time" db:"login_time"`
+	IPAddress string     `json:"ip_address" db:"ip_address"`
+	UserAgent string     `json:"user_agent" db:"user_agent"`
+	SessionID *uuid.UUID `json:"session_id" db:"session_id"`
+	// Where the login came from, located by GeoIP
+	Country     *string `json:"country" db:"country"`
+	City        *string `json:"city" db:"city"`
+	ASN         *int64  `json:"asn" db:"asn"`
+	ASOrg       *string `json:"as_org" db:"as_org"`
+	NewLocation bool    `json:"new_location" db:"new_location"` // First login of the account from this country
+}
+
+// LoginAuditEntry is a login in the admin login audit, with the account it was for
+type LoginAuditEntry struct {
+	Login
+	Email string `json:"email" db:"email"`
+	Name  string `json:"name" db:"name"`
 }
 
 // Search represents a search log entry
@@ -39,16 +63,70 @@ type Search struct {
 	SearchTime      time.Time   `json:"search_time" db:"search_time"`
 	ResultCount     int         `json:"result_count" db:"result_count"`
 	ExecutionTimeMs int         `json:"execution_time_ms" db:"execution_time_ms"`
+	ClientID        *string     `json:"client_id,omitempty" db:"client_id"`             // X-Client-Id of the calling application
+	IsDiagnostic    bool        `json:"is_diagnostic" db:"is_diagnostic"`               // Admin diagnostic search, exempt from quota
+	AnonymizedAt    *time.Time  `json:"anonymized_at,omitempty" db:"anonymized_at"`     // Searched values were dropped by retention
+	RequestID       *string     `json:"request_id,omitempty" db:"request_id"`           // X-Request-Id of the API call, also in search_performance
+	DatasetID       *uuid.UUID  `json:"dataset_id,omitempty" db:"dataset_id"`           // Dataset searched; empty for the default people table
+	ImpersonatedBy  *uuid.UUID  `json:"impersonated_by,omitempty" db:"impersonated_by"` // Admin who searched while impersonating the user
 }
 
 // Export represents an export log entry
 type Export struct {
-	ID            uuid.UUID  `json:"id" db:"id"`
+	ID              uuid.UUID  `json:"id" db:"id"`
+	UserID          uuid.UUID  `json:"user_id" db:"user_id"`
+	SearchID        *uuid.UUID `json:"search_id" db:"search_id"`
+	ExportedAt      time.Time  `json:"exported_at" db:"exported_at"`
+	RowCount        int        `json:"row_count" db:"row_count"`
+	FileSizeBytes   int64      `json:"file_size_bytes" db:"file_size_bytes"`
+	FileName        *string    `json:"file_name" db:"file_name"`
+	Format          *string    `json:"format" db:"format"`
+	SearchQuery     []byte     `json:"-" db:"search_query"` // Search that was exported, used to regenerate the file
+	ExpiresAt       *time.Time `json:"expires_at" db:"expires_at"`
+	RegeneratedFrom *uuid.UUID `json:"regenerated_from,omitempty" db:"regenerated_from"`
+	Storage         string     `json:"storage" db:"storage"`     // local or s3
+	Expired         bool       `json:"expired" db:"expired"`     // The file was removed after it expired
+	Encrypted       bool       `json:"encrypted" db:"encrypted"` // Delivered as a password-protected ZIP
+	Watermark       *string    `json:"-" db:"watermark"`         // Leak tracing token embedded in the file
+	WatermarkMode   *string    `json:"-" db:"watermark_mode"`    // column, order or both
+	Truncated       bool       `json:"truncated" db:"truncated"` // Stopped at the user's export row limit
+}
+
+// ExportTraceRequest identifies a leaked export file by its export_watermark value, or by person IDs
+// in the order they appear in the file
+type ExportTraceRequest struct {
+	Watermark string     `json:"watermark"`
+	IDs       []string   `json:"ids"`
+	Since     *time.Time `json:"since"` // Only exports made after this are compared by row order; 90 days by default
+}
+
+// ExportTraceMatch is an export a leaked file was traced to, with the account that made it
+type ExportTraceMatch struct {
+	ExportID      uuid.UUID  `json:"export_id" db:"id"`
 	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
-	SearchID      *uuid.UUID `json:"search_id" db:"search_id"`
+	UserName      string     `json:"user_name" db:"user_name"`
+	UserEmail     string     `json:"user_email" db:"user_email"`
 	ExportedAt    time.Time  `json:"exported_at" db:"exported_at"`
+	FileName      *string    `json:"file_name" db:"file_name"`
 	RowCount      int        `json:"row_count" db:"row_count"`
-	FileSizeBytes int64      `json:"file_size_bytes" db:"file_size_bytes"`
+	Watermark     string     `json:"watermark" db:"watermark"`
+	WatermarkMode string     `json:"watermark_mode" db:"watermark_mode"`
+	SearchID      *uuid.UUID `json:"search_id" db:"search_id"`
+}
+
+// ExportTraceResponse lists the exports a leaked file was traced to
+type ExportTraceResponse struct {
+	Method     string             `json:"method"`               // watermark or order
+	Candidates int                `json:"candidates,omitempty"` // Exports compared by row order
+	Matches    []ExportTraceMatch `json:"matches"`
+}
+
+// ExportRecord represents a past export and whether its file can still be downloaded
+type ExportRecord struct {
+	Export
+	Available   bool   `json:"available"`
+	DownloadURL string `json:"download_url,omitempty"`
+	Regenerable bool   `json:"regenerable"` // Expired, with its query recorded, so it can be regenerated
 }
 
 // DailyUsage represents daily usage statistics
@@ -58,19 +136,98 @@ type DailyUsage struct {
 	Date        time.Time `json:"date" db:"date"`
 	SearchCount int       `json:"search_count" db:"search_count"`
 	ExportCount int       `json:"export_count" db:"export_count"`
+	CreditsUsed int       `json:"credits_used" db:"credits_used"` // Searches beyond the daily limit paid for with credits
+	// Searches beyond the daily limit covered by quota adjustments
+	AdjustedSearchesUsed int `json:"adjusted_searches_used" db:"adjusted_searches_used"`
+}
+
+// SearchCredit represents a one-off grant of extra searches to a user for a single quota day
+type SearchCredit struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Date      time.Time  `json:"date" db:"date"`
+	Credits   int        `json:"credits" db:"credits"`
+	Reason    *string    `json:"reason" db:"reason"`
+	GrantedBy *uuid.UUID `json:"granted_by" db:"granted_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// GrantSearchCreditsRequest represents an admin request to grant extra searches for today
+type GrantSearchCreditsRequest struct {
+	Credits int    `json:"credits" validate:"required,min=1"`
+	Reason  string `json:"reason"`
+}
+
+// SearchCreditsResponse represents a user's search credits for the current quota day
+type SearchCreditsResponse struct {
+	UserID            uuid.UUID      `json:"user_id"`
+	Date              string         `json:"date"`
+	MaxSearchesPerDay int            `json:"max_searches_per_day"`
+	Granted           int            `json:"granted"`
+	Used              int            `json:"used"`
+	Remaining         int            `json:"remaining"`
+	Grants            []SearchCredit `json:"grants"`
+}
+
+// QuotaAdjustment represents extra searches and exports given to a user for a single quota day
+type QuotaAdjustment struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
+	Date          time.Time  `json:"date" db:"date"`
+	ExtraSearches int        `json:"extra_searches" db:"extra_searches"`
+	ExtraExports  int        `json:"extra_exports" db:"extra_exports"`
+	Reason        string     `json:"reason" db:"reason"`
+	AdjustedBy    *uuid.UUID `json:"adjusted_by" db:"adjusted_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+// QuotaAdjustmentRequest represents an admin request to raise a user's limits for today only
+type QuotaAdjustmentRequest struct {
+	ExtraSearches int    `json:"extra_searches"`
+	ExtraExports  int    `json:"extra_exports"`
+	Reason        string `json:"reason" validate:"required"`
+}
+
+// QuotaUsage represents a user's usage against their daily limits for the current quota day
+type QuotaUsage struct {
+	Date           string      `json:"date"`
+	Searches       QuotaStatus `json:"searches"`        // Limit includes today's search credits and adjustments
+	SearchesExempt bool        `json:"searches_exempt"` // Searches are not limited or counted
+	SearchCredits  int         `json:"search_credits"`
+	Exports        QuotaStatus `json:"exports"` // Limit includes today's adjustments
+	// Extra searches and exports from today's quota adjustments
+	AdjustedSearches int `json:"adjusted_searches,omitempty"`
+	AdjustedExports  int `json:"adjusted_exports,omitempty"`
+	// Organization reports the shared quotas of the user's organization, if any
+	Organization   *OrganizationQuota `json:"organization,omitempty"`
+	NextReset      time.Time          `json:"next_reset"`
+	TimeUntilReset string             `json:"time_until_reset"`
 }
 
 // UserSession represents an active user session
 type UserSession struct {
-	ID           uuid.UUID  `json:"id" db:"id"`
-	UserID       uuid.UUID  `json:"user_id" db:"user_id"`
-	SessionToken string     `json:"-" db:"session_token"` // Hash of JWT token
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
-	IsActive     bool       `json:"is_active" db:"is_active"`
-	IPAddress    string     `json:"ip_address" db:"ip_address"`
-	UserAgent    string     `json:"user_agent" db:"user_agent"`
-	LoggedOutAt  *time.Time `json:"logged_out_at" db:"logged_out_at"`
+	ID             uuid.UUID  `json:"id" db:"id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	SessionToken   string     `json:"-" db:"session_token"` // Hash of JWT token
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at" db:"expires_at"`
+	IsActive       bool       `json:"is_active" db:"is_active"`
+	IPAddress      string     `json:"ip_address" db:"ip_address"`
+	UserAgent      string     `json:"user_agent" db:"user_agent"`
+	LoggedOutAt    *time.Time `json:"logged_out_at" db:"logged_out_at"`
+	LastActivityAt *time.Time `json:"last_activity_at" db:"last_activity_at"`
+	ImpersonatedBy *uuid.UUID `json:"impersonated_by,omitempty" db:"impersonated_by"` // Admin acting as the user in this session
+	// Device the session was created from, parsed from its user agent
+	Browser           *string `json:"browser" db:"browser"`
+	OS                *string `json:"os" db:"os"`
+	DeviceType        *string `json:"device_type" db:"device_type"` // desktop, mobile, tablet, bot or other
+	DeviceFingerprint *string `json:"device_fingerprint" db:"device_fingerprint"`
+	Country           *string `json:"country" db:"country"` // ISO country code of the client, when known
+	City              *string `json:"city" db:"city"`
+	ASN               *int64  `json:"asn" db:"asn"`       // Autonomous system number of the client's network
+	ASOrg             *string `json:"as_org" db:"as_org"` // Owner of the client's network, e.g. an ISP or cloud provider
+	// The session's login was the account's first from its country
+	NewLocation bool `json:"new_location" db:"new_location"`
 }
 
 // LoginRequest represents the login request payload
@@ -87,6 +244,36 @@ type LoginResponse struct {
 	SessionID string    `json:"session_id"`
 }
 
+// ImpersonateRequest represents a request to act as a user for support
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required"` // Why, e.g. the support ticket being reproduced
+}
+
+// ImpersonationResponse represents a short-lived session acting as a user
+type ImpersonationResponse struct {
+	Token          string    `json:"token"`
+	User           User      `json:"user"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	SessionID      string    `json:"session_id"`
+	ImpersonatedBy uuid.UUID `json:"impersonated_by"`
+}
+
+// UserImpersonation records an impersonation session issued to an admin
+type UserImpersonation struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	AdminID    *uuid.UUID `json:"admin_id" db:"admin_id"`
+	AdminEmail *string    `json:"admin_email" db:"admin_email"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	UserEmail  string     `json:"user_email" db:"user_email"`
+	SessionID  *uuid.UUID `json:"session_id" db:"session_id"`
+	Reason     string     `json:"reason" db:"reason"`
+	IPAddress  *string    `json:"ip_address" db:"ip_address"`
+	UserAgent  *string    `json:"user_agent" db:"user_agent"`
+	StartedAt  time.Time  `json:"started_at" db:"started_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	Searches   int        `json:"searches" db:"searches"` // Searches made in the session
+}
+
 // CreateUserRequest represents the create user request payload
 type CreateUserRequest struct {
 	Name              string     `json:"name" validate:"required"`
@@ -97,6 +284,37 @@ type CreateUserRequest struct {
 	ExpiresAt         *time.Time `json:"expires_at"`
 	MaxSearchesPerDay int        `json:"max_searches_per_day"`
 	MaxExportsPerDay  int        `json:"max_exports_per_day"`
+	// AllowedSearchFields limits searches to these fields, e.g. ["mobile"] for reverse lookups only
+	AllowedSearchFields []string `json:"allowed_search_fields"`
+	QuotaExempt         bool     `json:"quota_exempt"`
+}
+
+// BulkCreateUsersRequest represents a request to create many users at once. Users without a password
+// get a generated one.
+type BulkCreateUsersRequest struct {
+	Users            []CreateUserRequest `json:"users"`
+	SendWelcomeEmail bool                `json:"send_welcome_email"` // Email each user their login details
+}
+
+// BulkCreatedUser is a user created by a bulk request
+type BulkCreatedUser struct {
+	Row      int    `json:"row"` // 1-based position in the request
+	User     User   `json:"user"`
+	Password string `json:"password,omitempty"` // Generated password, when none was given
+}
+
+// BulkUserError explains why a row of a bulk request was rejected
+type BulkUserError struct {
+	Row   int    `json:"row"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
+
+// BulkCreateUsersResponse represents the result of a bulk user creation
+type BulkCreateUsersResponse struct {
+	Created      int               `json:"created"`
+	Users        []BulkCreatedUser `json:"users"`
+	EmailsQueued bool              `json:"emails_queued"`
 }
 
 // UpdateUserRequest represents the update user request payload
@@ -109,37 +327,96 @@ type UpdateUserRequest struct {
 	ExpiresAt         *time.Time `json:"expires_at"`
 	MaxSearchesPerDay *int       `json:"max_searches_per_day"`
 	MaxExportsPerDay  *int       `json:"max_exports_per_day"`
+	// AllowedSearchFields replaces the user's allowed search fields; an empty list lifts the restriction
+	AllowedSearchFields *[]string `json:"allowed_search_fields"`
+	QuotaExempt         *bool     `json:"quota_exempt"`
+	// RateLimitPerMinute sets the user's API rate limit; 0 clears it, falling back to the configured limit
+	RateLimitPerMinute *int `json:"rate_limit_per_minute" validate:"omitempty,min=0"`
+	// MaxExportRows sets the rows an export may contain; 0 clears it, falling back to the configured
+	// limit for the user type
+	MaxExportRows *int `json:"max_export_rows" validate:"omitempty,min=0"`
+}
+
+// UpgradeUserRequest represents an admin request to upgrade a DEMO user to PERMANENT.
+// Limits left unset fall back to the configured defaults.
+type UpgradeUserRequest struct {
+	MaxSearchesPerDay *int `json:"max_searches_per_day" validate:"omitempty,min=1"`
+	MaxExportsPerDay  *int `json:"max_exports_per_day" validate:"omitempty,min=0"`
+	// PreserveHistory keeps the searches made during the demo; defaults to true
+	PreserveHistory *bool  `json:"preserve_history"`
+	Notes           string `json:"notes"`
 }
 
-// UserListResponse represents the user list response
-type UserListResponse struct {
-	Users      []User `json:"users"`
-	TotalCount int    `json:"total_count"`
-	Page       int    `json:"page"`
-	Limit      int    `json:"limit"`
+// UserUpgrade records a DEMO user upgraded to PERMANENT and the settings they had before
+type UserUpgrade struct {
+	ID                        uuid.UUID  `json:"id" db:"id"`
+	UserID                    uuid.UUID  `json:"user_id" db:"user_id"`
+	PreviousUserType          string     `json:"previous_user_type" db:"previous_user_type"`
+	NewUserType               string     `json:"new_user_type" db:"new_user_type"`
+	PreviousExpiresAt         *time.Time `json:"previous_expires_at" db:"previous_expires_at"`
+	PreviousMaxSearchesPerDay int        `json:"previous_max_searches_per_day" db:"previous_max_searches_per_day"`
+	PreviousMaxExportsPerDay  int        `json:"previous_max_exports_per_day" db:"previous_max_exports_per_day"`
+	MaxSearchesPerDay         int        `json:"max_searches_per_day" db:"max_searches_per_day"`
+	MaxExportsPerDay          int        `json:"max_exports_per_day" db:"max_exports_per_day"`
+	HistoryPreserved          bool       `json:"history_preserved" db:"history_preserved"`
+	SearchesRemoved           int        `json:"searches_removed" db:"searches_removed"`
+	Notes                     *string    `json:"notes" db:"notes"`
+	UpgradedBy                *uuid.UUID `json:"upgraded_by" db:"upgraded_by"`
+	UpgradedAt                time.Time  `json:"upgraded_at" db:"upgraded_at"`
+}
+
+// UpgradeUserResponse represents the upgraded user and the upgrade record
+type UpgradeUserResponse struct {
+	User    *User        `json:"user"`
+	Upgrade *UserUpgrade `json:"upgrade"`
 }
 
 // UserAnalytics represents user analytics for admin
 type UserAnalytics struct {
-	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
-	Name           string     `json:"name" db:"name"`
-	Email          string     `json:"email" db:"email"`
-	TotalSearches  int        `json:"total_searches" db:"total_searches"`
-	TodaySearches  int        `json:"today_searches" db:"today_searches"`
-	TotalExports   int        `json:"total_exports" db:"total_exports"`
-	TodayExports   int        `json:"today_exports" db:"today_exports"`
-	LastLogin      *time.Time `json:"last_login" db:"last_login"`
-	LastSearchTime *time.Time `json:"last_search_time" db:"last_search_time"`
+	UserID             uuid.UUID  `json:"user_id" db:"user_id"`
+	Name               string     `json:"name" db:"name"`
+	Email              string     `json:"email" db:"email"`
+	TotalSearches      int        `json:"total_searches" db:"total_searches"` // Excludes diagnostic searches
+	DiagnosticSearches int        `json:"diagnostic_searches" db:"diagnostic_searches"`
+	TodaySearches      int        `json:"today_searches" db:"today_searches"`
+	TotalExports       int        `json:"total_exports" db:"total_exports"`
+	TodayExports       int        `json:"today_exports" db:"today_exports"`
+	TodayCreditsUsed   int        `json:"today_credits_used" db:"today_credits_used"`
+	LastLogin          *time.Time `json:"last_login" db:"last_login"`
+	LastSearchTime     *time.Time `json:"last_search_time" db:"last_search_time"`
+}
+
+// ClientAnalytics represents API usage by one client application (X-Client-Id header) for admin
+type ClientAnalytics struct {
+	ClientID      string     `json:"client_id" db:"client_id"`
+	Requests      int64      `json:"requests" db:"requests"`
+	Errors        int64      `json:"errors" db:"errors"`
+	AvgDurationMs float64    `json:"avg_duration_ms" db:"avg_duration_ms"`
+	Searches      int64      `json:"searches" db:"searches"`
+	UniqueUsers   int64      `json:"unique_users" db:"unique_users"`
+	LastSeenAt    *time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// ClientAnalyticsResponse represents per-client API usage over a number of days
+type ClientAnalyticsResponse struct {
+	Days    int               `json:"days"`
+	Since   string            `json:"since"`
+	Clients []ClientAnalytics `json:"clients"`
 }
 
 // SearchWithinRequest represents search within previous results
 type SearchWithinRequest struct {
-	SearchID  string   `json:"search_id" validate:"required"`
-	Query     string   `json:"query" validate:"required"`
-	Fields    []string `json:"fields"`
-	MatchType string   `json:"match_type" validate:"oneof=partial full"`
-	Limit     int      `json:"limit" validate:"min=1,max=10000"`
-	Offset    int      `json:"offset" validate:"min=0"`
+	SearchID       string          `json:"search_id" validate:"required"`
+	Query          string          `json:"query" validate:"required"`
+	Fields         []string        `json:"fields"`
+	MatchType      string          `json:"match_type" validate:"oneof=partial full"`
+	Limit          int             `json:"limit" validate:"min=1,max=10000"`
+	Offset         int             `json:"offset" validate:"min=0"`
+	Diagnostic     bool            `json:"diagnostic,omitempty"` // Exempt from quota and marked as diagnostic (admins only)
+	ClientID       string          `json:"-"`                    // Set from the X-Client-Id header
+	RequestID      string          `json:"-"`                    // Set from the X-Request-Id header
+	Context        context.Context `json:"-"`                    // Context of the API call, carrying its trace span
+	ImpersonatedBy string          `json:"-"`                    // Admin acting as the user in an impersonation session
 }
 
 // RecentSearch represents a recent search with basic query info
@@ -149,6 +426,8 @@ type RecentSearch struct {
 	SearchQuery     interface{} `json:"search_query" db:"search_query"`
 	ResultCount     int         `json:"result_count" db:"result_count"`
 	ExecutionTimeMs int         `json:"execution_time_ms" db:"execution_time_ms"`
+	IsDiagnostic    bool        `json:"is_diagnostic" db:"is_diagnostic"`
+	AnonymizedAt    *time.Time  `json:"anonymized_at,omitempty" db:"anonymized_at"`
 }
 
 // UserAnalyticsWithSearches extends UserAnalytics with recent searches
@@ -164,8 +443,9 @@ type UserRegistrationRequest struct {
 	Email             string     `json:"email" db:"email"`
 	PhoneNumber       string     `json:"phone_number" db:"phone_number"`
 	RequestedSearches int        `json:"requested_searches" db:"requested_searches"`
-	Status            string     `json:"status" db:"status"` // PENDING, APPROVED, REJECTED
+	Status            string     `json:"status" db:"status"` // UNVERIFIED, PENDING, APPROVED, REJECTED
 	AdminNotes        *string    `json:"admin_notes" db:"admin_notes"`
+	VerifiedAt        *time.Time `json:"verified_at,omitempty" db:"verified_at"` // When the applicant confirmed their email
 	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
 	ReviewedAt        *time.Time `json:"reviewed_at" db:"reviewed_at"`
@@ -178,6 +458,7 @@ type CreateRegistrationRequest struct {
 	Email             string `json:"email" validate:"required,email"`
 	PhoneNumber       string `json:"phone_number" validate:"required,min=10,max=15"`
 	RequestedSearches int    `json:"requested_searches" validate:"required,min=1,max=10000"`
+	CaptchaToken      string `json:"captcha_token"` // Required when registration.captcha_provider is set
 }
 
 // UpdateRegistrationRequest represents admin's response to a registration request
@@ -186,14 +467,6 @@ type UpdateRegistrationRequest struct {
 	AdminNotes *string `json:"admin_notes"`
 }
 
-// RegistrationRequestListResponse represents the registration request list response
-type RegistrationRequestListResponse struct {
-	Requests   []UserRegistrationRequest `json:"requests"`
-	TotalCount int                       `json:"total_count"`
-	Page       int                       `json:"page"`
-	Limit      int                       `json:"limit"`
-}
-
 // UserPasswordChangeRequest represents a request from users to change their password
 type UserPasswordChangeRequest struct {
 	ID         uuid.UUID  `json:"id" db:"id"`
@@ -215,14 +488,147 @@ type CreatePasswordChangeRequest struct {
 
 // UpdatePasswordChangeRequest represents admin's response to a password change request
 type UpdatePasswordChangeRequest struct {
-	Status     string  `json:"status" validate:"required,oneof=COMPLETED REJECTED"`
+	Status     string  `json:"status" validate:"required,oneof=APPROVED REJECTED"`
 	AdminNotes *string `json:"admin_notes"`
+	// NewPassword is set as the user's password on approval; a password is generated when it is empty
+	NewPassword      *string `json:"new_password,omitempty"`
+	EmailCredentials bool    `json:"email_credentials"` // Email the new password to the user on approval
+}
+
+// PasswordChangeResolution is a password change request an admin approved or rejected. Approving
+// sets the user's new password and signs the user out of every session.
+type PasswordChangeResolution struct {
+	UserPasswordChangeRequest
+	Password            string `json:"password,omitempty"` // Generated password, when none was given
+	SessionsInvalidated int64  `json:"sessions_invalidated"`
+	CredentialsEmailed  bool   `json:"credentials_emailed"`
+}
+
+// FieldVisibilityPolicy lists the person fields masked for a specific user or a whole user type
+type FieldVisibilityPolicy struct {
+	ID           uuid.UUID      `json:"id" db:"id"`
+	UserID       *uuid.UUID     `json:"user_id" db:"user_id"`
+	UserType     *string        `json:"user_type" db:"user_type"` // DEMO, PERMANENT
+	MaskedFields pq.StringArray `json:"masked_fields" db:"masked_fields"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// UpsertFieldVisibilityPolicyRequest represents the payload for creating or replacing a field visibility policy
+type UpsertFieldVisibilityPolicyRequest struct {
+	UserID       *string  `json:"user_id"`
+	UserType     *string  `json:"user_type" validate:"omitempty,oneof=DEMO PERMANENT"`
+	MaskedFields []string `json:"masked_fields"`
+}
+
+// CSVImportAudit records a server-side CSV import, who triggered it and the checksum of the imported file
+type CSVImportAudit struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        *uuid.UUID `json:"user_id" db:"user_id"`
+	RequestedPath string     `json:"requested_path" db:"requested_path"`
+	ResolvedPath  string     `json:"resolved_path" db:"resolved_path"`
+	Checksum      string     `json:"checksum" db:"checksum"` // SHA-256 of the file contents
+	FileSize      int64      `json:"file_size" db:"file_size"`
+	Status        string     `json:"status" db:"status"` // STARTED, COMPLETED, FAILED, ROLLED_BACK
+	Forced        bool       `json:"forced" db:"forced"` // Re-import of an already imported file
+	TotalRows     int        `json:"total_rows" db:"total_rows"`
+	ProcessedRows int        `json:"processed_rows" db:"processed_rows"`
+	ErrorRows     int        `json:"error_rows" db:"error_rows"`
+	ErrorMessage  *string    `json:"error_message" db:"error_message"`
+	StartedAt     time.Time  `json:"started_at" db:"started_at"`
+	CompletedAt   *time.Time `json:"completed_at" db:"completed_at"`
+	// The import's rows carry its ID as import_job_id in this table
+	PeopleTable    *string    `json:"people_table" db:"people_table"`
+	RowsRolledBack int64      `json:"rows_rolled_back" db:"rows_rolled_back"`
+	RolledBackBy   *uuid.UUID `json:"rolled_back_by,omitempty" db:"rolled_back_by"`
+	RolledBackAt   *time.Time `json:"rolled_back_at,omitempty" db:"rolled_back_at"`
+}
+
+// UploadSession is a chunked CSV upload. Chunks are appended in order, so an interrupted upload
+// resumes at NextChunk.
+type UploadSession struct {
+	ID            uuid.UUID  `json:"id" db:"id"`
+	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
+	FileName      string     `json:"file_name" db:"file_name"`
+	UploadFile    string     `json:"-" db:"upload_file"`
+	TotalSize     *int64     `json:"total_size" db:"total_size"`
+	Checksum      *string    `json:"checksum" db:"checksum"` // Expected SHA-256 of the whole file
+	ReceivedBytes int64      `json:"received_bytes" db:"received_bytes"`
+	NextChunk     int        `json:"next_chunk" db:"chunks_received"` // Index of the next chunk to send
+	Status        string     `json:"status" db:"status"`              // UPLOADING, COMPLETING, COMPLETED, ABORTED
+	ImportJobID   *uuid.UUID `json:"import_job_id,omitempty" db:"import_job_id"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	MaxChunkSize  int64      `json:"max_chunk_size" db:"-"`
+}
+
+// CreateUploadRequest represents the payload for starting a chunked upload
+type CreateUploadRequest struct {
+	FileName  string `json:"file_name" binding:"required"`
+	TotalSize *int64 `json:"total_size"` // Checked on completion when given
+	Checksum  string `json:"checksum"`   // SHA-256 of the whole file, checked on completion when given
+}
+
+// UploadChunkResponse represents the state of an upload after a chunk
+type UploadChunkResponse struct {
+	UploadSession
+	Duplicate bool `json:"duplicate"` // The chunk had already been received with the same checksum
+}
+
+// Webhook is an admin-registered endpoint that receives signed JSON events
+type Webhook struct {
+	ID                 uuid.UUID      `json:"id" db:"id"`
+	URL                string         `json:"url" db:"url"`
+	Secret             string         `json:"-" db:"secret"` // HMAC-SHA256 signing secret, only returned on creation
+	Events             pq.StringArray `json:"events" db:"events"`
+	IsActive           bool           `json:"is_active" db:"is_active"`
+	CreatedBy          *uuid.UUID     `json:"created_by" db:"created_by"`
+	LastDeliveryStatus *string        `json:"last_delivery_status" db:"last_delivery_status"`
+	LastDeliveryAt     *time.Time     `json:"last_delivery_at" db:"last_delivery_at"`
+	CreatedAt          time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// CreateWebhookRequest represents the payload for registering a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Secret string   `json:"secret"` // Generated when empty
+	Events []string `json:"events" validate:"required,min=1"`
+}
+
+// UpdateWebhookRequest represents the payload for updating a webhook
+type UpdateWebhookRequest struct {
+	URL      *string  `json:"url"`
+	Secret   *string  `json:"secret"`
+	Events   []string `json:"events"`
+	IsActive *bool    `json:"is_active"`
+}
+
+// CreateWebhookResponse includes the signing secret, which is not returned again
+type CreateWebhookResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery tracks the delivery of one event to one webhook
+type WebhookDelivery struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	WebhookID      uuid.UUID  `json:"webhook_id" db:"webhook_id"`
+	Event          string     `json:"event" db:"event"`
+	Payload        []byte     `json:"-" db:"payload"`
+	Status         string     `json:"status" db:"status"` // PENDING, DELIVERED, FAILED
+	Attempts       int        `json:"attempts" db:"attempts"`
+	ResponseStatus *int       `json:"response_status" db:"response_status"`
+	LastError      *string    `json:"last_error" db:"last_error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	DeliveredAt    *time.Time `json:"delivered_at" db:"delivered_at"`
 }
 
-// PasswordChangeRequestListResponse represents the password change request list response
-type PasswordChangeRequestListResponse struct {
-	Requests   []UserPasswordChangeRequest `json:"requests"`
-	TotalCount int                         `json:"total_count"`
-	Page       int                         `json:"page"`
-	Limit      int                         `json:"limit"`
+// WebhookEvent is the JSON body posted to webhooks
+type WebhookEvent struct {
+	ID        string      `json:"id"` // Delivery ID, stable across retries
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
 }
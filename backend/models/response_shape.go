@@ -0,0 +1,176 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Key casings for shaped person rows
+const (
+	CasingSnake = "snake"
+	CasingCamel = "camel"
+)
+
+// Timestamp formats for shaped person rows
+const (
+	TimestampsRFC3339 = "rfc3339"
+	TimestampsUnix    = "unix"
+)
+
+// ResponseShape controls how person rows are serialized. Large result pages shrink considerably when
+// empty values are dropped and timestamps are sent as Unix seconds.
+type ResponseShape struct {
+	OmitEmpty  bool   // Leave out empty strings, zero timestamps, zero numbers and empty lists
+	Timestamps string // rfc3339 or unix; unset timestamps become null in unix form
+	Casing     string // snake or camel
+	// Columns are the only person columns written, by their snake_case names; all when empty
+	Columns map[string]bool
+}
+
+// PersonColumns are the person columns a search can be restricted to returning
+var PersonColumns = []string{"id", "master_id", "mobile", "name", "fname", "address", "alt", "circle", "email",
+	"created_at", "updated_at", "confidence", "quality_flags"}
+
+// NormalizePersonColumns lower-cases and deduplicates column names, rejecting any not in PersonColumns
+func NormalizePersonColumns(columns []string) ([]string, error) {
+	normalized := make([]string, 0, len(columns))
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		column = strings.ToLower(strings.TrimSpace(column))
+		known := false
+		for _, personColumn := range PersonColumns {
+			known = known || column == personColumn
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q; columns are %s", column, strings.Join(PersonColumns, ", "))
+		}
+		if !seen[column] {
+			seen[column] = true
+			normalized = append(normalized, column)
+		}
+	}
+	return normalized, nil
+}
+
+// IsDefault reports whether the shape leaves rows as the standard snake_case JSON
+func (s *ResponseShape) IsDefault() bool {
+	return s == nil || (!s.OmitEmpty && s.Timestamps != TimestampsUnix && s.Casing != CasingCamel && len(s.Columns) == 0)
+}
+
+// WithColumns returns a copy of the shape writing only the given columns, or the shape itself when
+// columns is empty
+func (s *ResponseShape) WithColumns(columns []string) *ResponseShape {
+	if len(columns) == 0 {
+		return s
+	}
+	shape := &ResponseShape{Timestamps: TimestampsRFC3339, Casing: CasingSnake}
+	if s != nil {
+		*shape = *s
+	}
+	shape.Columns = make(map[string]bool, len(columns))
+	for _, column := range columns {
+		shape.Columns[column] = true
+	}
+	return shape
+}
+
+// ShapePeople makes every person serialize with the given shape. Highlights of columns the shape
+// leaves out are dropped, so they do not reveal them.
+func ShapePeople(people []Person, shape *ResponseShape) {
+	for i := range people {
+		people[i].shape = shape
+		if shape != nil && len(shape.Columns) > 0 {
+			for field := range people[i].Highlights {
+				if !shape.Columns[field] {
+					delete(people[i].Highlights, field)
+				}
+			}
+		}
+	}
+}
+
+// MarshalJSON writes the person with the standard field names, or shaped when ShapePeople was applied.
+// Fields keep their declaration order.
+func (p Person) MarshalJSON() ([]byte, error) {
+	type person Person
+	if p.shape.IsDefault() {
+		return json.Marshal(person(p))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	value := reflect.ValueOf(p)
+	fields := value.Type()
+	written := 0
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if len(p.shape.Columns) > 0 && !p.shape.Columns[name] && name != "highlights" && name != "link_path" {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if (p.shape.OmitEmpty || strings.Contains(options, "omitempty")) && isEmptyJSONValue(fieldValue) {
+			continue
+		}
+
+		var encoded []byte
+		var err error
+		if t, ok := fieldValue.Interface().(time.Time); ok && p.shape.Timestamps == TimestampsUnix {
+			if t.IsZero() {
+				encoded = []byte("null")
+			} else {
+				encoded, err = json.Marshal(t.Unix())
+			}
+		} else {
+			encoded, err = json.Marshal(fieldValue.Interface())
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if p.shape.Casing == CasingCamel {
+			name = camelCase(name)
+		}
+		if written > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+		written++
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func isEmptyJSONValue(v reflect.Value) bool {
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.IsZero()
+	}
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
+
+// camelCase converts a snake_case name, e.g. master_id to masterId
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
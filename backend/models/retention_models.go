@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionPolicy represents one rule of the retention engine: rows of a table that match the
+// criteria and are older than the age are deleted, archived or anonymized
+type RetentionPolicy struct {
+	Name      string     `json:"name" db:"name"`
+	Table     string     `json:"table" db:"table_name"`
+	Database  string     `json:"database" db:"-"`        // postgres or clickhouse
+	Criteria  string     `json:"criteria" db:"criteria"` // Named row filter of the table; all matches every row
+	AgeDays   int        `json:"age_days" db:"age_days"` // 0 keeps rows forever
+	Action    string     `json:"action" db:"action"`     // delete, archive to retention_archive, anonymize, or ttl for a ClickHouse TTL
+	Enabled   bool       `json:"enabled" db:"enabled"`
+	Source    string     `json:"source" db:"-"` // default, config or database
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// RetentionTarget represents a table retention policies can act on, with the criteria and actions it supports
+type RetentionTarget struct {
+	Table      string   `json:"table"`
+	Database   string   `json:"database"`
+	TimeColumn string   `json:"time_column"` // Age is measured from this column
+	Criteria   []string `json:"criteria"`
+	Actions    []string `json:"actions"`
+}
+
+// RetentionPolicyRequest represents creating or changing a retention policy. Fields left out keep the
+// value of the policy of the same name, if any.
+type RetentionPolicyRequest struct {
+	Table    *string `json:"table"`
+	Criteria *string `json:"criteria"`
+	AgeDays  *int    `json:"age_days"`
+	Action   *string `json:"action"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// RetentionPurge represents one run of a retention policy
+type RetentionPurge struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	PolicyName     *string    `json:"policy,omitempty" db:"policy_name"` // Empty for runs recorded before policies
+	TableName      string     `json:"table" db:"table_name"`
+	Action         string     `json:"action" db:"action"` // delete, archive or anonymize
+	RetentionDays  int        `json:"retention_days" db:"retention_days"`
+	Cutoff         *time.Time `json:"cutoff,omitempty" db:"cutoff"` // Rows older than this were deleted, archived or anonymized
+	RowsDeleted    int64      `json:"rows_deleted" db:"rows_deleted"`
+	RowsArchived   int64      `json:"rows_archived" db:"rows_archived"`
+	RowsAnonymized int64      `json:"rows_anonymized" db:"rows_anonymized"`
+	Status         string     `json:"status" db:"status"`
+	Error          *string    `json:"error,omitempty" db:"error"`
+	TriggeredBy    *uuid.UUID `json:"triggered_by,omitempty" db:"triggered_by"` // Empty for scheduled runs
+	StartedAt      time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// RetentionStatusResponse represents the retention policies and the most recent runs
+type RetentionStatusResponse struct {
+	Policies     []RetentionPolicy `json:"policies"`
+	Targets      []RetentionTarget `json:"targets"`
+	NextPurgeAt  time.Time         `json:"next_purge_at"`
+	RecentPurges []RetentionPurge  `json:"recent_purges"`
+}
+
+// RetentionPurgeRequest represents a manual run; an empty table runs every enabled policy
+type RetentionPurgeRequest struct {
+	Table string `json:"table"`
+}
+
+// RetentionPurgeResponse reports the policy runs a manual run performed
+type RetentionPurgeResponse struct {
+	Purges []RetentionPurge `json:"purges"`
+}
+
+// AnonymizedSearchQuery is what remains of a logged search once anonymized: the fields and options used,
+// without the searched values. The result count stays on the search row.
+type AnonymizedSearchQuery struct {
+	Anonymized       bool     `json:"anonymized"`
+	Fields           []string `json:"fields"`
+	FieldQueryFields []string `json:"field_query_fields,omitempty"` // Fields that had field-specific queries
+	Logic            string   `json:"logic,omitempty"`
+	MatchType        string   `json:"match_type,omitempty"`
+	Limit            int      `json:"limit,omitempty"`
+	Offset           int      `json:"offset,omitempty"`
+	SearchWithin     bool     `json:"search_within,omitempty"`
+	EnhancedMobile   bool     `json:"enhanced_mobile,omitempty"`
+	Facets           []string `json:"facets,omitempty"`
+	SortBy           string   `json:"sort_by,omitempty"`
+	Quality          string   `json:"quality,omitempty"`
+	Near             bool     `json:"near,omitempty"` // Whether a nearby filter was used; the pincode is dropped
+	Diagnostic       bool     `json:"diagnostic,omitempty"`
+}
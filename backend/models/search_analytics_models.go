@@ -0,0 +1,115 @@
+package models
+
+import "time"
+
+// SearchLatency summarizes search execution times in milliseconds
+type SearchLatency struct {
+	Searches int64   `json:"searches"`
+	AvgMs    float64 `json:"avg_ms"`
+	P50Ms    float64 `json:"p50_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+	MaxMs    uint32  `json:"max_ms"`
+}
+
+// SearchVolumeBucket is search volume and latency for one hour or day
+type SearchVolumeBucket struct {
+	Bucket   time.Time `json:"bucket" ch:"bucket"`
+	Searches uint64    `json:"searches" ch:"searches"`
+	AvgMs    float64   `json:"avg_ms" ch:"avg_ms"`
+	P95Ms    float64   `json:"p95_ms" ch:"p95_ms"`
+}
+
+// SlowSearch is one of the slowest searches in a period
+type SlowSearch struct {
+	QueryID         string    `json:"search_id" ch:"query_id"`
+	UserID          string    `json:"user_id" ch:"user_id"`
+	RequestID       string    `json:"request_id,omitempty" ch:"request_id"`
+	QueryText       string    `json:"query_text" ch:"query_text"`
+	ExecutionTimeMs uint32    `json:"execution_time_ms" ch:"execution_time_ms"`
+	ResultCount     uint32    `json:"result_count" ch:"result_count"`
+	Timestamp       time.Time `json:"timestamp" ch:"timestamp"`
+}
+
+// SlowQuery is a search that took longer than the slow query threshold, with the query plan captured
+// for it
+type SlowQuery struct {
+	SlowSearch
+	ExplainPlan string   `json:"-" ch:"explain_plan"`
+	Plan        []string `json:"plan"` // EXPLAIN indexes = 1 output, one line per entry
+}
+
+// SlowQueryReport lists the slow searches in a period, most recent first
+type SlowQueryReport struct {
+	From        time.Time   `json:"from"`
+	To          time.Time   `json:"to"`
+	ThresholdMs int64       `json:"threshold_ms"` // Current search.slow_query_threshold; 0 when capture is disabled
+	Queries     []SlowQuery `json:"queries"`
+}
+
+// UserSearchLatency is one user's search volume and latency
+type UserSearchLatency struct {
+	UserID    string  `json:"user_id" ch:"user_id"`
+	UserName  string  `json:"user_name,omitempty"`
+	UserEmail string  `json:"user_email,omitempty"`
+	Searches  uint64  `json:"searches" ch:"searches"`
+	AvgMs     float64 `json:"avg_ms" ch:"avg_ms"`
+	P50Ms     float64 `json:"p50_ms" ch:"p50_ms"`
+	P95Ms     float64 `json:"p95_ms" ch:"p95_ms"`
+	MaxMs     uint32  `json:"max_ms" ch:"max_ms"`
+}
+
+// SearchPerformanceAnalytics reports search latency from the ClickHouse performance log over a period
+type SearchPerformanceAnalytics struct {
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Interval string               `json:"interval"` // hour or day
+	Latency  SearchLatency        `json:"latency"`
+	Volume   []SearchVolumeBucket `json:"volume"`
+	Slowest  []SlowSearch         `json:"slowest"`
+	ByUser   []UserSearchLatency  `json:"by_user"` // Slowest users by p95 first
+}
+
+// QueryTermStat is how often a search term was searched in a period
+type QueryTermStat struct {
+	Term               string    `json:"term" db:"term"`
+	Searches           int       `json:"searches" db:"searches"`
+	Users              int       `json:"users" db:"users"`
+	ZeroResultSearches int       `json:"zero_result_searches" db:"zero_result_searches"`
+	LastSearchedAt     time.Time `json:"last_searched_at" db:"last_searched_at"`
+}
+
+// FieldCombinationStat is how often a set of fields was searched together in a period
+type FieldCombinationStat struct {
+	Fields             []string `json:"fields"` // Empty when searches ran across every field
+	FieldList          string   `json:"-" db:"fields"`
+	Searches           int      `json:"searches" db:"searches"`
+	ZeroResultSearches int      `json:"zero_result_searches" db:"zero_result_searches"`
+}
+
+// TopQueriesReport lists the most frequent search terms and field combinations in a period
+type TopQueriesReport struct {
+	From              time.Time              `json:"from"`
+	To                time.Time              `json:"to"`
+	Terms             []QueryTermStat        `json:"terms"`
+	FieldCombinations []FieldCombinationStat `json:"field_combinations"`
+}
+
+// ZeroResultQuery is a search that found nothing, with how often it was repeated
+type ZeroResultQuery struct {
+	Term           string    `json:"term" db:"term"`
+	Fields         []string  `json:"fields"`
+	FieldList      string    `json:"-" db:"fields"`
+	Searches       int       `json:"searches" db:"searches"`
+	Users          int       `json:"users" db:"users"`
+	LastSearchedAt time.Time `json:"last_searched_at" db:"last_searched_at"`
+}
+
+// ZeroResultReport lists the searches that returned no results in a period, most repeated first
+type ZeroResultReport struct {
+	From               time.Time         `json:"from"`
+	To                 time.Time         `json:"to"`
+	Searches           int               `json:"searches" db:"searches"`
+	ZeroResultSearches int               `json:"zero_result_searches" db:"zero_result_searches"`
+	Queries            []ZeroResultQuery `json:"queries"`
+}
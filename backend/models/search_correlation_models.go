@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CorrelatedSearch represents a row of the searches log with its user
+type CorrelatedSearch struct {
+	ID              uuid.UUID       `json:"id" db:"id"`
+	UserID          uuid.UUID       `json:"user_id" db:"user_id"`
+	UserEmail       string          `json:"user_email" db:"user_email"`
+	SearchQuery     json.RawMessage `json:"search_query" db:"search_query"`
+	SearchTime      time.Time       `json:"search_time" db:"search_time"`
+	ResultCount     int             `json:"result_count" db:"result_count"`
+	ExecutionTimeMs int             `json:"execution_time_ms" db:"execution_time_ms"`
+	ClientID        *string         `json:"client_id,omitempty" db:"client_id"`
+	RequestID       *string         `json:"request_id,omitempty" db:"request_id"`
+	IsDiagnostic    bool            `json:"is_diagnostic" db:"is_diagnostic"`
+	AnonymizedAt    *time.Time      `json:"anonymized_at,omitempty" db:"anonymized_at"`
+}
+
+// SearchPerformanceEntry represents a row of the ClickHouse search_performance log
+type SearchPerformanceEntry struct {
+	QueryID         string    `json:"search_id" ch:"query_id"`
+	RequestID       string    `json:"request_id,omitempty" ch:"request_id"`
+	QueryText       string    `json:"query_text" ch:"query_text"`
+	ExecutionTimeMs uint32    `json:"execution_time_ms" ch:"execution_time_ms"`
+	ResultCount     uint32    `json:"result_count" ch:"result_count"`
+	Timestamp       time.Time `json:"timestamp" ch:"timestamp"`
+}
+
+// SearchCorrelation stitches together everything recorded about one search: the API request that
+// ran it, the search log row, the performance log rows and what was done with its results
+type SearchCorrelation struct {
+	SearchID    string                   `json:"search_id"`
+	RequestID   string                   `json:"request_id,omitempty"` // X-Request-Id of the API call
+	Search      CorrelatedSearch         `json:"search"`
+	Performance []SearchPerformanceEntry `json:"performance"`
+	// Other searches logged under the same request ID, e.g. a client action that searched and refined
+	RelatedSearches []CorrelatedSearch `json:"related_searches"`
+	Refinements     []CorrelatedSearch `json:"refinements"` // Searches within this search's results
+	Exports         []Export           `json:"exports"`     // Exports of this search's results
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// SearchTemplate represents a standard search for one use case, e.g. skip tracing or verification.
+// Users run it with just the values of its fields.
+type SearchTemplate struct {
+	ID            uuid.UUID      `json:"id" db:"id"`
+	Name          string         `json:"name" db:"name"`
+	Description   *string        `json:"description,omitempty" db:"description"`
+	Fields        pq.StringArray `json:"fields" db:"fields"`                 // Fields users give values for
+	Logic         string         `json:"logic" db:"logic"`                   // AND or OR
+	MatchType     string         `json:"match_type" db:"match_type"`         // partial or full
+	OutputColumns pq.StringArray `json:"output_columns" db:"output_columns"` // Person columns returned; all when empty
+	IsActive      bool           `json:"is_active" db:"is_active"`
+	CreatedBy     *uuid.UUID     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// CreateSearchTemplateRequest represents an admin request to create a search template
+type CreateSearchTemplateRequest struct {
+	Name          string   `json:"name" validate:"required"`
+	Description   string   `json:"description"`
+	Fields        []string `json:"fields" validate:"required"`
+	Logic         string   `json:"logic"`      // Defaults to AND
+	MatchType     string   `json:"match_type"` // Defaults to partial
+	OutputColumns []string `json:"output_columns"`
+}
+
+// UpdateSearchTemplateRequest represents an admin request to update a search template; inactive
+// templates are hidden from users and cannot be run
+type UpdateSearchTemplateRequest struct {
+	Name          *string   `json:"name"`
+	Description   *string   `json:"description"`
+	Fields        *[]string `json:"fields"`
+	Logic         *string   `json:"logic"`
+	MatchType     *string   `json:"match_type"`
+	OutputColumns *[]string `json:"output_columns"`
+	IsActive      *bool     `json:"is_active"`
+}
+
+// RunSearchTemplateRequest represents a search through a template
+type RunSearchTemplateRequest struct {
+	Values     map[string]string `json:"values" validate:"required"` // Template field to the value searched for; empty values are skipped
+	Limit      int               `json:"limit"`
+	Offset     int               `json:"offset"`
+	DatasetID  string            `json:"dataset_id,omitempty"`
+	Diagnostic bool              `json:"diagnostic,omitempty"` // Exempt from quota and marked as diagnostic (admins only)
+}
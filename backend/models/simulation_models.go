@@ -0,0 +1,45 @@
+package models
+
+// SimulationRequest describes a hypothetical search or export to evaluate for a user
+type SimulationRequest struct {
+	UserID string         `json:"user_id" validate:"required"`
+	Action string         `json:"action" validate:"oneof=search export"` // search (default) or export
+	Search *SearchRequest `json:"search,omitempty"`                      // Hypothetical search
+	Export *ExportRequest `json:"export,omitempty"`                      // Hypothetical export
+}
+
+// SimulationCheck represents a single access decision made during a simulation
+type SimulationCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+// QuotaStatus represents usage against a daily limit
+type QuotaStatus struct {
+	Used      int `json:"used"`
+	Limit     int `json:"limit"`
+	Remaining int `json:"remaining"`
+}
+
+// CostEstimate represents ClickHouse's estimate of the data a query would read
+type CostEstimate struct {
+	Parts uint64 `json:"parts"`
+	Rows  uint64 `json:"rows"`
+	Marks uint64 `json:"marks"`
+}
+
+// SimulationResponse reports whether a hypothetical action would be allowed, without executing it
+type SimulationResponse struct {
+	UserID         string            `json:"user_id"`
+	Action         string            `json:"action"`
+	Allowed        bool              `json:"allowed"`
+	Checks         []SimulationCheck `json:"checks"`
+	SearchQuota    QuotaStatus       `json:"search_quota"`
+	ExportQuota    QuotaStatus       `json:"export_quota"`
+	ConsumesQuota  bool              `json:"consumes_quota"` // false when the search is a duplicate for today
+	QuotaExempt    bool              `json:"quota_exempt"`   // The user's searches are not limited or counted
+	SearchMode     string            `json:"search_mode,omitempty"`
+	GeneratedQuery string            `json:"generated_query,omitempty"`
+	EstimatedCost  *CostEstimate     `json:"estimated_cost,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// PostgresPoolStats represents the PostgreSQL connection pool of this instance
+type PostgresPoolStats struct {
+	MaxOpenConns      int   `json:"max_open_conns"`
+	OpenConns         int   `json:"open_conns"`
+	InUse             int   `json:"in_use"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"wait_count"`           // Queries that had to wait for a free connection
+	WaitDurationMs    int64 `json:"wait_duration_ms"`     // Total time spent waiting
+	MaxIdleClosed     int64 `json:"max_idle_closed"`      // Connections closed because of max_idle_conns
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"` // Connections closed because of conn_max_idle_time
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`  // Connections closed because of conn_max_lifetime
+}
+
+// ClickHousePoolStats represents the ClickHouse connection pool of this instance
+type ClickHousePoolStats struct {
+	MaxOpenConns int  `json:"max_open_conns"`
+	MaxIdleConns int  `json:"max_idle_conns"`
+	OpenConns    int  `json:"open_conns"`
+	Idle         int  `json:"idle"`
+	CircuitOpen  bool `json:"circuit_open"` // Calls are failing fast after repeated connection failures
+}
+
+// StorageStatsResponse represents the database connection pools of the instance that served the request
+type StorageStatsResponse struct {
+	Postgres   PostgresPoolStats   `json:"postgres"`
+	ClickHouse ClickHousePoolStats `json:"clickhouse"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SystemMaintenance is the maintenance mode switch. While it is enabled only admins are served and
+// every other request is answered with 503 and the message.
+type SystemMaintenance struct {
+	Enabled   bool       `json:"enabled" db:"enabled"`
+	Message   string     `json:"message" db:"message"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SystemMaintenanceRequest turns maintenance mode on or off. An empty message uses the default one.
+type SystemMaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Message string `json:"message"`
+}
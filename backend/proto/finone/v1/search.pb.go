@@ -0,0 +1,1214 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: finone/v1/search.proto
+
+// Search API for internal services. Messages mirror the JSON API; see backend/README.md for the
+// meaning of each field. Calls authenticate with the same session token as the JSON API, sent as
+// "authorization: Bearer <token>" metadata.
+
+package finonev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Person struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	MasterId     string                 `protobuf:"bytes,2,opt,name=master_id,json=masterId,proto3" json:"master_id,omitempty"`
+	Mobile       string                 `protobuf:"bytes,3,opt,name=mobile,proto3" json:"mobile,omitempty"`
+	Name         string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Fname        string                 `protobuf:"bytes,5,opt,name=fname,proto3" json:"fname,omitempty"`
+	Address      string                 `protobuf:"bytes,6,opt,name=address,proto3" json:"address,omitempty"`
+	Alt          string                 `protobuf:"bytes,7,opt,name=alt,proto3" json:"alt,omitempty"`
+	Circle       string                 `protobuf:"bytes,8,opt,name=circle,proto3" json:"circle,omitempty"`
+	Email        string                 `protobuf:"bytes,9,opt,name=email,proto3" json:"email,omitempty"`
+	CreatedAt    *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt    *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Confidence   uint32                 `protobuf:"varint,12,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	QualityFlags []string               `protobuf:"bytes,13,rep,name=quality_flags,json=qualityFlags,proto3" json:"quality_flags,omitempty"`
+	// Where the search terms occur in each matched field; search results only
+	Highlights    map[string]*Highlights `protobuf:"bytes,14,rep,name=highlights,proto3" json:"highlights,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Person) Reset() {
+	*x = Person{}
+	mi := &file_finone_v1_search_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Person) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Person) ProtoMessage() {}
+
+func (x *Person) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Person.ProtoReflect.Descriptor instead.
+func (*Person) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Person) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Person) GetMasterId() string {
+	if x != nil {
+		return x.MasterId
+	}
+	return ""
+}
+
+func (x *Person) GetMobile() string {
+	if x != nil {
+		return x.Mobile
+	}
+	return ""
+}
+
+func (x *Person) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Person) GetFname() string {
+	if x != nil {
+		return x.Fname
+	}
+	return ""
+}
+
+func (x *Person) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Person) GetAlt() string {
+	if x != nil {
+		return x.Alt
+	}
+	return ""
+}
+
+func (x *Person) GetCircle() string {
+	if x != nil {
+		return x.Circle
+	}
+	return ""
+}
+
+func (x *Person) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *Person) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Person) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Person) GetConfidence() uint32 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Person) GetQualityFlags() []string {
+	if x != nil {
+		return x.QualityFlags
+	}
+	return nil
+}
+
+func (x *Person) GetHighlights() map[string]*Highlights {
+	if x != nil {
+		return x.Highlights
+	}
+	return nil
+}
+
+type Highlight struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int32                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int32                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	Snippet       string                 `protobuf:"bytes,3,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Highlight) Reset() {
+	*x = Highlight{}
+	mi := &file_finone_v1_search_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Highlight) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Highlight) ProtoMessage() {}
+
+func (x *Highlight) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Highlight.ProtoReflect.Descriptor instead.
+func (*Highlight) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Highlight) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Highlight) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Highlight) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+type Highlights struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Highlights    []*Highlight           `protobuf:"bytes,1,rep,name=highlights,proto3" json:"highlights,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Highlights) Reset() {
+	*x = Highlights{}
+	mi := &file_finone_v1_search_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Highlights) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Highlights) ProtoMessage() {}
+
+func (x *Highlights) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Highlights.ProtoReflect.Descriptor instead.
+func (*Highlights) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Highlights) GetHighlights() []*Highlight {
+	if x != nil {
+		return x.Highlights
+	}
+	return nil
+}
+
+type NearbyFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pincode       string                 `protobuf:"bytes,1,opt,name=pincode,proto3" json:"pincode,omitempty"`
+	RadiusKm      float64                `protobuf:"fixed64,2,opt,name=radius_km,json=radiusKm,proto3" json:"radius_km,omitempty"`
+	Prefixes      []string               `protobuf:"bytes,3,rep,name=prefixes,proto3" json:"prefixes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NearbyFilter) Reset() {
+	*x = NearbyFilter{}
+	mi := &file_finone_v1_search_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NearbyFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NearbyFilter) ProtoMessage() {}
+
+func (x *NearbyFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NearbyFilter.ProtoReflect.Descriptor instead.
+func (*NearbyFilter) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *NearbyFilter) GetPincode() string {
+	if x != nil {
+		return x.Pincode
+	}
+	return ""
+}
+
+func (x *NearbyFilter) GetRadiusKm() float64 {
+	if x != nil {
+		return x.RadiusKm
+	}
+	return 0
+}
+
+func (x *NearbyFilter) GetPrefixes() []string {
+	if x != nil {
+		return x.Prefixes
+	}
+	return nil
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Fields        []string               `protobuf:"bytes,2,rep,name=fields,proto3" json:"fields,omitempty"`
+	FieldQueries  map[string]string      `protobuf:"bytes,3,rep,name=field_queries,json=fieldQueries,proto3" json:"field_queries,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Logic         string                 `protobuf:"bytes,4,opt,name=logic,proto3" json:"logic,omitempty"`                          // AND or OR
+	MatchType     string                 `protobuf:"bytes,5,opt,name=match_type,json=matchType,proto3" json:"match_type,omitempty"` // partial or full
+	Limit         int32                  `protobuf:"varint,6,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,7,opt,name=offset,proto3" json:"offset,omitempty"`
+	Facets        []string               `protobuf:"bytes,8,rep,name=facets,proto3" json:"facets,omitempty"`
+	FacetLimit    int32                  `protobuf:"varint,9,opt,name=facet_limit,json=facetLimit,proto3" json:"facet_limit,omitempty"`
+	SortBy        string                 `protobuf:"bytes,10,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`
+	Quality       string                 `protobuf:"bytes,11,opt,name=quality,proto3" json:"quality,omitempty"`
+	Near          *NearbyFilter          `protobuf:"bytes,12,opt,name=near,proto3" json:"near,omitempty"`
+	Diagnostic    bool                   `protobuf:"varint,13,opt,name=diagnostic,proto3" json:"diagnostic,omitempty"` // Admins only
+	DatasetId     string                 `protobuf:"bytes,14,opt,name=dataset_id,json=datasetId,proto3" json:"dataset_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_finone_v1_search_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetFieldQueries() map[string]string {
+	if x != nil {
+		return x.FieldQueries
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetLogic() string {
+	if x != nil {
+		return x.Logic
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetMatchType() string {
+	if x != nil {
+		return x.MatchType
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetFacets() []string {
+	if x != nil {
+		return x.Facets
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetFacetLimit() int32 {
+	if x != nil {
+		return x.FacetLimit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetQuality() string {
+	if x != nil {
+		return x.Quality
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetNear() *NearbyFilter {
+	if x != nil {
+		return x.Near
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetDiagnostic() bool {
+	if x != nil {
+		return x.Diagnostic
+	}
+	return false
+}
+
+func (x *SearchRequest) GetDatasetId() string {
+	if x != nil {
+		return x.DatasetId
+	}
+	return ""
+}
+
+type FacetCount struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Count         uint64                 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FacetCount) Reset() {
+	*x = FacetCount{}
+	mi := &file_finone_v1_search_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FacetCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FacetCount) ProtoMessage() {}
+
+func (x *FacetCount) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FacetCount.ProtoReflect.Descriptor instead.
+func (*FacetCount) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *FacetCount) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *FacetCount) GetCount() uint64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type FacetCounts struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Counts        []*FacetCount          `protobuf:"bytes,1,rep,name=counts,proto3" json:"counts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FacetCounts) Reset() {
+	*x = FacetCounts{}
+	mi := &file_finone_v1_search_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FacetCounts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FacetCounts) ProtoMessage() {}
+
+func (x *FacetCounts) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FacetCounts.ProtoReflect.Descriptor instead.
+func (*FacetCounts) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FacetCounts) GetCounts() []*FacetCount {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+type SearchQuota struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Charged           bool                   `protobuf:"varint,1,opt,name=charged,proto3" json:"charged,omitempty"`
+	FreeReason        string                 `protobuf:"bytes,2,opt,name=free_reason,json=freeReason,proto3" json:"free_reason,omitempty"`
+	SearchesUsedToday int32                  `protobuf:"varint,3,opt,name=searches_used_today,json=searchesUsedToday,proto3" json:"searches_used_today,omitempty"`
+	SearchesLimit     int32                  `protobuf:"varint,4,opt,name=searches_limit,json=searchesLimit,proto3" json:"searches_limit,omitempty"`
+	SearchesRemaining int32                  `protobuf:"varint,5,opt,name=searches_remaining,json=searchesRemaining,proto3" json:"searches_remaining,omitempty"`
+	ResetsAt          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=resets_at,json=resetsAt,proto3" json:"resets_at,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *SearchQuota) Reset() {
+	*x = SearchQuota{}
+	mi := &file_finone_v1_search_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchQuota) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchQuota) ProtoMessage() {}
+
+func (x *SearchQuota) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchQuota.ProtoReflect.Descriptor instead.
+func (*SearchQuota) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SearchQuota) GetCharged() bool {
+	if x != nil {
+		return x.Charged
+	}
+	return false
+}
+
+func (x *SearchQuota) GetFreeReason() string {
+	if x != nil {
+		return x.FreeReason
+	}
+	return ""
+}
+
+func (x *SearchQuota) GetSearchesUsedToday() int32 {
+	if x != nil {
+		return x.SearchesUsedToday
+	}
+	return 0
+}
+
+func (x *SearchQuota) GetSearchesLimit() int32 {
+	if x != nil {
+		return x.SearchesLimit
+	}
+	return 0
+}
+
+func (x *SearchQuota) GetSearchesRemaining() int32 {
+	if x != nil {
+		return x.SearchesRemaining
+	}
+	return 0
+}
+
+func (x *SearchQuota) GetResetsAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ResetsAt
+	}
+	return nil
+}
+
+type SearchResponse struct {
+	state           protoimpl.MessageState  `protogen:"open.v1"`
+	Results         []*Person               `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	TotalCount      int32                   `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	ExecutionTimeMs int32                   `protobuf:"varint,3,opt,name=execution_time_ms,json=executionTimeMs,proto3" json:"execution_time_ms,omitempty"`
+	SearchId        string                  `protobuf:"bytes,4,opt,name=search_id,json=searchId,proto3" json:"search_id,omitempty"`
+	HasMore         bool                    `protobuf:"varint,5,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	Facets          map[string]*FacetCounts `protobuf:"bytes,6,rep,name=facets,proto3" json:"facets,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Quota           *SearchQuota            `protobuf:"bytes,7,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_finone_v1_search_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchResponse) GetResults() []*Person {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetExecutionTimeMs() int32 {
+	if x != nil {
+		return x.ExecutionTimeMs
+	}
+	return 0
+}
+
+func (x *SearchResponse) GetSearchId() string {
+	if x != nil {
+		return x.SearchId
+	}
+	return ""
+}
+
+func (x *SearchResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+func (x *SearchResponse) GetFacets() map[string]*FacetCounts {
+	if x != nil {
+		return x.Facets
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetQuota() *SearchQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type EnhancedMobileSearchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MobileNumber  string                 `protobuf:"bytes,1,opt,name=mobile_number,json=mobileNumber,proto3" json:"mobile_number,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Offset        int32                  `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	Diagnostic    bool                   `protobuf:"varint,4,opt,name=diagnostic,proto3" json:"diagnostic,omitempty"` // Admins only
+	DatasetId     string                 `protobuf:"bytes,5,opt,name=dataset_id,json=datasetId,proto3" json:"dataset_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnhancedMobileSearchRequest) Reset() {
+	*x = EnhancedMobileSearchRequest{}
+	mi := &file_finone_v1_search_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnhancedMobileSearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnhancedMobileSearchRequest) ProtoMessage() {}
+
+func (x *EnhancedMobileSearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnhancedMobileSearchRequest.ProtoReflect.Descriptor instead.
+func (*EnhancedMobileSearchRequest) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *EnhancedMobileSearchRequest) GetMobileNumber() string {
+	if x != nil {
+		return x.MobileNumber
+	}
+	return ""
+}
+
+func (x *EnhancedMobileSearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *EnhancedMobileSearchRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *EnhancedMobileSearchRequest) GetDiagnostic() bool {
+	if x != nil {
+		return x.Diagnostic
+	}
+	return false
+}
+
+func (x *EnhancedMobileSearchRequest) GetDatasetId() string {
+	if x != nil {
+		return x.DatasetId
+	}
+	return ""
+}
+
+type EnhancedMobileSearchResponse struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	DirectMatches        []*Person              `protobuf:"bytes,1,rep,name=direct_matches,json=directMatches,proto3" json:"direct_matches,omitempty"`
+	MasterIdMatches      []*Person              `protobuf:"bytes,2,rep,name=master_id_matches,json=masterIdMatches,proto3" json:"master_id_matches,omitempty"`
+	TotalDirectMatches   int32                  `protobuf:"varint,3,opt,name=total_direct_matches,json=totalDirectMatches,proto3" json:"total_direct_matches,omitempty"`
+	TotalMasterIdMatches int32                  `protobuf:"varint,4,opt,name=total_master_id_matches,json=totalMasterIdMatches,proto3" json:"total_master_id_matches,omitempty"`
+	TotalCount           int32                  `protobuf:"varint,5,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	ExecutionTimeMs      int32                  `protobuf:"varint,6,opt,name=execution_time_ms,json=executionTimeMs,proto3" json:"execution_time_ms,omitempty"`
+	SearchId             string                 `protobuf:"bytes,7,opt,name=search_id,json=searchId,proto3" json:"search_id,omitempty"`
+	HasMore              bool                   `protobuf:"varint,8,opt,name=has_more,json=hasMore,proto3" json:"has_more,omitempty"`
+	MasterIds            []string               `protobuf:"bytes,9,rep,name=master_ids,json=masterIds,proto3" json:"master_ids,omitempty"`
+	Quota                *SearchQuota           `protobuf:"bytes,10,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *EnhancedMobileSearchResponse) Reset() {
+	*x = EnhancedMobileSearchResponse{}
+	mi := &file_finone_v1_search_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnhancedMobileSearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnhancedMobileSearchResponse) ProtoMessage() {}
+
+func (x *EnhancedMobileSearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnhancedMobileSearchResponse.ProtoReflect.Descriptor instead.
+func (*EnhancedMobileSearchResponse) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *EnhancedMobileSearchResponse) GetDirectMatches() []*Person {
+	if x != nil {
+		return x.DirectMatches
+	}
+	return nil
+}
+
+func (x *EnhancedMobileSearchResponse) GetMasterIdMatches() []*Person {
+	if x != nil {
+		return x.MasterIdMatches
+	}
+	return nil
+}
+
+func (x *EnhancedMobileSearchResponse) GetTotalDirectMatches() int32 {
+	if x != nil {
+		return x.TotalDirectMatches
+	}
+	return 0
+}
+
+func (x *EnhancedMobileSearchResponse) GetTotalMasterIdMatches() int32 {
+	if x != nil {
+		return x.TotalMasterIdMatches
+	}
+	return 0
+}
+
+func (x *EnhancedMobileSearchResponse) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *EnhancedMobileSearchResponse) GetExecutionTimeMs() int32 {
+	if x != nil {
+		return x.ExecutionTimeMs
+	}
+	return 0
+}
+
+func (x *EnhancedMobileSearchResponse) GetSearchId() string {
+	if x != nil {
+		return x.SearchId
+	}
+	return ""
+}
+
+func (x *EnhancedMobileSearchResponse) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
+func (x *EnhancedMobileSearchResponse) GetMasterIds() []string {
+	if x != nil {
+		return x.MasterIds
+	}
+	return nil
+}
+
+func (x *EnhancedMobileSearchResponse) GetQuota() *SearchQuota {
+	if x != nil {
+		return x.Quota
+	}
+	return nil
+}
+
+type GetPersonRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	DatasetId     string                 `protobuf:"bytes,2,opt,name=dataset_id,json=datasetId,proto3" json:"dataset_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPersonRequest) Reset() {
+	*x = GetPersonRequest{}
+	mi := &file_finone_v1_search_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPersonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPersonRequest) ProtoMessage() {}
+
+func (x *GetPersonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finone_v1_search_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPersonRequest.ProtoReflect.Descriptor instead.
+func (*GetPersonRequest) Descriptor() ([]byte, []int) {
+	return file_finone_v1_search_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetPersonRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetPersonRequest) GetDatasetId() string {
+	if x != nil {
+		return x.DatasetId
+	}
+	return ""
+}
+
+var File_finone_v1_search_proto protoreflect.FileDescriptor
+
+const file_finone_v1_search_proto_rawDesc = "" +
+	"\n" +
+	"\x16finone/v1/search.proto\x12\tfinone.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa5\x04\n" +
+	"\x06Person\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tmaster_id\x18\x02 \x01(\tR\bmasterId\x12\x16\n" +
+	"\x06mobile\x18\x03 \x01(\tR\x06mobile\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x14\n" +
+	"\x05fname\x18\x05 \x01(\tR\x05fname\x12\x18\n" +
+	"\aaddress\x18\x06 \x01(\tR\aaddress\x12\x10\n" +
+	"\x03alt\x18\a \x01(\tR\x03alt\x12\x16\n" +
+	"\x06circle\x18\b \x01(\tR\x06circle\x12\x14\n" +
+	"\x05email\x18\t \x01(\tR\x05email\x129\n" +
+	"\n" +
+	"created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\f \x01(\rR\n" +
+	"confidence\x12#\n" +
+	"\rquality_flags\x18\r \x03(\tR\fqualityFlags\x12A\n" +
+	"\n" +
+	"highlights\x18\x0e \x03(\v2!.finone.v1.Person.HighlightsEntryR\n" +
+	"highlights\x1aT\n" +
+	"\x0fHighlightsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12+\n" +
+	"\x05value\x18\x02 \x01(\v2\x15.finone.v1.HighlightsR\x05value:\x028\x01\"M\n" +
+	"\tHighlight\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x05R\x03end\x12\x18\n" +
+	"\asnippet\x18\x03 \x01(\tR\asnippet\"B\n" +
+	"\n" +
+	"Highlights\x124\n" +
+	"\n" +
+	"highlights\x18\x01 \x03(\v2\x14.finone.v1.HighlightR\n" +
+	"highlights\"a\n" +
+	"\fNearbyFilter\x12\x18\n" +
+	"\apincode\x18\x01 \x01(\tR\apincode\x12\x1b\n" +
+	"\tradius_km\x18\x02 \x01(\x01R\bradiusKm\x12\x1a\n" +
+	"\bprefixes\x18\x03 \x03(\tR\bprefixes\"\x8a\x04\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x16\n" +
+	"\x06fields\x18\x02 \x03(\tR\x06fields\x12O\n" +
+	"\rfield_queries\x18\x03 \x03(\v2*.finone.v1.SearchRequest.FieldQueriesEntryR\ffieldQueries\x12\x14\n" +
+	"\x05logic\x18\x04 \x01(\tR\x05logic\x12\x1d\n" +
+	"\n" +
+	"match_type\x18\x05 \x01(\tR\tmatchType\x12\x14\n" +
+	"\x05limit\x18\x06 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\a \x01(\x05R\x06offset\x12\x16\n" +
+	"\x06facets\x18\b \x03(\tR\x06facets\x12\x1f\n" +
+	"\vfacet_limit\x18\t \x01(\x05R\n" +
+	"facetLimit\x12\x17\n" +
+	"\asort_by\x18\n" +
+	" \x01(\tR\x06sortBy\x12\x18\n" +
+	"\aquality\x18\v \x01(\tR\aquality\x12+\n" +
+	"\x04near\x18\f \x01(\v2\x17.finone.v1.NearbyFilterR\x04near\x12\x1e\n" +
+	"\n" +
+	"diagnostic\x18\r \x01(\bR\n" +
+	"diagnostic\x12\x1d\n" +
+	"\n" +
+	"dataset_id\x18\x0e \x01(\tR\tdatasetId\x1a?\n" +
+	"\x11FieldQueriesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"8\n" +
+	"\n" +
+	"FacetCount\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x04R\x05count\"<\n" +
+	"\vFacetCounts\x12-\n" +
+	"\x06counts\x18\x01 \x03(\v2\x15.finone.v1.FacetCountR\x06counts\"\x87\x02\n" +
+	"\vSearchQuota\x12\x18\n" +
+	"\acharged\x18\x01 \x01(\bR\acharged\x12\x1f\n" +
+	"\vfree_reason\x18\x02 \x01(\tR\n" +
+	"freeReason\x12.\n" +
+	"\x13searches_used_today\x18\x03 \x01(\x05R\x11searchesUsedToday\x12%\n" +
+	"\x0esearches_limit\x18\x04 \x01(\x05R\rsearchesLimit\x12-\n" +
+	"\x12searches_remaining\x18\x05 \x01(\x05R\x11searchesRemaining\x127\n" +
+	"\tresets_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\bresetsAt\"\x82\x03\n" +
+	"\x0eSearchResponse\x12+\n" +
+	"\aresults\x18\x01 \x03(\v2\x11.finone.v1.PersonR\aresults\x12\x1f\n" +
+	"\vtotal_count\x18\x02 \x01(\x05R\n" +
+	"totalCount\x12*\n" +
+	"\x11execution_time_ms\x18\x03 \x01(\x05R\x0fexecutionTimeMs\x12\x1b\n" +
+	"\tsearch_id\x18\x04 \x01(\tR\bsearchId\x12\x19\n" +
+	"\bhas_more\x18\x05 \x01(\bR\ahasMore\x12=\n" +
+	"\x06facets\x18\x06 \x03(\v2%.finone.v1.SearchResponse.FacetsEntryR\x06facets\x12,\n" +
+	"\x05quota\x18\a \x01(\v2\x16.finone.v1.SearchQuotaR\x05quota\x1aQ\n" +
+	"\vFacetsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x02 \x01(\v2\x16.finone.v1.FacetCountsR\x05value:\x028\x01\"\xaf\x01\n" +
+	"\x1bEnhancedMobileSearchRequest\x12#\n" +
+	"\rmobile_number\x18\x01 \x01(\tR\fmobileNumber\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\x12\x1e\n" +
+	"\n" +
+	"diagnostic\x18\x04 \x01(\bR\n" +
+	"diagnostic\x12\x1d\n" +
+	"\n" +
+	"dataset_id\x18\x05 \x01(\tR\tdatasetId\"\xd2\x03\n" +
+	"\x1cEnhancedMobileSearchResponse\x128\n" +
+	"\x0edirect_matches\x18\x01 \x03(\v2\x11.finone.v1.PersonR\rdirectMatches\x12=\n" +
+	"\x11master_id_matches\x18\x02 \x03(\v2\x11.finone.v1.PersonR\x0fmasterIdMatches\x120\n" +
+	"\x14total_direct_matches\x18\x03 \x01(\x05R\x12totalDirectMatches\x125\n" +
+	"\x17total_master_id_matches\x18\x04 \x01(\x05R\x14totalMasterIdMatches\x12\x1f\n" +
+	"\vtotal_count\x18\x05 \x01(\x05R\n" +
+	"totalCount\x12*\n" +
+	"\x11execution_time_ms\x18\x06 \x01(\x05R\x0fexecutionTimeMs\x12\x1b\n" +
+	"\tsearch_id\x18\a \x01(\tR\bsearchId\x12\x19\n" +
+	"\bhas_more\x18\b \x01(\bR\ahasMore\x12\x1d\n" +
+	"\n" +
+	"master_ids\x18\t \x03(\tR\tmasterIds\x12,\n" +
+	"\x05quota\x18\n" +
+	" \x01(\v2\x16.finone.v1.SearchQuotaR\x05quota\"A\n" +
+	"\x10GetPersonRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"dataset_id\x18\x02 \x01(\tR\tdatasetId2\xf4\x01\n" +
+	"\rSearchService\x12=\n" +
+	"\x06Search\x12\x18.finone.v1.SearchRequest\x1a\x19.finone.v1.SearchResponse\x12g\n" +
+	"\x14EnhancedMobileSearch\x12&.finone.v1.EnhancedMobileSearchRequest\x1a'.finone.v1.EnhancedMobileSearchResponse\x12;\n" +
+	"\tGetPerson\x12\x1b.finone.v1.GetPersonRequest\x1a\x11.finone.v1.PersonB/Z-finone-search-system/proto/finone/v1;finonev1b\x06proto3"
+
+var (
+	file_finone_v1_search_proto_rawDescOnce sync.Once
+	file_finone_v1_search_proto_rawDescData []byte
+)
+
+func file_finone_v1_search_proto_rawDescGZIP() []byte {
+	file_finone_v1_search_proto_rawDescOnce.Do(func() {
+		file_finone_v1_search_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_finone_v1_search_proto_rawDesc), len(file_finone_v1_search_proto_rawDesc)))
+	})
+	return file_finone_v1_search_proto_rawDescData
+}
+
+var file_finone_v1_search_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_finone_v1_search_proto_goTypes = []any{
+	(*Person)(nil),                       // 0: finone.v1.Person
+	(*Highlight)(nil),                    // 1: finone.v1.Highlight
+	(*Highlights)(nil),                   // 2: finone.v1.Highlights
+	(*NearbyFilter)(nil),                 // 3: finone.v1.NearbyFilter
+	(*SearchRequest)(nil),                // 4: finone.v1.SearchRequest
+	(*FacetCount)(nil),                   // 5: finone.v1.FacetCount
+	(*FacetCounts)(nil),                  // 6: finone.v1.FacetCounts
+	(*SearchQuota)(nil),                  // 7: finone.v1.SearchQuota
+	(*SearchResponse)(nil),               // 8: finone.v1.SearchResponse
+	(*EnhancedMobileSearchRequest)(nil),  // 9: finone.v1.EnhancedMobileSearchRequest
+	(*EnhancedMobileSearchResponse)(nil), // 10: finone.v1.EnhancedMobileSearchResponse
+	(*GetPersonRequest)(nil),             // 11: finone.v1.GetPersonRequest
+	nil,                                  // 12: finone.v1.Person.HighlightsEntry
+	nil,                                  // 13: finone.v1.SearchRequest.FieldQueriesEntry
+	nil,                                  // 14: finone.v1.SearchResponse.FacetsEntry
+	(*timestamppb.Timestamp)(nil),        // 15: google.protobuf.Timestamp
+}
+var file_finone_v1_search_proto_depIdxs = []int32{
+	15, // 0: finone.v1.Person.created_at:type_name -> google.protobuf.Timestamp
+	15, // 1: finone.v1.Person.updated_at:type_name -> google.protobuf.Timestamp
+	12, // 2: finone.v1.Person.highlights:type_name -> finone.v1.Person.HighlightsEntry
+	1,  // 3: finone.v1.Highlights.highlights:type_name -> finone.v1.Highlight
+	13, // 4: finone.v1.SearchRequest.field_queries:type_name -> finone.v1.SearchRequest.FieldQueriesEntry
+	3,  // 5: finone.v1.SearchRequest.near:type_name -> finone.v1.NearbyFilter
+	5,  // 6: finone.v1.FacetCounts.counts:type_name -> finone.v1.FacetCount
+	15, // 7: finone.v1.SearchQuota.resets_at:type_name -> google.protobuf.Timestamp
+	0,  // 8: finone.v1.SearchResponse.results:type_name -> finone.v1.Person
+	14, // 9: finone.v1.SearchResponse.facets:type_name -> finone.v1.SearchResponse.FacetsEntry
+	7,  // 10: finone.v1.SearchResponse.quota:type_name -> finone.v1.SearchQuota
+	0,  // 11: finone.v1.EnhancedMobileSearchResponse.direct_matches:type_name -> finone.v1.Person
+	0,  // 12: finone.v1.EnhancedMobileSearchResponse.master_id_matches:type_name -> finone.v1.Person
+	7,  // 13: finone.v1.EnhancedMobileSearchResponse.quota:type_name -> finone.v1.SearchQuota
+	2,  // 14: finone.v1.Person.HighlightsEntry.value:type_name -> finone.v1.Highlights
+	6,  // 15: finone.v1.SearchResponse.FacetsEntry.value:type_name -> finone.v1.FacetCounts
+	4,  // 16: finone.v1.SearchService.Search:input_type -> finone.v1.SearchRequest
+	9,  // 17: finone.v1.SearchService.EnhancedMobileSearch:input_type -> finone.v1.EnhancedMobileSearchRequest
+	11, // 18: finone.v1.SearchService.GetPerson:input_type -> finone.v1.GetPersonRequest
+	8,  // 19: finone.v1.SearchService.Search:output_type -> finone.v1.SearchResponse
+	10, // 20: finone.v1.SearchService.EnhancedMobileSearch:output_type -> finone.v1.EnhancedMobileSearchResponse
+	0,  // 21: finone.v1.SearchService.GetPerson:output_type -> finone.v1.Person
+	19, // [19:22] is the sub-list for method output_type
+	16, // [16:19] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_finone_v1_search_proto_init() }
+func file_finone_v1_search_proto_init() {
+	if File_finone_v1_search_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_finone_v1_search_proto_rawDesc), len(file_finone_v1_search_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_finone_v1_search_proto_goTypes,
+		DependencyIndexes: file_finone_v1_search_proto_depIdxs,
+		MessageInfos:      file_finone_v1_search_proto_msgTypes,
+	}.Build()
+	File_finone_v1_search_proto = out.File
+	file_finone_v1_search_proto_goTypes = nil
+	file_finone_v1_search_proto_depIdxs = nil
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// searchQueryColumnPrefix starts the columns of the flattened search_query in a searches export
+const searchQueryColumnPrefix = "query."
+
+// AdminExportFilter narrows an admin table export; zero values do not filter
+type AdminExportFilter struct {
+	From   time.Time
+	To     time.Time
+	UserID *uuid.UUID
+}
+
+// AdminExportService writes the users, daily_usage and searches tables as CSV for compliance reviews
+// and billing reconciliation. Rows are streamed from PostgreSQL as they are written.
+type AdminExportService struct{}
+
+func NewAdminExportService() *AdminExportService {
+	return &AdminExportService{}
+}
+
+// ExportUsers writes every user with their limits and organization. Password hashes are left out.
+func (s *AdminExportService) ExportUsers(ctx context.Context, w io.Writer) error {
+	query := `SELECT u.id, u.name, u.email, COALESCE(u.user_type, '') AS user_type, COALESCE(u.role, '') AS role,
+	                 COALESCE(u.is_active, false) AS is_active, u.expires_at, u.max_searches_per_day, u.max_exports_per_day,
+	                 u.quota_exempt, u.rate_limit_per_minute, u.max_export_rows, u.allowed_search_fields,
+	                 o.name AS organization, m.role AS organization_role, u.created_at, u.updated_at
+	          FROM users u
+	          LEFT JOIN organization_members m ON m.user_id = u.id
+	          LEFT JOIN organizations o ON o.id = m.organization_id
+	          ORDER BY u.created_at, u.id`
+	rows, err := database.PostgresDB.QueryxContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "name", "email", "user_type", "role", "is_active", "expires_at", "max_searches_per_day",
+		"max_exports_per_day", "quota_exempt", "rate_limit_per_minute", "max_export_rows", "allowed_search_fields",
+		"organization", "organization_role", "created_at", "updated_at"})
+	for rows.Next() {
+		var user struct {
+			ID                  uuid.UUID      `db:"id"`
+			Name                string         `db:"name"`
+			Email               string         `db:"email"`
+			UserType            string         `db:"user_type"`
+			Role                string         `db:"role"`
+			IsActive            bool           `db:"is_active"`
+			ExpiresAt           *time.Time     `db:"expires_at"`
+			MaxSearchesPerDay   *int           `db:"max_searches_per_day"`
+			MaxExportsPerDay    *int           `db:"max_exports_per_day"`
+			QuotaExempt         bool           `db:"quota_exempt"`
+			RateLimitPerMinute  *int           `db:"rate_limit_per_minute"`
+			MaxExportRows       *int           `db:"max_export_rows"`
+			AllowedSearchFields pq.StringArray `db:"allowed_search_fields"`
+			Organization        *string        `db:"organization"`
+			OrganizationRole    *string        `db:"organization_role"`
+			CreatedAt           *time.Time     `db:"created_at"`
+			UpdatedAt           *time.Time     `db:"updated_at"`
+		}
+		if err := rows.StructScan(&user); err != nil {
+			return fmt.Errorf("failed to read user: %w", err)
+		}
+		out.Write([]string{
+			user.ID.String(), user.Name, user.Email, user.UserType, user.Role, strconv.FormatBool(user.IsActive),
+			csvTime(user.ExpiresAt), csvInt(user.MaxSearchesPerDay), csvInt(user.MaxExportsPerDay),
+			strconv.FormatBool(user.QuotaExempt), csvInt(user.RateLimitPerMinute), csvInt(user.MaxExportRows),
+			strings.Join(user.AllowedSearchFields, "|"), csvString(user.Organization), csvString(user.OrganizationRole),
+			csvTime(user.CreatedAt), csvTime(user.UpdatedAt),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read users: %w", err)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ExportUsage writes the daily search and export counts of each user, oldest day first
+func (s *AdminExportService) ExportUsage(ctx context.Context, w io.Writer, filter AdminExportFilter) error {
+	where, args := filter.where("d.date", "d.user_id")
+	query := `SELECT d.date, d.user_id, COALESCE(u.email, '') AS email, COALESCE(u.user_type, '') AS user_type,
+	                 COALESCE(d.search_count, 0) AS search_count, COALESCE(d.export_count, 0) AS export_count, d.credits_used
+	          FROM daily_usage d
+	          LEFT JOIN users u ON u.id = d.user_id` + where + `
+	          ORDER BY d.date, u.email`
+	rows, err := database.PostgresDB.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query daily usage: %w", err)
+	}
+	defer rows.Close()
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "user_id", "email", "user_type", "search_count", "export_count", "credits_used"})
+	for rows.Next() {
+		var usage struct {
+			Date        time.Time `db:"date"`
+			UserID      uuid.UUID `db:"user_id"`
+			Email       string    `db:"email"`
+			UserType    string    `db:"user_type"`
+			SearchCount int       `db:"search_count"`
+			ExportCount int       `db:"export_count"`
+			CreditsUsed int       `db:"credits_used"`
+		}
+		if err := rows.StructScan(&usage); err != nil {
+			return fmt.Errorf("failed to read daily usage: %w", err)
+		}
+		out.Write([]string{
+			usage.Date.Format("2006-01-02"), usage.UserID.String(), usage.Email, usage.UserType,
+			strconv.Itoa(usage.SearchCount), strconv.Itoa(usage.ExportCount), strconv.Itoa(usage.CreditsUsed),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read daily usage: %w", err)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// ExportSearches writes the search log, oldest first, with each search's query flattened into
+// query.* columns: nested objects become dotted names and lists of values are joined with |. The
+// columns are the union of the fields of every exported search, so the log is read twice.
+func (s *AdminExportService) ExportSearches(ctx context.Context, w io.Writer, filter AdminExportFilter) error {
+	where, args := filter.where("s.search_time", "s.user_id")
+
+	var keys []string
+	seen := make(map[string]bool)
+	err := s.eachRow(ctx, `SELECT s.search_query FROM searches s`+where, args, func(rows *sqlx.Rows) error {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return err
+		}
+		for key := range flattenSearchQuery(raw) {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read search queries: %w", err)
+	}
+	sort.Strings(keys)
+
+	header := []string{"id", "search_time", "user_id", "email", "result_count", "execution_time_ms", "client_id",
+		"is_diagnostic", "request_id", "dataset_id", "impersonated_by", "anonymized_at"}
+	for _, key := range keys {
+		header = append(header, searchQueryColumnPrefix+key)
+	}
+	out := csv.NewWriter(w)
+	out.Write(header)
+
+	query := `SELECT s.id, s.search_time, s.user_id, COALESCE(u.email, '') AS email, COALESCE(s.result_count, 0) AS result_count,
+	                 COALESCE(s.execution_time_ms, 0) AS execution_time_ms, s.client_id, s.is_diagnostic, s.request_id,
+	                 s.dataset_id, s.impersonated_by, s.anonymized_at, s.search_query
+	          FROM searches s
+	          LEFT JOIN users u ON u.id = s.user_id` + where + `
+	          ORDER BY s.search_time, s.id`
+	err = s.eachRow(ctx, query, args, func(rows *sqlx.Rows) error {
+		var search struct {
+			ID              uuid.UUID  `db:"id"`
+			SearchTime      *time.Time `db:"search_time"`
+			UserID          *uuid.UUID `db:"user_id"`
+			Email           string     `db:"email"`
+			ResultCount     int        `db:"result_count"`
+			ExecutionTimeMs int        `db:"execution_time_ms"`
+			ClientID        *string    `db:"client_id"`
+			IsDiagnostic    bool       `db:"is_diagnostic"`
+			RequestID       *string    `db:"request_id"`
+			DatasetID       *uuid.UUID `db:"dataset_id"`
+			ImpersonatedBy  *uuid.UUID `db:"impersonated_by"`
+			AnonymizedAt    *time.Time `db:"anonymized_at"`
+			SearchQuery     []byte     `db:"search_query"`
+		}
+		if err := rows.StructScan(&search); err != nil {
+			return err
+		}
+		record := []string{
+			search.ID.String(), csvTime(search.SearchTime), csvUUID(search.UserID), search.Email,
+			strconv.Itoa(search.ResultCount), strconv.Itoa(search.ExecutionTimeMs), csvString(search.ClientID),
+			strconv.FormatBool(search.IsDiagnostic), csvString(search.RequestID), csvUUID(search.DatasetID),
+			csvUUID(search.ImpersonatedBy), csvTime(search.AnonymizedAt),
+		}
+		values := flattenSearchQuery(search.SearchQuery)
+		for _, key := range keys {
+			record = append(record, values[key])
+		}
+		return out.Write(record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read searches: %w", err)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func (s *AdminExportService) eachRow(ctx context.Context, query string, args []interface{}, fn func(*sqlx.Rows) error) error {
+	rows, err := database.PostgresDB.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// where returns the WHERE clause for the filter on the given time and user columns
+func (f AdminExportFilter) where(timeColumn, userColumn string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		conditions = append(conditions, fmt.Sprintf("%s >= $%d", timeColumn, len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		conditions = append(conditions, fmt.Sprintf("%s < $%d", timeColumn, len(args)))
+	}
+	if f.UserID != nil {
+		args = append(args, *f.UserID)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", userColumn, len(args)))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// flattenSearchQuery turns a logged search query into column values keyed by dotted field path.
+// Empty values are left out so unused request fields do not each become a column.
+func flattenSearchQuery(raw []byte) map[string]string {
+	values := make(map[string]string)
+	var query map[string]interface{}
+	if len(raw) == 0 || json.Unmarshal(raw, &query) != nil {
+		return values
+	}
+	flattenJSON("", query, values)
+	return values
+}
+
+func flattenJSON(prefix string, value interface{}, values map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenJSON(key, child, values)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				// Lists of objects are kept as JSON
+				encoded, _ := json.Marshal(v)
+				values[prefix] = string(encoded)
+				return
+			}
+			items = append(items, jsonScalar(item))
+		}
+		if len(items) > 0 {
+			values[prefix] = strings.Join(items, "|")
+		}
+	default:
+		if text := jsonScalar(v); text != "" {
+			values[prefix] = text
+		}
+	}
+}
+
+func jsonScalar(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func csvInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+func csvString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func csvUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"crypto/sha256"
+	"database/sql"
+	"errors"
 	"finone-search-system/config"
 	"finone-search-system/database"
 	"finone-search-system/models"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -21,28 +24,42 @@ func NewAuthService() *AuthService {
 	return &AuthService{}
 }
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrAccountExpired      = errors.New("user account has expired")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrSessionExpired      = errors.New("invalid or expired session")
+	ErrSearchLimitExceeded = errors.New("daily search limit exceeded")
+	ErrExportLimitExceeded = errors.New("daily export limit exceeded")
+)
+
 // Login authenticates a user and returns a JWT token with session management
-func (s *AuthService) Login(email, password string) (*models.LoginResponse, error) {
+func (s *AuthService) Login(email, password string, client LoginClient) (*models.LoginResponse, error) {
 	var user models.User
 	query := `SELECT * FROM users WHERE email = $1 AND is_active = true`
 
 	err := database.PostgresDB.Get(&user, query, email)
 	if err != nil {
 		utils.LogError("Failed to find user", err)
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
 	// Check if user has expired (for DEMO users)
 	if user.ExpiresAt != nil && user.ExpiresAt.Before(time.Now()) {
-		return nil, fmt.Errorf("user account has expired")
+		return nil, ErrAccountExpired
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		utils.LogError("Password verification failed", err)
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, ErrInvalidCredentials
 	}
 
+	return s.startSession(user, client)
+}
+
+// startSession issues a token and session to an authenticated user and records the login
+func (s *AuthService) startSession(user models.User, client LoginClient) (*models.LoginResponse, error) {
 	// Generate JWT token
 	token, expiresAt, err := s.generateJWT(user.ID.String(), user.Email, user.Role)
 	if err != nil {
@@ -51,14 +68,16 @@ func (s *AuthService) Login(email, password string) (*models.LoginResponse, erro
 	}
 
 	// Create session record
-	sessionID, err := s.createSession(user.ID, token, expiresAt, "127.0.0.1", "")
+	client.locate()
+	sessionID, err := s.createSession(user.ID, token, expiresAt, client)
 	if err != nil {
 		utils.LogError("Failed to create session", err)
 		return nil, fmt.Errorf("failed to create session")
 	}
 
 	// Log the login
-	s.logLogin(user.ID, "127.0.0.1", "")
+	newLocation := s.checkLoginOrigin(&user, sessionID, client)
+	s.logLogin(user.ID, sessionID, client, newLocation)
 
 	// Remove sensitive data
 	user.PasswordHash = ""
@@ -73,6 +92,31 @@ func (s *AuthService) Login(email, password string) (*models.LoginResponse, erro
 
 // CreateUser creates a new user account
 func (s *AuthService) CreateUser(req *models.CreateUserRequest) (*models.User, error) {
+	user, err := s.newUser(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insertUser(database.PostgresDB, user); err != nil {
+		utils.LogError("Failed to create user", err)
+		return nil, err
+	}
+
+	// Remove sensitive data
+	user.PasswordHash = ""
+
+	utils.LogInfo(fmt.Sprintf("Created new user: %s (%s)", user.Email, user.UserType))
+	NewNotificationService().NotifyUserCreated(user, req.Password)
+	return user, nil
+}
+
+// newUser builds the account for a create request, applying the default role, limits and DEMO expiry
+func (s *AuthService) newUser(req *models.CreateUserRequest) (*models.User, error) {
+	allowedFields, err := normalizeSearchFields(req.AllowedSearchFields)
+	if err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -84,10 +128,10 @@ func (s *AuthService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 		req.Role = "USER"
 	}
 	if req.MaxSearchesPerDay == 0 {
-		req.MaxSearchesPerDay = config.AppConfig.Limits.MaxSearchesPerDay
+		req.MaxSearchesPerDay = config.Get().Limits.MaxSearchesPerDay
 	}
 	if req.MaxExportsPerDay == 0 {
-		req.MaxExportsPerDay = config.AppConfig.Limits.MaxExportsPerDay
+		req.MaxExportsPerDay = config.Get().Limits.MaxExportsPerDay
 	}
 
 	// Set expiry for DEMO users
@@ -109,28 +153,28 @@ func (s *AuthService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 		MaxExportsPerDay:  req.MaxExportsPerDay,
 		CreatedAt:         time.Now(),
 		UpdatedAt:         time.Now(),
+
+		AllowedSearchFields: allowedFields,
+		QuotaExempt:         req.QuotaExempt,
 	}
+	return &user, nil
+}
 
+// insertUser stores a new account
+func insertUser(db sqlx.Execer, user *models.User) error {
 	query := `INSERT INTO users
 		(id, name, email, password_hash, user_type, role, expires_at, is_active,
-		 max_searches_per_day, max_exports_per_day, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+		 max_searches_per_day, max_exports_per_day, created_at, updated_at, allowed_search_fields, quota_exempt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
 
-	_, err = database.PostgresDB.Exec(query,
+	_, err := db.Exec(query,
 		user.ID, user.Name, user.Email, user.PasswordHash, user.UserType,
 		user.Role, user.ExpiresAt, user.IsActive, user.MaxSearchesPerDay,
-		user.MaxExportsPerDay, user.CreatedAt, user.UpdatedAt)
-
+		user.MaxExportsPerDay, user.CreatedAt, user.UpdatedAt, user.AllowedSearchFields, user.QuotaExempt)
 	if err != nil {
-		utils.LogError("Failed to create user", err)
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return fmt.Errorf("failed to create user: %w", err)
 	}
-
-	// Remove sensitive data
-	user.PasswordHash = ""
-
-	utils.LogInfo(fmt.Sprintf("Created new user: %s (%s)", user.Email, user.UserType))
-	return &user, nil
+	return nil
 }
 
 // UpdateUser updates user information
@@ -192,6 +236,34 @@ func (s *AuthService) UpdateUser(userID uuid.UUID, req *models.UpdateUserRequest
 		argIndex++
 	}
 
+	if req.AllowedSearchFields != nil {
+		allowedFields, err := normalizeSearchFields(*req.AllowedSearchFields)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, fmt.Sprintf("allowed_search_fields = $%d", argIndex))
+		args = append(args, allowedFields)
+		argIndex++
+	}
+
+	if req.QuotaExempt != nil {
+		updates = append(updates, fmt.Sprintf("quota_exempt = $%d", argIndex))
+		args = append(args, *req.QuotaExempt)
+		argIndex++
+	}
+
+	if req.RateLimitPerMinute != nil {
+		updates = append(updates, fmt.Sprintf("rate_limit_per_minute = NULLIF($%d, 0)", argIndex))
+		args = append(args, *req.RateLimitPerMinute)
+		argIndex++
+	}
+
+	if req.MaxExportRows != nil {
+		updates = append(updates, fmt.Sprintf("max_export_rows = NULLIF($%d, 0)", argIndex))
+		args = append(args, *req.MaxExportRows)
+		argIndex++
+	}
+
 	if len(updates) == 0 {
 		return nil, fmt.Errorf("no fields to update")
 	}
@@ -210,6 +282,9 @@ func (s *AuthService) UpdateUser(userID uuid.UUID, req *models.UpdateUserRequest
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	// Cached sessions carry the old role, limits and active state
+	sessionCache.invalidateUser(userID)
+
 	// Fetch updated user
 	return s.GetUserByID(userID)
 }
@@ -229,13 +304,11 @@ func (s *AuthService) GetUserByID(userID uuid.UUID) (*models.User, error) {
 }
 
 // GetUsers retrieves paginated list of users
-func (s *AuthService) GetUsers(page, limit int) (*models.UserListResponse, error) {
-	offset := (page - 1) * limit
-
+func (s *AuthService) GetUsers(params models.PageParams) (*models.Paginated[models.User], error) {
 	var users []models.User
 	query := `SELECT * FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2`
 
-	err := database.PostgresDB.Select(&users, query, limit, offset)
+	err := database.PostgresDB.Select(&users, query, params.Limit, params.Offset())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
 	}
@@ -253,17 +326,12 @@ func (s *AuthService) GetUsers(page, limit int) (*models.UserListResponse, error
 		return nil, fmt.Errorf("failed to get user count: %w", err)
 	}
 
-	return &models.UserListResponse{
-		Users:      users,
-		TotalCount: totalCount,
-		Page:       page,
-		Limit:      limit,
-	}, nil
+	return models.NewPaginated(users, totalCount, params), nil
 }
 
 // generateJWT generates a JWT token for the user
 func (s *AuthService) generateJWT(userID, email, role string) (string, time.Time, error) {
-	expiresAt := time.Now().Add(config.AppConfig.JWT.Expiry)
+	expiresAt := time.Now().Add(config.Get().JWT.Expiry)
 
 	claims := jwt.MapClaims{
 		"user_id": userID,
@@ -274,8 +342,7 @@ func (s *AuthService) generateJWT(userID, email, role string) (string, time.Time
 		"jti":     uuid.New().String(), // JWT ID for uniqueness
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(config.AppConfig.JWT.Secret))
+	tokenString, err := signJWT(claims)
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -283,14 +350,11 @@ func (s *AuthService) generateJWT(userID, email, role string) (string, time.Time
 	return tokenString, expiresAt, nil
 }
 
-// ValidateJWT validates a JWT token and returns the claims
+// ValidateJWT validates a JWT token against the signing key named by its kid header, or jwt.secret
+// when it has none, and returns the claims
 func (s *AuthService) ValidateJWT(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(config.AppConfig.JWT.Secret), nil
-	})
+	token, err := jwt.Parse(tokenString, jwtVerificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -303,18 +367,24 @@ func (s *AuthService) ValidateJWT(tokenString string) (jwt.MapClaims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-// createSession creates a new session record in the database
-func (s *AuthService) createSession(userID uuid.UUID, token string, expiresAt time.Time, ipAddress, userAgent string) (uuid.UUID, error) {
+// createSession creates a new session record in the database, with the device parsed from the
+// client's user agent
+func (s *AuthService) createSession(userID uuid.UUID, token string, expiresAt time.Time, client LoginClient) (uuid.UUID, error) {
 	// Generate session ID
 	sessionID := uuid.New()
 
 	// Create hash of the token for storage (for security)
 	tokenHash := s.hashToken(token)
 
-	query := `INSERT INTO user_sessions (id, user_id, session_token, created_at, expires_at, is_active, ip_address, user_agent)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	device := utils.ParseUserAgent(client.UserAgent)
+	now := time.Now()
+	location := client.Location
+	query := `INSERT INTO user_sessions (id, user_id, session_token, created_at, expires_at, is_active, ip_address, user_agent, last_activity_at,
+	                                     browser, os, device_type, device_fingerprint, country, city, asn, as_org)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), NULLIF($15, ''), NULLIF($16, 0), NULLIF($17, ''))`
 
-	_, err := database.PostgresDB.Exec(query, sessionID, userID, tokenHash, time.Now(), expiresAt, true, ipAddress, userAgent)
+	_, err := database.PostgresDB.Exec(query, sessionID, userID, tokenHash, now, expiresAt, true, client.IPAddress, client.UserAgent, now,
+		device.Browser, device.OS, device.DeviceType, device.Fingerprint(), location.Country, location.City, int64(location.ASN), location.ASOrg)
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -327,27 +397,38 @@ func (s *AuthService) ValidateSession(tokenString string) (*models.User, error)
 	// First validate the JWT token
 	claims, err := s.ValidateJWT(tokenString)
 	if err != nil {
-		return nil, fmt.Errorf("invalid token: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
 	}
 
 	userIDStr, ok := claims["user_id"].(string)
 	if !ok {
-		return nil, fmt.Errorf("invalid token claims")
+		return nil, fmt.Errorf("%w: invalid claims", ErrInvalidToken)
 	}
 
 	userID, err := uuid.Parse(userIDStr)
 	if err != nil {
-		return nil, fmt.Errorf("invalid user ID in token")
+		return nil, fmt.Errorf("%w: invalid user ID", ErrInvalidToken)
 	}
 
-	// Check if session exists and is active
+	// Serve recently validated sessions from the cache
 	tokenHash := s.hashToken(tokenString)
+	if user, ok := sessionCache.get(tokenHash); ok && user.ID == userID {
+		if user.ExpiresAt == nil || user.ExpiresAt.After(time.Now()) {
+			return user, nil
+		}
+	}
+
+	// Check if session exists and is active
 	var session models.UserSession
 	sessionQuery := `SELECT * FROM user_sessions WHERE session_token = $1 AND user_id = $2 AND is_active = true AND expires_at > now() AND logged_out_at IS NULL`
 
 	err = database.PostgresDB.Get(&session, sessionQuery, tokenHash, userID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid or expired session")
+		return nil, ErrSessionExpired
+	}
+	if sessionIdle(&session, time.Now()) {
+		s.expireIdleSession(session.ID)
+		return nil, fmt.Errorf("%w: idle for too long", ErrSessionExpired)
 	}
 
 	// Get user details and verify user is still active
@@ -360,18 +441,21 @@ func (s *AuthService) ValidateSession(tokenString string) (*models.User, error)
 
 	// Check if user has expired (for DEMO users)
 	if user.ExpiresAt != nil && user.ExpiresAt.Before(time.Now()) {
-		return nil, fmt.Errorf("user account has expired")
+		return nil, ErrAccountExpired
 	}
 
 	// Remove sensitive data
 	user.PasswordHash = ""
 
+	sessionCache.set(tokenHash, &user, session.ExpiresAt)
+
 	return &user, nil
 }
 
 // invalidateSession invalidates a session (logout)
 func (s *AuthService) InvalidateSession(tokenString string, userID uuid.UUID) error {
 	tokenHash := s.hashToken(tokenString)
+	sessionCache.invalidateToken(tokenHash)
 
 	query := `UPDATE user_sessions
 			  SET is_active = false, logged_out_at = now()
@@ -394,8 +478,34 @@ func (s *AuthService) InvalidateSession(tokenString string, userID uuid.UUID) er
 	return nil
 }
 
+// ErrSessionNotFound is returned when a session does not exist, belongs to another user or is already invalidated
+var ErrSessionNotFound = errors.New("session not found or already invalidated")
+
+// InvalidateSessionByID invalidates a single session, e.g. one on a lost or stolen device. When
+// ownerID is set, only that user's sessions can be invalidated.
+func (s *AuthService) InvalidateSessionByID(sessionID uuid.UUID, ownerID *uuid.UUID) error {
+	query := `UPDATE user_sessions
+			  SET is_active = false, logged_out_at = now()
+			  WHERE id = $1 AND ($2::uuid IS NULL OR user_id = $2) AND is_active = true
+			  RETURNING session_token`
+
+	var tokenHash string
+	err := database.PostgresDB.Get(&tokenHash, query, sessionID, ownerID)
+	if err == sql.ErrNoRows {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to invalidate session: %w", err)
+	}
+
+	sessionCache.invalidateToken(tokenHash)
+	return nil
+}
+
 // invalidateAllUserSessions invalidates all sessions for a user (useful for admin actions)
 func (s *AuthService) InvalidateAllUserSessions(userID uuid.UUID) error {
+	sessionCache.invalidateUser(userID)
+
 	query := `UPDATE user_sessions
 			  SET is_active = false, logged_out_at = now()
 			  WHERE user_id = $1 AND is_active = true`
@@ -410,6 +520,10 @@ func (s *AuthService) InvalidateAllUserSessions(userID uuid.UUID) error {
 
 // cleanupExpiredSessions removes old expired sessions from database
 func (s *AuthService) CleanupExpiredSessions() error {
+	if err := s.expireIdleSessions(); err != nil {
+		return err
+	}
+
 	query := `DELETE FROM user_sessions
 			  WHERE expires_at < now() OR (logged_out_at IS NOT NULL AND logged_out_at < now() - INTERVAL '7 days')`
 
@@ -432,60 +546,101 @@ func (s *AuthService) hashToken(token string) string {
 	return fmt.Sprintf("%x", hash)
 }
 
-// GetUserSessions returns active sessions for a user (admin function)
-func (s *AuthService) GetUserSessions(userID uuid.UUID) ([]models.UserSession, error) {
+// GetUserSessions returns a page of a user's sessions, newest first
+func (s *AuthService) GetUserSessions(userID uuid.UUID, params models.PageParams) (*models.Paginated[models.UserSession], error) {
+	var totalCount int
+	if err := database.PostgresDB.Get(&totalCount, `SELECT COUNT(*) FROM user_sessions WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to get session count: %w", err)
+	}
+
 	var sessions []models.UserSession
-	query := `SELECT id, user_id, created_at, expires_at, is_active, ip_address, user_agent, logged_out_at
+	query := `SELECT id, user_id, created_at, expires_at, is_active, ip_address, user_agent, logged_out_at, last_activity_at, impersonated_by,
+			         browser, os, device_type, device_fingerprint, country, city, asn, as_org, new_location
 			  FROM user_sessions
 			  WHERE user_id = $1
-			  ORDER BY created_at DESC`
+			  ORDER BY created_at DESC
+			  LIMIT $2 OFFSET $3`
 
-	err := database.PostgresDB.Select(&sessions, query, userID)
+	err := database.PostgresDB.Select(&sessions, query, userID, params.Limit, params.Offset())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user sessions: %w", err)
 	}
 
-	return sessions, nil
+	return models.NewPaginated(sessions, totalCount, params), nil
 }
 
-// GetAllActiveSessions returns all active sessions (admin function)
-func (s *AuthService) GetAllActiveSessions() ([]models.UserSession, error) {
+// GetAllActiveSessions returns a page of all active sessions, newest first (admin function)
+func (s *AuthService) GetAllActiveSessions(params models.PageParams) (*models.Paginated[models.UserSession], error) {
+	const activeCondition = `s.is_active = true AND s.expires_at > now() AND s.logged_out_at IS NULL`
+
+	var totalCount int
+	if err := database.PostgresDB.Get(&totalCount, `SELECT COUNT(*) FROM user_sessions s WHERE `+activeCondition); err != nil {
+		return nil, fmt.Errorf("failed to get session count: %w", err)
+	}
+
 	var sessions []models.UserSession
-	query := `SELECT s.id, s.user_id, s.created_at, s.expires_at, s.is_active, s.ip_address, s.user_agent, s.logged_out_at
+	query := `SELECT s.id, s.user_id, s.created_at, s.expires_at, s.is_active, s.ip_address, s.user_agent, s.logged_out_at, s.last_activity_at, s.impersonated_by,
+			         s.browser, s.os, s.device_type, s.device_fingerprint, s.country, s.city, s.asn, s.as_org, s.new_location
 			  FROM user_sessions s
-			  WHERE s.is_active = true AND s.expires_at > now() AND s.logged_out_at IS NULL
-			  ORDER BY s.created_at DESC`
+			  WHERE ` + activeCondition + `
+			  ORDER BY s.created_at DESC
+			  LIMIT $1 OFFSET $2`
 
-	err := database.PostgresDB.Select(&sessions, query)
+	err := database.PostgresDB.Select(&sessions, query, params.Limit, params.Offset())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active sessions: %w", err)
 	}
 
-	return sessions, nil
+	return models.NewPaginated(sessions, totalCount, params), nil
 }
 
-// logLogin logs a user login
-func (s *AuthService) logLogin(userID uuid.UUID, ipAddress, userAgent string) {
-	query := `INSERT INTO logins (user_id, ip_address, user_agent) VALUES ($1, $2, $3)`
-	_, err := database.PostgresDB.Exec(query, userID, ipAddress, userAgent)
+// logLogin logs a user login with where it came from
+func (s *AuthService) logLogin(userID, sessionID uuid.UUID, client LoginClient, newLocation bool) {
+	location := client.Location
+	query := `INSERT INTO logins (user_id, ip_address, user_agent, session_id, country, city, asn, as_org, new_location)
+	          VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, 0), NULLIF($8, ''), $9)`
+	_, err := database.PostgresDB.Exec(query, userID, client.IPAddress, client.UserAgent, sessionID,
+		location.Country, location.City, int64(location.ASN), location.ASOrg, newLocation)
 	if err != nil {
 		utils.LogError("Failed to log login", err)
 	}
 }
 
+// GetLogins lists logins, newest first, optionally for one user or only those from a new location
+func (s *AuthService) GetLogins(userID *uuid.UUID, newLocationOnly bool, limit int) ([]models.LoginAuditEntry, error) {
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	logins := []models.LoginAuditEntry{}
+	query := `SELECT l.id, l.user_id, u.email, u.name, l.login_time, COALESCE(host(l.ip_address), '') AS ip_address,
+	                 COALESCE(l.user_agent, '') AS user_agent, l.session_id, l.country, l.city, l.asn, l.as_org, l.new_location
+	          FROM logins l
+	          JOIN users u ON u.id = l.user_id
+	          WHERE ($1::uuid IS NULL OR l.user_id = $1) AND (NOT $2 OR l.new_location)
+	          ORDER BY l.login_time DESC
+	          LIMIT $3`
+	if err := database.PostgresDB.Select(&logins, query, userID, newLocationOnly, limit); err != nil {
+		return nil, fmt.Errorf("failed to get logins: %w", err)
+	}
+	return logins, nil
+}
+
 // CheckSearchLimit checks if user can perform more searches today
 func (s *AuthService) CheckSearchLimit(userID uuid.UUID) (bool, error) {
 	// Get user's daily limit
 	var user models.User
-	query := `SELECT max_searches_per_day FROM users WHERE id = $1 AND is_active = true`
+	query := `SELECT max_searches_per_day, quota_exempt FROM users WHERE id = $1 AND is_active = true`
 	err := database.PostgresDB.Get(&user, query, userID)
 	if err != nil {
 		return false, fmt.Errorf("failed to get user: %w", err)
 	}
+	if user.QuotaExempt {
+		return true, nil
+	}
 
-	// Get today's search count (IST timezone)
-	istNow := time.Now().Add(5*time.Hour + 30*time.Minute) // Convert to IST
-	today := istNow.Format("2006-01-02")
+	// Get today's search count (quota day in the configured reset timezone)
+	today := CurrentQuotaDate()
 
 	var searchCount int
 	countQuery := `SELECT COALESCE(search_count, 0) FROM daily_usage WHERE user_id = $1 AND date = $2`
@@ -495,27 +650,194 @@ func (s *AuthService) CheckSearchLimit(userID uuid.UUID) (bool, error) {
 		searchCount = 0
 	}
 
-	return searchCount < user.MaxSearchesPerDay, nil
+	if searchCount < user.MaxSearchesPerDay {
+		return s.checkOrganizationLimit(userID, "searches")
+	}
+
+	// Over the daily limit: allow the search if the user has been granted credits or a quota
+	// adjustment for today
+	extra, err := todayExtraSearches(userID)
+	if err != nil {
+		return false, err
+	}
+	if searchCount >= user.MaxSearchesPerDay+extra {
+		NewWebhookService().DispatchQuotaExceeded(userID, "searches", user.MaxSearchesPerDay+extra)
+		return false, nil
+	}
+	return s.checkOrganizationLimit(userID, "searches")
+}
+
+// checkOrganizationLimit checks the shared daily limit of the user's organization, which applies on
+// top of the user's own limit and is not raised by their search credits
+func (s *AuthService) checkOrganizationLimit(userID uuid.UUID, kind string) (bool, error) {
+	allowed, err := NewOrganizationService().CheckQuota(userID, kind)
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		utils.LogInfo(fmt.Sprintf("User %s reached their organization's daily %s limit", userID, kind))
+	}
+	return allowed, nil
+}
+
+// GetTodayUsage returns the user's search and export counters for the current quota day
+func (s *AuthService) GetTodayUsage(userID uuid.UUID) (*models.DailyUsage, error) {
+	today := CurrentQuotaDate()
+
+	usage := &models.DailyUsage{UserID: userID}
+	query := `SELECT COALESCE(search_count, 0) AS search_count, COALESCE(export_count, 0) AS export_count,
+	                 credits_used, adjusted_searches_used
+	          FROM daily_usage WHERE user_id = $1 AND date = $2`
+	err := database.PostgresDB.Get(usage, query, userID, today)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+
+	return usage, nil
 }
 
-// IncrementSearchCount increments the user's daily search count
+// GetQuotaUsage returns the user's search and export usage against their daily limits and when
+// the counters next reset
+func (s *AuthService) GetQuotaUsage(userID uuid.UUID) (*models.QuotaUsage, error) {
+	var user models.User
+	query := `SELECT max_searches_per_day, max_exports_per_day, quota_exempt FROM users WHERE id = $1`
+	if err := database.PostgresDB.Get(&user, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	usage, err := s.GetTodayUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	credits, err := NewSearchCreditService().GetTodaySearchCredits(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustedSearches, adjustedExports, err := NewQuotaAdjustmentService().GetTodayAdjustments(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	organization, err := NewOrganizationService().GetQuota(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	nextReset := NextQuotaReset()
+	return &models.QuotaUsage{
+		Date:             CurrentQuotaDate(),
+		Searches:         newQuotaStatus(usage.SearchCount, user.MaxSearchesPerDay+credits+adjustedSearches),
+		SearchesExempt:   user.QuotaExempt,
+		SearchCredits:    credits,
+		Exports:          newQuotaStatus(usage.ExportCount, user.MaxExportsPerDay+adjustedExports),
+		AdjustedSearches: adjustedSearches,
+		AdjustedExports:  adjustedExports,
+		Organization:     organization,
+		NextReset:        nextReset,
+		TimeUntilReset:   time.Until(nextReset).Round(time.Second).String(),
+	}, nil
+}
+
+// IncrementSearchCount increments the user's daily search count. Searches by quota exempt users are
+// not counted.
 func (s *AuthService) IncrementSearchCount(userID uuid.UUID) error {
-	istNow := time.Now().Add(5*time.Hour + 30*time.Minute) // Convert to IST
-	today := istNow.Format("2006-01-02")
+	var exempt bool
+	if err := database.PostgresDB.Get(&exempt, `SELECT quota_exempt FROM users WHERE id = $1`, userID); err != nil {
+		return err
+	}
+	if exempt {
+		return nil
+	}
+
+	today := CurrentQuotaDate()
+
+	adjustedSearches, _, err := NewQuotaAdjustmentService().GetTodayAdjustments(userID)
+	if err != nil {
+		return err
+	}
+
+	// A search beyond max_searches_per_day is covered by today's quota adjustments while they last
+	// and recorded as adjusted_searches_used, after that it is paid for with credits and recorded
+	// as credits_used
+	query := `WITH u AS (SELECT max_searches_per_day AS max_searches FROM users WHERE id = $1)
+	          INSERT INTO daily_usage (user_id, date, search_count, export_count, credits_used, adjusted_searches_used)
+	          SELECT $1, $2, 1, 0,
+	                 CASE WHEN u.max_searches < 1 AND $3 < 1 THEN 1 ELSE 0 END,
+	                 CASE WHEN u.max_searches < 1 AND $3 >= 1 THEN 1 ELSE 0 END
+	          FROM u
+	          ON CONFLICT (user_id, date)
+	          DO UPDATE SET search_count = daily_usage.search_count + 1,
+	                        credits_used = daily_usage.credits_used +
+	                            CASE WHEN daily_usage.search_count + 1 > (SELECT max_searches FROM u)
+	                                  AND daily_usage.adjusted_searches_used >= $3 THEN 1 ELSE 0 END,
+	                        adjusted_searches_used = daily_usage.adjusted_searches_used +
+	                            CASE WHEN daily_usage.search_count + 1 > (SELECT max_searches FROM u)
+	                                  AND daily_usage.adjusted_searches_used < $3 THEN 1 ELSE 0 END
+	          RETURNING search_count`
+
+	var searchCount int
+	if err := database.PostgresDB.Get(&searchCount, query, userID, today, adjustedSearches); err != nil {
+		return err
+	}
+
+	NewNotificationService().NotifyQuotaUsage(userID, "searches", searchCount)
+	return nil
+}
+
+// CheckExportLimit checks if user can perform more exports today
+func (s *AuthService) CheckExportLimit(userID uuid.UUID) (bool, error) {
+	var user models.User
+	query := `SELECT max_exports_per_day FROM users WHERE id = $1 AND is_active = true`
+	err := database.PostgresDB.Get(&user, query, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	usage, err := s.GetTodayUsage(userID)
+	if err != nil {
+		return false, err
+	}
+
+	if usage.ExportCount < user.MaxExportsPerDay {
+		return s.checkOrganizationLimit(userID, "exports")
+	}
+
+	// Over the daily limit: allow the export if the user's quota was adjusted for today
+	_, extra, err := NewQuotaAdjustmentService().GetTodayAdjustments(userID)
+	if err != nil {
+		return false, err
+	}
+	if usage.ExportCount >= user.MaxExportsPerDay+extra {
+		NewWebhookService().DispatchQuotaExceeded(userID, "exports", user.MaxExportsPerDay+extra)
+		return false, nil
+	}
+	return s.checkOrganizationLimit(userID, "exports")
+}
+
+// IncrementExportCount increments the user's daily export count
+func (s *AuthService) IncrementExportCount(userID uuid.UUID) error {
+	today := CurrentQuotaDate()
 
 	query := `INSERT INTO daily_usage (user_id, date, search_count, export_count)
-	          VALUES ($1, $2, 1, 0)
+	          VALUES ($1, $2, 0, 1)
 	          ON CONFLICT (user_id, date)
-	          DO UPDATE SET search_count = daily_usage.search_count + 1`
+	          DO UPDATE SET export_count = daily_usage.export_count + 1
+	          RETURNING export_count`
+
+	var exportCount int
+	if err := database.PostgresDB.Get(&exportCount, query, userID, today); err != nil {
+		return err
+	}
 
-	_, err := database.PostgresDB.Exec(query, userID, today)
-	return err
+	NewNotificationService().NotifyQuotaUsage(userID, "exports", exportCount)
+	return nil
 }
 
 // ResetUserDailySearchCount resets the daily search count for a specific user to 0
 func (s *AuthService) ResetUserDailySearchCount(userID uuid.UUID) error {
-	istNow := time.Now().Add(5*time.Hour + 30*time.Minute) // Convert to IST
-	today := istNow.Format("2006-01-02")
+	today := CurrentQuotaDate()
 
 	// Delete the daily usage record for today - this effectively resets count to 0
 	query := `DELETE FROM daily_usage WHERE user_id = $1 AND date = $2`
@@ -534,8 +856,7 @@ func (s *AuthService) ResetUserDailySearchCount(userID uuid.UUID) error {
 
 // GetUserAnalytics returns analytics for all users (admin only)
 func (s *AuthService) GetUserAnalytics() ([]models.UserAnalytics, error) {
-	istNow := time.Now().Add(5*time.Hour + 30*time.Minute)
-	today := istNow.Format("2006-01-02")
+	today := CurrentQuotaDate()
 
 	query := `
 	SELECT
@@ -543,14 +864,18 @@ func (s *AuthService) GetUserAnalytics() ([]models.UserAnalytics, error) {
 		u.name,
 		u.email,
 		COALESCE(total_searches.count, 0) as total_searches,
+		COALESCE(total_searches.diagnostic, 0) as diagnostic_searches,
 		COALESCE(today_usage.search_count, 0) as today_searches,
 		COALESCE(total_exports.count, 0) as total_exports,
 		COALESCE(today_usage.export_count, 0) as today_exports,
+		COALESCE(today_usage.credits_used, 0) as today_credits_used,
 		last_login.login_time as last_login,
 		last_search.search_time as last_search_time
 	FROM users u
 	LEFT JOIN (
-		SELECT user_id, COUNT(*) as count
+		SELECT user_id,
+		       COUNT(*) FILTER (WHERE NOT is_diagnostic) as count,
+		       COUNT(*) FILTER (WHERE is_diagnostic) as diagnostic
 		FROM searches
 		GROUP BY user_id
 	) total_searches ON u.id = total_searches.user_id
@@ -560,7 +885,7 @@ func (s *AuthService) GetUserAnalytics() ([]models.UserAnalytics, error) {
 		GROUP BY user_id
 	) total_exports ON u.id = total_exports.user_id
 	LEFT JOIN (
-		SELECT user_id, search_count, export_count
+		SELECT user_id, search_count, export_count, credits_used
 		FROM daily_usage
 		WHERE date = $1
 	) today_usage ON u.id = today_usage.user_id
@@ -583,8 +908,7 @@ func (s *AuthService) GetUserAnalytics() ([]models.UserAnalytics, error) {
 
 // GetUserAnalyticsByID returns analytics for a specific user
 func (s *AuthService) GetUserAnalyticsByID(userID uuid.UUID) (*models.UserAnalytics, error) {
-	istNow := time.Now().Add(5*time.Hour + 30*time.Minute)
-	today := istNow.Format("2006-01-02")
+	today := CurrentQuotaDate()
 
 	query := `
 	SELECT
@@ -592,14 +916,18 @@ func (s *AuthService) GetUserAnalyticsByID(userID uuid.UUID) (*models.UserAnalyt
 		u.name,
 		u.email,
 		COALESCE(total_searches.count, 0) as total_searches,
+		COALESCE(total_searches.diagnostic, 0) as diagnostic_searches,
 		COALESCE(today_usage.search_count, 0) as today_searches,
 		COALESCE(total_exports.count, 0) as total_exports,
 		COALESCE(today_usage.export_count, 0) as today_exports,
+		COALESCE(today_usage.credits_used, 0) as today_credits_used,
 		last_login.login_time as last_login,
 		last_search.search_time as last_search_time
 	FROM users u
 	LEFT JOIN (
-		SELECT user_id, COUNT(*) as count
+		SELECT user_id,
+		       COUNT(*) FILTER (WHERE NOT is_diagnostic) as count,
+		       COUNT(*) FILTER (WHERE is_diagnostic) as diagnostic
 		FROM searches
 		GROUP BY user_id
 	) total_searches ON u.id = total_searches.user_id
@@ -609,7 +937,7 @@ func (s *AuthService) GetUserAnalyticsByID(userID uuid.UUID) (*models.UserAnalyt
 		GROUP BY user_id
 	) total_exports ON u.id = total_exports.user_id
 	LEFT JOIN (
-		SELECT user_id, search_count, export_count
+		SELECT user_id, search_count, export_count, credits_used
 		FROM daily_usage
 		WHERE date = $1
 	) today_usage ON u.id = today_usage.user_id
@@ -641,7 +969,7 @@ func (s *AuthService) GetUserRecentSearches(userID uuid.UUID, limit int) ([]mode
 	}
 
 	query := `
-	SELECT id, search_time, search_query, result_count, execution_time_ms
+	SELECT id, search_time, search_query, result_count, execution_time_ms, is_diagnostic, anonymized_at
 	FROM searches
 	WHERE user_id = $1
 	ORDER BY search_time DESC
@@ -656,6 +984,29 @@ func (s *AuthService) GetUserRecentSearches(userID uuid.UUID, limit int) ([]mode
 	return searches, nil
 }
 
+// GetUserSearchHistory returns a page of a user's searches, newest first (admin only)
+func (s *AuthService) GetUserSearchHistory(userID uuid.UUID, params models.PageParams) (*models.Paginated[models.RecentSearch], error) {
+	var totalCount int
+	if err := database.PostgresDB.Get(&totalCount, `SELECT COUNT(*) FROM searches WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to get search count: %w", err)
+	}
+
+	query := `
+	SELECT id, search_time, search_query, result_count, execution_time_ms, is_diagnostic, anonymized_at
+	FROM searches
+	WHERE user_id = $1
+	ORDER BY search_time DESC
+	LIMIT $2 OFFSET $3`
+
+	var searches []models.RecentSearch
+	err := database.PostgresDB.Select(&searches, query, userID, params.Limit, params.Offset())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search history: %w", err)
+	}
+
+	return models.NewPaginated(searches, totalCount, params), nil
+}
+
 // GetUserAnalyticsWithSearches returns analytics with recent searches for a user (admin only)
 func (s *AuthService) GetUserAnalyticsWithSearches(userID uuid.UUID) (*models.UserAnalyticsWithSearches, error) {
 	// Get basic analytics
@@ -737,5 +1088,7 @@ func (s *AuthService) DeleteUser(userID uuid.UUID) error {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	sessionCache.invalidateUser(userID)
+
 	return nil
 }
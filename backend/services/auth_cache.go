@@ -0,0 +1,146 @@
+package services
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+)
+
+// authCache is a read-through cache of validated sessions keyed by token hash. Entries live for
+// a short TTL and are dropped explicitly on logout and on any change to the user, so a stale
+// entry can only outlive a change made by another instance for at most the TTL.
+type authCache struct {
+	mu       sync.RWMutex
+	sessions map[string]authCacheEntry
+	byUser   map[uuid.UUID]map[string]bool // user ID -> cached token hashes
+	hits     atomic.Uint64
+	misses   atomic.Uint64
+}
+
+type authCacheEntry struct {
+	user      models.User
+	expiresAt time.Time
+}
+
+// sessionCache is shared by every AuthService since services are created per request
+var sessionCache = &authCache{
+	sessions: make(map[string]authCacheEntry),
+	byUser:   make(map[uuid.UUID]map[string]bool),
+}
+
+// get returns a copy of the cached user for a token hash if the entry is still fresh
+func (c *authCache) get(tokenHash string) (*models.User, bool) {
+	c.mu.RLock()
+	entry, ok := c.sessions[tokenHash]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+
+	user := entry.user
+	return &user, true
+}
+
+// set caches a validated session, never beyond the session's own expiry
+func (c *authCache) set(tokenHash string, user *models.User, sessionExpiresAt time.Time) {
+	ttl := config.Get().Cache.AuthTTL
+	if ttl <= 0 {
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if sessionExpiresAt.Before(expiresAt) {
+		expiresAt = sessionExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sessions[tokenHash] = authCacheEntry{user: *user, expiresAt: expiresAt}
+	if c.byUser[user.ID] == nil {
+		c.byUser[user.ID] = make(map[string]bool)
+	}
+	c.byUser[user.ID][tokenHash] = true
+
+	// Opportunistically drop expired entries so the maps cannot grow without bound
+	if len(c.sessions)%1000 == 0 {
+		c.evictExpiredLocked()
+	}
+}
+
+// invalidateToken drops a single cached session, e.g. on logout
+func (c *authCache) invalidateToken(tokenHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.sessions[tokenHash]; ok {
+		delete(c.byUser[entry.user.ID], tokenHash)
+		if len(c.byUser[entry.user.ID]) == 0 {
+			delete(c.byUser, entry.user.ID)
+		}
+		delete(c.sessions, tokenHash)
+	}
+}
+
+// invalidateUser drops every cached session of a user, e.g. on role change or deactivation
+func (c *authCache) invalidateUser(userID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for tokenHash := range c.byUser[userID] {
+		delete(c.sessions, tokenHash)
+	}
+	delete(c.byUser, userID)
+}
+
+// flush drops every cached session and returns how many there were
+func (c *authCache) flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := len(c.sessions)
+	c.sessions = make(map[string]authCacheEntry)
+	c.byUser = make(map[uuid.UUID]map[string]bool)
+	return entries
+}
+
+// stats returns the number of cached sessions and users, an estimate of their size in bytes and the
+// lookup counters since startup
+func (c *authCache) stats() (entries, users, approxBytes int, hits, misses uint64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for tokenHash, entry := range c.sessions {
+		user := &entry.user
+		approxBytes += len(tokenHash) + len(user.Name) + len(user.Email) + len(user.UserType) + len(user.Role)
+		for _, field := range user.AllowedSearchFields {
+			approxBytes += len(field)
+		}
+		approxBytes += authCacheEntryOverhead
+	}
+	return len(c.sessions), len(c.byUser), approxBytes, c.hits.Load(), c.misses.Load()
+}
+
+// authCacheEntryOverhead approximates the fixed size of an entry and its map slots
+const authCacheEntryOverhead = 256
+
+func (c *authCache) evictExpiredLocked() {
+	now := time.Now()
+	for tokenHash, entry := range c.sessions {
+		if now.After(entry.expiresAt) {
+			delete(c.byUser[entry.user.ID], tokenHash)
+			if len(c.byUser[entry.user.ID]) == 0 {
+				delete(c.byUser, entry.user.ID)
+			}
+			delete(c.sessions, tokenHash)
+		}
+	}
+}
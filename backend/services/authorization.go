@@ -0,0 +1,359 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"finone-search-system/models"
+)
+
+// Permissions required by protected routes
+const (
+	PermissionProfile               = "profile"
+	PermissionPasswordChange        = "password_change"
+	PermissionSearch                = "search"
+	PermissionExport                = "export"
+	PermissionManageUsers           = "admin:users"
+	PermissionManageRegistrations   = "admin:registrations"
+	PermissionManagePasswordChanges = "admin:password_changes"
+	PermissionManageSessions        = "admin:sessions"
+	PermissionManageQuotas          = "admin:quotas"
+	PermissionImport                = "admin:import"
+	PermissionSimulate              = "admin:simulate"
+	PermissionFieldPolicies         = "admin:field_policies"
+	PermissionClickHouse            = "admin:clickhouse"
+	PermissionAudit                 = "admin:audit"
+	PermissionWebhooks              = "admin:webhooks"
+	PermissionSearchDebug           = "admin:search_debug" // Request execution traces with debug on searches
+	PermissionCache                 = "admin:cache"
+	PermissionSearchDiagnostic      = "admin:search_diagnostic" // Run quota-exempt diagnostic searches
+	PermissionRetention             = "admin:retention"
+	PermissionManagePeople          = "admin:people"   // Create, correct and delete individual people rows
+	PermissionManageDatasets        = "admin:datasets" // Register datasets and entitle users; also grants use of every dataset
+	PermissionOrganization          = "organization"   // Manage the members of one's own organization; organization admins only
+	PermissionManageOrganizations   = "admin:organizations"
+	PermissionConfig                = "admin:config" // Reload the configuration without a restart
+	PermissionSigningKeys           = "admin:signing_keys"
+	PermissionDataExport            = "admin:data_export" // Download the users, usage and search tables as CSV
+	PermissionSearchTemplates       = "admin:search_templates"
+	PermissionJobs                  = "admin:jobs"               // List background jobs and retry dead ones
+	PermissionSystemMaintenance     = "admin:system_maintenance" // Turn maintenance mode on and off
+)
+
+// rolePermissions lists the permissions granted to each role
+var rolePermissions = map[string][]string{
+	"USER": {
+		PermissionProfile,
+		PermissionPasswordChange,
+		PermissionSearch,
+		PermissionExport,
+		PermissionOrganization,
+	},
+	"ADMIN": {
+		PermissionProfile,
+		PermissionPasswordChange,
+		PermissionSearch,
+		PermissionExport,
+		PermissionManageUsers,
+		PermissionManageRegistrations,
+		PermissionManagePasswordChanges,
+		PermissionManageSessions,
+		PermissionManageQuotas,
+		PermissionImport,
+		PermissionSimulate,
+		PermissionFieldPolicies,
+		PermissionClickHouse,
+		PermissionAudit,
+		PermissionWebhooks,
+		PermissionSearchDebug,
+		PermissionCache,
+		PermissionSearchDiagnostic,
+		PermissionRetention,
+		PermissionManagePeople,
+		PermissionManageDatasets,
+		PermissionManageOrganizations,
+		PermissionConfig,
+		PermissionSigningKeys,
+		PermissionDataExport,
+		PermissionSearchTemplates,
+		PermissionJobs,
+		PermissionSystemMaintenance,
+	},
+}
+
+// routePermissions maps "METHOD /full/path" of every protected route to the permission it requires.
+// Protected routes missing from this table are denied.
+var routePermissions = map[string]string{
+	// User routes
+	"GET /api/v1/users/profile":   PermissionProfile,
+	"GET /api/v1/users/analytics": PermissionProfile,
+	"GET /api/v1/users/quota":     PermissionProfile,
+	"POST /api/v1/users/logout":   PermissionProfile,
+
+	// Import and export progress; what each user receives is scoped in the handler
+	"GET /api/v1/ws": PermissionProfile,
+
+	"GET /api/v1/users/sessions":                PermissionProfile,
+	"DELETE /api/v1/users/sessions/:session_id": PermissionProfile,
+
+	"GET /api/v1/users/exports":                 PermissionExport,
+	"POST /api/v1/users/exports/:id/regenerate": PermissionExport,
+
+	// Password change request routes
+	"POST /api/v1/password-change-requests/":  PermissionPasswordChange,
+	"GET /api/v1/password-change-requests/my": PermissionPasswordChange,
+
+	// Search routes
+	"POST /api/v1/search/":                      PermissionSearch,
+	"POST /api/v1/search/within":                PermissionSearch,
+	"GET /api/v1/search/:search_id/results":     PermissionSearch,
+	"POST /api/v1/search/mobile/enhanced":       PermissionSearch,
+	"POST /api/v1/search/enhanced-mobile":       PermissionSearch,
+	"POST /api/v1/search/enhanced":              PermissionSearch,
+	"GET /api/v1/search/person/:id":             PermissionSearch,
+	"GET /api/v1/search/person/:id/graph":       PermissionSearch,
+	"GET /api/v1/search/person/:id/history":     PermissionSearch,
+	"GET /api/v1/search/stats":                  PermissionSearch,
+	"GET /api/v1/search/stats/pincode/:pincode": PermissionSearch,
+	"GET /api/v1/search/datasets":               PermissionSearch,
+	"GET /api/v1/search/templates":              PermissionSearch,
+	"POST /api/v1/search/templates/:id/run":     PermissionSearch,
+	"POST /api/v1/search/export":                PermissionExport,
+
+	// User management
+	"POST /api/v1/admin/users":                        PermissionManageUsers,
+	"POST /api/v1/admin/users/bulk":                   PermissionManageUsers,
+	"GET /api/v1/admin/users":                         PermissionManageUsers,
+	"GET /api/v1/admin/users/:id":                     PermissionManageUsers,
+	"PUT /api/v1/admin/users/:id":                     PermissionManageUsers,
+	"DELETE /api/v1/admin/users/:id":                  PermissionManageUsers,
+	"GET /api/v1/admin/dashboard":                     PermissionManageUsers,
+	"GET /api/v1/admin/analytics":                     PermissionManageUsers,
+	"GET /api/v1/admin/analytics/clients":             PermissionManageUsers,
+	"GET /api/v1/admin/analytics/search-performance":  PermissionManageUsers,
+	"GET /api/v1/admin/analytics/slow-queries":        PermissionManageUsers,
+	"GET /api/v1/admin/analytics/top-queries":         PermissionManageUsers,
+	"GET /api/v1/admin/analytics/zero-result-queries": PermissionManageUsers,
+
+	// Registration request management
+	"GET /api/v1/admin/registration-requests":        PermissionManageRegistrations,
+	"GET /api/v1/admin/registration-requests/:id":    PermissionManageRegistrations,
+	"PUT /api/v1/admin/registration-requests/:id":    PermissionManageRegistrations,
+	"DELETE /api/v1/admin/registration-requests/:id": PermissionManageRegistrations,
+
+	// Password change request management
+	"GET /api/v1/admin/password-change-requests":        PermissionManagePasswordChanges,
+	"GET /api/v1/admin/password-change-requests/:id":    PermissionManagePasswordChanges,
+	"PUT /api/v1/admin/password-change-requests/:id":    PermissionManagePasswordChanges,
+	"DELETE /api/v1/admin/password-change-requests/:id": PermissionManagePasswordChanges,
+
+	// Session management
+	"GET /api/v1/admin/sessions":                PermissionManageSessions,
+	"GET /api/v1/admin/users/:id/sessions":      PermissionManageSessions,
+	"DELETE /api/v1/admin/users/:id/sessions":   PermissionManageSessions,
+	"DELETE /api/v1/admin/sessions/:session_id": PermissionManageSessions,
+	"POST /api/v1/admin/sessions/cleanup":       PermissionManageSessions,
+
+	// User search history
+	"GET /api/v1/admin/users/:id/search-history": PermissionManageUsers,
+
+	// Daily reset management
+	"POST /api/v1/admin/reset/daily-search-counts":          PermissionManageQuotas,
+	"POST /api/v1/admin/users/:id/reset-daily-search-count": PermissionManageQuotas,
+	"GET /api/v1/admin/users/:id/search-credits":            PermissionManageQuotas,
+	"POST /api/v1/admin/users/:id/search-credits":           PermissionManageQuotas,
+	"POST /api/v1/admin/users/:id/quota-adjustment":         PermissionManageQuotas,
+	"POST /api/v1/admin/users/:id/upgrade":                  PermissionManageUsers,
+	"GET /api/v1/admin/users/:id/upgrades":                  PermissionManageUsers,
+	"POST /api/v1/admin/users/:id/impersonate":              PermissionManageUsers,
+	"GET /api/v1/admin/reset/next-reset-time":               PermissionManageQuotas,
+
+	// CSV import
+	"POST /api/v1/admin/import/csv":                             PermissionImport,
+	"POST /api/v1/admin/import/csv-path":                        PermissionImport,
+	"POST /api/v1/admin/import/url":                             PermissionImport,
+	"POST /api/v1/admin/import/uploads":                         PermissionImport,
+	"GET /api/v1/admin/import/uploads/:upload_id":               PermissionImport,
+	"PUT /api/v1/admin/import/uploads/:upload_id/chunks/:index": PermissionImport,
+	"POST /api/v1/admin/import/uploads/:upload_id/complete":     PermissionImport,
+	"DELETE /api/v1/admin/import/uploads/:upload_id":            PermissionImport,
+	"POST /api/v1/admin/import/profile":                         PermissionImport,
+	"POST /api/v1/admin/import/profile-path":                    PermissionImport,
+	"GET /api/v1/admin/import/audit":                            PermissionImport,
+	"GET /api/v1/admin/import/jobs/:job_id/rows":                PermissionImport,
+	"DELETE /api/v1/admin/import/jobs/:job_id/rows":             PermissionImport,
+	"GET /api/v1/admin/import/jobs/:job_id/errors":              PermissionImport,
+	"GET /api/v1/admin/import/jobs/:job_id/changes":             PermissionImport,
+	"POST /api/v1/admin/import/jobs/:job_id/changes/apply":      PermissionImport,
+	"DELETE /api/v1/admin/import/jobs/:job_id/changes":          PermissionImport,
+	"GET /api/v1/admin/import/pincodes":                         PermissionImport,
+	"POST /api/v1/admin/import/pincodes":                        PermissionImport,
+	"GET /api/v1/admin/import/circles":                          PermissionImport,
+	"POST /api/v1/admin/import/circles":                         PermissionImport,
+	"DELETE /api/v1/admin/import/circles/:alias":                PermissionImport,
+	"GET /api/v1/admin/import/circles/unmapped":                 PermissionImport,
+	"POST /api/v1/admin/quality/validate":                       PermissionImport,
+	"POST /api/v1/admin/quality/revalidate":                     PermissionClickHouse,
+	"GET /api/v1/admin/quality/summary":                         PermissionImport,
+
+	// Quota and access decision simulation
+	"POST /api/v1/admin/simulate": PermissionSimulate,
+
+	// Field visibility policies
+	"GET /api/v1/admin/field-policies":        PermissionFieldPolicies,
+	"PUT /api/v1/admin/field-policies":        PermissionFieldPolicies,
+	"DELETE /api/v1/admin/field-policies/:id": PermissionFieldPolicies,
+
+	// ClickHouse index management
+	"GET /api/v1/admin/clickhouse/indexes":               PermissionClickHouse,
+	"POST /api/v1/admin/clickhouse/indexes/materialize":  PermissionClickHouse,
+	"GET /api/v1/admin/clickhouse/people-tables":         PermissionClickHouse,
+	"POST /api/v1/admin/clickhouse/people-tables":        PermissionClickHouse,
+	"POST /api/v1/admin/clickhouse/people-tables/switch": PermissionClickHouse,
+	"GET /api/v1/admin/system/clickhouse":                PermissionClickHouse,
+	"GET /api/v1/admin/system/maintenance":               PermissionSystemMaintenance,
+	"PUT /api/v1/admin/system/maintenance":               PermissionSystemMaintenance,
+	"GET /api/v1/admin/maintenance/optimize":             PermissionClickHouse,
+	"POST /api/v1/admin/maintenance/optimize":            PermissionClickHouse,
+
+	// Access auditing
+	"GET /api/v1/admin/permissions/matrix":  PermissionAudit,
+	"GET /api/v1/admin/config":              PermissionAudit,
+	"POST /api/v1/admin/config/reload":      PermissionConfig,
+	"GET /api/v1/admin/searches/:search_id": PermissionAudit,
+	"POST /api/v1/admin/exports/trace":      PermissionAudit,
+	"GET /api/v1/admin/impersonations":      PermissionAudit,
+	"GET /api/v1/admin/logins":              PermissionAudit,
+
+	// Session token signing keys
+	"GET /api/v1/admin/jwt-keys":              PermissionSigningKeys,
+	"POST /api/v1/admin/jwt-keys/rotate":      PermissionSigningKeys,
+	"POST /api/v1/admin/jwt-keys/:kid/retire": PermissionSigningKeys,
+
+	// Table exports for compliance reviews and billing reconciliation
+	"GET /api/v1/admin/export/users":    PermissionDataExport,
+	"GET /api/v1/admin/export/usage":    PermissionDataExport,
+	"GET /api/v1/admin/export/searches": PermissionDataExport,
+
+	// Manual fixes to individual people rows
+	"POST /api/v1/admin/people":          PermissionManagePeople,
+	"PUT /api/v1/admin/people/:id":       PermissionManagePeople,
+	"DELETE /api/v1/admin/people/:id":    PermissionManagePeople,
+	"GET /api/v1/admin/people/:id/edits": PermissionManagePeople,
+
+	// Datasets
+	"GET /api/v1/admin/datasets":                        PermissionManageDatasets,
+	"POST /api/v1/admin/datasets":                       PermissionManageDatasets,
+	"PUT /api/v1/admin/datasets/:id":                    PermissionManageDatasets,
+	"DELETE /api/v1/admin/datasets/:id":                 PermissionManageDatasets,
+	"GET /api/v1/admin/datasets/:id/stats":              PermissionManageDatasets,
+	"GET /api/v1/admin/datasets/:id/access":             PermissionManageDatasets,
+	"POST /api/v1/admin/datasets/:id/access":            PermissionManageDatasets,
+	"DELETE /api/v1/admin/datasets/:id/access/:user_id": PermissionManageDatasets,
+
+	"GET /api/v1/admin/search-templates":        PermissionSearchTemplates,
+	"POST /api/v1/admin/search-templates":       PermissionSearchTemplates,
+	"PUT /api/v1/admin/search-templates/:id":    PermissionSearchTemplates,
+	"DELETE /api/v1/admin/search-templates/:id": PermissionSearchTemplates,
+
+	"GET /api/v1/admin/jobs":            PermissionJobs,
+	"GET /api/v1/admin/jobs/:id":        PermissionJobs,
+	"POST /api/v1/admin/jobs/:id/retry": PermissionJobs,
+
+	// Organizations
+	"GET /api/v1/organization":                                PermissionOrganization,
+	"GET /api/v1/organization/members":                        PermissionOrganization,
+	"POST /api/v1/organization/members":                       PermissionOrganization,
+	"PUT /api/v1/organization/members/:user_id":               PermissionOrganization,
+	"DELETE /api/v1/organization/members/:user_id":            PermissionOrganization,
+	"GET /api/v1/admin/organizations":                         PermissionManageOrganizations,
+	"POST /api/v1/admin/organizations":                        PermissionManageOrganizations,
+	"GET /api/v1/admin/organizations/:id":                     PermissionManageOrganizations,
+	"PUT /api/v1/admin/organizations/:id":                     PermissionManageOrganizations,
+	"DELETE /api/v1/admin/organizations/:id":                  PermissionManageOrganizations,
+	"PUT /api/v1/admin/organizations/:id/members/:user_id":    PermissionManageOrganizations,
+	"DELETE /api/v1/admin/organizations/:id/members/:user_id": PermissionManageOrganizations,
+
+	// Database connection pools
+	"GET /api/v1/admin/storage": PermissionAudit,
+
+	// In-process caches
+	"GET /api/v1/admin/cache/stats":  PermissionCache,
+	"POST /api/v1/admin/cache/flush": PermissionCache,
+
+	// Log retention
+	"GET /api/v1/admin/retention":                     PermissionRetention,
+	"POST /api/v1/admin/retention/purge":              PermissionRetention,
+	"POST /api/v1/admin/retention/anonymize":          PermissionRetention,
+	"PUT /api/v1/admin/retention/policies/:name":      PermissionRetention,
+	"DELETE /api/v1/admin/retention/policies/:name":   PermissionRetention,
+	"POST /api/v1/admin/retention/policies/:name/run": PermissionRetention,
+
+	// Webhooks
+	"GET /api/v1/admin/webhooks":                                   PermissionWebhooks,
+	"POST /api/v1/admin/webhooks":                                  PermissionWebhooks,
+	"PUT /api/v1/admin/webhooks/:id":                               PermissionWebhooks,
+	"DELETE /api/v1/admin/webhooks/:id":                            PermissionWebhooks,
+	"GET /api/v1/admin/webhooks/:id/deliveries":                    PermissionWebhooks,
+	"POST /api/v1/admin/webhooks/:id/deliveries/:deliveryId/retry": PermissionWebhooks,
+}
+
+type AuthorizationService struct{}
+
+func NewAuthorizationService() *AuthorizationService {
+	return &AuthorizationService{}
+}
+
+// RequiredPermission returns the permission a route requires and whether the route is in the table
+func (s *AuthorizationService) RequiredPermission(method, path string) (string, bool) {
+	permission, ok := routePermissions[method+" "+path]
+	return permission, ok
+}
+
+// HasPermission reports whether a role has been granted a permission
+func (s *AuthorizationService) HasPermission(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPermissionMatrix returns the effective access of every role for every protected route
+func (s *AuthorizationService) GetPermissionMatrix() *models.PermissionMatrix {
+	roles := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	matrix := &models.PermissionMatrix{
+		Roles:           roles,
+		RolePermissions: rolePermissions,
+		Routes:          make([]models.RouteAccess, 0, len(routePermissions)),
+	}
+
+	for route, permission := range routePermissions {
+		method, path, _ := strings.Cut(route, " ")
+		access := models.RouteAccess{
+			Method:     method,
+			Path:       path,
+			Permission: permission,
+			Roles:      make(map[string]bool, len(roles)),
+		}
+		for _, role := range roles {
+			access.Roles[role] = s.HasPermission(role, permission)
+		}
+		matrix.Routes = append(matrix.Routes, access)
+	}
+
+	sort.Slice(matrix.Routes, func(i, j int) bool {
+		if matrix.Routes[i].Path != matrix.Routes[j].Path {
+			return matrix.Routes[i].Path < matrix.Routes[j].Path
+		}
+		return matrix.Routes[i].Method < matrix.Routes[j].Method
+	})
+
+	return matrix
+}
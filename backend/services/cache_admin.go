@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// Cache namespaces that can be inspected and flushed
+const (
+	CacheUserProfiles     = "user_profiles"     // Validated sessions with the user record, see authCache
+	CachePincodeLocations = "pincode_locations" // Pincode coordinates used by nearby searches
+	CacheCircleAliases    = "circle_aliases"    // Circle normalization map used by imports and circle searches
+)
+
+// cacheDescriptions lists every cache namespace
+var cacheDescriptions = map[string]string{
+	CacheUserProfiles:     "Validated sessions and their user profiles, used by the auth middleware",
+	CachePincodeLocations: "Pincode coordinates used by nearby searches",
+	CacheCircleAliases:    "Circle normalization map used by imports and circle searches",
+}
+
+// CacheAdminService reports on and clears the in-process caches. Caches are per instance, so a
+// flush only affects the instance that served the request.
+type CacheAdminService struct{}
+
+func NewCacheAdminService() *CacheAdminService {
+	return &CacheAdminService{}
+}
+
+// GetStats returns the size and hit rate of every cache namespace
+func (s *CacheAdminService) GetStats() *models.CacheStatsResponse {
+	entries, users, approxBytes, hits, misses := sessionCache.stats()
+	profiles := models.CacheStats{
+		Namespace:   CacheUserProfiles,
+		Description: fmt.Sprintf("%s (%d users)", cacheDescriptions[CacheUserProfiles], users),
+		Entries:     entries,
+		ApproxBytes: approxBytes,
+		Hits:        &hits,
+		Misses:      &misses,
+	}
+	if hits+misses > 0 {
+		rate := float64(hits) / float64(hits+misses)
+		profiles.HitRate = &rate
+	}
+	if config.Get() != nil {
+		profiles.TTL = config.Get().Cache.AuthTTL.String()
+	}
+
+	locations, locationBytes := pincodeLocationStats()
+	aliases, aliasBytes := circleMapStats()
+	return &models.CacheStatsResponse{
+		Namespaces: []models.CacheStats{
+			profiles,
+			{
+				Namespace:   CachePincodeLocations,
+				Description: cacheDescriptions[CachePincodeLocations],
+				Entries:     locations,
+				ApproxBytes: locationBytes,
+			},
+			{
+				Namespace:   CacheCircleAliases,
+				Description: cacheDescriptions[CacheCircleAliases],
+				Entries:     aliases,
+				ApproxBytes: aliasBytes,
+				TTL:         circleMapTTL.String(),
+			},
+		},
+	}
+}
+
+// Flush clears one cache namespace, or every namespace when it is empty
+func (s *CacheAdminService) Flush(namespace string) (*models.FlushCacheResponse, error) {
+	namespace = strings.ToLower(strings.TrimSpace(namespace))
+	if namespace != "" && namespace != "all" {
+		if _, ok := cacheDescriptions[namespace]; !ok {
+			return nil, fmt.Errorf("unknown cache namespace %q; available: %s", namespace, strings.Join(cacheNamespaces(), ", "))
+		}
+	}
+
+	response := &models.FlushCacheResponse{Flushed: make(map[string]int)}
+	if namespace == "" || namespace == "all" || namespace == CacheUserProfiles {
+		response.Flushed[CacheUserProfiles] = sessionCache.flush()
+	}
+	if namespace == "" || namespace == "all" || namespace == CachePincodeLocations {
+		response.Flushed[CachePincodeLocations] = flushPincodeLocations()
+	}
+	if namespace == "" || namespace == "all" || namespace == CacheCircleAliases {
+		response.Flushed[CacheCircleAliases] = flushCircleMap()
+	}
+
+	utils.LogInfo(fmt.Sprintf("Flushed caches: %v", response.Flushed))
+	return response, nil
+}
+
+func cacheNamespaces() []string {
+	namespaces := make([]string, 0, len(cacheDescriptions))
+	for namespace := range cacheDescriptions {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
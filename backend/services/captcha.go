@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+)
+
+var (
+	ErrCaptchaRequired = errors.New("a CAPTCHA token is required")
+	ErrCaptchaFailed   = errors.New("CAPTCHA verification failed")
+	// ErrCaptchaUnavailable means the provider could not be asked, so the token was not checked
+	ErrCaptchaUnavailable = errors.New("CAPTCHA verification is unavailable")
+)
+
+// CaptchaVerifier checks a CAPTCHA token solved by a client with the provider that issued it
+type CaptchaVerifier interface {
+	Verify(token, remoteIP string) error
+}
+
+var (
+	captchaVerifiersMu sync.RWMutex
+	captchaVerifiers   = map[string]func(cfg config.RegistrationConfig) CaptchaVerifier{
+		"hcaptcha": func(cfg config.RegistrationConfig) CaptchaVerifier {
+			return &siteVerifyCaptcha{url: "https://api.hcaptcha.com/siteverify", secret: cfg.CaptchaSecret}
+		},
+		"turnstile": func(cfg config.RegistrationConfig) CaptchaVerifier {
+			return &siteVerifyCaptcha{url: "https://challenges.cloudflare.com/turnstile/v0/siteverify", secret: cfg.CaptchaSecret}
+		},
+	}
+)
+
+// RegisterCaptchaVerifier makes a CAPTCHA provider selectable by name through registration.captcha_provider
+func RegisterCaptchaVerifier(name string, factory func(cfg config.RegistrationConfig) CaptchaVerifier) {
+	captchaVerifiersMu.Lock()
+	defer captchaVerifiersMu.Unlock()
+	captchaVerifiers[name] = factory
+}
+
+// newCaptchaVerifier returns the configured CAPTCHA verifier, or nil when no CAPTCHA is required
+func newCaptchaVerifier(cfg config.RegistrationConfig) (CaptchaVerifier, error) {
+	if cfg.CaptchaProvider == "" {
+		return nil, nil
+	}
+	captchaVerifiersMu.RLock()
+	factory, ok := captchaVerifiers[cfg.CaptchaProvider]
+	captchaVerifiersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown CAPTCHA provider: %s", cfg.CaptchaProvider)
+	}
+	return factory(cfg), nil
+}
+
+// siteVerifyCaptcha verifies tokens with a siteverify endpoint, which hCaptcha and Turnstile share
+type siteVerifyCaptcha struct {
+	url    string
+	secret string
+}
+
+var captchaClient = &http.Client{Timeout: 10 * time.Second}
+
+func (v *siteVerifyCaptcha) Verify(token, remoteIP string) error {
+	if strings.TrimSpace(token) == "" {
+		return ErrCaptchaRequired
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	resp, err := captchaClient.PostForm(v.url, form)
+	if err != nil {
+		return fmt.Errorf("%w: failed to reach provider: %v", ErrCaptchaUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: provider returned %s", ErrCaptchaUnavailable, resp.Status)
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%w: failed to read provider response: %v", ErrCaptchaUnavailable, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrCaptchaFailed, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Chunked upload statuses
+const (
+	UploadUploading  = "UPLOADING"
+	UploadCompleting = "COMPLETING"
+	UploadCompleted  = "COMPLETED"
+	UploadAborted    = "ABORTED"
+)
+
+var (
+	ErrUploadNotFound         = errors.New("upload not found")
+	ErrInvalidUpload          = errors.New("invalid upload")
+	ErrInvalidChunk           = errors.New("invalid chunk")
+	ErrUploadNotOpen          = errors.New("upload is not accepting chunks")
+	ErrChunkOutOfOrder        = errors.New("chunk is out of order")
+	ErrChunkConflict          = errors.New("chunk was already received with a different checksum")
+	ErrChunkChecksumMismatch  = errors.New("chunk checksum does not match its contents")
+	ErrChunkTooLarge          = errors.New("chunk is larger than the maximum chunk size")
+	ErrUploadTooLarge         = errors.New("upload is larger than its declared or maximum size")
+	ErrUploadIncomplete       = errors.New("upload is missing bytes")
+	ErrUploadChecksumMismatch = errors.New("upload checksum does not match the file")
+)
+
+// ChunkedUploadService stores large CSV uploads sent in checksummed chunks. Each chunk is appended to
+// a partial file under the CSV temp directory and confirmed in the database, so a client that loses
+// its connection asks for the upload and resumes at the next chunk.
+type ChunkedUploadService struct {
+	db *sqlx.DB
+}
+
+func NewChunkedUploadService() *ChunkedUploadService {
+	return &ChunkedUploadService{
+		db: database.PostgresDB,
+	}
+}
+
+// UploadDir is where partial uploads are stored
+func UploadDir() string {
+	return filepath.Join(config.Get().CSV.TempDir, "uploads")
+}
+
+// CreateUpload starts a chunked upload
+func (s *ChunkedUploadService) CreateUpload(userID uuid.UUID, req *models.CreateUploadRequest) (*models.UploadSession, error) {
+	if req.TotalSize != nil {
+		if *req.TotalSize <= 0 {
+			return nil, fmt.Errorf("%w: total_size must be positive", ErrInvalidUpload)
+		}
+		if limit := maxUploadBytes(); limit > 0 && *req.TotalSize > limit {
+			return nil, fmt.Errorf("%w: the maximum is %s", ErrUploadTooLarge, config.Get().Limits.MaxUploadSize)
+		}
+	}
+
+	if err := os.MkdirAll(UploadDir(), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	id := uuid.New()
+	var checksum *string
+	if req.Checksum != "" {
+		value := strings.ToLower(req.Checksum)
+		checksum = &value
+	}
+
+	var upload models.UploadSession
+	query := `INSERT INTO upload_sessions (id, user_id, file_name, upload_file, total_size, checksum)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING *`
+	err := s.db.Get(&upload, query, id, userID, filepath.Base(req.FileName), id.String()+".part", req.TotalSize, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	file, err := os.OpenFile(s.filePath(&upload), os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	file.Close()
+
+	upload.MaxChunkSize = maxChunkBytes()
+	return &upload, nil
+}
+
+// GetUpload returns an upload started by the user
+func (s *ChunkedUploadService) GetUpload(id, userID uuid.UUID) (*models.UploadSession, error) {
+	var upload models.UploadSession
+	err := s.db.Get(&upload, `SELECT * FROM upload_sessions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	upload.MaxChunkSize = maxChunkBytes()
+	return &upload, nil
+}
+
+// AppendChunk appends the chunk at index to an upload after checking it against its SHA-256 checksum.
+// Chunks must arrive in order; resending a confirmed chunk with the same checksum is a no-op, so a
+// client that did not see the response to a chunk can safely send it again.
+func (s *ChunkedUploadService) AppendChunk(id, userID uuid.UUID, index int, checksum string, body io.Reader) (*models.UploadChunkResponse, error) {
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if checksum == "" {
+		return nil, fmt.Errorf("%w: the chunk's SHA-256 is required", ErrInvalidChunk)
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The row lock serializes chunks of the same upload
+	upload, err := s.lockUpload(tx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status != UploadUploading {
+		return nil, fmt.Errorf("%w: it is %s", ErrUploadNotOpen, strings.ToLower(upload.Status))
+	}
+
+	if index < upload.NextChunk {
+		var existing string
+		err := tx.Get(&existing, `SELECT checksum FROM upload_chunks WHERE upload_id = $1 AND chunk_index = $2`, id, index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up chunk: %w", err)
+		}
+		if existing != checksum {
+			return nil, fmt.Errorf("%w: chunk %d", ErrChunkConflict, index)
+		}
+		return &models.UploadChunkResponse{UploadSession: *upload, Duplicate: true}, nil
+	}
+	if index > upload.NextChunk {
+		return nil, fmt.Errorf("%w: expected chunk %d", ErrChunkOutOfOrder, upload.NextChunk)
+	}
+
+	size, err := s.writeChunk(upload, checksum, body)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO upload_chunks (upload_id, chunk_index, byte_offset, size, checksum)
+	                  VALUES ($1, $2, $3, $4, $5)`, id, index, upload.ReceivedBytes, size, checksum)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record chunk: %w", err)
+	}
+	err = tx.Get(upload, `UPDATE upload_sessions
+	                      SET received_bytes = received_bytes + $1, chunks_received = chunks_received + 1, updated_at = now()
+	                      WHERE id = $2
+	                      RETURNING *`, size, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update upload: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit chunk: %w", err)
+	}
+
+	upload.MaxChunkSize = maxChunkBytes()
+	return &models.UploadChunkResponse{UploadSession: *upload}, nil
+}
+
+// writeChunk appends a chunk to the partial file and syncs it. The file is first cut back to the
+// confirmed bytes, dropping whatever an interrupted request left behind, and is cut back again if the
+// chunk is rejected.
+func (s *ChunkedUploadService) writeChunk(upload *models.UploadSession, checksum string, body io.Reader) (int64, error) {
+	file, err := os.OpenFile(s.filePath(upload), os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Truncate(upload.ReceivedBytes); err != nil {
+		return 0, fmt.Errorf("failed to prepare upload file: %w", err)
+	}
+	if _, err := file.Seek(upload.ReceivedBytes, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to prepare upload file: %w", err)
+	}
+
+	maxChunk := maxChunkBytes()
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(body, maxChunk+1))
+
+	reject := func(err error) (int64, error) {
+		file.Truncate(upload.ReceivedBytes)
+		return 0, err
+	}
+	if err != nil {
+		return reject(fmt.Errorf("failed to write chunk: %w", err))
+	}
+	if size == 0 {
+		return reject(fmt.Errorf("%w: the chunk is empty", ErrInvalidChunk))
+	}
+	if size > maxChunk {
+		return reject(fmt.Errorf("%w (%d bytes)", ErrChunkTooLarge, maxChunk))
+	}
+	total := upload.ReceivedBytes + size
+	if (upload.TotalSize != nil && total > *upload.TotalSize) || (maxUploadBytes() > 0 && total > maxUploadBytes()) {
+		return reject(ErrUploadTooLarge)
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != checksum {
+		return reject(ErrChunkChecksumMismatch)
+	}
+	if err := file.Sync(); err != nil {
+		return reject(fmt.Errorf("failed to sync upload file: %w", err))
+	}
+	return size, nil
+}
+
+// CompleteUpload checks that an upload is whole and holds it for import. It returns the path of the
+// uploaded file with its SHA-256 checksum and size. The caller imports the file and then calls
+// FinishUpload.
+func (s *ChunkedUploadService) CompleteUpload(id, userID uuid.UUID) (*models.UploadSession, string, string, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upload, err := s.lockUpload(tx, id, userID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if upload.Status != UploadUploading {
+		return nil, "", "", fmt.Errorf("%w: it is %s", ErrUploadNotOpen, strings.ToLower(upload.Status))
+	}
+	if upload.ReceivedBytes == 0 {
+		return nil, "", "", fmt.Errorf("%w: no chunks were received", ErrUploadIncomplete)
+	}
+	if upload.TotalSize != nil && upload.ReceivedBytes != *upload.TotalSize {
+		return nil, "", "", fmt.Errorf("%w: received %d of %d bytes", ErrUploadIncomplete, upload.ReceivedBytes, *upload.TotalSize)
+	}
+
+	path := s.filePath(upload)
+	checksum, err := fileSHA256(path, upload.ReceivedBytes)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if upload.Checksum != nil && *upload.Checksum != checksum {
+		return nil, "", "", ErrUploadChecksumMismatch
+	}
+
+	if _, err := tx.Exec(`UPDATE upload_sessions SET status = $1, updated_at = now() WHERE id = $2`, UploadCompleting, id); err != nil {
+		return nil, "", "", fmt.Errorf("failed to update upload: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, "", "", fmt.Errorf("failed to commit upload: %w", err)
+	}
+
+	upload.Status = UploadCompleting
+	return upload, path, checksum, nil
+}
+
+// FinishUpload ends a completing upload. With an import job the upload is completed and its file
+// removed; without one (the import was rejected or only validated) it is reopened so completion can
+// be retried without uploading again.
+func (s *ChunkedUploadService) FinishUpload(id uuid.UUID, importJobID *uuid.UUID) error {
+	if importJobID == nil {
+		_, err := s.db.Exec(`UPDATE upload_sessions SET status = $1, updated_at = now() WHERE id = $2 AND status = $3`,
+			UploadUploading, id, UploadCompleting)
+		if err != nil {
+			return fmt.Errorf("failed to reopen upload: %w", err)
+		}
+		return nil
+	}
+
+	var uploadFile string
+	err := s.db.Get(&uploadFile, `UPDATE upload_sessions SET status = $1, import_job_id = $2, updated_at = now()
+	                              WHERE id = $3
+	                              RETURNING upload_file`, UploadCompleted, importJobID, id)
+	if err != nil {
+		return fmt.Errorf("failed to complete upload: %w", err)
+	}
+	return removeUploadFile(uploadFile)
+}
+
+// AbortUpload abandons an upload and removes its partial file
+func (s *ChunkedUploadService) AbortUpload(id, userID uuid.UUID) error {
+	var uploadFile string
+	err := s.db.Get(&uploadFile, `UPDATE upload_sessions SET status = $1, updated_at = now()
+	                              WHERE id = $2 AND user_id = $3 AND status = $4
+	                              RETURNING upload_file`, UploadAborted, id, userID, UploadUploading)
+	if err == sql.ErrNoRows {
+		if _, getErr := s.GetUpload(id, userID); getErr != nil {
+			return getErr
+		}
+		return ErrUploadNotOpen
+	}
+	if err != nil {
+		return fmt.Errorf("failed to abort upload: %w", err)
+	}
+	return removeUploadFile(uploadFile)
+}
+
+// lockUpload locks an upload row for the rest of the transaction
+func (s *ChunkedUploadService) lockUpload(tx *sqlx.Tx, id, userID uuid.UUID) (*models.UploadSession, error) {
+	var upload models.UploadSession
+	err := tx.Get(&upload, `SELECT * FROM upload_sessions WHERE id = $1 AND user_id = $2 FOR UPDATE`, id, userID)
+	if err == sql.ErrNoRows {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	return &upload, nil
+}
+
+func (s *ChunkedUploadService) filePath(upload *models.UploadSession) string {
+	return filepath.Join(UploadDir(), filepath.Base(upload.UploadFile))
+}
+
+func removeUploadFile(uploadFile string) error {
+	path := filepath.Join(UploadDir(), filepath.Base(uploadFile))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove upload file: %w", err)
+	}
+	return nil
+}
+
+// fileSHA256 returns the SHA-256 checksum of the first size bytes of a file, the confirmed part of
+// an upload
+func fileSHA256(path string, size int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	n, err := io.Copy(hash, io.LimitReader(file, size))
+	if err != nil {
+		return "", fmt.Errorf("failed to checksum upload file: %w", err)
+	}
+	if n != size {
+		return "", fmt.Errorf("%w: the upload file is shorter than the confirmed chunks", ErrUploadIncomplete)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func maxChunkBytes() int64 {
+	return int64(config.Get().CSV.MaxChunkSizeMB) << 20
+}
+
+// maxUploadBytes returns limits.max_upload_size in bytes, or 0 when it is unset or unreadable
+func maxUploadBytes() int64 {
+	size, err := parseByteSize(config.Get().Limits.MaxUploadSize)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// parseByteSize parses sizes such as "500MB" or "2GB", in multiples of 1024, or a plain byte count
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	units := []struct {
+		suffix string
+		shift  uint
+	}{{"TB", 40}, {"GB", 30}, {"MB", 20}, {"KB", 10}, {"B", 0}}
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), 10, 64)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid size: %s", value)
+			}
+			return n << unit.shift, nil
+		}
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+		return n, nil
+	}
+	return 0, fmt.Errorf("invalid size: %s", value)
+}
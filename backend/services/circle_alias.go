@@ -0,0 +1,218 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// circleMapTTL is how long an instance uses the loaded circle normalization map before reloading it,
+// so edits made through another instance reach this one
+const circleMapTTL = time.Minute
+
+// maxUnmappedCircleLength is the longest unmapped circle value recorded; longer ones are not circles
+const maxUnmappedCircleLength = 255
+
+// Errors returned when managing the circle normalization map
+var (
+	ErrCircleAliasNotFound = errors.New("circle alias not found")
+	ErrInvalidCircleAlias  = errors.New("invalid circle alias")
+)
+
+// loadedCircleMap holds the circle normalization map until it expires or the circle_aliases cache is flushed
+var loadedCircleMap struct {
+	mu       sync.Mutex
+	circles  *utils.CircleMap // nil until loaded
+	loadedAt time.Time
+}
+
+// CircleAliasService manages the circle normalization map that imports apply to circle values and
+// circle searches expand to, and the report of circle values it does not know
+type CircleAliasService struct{}
+
+func NewCircleAliasService() *CircleAliasService {
+	return &CircleAliasService{}
+}
+
+// CircleMap returns the circle normalization map
+func (s *CircleAliasService) CircleMap() *utils.CircleMap {
+	return circleMap()
+}
+
+// ListAliases returns every alias of the circle normalization map, by circle
+func (s *CircleAliasService) ListAliases() ([]models.CircleAlias, error) {
+	aliases := []models.CircleAlias{}
+	if err := database.PostgresDB.Select(&aliases, `SELECT * FROM circle_aliases ORDER BY circle, alias`); err != nil {
+		return nil, fmt.Errorf("failed to list circle aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// SetAlias maps an alias to a circle, replacing the alias's previous circle. Aliases cannot be
+// chained: the circle cannot itself be an alias, nor the alias the circle of other aliases.
+func (s *CircleAliasService) SetAlias(req *models.CircleAliasRequest, updatedBy uuid.UUID) (*models.CircleAlias, error) {
+	alias, circle := utils.NormalizeCircleKey(req.Alias), utils.NormalizeCircleKey(req.Circle)
+	if alias == "" || circle == "" {
+		return nil, fmt.Errorf("%w: alias and circle are required", ErrInvalidCircleAlias)
+	}
+	if len(alias) > 100 || len(circle) > 100 {
+		return nil, fmt.Errorf("%w: alias and circle must be at most 100 characters", ErrInvalidCircleAlias)
+	}
+	if alias == circle {
+		return nil, fmt.Errorf("%w: a circle needs no alias for itself", ErrInvalidCircleAlias)
+	}
+
+	var conflict string
+	err := database.PostgresDB.Get(&conflict, `SELECT circle FROM circle_aliases WHERE alias = $1`, circle)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s is an alias of %s; map %s to %s instead", ErrInvalidCircleAlias, circle, conflict, alias, conflict)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check circle aliases: %w", err)
+	}
+	err = database.PostgresDB.Get(&conflict, `SELECT alias FROM circle_aliases WHERE circle = $1 LIMIT 1`, alias)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s is the circle of alias %s", ErrInvalidCircleAlias, alias, conflict)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to check circle aliases: %w", err)
+	}
+
+	var saved models.CircleAlias
+	query := `INSERT INTO circle_aliases (alias, circle, updated_by)
+	          VALUES ($1, $2, $3)
+	          ON CONFLICT (alias) DO UPDATE SET circle = EXCLUDED.circle, updated_by = EXCLUDED.updated_by, updated_at = now()
+	          RETURNING *`
+	if err := database.PostgresDB.Get(&saved, query, alias, circle, updatedBy); err != nil {
+		return nil, fmt.Errorf("failed to save circle alias: %w", err)
+	}
+	flushCircleMap()
+
+	utils.LogInfo(fmt.Sprintf("Circle alias %s mapped to %s by %s", alias, circle, updatedBy))
+	return &saved, nil
+}
+
+// DeleteAlias removes an alias from the circle normalization map
+func (s *CircleAliasService) DeleteAlias(alias string) error {
+	result, err := database.PostgresDB.Exec(`DELETE FROM circle_aliases WHERE alias = $1`, utils.NormalizeCircleKey(alias))
+	if err != nil {
+		return fmt.Errorf("failed to delete circle alias: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrCircleAliasNotFound
+	}
+	flushCircleMap()
+
+	utils.LogInfo("Circle alias deleted: " + alias)
+	return nil
+}
+
+// GetUnmapped returns the circle values imports found that the map still does not know, most frequent first
+func (s *CircleAliasService) GetUnmapped(params models.PageParams) (*models.Paginated[models.UnmappedCircle], error) {
+	const unmapped = `FROM unmapped_circles
+	                  WHERE value NOT IN (SELECT alias FROM circle_aliases)
+	                    AND value NOT IN (SELECT circle FROM circle_aliases)`
+
+	var total int
+	if err := database.PostgresDB.Get(&total, `SELECT COUNT(*) `+unmapped); err != nil {
+		return nil, fmt.Errorf("failed to count unmapped circles: %w", err)
+	}
+
+	values := []models.UnmappedCircle{}
+	query := `SELECT * ` + unmapped + ` ORDER BY occurrences DESC, value LIMIT $1 OFFSET $2`
+	if err := database.PostgresDB.Select(&values, query, params.Limit, params.Offset()); err != nil {
+		return nil, fmt.Errorf("failed to get unmapped circles: %w", err)
+	}
+	return models.NewPaginated(values, total, params), nil
+}
+
+// RecordUnmapped adds the unmapped circle values counted by an import to the report
+func (s *CircleAliasService) RecordUnmapped(jobID string, counts []models.FacetCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(counts))
+	occurrences := make([]int64, 0, len(counts))
+	for _, count := range counts {
+		if len(count.Value) > maxUnmappedCircleLength {
+			continue
+		}
+		values = append(values, count.Value)
+		occurrences = append(occurrences, int64(count.Count))
+	}
+
+	query := `INSERT INTO unmapped_circles (value, occurrences, last_import_job_id)
+	          SELECT value, occurrences, $3 FROM unnest($1::text[], $2::bigint[]) AS t(value, occurrences)
+	          ON CONFLICT (value) DO UPDATE SET
+	              occurrences = unmapped_circles.occurrences + EXCLUDED.occurrences,
+	              last_import_job_id = EXCLUDED.last_import_job_id,
+	              last_seen_at = now()`
+	if _, err := database.PostgresDB.Exec(query, pq.Array(values), pq.Array(occurrences), jobID); err != nil {
+		return fmt.Errorf("failed to record unmapped circles: %w", err)
+	}
+	return nil
+}
+
+// circleMap returns the circle normalization map, loading it when it is missing or expired. If it
+// cannot be loaded the previous map is kept, or an empty one used, so imports and searches go on
+// without normalization.
+func circleMap() *utils.CircleMap {
+	loadedCircleMap.mu.Lock()
+	defer loadedCircleMap.mu.Unlock()
+
+	if loadedCircleMap.circles != nil && time.Since(loadedCircleMap.loadedAt) < circleMapTTL {
+		return loadedCircleMap.circles
+	}
+	if database.PostgresDB == nil {
+		return utils.NewCircleMap(nil)
+	}
+
+	var aliases []models.CircleAlias
+	if err := database.PostgresDB.Select(&aliases, `SELECT alias, circle FROM circle_aliases`); err != nil {
+		utils.LogError("Failed to load circle aliases", err)
+		if loadedCircleMap.circles == nil {
+			return utils.NewCircleMap(nil)
+		}
+		loadedCircleMap.loadedAt = time.Now() // Keep the stale map for another TTL rather than retry on every use
+		return loadedCircleMap.circles
+	}
+
+	byAlias := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		byAlias[alias.Alias] = alias.Circle
+	}
+	loadedCircleMap.circles = utils.NewCircleMap(byAlias)
+	loadedCircleMap.loadedAt = time.Now()
+	return loadedCircleMap.circles
+}
+
+// flushCircleMap drops the loaded circle normalization map so the next use reloads it, returning
+// how many aliases it had
+func flushCircleMap() int {
+	loadedCircleMap.mu.Lock()
+	defer loadedCircleMap.mu.Unlock()
+
+	entries := loadedCircleMap.circles.Len()
+	loadedCircleMap.circles = nil
+	return entries
+}
+
+// circleMapStats returns the number of loaded circle aliases and an estimate of their size in bytes
+func circleMapStats() (entries, approxBytes int) {
+	loadedCircleMap.mu.Lock()
+	defer loadedCircleMap.mu.Unlock()
+
+	// An alias and circle of about 12 bytes each, with their string headers, in both directions
+	entries = loadedCircleMap.circles.Len()
+	return entries, entries * 2 * (12 + 16) * 2
+}
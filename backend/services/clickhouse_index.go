@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+type ClickHouseIndexService struct{}
+
+func NewClickHouseIndexService() *ClickHouseIndexService {
+	return &ClickHouseIndexService{}
+}
+
+// GetIndexes lists the data skipping indexes on the people table along with their materialization state
+func (s *ClickHouseIndexService) GetIndexes() ([]models.ClickHouseIndex, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var indexes []models.ClickHouseIndex
+	query := `SELECT name, type, expr, granularity, data_compressed_bytes, data_uncompressed_bytes, marks
+	          FROM system.data_skipping_indices
+	          WHERE database = ? AND table = ?
+	          ORDER BY name`
+	if err := database.ClickHouseDB.Select(ctx, &indexes, query, database.PeopleDatabase, database.PeopleTableName()); err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	mutations, err := s.getIndexMutations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var totalRows uint64
+	err = database.ClickHouseDB.QueryRow(ctx,
+		`SELECT sum(rows) FROM system.parts WHERE database = ? AND table = ? AND active`,
+		database.PeopleDatabase, database.PeopleTableName()).Scan(&totalRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count table rows: %w", err)
+	}
+
+	for i := range indexes {
+		index := &indexes[i]
+		if mutation, ok := mutations[index.Name]; ok {
+			index.Mutation = mutation
+		}
+
+		switch {
+		case index.Mutation != nil && index.Mutation.IsDone == 0:
+			index.State = "materializing"
+		case index.Marks > 0 || totalRows == 0:
+			index.State = "materialized"
+		default:
+			index.State = "not_materialized"
+		}
+	}
+
+	return indexes, nil
+}
+
+// MaterializeIndexes starts MATERIALIZE INDEX mutations so existing data is indexed.
+// Mutations run asynchronously; progress is reported by GetIndexes.
+func (s *ClickHouseIndexService) MaterializeIndexes(names []string) ([]models.ClickHouseIndex, error) {
+	indexes, err := s.GetIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	defined := make(map[string]bool, len(indexes))
+	for _, index := range indexes {
+		defined[index.Name] = true
+	}
+
+	if len(names) == 0 {
+		for _, index := range indexes {
+			names = append(names, index.Name)
+		}
+	}
+
+	// Index names are validated against the table definition since they cannot be bound as parameters
+	for _, name := range names {
+		if !defined[name] {
+			return nil, fmt.Errorf("index not found: %s", name)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, name := range names {
+		query := fmt.Sprintf("ALTER TABLE %s MATERIALIZE INDEX %s", database.PeopleTable(), name)
+		if err := database.ClickHouseDB.Exec(ctx, query); err != nil {
+			return nil, fmt.Errorf("failed to materialize index %s: %w", name, err)
+		}
+		utils.LogInfo("Started materialization of index: " + name)
+	}
+
+	return s.GetIndexes()
+}
+
+// getIndexMutations returns the most recent MATERIALIZE INDEX mutation for each index
+func (s *ClickHouseIndexService) getIndexMutations(ctx context.Context) (map[string]*models.IndexMutation, error) {
+	var mutations []models.IndexMutation
+	query := `SELECT mutation_id, command, create_time, parts_to_do, is_done, latest_fail_reason
+	          FROM system.mutations
+	          WHERE database = ? AND table = ? AND command LIKE 'MATERIALIZE INDEX%'
+	          ORDER BY create_time DESC`
+	if err := database.ClickHouseDB.Select(ctx, &mutations, query, database.PeopleDatabase, database.PeopleTableName()); err != nil {
+		return nil, fmt.Errorf("failed to get index mutations: %w", err)
+	}
+
+	latest := make(map[string]*models.IndexMutation)
+	for i := range mutations {
+		fields := strings.Fields(mutations[i].Command)
+		if len(fields) < 3 {
+			continue
+		}
+		name := fields[2]
+		if _, ok := latest[name]; !ok {
+			latest[name] = &mutations[i]
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+)
+
+// ClickHouse warning kinds
+const (
+	ClickHouseWarningParts           = "parts"
+	ClickHouseWarningReplicaLag      = "replica_lag"
+	ClickHouseWarningReplicaReadonly = "replica_readonly"
+	ClickHouseWarningDiskSpace       = "disk_space"
+)
+
+type ClickHouseSystemService struct{}
+
+func NewClickHouseSystemService() *ClickHouseSystemService {
+	return &ClickHouseSystemService{}
+}
+
+// GetStatus reports table sizes, part counts, merges in progress, replica lag and disk space of the
+// people database from the ClickHouse system tables, with warnings for whatever crosses the
+// configured thresholds: too many parts call for OPTIMIZE or fewer, larger inserts, while lagging
+// replicas and full disks call for scaling.
+func (s *ClickHouseSystemService) GetStatus(ctx context.Context) (*models.ClickHouseSystemStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	status := &models.ClickHouseSystemStatus{
+		Database: database.PeopleDatabase,
+		Tables:   []models.ClickHouseTableStorage{},
+		Merges:   []models.ClickHouseMerge{},
+		Replicas: []models.ClickHouseReplica{},
+		Disks:    []models.ClickHouseDisk{},
+		Warnings: []models.ClickHouseWarning{},
+	}
+
+	tablesQuery := `SELECT table,
+	                       sum(rows) AS rows,
+	                       sum(parts) AS parts,
+	                       count() AS partitions,
+	                       max(parts) AS max_partition_parts,
+	                       sum(bytes_on_disk) AS bytes_on_disk,
+	                       sum(compressed_bytes) AS compressed_bytes,
+	                       sum(uncompressed_bytes) AS uncompressed_bytes
+	                FROM (
+	                    SELECT table, partition,
+	                           toUInt64(count()) AS parts,
+	                           toUInt64(sum(rows)) AS rows,
+	                           toUInt64(sum(bytes_on_disk)) AS bytes_on_disk,
+	                           toUInt64(sum(data_compressed_bytes)) AS compressed_bytes,
+	                           toUInt64(sum(data_uncompressed_bytes)) AS uncompressed_bytes
+	                    FROM system.parts
+	                    WHERE database = ? AND active
+	                    GROUP BY table, partition
+	                )
+	                GROUP BY table
+	                ORDER BY bytes_on_disk DESC`
+	if err := database.ClickHouseDB.Select(ctx, &status.Tables, tablesQuery, database.PeopleDatabase); err != nil {
+		return nil, fmt.Errorf("failed to get table storage: %w", err)
+	}
+	for i := range status.Tables {
+		table := &status.Tables[i]
+		if table.CompressedBytes > 0 {
+			table.CompressionRatio = float64(table.UncompressedBytes) / float64(table.CompressedBytes)
+		}
+	}
+
+	mergesQuery := `SELECT table, result_part_name, elapsed, progress,
+	                       toUInt64(num_parts) AS num_parts,
+	                       toUInt64(total_size_bytes_compressed) AS total_size_bytes_compressed,
+	                       toUInt64(rows_read) AS rows_read,
+	                       toUInt8(is_mutation) AS is_mutation
+	                FROM system.merges
+	                WHERE database = ?
+	                ORDER BY elapsed DESC`
+	if err := database.ClickHouseDB.Select(ctx, &status.Merges, mergesQuery, database.PeopleDatabase); err != nil {
+		return nil, fmt.Errorf("failed to get merges: %w", err)
+	}
+
+	replicasQuery := `SELECT table,
+	                         toUInt8(is_readonly) AS is_readonly,
+	                         toUInt8(is_session_expired) AS is_session_expired,
+	                         toUInt64(absolute_delay) AS absolute_delay,
+	                         toUInt64(queue_size) AS queue_size,
+	                         toUInt64(active_replicas) AS active_replicas,
+	                         toUInt64(total_replicas) AS total_replicas
+	                  FROM system.replicas
+	                  WHERE database = ?
+	                  ORDER BY table`
+	if err := database.ClickHouseDB.Select(ctx, &status.Replicas, replicasQuery, database.PeopleDatabase); err != nil {
+		return nil, fmt.Errorf("failed to get replicas: %w", err)
+	}
+
+	disksQuery := `SELECT name, path, toUInt64(free_space) AS free_space, toUInt64(total_space) AS total_space
+	               FROM system.disks
+	               ORDER BY name`
+	if err := database.ClickHouseDB.Select(ctx, &status.Disks, disksQuery); err != nil {
+		return nil, fmt.Errorf("failed to get disks: %w", err)
+	}
+
+	status.Warnings = append(status.Warnings, s.warnings(status)...)
+	return status, nil
+}
+
+// warnings checks the status against the configured thresholds
+func (s *ClickHouseSystemService) warnings(status *models.ClickHouseSystemStatus) []models.ClickHouseWarning {
+	cfg := config.Get().Database.ClickHouse
+	var warnings []models.ClickHouseWarning
+
+	for _, table := range status.Tables {
+		if table.MaxPartitionParts > uint64(cfg.PartsWarningPerPartition) {
+			warnings = append(warnings, models.ClickHouseWarning{
+				Kind:   ClickHouseWarningParts,
+				Target: table.Table,
+				Message: fmt.Sprintf("A partition has %d active parts (threshold %d); run OPTIMIZE or batch inserts into fewer, larger ones",
+					table.MaxPartitionParts, cfg.PartsWarningPerPartition),
+			})
+		}
+		if table.Parts > uint64(cfg.PartsWarningPerTable) {
+			warnings = append(warnings, models.ClickHouseWarning{
+				Kind:   ClickHouseWarningParts,
+				Target: table.Table,
+				Message: fmt.Sprintf("The table has %d active parts (threshold %d); run OPTIMIZE or coarsen the partitioning",
+					table.Parts, cfg.PartsWarningPerTable),
+			})
+		}
+	}
+
+	for _, replica := range status.Replicas {
+		if replica.IsReadonly || replica.IsSessionExpired {
+			warnings = append(warnings, models.ClickHouseWarning{
+				Kind:    ClickHouseWarningReplicaReadonly,
+				Target:  replica.Table,
+				Message: "The replica is read-only or has lost its ZooKeeper/Keeper session",
+			})
+		}
+		if lag := time.Duration(replica.AbsoluteDelaySeconds) * time.Second; lag > cfg.ReplicaLagWarning {
+			warnings = append(warnings, models.ClickHouseWarning{
+				Kind:   ClickHouseWarningReplicaLag,
+				Target: replica.Table,
+				Message: fmt.Sprintf("The replica is %s behind (threshold %s) with %d queued entries",
+					lag, cfg.ReplicaLagWarning, replica.QueueSize),
+			})
+		}
+	}
+
+	for _, disk := range status.Disks {
+		if disk.TotalBytes == 0 {
+			continue
+		}
+		freePercent := float64(disk.FreeBytes) * 100 / float64(disk.TotalBytes)
+		if freePercent < float64(cfg.DiskFreeWarningPercent) {
+			warnings = append(warnings, models.ClickHouseWarning{
+				Kind:   ClickHouseWarningDiskSpace,
+				Target: disk.Name,
+				Message: fmt.Sprintf("%.1f%% of the disk is free (threshold %d%%); merges need free space to run",
+					freePercent, cfg.DiskFreeWarningPercent),
+			})
+		}
+	}
+
+	return warnings
+}
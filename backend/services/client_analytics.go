@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// ClientIDHeader identifies the calling application, e.g. web-ui, ops-scripts or a partner name
+const ClientIDHeader = "X-Client-Id"
+
+// UnknownClientID is recorded for requests without a usable X-Client-Id header
+const UnknownClientID = "unknown"
+
+// clientStatsFlushInterval is how often the in-memory request counters are written to PostgreSQL
+const clientStatsFlushInterval = time.Minute
+
+var clientIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type clientStatsKey struct {
+	clientID string
+	date     string
+}
+
+type clientStats struct {
+	requests   int64
+	errors     int64
+	durationMs int64
+	lastSeen   time.Time
+}
+
+// pendingClientStats accumulates request counters between flushes so requests never wait on the database
+var pendingClientStats = struct {
+	sync.Mutex
+	stats map[clientStatsKey]*clientStats
+}{stats: make(map[clientStatsKey]*clientStats)}
+
+type ClientAnalyticsService struct{}
+
+func NewClientAnalyticsService() *ClientAnalyticsService {
+	return &ClientAnalyticsService{}
+}
+
+// NormalizeClientID returns the client ID from an X-Client-Id header value. Missing or malformed
+// values are recorded as "unknown" so a client cannot flood the analytics with arbitrary keys.
+func NormalizeClientID(raw string) string {
+	clientID := strings.ToLower(strings.TrimSpace(raw))
+	if !clientIDPattern.MatchString(clientID) {
+		return UnknownClientID
+	}
+	return clientID
+}
+
+// RecordRequest counts one API request by a client; 5xx responses are counted as errors
+func (s *ClientAnalyticsService) RecordRequest(clientID string, status int, duration time.Duration) {
+	now := time.Now()
+	key := clientStatsKey{clientID: clientID, date: CurrentQuotaDate()}
+
+	pendingClientStats.Lock()
+	defer pendingClientStats.Unlock()
+
+	stats, ok := pendingClientStats.stats[key]
+	if !ok {
+		stats = &clientStats{}
+		pendingClientStats.stats[key] = stats
+	}
+	stats.requests++
+	if status >= 500 {
+		stats.errors++
+	}
+	stats.durationMs += duration.Milliseconds()
+	stats.lastSeen = now
+}
+
+// StartFlusher periodically writes the accumulated request counters to PostgreSQL
+func (s *ClientAnalyticsService) StartFlusher() {
+	go func() {
+		ticker := time.NewTicker(clientStatsFlushInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.Flush()
+		}
+	}()
+}
+
+// Flush writes the accumulated request counters to PostgreSQL. Counters that fail to save are kept
+// for the next flush.
+func (s *ClientAnalyticsService) Flush() {
+	pendingClientStats.Lock()
+	pending := pendingClientStats.stats
+	pendingClientStats.stats = make(map[clientStatsKey]*clientStats)
+	pendingClientStats.Unlock()
+
+	query := `INSERT INTO client_request_stats (client_id, date, request_count, error_count, total_duration_ms, last_seen_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (client_id, date) DO UPDATE SET
+	              request_count = client_request_stats.request_count + EXCLUDED.request_count,
+	              error_count = client_request_stats.error_count + EXCLUDED.error_count,
+	              total_duration_ms = client_request_stats.total_duration_ms + EXCLUDED.total_duration_ms,
+	              last_seen_at = GREATEST(client_request_stats.last_seen_at, EXCLUDED.last_seen_at)`
+
+	for key, stats := range pending {
+		_, err := database.PostgresDB.Exec(query, key.clientID, key.date, stats.requests, stats.errors, stats.durationMs, stats.lastSeen)
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Failed to save request stats for client %s", key.clientID), err)
+			s.requeue(key, stats)
+		}
+	}
+}
+
+// requeue merges counters that failed to save back into the pending counters
+func (s *ClientAnalyticsService) requeue(key clientStatsKey, failed *clientStats) {
+	pendingClientStats.Lock()
+	defer pendingClientStats.Unlock()
+
+	stats, ok := pendingClientStats.stats[key]
+	if !ok {
+		pendingClientStats.stats[key] = failed
+		return
+	}
+	stats.requests += failed.requests
+	stats.errors += failed.errors
+	stats.durationMs += failed.durationMs
+	if failed.lastSeen.After(stats.lastSeen) {
+		stats.lastSeen = failed.lastSeen
+	}
+}
+
+// GetClientAnalytics returns request and search usage per client application over the last days
+func (s *ClientAnalyticsService) GetClientAnalytics(days int) (*models.ClientAnalyticsResponse, error) {
+	since := time.Now().In(QuotaLocation()).AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+
+	query := `
+	WITH requests AS (
+		SELECT client_id,
+		       SUM(request_count) AS requests,
+		       SUM(error_count) AS errors,
+		       SUM(total_duration_ms) AS total_duration_ms,
+		       MAX(last_seen_at) AS last_seen_at
+		FROM client_request_stats
+		WHERE date >= $1
+		GROUP BY client_id
+	), client_searches AS (
+		SELECT COALESCE(client_id, 'unknown') AS client_id,
+		       COUNT(*) AS searches,
+		       COUNT(DISTINCT user_id) AS unique_users
+		FROM searches
+		WHERE search_time >= $1::date
+		GROUP BY COALESCE(client_id, 'unknown')
+	)
+	SELECT
+		COALESCE(r.client_id, cs.client_id) AS client_id,
+		COALESCE(r.requests, 0) AS requests,
+		COALESCE(r.errors, 0) AS errors,
+		CASE WHEN r.requests > 0 THEN r.total_duration_ms::float8 / r.requests ELSE 0 END AS avg_duration_ms,
+		COALESCE(cs.searches, 0) AS searches,
+		COALESCE(cs.unique_users, 0) AS unique_users,
+		r.last_seen_at
+	FROM requests r
+	FULL OUTER JOIN client_searches cs ON r.client_id = cs.client_id
+	ORDER BY requests DESC, searches DESC`
+
+	clients := []models.ClientAnalytics{}
+	if err := database.PostgresDB.Select(&clients, query, since); err != nil {
+		return nil, fmt.Errorf("failed to get client analytics: %w", err)
+	}
+
+	return &models.ClientAnalyticsResponse{
+		Days:    days,
+		Since:   since,
+		Clients: clients,
+	}, nil
+}
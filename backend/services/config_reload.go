@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// configWatchInterval is how often config.yaml is checked for changes
+const configWatchInterval = 10 * time.Second
+
+// ErrConfigInvalid means a reloaded configuration failed validation and was not applied
+var ErrConfigInvalid = errors.New("configuration is invalid")
+
+var registerReloadHooks sync.Once
+
+type ConfigReloadService struct{}
+
+func NewConfigReloadService() *ConfigReloadService {
+	return &ConfigReloadService{}
+}
+
+// Reload reads config.yaml and the environment again and swaps the new configuration in. Requests
+// already running finish with the configuration they started with.
+func (s *ConfigReloadService) Reload() (*models.ConfigReloadResponse, error) {
+	registerReloadHooks.Do(addReloadHooks)
+
+	restartRequired, err := config.Reload()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConfigInvalid, err)
+	}
+	if len(restartRequired) > 0 {
+		utils.LogWarning(fmt.Sprintf("Configuration reloaded; changes to %s take effect after a restart",
+			strings.Join(restartRequired, ", ")))
+	} else {
+		utils.LogInfo("Configuration reloaded")
+	}
+	return &models.ConfigReloadResponse{
+		ReloadedAt:      time.Now(),
+		RestartRequired: restartRequired,
+		Warnings:        config.Get().Problems(),
+	}, nil
+}
+
+// StartWatcher reloads the configuration on SIGHUP and whenever config.yaml changes
+func (s *ConfigReloadService) StartWatcher() {
+	registerReloadHooks.Do(addReloadHooks)
+	utils.LogInfo(fmt.Sprintf("Watching %s for changes and reloading on SIGHUP", config.FilePath))
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		modTime := configModTime()
+		ticker := time.NewTicker(configWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hangup:
+				utils.LogInfo("SIGHUP received, reloading configuration")
+			case <-ticker.C:
+				latest := configModTime()
+				if latest.Equal(modTime) {
+					continue
+				}
+				modTime = latest
+				utils.LogInfo(config.FilePath + " changed, reloading configuration")
+			}
+			if _, err := s.Reload(); err != nil {
+				utils.LogError("Configuration reload failed, keeping the current configuration", err)
+			}
+		}
+	}()
+}
+
+// configModTime returns when config.yaml last changed, or the zero time if it cannot be read
+func configModTime() time.Time {
+	info, err := os.Stat(config.FilePath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// addReloadHooks drops state derived from settings that changed in a reload
+func addReloadHooks() {
+	config.OnReload(func(previous, next *config.Config) {
+		if previous != nil && previous.Search.PincodeGeoFile != next.Search.PincodeGeoFile {
+			flushPincodeLocations()
+		}
+		if previous != nil && previous.GeoIP != next.GeoIP {
+			if err := utils.InitGeoIP(next.GeoIP); err != nil {
+				utils.LogError("Failed to reload GeoIP databases, keeping the current ones", err)
+			}
+		}
+	})
+}
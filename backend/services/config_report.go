@@ -0,0 +1,171 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+)
+
+const redacted = "[REDACTED]"
+
+type ConfigReportService struct{}
+
+func NewConfigReportService() *ConfigReportService {
+	return &ConfigReportService{}
+}
+
+// GetEffectiveConfig returns the configuration this instance is running with, with secrets redacted
+func (s *ConfigReportService) GetEffectiveConfig() *models.EffectiveConfig {
+	cfg := config.Get()
+
+	formats := make([]string, 0, len(exportFormats))
+	for format := range exportFormats {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return &models.EffectiveConfig{
+		Server: models.ServerSettings{
+			Host:           cfg.Server.Host,
+			Port:           cfg.Server.Port,
+			Timeout:        cfg.Server.Timeout.String(),
+			CORSOrigins:    cfg.Server.CORSOrigins,
+			TrustedProxies: cfg.Server.TrustedProxies,
+		},
+		Postgres: models.PostgresSettings{
+			Host:            cfg.Database.Postgres.Host,
+			Port:            cfg.Database.Postgres.Port,
+			User:            cfg.Database.Postgres.User,
+			Password:        redactSecret(cfg.Database.Postgres.Password),
+			DBName:          cfg.Database.Postgres.DBName,
+			SSLMode:         cfg.Database.Postgres.SSLMode,
+			MaxOpenConns:    cfg.Database.Postgres.MaxOpenConns,
+			MaxIdleConns:    cfg.Database.Postgres.MaxIdleConns,
+			ConnMaxLifetime: cfg.Database.Postgres.ConnMaxLifetime.String(),
+			ConnMaxIdleTime: cfg.Database.Postgres.ConnMaxIdleTime.String(),
+		},
+		ClickHouse: models.ClickHouseSettings{
+			Host:                    cfg.Database.ClickHouse.Host,
+			Port:                    cfg.Database.ClickHouse.Port,
+			HTTPPort:                cfg.Database.ClickHouse.HTTPPort,
+			User:                    cfg.Database.ClickHouse.User,
+			Password:                redactSecret(cfg.Database.ClickHouse.Password),
+			Database:                cfg.Database.ClickHouse.Database,
+			MaxOpenConns:            cfg.Database.ClickHouse.MaxOpenConns,
+			MaxIdleConns:            cfg.Database.ClickHouse.MaxIdleConns,
+			ConnMaxLifetime:         cfg.Database.ClickHouse.ConnMaxLifetime.String(),
+			DialTimeout:             cfg.Database.ClickHouse.DialTimeout.String(),
+			ReadTimeout:             cfg.Database.ClickHouse.ReadTimeout.String(),
+			ConnectRetries:          cfg.Database.ClickHouse.ConnectRetries,
+			ConnectBackoff:          cfg.Database.ClickHouse.ConnectBackoff.String(),
+			CircuitBreakerThreshold: cfg.Database.ClickHouse.CircuitBreakerThreshold,
+			CircuitBreakerCooldown:  cfg.Database.ClickHouse.CircuitBreakerCooldown.String(),
+			PeopleTable:             cfg.Database.ClickHouse.PeopleTable,
+			ActivePeopleTable:       database.PeopleTableName(),
+		},
+		Auth: models.AuthSettings{
+			JWTSecret:    redactSecret(cfg.JWT.Secret),
+			JWTExpiry:    cfg.JWT.Expiry.String(),
+			SessionCache: cfg.Cache.AuthTTL.String(),
+			SSOProviders: ssoProviderNames(cfg.OIDC),
+		},
+		Limits: models.LimitSettings{
+			MaxSearchesPerDay: cfg.Limits.MaxSearchesPerDay,
+			MaxExportsPerDay:  cfg.Limits.MaxExportsPerDay,
+			MaxRowsPerSearch:  cfg.Limits.MaxRowsPerSearch,
+			MaxExportRows:     cfg.Limits.MaxExportRows,
+			MaxUploadSize:     cfg.Limits.MaxUploadSize,
+
+			MaxExportRowsByUserType: cfg.Limits.MaxExportRowsByUserType,
+			SearchBudgets:           searchBudgetSettings(cfg.Search.Budgets),
+		},
+		Import: models.ImportSettings{
+			BatchSize:    cfg.CSV.BatchSize,
+			MaxRetries:   cfg.CSV.MaxRetries,
+			RetryBackoff: cfg.CSV.RetryBackoff.String(),
+		},
+		Export: models.ExportSettings{
+			FastPathThreshold: cfg.Export.FastPathThreshold,
+			Expiry:            cfg.Export.Expiry.String(),
+			Formats:           formats,
+			RowLimitAction:    cfg.Export.RowLimitAction,
+		},
+		Scheduler: models.SchedulerSettings{
+			QuotaResetTimezone: QuotaLocation().String(),
+			QuotaResetTime:     cfg.Quota.ResetTime,
+			NextQuotaReset:     NextQuotaReset().Format(time.RFC3339),
+			UsageCleanup:       "Nightly 03:00 by the daily_usage retention policy",
+			JobWorkers:         cfg.Jobs.Workers,
+			JobPollInterval:    cfg.Jobs.PollInterval.String(),
+			JobMaxAttempts:     cfg.Jobs.MaxAttempts,
+			JobRetryBackoff:    cfg.Jobs.RetryBackoff.String(),
+			JobStaleAfter:      cfg.Jobs.StaleAfter.String(),
+		},
+		Storage: models.StorageSettings{
+			SearchBackend: cfg.Search.Backend,
+			ExportBackend: cfg.Export.Storage,
+			ExportDir:     cfg.Export.Dir,
+			ExportBucket:  cfg.Export.S3Bucket,
+			ImportTempDir: cfg.CSV.TempDir,
+		},
+		Notifications: models.NotificationSettings{
+			Provider:       cfg.Notifications.Provider,
+			From:           cfg.Notifications.From,
+			SMTPHost:       cfg.Notifications.SMTP.Host,
+			SMTPPort:       cfg.Notifications.SMTP.Port,
+			SMTPUsername:   cfg.Notifications.SMTP.Username,
+			SMTPPassword:   redactSecret(cfg.Notifications.SMTP.Password),
+			QuotaThreshold: cfg.Notifications.QuotaThreshold,
+			Events:         notificationEvents(),
+		},
+		Features: map[string]bool{
+			"session_cache": cfg.Cache.AuthTTL > 0,
+			"notifications": cfg.Notifications.Enabled,
+			"grpc":          cfg.GRPC.Enabled,
+		},
+		Warnings: cfg.Problems(),
+	}
+}
+
+// ssoProviderNames lists the configured OIDC providers
+func ssoProviderNames(cfg config.OIDCConfig) []string {
+	names := make([]string, 0, len(cfg.Providers))
+	for _, provider := range cfg.Providers {
+		names = append(names, provider.Name)
+	}
+	return names
+}
+
+// notificationEvents reports whether emails are sent for each notification event
+func notificationEvents() map[string]bool {
+	notificationService := NewNotificationService()
+	events := make(map[string]bool, len(notificationTemplates))
+	for event := range notificationTemplates {
+		events[event] = notificationService.IsEnabled(event)
+	}
+	return events
+}
+
+// redactSecret hides a configured secret while still showing whether one is set
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+// searchBudgetSettings reports the configured search budgets
+func searchBudgetSettings(budgets map[string]config.QueryBudgetConfig) map[string]models.SearchBudgetSettings {
+	settings := make(map[string]models.SearchBudgetSettings, len(budgets))
+	for userType, budget := range budgets {
+		settings[userType] = models.SearchBudgetSettings{
+			Timeout:        budget.Timeout.String(),
+			MaxRowsToRead:  budget.MaxRowsToRead,
+			MaxResultBytes: budget.MaxResultBytes,
+		}
+	}
+	return settings
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+)
+
+type DashboardService struct{}
+
+func NewDashboardService() *DashboardService {
+	return &DashboardService{}
+}
+
+// GetDashboard gathers the admin home screen figures. Postgres figures are required; database health
+// and the people table size are reported as they are, so the dashboard still loads while ClickHouse
+// is down.
+func (s *DashboardService) GetDashboard(ctx context.Context) (*models.AdminDashboardResponse, error) {
+	today := CurrentQuotaDate()
+	todayDate, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse quota date: %w", err)
+	}
+	yesterday := todayDate.AddDate(0, 0, -1).Format("2006-01-02")
+
+	dashboard := &models.AdminDashboardResponse{
+		QuotaDate:   today,
+		GeneratedAt: time.Now(),
+	}
+
+	err = database.PostgresDB.Get(&dashboard.Users, `
+		SELECT COUNT(*) AS total,
+		       COUNT(*) FILTER (WHERE is_active AND (expires_at IS NULL OR expires_at > now())) AS active
+		FROM users`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	var usage []struct {
+		Date     string `db:"date"`
+		Searches int    `db:"searches"`
+		Exports  int    `db:"exports"`
+	}
+	err = database.PostgresDB.Select(&usage, `
+		SELECT to_char(date, 'YYYY-MM-DD') AS date,
+		       COALESCE(SUM(search_count), 0) AS searches,
+		       COALESCE(SUM(export_count), 0) AS exports
+		FROM daily_usage
+		WHERE date IN ($1, $2)
+		GROUP BY date`, today, yesterday)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+	for _, day := range usage {
+		switch day.Date {
+		case today:
+			dashboard.Searches.Today, dashboard.Exports.Today = day.Searches, day.Exports
+		case yesterday:
+			dashboard.Searches.Yesterday, dashboard.Exports.Yesterday = day.Searches, day.Exports
+		}
+	}
+
+	err = database.PostgresDB.Get(&dashboard.Pending.Registrations,
+		`SELECT COUNT(*) FROM user_registration_requests WHERE status = 'PENDING'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count registration requests: %w", err)
+	}
+	err = database.PostgresDB.Get(&dashboard.Pending.PasswordChanges,
+		`SELECT COUNT(*) FROM user_password_change_requests WHERE status = 'PENDING'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count password change requests: %w", err)
+	}
+
+	err = database.PostgresDB.Get(&dashboard.ActiveSessions, `
+		SELECT COUNT(*) FROM user_sessions
+		WHERE is_active = true AND expires_at > now() AND logged_out_at IS NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active sessions: %w", err)
+	}
+
+	var lastImport models.CSVImportAudit
+	err = database.PostgresDB.Get(&lastImport, `SELECT * FROM csv_import_audit ORDER BY started_at DESC LIMIT 1`)
+	switch {
+	case err == nil:
+		dashboard.LastImport = &lastImport
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to get last import: %w", err)
+	}
+
+	dashboard.Health = s.checkHealth()
+	dashboard.PeopleTable = s.peopleTableSize(ctx)
+
+	return dashboard, nil
+}
+
+// checkHealth pings both databases, timing each
+func (s *DashboardService) checkHealth() models.DashboardHealth {
+	return models.DashboardHealth{
+		PostgreSQL:            componentHealth(database.PostgresHealthCheck),
+		ClickHouse:            componentHealth(database.ClickHouseHealthCheck),
+		ClickHouseCircuitOpen: database.ClickHouseCircuitOpen(),
+	}
+}
+
+// componentHealth runs a health check, recording how long it took and why it failed
+func componentHealth(check func() error) models.DashboardComponentHealth {
+	start := time.Now()
+	err := check()
+	health := models.DashboardComponentHealth{
+		Healthy:   err == nil,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		health.Error = err.Error()
+	}
+	return health
+}
+
+// peopleTableSize reads the row count and on-disk size of the active people table from its parts
+func (s *DashboardService) peopleTableSize(ctx context.Context) models.DashboardPeopleTable {
+	size := models.DashboardPeopleTable{Table: database.PeopleTableName()}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := database.ClickHouseDB.QueryRow(ctx,
+		`SELECT sum(rows), sum(bytes_on_disk) FROM system.parts WHERE database = ? AND table = ? AND active`,
+		database.PeopleDatabase, size.Table).Scan(&size.Rows, &size.BytesOnDisk)
+	if err != nil {
+		size.Error = fmt.Sprintf("failed to get table size: %v", err)
+	}
+	return size
+}
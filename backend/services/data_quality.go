@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// maxValidatedRecords caps the records checked by a single validation request
+const maxValidatedRecords = 10000
+
+// DataQualityService flags people rows whose fields contradict each other, e.g. a pincode outside
+// the circle's states, an alternate number equal to the mobile or a malformed email
+type DataQualityService struct{}
+
+func NewDataQualityService() *DataQualityService {
+	return &DataQualityService{}
+}
+
+// ValidateRecords runs the import's consistency checks on records without storing them
+func (s *DataQualityService) ValidateRecords(records []models.Person) (*models.ValidateRecordsResponse, error) {
+	if len(records) == 0 || len(records) > maxValidatedRecords {
+		return nil, fmt.Errorf("records must contain between 1 and %d entries", maxValidatedRecords)
+	}
+
+	response := &models.ValidateRecordsResponse{
+		Total:   len(records),
+		Results: make([]models.RecordValidation, 0, len(records)),
+	}
+	for i := range records {
+		flags := utils.CheckConsistency(&records[i])
+		if len(flags) > 0 {
+			response.Flagged++
+		}
+		response.Results = append(response.Results, models.RecordValidation{
+			Index:        i,
+			Mobile:       records[i].Mobile,
+			QualityFlags: flags,
+		})
+	}
+	return response, nil
+}
+
+// Revalidate starts a mutation recomputing the quality flags of every row in the active people
+// table, e.g. after the column is added or the checks change. Progress shows in system.mutations.
+func (s *DataQualityService) Revalidate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query := fmt.Sprintf("ALTER TABLE %s UPDATE quality_flags = %s WHERE 1", database.PeopleTable(), utils.QualityFlagsSQL())
+	if err := database.ClickHouseDB.Exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to start quality revalidation: %w", err)
+	}
+
+	utils.LogInfo("Started revalidating quality flags on " + database.PeopleTable())
+	return nil
+}
+
+// GetSummary counts the flagged rows in the active people table, per flag
+func (s *DataQualityService) GetSummary() (*models.QualitySummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	summary := &models.QualitySummary{Table: database.PeopleTable()}
+	query := "SELECT count(), countIf(notEmpty(quality_flags)) FROM " + database.PeopleTable()
+	if err := database.ClickHouseDB.QueryRow(ctx, query).Scan(&summary.TotalRows, &summary.FlaggedRows); err != nil {
+		return nil, fmt.Errorf("failed to count flagged rows: %w", err)
+	}
+
+	summary.Flags = []models.QualityFlagCount{}
+	query = `SELECT toString(arrayJoin(quality_flags)) AS flag, count() AS count
+	         FROM ` + database.PeopleTable() + `
+	         WHERE notEmpty(quality_flags)
+	         GROUP BY flag
+	         ORDER BY count DESC`
+	if err := database.ClickHouseDB.Select(ctx, &summary.Flags, query); err != nil {
+		return nil, fmt.Errorf("failed to count quality flags: %w", err)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDatasetNotFound       = errors.New("dataset not found")
+	ErrDatasetAccessDenied   = errors.New("you do not have access to this dataset")
+	ErrDatasetExists         = errors.New("a dataset with this slug already exists")
+	ErrDatasetAccessNotFound = errors.New("user does not have access to this dataset")
+	ErrInvalidDataset        = errors.New("invalid dataset")
+)
+
+var datasetSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// DatasetService manages the datasets hosted next to the default people table and who may use them.
+// Searches, exports and imports name a dataset by ID or slug; without one they use the active people
+// table, which every user can search.
+type DatasetService struct{}
+
+func NewDatasetService() *DatasetService {
+	return &DatasetService{}
+}
+
+// ListDatasets returns every registered dataset
+func (s *DatasetService) ListDatasets() ([]models.Dataset, error) {
+	datasets := []models.Dataset{}
+	if err := database.PostgresDB.Select(&datasets, `SELECT * FROM datasets ORDER BY slug`); err != nil {
+		return nil, fmt.Errorf("failed to list datasets: %w", err)
+	}
+	return datasets, nil
+}
+
+// ListUserDatasets returns the active datasets a user may search: every dataset for dataset admins,
+// otherwise the ones they were granted
+func (s *DatasetService) ListUserDatasets(userID uuid.UUID, role string) ([]models.Dataset, error) {
+	datasets := []models.Dataset{}
+	query := `SELECT d.* FROM datasets d
+			  WHERE d.is_active AND ($2 OR EXISTS (
+				  SELECT 1 FROM user_dataset_access a WHERE a.dataset_id = d.id AND a.user_id = $1
+			  ))
+			  ORDER BY d.slug`
+	allDatasets := NewAuthorizationService().HasPermission(role, PermissionManageDatasets)
+	if err := database.PostgresDB.Select(&datasets, query, userID, allDatasets); err != nil {
+		return nil, fmt.Errorf("failed to list datasets: %w", err)
+	}
+	return datasets, nil
+}
+
+// GetDataset returns a dataset by ID or slug, active or not
+func (s *DatasetService) GetDataset(ref string) (*models.Dataset, error) {
+	var dataset models.Dataset
+	query := `SELECT * FROM datasets WHERE id::text = $1 OR slug = $1`
+	err := database.PostgresDB.Get(&dataset, query, strings.ToLower(strings.TrimSpace(ref)))
+	if err == sql.ErrNoRows {
+		return nil, ErrDatasetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset: %w", err)
+	}
+	return &dataset, nil
+}
+
+// ResolveDataset returns the active dataset a request names, checking the user is entitled to it.
+// An empty reference means the default people table and returns nil.
+func (s *DatasetService) ResolveDataset(userID uuid.UUID, ref string) (*models.Dataset, error) {
+	if strings.TrimSpace(ref) == "" {
+		return nil, nil
+	}
+
+	dataset, err := s.GetDataset(ref)
+	if err != nil {
+		return nil, err
+	}
+	if !dataset.IsActive {
+		return nil, ErrDatasetNotFound
+	}
+
+	var role string
+	var granted bool
+	query := `SELECT u.role, EXISTS (
+				  SELECT 1 FROM user_dataset_access a WHERE a.user_id = u.id AND a.dataset_id = $2
+			  ) FROM users u WHERE u.id = $1`
+	if err := database.PostgresDB.QueryRow(query, userID, dataset.ID).Scan(&role, &granted); err != nil {
+		return nil, fmt.Errorf("failed to check dataset access: %w", err)
+	}
+	if !granted && !NewAuthorizationService().HasPermission(role, PermissionManageDatasets) {
+		return nil, ErrDatasetAccessDenied
+	}
+	return dataset, nil
+}
+
+// CreateDataset registers an existing people table as a dataset
+func (s *DatasetService) CreateDataset(req *models.CreateDatasetRequest, createdBy uuid.UUID) (*models.Dataset, error) {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !datasetSlugPattern.MatchString(slug) {
+		return nil, fmt.Errorf("%w: slug must be lowercase letters, digits, '-' or '_'", ErrInvalidDataset)
+	}
+	if _, err := uuid.Parse(slug); err == nil {
+		return nil, fmt.Errorf("%w: slug cannot be a UUID", ErrInvalidDataset)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidDataset)
+	}
+	if _, err := NewPeopleTableService().ValidateTable(req.Table); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDataset, err)
+	}
+
+	var dataset models.Dataset
+	query := `INSERT INTO datasets (slug, name, description, table_name, created_by)
+			  VALUES ($1, $2, NULLIF($3, ''), $4, $5)
+			  RETURNING *`
+	err := database.PostgresDB.Get(&dataset, query, slug, name, strings.TrimSpace(req.Description), req.Table, createdBy)
+	if isUniqueViolation(err) {
+		return nil, ErrDatasetExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dataset: %w", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Registered dataset %s on %s", dataset.Slug, database.QualifiedTable(dataset.TableName)))
+	return &dataset, nil
+}
+
+// UpdateDataset changes a dataset's name, description, table or whether it can be used
+func (s *DatasetService) UpdateDataset(ref string, req *models.UpdateDatasetRequest) (*models.Dataset, error) {
+	dataset, err := s.GetDataset(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if name := strings.TrimSpace(*req.Name); name != "" {
+			dataset.Name = name
+		}
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		dataset.Description = &description
+		if description == "" {
+			dataset.Description = nil
+		}
+	}
+	if req.Table != nil && *req.Table != dataset.TableName {
+		if _, err := NewPeopleTableService().ValidateTable(*req.Table); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDataset, err)
+		}
+		dataset.TableName = *req.Table
+	}
+	if req.IsActive != nil {
+		dataset.IsActive = *req.IsActive
+	}
+
+	query := `UPDATE datasets
+			  SET name = $2, description = $3, table_name = $4, is_active = $5, updated_at = now()
+			  WHERE id = $1
+			  RETURNING *`
+	if err := database.PostgresDB.Get(dataset, query, dataset.ID, dataset.Name, dataset.Description, dataset.TableName, dataset.IsActive); err != nil {
+		return nil, fmt.Errorf("failed to update dataset: %w", err)
+	}
+	return dataset, nil
+}
+
+// DeleteDataset unregisters a dataset and its entitlements. The ClickHouse table is left in place.
+func (s *DatasetService) DeleteDataset(ref string) error {
+	dataset, err := s.GetDataset(ref)
+	if err != nil {
+		return err
+	}
+	if _, err := database.PostgresDB.Exec(`DELETE FROM datasets WHERE id = $1`, dataset.ID); err != nil {
+		return fmt.Errorf("failed to delete dataset: %w", err)
+	}
+	utils.LogInfo(fmt.Sprintf("Unregistered dataset %s; table %s was kept", dataset.Slug, database.QualifiedTable(dataset.TableName)))
+	return nil
+}
+
+// GetAccess returns the users entitled to a dataset
+func (s *DatasetService) GetAccess(ref string) ([]models.DatasetAccess, error) {
+	dataset, err := s.GetDataset(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	access := []models.DatasetAccess{}
+	query := `SELECT a.user_id, u.email AS user_email, a.dataset_id, a.granted_by, a.granted_at
+			  FROM user_dataset_access a JOIN users u ON u.id = a.user_id
+			  WHERE a.dataset_id = $1
+			  ORDER BY u.email`
+	if err := database.PostgresDB.Select(&access, query, dataset.ID); err != nil {
+		return nil, fmt.Errorf("failed to get dataset access: %w", err)
+	}
+	return access, nil
+}
+
+// GrantAccess entitles users to a dataset; users who already have access are left unchanged
+func (s *DatasetService) GrantAccess(ref string, userIDs []uuid.UUID, grantedBy uuid.UUID) ([]models.DatasetAccess, error) {
+	if len(userIDs) == 0 {
+		return nil, fmt.Errorf("%w: user_ids is required", ErrInvalidDataset)
+	}
+	dataset, err := s.GetDataset(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO user_dataset_access (user_id, dataset_id, granted_by)
+			  SELECT u.id, $2, $3 FROM users u WHERE u.id = ANY($1)
+			  ON CONFLICT (user_id, dataset_id) DO NOTHING`
+	ids := make(pq.StringArray, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+	if _, err := database.PostgresDB.Exec(query, ids, dataset.ID, grantedBy); err != nil {
+		return nil, fmt.Errorf("failed to grant dataset access: %w", err)
+	}
+	return s.GetAccess(dataset.ID.String())
+}
+
+// RevokeAccess removes a user's entitlement to a dataset
+func (s *DatasetService) RevokeAccess(ref string, userID uuid.UUID) error {
+	dataset, err := s.GetDataset(ref)
+	if err != nil {
+		return err
+	}
+	result, err := database.PostgresDB.Exec(`DELETE FROM user_dataset_access WHERE dataset_id = $1 AND user_id = $2`, dataset.ID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke dataset access: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrDatasetAccessNotFound
+	}
+	return nil
+}
+
+// GetStats returns the size of a dataset's table and how much it is searched
+func (s *DatasetService) GetStats(ref string) (*models.DatasetStats, error) {
+	dataset, err := s.GetDataset(ref)
+	if err != nil {
+		return nil, err
+	}
+	stats := &models.DatasetStats{Dataset: *dataset}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tableQuery := `SELECT ifNull(total_rows, 0), ifNull(total_bytes, 0) FROM system.tables WHERE database = ? AND name = ?`
+	if err := database.ClickHouseDB.QueryRow(ctx, tableQuery, database.PeopleDatabase, dataset.TableName).Scan(&stats.TotalRows, &stats.TotalBytes); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to get size of dataset %s", dataset.Slug), err)
+	}
+
+	searchQuery := `SELECT count(*) FILTER (WHERE search_time >= now() - INTERVAL '1 day'),
+						   count(*),
+						   COALESCE(avg(execution_time_ms) FILTER (WHERE search_time >= now() - INTERVAL '1 day'), 0)
+					FROM searches
+					WHERE dataset_id = $1 AND search_time >= now() - INTERVAL '30 days'`
+	if err := database.PostgresDB.QueryRow(searchQuery, dataset.ID).Scan(&stats.SearchesLast24h, &stats.SearchesLast30d, &stats.AvgSearchTimeMs); err != nil {
+		return nil, fmt.Errorf("failed to get dataset searches: %w", err)
+	}
+
+	if err := database.PostgresDB.Get(&stats.EntitledUsers, `SELECT count(*) FROM user_dataset_access WHERE dataset_id = $1`, dataset.ID); err != nil {
+		return nil, fmt.Errorf("failed to count dataset users: %w", err)
+	}
+	return stats, nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// resolveDatasetTable checks the user may use the dataset a request names and returns its fully
+// qualified table, or "" for the default people table. The reference is rewritten to the dataset
+// ID, so logged searches name the dataset even if its slug changes.
+func resolveDatasetTable(userID uuid.UUID, datasetID *string) (string, error) {
+	dataset, err := NewDatasetService().ResolveDataset(userID, *datasetID)
+	if err != nil {
+		return "", err
+	}
+	if dataset == nil {
+		*datasetID = ""
+		return "", nil
+	}
+	*datasetID = dataset.ID.String()
+	return database.QualifiedTable(dataset.TableName), nil
+}
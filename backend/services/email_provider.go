@@ -0,0 +1,102 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/utils"
+)
+
+// EmailMessage is a rendered email ready to be delivered
+type EmailMessage struct {
+	To      string
+	Subject string
+	HTML    string
+}
+
+// EmailProvider delivers rendered emails
+type EmailProvider interface {
+	Send(msg *EmailMessage) error
+}
+
+var (
+	emailProvidersMu sync.RWMutex
+	emailProviders   = map[string]func(cfg config.NotificationConfig) EmailProvider{
+		"smtp": func(cfg config.NotificationConfig) EmailProvider { return &smtpProvider{cfg: cfg} },
+		"log":  func(cfg config.NotificationConfig) EmailProvider { return &logProvider{} },
+	}
+)
+
+// RegisterEmailProvider makes an email provider selectable by name through notifications.provider
+func RegisterEmailProvider(name string, factory func(cfg config.NotificationConfig) EmailProvider) {
+	emailProvidersMu.Lock()
+	defer emailProvidersMu.Unlock()
+	emailProviders[name] = factory
+}
+
+// newEmailProvider returns the configured email provider
+func newEmailProvider(cfg config.NotificationConfig) (EmailProvider, error) {
+	emailProvidersMu.RLock()
+	factory, ok := emailProviders[cfg.Provider]
+	emailProvidersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown email provider: %s", cfg.Provider)
+	}
+	return factory(cfg), nil
+}
+
+// smtpProvider sends email through an SMTP relay, authenticating when a username is configured
+type smtpProvider struct {
+	cfg config.NotificationConfig
+}
+
+func (p *smtpProvider) Send(msg *EmailMessage) error {
+	if p.cfg.SMTP.Host == "" {
+		return fmt.Errorf("SMTP host is not configured")
+	}
+
+	from, err := mail.ParseAddress(p.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", from.String())
+	fmt.Fprintf(&body, "To: %s\r\n", to.String())
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(msg.HTML)
+
+	var auth smtp.Auth
+	if p.cfg.SMTP.Username != "" {
+		auth = smtp.PlainAuth("", p.cfg.SMTP.Username, p.cfg.SMTP.Password, p.cfg.SMTP.Host)
+	}
+
+	addr := p.cfg.SMTP.Host + ":" + strconv.Itoa(p.cfg.SMTP.Port)
+	if err := smtp.SendMail(addr, auth, from.Address, []string{to.Address}, body.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// logProvider writes emails to the application log instead of delivering them (development)
+type logProvider struct{}
+
+func (p *logProvider) Send(msg *EmailMessage) error {
+	utils.LogInfo(fmt.Sprintf("Email to %s: %s", msg.To, msg.Subject))
+	return nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// ErrInvalidEnhancedSearch is returned for enhanced searches with an unknown key type or no usable value
+var ErrInvalidEnhancedSearch = errors.New("invalid enhanced search")
+
+// maxAltNumbersFollowed caps the alt numbers the second hop of an enhanced mobile search looks up,
+// one query each
+const maxAltNumbersFollowed = 50
+
+// linkPath returns a copy of path with a step appended, so records never share a path's backing array
+func linkPath(path []models.LinkStep, step models.LinkStep) []models.LinkStep {
+	linked := make([]models.LinkStep, 0, len(path)+1)
+	return append(append(linked, path...), step)
+}
+
+// linkFirstHop splits a page of first hop matches into direct and master ID matches and sets their link
+// paths. A master ID match is linked through the first direct match with its master ID.
+func linkFirstHop(key models.EnhancedSearchKey, firstByMasterID map[string]string, matches []EnhancedMatch) ([]models.Person, []models.Person) {
+	var directMatches, masterIDMatches []models.Person
+	for _, match := range matches {
+		if match.MatchClass == EnhancedMatchDirect {
+			directMatches = append(directMatches, linkMatch(key, firstByMasterID, match))
+		} else {
+			masterIDMatches = append(masterIDMatches, linkMatch(key, firstByMasterID, match))
+		}
+	}
+	return directMatches, masterIDMatches
+}
+
+// linkMatch returns the person of a first hop match with its link path set
+func linkMatch(key models.EnhancedSearchKey, firstByMasterID map[string]string, match EnhancedMatch) models.Person {
+	person := match.Person
+	if match.MatchClass == EnhancedMatchDirect {
+		person.LinkPath = []models.LinkStep{{Via: key.Type, Value: key.Value, PersonID: person.ID}}
+		return person
+	}
+	person.LinkPath = []models.LinkStep{
+		{Via: key.Type, Value: key.Value, PersonID: firstByMasterID[person.MasterID]},
+		{Via: models.LinkViaMasterID, Value: person.MasterID, PersonID: person.ID},
+	}
+	return person
+}
+
+// expandSecondHop follows the alt numbers of the first hop's records to the records carrying those
+// numbers, and on to the records sharing their master IDs. Only the first record carrying each alt number
+// is read, and records, numbers and master IDs already visited are not followed again, so link cycles
+// end. At most search.enhanced_mobile_max_linked records are added; it reports whether that cap, or the
+// cap on alt numbers followed, cut the hop short. New master IDs are added to masterIDs.
+func (s *SearchService) expandSecondHop(ctx context.Context, hop EnhancedHop, firstByMasterID map[string]string, masterIDs map[string]bool) ([]models.Person, bool, error) {
+	maxLinked := 500
+	if cfg := config.Get(); cfg != nil {
+		maxLinked = cfg.Search.EnhancedMobileMaxLinked
+	}
+
+	seenNumbers := make(map[string]bool)
+	if hop.Key.Type != models.EnhancedKeyEmail {
+		seenNumbers[hop.Key.Value] = true
+	}
+	// One carrier more than can be followed, and one for the searched number, shows when the cap cut in
+	carriers, err := s.backend.FindEnhancedAltCarriers(ctx, hop, maxAltNumbersFollowed+2)
+	if err != nil {
+		return nil, false, fmt.Errorf("alt number search failed: %w", err)
+	}
+
+	seenRecords := make(map[string]bool)
+	var linked []models.Person
+	add := func(person models.Person, path []models.LinkStep) bool {
+		if len(linked) >= maxLinked {
+			return false
+		}
+		seenRecords[person.ID] = true
+		person.LinkPath = path
+		linked = append(linked, person)
+		return true
+	}
+
+	// Alt numbers of the records found so far, to the records with those numbers. Records of the first hop
+	// are excluded by the backend and the second hop's own records by seenRecords, so maxLinked+1 records
+	// of each number are enough to fill the hop or see that it is full.
+	truncated := false
+	followed := 0
+	var newMasterIDs []string
+	pathByMasterID := make(map[string][]models.LinkStep)
+alts:
+	for _, carrier := range carriers {
+		person := linkMatch(hop.Key, firstByMasterID, carrier)
+		alt := utils.NormalizeMobile(nonDigits.ReplaceAllString(person.Alt, ""))
+		if seenNumbers[alt] || !s.isMobileNumber(alt) {
+			continue
+		}
+		seenNumbers[alt] = true
+		if followed == maxAltNumbersFollowed {
+			truncated = true
+			break
+		}
+		followed++
+
+		matches, err := s.backend.FindByKey(ctx, models.EnhancedSearchKey{Type: models.EnhancedKeyMobile, Value: alt}, hop, maxLinked+1)
+		if err != nil {
+			return nil, false, fmt.Errorf("alt number search failed: %w", err)
+		}
+		for _, match := range matches {
+			if seenRecords[match.ID] {
+				continue
+			}
+			path := linkPath(person.LinkPath, models.LinkStep{Via: models.LinkViaAltNumber, Value: alt, PersonID: match.ID})
+			if !add(match, path) {
+				truncated = true
+				break alts
+			}
+			if match.MasterID != "" && !masterIDs[match.MasterID] && s.isValidMasterID(match.MasterID) {
+				masterIDs[match.MasterID] = true
+				newMasterIDs = append(newMasterIDs, match.MasterID)
+				pathByMasterID[match.MasterID] = path
+			}
+		}
+	}
+
+	// Their master IDs, to the other records of the same people
+	if len(newMasterIDs) > 0 && !truncated {
+		matches, err := s.backend.FindByMasterIDs(ctx, newMasterIDs, hop, maxLinked+1)
+		if err != nil {
+			return nil, false, fmt.Errorf("second hop master ID search failed: %w", err)
+		}
+		for _, match := range matches {
+			if seenRecords[match.ID] {
+				continue
+			}
+			path := linkPath(pathByMasterID[match.MasterID], models.LinkStep{Via: models.LinkViaMasterID, Value: match.MasterID, PersonID: match.ID})
+			if !add(match, path) {
+				truncated = true
+				break
+			}
+		}
+	}
+
+	if truncated {
+		utils.LogWarning(fmt.Sprintf("Enhanced %s search second hop for %s stopped at %d records after %d alt numbers",
+			hop.Key.Type, hop.Key.Value, len(linked), followed))
+	}
+	return linked, truncated, nil
+}
+
+// enhancedSearchKey cleans the value of an enhanced search key: the digits of a number, or a
+// lower-case email
+func enhancedSearchKey(keyType, value string) (models.EnhancedSearchKey, error) {
+	key := models.EnhancedSearchKey{Type: strings.ToLower(strings.TrimSpace(keyType))}
+	switch key.Type {
+	case "":
+		key.Type = models.EnhancedKeyMobile
+		fallthrough
+	case models.EnhancedKeyMobile, models.EnhancedKeyAlt:
+		key.Value = utils.NormalizeMobile(nonDigits.ReplaceAllString(value, ""))
+	case models.EnhancedKeyEmail:
+		key.Value = strings.ToLower(strings.TrimSpace(value))
+		if !strings.Contains(key.Value, "@") {
+			return key, fmt.Errorf("%w: email must be an email address", ErrInvalidEnhancedSearch)
+		}
+	default:
+		return key, fmt.Errorf("%w: key_type must be mobile, alt or email", ErrInvalidEnhancedSearch)
+	}
+	if key.Value == "" {
+		return key, fmt.Errorf("%w: a %s value is required", ErrInvalidEnhancedSearch, key.Type)
+	}
+	return key, nil
+}
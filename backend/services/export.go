@@ -0,0 +1,800 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// exportColumns are the person columns written to export files, in order
+const exportColumns = "id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at, confidence, quality_flags"
+
+// exportFormats maps the supported export formats to their file extensions
+var exportFormats = map[string]string{
+	"csv":     "csv",
+	"json":    "json",
+	"parquet": "parquet",
+}
+
+// fastPathFormats maps the formats the fast path can produce to ClickHouse output formats
+// (large JSON exports are newline-delimited rather than a single array)
+var fastPathFormats = map[string]string{
+	"csv":     "CSVWithNames",
+	"json":    "JSONEachRow",
+	"parquet": "Parquet",
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// minExportPasswordLength is the shortest password a requester may set on an encrypted export
+const minExportPasswordLength = 8
+
+type ExportService struct {
+	authService         *AuthService
+	searchService       *SearchService
+	fieldMaskingService *FieldMaskingService
+	notificationService *NotificationService
+	webhookService      *WebhookService
+	httpClient          *http.Client
+}
+
+func NewExportService() *ExportService {
+	return &ExportService{
+		authService:         NewAuthService(),
+		searchService:       NewSearchService(),
+		fieldMaskingService: NewFieldMaskingService(),
+		notificationService: NewNotificationService(),
+		webhookService:      NewWebhookService(),
+		httpClient:          &http.Client{},
+	}
+}
+
+// ErrExportStillAvailable is returned when regenerating an export whose file can still be downloaded
+var ErrExportStillAvailable = errors.New("export file is still available")
+
+// ErrExportTooLarge is returned when an export matches more rows than the user may export and is not truncated
+var ErrExportTooLarge = errors.New("export exceeds the maximum number of rows")
+
+// ErrInvalidExport is returned when an export request is malformed: its format, password or source search
+var ErrInvalidExport = errors.New("invalid export request")
+
+var exportFileSuffix = regexp.MustCompile(`_[0-9a-f]{8}$`)
+
+// exportOptions adjusts how an export is run and recorded
+type exportOptions struct {
+	countQuota      bool       // Check and consume the user's daily export quota
+	searchID        *uuid.UUID // Search the export belongs to when the query is supplied directly
+	regeneratedFrom *uuid.UUID // Expired export this one replaces
+}
+
+// Export writes every row matching a previous search or a new query to a file. Small exports of a
+// previous search go through the regular query path; exports run from a query, large ones and Parquet
+// stream straight from ClickHouse's HTTP interface in a native format and are post-processed for
+// masking. An export is not capped like a search, but may not contain more rows than the user's export
+// row limit: a larger one is rejected, or truncated to the limit when requested or configured.
+func (s *ExportService) Export(userID uuid.UUID, req *models.ExportRequest) (*models.ExportResponse, error) {
+	return s.export(userID, req, exportOptions{countQuota: true})
+}
+
+// Regenerate re-runs the query of an export whose file has expired or been removed and writes a fresh
+// file. The current field visibility policy applies. It counts against the export quota unless
+// export.regenerate_free is set.
+func (s *ExportService) Regenerate(userID, exportID uuid.UUID) (*models.ExportResponse, error) {
+	var export models.Export
+	err := database.PostgresDB.Get(&export, `SELECT * FROM exports WHERE id = $1 AND user_id = $2`, exportID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("export not found")
+	}
+	if s.fileAvailable(&export) {
+		return nil, ErrExportStillAvailable
+	}
+
+	req := &models.ExportRequest{Format: "csv"}
+	if export.Format != nil {
+		req.Format = *export.Format
+	}
+	if export.FileName != nil {
+		base := strings.TrimSuffix(*export.FileName, ".zip")
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+		req.FileName = exportFileSuffix.ReplaceAllString(base, "")
+	}
+	// The password is not kept, so an encrypted export is regenerated with a new one
+	req.Encrypt = export.Encrypted
+	req.Truncate = export.Truncated
+
+	switch {
+	case export.SearchQuery != nil:
+		var searchReq models.SearchRequest
+		if err := json.Unmarshal(export.SearchQuery, &searchReq); err != nil {
+			return nil, fmt.Errorf("failed to parse stored export query: %w", err)
+		}
+		req.Query = &searchReq
+	case export.SearchID != nil:
+		// Exported before queries were recorded with exports
+		searchID := export.SearchID.String()
+		req.SearchID = &searchID
+	default:
+		return nil, fmt.Errorf("export cannot be regenerated: its query was not recorded")
+	}
+
+	countQuota := config.Get() == nil || !config.Get().Export.RegenerateFree
+	utils.LogInfo(fmt.Sprintf("Regenerating export %s for user %s", exportID, userID))
+	return s.export(userID, req, exportOptions{
+		countQuota:      countQuota,
+		searchID:        export.SearchID,
+		regeneratedFrom: &export.ID,
+	})
+}
+
+// GetUserExports returns a user's most recent exports and whether their files can still be downloaded
+func (s *ExportService) GetUserExports(userID uuid.UUID, limit int) ([]models.ExportRecord, error) {
+	var exports []models.Export
+	query := `SELECT * FROM exports WHERE user_id = $1 ORDER BY exported_at DESC LIMIT $2`
+	if err := database.PostgresDB.Select(&exports, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get exports: %w", err)
+	}
+
+	records := make([]models.ExportRecord, 0, len(exports))
+	for i := range exports {
+		record := models.ExportRecord{Export: exports[i], Available: s.fileAvailable(&exports[i])}
+		if record.Available {
+			url, err := ExportStorageFor(exports[i].Storage).URL(*exports[i].FileName, *exports[i].ExpiresAt)
+			if err != nil {
+				utils.LogError("Failed to create export download URL", err)
+				record.Available = false
+			}
+			record.DownloadURL = url
+		}
+		record.Regenerable = !record.Available && (exports[i].SearchQuery != nil || exports[i].SearchID != nil)
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// fileAvailable reports whether an export's file has not expired and is still stored
+func (s *ExportService) fileAvailable(export *models.Export) bool {
+	if export.FileName == nil || export.ExpiresAt == nil || export.Expired || time.Now().After(*export.ExpiresAt) || config.Get() == nil {
+		return false
+	}
+	return ExportStorageFor(export.Storage).Exists(*export.FileName)
+}
+
+// CleanupExpired removes the files of exports past their expiry from the storage they were kept in
+// and marks the exports expired. Exports whose file cannot be removed are left for the next run.
+func (s *ExportService) CleanupExpired(ctx context.Context) (int, error) {
+	const batchSize = 500
+	removed := 0
+	for {
+		var exports []models.Export
+		query := `SELECT * FROM exports WHERE NOT expired AND expires_at < now() ORDER BY expires_at LIMIT $1`
+		if err := database.PostgresDB.SelectContext(ctx, &exports, query, batchSize); err != nil {
+			return removed, fmt.Errorf("failed to get expired exports: %w", err)
+		}
+
+		var expired []uuid.UUID
+		for _, export := range exports {
+			if export.FileName != nil {
+				if err := ExportStorageFor(export.Storage).Delete(ctx, *export.FileName); err != nil {
+					utils.LogError(fmt.Sprintf("Failed to remove expired export file %s", *export.FileName), err)
+					continue
+				}
+			}
+			expired = append(expired, export.ID)
+		}
+
+		if len(expired) > 0 {
+			if _, err := database.PostgresDB.ExecContext(ctx, `UPDATE exports SET expired = true WHERE id = ANY($1)`, pq.Array(expired)); err != nil {
+				return removed, fmt.Errorf("failed to mark exports expired: %w", err)
+			}
+			removed += len(expired)
+		}
+		// A short batch is the last one; a batch whose files could not be removed would only repeat
+		if len(exports) < batchSize || len(expired) == 0 {
+			return removed, nil
+		}
+	}
+}
+
+func (s *ExportService) export(userID uuid.UUID, req *models.ExportRequest, opts exportOptions) (response *models.ExportResponse, err error) {
+	// Progress is reported to /ws subscribers once the rows are counted
+	var progress *JobProgressTracker
+	defer func() {
+		if err != nil {
+			progress.Fail(err)
+		}
+	}()
+
+	if opts.countQuota {
+		canExport, err := s.authService.CheckExportLimit(userID)
+		if err != nil {
+			utils.LogError("Failed to check export limit", err)
+			return nil, fmt.Errorf("failed to check export limit")
+		}
+		if !canExport {
+			return nil, ErrExportLimitExceeded
+		}
+	}
+
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = "csv"
+	}
+	extension, ok := exportFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported export format: %s", ErrInvalidExport, format)
+	}
+
+	encrypt := req.Encrypt || req.Password != ""
+	password, generatedPassword := req.Password, ""
+	if req.Password != "" && len(req.Password) < minExportPasswordLength {
+		return nil, fmt.Errorf("%w: export password must be at least %d characters", ErrInvalidExport, minExportPasswordLength)
+	}
+	if encrypt && password == "" {
+		password = rand.Text()
+		generatedPassword = password
+	}
+
+	searchReq, searchID, err := s.resolveSearch(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	fromQuery := searchID == nil
+	if searchID == nil {
+		searchID = opts.searchID
+	}
+
+	// Export from the search's dataset, provided the user can still use it
+	table, err := resolveDatasetTable(userID, &searchReq.DatasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	maskedFields, err := s.fieldMaskingService.GetMaskedFieldsForUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve field visibility: %w", err)
+	}
+
+	whereClause, args := NewQueryBuilder().AddSearch(searchReq).Where()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	ctx = database.WithPeopleTable(ctx, table)
+
+	var rowCount uint64
+	countQuery := "SELECT count() FROM " + database.PeopleTableFor(ctx) + " WHERE " + whereClause
+	if err := database.ClickHouseDB.QueryRow(ctx, countQuery, args...).Scan(&rowCount); err != nil {
+		return nil, fmt.Errorf("failed to count export rows: %w", err)
+	}
+
+	// Past the user's row limit the export fails, or keeps the first rows in the search's order
+	truncated, limitClause := false, ""
+	if maxRows := s.maxExportRows(userID); maxRows > 0 && rowCount > uint64(maxRows) {
+		if !req.Truncate && config.Get().Export.RowLimitAction != "truncate" {
+			return nil, fmt.Errorf("%w: %d rows match, the limit is %d", ErrExportTooLarge, rowCount, maxRows)
+		}
+		utils.LogInfo(fmt.Sprintf("Truncating export for user %s to %d of %d rows", userID, maxRows, rowCount))
+		rowCount, truncated = uint64(maxRows), true
+		limitClause = fmt.Sprintf(" LIMIT %d", maxRows)
+	}
+
+	useFastPath := fromQuery || format == "parquet" || int(rowCount) >= config.Get().Export.FastPathThreshold
+	if useFastPath && format == "parquet" && len(maskedFields) > 0 {
+		return nil, fmt.Errorf("%w: parquet export is not available for accounts with masked fields", ErrInvalidExport)
+	}
+
+	if err := os.MkdirAll(config.Get().Export.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	fileName := s.fileName(req.FileName, extension)
+	filePath := filepath.Join(config.Get().Export.Dir, fileName)
+	progress = TrackJob(models.JobKindExport, uuid.New().String(), userID, fileName, int(rowCount))
+
+	// Mark the file so a leaked copy can be traced back to this export
+	watermark, err := newExportWatermark()
+	if err != nil {
+		return nil, err
+	}
+	orderBy := searchOrderBy(searchReq)
+	if order := watermark.orderBy(); order != "" {
+		orderBy = order
+	}
+
+	method := "standard"
+	if useFastPath {
+		method = "fast_path"
+		query := "SELECT " + exportColumns + watermark.selectColumn() + " FROM " + database.PeopleTableFor(ctx) + " WHERE " + whereClause + " ORDER BY " + orderBy + limitClause
+		err = s.exportFastPath(ctx, query, args, format, maskedFields, filePath, progress)
+	} else {
+		query := "SELECT " + exportColumns + " FROM " + database.PeopleTableFor(ctx) + " WHERE " + whereClause + " ORDER BY " + orderBy + limitClause
+		err = s.exportStandard(ctx, query, args, format, maskedFields, watermark.column(), filePath, progress)
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
+	if encrypt {
+		zipPath, err := s.encryptExport(filePath, fileName, password)
+		os.Remove(filePath)
+		if err != nil {
+			return nil, err
+		}
+		filePath, fileName = zipPath, fileName+".zip"
+	}
+
+	fileSize, err := utils.GetFileSize(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat export file: %w", err)
+	}
+
+	storage := NewExportStorage()
+	if err := storage.Store(ctx, filePath, fileName); err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to store export file: %w", err)
+	}
+
+	expiresAt := time.Now().Add(config.Get().Export.Expiry)
+	downloadURL, err := storage.URL(fileName, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export download URL: %w", err)
+	}
+	exportID := s.logExport(userID, searchID, searchReq, format, fileName, storage.Name(), encrypt, watermark, int(rowCount), truncated, fileSize, expiresAt, opts.regeneratedFrom)
+	if opts.countQuota {
+		if err := s.authService.IncrementExportCount(userID); err != nil {
+			utils.LogError("Failed to increment export count", err)
+		}
+	}
+
+	utils.LogInfo(fmt.Sprintf("Export completed (%s): %s, %d rows, %s", method, fileName, rowCount, utils.FormatFileSize(fileSize)))
+	progress.Complete(int(rowCount), 0, nil)
+
+	response = &models.ExportResponse{
+		ExportID:        exportID,
+		DownloadURL:     downloadURL,
+		FileName:        fileName,
+		FileSize:        fileSize,
+		RowCount:        int(rowCount),
+		ExpiresAt:       expiresAt,
+		Method:          method,
+		RegeneratedFrom: opts.regeneratedFrom,
+		Encrypted:       encrypt,
+		Truncated:       truncated,
+	}
+
+	s.notificationService.NotifyExportReady(userID, response)
+	s.webhookService.Dispatch(WebhookEventExportCompleted, map[string]interface{}{
+		"user_id":   userID,
+		"search_id": searchID,
+		"file_name": response.FileName,
+		"file_size": response.FileSize,
+		"row_count": response.RowCount,
+		"format":    format,
+		"method":    method,
+		"encrypted": encrypt,
+		"truncated": truncated,
+	})
+
+	// Only the requester sees a generated password, after the notification and webhook have gone out
+	response.Password = generatedPassword
+	return response, nil
+}
+
+// resolveSearch returns the search to export, loading it from the user's history when a search ID is given
+func (s *ExportService) resolveSearch(userID uuid.UUID, req *models.ExportRequest) (*models.SearchRequest, *uuid.UUID, error) {
+	if req.Query != nil {
+		// A new query gets the same checks as a search
+		s.searchService.ApplyDefaults(req.Query)
+		if !utils.IsValidQualityFilter(req.Query.Quality) {
+			return nil, nil, fmt.Errorf("%w: invalid quality filter: %s", ErrInvalidExport, req.Query.Quality)
+		}
+		if err := s.searchService.enforceAllowedFields(userID, req.Query); err != nil {
+			return nil, nil, err
+		}
+		if _, err := s.searchService.PrepareNearby(req.Query); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidExport, err)
+		}
+		return req.Query, nil, nil
+	}
+
+	if req.SearchID == nil {
+		return nil, nil, fmt.Errorf("%w: either search_id or query is required", ErrInvalidExport)
+	}
+
+	searchID, err := uuid.Parse(*req.SearchID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid search ID", ErrInvalidExport)
+	}
+
+	var search struct {
+		SearchQuery  []byte     `db:"search_query"`
+		AnonymizedAt *time.Time `db:"anonymized_at"`
+	}
+	err = database.PostgresDB.Get(&search,
+		`SELECT search_query, anonymized_at FROM searches WHERE id = $1 AND user_id = $2`, searchID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrSearchNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get search: %w", err)
+	}
+	if search.AnonymizedAt != nil {
+		return nil, nil, ErrSearchAnonymized
+	}
+
+	var searchReq models.SearchRequest
+	if err := json.Unmarshal(search.SearchQuery, &searchReq); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse stored search: %w", err)
+	}
+	s.searchService.ApplyDefaults(&searchReq)
+
+	return &searchReq, &searchID, nil
+}
+
+// maxExportRows returns the rows an export may contain for a user: their own max_export_rows, else
+// the limit for their user type, else limits.max_export_rows. 0 means no limit.
+func (s *ExportService) maxExportRows(userID uuid.UUID) int {
+	var user struct {
+		UserType      string `db:"user_type"`
+		MaxExportRows *int   `db:"max_export_rows"`
+	}
+	if err := database.PostgresDB.Get(&user, `SELECT COALESCE(user_type, '') AS user_type, max_export_rows FROM users WHERE id = $1`, userID); err != nil {
+		utils.LogError("Failed to get export row limit", err)
+	}
+	if user.MaxExportRows != nil {
+		return *user.MaxExportRows
+	}
+	limits := config.Get().Limits
+	if limit, ok := limits.MaxExportRowsByUserType[user.UserType]; ok && limit > 0 {
+		return limit
+	}
+	return limits.MaxExportRows
+}
+
+// exportStandard reads the rows through the driver and writes them from Go, adding the
+// export_watermark column when a watermark token is given
+func (s *ExportService) exportStandard(ctx context.Context, query string, args []interface{}, format string, maskedFields []string,
+	watermark, filePath string, progress *JobProgressTracker) error {
+	var people []models.Person
+	if err := database.ClickHouseDB.Select(ctx, &people, query, args...); err != nil {
+		return fmt.Errorf("export query failed: %w", err)
+	}
+	s.fieldMaskingService.MaskPeople(people, maskedFields)
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	if format == "json" {
+		if watermark == "" {
+			encoder := json.NewEncoder(file)
+			if err := encoder.Encode(people); err != nil {
+				return fmt.Errorf("failed to write export file: %w", err)
+			}
+			return nil
+		}
+		return writeWatermarkedJSON(file, people, watermark)
+	}
+
+	header := strings.Split(strings.ReplaceAll(exportColumns, " ", ""), ",")
+	if watermark != "" {
+		header = append(header, watermarkColumn)
+	}
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+	for i, person := range people {
+		if i%exportProgressRows == 0 {
+			progress.Update(i, 0, progress.fraction(i), nil)
+		}
+		record := []string{
+			person.ID, person.MasterID, person.Mobile, person.Name, person.FName, person.Address,
+			person.Alt, person.Circle, person.Email,
+			person.CreatedAt.Format("2006-01-02 15:04:05"), person.UpdatedAt.Format("2006-01-02 15:04:05"),
+			strconv.Itoa(int(person.Confidence)), strings.Join(person.QualityFlags, "|"),
+		}
+		if watermark != "" {
+			record = append(record, watermark)
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	return nil
+}
+
+// writeWatermarkedJSON writes people as a JSON array with an export_watermark field on each. Person
+// marshals itself, so the field is spliced into each encoded object.
+func writeWatermarkedJSON(dst io.Writer, people []models.Person, watermark string) error {
+	w := bufio.NewWriter(dst)
+	field := []byte(`"` + watermarkColumn + `":"` + watermark + `"}`)
+
+	w.WriteByte('[')
+	for i := range people {
+		encoded, err := json.Marshal(&people[i])
+		if err != nil {
+			return fmt.Errorf("failed to write export file: %w", err)
+		}
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.Write(encoded[:len(encoded)-1])
+		if len(encoded) > 2 {
+			w.WriteByte(',')
+		}
+		w.Write(field)
+	}
+	w.WriteString("]\n")
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return nil
+}
+
+// exportFastPath streams the result from ClickHouse's HTTP interface in a native output format.
+// Output is rewritten row by row only when the user has masked fields.
+func (s *ExportService) exportFastPath(ctx context.Context, query string, args []interface{}, format string, maskedFields []string,
+	filePath string, progress *JobProgressTracker) error {
+	body, err := s.streamQuery(ctx, query+" FORMAT "+fastPathFormats[format], args)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	// CSV and JSON are written a row per line, so rows can be counted as they stream; Parquet cannot
+	var out io.Writer = file
+	if format != "parquet" {
+		header := 0
+		if format == "csv" {
+			header = 1
+		}
+		out = &exportProgressWriter{w: file, header: header, progress: progress}
+	}
+
+	if len(maskedFields) == 0 {
+		if _, err := io.Copy(out, body); err != nil {
+			return fmt.Errorf("failed to stream export: %w", err)
+		}
+		return nil
+	}
+
+	if format == "json" {
+		return s.maskJSONStream(body, out, maskedFields)
+	}
+	return s.maskCSVStream(body, out, maskedFields)
+}
+
+// exportProgressRows is how often, in rows written, export progress is reported
+const exportProgressRows = 10000
+
+// exportProgressWriter reports the rows of a streamed export as they are written, counting a row per
+// line after the header lines. Values with line breaks make the count run slightly ahead.
+type exportProgressWriter struct {
+	w        io.Writer
+	header   int
+	lines    int
+	progress *JobProgressTracker
+}
+
+func (w *exportProgressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	lines := bytes.Count(p[:n], []byte{'\n'})
+	if lines > 0 {
+		w.lines += lines
+		if rows := w.lines - w.header; rows > 0 {
+			w.progress.Update(rows, 0, w.progress.fraction(rows), nil)
+		}
+	}
+	return n, err
+}
+
+// maskJSONStream copies JSONEachRow output, masking the fields named in maskedFields
+func (s *ExportService) maskJSONStream(src io.Reader, dst io.Writer, maskedFields []string) error {
+	decoder := json.NewDecoder(src)
+	encoder := json.NewEncoder(dst)
+
+	for {
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read export row: %w", err)
+		}
+
+		for _, field := range maskedFields {
+			if value, ok := row[field].(string); ok {
+				row[field] = s.fieldMaskingService.MaskValue(field, value)
+			}
+		}
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+	}
+}
+
+// maskCSVStream copies CSVWithNames output, masking the columns named in maskedFields
+func (s *ExportService) maskCSVStream(src io.Reader, dst io.Writer, maskedFields []string) error {
+	reader := csv.NewReader(src)
+	reader.ReuseRecord = true
+	writer := csv.NewWriter(dst)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read export header: %w", err)
+	}
+	writer.Write(header)
+
+	masked := make(map[string]bool, len(maskedFields))
+	for _, field := range maskedFields {
+		masked[field] = true
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		if masked[name] {
+			columns[i] = name
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read export row: %w", err)
+		}
+
+		for i, field := range columns {
+			if field != "" && i < len(record) {
+				record[i] = s.fieldMaskingService.MaskValue(field, record[i])
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write export row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// streamQuery runs a query over the ClickHouse HTTP interface and returns the response body.
+// Positional ? arguments are sent as typed query parameters so values are never inlined.
+func (s *ExportService) streamQuery(ctx context.Context, query string, args []interface{}) (io.ReadCloser, error) {
+	cfg := config.Get().Database.ClickHouse
+
+	params := url.Values{}
+	params.Set("database", cfg.Database)
+
+	var sql strings.Builder
+	argIndex := 0
+	for _, r := range query {
+		if r == '?' && argIndex < len(args) {
+			name := fmt.Sprintf("p%d", argIndex)
+			fmt.Fprintf(&sql, "{%s:String}", name)
+			params.Set("param_"+name, fmt.Sprint(args[argIndex]))
+			argIndex++
+			continue
+		}
+		sql.WriteRune(r)
+	}
+
+	endpoint := fmt.Sprintf("http://%s:%d/?%s", cfg.Host, cfg.HTTPPort, params.Encode())
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(sql.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build export request: %w", err)
+	}
+	httpReq.Header.Set("X-ClickHouse-User", cfg.User)
+	httpReq.Header.Set("X-ClickHouse-Key", cfg.Password)
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ClickHouse HTTP interface: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("clickhouse export query failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return resp.Body, nil
+}
+
+// encryptExport writes an export file into an AES-256 encrypted ZIP next to it and returns the ZIP's path
+func (s *ExportService) encryptExport(filePath, fileName, password string) (string, error) {
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer src.Close()
+
+	zipPath := filePath + ".zip"
+	dst, err := os.Create(zipPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create encrypted export file: %w", err)
+	}
+	err = utils.WriteEncryptedZip(dst, fileName, time.Now(), src, password)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(zipPath)
+		return "", fmt.Errorf("failed to encrypt export file: %w", err)
+	}
+	return zipPath, nil
+}
+
+// fileName builds a unique, filesystem-safe export file name
+func (s *ExportService) fileName(requested, extension string) string {
+	base := strings.TrimSuffix(filepath.Base(requested), filepath.Ext(requested))
+	base = unsafeFileNameChars.ReplaceAllString(base, "_")
+	if base == "" || base == "." || base == "_" {
+		base = "search_results"
+	}
+	return fmt.Sprintf("%s_%s.%s", base, uuid.New().String()[:8], extension)
+}
+
+// logExport records the export in PostgreSQL with the query it ran, so it can be regenerated later.
+// Returns the export ID, or nil if it could not be recorded.
+func (s *ExportService) logExport(userID uuid.UUID, searchID *uuid.UUID, searchReq *models.SearchRequest, format, fileName, storage string,
+	encrypted bool, watermark *exportWatermark, rowCount int, truncated bool, fileSize int64, expiresAt time.Time, regeneratedFrom *uuid.UUID) *uuid.UUID {
+	searchQuery, err := json.Marshal(searchReq)
+	if err != nil {
+		utils.LogError("Failed to encode export query", err)
+		searchQuery = nil
+	}
+
+	var watermarkToken, watermarkMode *string
+	if watermark != nil {
+		watermarkToken, watermarkMode = &watermark.token, &watermark.mode
+	}
+
+	var exportID uuid.UUID
+	query := `INSERT INTO exports (user_id, search_id, row_count, file_size_bytes, file_name, format, search_query, expires_at, regenerated_from,
+	                               storage, encrypted, watermark, watermark_mode, truncated)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	          RETURNING id`
+	err = database.PostgresDB.Get(&exportID, query, userID, searchID, rowCount, fileSize, fileName, format, searchQuery, expiresAt, regeneratedFrom,
+		storage, encrypted, watermarkToken, watermarkMode, truncated)
+	if err != nil {
+		utils.LogError("Failed to log export", err)
+		return nil
+	}
+	return &exportID
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/utils"
+)
+
+// Export storage backends
+const (
+	ExportStorageLocal = "local"
+	ExportStorageS3    = "s3"
+)
+
+// maxPresignExpiry is the longest a Signature Version 4 presigned URL can be valid
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// ExportStorage keeps finished export files and hands out their download URLs. Exports are always
+// written to the local export directory first; Store then moves them to the backend.
+type ExportStorage interface {
+	Name() string
+	// Store takes over a finished export file at localPath
+	Store(ctx context.Context, localPath, fileName string) error
+	// URL returns a download URL for the file, valid until expiresAt where the backend can enforce it
+	URL(fileName string, expiresAt time.Time) (string, error)
+	// Exists reports whether the file is still stored
+	Exists(fileName string) bool
+	Delete(ctx context.Context, fileName string) error
+}
+
+// NewExportStorage returns the configured storage backend for new exports
+func NewExportStorage() ExportStorage {
+	return ExportStorageFor(config.Get().Export.Storage)
+}
+
+// ExportStorageFor returns a storage backend by name, so each export is read from and removed from
+// the backend it was stored in even after export.storage changes. Unknown names fall back to local.
+func ExportStorageFor(name string) ExportStorage {
+	if name == ExportStorageS3 {
+		return &s3ExportStorage{
+			client: utils.NewS3Client(config.Get().S3, &http.Client{}),
+			bucket: config.Get().Export.S3Bucket,
+			prefix: config.Get().Export.S3Prefix,
+		}
+	}
+	return &localExportStorage{}
+}
+
+// localExportStorage keeps export files in the export directory, served as static files under /downloads
+type localExportStorage struct{}
+
+func (s *localExportStorage) Name() string { return ExportStorageLocal }
+
+func (s *localExportStorage) Store(ctx context.Context, localPath, fileName string) error {
+	return nil
+}
+
+func (s *localExportStorage) URL(fileName string, expiresAt time.Time) (string, error) {
+	return "/downloads/" + filepath.Base(config.Get().Export.Dir) + "/" + fileName, nil
+}
+
+func (s *localExportStorage) Exists(fileName string) bool {
+	_, err := os.Stat(s.path(fileName))
+	return err == nil
+}
+
+func (s *localExportStorage) Delete(ctx context.Context, fileName string) error {
+	if err := os.Remove(s.path(fileName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *localExportStorage) path(fileName string) string {
+	return filepath.Join(config.Get().Export.Dir, filepath.Base(fileName))
+}
+
+// s3ExportStorage uploads export files to a bucket and serves them through presigned URLs, so
+// downloads do not go through the API nodes
+type s3ExportStorage struct {
+	client *utils.S3Client
+	bucket string
+	prefix string
+}
+
+func (s *s3ExportStorage) Name() string { return ExportStorageS3 }
+
+// Store uploads the file and removes the local copy
+func (s *s3ExportStorage) Store(ctx context.Context, localPath, fileName string) error {
+	if s.bucket == "" {
+		return fmt.Errorf("export.s3_bucket is not set")
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat export file: %w", err)
+	}
+
+	if err := s.client.PutObject(ctx, s.bucket, s.key(fileName), file, info.Size(), exportContentType(fileName)); err != nil {
+		return err
+	}
+	file.Close()
+	if err := os.Remove(localPath); err != nil {
+		utils.LogError("Failed to remove uploaded export file "+localPath, err)
+	}
+	return nil
+}
+
+// URL presigns a download that expires with the export, or after 7 days if that is sooner
+func (s *s3ExportStorage) URL(fileName string, expiresAt time.Time) (string, error) {
+	expires := time.Until(expiresAt)
+	if expires > maxPresignExpiry {
+		expires = maxPresignExpiry
+	}
+	if expires < time.Second {
+		return "", fmt.Errorf("export has expired")
+	}
+	return s.client.PresignGetObject(s.bucket, s.key(fileName), expires.Round(time.Second), fileName)
+}
+
+// Exists trusts the export record rather than asking S3 for every listed export: objects are only
+// removed by the export cleanup job, which marks their exports expired
+func (s *s3ExportStorage) Exists(fileName string) bool {
+	return s.bucket != ""
+}
+
+func (s *s3ExportStorage) Delete(ctx context.Context, fileName string) error {
+	return s.client.DeleteObject(ctx, s.bucket, s.key(fileName))
+}
+
+func (s *s3ExportStorage) key(fileName string) string {
+	return s.prefix + filepath.Base(fileName)
+}
+
+// exportContentType returns the content type of an export file by its extension
+func exportContentType(fileName string) string {
+	switch strings.TrimPrefix(filepath.Ext(fileName), ".") {
+	case "csv":
+		return "text/csv"
+	case "json":
+		return "application/json"
+	case "zip":
+		return "application/zip"
+	default:
+		return "application/octet-stream"
+	}
+}
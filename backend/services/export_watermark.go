@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/lib/pq"
+)
+
+// Export watermark modes
+const (
+	WatermarkOff    = "off"
+	WatermarkColumn = "column"
+	WatermarkOrder  = "order"
+	WatermarkBoth   = "both"
+)
+
+// watermarkColumn is the column that carries an export's watermark token
+const watermarkColumn = "export_watermark"
+
+// minTraceIDs is the fewest rows an order trace accepts. Ten IDs fall in a given order by chance once
+// in 3.6 million, so comparing against even thousands of exports does not produce false matches.
+const minTraceIDs = 10
+
+// maxTraceIDs bounds the IDs sent to ClickHouse for one trace
+const maxTraceIDs = 1000
+
+// watermarkPattern matches generated tokens; they are inlined into export SQL, so nothing else may be
+var watermarkPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+var ErrInvalidTrace = errors.New("invalid export trace")
+
+// exportWatermark is the leak tracing mark embedded in one export
+type exportWatermark struct {
+	token string
+	mode  string
+}
+
+// newExportWatermark returns a fresh watermark in the configured mode, or nil when watermarking is off
+func newExportWatermark() (*exportWatermark, error) {
+	mode := config.Get().Export.Watermark
+	switch mode {
+	case WatermarkColumn, WatermarkOrder, WatermarkBoth:
+	default:
+		return nil, nil
+	}
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate export watermark: %w", err)
+	}
+	return &exportWatermark{token: hex.EncodeToString(buf), mode: mode}, nil
+}
+
+// column returns the token written to the export_watermark column, or "" when the mode has no column
+func (w *exportWatermark) column() string {
+	if w == nil || (w.mode != WatermarkColumn && w.mode != WatermarkBoth) {
+		return ""
+	}
+	return w.token
+}
+
+// selectColumn returns the SQL that adds the export_watermark column to an export query
+func (w *exportWatermark) selectColumn() string {
+	if w.column() == "" {
+		return ""
+	}
+	return fmt.Sprintf(", '%s' AS %s", w.token, watermarkColumn)
+}
+
+// orderBy returns the ORDER BY that sorts rows by a hash of their ID salted with the token, an order
+// no other export shares, or "" when the mode keeps the usual order
+func (w *exportWatermark) orderBy() string {
+	if w == nil || (w.mode != WatermarkOrder && w.mode != WatermarkBoth) {
+		return ""
+	}
+	return watermarkOrderExpr("id", "'"+w.token+"'")
+}
+
+func watermarkOrderExpr(idExpr, tokenExpr string) string {
+	return fmt.Sprintf("cityHash64(%s, %s)", idExpr, tokenExpr)
+}
+
+// TraceExport finds the export a leaked file came from, by the export_watermark value it carries or,
+// when that column was removed, by the order of its rows
+func (s *ExportService) TraceExport(ctx context.Context, req *models.ExportTraceRequest) (*models.ExportTraceResponse, error) {
+	if token := strings.ToLower(strings.TrimSpace(req.Watermark)); token != "" {
+		if !watermarkPattern.MatchString(token) {
+			return nil, fmt.Errorf("%w: watermark must be 16 hex characters", ErrInvalidTrace)
+		}
+		matches, err := s.traceMatches(ctx, `e.watermark = $1`, token)
+		if err != nil {
+			return nil, err
+		}
+		return &models.ExportTraceResponse{Method: "watermark", Matches: matches}, nil
+	}
+
+	if len(req.IDs) < minTraceIDs || len(req.IDs) > maxTraceIDs {
+		return nil, fmt.Errorf("%w: give a watermark, or between %d and %d person IDs in file order", ErrInvalidTrace, minTraceIDs, maxTraceIDs)
+	}
+	seen := make(map[string]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: person IDs must be distinct", ErrInvalidTrace)
+		}
+		seen[id] = true
+	}
+
+	since := time.Now().AddDate(0, 0, -90)
+	if req.Since != nil {
+		since = *req.Since
+	}
+	var tokens []string
+	query := `SELECT watermark FROM exports WHERE watermark_mode IN ($1, $2) AND exported_at >= $3`
+	if err := database.PostgresDB.SelectContext(ctx, &tokens, query, WatermarkOrder, WatermarkBoth, since); err != nil {
+		return nil, fmt.Errorf("failed to get watermarked exports: %w", err)
+	}
+
+	response := &models.ExportTraceResponse{Method: "order", Candidates: len(tokens), Matches: []models.ExportTraceMatch{}}
+	if len(tokens) == 0 {
+		return response, nil
+	}
+
+	// Sort the IDs in each candidate export's order in one pass; rows leaked from an export keep its order
+	rows, err := database.ClickHouseDB.Query(ctx,
+		"SELECT token FROM (SELECT arrayJoin(?) AS token) WHERE arraySort(x -> "+watermarkOrderExpr("x", "token")+", ?) = ?",
+		tokens, req.IDs, req.IDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare export row order: %w", err)
+	}
+	defer rows.Close()
+
+	var matched []string
+	for rows.Next() {
+		var token string
+		if err := rows.Scan(&token); err != nil {
+			return nil, fmt.Errorf("failed to read export order match: %w", err)
+		}
+		matched = append(matched, token)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to compare export row order: %w", err)
+	}
+	if len(matched) == 0 {
+		return response, nil
+	}
+
+	response.Matches, err = s.traceMatches(ctx, `e.watermark = ANY($1)`, pq.StringArray(matched))
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// traceMatches returns the watermarked exports matching a condition with the users who made them
+func (s *ExportService) traceMatches(ctx context.Context, condition string, arg interface{}) ([]models.ExportTraceMatch, error) {
+	matches := []models.ExportTraceMatch{}
+	query := `SELECT e.id, e.user_id, u.name AS user_name, u.email AS user_email, e.exported_at, e.file_name, e.row_count,
+	                 e.watermark, e.watermark_mode, e.search_id
+	          FROM exports e
+	          JOIN users u ON u.id = e.user_id
+	          WHERE e.watermark IS NOT NULL AND ` + condition + `
+	          ORDER BY e.exported_at DESC`
+	if err := database.PostgresDB.SelectContext(ctx, &matches, query, arg); err != nil {
+		return nil, fmt.Errorf("failed to look up traced exports: %w", err)
+	}
+	return matches, nil
+}
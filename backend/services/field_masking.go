@@ -0,0 +1,249 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// maskableFields lists the person fields that a visibility policy may mask
+var maskableFields = map[string]bool{
+	"mobile":    true,
+	"alt":       true,
+	"master_id": true,
+	"name":      true,
+	"fname":     true,
+	"address":   true,
+	"email":     true,
+	"circle":    true,
+}
+
+var pincodePattern = regexp.MustCompile(`(^|\D)(\d{6})(\D|$)`)
+
+type FieldMaskingService struct {
+	db *sqlx.DB
+}
+
+func NewFieldMaskingService() *FieldMaskingService {
+	return &FieldMaskingService{
+		db: database.PostgresDB,
+	}
+}
+
+// GetMaskedFieldsForUser returns the fields to mask for a user. Admins are exempt, a user-specific
+// policy takes precedence over the policy for the user's type, and no policy means nothing is masked.
+func (s *FieldMaskingService) GetMaskedFieldsForUser(userID uuid.UUID) ([]string, error) {
+	var user models.User
+	err := s.db.Get(&user, `SELECT role, user_type FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	if user.Role == "ADMIN" {
+		return nil, nil
+	}
+
+	var fields pq.StringArray
+	err = s.db.Get(&fields, `SELECT masked_fields FROM field_visibility_policies WHERE user_id = $1`, userID)
+	if err == nil {
+		return fields, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get user field policy: %w", err)
+	}
+
+	err = s.db.Get(&fields, `SELECT masked_fields FROM field_visibility_policies WHERE user_type = $1`, user.UserType)
+	if err == nil {
+		return fields, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get user type field policy: %w", err)
+	}
+
+	return nil, nil
+}
+
+// MaskPeople masks the configured fields in place for every person in the slice
+func (s *FieldMaskingService) MaskPeople(people []models.Person, fields []string) {
+	if len(fields) == 0 {
+		return
+	}
+	for i := range people {
+		s.MaskPerson(&people[i], fields)
+	}
+}
+
+// MaskPerson masks the configured fields of a single person in place
+func (s *FieldMaskingService) MaskPerson(person *models.Person, fields []string) {
+	for _, field := range fields {
+		switch field {
+		case "mobile":
+			person.Mobile = s.MaskValue(field, person.Mobile)
+		case "alt":
+			person.Alt = s.MaskValue(field, person.Alt)
+		case "master_id":
+			person.MasterID = s.MaskValue(field, person.MasterID)
+		case "name":
+			person.Name = s.MaskValue(field, person.Name)
+		case "fname":
+			person.FName = s.MaskValue(field, person.FName)
+		case "address":
+			person.Address = s.MaskValue(field, person.Address)
+		case "email":
+			person.Email = s.MaskValue(field, person.Email)
+		case "circle":
+			person.Circle = s.MaskValue(field, person.Circle)
+		}
+		// Link paths carry the master IDs and alt numbers that linked records
+		for i, step := range person.LinkPath {
+			if (step.Via == models.LinkViaMasterID && field == "master_id") || (step.Via == models.LinkViaAltNumber && field == "alt") {
+				person.LinkPath[i].Value = s.MaskValue(field, step.Value)
+			}
+		}
+	}
+}
+
+// MaskValue masks a single raw field value, used when post-processing exported files
+func (s *FieldMaskingService) MaskValue(field, value string) string {
+	switch field {
+	case "mobile", "alt", "master_id":
+		return maskDigits(value)
+	case "name", "fname", "circle":
+		return maskWords(value)
+	case "address":
+		return maskAddress(value)
+	case "email":
+		return maskEmail(value)
+	}
+	return value
+}
+
+// GetPolicies returns all configured field visibility policies (admin only)
+func (s *FieldMaskingService) GetPolicies() ([]models.FieldVisibilityPolicy, error) {
+	var policies []models.FieldVisibilityPolicy
+	query := `SELECT * FROM field_visibility_policies ORDER BY user_type NULLS LAST, created_at DESC`
+	if err := s.db.Select(&policies, query); err != nil {
+		return nil, fmt.Errorf("failed to get field visibility policies: %w", err)
+	}
+	return policies, nil
+}
+
+// UpsertPolicy creates or replaces the policy for a user or a user type (admin only)
+func (s *FieldMaskingService) UpsertPolicy(req *models.UpsertFieldVisibilityPolicyRequest) (*models.FieldVisibilityPolicy, error) {
+	if (req.UserID == nil) == (req.UserType == nil) {
+		return nil, fmt.Errorf("exactly one of user_id or user_type is required")
+	}
+
+	fields := make([]string, 0, len(req.MaskedFields))
+	seen := map[string]bool{}
+	for _, field := range req.MaskedFields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !maskableFields[field] {
+			return nil, fmt.Errorf("field cannot be masked: %s", field)
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+
+	var policy models.FieldVisibilityPolicy
+	if req.UserID != nil {
+		userID, err := uuid.Parse(*req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID")
+		}
+		query := `INSERT INTO field_visibility_policies (user_id, masked_fields)
+		          VALUES ($1, $2)
+		          ON CONFLICT (user_id) WHERE user_id IS NOT NULL
+		          DO UPDATE SET masked_fields = EXCLUDED.masked_fields, updated_at = now()
+		          RETURNING *`
+		if err := s.db.Get(&policy, query, userID, pq.StringArray(fields)); err != nil {
+			return nil, fmt.Errorf("failed to save field visibility policy: %w", err)
+		}
+		return &policy, nil
+	}
+
+	userType := strings.ToUpper(strings.TrimSpace(*req.UserType))
+	if userType != "DEMO" && userType != "PERMANENT" {
+		return nil, fmt.Errorf("user_type must be DEMO or PERMANENT")
+	}
+	query := `INSERT INTO field_visibility_policies (user_type, masked_fields)
+	          VALUES ($1, $2)
+	          ON CONFLICT (user_type) WHERE user_type IS NOT NULL
+	          DO UPDATE SET masked_fields = EXCLUDED.masked_fields, updated_at = now()
+	          RETURNING *`
+	if err := s.db.Get(&policy, query, userType, pq.StringArray(fields)); err != nil {
+		return nil, fmt.Errorf("failed to save field visibility policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// DeletePolicy removes a field visibility policy (admin only)
+func (s *FieldMaskingService) DeletePolicy(id uuid.UUID) error {
+	result, err := s.db.Exec(`DELETE FROM field_visibility_policies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete field visibility policy: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("field visibility policy not found")
+	}
+
+	return nil
+}
+
+// maskDigits keeps the first two and last four characters, e.g. 9876541234 -> 98XXXX1234
+func maskDigits(value string) string {
+	if value == "" {
+		return value
+	}
+	runes := []rune(value)
+	if len(runes) <= 6 {
+		return strings.Repeat("X", len(runes))
+	}
+	return string(runes[:2]) + strings.Repeat("X", len(runes)-6) + string(runes[len(runes)-4:])
+}
+
+// maskWords keeps the first letter of every word, e.g. Rahul Kumar -> RXXXX KXXXX
+func maskWords(value string) string {
+	words := strings.Fields(value)
+	for i, word := range words {
+		runes := []rune(word)
+		words[i] = string(runes[0]) + strings.Repeat("X", len(runes)-1)
+	}
+	return strings.Join(words, " ")
+}
+
+// maskAddress hides the address but keeps its pincode so coarse location remains visible
+func maskAddress(value string) string {
+	if value == "" {
+		return value
+	}
+	if match := pincodePattern.FindStringSubmatch(value); match != nil {
+		return "XXXX, " + match[2]
+	}
+	return "XXXX"
+}
+
+// maskEmail keeps the first character of the local part and the domain, e.g. rahul@x.com -> rXXXX@x.com
+func maskEmail(value string) string {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return maskDigits(value)
+	}
+	local := []rune(value[:at])
+	return string(local[0]) + strings.Repeat("X", len(local)-1) + value[at:]
+}
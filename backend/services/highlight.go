@@ -0,0 +1,173 @@
+package services
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"finone-search-system/models"
+)
+
+const (
+	// maxHighlightsPerField caps the highlighted occurrences of a term in one field
+	maxHighlightsPerField = 5
+	// highlightContextChars is how much text a snippet keeps on each side of a match
+	highlightContextChars = 30
+)
+
+var highlightNonDigit = regexp.MustCompile(`\D`)
+
+// highlightTerm is a value a search compared against a person field
+type highlightTerm struct {
+	field     string
+	value     string
+	matchType string
+}
+
+// searchHighlightTerms returns the field and value pairs a search request matches, mirroring QueryBuilder.AddSearch
+func searchHighlightTerms(req *models.SearchRequest) []highlightTerm {
+	var terms []highlightTerm
+	if len(req.FieldQueries) > 0 {
+		for field, value := range req.FieldQueries {
+			if value = strings.TrimSpace(value); value != "" && isSearchableField(field) {
+				terms = append(terms, highlightTerm{field: field, value: value, matchType: req.MatchType})
+			}
+		}
+	} else {
+		for _, field := range req.Fields {
+			if isSearchableField(field) {
+				terms = append(terms, highlightTerm{field: field, value: req.Query, matchType: req.MatchType})
+			}
+		}
+	}
+
+	// Default search across all fields if no specific fields provided
+	if len(terms) == 0 {
+		for _, field := range searchableFields {
+			terms = append(terms, highlightTerm{field: field, value: req.Query, matchType: req.MatchType})
+		}
+	}
+	return terms
+}
+
+// withinHighlightTerms returns the terms of a previous search and of its refinement
+func withinHighlightTerms(originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) []highlightTerm {
+	terms := searchHighlightTerms(originalReq)
+	fields := withinReq.Fields
+	if len(fields) == 0 {
+		fields = searchableFields
+	}
+	for _, field := range fields {
+		if isSearchableField(field) {
+			terms = append(terms, highlightTerm{field: field, value: withinReq.Query, matchType: withinReq.MatchType})
+		}
+	}
+	return terms
+}
+
+// highlightPeople sets the highlights of each person to the places its fields match the terms. Run it
+// after masking so masked values are never matched or echoed back in snippets.
+func highlightPeople(people []models.Person, terms []highlightTerm) {
+	for i := range people {
+		highlights := make(map[string][]models.Highlight)
+		for _, term := range terms {
+			field, value := term.field, term.value
+			if field == "pincode" {
+				// The pincode is extracted from the address, so highlight it there
+				field = "address"
+			}
+			if field == "mobile" || field == "alt" {
+				if digits := highlightNonDigit.ReplaceAllString(value, ""); len(digits) >= 10 && len(digits) <= 12 {
+					value = digits
+				}
+			}
+
+			matches := highlightField(personField(&people[i], field), value, term.matchType)
+			if len(matches) > 0 && len(highlights[field]) < maxHighlightsPerField {
+				highlights[field] = append(highlights[field], matches...)
+				if len(highlights[field]) > maxHighlightsPerField {
+					highlights[field] = highlights[field][:maxHighlightsPerField]
+				}
+			}
+		}
+		for field := range highlights {
+			sort.SliceStable(highlights[field], func(a, b int) bool {
+				return highlights[field][a].Start < highlights[field][b].Start
+			})
+		}
+		if len(highlights) > 0 {
+			people[i].Highlights = highlights
+		}
+	}
+}
+
+// highlightField finds a term in a field value: the whole value for full matches, or every
+// case-insensitive occurrence for partial matches. Offsets count characters, not bytes.
+func highlightField(value, term, matchType string) []models.Highlight {
+	if value == "" || term == "" {
+		return nil
+	}
+
+	runes := []rune(value)
+	if matchType == "full" {
+		if value != term {
+			return nil
+		}
+		return []models.Highlight{newHighlight(runes, 0, len(runes))}
+	}
+
+	lowerValue := []rune(strings.ToLower(value))
+	lowerTerm := []rune(strings.ToLower(term))
+	if len(lowerValue) != len(runes) {
+		// Lower-casing changed the length (rare Unicode cases); offsets would not line up
+		return nil
+	}
+
+	var highlights []models.Highlight
+	for start := 0; start+len(lowerTerm) <= len(lowerValue) && len(highlights) < maxHighlightsPerField; {
+		if runesHavePrefix(lowerValue[start:], lowerTerm) {
+			highlights = append(highlights, newHighlight(runes, start, start+len(lowerTerm)))
+			start += len(lowerTerm)
+			continue
+		}
+		start++
+	}
+	return highlights
+}
+
+// newHighlight builds a highlight with an HTML-escaped snippet of the surrounding text and the match in <em>
+func newHighlight(runes []rune, start, end int) models.Highlight {
+	from := start - highlightContextChars
+	if from < 0 {
+		from = 0
+	}
+	to := end + highlightContextChars
+	if to > len(runes) {
+		to = len(runes)
+	}
+
+	var snippet strings.Builder
+	if from > 0 {
+		snippet.WriteString("…")
+	}
+	snippet.WriteString(html.EscapeString(string(runes[from:start])))
+	snippet.WriteString("<em>")
+	snippet.WriteString(html.EscapeString(string(runes[start:end])))
+	snippet.WriteString("</em>")
+	snippet.WriteString(html.EscapeString(string(runes[end:to])))
+	if to < len(runes) {
+		snippet.WriteString("…")
+	}
+
+	return models.Highlight{Start: start, End: end, Snippet: snippet.String()}
+}
+
+func runesHavePrefix(value, prefix []rune) bool {
+	for i := range prefix {
+		if value[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// impersonationClaim carries the real admin in the token of an impersonation session
+const impersonationClaim = "impersonated_by"
+
+// impersonationPermissions are the permissions an impersonation session keeps from the user's role:
+// enough to reproduce what the user sees, but not to export data or change their account
+var impersonationPermissions = map[string]bool{
+	PermissionProfile: true,
+	PermissionSearch:  true,
+}
+
+var (
+	ErrCannotImpersonate   = errors.New("this user cannot be impersonated")
+	ErrImpersonationReason = errors.New("a reason is required to impersonate a user")
+)
+
+// AllowedWhileImpersonating reports whether an impersonation session may use a permission
+func AllowedWhileImpersonating(permission string) bool {
+	return impersonationPermissions[permission]
+}
+
+// Impersonate issues adminID a short-lived session acting as userID, so support can reproduce what
+// the user sees without their password. The session carries the admin in its token and session row,
+// is recorded in user_impersonations and is never extended. Admins and inactive or expired accounts
+// cannot be impersonated.
+func (s *AuthService) Impersonate(adminID, userID uuid.UUID, reason, ipAddress, userAgent string) (*models.ImpersonationResponse, error) {
+	if reason == "" {
+		return nil, ErrImpersonationReason
+	}
+	if adminID == userID {
+		return nil, fmt.Errorf("%w: cannot impersonate yourself", ErrCannotImpersonate)
+	}
+
+	var user models.User
+	err := database.PostgresDB.Get(&user, `SELECT * FROM users WHERE id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	switch {
+	case user.Role == "ADMIN":
+		return nil, fmt.Errorf("%w: admins cannot be impersonated", ErrCannotImpersonate)
+	case !user.IsActive:
+		return nil, fmt.Errorf("%w: the account is inactive", ErrCannotImpersonate)
+	case user.ExpiresAt != nil && user.ExpiresAt.Before(time.Now()):
+		return nil, fmt.Errorf("%w: the account has expired", ErrCannotImpersonate)
+	}
+
+	expiresAt := time.Now().Add(config.Get().JWT.ImpersonationExpiry)
+	token, err := signJWT(jwt.MapClaims{
+		"user_id":          user.ID.String(),
+		"email":            user.Email,
+		"role":             user.Role,
+		impersonationClaim: adminID.String(),
+		"exp":              expiresAt.Unix(),
+		"iat":              time.Now().Unix(),
+		"jti":              uuid.New().String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	sessionID := uuid.New()
+	device := utils.ParseUserAgent(userAgent)
+	err = database.WithTransaction(func(tx *sqlx.Tx) error {
+		_, err := tx.Exec(`INSERT INTO user_sessions
+			(id, user_id, session_token, created_at, expires_at, is_active, ip_address, user_agent, last_activity_at, impersonated_by,
+			 browser, os, device_type, device_fingerprint)
+			VALUES ($1, $2, $3, now(), $4, true, $5, $6, now(), $7, $8, $9, $10, $11)`,
+			sessionID, user.ID, s.hashToken(token), expiresAt, ipAddress, userAgent, adminID,
+			device.Browser, device.OS, device.DeviceType, device.Fingerprint())
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		_, err = tx.Exec(`INSERT INTO user_impersonations (admin_id, user_id, session_id, reason, ip_address, user_agent, expires_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7)`,
+			adminID, user.ID, sessionID, reason, ipAddress, userAgent, expiresAt)
+		if err != nil {
+			return fmt.Errorf("failed to record impersonation: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	utils.LogInfo(fmt.Sprintf("Admin %s started impersonating user %s (session %s, until %s): %s",
+		adminID, user.ID, sessionID, expiresAt.Format(time.RFC3339), reason))
+
+	user.PasswordHash = ""
+	return &models.ImpersonationResponse{
+		Token:          token,
+		User:           user,
+		ExpiresAt:      expiresAt,
+		SessionID:      sessionID.String(),
+		ImpersonatedBy: adminID,
+	}, nil
+}
+
+// Impersonator returns the admin acting through a validated session token, or nil for the user's
+// own sessions
+func (s *AuthService) Impersonator(tokenString string) *uuid.UUID {
+	claims, err := s.ValidateJWT(tokenString)
+	if err != nil {
+		return nil
+	}
+	adminIDStr, ok := claims[impersonationClaim].(string)
+	if !ok {
+		return nil
+	}
+	adminID, err := uuid.Parse(adminIDStr)
+	if err != nil {
+		return nil
+	}
+	return &adminID
+}
+
+// GetImpersonations lists impersonation sessions, newest first, optionally for one user
+func (s *AuthService) GetImpersonations(userID *uuid.UUID, limit int) ([]models.UserImpersonation, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	impersonations := []models.UserImpersonation{}
+	query := `SELECT i.id, i.admin_id, a.email AS admin_email, i.user_id, u.email AS user_email, i.session_id,
+	                 i.reason, i.ip_address, i.user_agent, i.started_at, i.expires_at,
+	                 (SELECT COUNT(*) FROM searches s
+	                  WHERE s.user_id = i.user_id AND s.impersonated_by = i.admin_id
+	                    AND s.search_time >= i.started_at AND s.search_time <= i.expires_at) AS searches
+	          FROM user_impersonations i
+	          JOIN users u ON u.id = i.user_id
+	          LEFT JOIN users a ON a.id = i.admin_id
+	          WHERE $1::uuid IS NULL OR i.user_id = $1
+	          ORDER BY i.started_at DESC
+	          LIMIT $2`
+	if err := database.PostgresDB.Select(&impersonations, query, userID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get impersonations: %w", err)
+	}
+	return impersonations, nil
+}
@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrImportRowsNotFound is returned when no row in the people table carries an import job ID
+var ErrImportRowsNotFound = errors.New("no rows found for this import job")
+
+// ErrImportErrorsNotFound is returned when an import job has no error file, because it ran without
+// failed rows or is unknown
+var ErrImportErrorsNotFound = errors.New("no error file found for this import job")
+
+type ImportAuditService struct {
+	db *sqlx.DB
+}
+
+func NewImportAuditService() *ImportAuditService {
+	return &ImportAuditService{
+		db: database.PostgresDB,
+	}
+}
+
+// ResolveImportPath resolves a requested file path against the configured import directory and
+// rejects anything outside it, including paths that escape through ".." or symlinks. Relative
+// paths are taken relative to the import directory.
+func (s *ImportAuditService) ResolveImportPath(requested string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return "", fmt.Errorf("file path is required")
+	}
+
+	importDir, err := filepath.Abs(config.Get().CSV.ImportDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid import directory: %w", err)
+	}
+	importDir, err = filepath.EvalSymlinks(importDir)
+	if err != nil {
+		return "", fmt.Errorf("import directory is not available: %w", err)
+	}
+
+	path := requested
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(importDir, path)
+	}
+	path, err = filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("file not found: %s", requested)
+	}
+
+	rel, err := filepath.Rel(importDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("file path is outside the allowed import directory")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("file not found: %s", requested)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("not a regular file: %s", requested)
+	}
+
+	return path, nil
+}
+
+// FileChecksum returns the SHA-256 checksum and size of a file
+func (s *ImportAuditService) FileChecksum(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to checksum file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+// FindPreviousImport returns the latest import of a file with the same checksum that completed
+// or is still running, or nil if the file has not been imported
+func (s *ImportAuditService) FindPreviousImport(checksum string) (*models.CSVImportAudit, error) {
+	var audit models.CSVImportAudit
+	query := `SELECT * FROM csv_import_audit
+	          WHERE checksum = $1 AND status IN ('STARTED', 'COMPLETED')
+	          ORDER BY started_at DESC LIMIT 1`
+	err := s.db.Get(&audit, query, checksum)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up previous imports: %w", err)
+	}
+	return &audit, nil
+}
+
+// RecordImportStart records an import that is about to run
+func (s *ImportAuditService) RecordImportStart(userID uuid.UUID, requestedPath, resolvedPath, checksum string, fileSize int64, forced bool) (uuid.UUID, error) {
+	id := uuid.New()
+	query := `INSERT INTO csv_import_audit
+	          (id, user_id, requested_path, resolved_path, checksum, file_size, status, forced, started_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, 'STARTED', $7, $8)`
+	_, err := s.db.Exec(query, id, userID, requestedPath, resolvedPath, checksum, fileSize, forced, time.Now())
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record import: %w", err)
+	}
+	return id, nil
+}
+
+// RecordImportSource records the checksum and size of a streamed import, which are only known once
+// the whole file has been read
+func (s *ImportAuditService) RecordImportSource(id uuid.UUID, checksum string, fileSize int64) error {
+	query := `UPDATE csv_import_audit SET checksum = $1, file_size = $2 WHERE id = $3`
+	if _, err := s.db.Exec(query, checksum, fileSize, id); err != nil {
+		return fmt.Errorf("failed to record import checksum: %w", err)
+	}
+	return nil
+}
+
+// RecordImportResult records the outcome of an import
+func (s *ImportAuditService) RecordImportResult(id uuid.UUID, response *models.CSVImportResponse, importErr error) error {
+	status := "COMPLETED"
+	var errorMessage *string
+	if importErr != nil {
+		status = "FAILED"
+		message := importErr.Error()
+		errorMessage = &message
+	}
+
+	var totalRows, processedRows, errorRows int
+	var table *string
+	if response != nil {
+		totalRows, processedRows, errorRows = response.TotalRows, response.ProcessedRows, response.ErrorRows
+		table = &response.Table
+	}
+
+	query := `UPDATE csv_import_audit
+	          SET status = $1, total_rows = $2, processed_rows = $3, error_rows = $4, error_message = $5, completed_at = $6,
+	              people_table = $7
+	          WHERE id = $8`
+	_, err := s.db.Exec(query, status, totalRows, processedRows, errorRows, errorMessage, time.Now(), table, id)
+	if err != nil {
+		return fmt.Errorf("failed to record import result: %w", err)
+	}
+	return nil
+}
+
+// GetImportAudit returns a page of recorded imports, newest first
+func (s *ImportAuditService) GetImportAudit(params models.PageParams) (*models.Paginated[models.CSVImportAudit], error) {
+	var totalCount int
+	if err := s.db.Get(&totalCount, `SELECT COUNT(*) FROM csv_import_audit`); err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	imports := []models.CSVImportAudit{}
+	query := `SELECT * FROM csv_import_audit ORDER BY started_at DESC LIMIT $1 OFFSET $2`
+	if err := s.db.Select(&imports, query, params.Limit, params.Offset()); err != nil {
+		return nil, fmt.Errorf("failed to get import audit: %w", err)
+	}
+
+	return models.NewPaginated(imports, totalCount, params), nil
+}
+
+// ImportTable returns the people table an import job loaded: the table recorded by its audit, if it
+// was an audited import, or else the active people table
+func (s *ImportAuditService) ImportTable(jobID uuid.UUID) (string, error) {
+	var table sql.NullString
+	err := s.db.Get(&table, `SELECT people_table FROM csv_import_audit WHERE id = $1`, jobID)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to look up import: %w", err)
+	}
+	if table.Valid && table.String != "" {
+		return table.String, nil
+	}
+	return database.PeopleTable(), nil
+}
+
+// GetImportRows counts the rows an import job left in a fully qualified people table
+func (s *ImportAuditService) GetImportRows(jobID uuid.UUID, table string) (*models.ImportRowsResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	rows := models.ImportRowsResponse{JobID: jobID.String(), Table: table}
+	query := `SELECT count(), any(source_file), min(imported_at) FROM ` + table + ` WHERE import_job_id = ?`
+	if err := database.ClickHouseDB.QueryRow(ctx, query, jobID.String()).Scan(&rows.RowCount, &rows.SourceFile, &rows.ImportedAt); err != nil {
+		return nil, fmt.Errorf("failed to count import rows: %w", err)
+	}
+	if rows.RowCount == 0 {
+		return nil, ErrImportRowsNotFound
+	}
+	return &rows, nil
+}
+
+// ImportErrorFile returns the path of the file listing the rows an import job could not load, with
+// the line and the reason for each
+func (s *ImportAuditService) ImportErrorFile(jobID uuid.UUID) (string, error) {
+	path := utils.ImportErrorFilePath(jobID.String())
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", ErrImportErrorsNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open error file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", ErrImportErrorsNotFound
+	}
+	return path, nil
+}
+
+// DeleteImportRows rolls back an import by deleting every row carrying its job ID with a lightweight
+// DELETE, which hides them from searches at once, and discarding the changes it staged. An audited
+// import is marked ROLLED_BACK, so its file can be imported again without forcing.
+func (s *ImportAuditService) DeleteImportRows(jobID uuid.UUID, table string, deletedBy uuid.UUID) (*models.ImportRowsResponse, error) {
+	rows, err := s.GetImportRows(jobID, table)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	if err := database.ClickHouseDB.Exec(ctx, `DELETE FROM `+table+` WHERE import_job_id = ?`, jobID.String()); err != nil {
+		return nil, fmt.Errorf("failed to delete import rows: %w", err)
+	}
+	rows.Deleted = true
+
+	// Put back the rows an import with update_existing replaced
+	restored, err := database.RestorePeopleRows(ctx, table, jobID.String())
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to restore the rows replaced by import %s", jobID), err)
+	}
+	rows.RestoredRows = restored
+
+	// Changes a delta import staged would otherwise still be applicable after it was rolled back
+	discarded, err := database.DiscardStagedPeopleRows(ctx, jobID.String(), nil)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to discard the changes staged by import %s", jobID), err)
+	}
+	rows.DiscardedChanges = discarded
+
+	query := `UPDATE csv_import_audit
+	          SET status = 'ROLLED_BACK', rows_rolled_back = rows_rolled_back + $2, rolled_back_by = $3, rolled_back_at = now()
+	          WHERE id = $1`
+	if _, err := s.db.Exec(query, jobID, rows.RowCount, deletedBy); err != nil {
+		utils.LogError("Failed to mark import as rolled back", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Rolled back import %s: deleted %d rows of %s from %s, restored %d replaced rows",
+		jobID, rows.RowCount, rows.SourceFile, table, restored))
+	return rows, nil
+}
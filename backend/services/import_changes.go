@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNoStagedChanges is returned when an import has no staged changes matching a request
+	ErrNoStagedChanges = errors.New("no staged changes found for this import job")
+	// ErrInvalidStagedChange is returned when a staged change is selected by an invalid ID
+	ErrInvalidStagedChange = errors.New("invalid staged change ID")
+)
+
+// ImportChangesService reviews the changed rows delta imports staged: listing them next to the rows
+// they would replace, and applying or discarding them
+type ImportChangesService struct{}
+
+func NewImportChangesService() *ImportChangesService {
+	return &ImportChangesService{}
+}
+
+// GetChanges returns a page of the changes an import staged, ordered by row ID, each with the current
+// version of the row it would replace
+func (s *ImportChangesService) GetChanges(jobID uuid.UUID, params models.PageParams) (*models.Paginated[models.StagedChange], error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var total uint64
+	countQuery := `SELECT count() FROM ` + database.PeopleStagingTable + ` WHERE import_job_id = ?`
+	if err := database.ClickHouseDB.QueryRow(ctx, countQuery, jobID.String()).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count staged changes: %w", err)
+	}
+
+	query := `SELECT ` + personColumns + `, ` + provenanceColumns + `, people_table, changed_fields, staged_at
+			  FROM ` + database.PeopleStagingTable + `
+			  WHERE import_job_id = ?
+			  ORDER BY id, staged_at
+			  LIMIT ? OFFSET ?`
+	rows, err := database.ClickHouseDB.Query(ctx, query, jobID.String(), params.Limit, params.Offset())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.StagedChange{}
+	for rows.Next() {
+		var change models.StagedChange
+		p := &change.Incoming
+		if err := rows.Scan(&p.ID, &p.MasterID, &p.Mobile, &p.Name, &p.FName, &p.Address, &p.Alt, &p.Circle, &p.Email,
+			&p.CreatedAt, &p.UpdatedAt, &p.Confidence, &p.QualityFlags, &p.SourceFile, &p.ImportJobID, &p.ImportedAt,
+			&p.MobileRaw, &p.AltRaw, &change.PeopleTable, &change.ChangedFields, &change.StagedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan staged change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read staged changes: %w", err)
+	}
+
+	if err := s.loadCurrent(ctx, changes); err != nil {
+		return nil, err
+	}
+	return models.NewPaginated(changes, int(total), params), nil
+}
+
+// loadCurrent fills in the current version of the row each change would replace, one query per table
+func (s *ImportChangesService) loadCurrent(ctx context.Context, changes []models.StagedChange) error {
+	byTable := make(map[string][]int)
+	for i, change := range changes {
+		byTable[change.PeopleTable] = append(byTable[change.PeopleTable], i)
+	}
+
+	for table, indexes := range byTable {
+		ids := make([]string, len(indexes))
+		for i, index := range indexes {
+			ids[i] = changes[index].Incoming.ID
+		}
+
+		idList, args := database.InList(ids)
+		query := `SELECT ` + personColumns + `, ` + provenanceColumns + ` FROM ` + table + ` WHERE id IN ` + idList + ` AND import_job_id != ?`
+		rows, err := database.ClickHouseDB.Query(ctx, query, append(args, changes[indexes[0]].Incoming.ImportJobID)...)
+		if err != nil {
+			return fmt.Errorf("failed to get current rows: %w", err)
+		}
+
+		current := make(map[string]models.Person)
+		for rows.Next() {
+			var p models.Person
+			if err := rows.Scan(&p.ID, &p.MasterID, &p.Mobile, &p.Name, &p.FName, &p.Address, &p.Alt, &p.Circle, &p.Email,
+				&p.CreatedAt, &p.UpdatedAt, &p.Confidence, &p.QualityFlags, &p.SourceFile, &p.ImportJobID, &p.ImportedAt,
+				&p.MobileRaw, &p.AltRaw); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan current row: %w", err)
+			}
+			current[p.ID] = p
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read current rows: %w", err)
+		}
+
+		for _, index := range indexes {
+			if p, ok := current[changes[index].Incoming.ID]; ok {
+				changes[index].Current = &p
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyChanges writes the changes an import staged, or only those for the given row IDs, over the
+// rows they replace. The replaced rows go to the person history as replaced by the import, so
+// rolling the import back restores them.
+func (s *ImportChangesService) ApplyChanges(jobID uuid.UUID, ids []string) (*models.StagedChangesResult, error) {
+	if err := validateStagedIDs(ids); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	applied, err := database.ApplyStagedPeopleRows(ctx, jobID.String(), ids, models.PersonChangeImport)
+	if err != nil {
+		return nil, err
+	}
+	if applied == 0 {
+		return nil, ErrNoStagedChanges
+	}
+
+	utils.LogInfo(fmt.Sprintf("Applied %d staged changes of import %s", applied, jobID))
+	return &models.StagedChangesResult{JobID: jobID.String(), Applied: applied}, nil
+}
+
+// DiscardChanges drops the changes an import staged, or only those for the given row IDs
+func (s *ImportChangesService) DiscardChanges(jobID uuid.UUID, ids []string) (*models.StagedChangesResult, error) {
+	if err := validateStagedIDs(ids); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	discarded, err := database.DiscardStagedPeopleRows(ctx, jobID.String(), ids)
+	if err != nil {
+		return nil, err
+	}
+	if discarded == 0 {
+		return nil, ErrNoStagedChanges
+	}
+
+	utils.LogInfo(fmt.Sprintf("Discarded %d staged changes of import %s", discarded, jobID))
+	return &models.StagedChangesResult{JobID: jobID.String(), Discarded: discarded}, nil
+}
+
+// validateStagedIDs checks that staged changes are selected by row UUIDs
+func validateStagedIDs(ids []string) error {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidStagedChange, id)
+		}
+	}
+	return nil
+}
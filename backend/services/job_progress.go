@@ -0,0 +1,181 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+)
+
+// jobProgressInterval is the least time between running progress events of one job
+const jobProgressInterval = time.Second
+
+// jobProgressBuffer is how many events a subscriber may fall behind before events to it are dropped
+const jobProgressBuffer = 64
+
+// maxProgressErrors caps the latest row errors sent with a progress event
+const maxProgressErrors = 10
+
+// jobProgressHub fans progress events from import and export workers out to WebSocket subscribers
+type jobProgressHub struct {
+	mu          sync.RWMutex
+	subscribers map[*JobProgressSubscription]struct{}
+}
+
+// jobProgress is shared by every request, since jobs run in the handlers of other requests
+var jobProgress = &jobProgressHub{subscribers: make(map[*JobProgressSubscription]struct{})}
+
+// JobProgressSubscription receives the progress events of the jobs a user may follow
+type JobProgressSubscription struct {
+	Events  chan models.JobProgressEvent
+	userID  uuid.UUID
+	allJobs bool
+}
+
+// SubscribeJobProgress streams progress events to a user: their own jobs, or every job with allJobs.
+// Events are dropped rather than holding up a job when the subscriber falls behind.
+func SubscribeJobProgress(userID uuid.UUID, allJobs bool) *JobProgressSubscription {
+	sub := &JobProgressSubscription{
+		Events:  make(chan models.JobProgressEvent, jobProgressBuffer),
+		userID:  userID,
+		allJobs: allJobs,
+	}
+	jobProgress.mu.Lock()
+	jobProgress.subscribers[sub] = struct{}{}
+	jobProgress.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe stops the events of a subscription
+func (s *JobProgressSubscription) Unsubscribe() {
+	jobProgress.mu.Lock()
+	delete(jobProgress.subscribers, s)
+	jobProgress.mu.Unlock()
+}
+
+func (h *jobProgressHub) publish(event models.JobProgressEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subscribers {
+		if !sub.allJobs && sub.userID != event.UserID {
+			continue
+		}
+		select {
+		case sub.Events <- event:
+		default:
+		}
+	}
+}
+
+// JobProgressTracker publishes the progress of one import or export
+type JobProgressTracker struct {
+	mu          sync.Mutex
+	event       models.JobProgressEvent
+	lastPublish time.Time
+}
+
+// TrackJob starts reporting the progress of a job. totalRows is 0 when it is not known in advance.
+func TrackJob(kind, jobID string, userID uuid.UUID, name string, totalRows int) *JobProgressTracker {
+	now := time.Now()
+	t := &JobProgressTracker{event: models.JobProgressEvent{
+		JobID:     jobID,
+		Kind:      kind,
+		Status:    models.JobStatusRunning,
+		UserID:    userID,
+		Name:      name,
+		TotalRows: totalRows,
+		StartedAt: now,
+	}}
+	t.publish(now)
+	return t
+}
+
+// Update reports the rows done so far and the fraction of the job complete, between 0 and 1. Updates
+// within a second of the last one are not published.
+func (t *JobProgressTracker) Update(rows, errorRows int, fraction float64, errors []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.event.RowsProcessed = rows
+	t.event.ErrorRows = errorRows
+	t.event.Errors = latestErrors(errors)
+	if fraction > 1 {
+		fraction = 1
+	}
+	t.event.Percent = roundPercent(fraction * 100)
+	t.event.ETASeconds = nil
+	if fraction > 0 {
+		elapsed := now.Sub(t.event.StartedAt)
+		eta := int((time.Duration(float64(elapsed)/fraction) - elapsed).Seconds())
+		t.event.ETASeconds = &eta
+	}
+
+	if now.Sub(t.lastPublish) >= jobProgressInterval {
+		t.publish(now)
+	}
+}
+
+// Complete reports that the job finished
+func (t *JobProgressTracker) Complete(rows, errorRows int, errors []string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.event.Status = models.JobStatusCompleted
+	t.event.RowsProcessed = rows
+	t.event.ErrorRows = errorRows
+	t.event.Errors = latestErrors(errors)
+	t.event.Percent = 100
+	eta := 0
+	t.event.ETASeconds = &eta
+	t.publish(time.Now())
+}
+
+// Fail reports that the job stopped with an error
+func (t *JobProgressTracker) Fail(err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.event.Status = models.JobStatusFailed
+	t.event.Error = err.Error()
+	t.event.ETASeconds = nil
+	t.publish(time.Now())
+}
+
+// fraction returns the share of the job's known total rows that rows is, or 0 when the total is unknown
+func (t *JobProgressTracker) fraction(rows int) float64 {
+	if t == nil || t.event.TotalRows <= 0 {
+		return 0
+	}
+	return float64(rows) / float64(t.event.TotalRows)
+}
+
+func (t *JobProgressTracker) publish(now time.Time) {
+	t.lastPublish = now
+	event := t.event
+	event.Timestamp = now
+	jobProgress.publish(event)
+}
+
+// latestErrors returns the last maxProgressErrors errors
+func latestErrors(errors []string) []string {
+	if len(errors) > maxProgressErrors {
+		errors = errors[len(errors)-maxProgressErrors:]
+	}
+	return append([]string(nil), errors...)
+}
+
+func roundPercent(percent float64) float64 {
+	return float64(int(percent*10)) / 10
+}
@@ -0,0 +1,470 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusDead      = "dead" // Out of attempts, or failed permanently; kept until retried by an admin
+)
+
+// Job types
+const (
+	JobTypeImport         = "import"
+	JobTypeExport         = "export"
+	JobTypeRetentionPurge = "retention_purge"
+	JobTypeNotification   = "notification"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobNotDead       = errors.New("only dead jobs can be retried")
+	ErrUnknownJobType   = errors.New("unknown job type")
+	ErrInvalidJobStatus = errors.New("status must be queued, running, succeeded or dead")
+)
+
+// JobHandler runs one job. The result, if not nil, is stored with the job when it succeeds. A failed job
+// is retried with backoff until it runs out of attempts, unless the error is a PermanentJobError.
+type JobHandler func(ctx context.Context, job *models.Job) (interface{}, error)
+
+// PermanentJobError marks a job failure that retrying cannot fix, such as an invalid payload; the job
+// goes straight to the dead letters
+type PermanentJobError struct {
+	Err error
+}
+
+func (e *PermanentJobError) Error() string { return e.Err.Error() }
+
+func (e *PermanentJobError) Unwrap() error { return e.Err }
+
+// DeferJobError asks for a job to be run again later without using up an attempt, e.g. while another
+// import holds the import slot
+type DeferJobError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *DeferJobError) Error() string { return e.Err.Error() }
+
+func (e *DeferJobError) Unwrap() error { return e.Err }
+
+var (
+	jobHandlersMu sync.RWMutex
+	jobHandlers   = map[string]JobHandler{
+		JobTypeExport:         runExportJob,
+		JobTypeRetentionPurge: runRetentionPurgeJob,
+		JobTypeNotification:   runNotificationJob,
+	}
+)
+
+// RegisterJobHandler makes a job type runnable by the queue workers
+func RegisterJobHandler(jobType string, handler JobHandler) {
+	jobHandlersMu.Lock()
+	defer jobHandlersMu.Unlock()
+	jobHandlers[jobType] = handler
+}
+
+func jobHandlerFor(jobType string) (JobHandler, bool) {
+	jobHandlersMu.RLock()
+	defer jobHandlersMu.RUnlock()
+	handler, ok := jobHandlers[jobType]
+	return handler, ok
+}
+
+// jobWorkerID names the workers of this instance in locked_by
+var jobWorkerID = func() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "finone"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+// JobQueueService runs background work from the jobs table. Workers on every instance claim queued
+// jobs with FOR UPDATE SKIP LOCKED, so each job runs once however many instances share the queue.
+type JobQueueService struct{}
+
+func NewJobQueueService() *JobQueueService {
+	return &JobQueueService{}
+}
+
+// Enqueue queues a job to run as soon as a worker is free. maxAttempts 0 uses jobs.max_attempts. Jobs
+// of a type no handler is registered for are dead-lettered when claimed.
+func (s *JobQueueService) Enqueue(jobType string, payload interface{}, createdBy *uuid.UUID, maxAttempts int) (*models.Job, error) {
+	if maxAttempts <= 0 {
+		maxAttempts = config.Get().Jobs.MaxAttempts
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	var job models.Job
+	query := `INSERT INTO jobs (type, payload, max_attempts, created_by)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING *`
+	if err := database.PostgresDB.Get(&job, query, jobType, data, maxAttempts, createdBy); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	return &job, nil
+}
+
+// ListJobs returns a page of jobs, newest first, optionally of one status and type
+func (s *JobQueueService) ListJobs(status, jobType string, params models.PageParams) (*models.Paginated[models.Job], error) {
+	var conditions []string
+	var args []interface{}
+	if status != "" {
+		switch status {
+		case JobStatusQueued, JobStatusRunning, JobStatusSucceeded, JobStatusDead:
+		default:
+			return nil, ErrInvalidJobStatus
+		}
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if jobType != "" {
+		args = append(args, jobType)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var totalCount int
+	if err := database.PostgresDB.Get(&totalCount, `SELECT COUNT(*) FROM jobs `+where, args...); err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	jobs := []models.Job{}
+	query := fmt.Sprintf(`SELECT * FROM jobs %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	if err := database.PostgresDB.Select(&jobs, query, append(args, params.Limit, params.Offset())...); err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return models.NewPaginated(jobs, totalCount, params), nil
+}
+
+// GetJob returns a job by ID
+func (s *JobQueueService) GetJob(id string) (*models.Job, error) {
+	jobID, err := uuid.Parse(strings.TrimSpace(id))
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+
+	var job models.Job
+	err = database.PostgresDB.Get(&job, `SELECT * FROM jobs WHERE id = $1`, jobID)
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return &job, nil
+}
+
+// RetryJob queues a dead job again with a fresh set of attempts
+func (s *JobQueueService) RetryJob(id string) (*models.Job, error) {
+	job, err := s.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE jobs
+			  SET status = $1, attempts = 0, run_at = now(), locked_by = NULL, locked_at = NULL,
+			      finished_at = NULL, updated_at = now()
+			  WHERE id = $2 AND status = $3
+			  RETURNING *`
+	err = database.PostgresDB.Get(job, query, JobStatusQueued, job.ID, JobStatusDead)
+	if err == sql.ErrNoRows {
+		return nil, ErrJobNotDead
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry job: %w", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Job %s (%s) queued again for retry", job.ID, job.Type))
+	return job, nil
+}
+
+// StartWorkers starts jobs.workers workers and a reaper that takes back jobs whose worker stopped
+// heartbeating, e.g. because its instance was restarted mid-job
+func (s *JobQueueService) StartWorkers() {
+	cfg := config.Get().Jobs
+	utils.LogInfo(fmt.Sprintf("Starting %d job queue workers...", cfg.Workers))
+
+	for i := 0; i < cfg.Workers; i++ {
+		go s.work(fmt.Sprintf("%s/%d", jobWorkerID, i))
+	}
+
+	go func() {
+		ticker := time.NewTicker(cfg.StaleAfter / 2)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.reapStaleJobs()
+		}
+	}()
+}
+
+// work runs jobs one at a time, polling when the queue is empty
+func (s *JobQueueService) work(workerID string) {
+	for {
+		job, err := s.claim(workerID)
+		if err != nil {
+			utils.LogError("Failed to claim job", err)
+		}
+		if job == nil {
+			time.Sleep(config.Get().Jobs.PollInterval)
+			continue
+		}
+		s.run(workerID, job)
+	}
+}
+
+// claim takes the next due job, skipping jobs other workers are claiming
+func (s *JobQueueService) claim(workerID string) (*models.Job, error) {
+	var job models.Job
+	query := `UPDATE jobs
+			  SET status = $1, attempts = attempts + 1, locked_by = $2, locked_at = now(), updated_at = now()
+			  WHERE id = (
+			      SELECT id FROM jobs
+			      WHERE status = $3 AND run_at <= now()
+			      ORDER BY run_at
+			      LIMIT 1
+			      FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING *`
+	err := database.PostgresDB.Get(&job, query, JobStatusRunning, workerID, JobStatusQueued)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// run runs a claimed job, heartbeating while it runs, and records how it ended
+func (s *JobQueueService) run(workerID string, job *models.Job) {
+	handler, ok := jobHandlerFor(job.Type)
+	if !ok {
+		s.fail(job, &PermanentJobError{Err: fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type)})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.heartbeat(ctx, workerID, job.ID)
+
+	utils.LogInfo(fmt.Sprintf("Running job %s (%s), attempt %d of %d", job.ID, job.Type, job.Attempts, job.MaxAttempts))
+	result, err := s.call(ctx, handler, job)
+	if err != nil {
+		s.fail(job, err)
+		return
+	}
+	s.succeed(job, result)
+}
+
+// call runs a job handler, turning a panic into a failure of the job
+func (s *JobQueueService) call(ctx context.Context, handler JobHandler, job *models.Job) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// heartbeat keeps a running job's lock fresh so the reaper leaves it alone
+func (s *JobQueueService) heartbeat(ctx context.Context, workerID string, jobID uuid.UUID) {
+	ticker := time.NewTicker(config.Get().Jobs.StaleAfter / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			query := `UPDATE jobs SET locked_at = now() WHERE id = $1 AND locked_by = $2 AND status = $3`
+			if _, err := database.PostgresDB.Exec(query, jobID, workerID, JobStatusRunning); err != nil {
+				utils.LogError(fmt.Sprintf("Failed to heartbeat job %s", jobID), err)
+			}
+		}
+	}
+}
+
+func (s *JobQueueService) succeed(job *models.Job, result interface{}) {
+	var data []byte
+	if result != nil {
+		var err error
+		if data, err = json.Marshal(result); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to encode result of job %s", job.ID), err)
+			data = nil
+		}
+	}
+
+	query := `UPDATE jobs
+			  SET status = $1, result = $2, last_error = NULL, locked_by = NULL, locked_at = NULL,
+			      finished_at = now(), updated_at = now()
+			  WHERE id = $3`
+	if _, err := database.PostgresDB.Exec(query, JobStatusSucceeded, data, job.ID); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to record success of job %s", job.ID), err)
+		return
+	}
+	utils.LogInfo(fmt.Sprintf("Job %s (%s) succeeded", job.ID, job.Type))
+}
+
+// fail queues a failed job again after a backoff or, when it cannot succeed, dead-letters it
+func (s *JobQueueService) fail(job *models.Job, jobErr error) {
+	message := jobErr.Error()
+
+	var deferErr *DeferJobError
+	if errors.As(jobErr, &deferErr) {
+		query := `UPDATE jobs
+				  SET status = $1, attempts = attempts - 1, run_at = $2, last_error = $3, locked_by = NULL,
+				      locked_at = NULL, updated_at = now()
+				  WHERE id = $4`
+		after := deferErr.After
+		if after <= 0 {
+			after = config.Get().Jobs.PollInterval
+		}
+		if _, err := database.PostgresDB.Exec(query, JobStatusQueued, time.Now().Add(after), message, job.ID); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to defer job %s", job.ID), err)
+		}
+		return
+	}
+
+	var permanent *PermanentJobError
+	if errors.As(jobErr, &permanent) || job.Attempts >= job.MaxAttempts {
+		query := `UPDATE jobs
+				  SET status = $1, last_error = $2, locked_by = NULL, locked_at = NULL, finished_at = now(),
+				      updated_at = now()
+				  WHERE id = $3`
+		if _, err := database.PostgresDB.Exec(query, JobStatusDead, message, job.ID); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to dead-letter job %s", job.ID), err)
+		}
+		utils.LogError(fmt.Sprintf("Job %s (%s) failed after %d attempts and was dead-lettered", job.ID, job.Type, job.Attempts), jobErr)
+		return
+	}
+
+	runAt := time.Now().Add(jobRetryBackoff(job.Attempts))
+	query := `UPDATE jobs
+			  SET status = $1, run_at = $2, last_error = $3, locked_by = NULL, locked_at = NULL, updated_at = now()
+			  WHERE id = $4`
+	if _, err := database.PostgresDB.Exec(query, JobStatusQueued, runAt, message, job.ID); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to requeue job %s", job.ID), err)
+	}
+	utils.LogWarning(fmt.Sprintf("Job %s (%s) failed on attempt %d, retrying at %s: %v",
+		job.ID, job.Type, job.Attempts, runAt.Format(time.RFC3339), jobErr))
+}
+
+// jobRetryBackoff is the wait before the retry after a job's attempt-th failed attempt:
+// jobs.retry_backoff, doubled for each further attempt up to a day
+func jobRetryBackoff(attempt int) time.Duration {
+	backoff := config.Get().Jobs.RetryBackoff
+	for i := 1; i < attempt && backoff < 24*time.Hour; i++ {
+		backoff *= 2
+	}
+	if backoff > 24*time.Hour {
+		backoff = 24 * time.Hour
+	}
+	return backoff
+}
+
+// reapStaleJobs requeues running jobs whose worker stopped heartbeating, or dead-letters them when
+// they have no attempts left
+func (s *JobQueueService) reapStaleJobs() {
+	staleBefore := time.Now().Add(-config.Get().Jobs.StaleAfter)
+	query := `UPDATE jobs
+			  SET status = CASE WHEN attempts >= max_attempts THEN $1 ELSE $2 END,
+			      finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+			      last_error = 'worker ' || COALESCE(locked_by, '') || ' stopped while running the job',
+			      locked_by = NULL, locked_at = NULL, run_at = now(), updated_at = now()
+			  WHERE status = $3 AND locked_at < $4`
+	result, err := database.PostgresDB.Exec(query, JobStatusDead, JobStatusQueued, JobStatusRunning, staleBefore)
+	if err != nil {
+		utils.LogError("Failed to reap stale jobs", err)
+		return
+	}
+	if reaped, _ := result.RowsAffected(); reaped > 0 {
+		utils.LogWarning(fmt.Sprintf("Took back %d jobs whose worker stopped", reaped))
+	}
+}
+
+// decodeJobPayload decodes a job's payload; a payload that does not decode can never run
+func decodeJobPayload(job *models.Job, payload interface{}) error {
+	if err := json.Unmarshal(job.Payload, payload); err != nil {
+		return &PermanentJobError{Err: fmt.Errorf("invalid %s job payload: %w", job.Type, err)}
+	}
+	return nil
+}
+
+// ExportJobPayload is the payload of an async export
+type ExportJobPayload struct {
+	UserID  uuid.UUID            `json:"user_id"`
+	Request models.ExportRequest `json:"request"`
+}
+
+// runExportJob writes an async export. Only failures to reach ClickHouse are retried; anything else,
+// such as the user's export quota running out, would fail again.
+func runExportJob(ctx context.Context, job *models.Job) (interface{}, error) {
+	var payload ExportJobPayload
+	if err := decodeJobPayload(job, &payload); err != nil {
+		return nil, err
+	}
+
+	response, err := NewExportService().Export(payload.UserID, &payload.Request)
+	if err != nil {
+		if database.IsTransientError(err) || errors.Is(err, database.ErrClickHouseUnavailable) {
+			return nil, err
+		}
+		return nil, &PermanentJobError{Err: err}
+	}
+	return response, nil
+}
+
+// runRetentionPurgeJob runs every enabled retention policy, waiting its turn behind a purge that is
+// already running
+func runRetentionPurgeJob(ctx context.Context, job *models.Job) (interface{}, error) {
+	purges, err := NewRetentionService().Purge("", nil)
+	if errors.Is(err, ErrPurgeRunning) {
+		return nil, &DeferJobError{Err: err, After: time.Minute}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return purges, nil
+}
+
+// runNotificationJob sends a rendered notification email
+func runNotificationJob(ctx context.Context, job *models.Job) (interface{}, error) {
+	var msg EmailMessage
+	if err := decodeJobPayload(job, &msg); err != nil {
+		return nil, err
+	}
+
+	provider, err := newEmailProvider(config.Get().Notifications)
+	if err != nil {
+		return nil, &PermanentJobError{Err: err}
+	}
+	return nil, provider.Send(&msg)
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrJWTKeyNotFound      = errors.New("signing key not found")
+	ErrJWTKeyActive        = errors.New("the active signing key cannot be retired; rotate to a new key first")
+	ErrConfigJWTKey        = errors.New("the configured jwt.secret cannot be retired here; it stops being accepted once the tokens it signed expire, or change jwt.secret to reject them now")
+	ErrInvalidJWTAlgorithm = errors.New("algorithm must be HS256 or RS256")
+)
+
+// jwtKeysRefreshInterval is how often each instance reloads the keyset, so rotations made through
+// another instance are picked up
+const jwtKeysRefreshInterval = time.Minute
+
+// jwtKeysMissInterval is the least time between reloads caused by tokens with an unknown kid
+const jwtKeysMissInterval = 5 * time.Second
+
+// rsaKeyBits is the size of generated RS256 keys
+const rsaKeyBits = 2048
+
+// jwtKey is a parsed signing key
+type jwtKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	signingKey any // []byte or *rsa.PrivateKey
+	verifyKey  any // []byte or *rsa.PublicKey
+}
+
+// jwtKeySet caches the signing keys still accepted, shared by every request
+type jwtKeySet struct {
+	mu       sync.RWMutex
+	loaded   bool
+	loadedAt time.Time
+	keys     map[string]*jwtKey // By kid
+	signing  *jwtKey            // nil while jwt.secret signs tokens
+	// configUntil is when tokens signed with jwt.secret stop being accepted: once the tokens signed
+	// before the first rotation have expired. nil until a key is rotated in.
+	configUntil *time.Time
+}
+
+var jwtKeys = &jwtKeySet{}
+
+// maxTokenLifetime is how long any token stays valid, so how long a rotated key must keep verifying
+func maxTokenLifetime() time.Duration {
+	cfg := config.Get()
+	if cfg.JWT.ImpersonationExpiry > cfg.JWT.Expiry {
+		return cfg.JWT.ImpersonationExpiry
+	}
+	return cfg.JWT.Expiry
+}
+
+// rotatedKeyLifetime is how long a rotated key keeps verifying: the longest token lifetime, plus the
+// time other instances may go on signing with it before they reload the keyset
+func rotatedKeyLifetime() time.Duration {
+	return maxTokenLifetime() + jwtKeysRefreshInterval
+}
+
+// load reads the keys from PostgreSQL, keeping the previous keyset if that fails
+func (ks *jwtKeySet) load() error {
+	var rows []models.JWTSigningKey
+	err := database.PostgresDB.Select(&rows, `SELECT * FROM jwt_signing_keys ORDER BY created_at`)
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.loadedAt = time.Now()
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	keys := make(map[string]*jwtKey, len(rows))
+	var signing *jwtKey
+	var configUntil *time.Time
+	now := time.Now()
+	for i := range rows {
+		row := &rows[i]
+		if i == 0 {
+			until := row.CreatedAt.Add(rotatedKeyLifetime())
+			configUntil = &until
+		}
+		status := jwtKeyStatus(row, now)
+		if status == models.JWTKeyRetired || status == models.JWTKeyExpired {
+			continue
+		}
+		key, err := parseJWTKey(row)
+		if err != nil {
+			utils.LogError("Skipping unreadable signing key "+row.KID, err)
+			continue
+		}
+		keys[row.KID] = key
+		if status == models.JWTKeyActive {
+			signing = key
+		}
+	}
+
+	ks.loaded = true
+	ks.keys = keys
+	ks.signing = signing
+	ks.configUntil = configUntil
+	return nil
+}
+
+// refresh reloads the keyset when it is older than maxAge. Failed loads are retried at most every
+// jwtKeysMissInterval, with jwt.secret alone accepted until one succeeds.
+func (ks *jwtKeySet) refresh(maxAge time.Duration) {
+	ks.mu.RLock()
+	age := time.Since(ks.loadedAt)
+	skip := (ks.loaded && age < maxAge) || (!ks.loaded && age < jwtKeysMissInterval)
+	ks.mu.RUnlock()
+	if skip {
+		return
+	}
+	if err := ks.load(); err != nil {
+		utils.LogError("Failed to refresh JWT signing keys", err)
+	}
+}
+
+// signingKey returns the key new tokens are signed with, or nil for jwt.secret
+func (ks *jwtKeySet) signingKey() *jwtKey {
+	ks.refresh(jwtKeysRefreshInterval)
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.signing
+}
+
+// verificationKey returns the accepted key with the given kid, reloading the keyset once in a while
+// when the kid is unknown, since another instance may just have rotated it in
+func (ks *jwtKeySet) verificationKey(kid string) *jwtKey {
+	ks.refresh(jwtKeysRefreshInterval)
+	ks.mu.RLock()
+	key := ks.keys[kid]
+	ks.mu.RUnlock()
+	if key != nil {
+		return key
+	}
+
+	ks.refresh(jwtKeysMissInterval)
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.keys[kid]
+}
+
+// configKeyAccepted reports whether tokens signed with jwt.secret are still accepted
+func (ks *jwtKeySet) configKeyAccepted(now time.Time) bool {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.configUntil == nil || now.Before(*ks.configUntil)
+}
+
+// signJWT signs claims with the active key, adding its kid to the header
+func signJWT(claims jwt.MapClaims) (string, error) {
+	key := jwtKeys.signingKey()
+	if key == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Get().JWT.Secret))
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.signingKey)
+}
+
+// jwtVerificationKey is the jwt.Keyfunc that finds the key a token was signed with by its kid header
+func jwtVerificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		jwtKeys.refresh(jwtKeysRefreshInterval)
+		if !jwtKeys.configKeyAccepted(time.Now()) {
+			return nil, fmt.Errorf("token signed with a retired key")
+		}
+		return []byte(config.Get().JWT.Secret), nil
+	}
+
+	key := jwtKeys.verificationKey(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown or retired signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.verifyKey, nil
+}
+
+func jwtKeyStatus(key *models.JWTSigningKey, now time.Time) string {
+	switch {
+	case key.RetiredAt != nil:
+		return models.JWTKeyRetired
+	case key.RotatedAt == nil:
+		return models.JWTKeyActive
+	case key.VerifyUntil != nil && !now.Before(*key.VerifyUntil):
+		return models.JWTKeyExpired
+	default:
+		return models.JWTKeyVerifying
+	}
+}
+
+func parseJWTKey(row *models.JWTSigningKey) (*jwtKey, error) {
+	switch row.Algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		secret, err := base64.StdEncoding.DecodeString(row.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HMAC secret: %w", err)
+		}
+		return &jwtKey{kid: row.KID, method: jwt.SigningMethodHS256, signingKey: secret, verifyKey: secret}, nil
+	case jwt.SigningMethodRS256.Alg():
+		private, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(row.Secret))
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA private key: %w", err)
+		}
+		return &jwtKey{kid: row.KID, method: jwt.SigningMethodRS256, signingKey: private, verifyKey: &private.PublicKey}, nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm %q", row.Algorithm)
+}
+
+// JWTKeyService rotates the keys that sign session tokens. Rotating starts signing with a new key
+// while the old one keeps verifying the tokens it signed until they expire, so sessions are not all
+// logged out at once; retiring a key rejects its tokens immediately.
+type JWTKeyService struct{}
+
+func NewJWTKeyService() *JWTKeyService {
+	return &JWTKeyService{}
+}
+
+// ListKeys returns every signing key, newest first, and the status of jwt.secret
+func (s *JWTKeyService) ListKeys() (*models.JWTKeyListResponse, error) {
+	keys := []models.JWTSigningKey{}
+	if err := database.PostgresDB.Select(&keys, `SELECT * FROM jwt_signing_keys ORDER BY created_at DESC`); err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+
+	now := time.Now()
+	for i := range keys {
+		keys[i].Status = jwtKeyStatus(&keys[i], now)
+	}
+
+	response := &models.JWTKeyListResponse{
+		Keys:      keys,
+		ConfigKey: models.ConfigJWTKey{KID: models.ConfigJWTKeyID, Algorithm: jwt.SigningMethodHS256.Alg(), Status: models.JWTKeyActive},
+	}
+	if len(keys) > 0 {
+		first := keys[len(keys)-1].CreatedAt
+		until := first.Add(rotatedKeyLifetime())
+		response.ConfigKey.RotatedAt = &first
+		response.ConfigKey.VerifyUntil = &until
+		response.ConfigKey.Status = models.JWTKeyVerifying
+		if !now.Before(until) {
+			response.ConfigKey.Status = models.JWTKeyExpired
+		}
+	}
+	return response, nil
+}
+
+// RotateKey generates a new signing key and makes it the active one. The previous key keeps
+// verifying for the longest token lifetime.
+func (s *JWTKeyService) RotateKey(req *models.RotateJWTKeyRequest, adminID uuid.UUID) (*models.JWTSigningKey, error) {
+	algorithm := strings.ToUpper(strings.TrimSpace(req.Algorithm))
+	if algorithm == "" {
+		algorithm = jwt.SigningMethodHS256.Alg()
+	}
+	secret, err := generateJWTSecret(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := generateKID()
+	if err != nil {
+		return nil, err
+	}
+
+	var key models.JWTSigningKey
+	err = database.WithTransaction(func(tx *sqlx.Tx) error {
+		// Serialize rotations so only one key is ever active
+		if _, err := tx.Exec(`LOCK TABLE jwt_signing_keys IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return fmt.Errorf("failed to lock signing keys: %w", err)
+		}
+		_, err := tx.Exec(`UPDATE jwt_signing_keys SET rotated_at = now(), verify_until = now() + $1 * interval '1 second'
+			WHERE rotated_at IS NULL AND retired_at IS NULL`, int64(rotatedKeyLifetime().Seconds()))
+		if err != nil {
+			return fmt.Errorf("failed to rotate out the active key: %w", err)
+		}
+		err = tx.Get(&key, `INSERT INTO jwt_signing_keys (kid, algorithm, secret, created_by)
+			VALUES ($1, $2, $3, $4) RETURNING *`, kid, algorithm, secret, adminID)
+		if err != nil {
+			return fmt.Errorf("failed to create signing key: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jwtKeys.load(); err != nil {
+		utils.LogError("Failed to reload JWT signing keys after rotation", err)
+	}
+	utils.LogInfo(fmt.Sprintf("JWT signing key rotated to %s (%s) by %s", key.KID, key.Algorithm, adminID))
+	key.Status = models.JWTKeyActive
+	return &key, nil
+}
+
+// RetireKey stops accepting the tokens signed with a rotated-out key, logging their sessions out
+func (s *JWTKeyService) RetireKey(kid string) (*models.JWTSigningKey, error) {
+	if kid == models.ConfigJWTKeyID {
+		return nil, ErrConfigJWTKey
+	}
+
+	var key models.JWTSigningKey
+	err := database.PostgresDB.Get(&key, `UPDATE jwt_signing_keys SET retired_at = COALESCE(retired_at, now())
+		WHERE kid = $1 AND rotated_at IS NOT NULL RETURNING *`, kid)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := database.PostgresDB.Get(&exists, `SELECT EXISTS (SELECT 1 FROM jwt_signing_keys WHERE kid = $1)`, kid); err != nil {
+			return nil, fmt.Errorf("failed to get signing key: %w", err)
+		}
+		if exists {
+			return nil, ErrJWTKeyActive
+		}
+		return nil, ErrJWTKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retire signing key: %w", err)
+	}
+
+	if err := jwtKeys.load(); err != nil {
+		utils.LogError("Failed to reload JWT signing keys after retiring a key", err)
+	}
+	utils.LogInfo("JWT signing key retired: " + kid)
+	key.Status = models.JWTKeyRetired
+	return &key, nil
+}
+
+// JWKS returns the public keys of the RS256 keys still accepted, so other services can verify
+// session tokens without the secrets
+func (s *JWTKeyService) JWKS() *models.JSONWebKeySet {
+	jwtKeys.refresh(jwtKeysRefreshInterval)
+	jwtKeys.mu.RLock()
+	defer jwtKeys.mu.RUnlock()
+
+	set := &models.JSONWebKeySet{Keys: []models.JSONWebKey{}}
+	for _, key := range jwtKeys.keys {
+		public, ok := key.verifyKey.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		set.Keys = append(set.Keys, models.JSONWebKey{
+			KeyType:   "RSA",
+			KID:       key.kid,
+			Algorithm: key.method.Alg(),
+			Use:       "sig",
+			Modulus:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+			Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		})
+	}
+	return set
+}
+
+// generateJWTSecret returns a new key for algorithm as stored in jwt_signing_keys
+func generateJWTSecret(algorithm string) (string, error) {
+	switch algorithm {
+	case jwt.SigningMethodHS256.Alg():
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return "", fmt.Errorf("failed to generate secret: %w", err)
+		}
+		return base64.StdEncoding.EncodeToString(secret), nil
+	case jwt.SigningMethodRS256.Alg():
+		private, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode RSA key: %w", err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+	}
+	return "", ErrInvalidJWTAlgorithm
+}
+
+func generateKID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key ID: %w", err)
+	}
+	return time.Now().Format("20060102") + "-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of login origin remembered per account
+const (
+	loginOriginDevice  = "device"
+	loginOriginCountry = "country"
+)
+
+// LoginClient is the client a session is created for
+type LoginClient struct {
+	IPAddress string
+	UserAgent string
+	Location  utils.GeoLocation // Empty fields are unknown
+}
+
+// locate fills in the client's location from GeoIP. A country already set, from a trusted proxy's
+// header, is kept, along with the GeoIP city only when GeoIP agrees on the country.
+func (c *LoginClient) locate() {
+	location := utils.LookupGeoIP(c.IPAddress)
+	if c.Location.Country != "" {
+		if location.Country != c.Location.Country {
+			location.City = ""
+		}
+		location.Country = c.Location.Country
+	}
+	c.Location = location
+}
+
+// checkLoginOrigin remembers the device and country of a login and, when the account has logged in
+// before from other devices or countries but not this one, emails the user and sends a
+// login.new_device event. An account's first login is only remembered. It reports whether the
+// country was new, which marks the session as from a new location.
+func (s *AuthService) checkLoginOrigin(user *models.User, sessionID uuid.UUID, client LoginClient) bool {
+	device := utils.ParseUserAgent(client.UserAgent)
+	newDevice, err := rememberLoginOrigin(user.ID, loginOriginDevice, device.Fingerprint(), device.String())
+	if err != nil {
+		utils.LogError("Failed to record login device", err)
+		return false
+	}
+
+	newCountry := false
+	country := client.Location.Country
+	if country != "" {
+		newCountry, err = rememberLoginOrigin(user.ID, loginOriginCountry, country, country)
+		if err != nil {
+			utils.LogError("Failed to record login country", err)
+		}
+	}
+	if !newDevice && !newCountry {
+		return false
+	}
+	if newCountry {
+		_, err := database.PostgresDB.Exec(`UPDATE user_sessions SET new_location = true WHERE id = $1`, sessionID)
+		if err != nil {
+			utils.LogError("Failed to flag session from a new location", err)
+		}
+	}
+
+	utils.LogWarning(fmt.Sprintf("Login for user %s from a new %s: %s, %s, %s",
+		user.ID, newLoginOriginKind(newDevice, newCountry), device, client.IPAddress, describeLocation(client.Location)))
+
+	NewNotificationService().NotifyNewLogin(user, device, client, newDevice, newCountry)
+	NewWebhookService().Dispatch(WebhookEventLoginNewDevice, map[string]interface{}{
+		"user_id":     user.ID,
+		"session_id":  sessionID,
+		"ip_address":  client.IPAddress,
+		"browser":     device.Browser,
+		"os":          device.OS,
+		"device_type": device.DeviceType,
+		"country":     country,
+		"city":        client.Location.City,
+		"asn":         client.Location.ASN,
+		"as_org":      client.Location.ASOrg,
+		"new_device":  newDevice,
+		"new_country": newCountry,
+	})
+	return newCountry
+}
+
+// rememberLoginOrigin records that an account logged in from a device or country. It reports whether
+// that is new for an account that already had one of that kind recorded.
+func rememberLoginOrigin(userID uuid.UUID, kind, value, description string) (bool, error) {
+	var known int
+	err := database.PostgresDB.Get(&known, `SELECT COUNT(*) FROM user_login_origins WHERE user_id = $1 AND kind = $2`, userID, kind)
+	if err != nil {
+		return false, err
+	}
+
+	var inserted bool
+	query := `INSERT INTO user_login_origins (user_id, kind, value, description, first_seen_at, last_seen_at)
+	          VALUES ($1, $2, $3, $4, $5, $5)
+	          ON CONFLICT (user_id, kind, value) DO UPDATE SET last_seen_at = EXCLUDED.last_seen_at
+	          RETURNING xmax = 0`
+	if err := database.PostgresDB.Get(&inserted, query, userID, kind, value, description, time.Now()); err != nil {
+		return false, err
+	}
+	return inserted && known > 0, nil
+}
+
+// describeLocation describes a location for people, e.g. "Mumbai, IN (AS9829 BSNL)"
+func describeLocation(location utils.GeoLocation) string {
+	description := location.Country
+	if description == "" {
+		description = "unknown country"
+	}
+	if location.City != "" {
+		description = location.City + ", " + description
+	}
+	if location.ASN != 0 {
+		description += fmt.Sprintf(" (AS%d %s)", location.ASN, location.ASOrg)
+	}
+	return description
+}
+
+func newLoginOriginKind(newDevice, newCountry bool) string {
+	switch {
+	case newDevice && newCountry:
+		return "device and country"
+	case newCountry:
+		return "country"
+	default:
+		return "device"
+	}
+}
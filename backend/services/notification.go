@@ -0,0 +1,364 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// Notification events, used as keys of notifications.events in the config
+const (
+	EventRegistrationReceived   = "registration_received"
+	EventRegistrationVerify     = "registration_verify"
+	EventRegistrationApproved   = "registration_approved"
+	EventRegistrationRejected   = "registration_rejected"
+	EventUserCreated            = "user_created"
+	EventPasswordChangeResolved = "password_change_resolved"
+	EventQuotaNearlyExhausted   = "quota_nearly_exhausted"
+	EventExportReady            = "export_ready"
+	EventUserUpgraded           = "user_upgraded"
+	EventNewLogin               = "new_login"
+)
+
+type notificationTemplate struct {
+	subject *texttemplate.Template
+	body    *template.Template
+}
+
+const emailLayout = `<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #1f2933; line-height: 1.5;">
+  <div style="max-width: 560px; margin: 0 auto; padding: 24px;">
+    <h2 style="color: #0b5394;">FinOne Search</h2>
+    {{template "content" .}}
+    <p style="color: #7b8794; font-size: 12px; margin-top: 32px;">This is an automated message, please do not reply.</p>
+  </div>
+</body>
+</html>`
+
+// notificationTemplates holds the subject and HTML body of every event
+var notificationTemplates = map[string]notificationTemplate{
+	EventRegistrationReceived: newNotificationTemplate("Registration request received", `
+<p>Hi {{.Name}},</p>
+<p>We have received your request for access to FinOne Search. An administrator will review it shortly and you will be notified by email once it has been processed.</p>
+{{if .Admin}}<p>Applicant: {{.Email}}, {{.PhoneNumber}} ({{.RequestedSearches}} searches per day requested)</p>{{end}}`),
+
+	EventRegistrationVerify: newNotificationTemplate("Confirm your email address", `
+<p>Hi {{.Name}},</p>
+<p>Please confirm your email address to submit your request for access to FinOne Search.</p>
+<p><a href="{{.VerifyURL}}">Confirm my email address</a>. The link expires at {{.ExpiresAt}}.</p>
+<p>If you did not request access, you can ignore this email.</p>`),
+
+	EventRegistrationApproved: newNotificationTemplate("Your registration request was approved", `
+<p>Hi {{.Name}},</p>
+<p>Your request for access to FinOne Search has been approved. You will receive your login details in a separate email.</p>
+{{if .Notes}}<p>Notes from the administrator: {{.Notes}}</p>{{end}}`),
+
+	EventRegistrationRejected: newNotificationTemplate("Your registration request was not approved", `
+<p>Hi {{.Name}},</p>
+<p>Unfortunately your request for access to FinOne Search was not approved.</p>
+{{if .Notes}}<p>Notes from the administrator: {{.Notes}}</p>{{end}}`),
+
+	EventUserCreated: newNotificationTemplate("Your FinOne Search account", `
+<p>Hi {{.Name}},</p>
+<p>An account has been created for you.</p>
+<p>Email: <strong>{{.Email}}</strong><br>Temporary password: <strong>{{.Password}}</strong></p>
+<p>Daily limits: {{.MaxSearches}} searches and {{.MaxExports}} exports.</p>
+{{if .LoginURL}}<p><a href="{{.LoginURL}}">Sign in</a> and request a password change once you are logged in.</p>{{end}}`),
+
+	EventPasswordChangeResolved: newNotificationTemplate("Your password change request was {{.Status}}", `
+<p>Hi {{.Name}},</p>
+<p>Your password change request has been {{.Status}}.</p>
+{{if .Password}}<p>Your new password: <strong>{{.Password}}</strong><br>You have been signed out everywhere; sign in again with it.</p>{{end}}
+{{if .Notes}}<p>Notes from the administrator: {{.Notes}}</p>{{end}}`),
+
+	EventQuotaNearlyExhausted: newNotificationTemplate("You have used {{.Used}} of {{.Limit}} daily {{.Kind}}", `
+<p>Hi {{.Name}},</p>
+<p>You have used <strong>{{.Used}} of {{.Limit}}</strong> {{.Kind}} allowed today. Your quota resets at {{.ResetAt}}.</p>`),
+
+	EventExportReady: newNotificationTemplate("Your export is ready for download", `
+<p>Hi {{.Name}},</p>
+<p>Your export <strong>{{.FileName}}</strong> ({{.RowCount}} rows, {{.FileSize}}) is ready.</p>
+<p><a href="{{.DownloadURL}}">Download it here</a>. The link expires at {{.ExpiresAt}}.</p>`),
+
+	EventUserUpgraded: newNotificationTemplate("Your FinOne Search account has been upgraded", `
+<p>Hi {{.Name}},</p>
+<p>Your demo account has been upgraded to a permanent account and no longer expires. You can keep using your current login.</p>
+<p>Daily limits: {{.MaxSearches}} searches and {{.MaxExports}} exports.</p>`),
+
+	EventNewLogin: newNotificationTemplate("New sign-in to your FinOne Search account", `
+<p>Hi {{.Name}},</p>
+<p>Your account was signed in to from a {{.What}} at {{.Time}}:</p>
+<p>Device: {{.Device}}<br>IP address: {{.IPAddress}}{{if .Location}}<br>Location: {{.Location}}{{end}}</p>
+<p>If this was you, no action is needed. If not, sign out of the session under your account's sessions and ask an administrator to reset your password.</p>`),
+}
+
+func newNotificationTemplate(subject, content string) notificationTemplate {
+	body := template.Must(template.New("layout").Parse(emailLayout))
+	template.Must(body.New("content").Parse(content))
+	return notificationTemplate{
+		subject: texttemplate.Must(texttemplate.New("subject").Parse(subject)),
+		body:    body,
+	}
+}
+
+type NotificationService struct{}
+
+func NewNotificationService() *NotificationService {
+	return &NotificationService{}
+}
+
+// IsEnabled reports whether emails are sent for an event. Events missing from the config are enabled.
+func (s *NotificationService) IsEnabled(event string) bool {
+	cfg := config.Get().Notifications
+	if !cfg.Enabled {
+		return false
+	}
+	enabled, ok := cfg.Events[event]
+	return !ok || enabled
+}
+
+// NotifyRegistrationReceived acknowledges a registration request and alerts the admin mailbox
+func (s *NotificationService) NotifyRegistrationReceived(req *models.UserRegistrationRequest) {
+	data := map[string]interface{}{
+		"Name":              req.Name,
+		"Email":             req.Email,
+		"PhoneNumber":       req.PhoneNumber,
+		"RequestedSearches": req.RequestedSearches,
+		"Admin":             false,
+	}
+	s.send(EventRegistrationReceived, req.Email, data)
+
+	if adminEmail := config.Get().Notifications.AdminEmail; adminEmail != "" {
+		adminData := map[string]interface{}{}
+		for key, value := range data {
+			adminData[key] = value
+		}
+		adminData["Name"] = "admin"
+		adminData["Admin"] = true
+		s.send(EventRegistrationReceived, adminEmail, adminData)
+	}
+}
+
+// NotifyRegistrationVerify sends an applicant the link that confirms their email address
+func (s *NotificationService) NotifyRegistrationVerify(req *models.UserRegistrationRequest, token string, expiresAt time.Time) {
+	s.send(EventRegistrationVerify, req.Email, map[string]interface{}{
+		"Name":      req.Name,
+		"VerifyURL": absoluteURL("/api/v1/register/verify?token=" + url.QueryEscape(token)),
+		"ExpiresAt": expiresAt.In(QuotaLocation()).Format("2006-01-02 15:04 MST"),
+	})
+}
+
+// NotifyRegistrationReviewed tells the applicant their registration request was approved or rejected
+func (s *NotificationService) NotifyRegistrationReviewed(req *models.UserRegistrationRequest) {
+	event := EventRegistrationRejected
+	if req.Status == "APPROVED" {
+		event = EventRegistrationApproved
+	}
+	s.send(event, req.Email, map[string]interface{}{
+		"Name":  req.Name,
+		"Notes": stringValue(req.AdminNotes),
+	})
+}
+
+// NotifyUserCreated sends a new user their login details
+func (s *NotificationService) NotifyUserCreated(user *models.User, tempPassword string) {
+	loginURL := ""
+	if baseURL := config.Get().Notifications.BaseURL; baseURL != "" {
+		loginURL = strings.TrimRight(baseURL, "/") + "/login"
+	}
+	s.send(EventUserCreated, user.Email, map[string]interface{}{
+		"Name":        user.Name,
+		"Email":       user.Email,
+		"Password":    tempPassword,
+		"MaxSearches": user.MaxSearchesPerDay,
+		"MaxExports":  user.MaxExportsPerDay,
+		"LoginURL":    loginURL,
+	})
+}
+
+// NotifyPasswordChangeResolved tells a user the outcome of their password change request, with their
+// new password when it is given
+func (s *NotificationService) NotifyPasswordChangeResolved(req *models.UserPasswordChangeRequest, newPassword string) {
+	s.send(EventPasswordChangeResolved, req.UserEmail, map[string]interface{}{
+		"Name":     req.UserName,
+		"Status":   strings.ToLower(req.Status),
+		"Notes":    stringValue(req.AdminNotes),
+		"Password": newPassword,
+	})
+}
+
+// NotifyQuotaUsage warns a user the first time their usage reaches the configured share of a
+// daily quota. kind is "searches" or "exports".
+func (s *NotificationService) NotifyQuotaUsage(userID uuid.UUID, kind string, used int) {
+	if !s.IsEnabled(EventQuotaNearlyExhausted) {
+		return
+	}
+
+	user, ok := s.lookupUser(userID)
+	if !ok {
+		return
+	}
+	limit := user.MaxSearchesPerDay
+	if kind == "exports" {
+		limit = user.MaxExportsPerDay
+	}
+	if limit <= 0 {
+		return
+	}
+
+	threshold := int(float64(limit)*config.Get().Notifications.QuotaThreshold + 0.5)
+	if threshold < 1 {
+		threshold = 1
+	}
+	// Only the increment that crosses the threshold sends a warning
+	if used != threshold {
+		return
+	}
+
+	s.send(EventQuotaNearlyExhausted, user.Email, map[string]interface{}{
+		"Name":    user.Name,
+		"Kind":    kind,
+		"Used":    used,
+		"Limit":   limit,
+		"ResetAt": NextQuotaReset().Format("2006-01-02 15:04 MST"),
+	})
+}
+
+// NotifyExportReady sends a user the download link of a completed export
+func (s *NotificationService) NotifyExportReady(userID uuid.UUID, export *models.ExportResponse) {
+	if !s.IsEnabled(EventExportReady) {
+		return
+	}
+	user, ok := s.lookupUser(userID)
+	if !ok {
+		return
+	}
+	s.send(EventExportReady, user.Email, map[string]interface{}{
+		"Name":        user.Name,
+		"FileName":    export.FileName,
+		"RowCount":    export.RowCount,
+		"FileSize":    utils.FormatFileSize(export.FileSize),
+		"DownloadURL": absoluteURL(export.DownloadURL),
+		"ExpiresAt":   export.ExpiresAt.In(QuotaLocation()).Format("2006-01-02 15:04 MST"),
+	})
+}
+
+// NotifyUserUpgraded tells a user their demo account is now permanent and what their new limits are
+func (s *NotificationService) NotifyUserUpgraded(user *models.User) {
+	s.send(EventUserUpgraded, user.Email, map[string]interface{}{
+		"Name":        user.Name,
+		"MaxSearches": user.MaxSearchesPerDay,
+		"MaxExports":  user.MaxExportsPerDay,
+	})
+}
+
+// NotifyNewLogin warns a user that their account was signed in to from a device or country it had
+// not been used from before
+func (s *NotificationService) NotifyNewLogin(user *models.User, device utils.DeviceInfo, client LoginClient, newDevice, newCountry bool) {
+	s.send(EventNewLogin, user.Email, map[string]interface{}{
+		"Name":      user.Name,
+		"What":      "new " + newLoginOriginKind(newDevice, newCountry),
+		"Device":    device.String(),
+		"IPAddress": client.IPAddress,
+		"Location":  notificationLocation(client.Location),
+		"Time":      time.Now().In(QuotaLocation()).Format("2006-01-02 15:04 MST"),
+	})
+}
+
+// notificationLocation describes a known location, or returns "" when nothing is known
+func notificationLocation(location utils.GeoLocation) string {
+	if location == (utils.GeoLocation{}) {
+		return ""
+	}
+	return describeLocation(location)
+}
+
+// send renders an event template and queues it for delivery, so failed sends are retried. Failures
+// are logged and never affect the operation that triggered the notification.
+func (s *NotificationService) send(event, to string, data map[string]interface{}) {
+	if !s.IsEnabled(event) || to == "" {
+		return
+	}
+
+	msg, err := renderNotification(event, to, data)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to render %s notification", event), err)
+		return
+	}
+
+	if _, err = NewJobQueueService().Enqueue(JobTypeNotification, msg, nil, 0); err == nil {
+		return
+	}
+	utils.LogError(fmt.Sprintf("Failed to queue %s notification, sending it directly", event), err)
+
+	provider, err := newEmailProvider(config.Get().Notifications)
+	if err != nil {
+		utils.LogError("Failed to create email provider", err)
+		return
+	}
+
+	go func() {
+		if err := provider.Send(msg); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to send %s notification to %s", event, to), err)
+		}
+	}()
+}
+
+// renderNotification renders the subject and HTML body of an event
+func renderNotification(event, to string, data map[string]interface{}) (*EmailMessage, error) {
+	tmpl, ok := notificationTemplates[event]
+	if !ok {
+		return nil, fmt.Errorf("no template for event %s", event)
+	}
+
+	var subject bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	return &EmailMessage{To: to, Subject: subject.String(), HTML: body.String()}, nil
+}
+
+// lookupUser loads the contact details and daily limits of a notification recipient
+func (s *NotificationService) lookupUser(userID uuid.UUID) (*models.User, bool) {
+	var user models.User
+	query := `SELECT name, email, max_searches_per_day, max_exports_per_day FROM users WHERE id = $1`
+	if err := database.PostgresDB.Get(&user, query, userID); err != nil {
+		utils.LogError("Failed to look up notification recipient", err)
+		return nil, false
+	}
+	return &user, true
+}
+
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// absoluteURL prefixes server paths such as local export downloads with the public base URL;
+// presigned object storage URLs are already absolute
+func absoluteURL(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		return path
+	}
+	return strings.TrimRight(config.Get().Notifications.BaseURL, "/") + path
+}
@@ -0,0 +1,626 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrOIDCNotConfigured    = errors.New("single sign-on is not configured")
+	ErrOIDCProviderNotFound = errors.New("unknown single sign-on provider")
+	ErrOIDCStateInvalid     = errors.New("single sign-on login is invalid or has expired; start again")
+	ErrOIDCFailed           = errors.New("single sign-on login failed")
+	ErrOIDCEmailNotVerified = errors.New("the single sign-on provider has not verified your email")
+	ErrOIDCDomainNotAllowed = errors.New("your email domain may not sign in with this provider")
+	ErrOIDCNoAccount        = errors.New("no account exists for your email; ask an administrator for one")
+	// ErrOIDCUnavailable means the provider could not be reached, so the login could not be checked
+	ErrOIDCUnavailable = errors.New("single sign-on provider is unavailable")
+	ErrAccountDisabled = errors.New("user account is disabled")
+)
+
+const (
+	// oidcMetadataTTL is how long a provider's discovery document and keys are used before they are fetched again
+	oidcMetadataTTL = time.Hour
+	// oidcKeyRefreshInterval limits fetching keys again for tokens signed with an unknown key
+	oidcKeyRefreshInterval = time.Minute
+	// oidcMaxResponseSize caps what is read from a provider
+	oidcMaxResponseSize = 1 << 20
+)
+
+var oidcClient = &http.Client{Timeout: 10 * time.Second}
+
+// oidcSigningMethods are the ID token algorithms accepted; none and HMAC never are
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// oidcDiscovery is the part of a provider's /.well-known/openid-configuration logins use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcMetadata is a provider's discovery document and signing keys. It is not changed once cached;
+// refreshing keys caches a copy.
+type oidcMetadata struct {
+	discovery     oidcDiscovery
+	fetchedAt     time.Time
+	keys          map[string]interface{} // Public keys by kid
+	keysFetchedAt time.Time
+}
+
+var oidcCache = struct {
+	mu        sync.Mutex
+	providers map[string]*oidcMetadata // By issuer
+}{providers: map[string]*oidcMetadata{}}
+
+// oidcJWK is a key in a provider's JWKS
+type oidcJWK struct {
+	KeyType string `json:"kty"`
+	KID     string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// oidcClaims are the ID token claims logins use
+type oidcClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // A boolean, or a string at some providers
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+}
+
+// OIDCService signs users in through OpenID Connect providers with the authorization code flow
+type OIDCService struct {
+	authService *AuthService
+}
+
+func NewOIDCService() *OIDCService {
+	return &OIDCService{
+		authService: NewAuthService(),
+	}
+}
+
+// StartLogin begins a login at a provider, named or the only one configured, and returns the URL to
+// send the browser to with the login's state, which the caller binds to the browser. The state, nonce
+// and PKCE verifier are kept until the provider returns.
+func (s *OIDCService) StartLogin(ctx context.Context, providerName string) (string, string, error) {
+	cfg := config.Get().OIDC
+	provider, err := oidcProvider(cfg, providerName)
+	if err != nil {
+		return "", "", err
+	}
+	metadata, err := oidcProviderMetadata(ctx, provider.Issuer)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := newVerificationToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := newVerificationToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := newVerificationToken()
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	// Logins abandoned at the provider are cleared as new ones start
+	if _, err := database.PostgresDB.Exec(`DELETE FROM oidc_login_states WHERE expires_at <= now()`); err != nil {
+		utils.LogError("Failed to delete expired SSO logins", err)
+	}
+	_, err = database.PostgresDB.Exec(`INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, expires_at)
+	                                   VALUES ($1, $2, $3, $4, $5)`,
+		hashVerificationToken(state), provider.Name, nonce, verifier, time.Now().Add(cfg.StateExpiry))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to store SSO login: %w", err)
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {provider.ClientID},
+		"redirect_uri":          {cfg.RedirectURL},
+		"scope":                 {strings.Join(provider.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(metadata.discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.discovery.AuthorizationEndpoint + separator + query.Encode(), state, nil
+}
+
+// FinishLogin completes a login the provider has returned from: it exchanges the code for an ID
+// token, verifies it and starts a session for the user's account
+func (s *OIDCService) FinishLogin(ctx context.Context, state, code string, client LoginClient) (*models.LoginResponse, error) {
+	if state == "" || code == "" {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	// A state is used once, whether or not the login succeeds
+	var login struct {
+		Provider     string `db:"provider"`
+		Nonce        string `db:"nonce"`
+		CodeVerifier string `db:"code_verifier"`
+		Valid        bool   `db:"valid"`
+	}
+	err := database.PostgresDB.Get(&login, `DELETE FROM oidc_login_states WHERE state_hash = $1
+	                                         RETURNING provider, nonce, code_verifier, expires_at > now() AS valid`,
+		hashVerificationToken(state))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOIDCStateInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find SSO login: %w", err)
+	}
+	if !login.Valid {
+		return nil, ErrOIDCStateInvalid
+	}
+
+	cfg := config.Get().OIDC
+	provider, err := oidcProvider(cfg, login.Provider)
+	if err != nil {
+		// The provider was removed from the configuration while the user was away
+		return nil, ErrOIDCStateInvalid
+	}
+	metadata, err := oidcProviderMetadata(ctx, provider.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	idToken, err := exchangeOIDCCode(ctx, metadata.discovery.TokenEndpoint, provider, cfg.RedirectURL, code, login.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := verifyIDToken(ctx, provider, metadata.discovery.Issuer, idToken, login.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userForIdentity(provider, metadata.discovery.Issuer, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrAccountDisabled
+	}
+	if user.ExpiresAt != nil && user.ExpiresAt.Before(time.Now()) {
+		return nil, ErrAccountExpired
+	}
+
+	utils.LogInfo(fmt.Sprintf("User %s signed in with %s", user.Email, provider.Name))
+	return s.authService.startSession(*user, client)
+}
+
+// userForIdentity finds the account of a provider's user: the one linked to their identity, else the
+// one with their verified email, which is then linked, else a new one when the provider provisions accounts
+func (s *OIDCService) userForIdentity(provider *config.OIDCProviderConfig, issuer string, claims *oidcClaims) (*models.User, error) {
+	email, verified := claims.verifiedEmail(provider.TrustEmail)
+
+	var user models.User
+	err := database.PostgresDB.Get(&user, `SELECT u.* FROM user_identities i JOIN users u ON u.id = i.user_id
+	                                        WHERE i.issuer = $1 AND i.subject = $2`, issuer, claims.Subject)
+	if err == nil {
+		_, err := database.PostgresDB.Exec(`UPDATE user_identities SET last_login_at = now(), email = COALESCE(NULLIF($3, ''), email)
+		                                     WHERE issuer = $1 AND subject = $2`, issuer, claims.Subject, email)
+		if err != nil {
+			utils.LogError("Failed to update linked SSO identity", err)
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to find linked account: %w", err)
+	}
+
+	if email == "" || !verified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+	if !emailDomainAllowed(email, provider.AllowedDomains) {
+		return nil, ErrOIDCDomainNotAllowed
+	}
+
+	err = database.PostgresDB.Get(&user, `SELECT * FROM users WHERE LOWER(email) = $1`, email)
+	if err == nil {
+		if err := linkIdentity(database.PostgresDB, provider.Name, issuer, claims.Subject, user.ID, email); err != nil {
+			return nil, err
+		}
+		utils.LogInfo(fmt.Sprintf("Linked %s identity %s to user %s by verified email", provider.Name, claims.Subject, user.Email))
+		return &user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if !provider.AutoProvision {
+		return nil, ErrOIDCNoAccount
+	}
+	return s.provisionUser(provider, issuer, claims, email)
+}
+
+// provisionUser creates the account of a provider's user on their first login, with the provider's
+// default role, user type and quotas. Its random password is never shown, so the account signs in
+// through the provider unless an admin sets one.
+func (s *OIDCService) provisionUser(provider *config.OIDCProviderConfig, issuer string, claims *oidcClaims, email string) (*models.User, error) {
+	password, err := generatePassword()
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = email
+	}
+	user, err := s.authService.newUser(&models.CreateUserRequest{
+		Name:              name,
+		Email:             email,
+		Password:          password,
+		UserType:          provider.DefaultUserType,
+		Role:              provider.DefaultRole,
+		MaxSearchesPerDay: provider.MaxSearchesPerDay,
+		MaxExportsPerDay:  provider.MaxExportsPerDay,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := database.PostgresDB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertUser(tx, user); err != nil {
+		return nil, err
+	}
+	if err := linkIdentity(tx, provider.Name, issuer, claims.Subject, user.ID, email); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Created user %s (%s, %s) on their first %s login", user.Email, user.Role, user.UserType, provider.Name))
+	return user, nil
+}
+
+// linkIdentity records that a provider's user signs in as an account
+func linkIdentity(db sqlx.Execer, provider, issuer, subject string, userID uuid.UUID, email string) error {
+	_, err := db.Exec(`INSERT INTO user_identities (issuer, subject, provider, user_id, email) VALUES ($1, $2, $3, $4, $5)`,
+		issuer, subject, provider, userID, email)
+	if err != nil {
+		return fmt.Errorf("failed to link SSO identity: %w", err)
+	}
+	return nil
+}
+
+// verifiedEmail returns the email in the claims, lower-cased, and whether the provider vouches for it.
+// Providers that send no email_verified claim are trusted only with trustEmail, which also accepts an
+// email-like preferred_username when there is no email, as Azure AD sends.
+func (c *oidcClaims) verifiedEmail(trustEmail bool) (string, bool) {
+	email := c.Email
+	if email == "" && trustEmail && strings.Contains(c.PreferredUsername, "@") {
+		email = c.PreferredUsername
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	switch verified := c.EmailVerified.(type) {
+	case bool:
+		return email, verified
+	case string:
+		return email, verified == "true"
+	case nil:
+		return email, trustEmail
+	}
+	return email, false
+}
+
+// emailDomainAllowed reports whether an email is in one of domains, or domains is empty
+func emailDomainAllowed(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, allowed := range domains {
+		if strings.EqualFold(domain, strings.TrimPrefix(allowed, "@")) {
+			return true
+		}
+	}
+	return false
+}
+
+// oidcProvider finds a configured provider by name; with no name, the only one configured
+func oidcProvider(cfg config.OIDCConfig, name string) (*config.OIDCProviderConfig, error) {
+	if len(cfg.Providers) == 0 || cfg.RedirectURL == "" {
+		return nil, ErrOIDCNotConfigured
+	}
+	if name == "" {
+		if len(cfg.Providers) == 1 {
+			return &cfg.Providers[0], nil
+		}
+		return nil, fmt.Errorf("%w: choose one with ?provider=", ErrOIDCProviderNotFound)
+	}
+	for i := range cfg.Providers {
+		if cfg.Providers[i].Name == name {
+			return &cfg.Providers[i], nil
+		}
+	}
+	return nil, ErrOIDCProviderNotFound
+}
+
+// exchangeOIDCCode trades an authorization code at the provider's token endpoint for an ID token
+func exchangeOIDCCode(ctx context.Context, tokenEndpoint string, provider *config.OIDCProviderConfig, redirectURL, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {provider.ClientID},
+		"code_verifier": {verifier},
+	}
+	if provider.ClientSecret != "" {
+		form.Set("client_secret", provider.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oidcClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to reach token endpoint: %v", ErrOIDCUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return "", fmt.Errorf("%w: token endpoint returned %s", ErrOIDCUnavailable, resp.Status)
+	}
+
+	var result struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(&result); err != nil {
+		return "", fmt.Errorf("%w: failed to read token response: %v", ErrOIDCUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		return "", fmt.Errorf("%w: provider refused the authorization code: %s %s", ErrOIDCFailed, result.Error, result.ErrorDescription)
+	}
+	if result.IDToken == "" {
+		return "", fmt.Errorf("%w: provider returned no ID token", ErrOIDCFailed)
+	}
+	return result.IDToken, nil
+}
+
+// verifyIDToken checks an ID token's signature against the provider's keys, and that it was issued
+// by the provider, for this client and this login
+func verifyIDToken(ctx context.Context, provider *config.OIDCProviderConfig, issuer, idToken, nonce string) (*oidcClaims, error) {
+	claims := &oidcClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return oidcKey(ctx, provider.Issuer, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(provider.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if errors.Is(err, ErrOIDCUnavailable) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ID token: %v", ErrOIDCFailed, err)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: ID token is for another login", ErrOIDCFailed)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: ID token has no subject", ErrOIDCFailed)
+	}
+	return claims, nil
+}
+
+// oidcProviderMetadata returns a provider's discovery document and keys, fetching them when they are
+// not cached or are stale. Stale ones are still used while the provider cannot be reached.
+func oidcProviderMetadata(ctx context.Context, issuer string) (*oidcMetadata, error) {
+	oidcCache.mu.Lock()
+	cached := oidcCache.providers[issuer]
+	oidcCache.mu.Unlock()
+	if cached != nil && time.Since(cached.fetchedAt) < oidcMetadataTTL {
+		return cached, nil
+	}
+
+	metadata, err := fetchOIDCMetadata(ctx, issuer)
+	if err != nil {
+		if cached != nil {
+			utils.LogError("Failed to refresh SSO provider metadata, using the cached copy", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	storeOIDCMetadata(issuer, metadata)
+	return metadata, nil
+}
+
+func fetchOIDCMetadata(ctx context.Context, issuer string) (*oidcMetadata, error) {
+	var discovery oidcDiscovery
+	if err := getOIDCJSON(ctx, issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("%w: discovery document is for issuer %q", ErrOIDCUnavailable, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document is missing endpoints", ErrOIDCUnavailable)
+	}
+	keys, err := fetchOIDCKeys(ctx, discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &oidcMetadata{discovery: discovery, fetchedAt: now, keys: keys, keysFetchedAt: now}, nil
+}
+
+func storeOIDCMetadata(issuer string, metadata *oidcMetadata) {
+	oidcCache.mu.Lock()
+	defer oidcCache.mu.Unlock()
+	oidcCache.providers[issuer] = metadata
+}
+
+// oidcKey returns the provider key a token was signed with. Unknown keys make the provider's keys be
+// fetched again, at most once a minute, since providers rotate them.
+func oidcKey(ctx context.Context, issuer, kid string) (interface{}, error) {
+	metadata, err := oidcProviderMetadata(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if key := metadata.key(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(metadata.keysFetchedAt) < oidcKeyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	keys, err := fetchOIDCKeys(ctx, metadata.discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	refreshed := *metadata
+	refreshed.keys = keys
+	refreshed.keysFetchedAt = time.Now()
+	storeOIDCMetadata(issuer, &refreshed)
+	if key := refreshed.key(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// key finds a signing key by kid; tokens without a kid can only use a provider's only key
+func (m *oidcMetadata) key(kid string) interface{} {
+	if kid == "" && len(m.keys) == 1 {
+		for _, key := range m.keys {
+			return key
+		}
+	}
+	return m.keys[kid]
+}
+
+// fetchOIDCKeys reads the RSA and EC signing keys of a provider's JWKS, skipping any it cannot use
+func fetchOIDCKeys(ctx context.Context, jwksURI string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := getOIDCJSON(ctx, jwksURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			utils.LogWarning(fmt.Sprintf("Skipping SSO provider key %q: %v", jwk.KID, err))
+			continue
+		}
+		keys[jwk.KID] = key
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA or EC public key
+func (k oidcJWK) publicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// getOIDCJSON fetches a JSON document from a provider
+func getOIDCJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrOIDCUnavailable, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := oidcClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: failed to fetch %s: %v", ErrOIDCUnavailable, url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %s", ErrOIDCUnavailable, url, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: failed to read %s: %v", ErrOIDCUnavailable, url, err)
+	}
+	return nil
+}
@@ -0,0 +1,554 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrOrganizationNotFound  = errors.New("organization not found")
+	ErrOrganizationExists    = errors.New("an organization with this name already exists")
+	ErrInvalidOrganization   = errors.New("invalid organization")
+	ErrOrganizationFull      = errors.New("the organization has no free seats")
+	ErrNotOrganizationMember = errors.New("user is not a member of this organization")
+	ErrInOtherOrganization   = errors.New("user already belongs to another organization")
+	ErrNotOrganizationAdmin  = errors.New("organization admin access required")
+	ErrMemberEmailExists     = errors.New("a user with this email already exists")
+)
+
+// OrganizationService manages organizations, their members and the daily quotas members share.
+// A user belongs to at most one organization; its search and export limits apply on top of each
+// member's own limits. Organization admins manage the members of their organization only.
+type OrganizationService struct{}
+
+func NewOrganizationService() *OrganizationService {
+	return &OrganizationService{}
+}
+
+const organizationColumns = `o.*, (SELECT COUNT(*) FROM organization_members m WHERE m.organization_id = o.id) AS members`
+
+// ListOrganizations returns every organization with its member count
+func (s *OrganizationService) ListOrganizations() ([]models.Organization, error) {
+	organizations := []models.Organization{}
+	query := `SELECT ` + organizationColumns + ` FROM organizations o ORDER BY o.name`
+	if err := database.PostgresDB.Select(&organizations, query); err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	return organizations, nil
+}
+
+// GetOrganization returns an organization with its member count
+func (s *OrganizationService) GetOrganization(id uuid.UUID) (*models.Organization, error) {
+	var organization models.Organization
+	query := `SELECT ` + organizationColumns + ` FROM organizations o WHERE o.id = $1`
+	err := database.PostgresDB.Get(&organization, query, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &organization, nil
+}
+
+// GetOrganizationDetails returns an organization with its members and their usage today
+func (s *OrganizationService) GetOrganizationDetails(id uuid.UUID) (*models.OrganizationDetails, error) {
+	organization, err := s.GetOrganization(id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.ListMembers(id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &models.OrganizationDetails{Organization: *organization, Members: members}
+	for _, member := range members {
+		details.Searches.Used += member.TodaySearches
+		details.Exports.Used += member.TodayExports
+	}
+	details.Searches = newQuotaStatus(details.Searches.Used, organization.MaxSearchesPerDay)
+	details.Exports = newQuotaStatus(details.Exports.Used, organization.MaxExportsPerDay)
+	return details, nil
+}
+
+// CreateOrganization creates an organization without members
+func (s *OrganizationService) CreateOrganization(req *models.CreateOrganizationRequest, createdBy uuid.UUID) (*models.Organization, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidOrganization)
+	}
+	if req.MaxMembers < 0 || req.MaxSearchesPerDay < 0 || req.MaxExportsPerDay < 0 {
+		return nil, fmt.Errorf("%w: limits cannot be negative", ErrInvalidOrganization)
+	}
+
+	var organization models.Organization
+	query := `INSERT INTO organizations (name, max_members, max_searches_per_day, max_exports_per_day, created_by)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING *`
+	err := database.PostgresDB.Get(&organization, query, name, req.MaxMembers, req.MaxSearchesPerDay, req.MaxExportsPerDay, createdBy)
+	if isUniqueViolation(err) {
+		return nil, ErrOrganizationExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Created organization %s (%s)", organization.Name, organization.ID))
+	return &organization, nil
+}
+
+// UpdateOrganization changes an organization's name, seats or shared limits. Lowering the seats does
+// not remove members; it only stops new ones from being added.
+func (s *OrganizationService) UpdateOrganization(id uuid.UUID, req *models.UpdateOrganizationRequest) (*models.Organization, error) {
+	organization, err := s.GetOrganization(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if name := strings.TrimSpace(*req.Name); name != "" {
+			organization.Name = name
+		}
+	}
+	for _, limit := range []struct {
+		value  *int
+		target *int
+	}{
+		{req.MaxMembers, &organization.MaxMembers},
+		{req.MaxSearchesPerDay, &organization.MaxSearchesPerDay},
+		{req.MaxExportsPerDay, &organization.MaxExportsPerDay},
+	} {
+		if limit.value == nil {
+			continue
+		}
+		if *limit.value < 0 {
+			return nil, fmt.Errorf("%w: limits cannot be negative", ErrInvalidOrganization)
+		}
+		*limit.target = *limit.value
+	}
+
+	query := `UPDATE organizations
+			  SET name = $2, max_members = $3, max_searches_per_day = $4, max_exports_per_day = $5, updated_at = now()
+			  WHERE id = $1`
+	_, err = database.PostgresDB.Exec(query, id, organization.Name, organization.MaxMembers, organization.MaxSearchesPerDay, organization.MaxExportsPerDay)
+	if isUniqueViolation(err) {
+		return nil, ErrOrganizationExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+	return s.GetOrganization(id)
+}
+
+// DeleteOrganization deletes an organization. Its members keep their accounts.
+func (s *OrganizationService) DeleteOrganization(id uuid.UUID) error {
+	result, err := database.PostgresDB.Exec(`DELETE FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrOrganizationNotFound
+	}
+	return nil
+}
+
+// ListMembers returns an organization's members with their usage today
+func (s *OrganizationService) ListMembers(id uuid.UUID) ([]models.OrganizationMember, error) {
+	members := []models.OrganizationMember{}
+	query := `SELECT m.user_id, u.name, u.email, m.role, u.is_active, u.expires_at,
+	                 u.max_searches_per_day, u.max_exports_per_day,
+	                 COALESCE(d.search_count, 0) AS today_searches, COALESCE(d.export_count, 0) AS today_exports,
+	                 m.added_at
+	          FROM organization_members m
+	          JOIN users u ON u.id = m.user_id
+	          LEFT JOIN daily_usage d ON d.user_id = m.user_id AND d.date = $2
+	          WHERE m.organization_id = $1
+	          ORDER BY u.name`
+	if err := database.PostgresDB.Select(&members, query, id, CurrentQuotaDate()); err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+	return members, nil
+}
+
+// AddMember adds an existing user to an organization, or changes their role if they already belong to
+// it. Admins cannot join organizations.
+func (s *OrganizationService) AddMember(id, userID uuid.UUID, role string, addedBy uuid.UUID) error {
+	role, err := organizationRole(role)
+	if err != nil {
+		return err
+	}
+
+	var userRole string
+	err = database.PostgresDB.Get(&userRole, `SELECT role FROM users WHERE id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if userRole == "ADMIN" {
+		return fmt.Errorf("%w: admins cannot join organizations", ErrInvalidOrganization)
+	}
+
+	return database.WithTransaction(func(tx *sqlx.Tx) error {
+		var currentOrganization uuid.UUID
+		err := tx.Get(&currentOrganization, `SELECT organization_id FROM organization_members WHERE user_id = $1`, userID)
+		switch {
+		case err == nil && currentOrganization != id:
+			return ErrInOtherOrganization
+		case err == nil:
+			_, err = tx.Exec(`UPDATE organization_members SET role = $3 WHERE organization_id = $1 AND user_id = $2`, id, userID, role)
+			return err
+		case err != sql.ErrNoRows:
+			return fmt.Errorf("failed to get membership: %w", err)
+		}
+
+		if err := takeSeat(tx, id); err != nil {
+			return err
+		}
+		query := `INSERT INTO organization_members (organization_id, user_id, role, added_by) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(query, id, userID, role, addedBy); err != nil {
+			if isUniqueViolation(err) {
+				return ErrInOtherOrganization
+			}
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+		return nil
+	})
+}
+
+// CreateMember creates an account in an organization, taking one of its seats. Members always get
+// the USER role and a PERMANENT account; a password is generated when none is given and returned.
+func (s *OrganizationService) CreateMember(id uuid.UUID, req *models.CreateOrganizationMemberRequest, addedBy uuid.UUID) (*models.CreatedOrganizationMember, error) {
+	role, err := organizationRole(req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if req.MaxSearchesPerDay < 0 || req.MaxExportsPerDay < 0 {
+		return nil, fmt.Errorf("%w: limits cannot be negative", ErrInvalidOrganization)
+	}
+	maxSearches, maxExports, err := memberLimits(id, addedBy, req.MaxSearchesPerDay, req.MaxExportsPerDay)
+	if err != nil {
+		return nil, err
+	}
+
+	userReq := models.CreateUserRequest{
+		Name:              strings.TrimSpace(req.Name),
+		Email:             strings.TrimSpace(req.Email),
+		Password:          req.Password,
+		UserType:          "PERMANENT",
+		Role:              "USER",
+		ExpiresAt:         req.ExpiresAt,
+		MaxSearchesPerDay: maxSearches,
+		MaxExportsPerDay:  maxExports,
+	}
+	rowErrors, err := validateBulkUsers([]models.CreateUserRequest{userReq})
+	if err != nil {
+		return nil, err
+	}
+	if len(rowErrors) > 0 {
+		if rowErrors[0].Error == emailTakenMessage {
+			return nil, ErrMemberEmailExists
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOrganization, rowErrors[0].Error)
+	}
+
+	created := &models.CreatedOrganizationMember{Role: role}
+	if userReq.Password == "" {
+		if userReq.Password, err = generatePassword(); err != nil {
+			return nil, err
+		}
+		created.Password = userReq.Password
+	}
+	user, err := NewAuthService().newUser(&userReq)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.WithTransaction(func(tx *sqlx.Tx) error {
+		if err := takeSeat(tx, id); err != nil {
+			return err
+		}
+		if err := insertUser(tx, user); err != nil {
+			if isUniqueViolation(err) {
+				return ErrMemberEmailExists
+			}
+			return err
+		}
+		query := `INSERT INTO organization_members (organization_id, user_id, role, added_by) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(query, id, user.ID, role, addedBy); err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.PasswordHash = ""
+	created.User = *user
+	utils.LogInfo(fmt.Sprintf("Created user %s in organization %s", user.Email, id))
+	NewNotificationService().NotifyUserCreated(user, userReq.Password)
+	return created, nil
+}
+
+// UpdateMember changes a member's organization role, whether their account is active and their own
+// daily limits. Deactivating a member signs them out.
+func (s *OrganizationService) UpdateMember(id, userID, actorID uuid.UUID, req *models.UpdateOrganizationMemberRequest) error {
+	if err := s.requireMember(id, userID); err != nil {
+		return err
+	}
+	if userID == actorID && (req.MaxSearchesPerDay != nil || req.MaxExportsPerDay != nil) {
+		return fmt.Errorf("%w: you cannot change your own limits", ErrInvalidOrganization)
+	}
+
+	var role string
+	if req.Role != nil {
+		var err error
+		if role, err = organizationRole(*req.Role); err != nil {
+			return err
+		}
+	}
+	for _, limit := range []*int{req.MaxSearchesPerDay, req.MaxExportsPerDay} {
+		if limit != nil && *limit < 0 {
+			return fmt.Errorf("%w: limits cannot be negative", ErrInvalidOrganization)
+		}
+	}
+	if req.MaxSearchesPerDay != nil || req.MaxExportsPerDay != nil {
+		searches, exports := -1, -1 // Unchanged limits are not checked
+		if req.MaxSearchesPerDay != nil {
+			searches = *req.MaxSearchesPerDay
+		}
+		if req.MaxExportsPerDay != nil {
+			exports = *req.MaxExportsPerDay
+		}
+		if _, _, err := memberLimits(id, actorID, searches, exports); err != nil {
+			return err
+		}
+	}
+
+	err := database.WithTransaction(func(tx *sqlx.Tx) error {
+		if role != "" {
+			if _, err := tx.Exec(`UPDATE organization_members SET role = $3 WHERE organization_id = $1 AND user_id = $2`, id, userID, role); err != nil {
+				return fmt.Errorf("failed to update member role: %w", err)
+			}
+		}
+		query := `UPDATE users
+				  SET is_active = COALESCE($2, is_active),
+				      max_searches_per_day = COALESCE($3, max_searches_per_day),
+				      max_exports_per_day = COALESCE($4, max_exports_per_day),
+				      updated_at = now()
+				  WHERE id = $1`
+		if _, err := tx.Exec(query, userID, req.IsActive, req.MaxSearchesPerDay, req.MaxExportsPerDay); err != nil {
+			return fmt.Errorf("failed to update member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sessionCache.invalidateUser(userID)
+	if req.IsActive != nil && !*req.IsActive {
+		if err := NewAuthService().InvalidateAllUserSessions(userID); err != nil {
+			utils.LogError("Failed to sign out deactivated member", err)
+		}
+	}
+	return nil
+}
+
+// memberLimits checks the daily search and export limits an organization admin gives a member against
+// the highest they may give: the organization's shared limits or, where the organization has none, the
+// admin's own limits, which only a site admin can change. A limit of 0 stands for the configured
+// default, which is lowered to the cap; a negative limit is not checked. Returns the limits to store.
+func memberLimits(id, actorID uuid.UUID, searches, exports int) (int, int, error) {
+	var caps struct {
+		OrgSearches   int `db:"org_searches"`
+		OrgExports    int `db:"org_exports"`
+		AdminSearches int `db:"admin_searches"`
+		AdminExports  int `db:"admin_exports"`
+	}
+	query := `SELECT o.max_searches_per_day AS org_searches, o.max_exports_per_day AS org_exports,
+	                 u.max_searches_per_day AS admin_searches, u.max_exports_per_day AS admin_exports
+	          FROM organizations o, users u
+	          WHERE o.id = $1 AND u.id = $2`
+	err := database.PostgresDB.Get(&caps, query, id, actorID)
+	if err == sql.ErrNoRows {
+		return 0, 0, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get organization limits: %w", err)
+	}
+
+	searches, err = capMemberLimit("max_searches_per_day", searches, caps.OrgSearches, caps.AdminSearches, config.Get().Limits.MaxSearchesPerDay)
+	if err != nil {
+		return 0, 0, err
+	}
+	exports, err = capMemberLimit("max_exports_per_day", exports, caps.OrgExports, caps.AdminExports, config.Get().Limits.MaxExportsPerDay)
+	if err != nil {
+		return 0, 0, err
+	}
+	return searches, exports, nil
+}
+
+// capMemberLimit checks one member limit against the organization's limit, or the admin's own when the
+// organization has none
+func capMemberLimit(field string, limit, orgLimit, adminLimit, defaultLimit int) (int, error) {
+	maxLimit, source := orgLimit, "the organization's limit"
+	if maxLimit == 0 {
+		maxLimit, source = adminLimit, "your own limit"
+	}
+	switch {
+	case limit < 0 || maxLimit <= 0:
+		return limit, nil
+	case limit == 0:
+		return min(defaultLimit, maxLimit), nil
+	case limit > maxLimit:
+		return 0, fmt.Errorf("%w: %s cannot exceed %d, %s", ErrInvalidOrganization, field, maxLimit, source)
+	}
+	return limit, nil
+}
+
+// RemoveMember takes a user out of an organization, freeing their seat. With deactivate, as when an
+// organization admin removes a member, their account is also deactivated and signed out.
+func (s *OrganizationService) RemoveMember(id, userID uuid.UUID, deactivate bool) error {
+	result, err := database.PostgresDB.Exec(`DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrNotOrganizationMember
+	}
+	if !deactivate {
+		return nil
+	}
+
+	if _, err := database.PostgresDB.Exec(`UPDATE users SET is_active = false, updated_at = now() WHERE id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to deactivate removed member: %w", err)
+	}
+	sessionCache.invalidateUser(userID)
+	return NewAuthService().InvalidateAllUserSessions(userID)
+}
+
+// AdministeredOrganization returns the organization a user is an organization admin of
+func (s *OrganizationService) AdministeredOrganization(userID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	query := `SELECT organization_id FROM organization_members WHERE user_id = $1 AND role = $2`
+	err := database.PostgresDB.Get(&id, query, userID, models.OrganizationRoleAdmin)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ErrNotOrganizationAdmin
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get organization membership: %w", err)
+	}
+	return id, nil
+}
+
+// CheckQuota reports whether the organization of a user, if any, has searches or exports left today
+func (s *OrganizationService) CheckQuota(userID uuid.UUID, kind string) (bool, error) {
+	quota, err := s.GetQuota(userID)
+	if err != nil || quota == nil {
+		return err == nil, err
+	}
+
+	status := quota.Searches
+	if kind == "exports" {
+		status = quota.Exports
+	}
+	return status.Limit == 0 || status.Used < status.Limit, nil
+}
+
+// GetQuota returns the shared quotas of a user's organization, or nil when they have none
+func (s *OrganizationService) GetQuota(userID uuid.UUID) (*models.OrganizationQuota, error) {
+	var organization models.Organization
+	query := `SELECT o.* FROM organizations o
+	          JOIN organization_members m ON m.organization_id = o.id
+	          WHERE m.user_id = $1`
+	err := database.PostgresDB.Get(&organization, query, userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	var usage struct {
+		SearchCount int `db:"search_count"`
+		ExportCount int `db:"export_count"`
+	}
+	if organization.MaxSearchesPerDay > 0 || organization.MaxExportsPerDay > 0 {
+		usageQuery := `SELECT COALESCE(SUM(d.search_count), 0) AS search_count, COALESCE(SUM(d.export_count), 0) AS export_count
+		               FROM daily_usage d
+		               JOIN organization_members m ON m.user_id = d.user_id
+		               WHERE m.organization_id = $1 AND d.date = $2`
+		if err := database.PostgresDB.Get(&usage, usageQuery, organization.ID, CurrentQuotaDate()); err != nil {
+			return nil, fmt.Errorf("failed to get organization usage: %w", err)
+		}
+	}
+
+	return &models.OrganizationQuota{
+		ID:       organization.ID,
+		Name:     organization.Name,
+		Searches: newQuotaStatus(usage.SearchCount, organization.MaxSearchesPerDay),
+		Exports:  newQuotaStatus(usage.ExportCount, organization.MaxExportsPerDay),
+	}, nil
+}
+
+// requireMember checks a user belongs to an organization
+func (s *OrganizationService) requireMember(id, userID uuid.UUID) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM organization_members WHERE organization_id = $1 AND user_id = $2)`
+	if err := database.PostgresDB.Get(&exists, query, id, userID); err != nil {
+		return fmt.Errorf("failed to get membership: %w", err)
+	}
+	if !exists {
+		return ErrNotOrganizationMember
+	}
+	return nil
+}
+
+// takeSeat locks an organization and checks it has a free seat for a new member
+func takeSeat(tx *sqlx.Tx, id uuid.UUID) error {
+	var maxMembers int
+	err := tx.Get(&maxMembers, `SELECT max_members FROM organizations WHERE id = $1 FOR UPDATE`, id)
+	if err == sql.ErrNoRows {
+		return ErrOrganizationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get organization: %w", err)
+	}
+	if maxMembers == 0 {
+		return nil
+	}
+
+	var members int
+	if err := tx.Get(&members, `SELECT COUNT(*) FROM organization_members WHERE organization_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to count organization members: %w", err)
+	}
+	if members >= maxMembers {
+		return ErrOrganizationFull
+	}
+	return nil
+}
+
+// organizationRole validates an organization role, defaulting to MEMBER
+func organizationRole(role string) (string, error) {
+	switch role = strings.ToUpper(strings.TrimSpace(role)); role {
+	case "":
+		return models.OrganizationRoleMember, nil
+	case models.OrganizationRoleMember, models.OrganizationRoleAdmin:
+		return role, nil
+	}
+	return "", fmt.Errorf("%w: role must be MEMBER or ADMIN", ErrInvalidOrganization)
+}
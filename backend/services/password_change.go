@@ -1,23 +1,39 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"finone-search-system/database"
 	"finone-search-system/models"
+	"finone-search-system/utils"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength is the shortest password an admin may set on a password change request, as for new users
+const minPasswordLength = 6
+
+// Errors returned when resolving password change requests
+var (
+	ErrPasswordChangeNotFound  = errors.New("password change request not found")
+	ErrPasswordChangeProcessed = errors.New("password change request has already been processed")
+	ErrInvalidPasswordChange   = errors.New("invalid password change")
 )
 
 type PasswordChangeService struct {
-	db *sqlx.DB
+	db                  *sqlx.DB
+	notificationService *NotificationService
 }
 
 func NewPasswordChangeService() *PasswordChangeService {
 	return &PasswordChangeService{
-		db: database.PostgresDB,
+		db:                  database.PostgresDB,
+		notificationService: NewNotificationService(),
 	}
 }
 
@@ -61,9 +77,7 @@ func (s *PasswordChangeService) CreatePasswordChangeRequest(userID uuid.UUID, us
 }
 
 // GetPasswordChangeRequests gets paginated list of password change requests (admin only)
-func (s *PasswordChangeService) GetPasswordChangeRequests(page, limit int, status string) (*models.PasswordChangeRequestListResponse, error) {
-	offset := (page - 1) * limit
-
+func (s *PasswordChangeService) GetPasswordChangeRequests(params models.PageParams, status string) (*models.Paginated[models.UserPasswordChangeRequest], error) {
 	// Build WHERE clause
 	whereClause := ""
 	args := []interface{}{}
@@ -90,7 +104,7 @@ func (s *PasswordChangeService) GetPasswordChangeRequests(page, limit int, statu
 		ORDER BY created_at DESC
 		LIMIT $` + fmt.Sprintf("%d", argIndex) + ` OFFSET $` + fmt.Sprintf("%d", argIndex+1)
 
-	args = append(args, limit, offset)
+	args = append(args, params.Limit, params.Offset())
 
 	var requests []models.UserPasswordChangeRequest
 	err = s.db.Select(&requests, query, args...)
@@ -98,12 +112,7 @@ func (s *PasswordChangeService) GetPasswordChangeRequests(page, limit int, statu
 		return nil, fmt.Errorf("failed to get password change requests: %w", err)
 	}
 
-	return &models.PasswordChangeRequestListResponse{
-		Requests:   requests,
-		TotalCount: totalCount,
-		Page:       page,
-		Limit:      limit,
-	}, nil
+	return models.NewPaginated(requests, totalCount, params), nil
 }
 
 // GetPasswordChangeRequest gets a single password change request by ID
@@ -119,39 +128,116 @@ func (s *PasswordChangeService) GetPasswordChangeRequest(id uuid.UUID) (*models.
 	return &request, nil
 }
 
-// UpdatePasswordChangeRequest updates a password change request status (admin only)
-func (s *PasswordChangeService) UpdatePasswordChangeRequest(id uuid.UUID, req models.UpdatePasswordChangeRequest, adminID uuid.UUID) (*models.UserPasswordChangeRequest, error) {
-	// Get existing request
-	existingRequest, err := s.GetPasswordChangeRequest(id)
+// UpdatePasswordChangeRequest approves or rejects a pending password change request (admin only).
+// Approving sets the user's password to the one given, or a generated one, and signs the user out of
+// every session in the same transaction that resolves the request.
+func (s *PasswordChangeService) UpdatePasswordChangeRequest(id uuid.UUID, req models.UpdatePasswordChangeRequest, adminID uuid.UUID) (*models.PasswordChangeResolution, error) {
+	approved := req.Status == "APPROVED"
+	resolution := &models.PasswordChangeResolution{}
+
+	var passwordHash string
+	var newPassword string
+	if approved {
+		if req.NewPassword != nil && *req.NewPassword != "" {
+			newPassword = *req.NewPassword
+			if len(newPassword) < minPasswordLength {
+				return nil, fmt.Errorf("%w: the new password must be at least %d characters", ErrInvalidPasswordChange, minPasswordLength)
+			}
+		} else {
+			generated, err := generatePassword()
+			if err != nil {
+				return nil, err
+			}
+			newPassword = generated
+			resolution.Password = generated
+		}
+		hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		passwordHash = string(hashed)
+	} else if req.NewPassword != nil || req.EmailCredentials {
+		return nil, fmt.Errorf("%w: a new password is only set when approving", ErrInvalidPasswordChange)
+	}
+
+	tx, err := s.db.Beginx()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to start password change: %w", err)
 	}
+	defer tx.Rollback()
 
+	var existingRequest models.UserPasswordChangeRequest
+	err = tx.Get(&existingRequest, `SELECT * FROM user_password_change_requests WHERE id = $1 FOR UPDATE`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPasswordChangeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password change request: %w", err)
+	}
 	if existingRequest.Status != "PENDING" {
-		return nil, fmt.Errorf("password change request has already been processed")
+		return nil, ErrPasswordChangeProcessed
 	}
 
 	// Update the request
-	now := time.Now()
 	query := `
 		UPDATE user_password_change_requests
 		SET status = $1, admin_notes = $2, admin_id = $3, updated_at = $4
 		WHERE id = $5
 	`
-
-	_, err = s.db.Exec(query, req.Status, req.AdminNotes, adminID, now, id)
-	if err != nil {
+	if _, err = tx.Exec(query, req.Status, req.AdminNotes, adminID, time.Now(), id); err != nil {
 		return nil, fmt.Errorf("failed to update password change request: %w", err)
 	}
 
+	var userEmail string
+	if approved {
+		err := tx.Get(&userEmail, `UPDATE users SET password_hash = $1, updated_at = now() WHERE id = $2 RETURNING email`,
+			passwordHash, existingRequest.UserID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update password: %w", err)
+		}
+
+		result, err := tx.Exec(`UPDATE user_sessions SET is_active = false, logged_out_at = now()
+		                        WHERE user_id = $1 AND is_active = true`, existingRequest.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to invalidate user sessions: %w", err)
+		}
+		resolution.SessionsInvalidated, _ = result.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit password change: %w", err)
+	}
+	if approved {
+		sessionCache.invalidateUser(existingRequest.UserID)
+		utils.LogInfo(fmt.Sprintf("Password of user %s changed by %s through request %s; %d sessions invalidated",
+			existingRequest.UserID, adminID, id, resolution.SessionsInvalidated))
+	}
+
 	// Return updated request
-	return s.GetPasswordChangeRequest(id)
+	updatedRequest, err := s.GetPasswordChangeRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	resolution.UserPasswordChangeRequest = *updatedRequest
+
+	// Credentials go to the user's current email address
+	notified := *updatedRequest
+	emailedPassword := ""
+	if approved && req.EmailCredentials {
+		notified.UserEmail = userEmail
+		emailedPassword = newPassword
+		resolution.CredentialsEmailed = s.notificationService.IsEnabled(EventPasswordChangeResolved)
+	}
+	s.notificationService.NotifyPasswordChangeResolved(&notified, emailedPassword)
+
+	return resolution, nil
 }
 
 // GetUserPasswordChangeRequests gets password change requests for a specific user
-func (s *PasswordChangeService) GetUserPasswordChangeRequests(userID uuid.UUID, page, limit int) (*models.PasswordChangeRequestListResponse, error) {
-	offset := (page - 1) * limit
-
+func (s *PasswordChangeService) GetUserPasswordChangeRequests(userID uuid.UUID, params models.PageParams) (*models.Paginated[models.UserPasswordChangeRequest], error) {
 	// Get total count
 	var totalCount int
 	err := s.db.Get(&totalCount, "SELECT COUNT(*) FROM user_password_change_requests WHERE user_id = $1", userID)
@@ -168,17 +254,12 @@ func (s *PasswordChangeService) GetUserPasswordChangeRequests(userID uuid.UUID,
 	`
 
 	var requests []models.UserPasswordChangeRequest
-	err = s.db.Select(&requests, query, userID, limit, offset)
+	err = s.db.Select(&requests, query, userID, params.Limit, params.Offset())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get password change requests: %w", err)
 	}
 
-	return &models.PasswordChangeRequestListResponse{
-		Requests:   requests,
-		TotalCount: totalCount,
-		Page:       page,
-		Limit:      limit,
-	}, nil
+	return models.NewPaginated(requests, totalCount, params), nil
 }
 
 // DeletePasswordChangeRequest deletes a password change request
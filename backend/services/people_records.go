@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// ErrPersonNotFound is returned when a person ID does not exist in the active people table
+var ErrPersonNotFound = errors.New("person not found")
+
+// personEditMu serializes manual edits so two admins cannot replace the same row at once
+var personEditMu sync.Mutex
+
+// PeopleRecordService lets admins fix individual people rows without a re-import. Every edit is
+// recorded in person_edits with the row before and after, and the replaced row is kept in the people
+// history.
+//
+// Rows are replaced rather than mutated in place, since mobile, name and master_id are part of the
+// table's sorting key, which ALTER TABLE ... UPDATE cannot change. A replaced or deleted row is
+// removed with a lightweight DELETE, which masks it from queries at once and drops it on the next merge.
+type PeopleRecordService struct{}
+
+func NewPeopleRecordService() *PeopleRecordService {
+	return &PeopleRecordService{}
+}
+
+// Create adds a person row to the active people table
+func (s *PeopleRecordService) Create(req *models.CreatePersonRequest, editedBy *uuid.UUID) (*models.Person, error) {
+	now := time.Now()
+	person := &models.Person{
+		ID:        uuid.New().String(),
+		MasterID:  strings.TrimSpace(req.MasterID),
+		Mobile:    utils.NormalizeMobile(req.Mobile),
+		MobileRaw: strings.TrimSpace(req.Mobile),
+		Name:      strings.TrimSpace(req.Name),
+		FName:     strings.TrimSpace(req.FName),
+		Address:   strings.TrimSpace(req.Address),
+		Alt:       utils.NormalizeMobile(req.Alt),
+		AltRaw:    strings.TrimSpace(req.Alt),
+		Circle:    strings.TrimSpace(req.Circle),
+		Email:     strings.TrimSpace(req.Email),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if person.Mobile == "" {
+		return nil, fmt.Errorf("mobile is required")
+	}
+	scorePerson(person)
+
+	personEditMu.Lock()
+	defer personEditMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	table := database.PeopleTable()
+	if err := s.insert(ctx, table, person); err != nil {
+		return nil, err
+	}
+
+	s.logEdit(person.ID, table, "CREATE", nil, person, req.Reason, editedBy)
+	utils.LogInfo(fmt.Sprintf("Person %s created in %s", person.ID, table))
+	return person, nil
+}
+
+// Update applies a correction to a person row, keeping its ID and creation time
+func (s *PeopleRecordService) Update(id string, req *models.UpdatePersonRequest, editedBy *uuid.UUID) (*models.Person, error) {
+	personEditMu.Lock()
+	defer personEditMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	table := database.PeopleTable()
+	before, err := s.get(ctx, table, id)
+	if err != nil {
+		return nil, err
+	}
+
+	after := *before
+	applyPersonUpdate(&after, req)
+	if req.Mobile != nil {
+		after.MobileRaw, after.Mobile = after.Mobile, utils.NormalizeMobile(after.Mobile)
+	}
+	if req.Alt != nil {
+		after.AltRaw, after.Alt = after.Alt, utils.NormalizeMobile(after.Alt)
+	}
+	if after.Mobile == "" {
+		return nil, fmt.Errorf("mobile cannot be empty")
+	}
+	after.UpdatedAt = time.Now()
+	scorePerson(&after)
+
+	if err := database.ArchivePeopleRows(ctx, table, []string{id}, models.PersonChangeEdit, "", ""); err != nil {
+		return nil, err
+	}
+	if err := s.delete(ctx, table, id); err != nil {
+		return nil, err
+	}
+	if err := s.insert(ctx, table, &after); err != nil {
+		// Put the original row back so a failed edit does not lose the record
+		if restoreErr := s.insert(ctx, table, before); restoreErr != nil {
+			utils.LogError(fmt.Sprintf("Failed to restore person %s after a failed update", id), restoreErr)
+		}
+		return nil, err
+	}
+
+	s.logEdit(id, table, "UPDATE", before, &after, req.Reason, editedBy)
+	utils.LogInfo(fmt.Sprintf("Person %s updated in %s", id, table))
+	return &after, nil
+}
+
+// Delete removes a person row; the row stays in the edit history
+func (s *PeopleRecordService) Delete(id, reason string, editedBy *uuid.UUID) error {
+	personEditMu.Lock()
+	defer personEditMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	table := database.PeopleTable()
+	before, err := s.get(ctx, table, id)
+	if err != nil {
+		return err
+	}
+	if err := database.ArchivePeopleRows(ctx, table, []string{id}, models.PersonChangeDelete, "", ""); err != nil {
+		return err
+	}
+	if err := s.delete(ctx, table, id); err != nil {
+		return err
+	}
+
+	s.logEdit(id, table, "DELETE", before, nil, reason, editedBy)
+	utils.LogInfo(fmt.Sprintf("Person %s deleted from %s", id, table))
+	return nil
+}
+
+// GetEdits returns the manual edits of a person row, newest first
+func (s *PeopleRecordService) GetEdits(personID string, limit int) ([]models.PersonEdit, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	edits := []models.PersonEdit{}
+	query := `SELECT id, person_id, people_table, action, before, after, reason, edited_by, edited_at
+			  FROM person_edits
+			  WHERE person_id = $1
+			  ORDER BY edited_at DESC
+			  LIMIT $2`
+	if err := database.PostgresDB.Select(&edits, query, personID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get person edits: %w", err)
+	}
+	return edits, nil
+}
+
+// get loads a person row from the given table
+func (s *PeopleRecordService) get(ctx context.Context, table, id string) (*models.Person, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPersonNotFound
+	}
+
+	var person models.Person
+	query := `SELECT ` + personColumns + `, ` + provenanceColumns + ` FROM ` + table + ` WHERE id = ? LIMIT 1`
+	if err := database.ClickHouseDB.QueryRow(ctx, query, id).ScanStruct(&person); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPersonNotFound
+		}
+		return nil, fmt.Errorf("failed to load person: %w", err)
+	}
+	return &person, nil
+}
+
+// insert writes a single person row, keeping the provenance of an edited imported row
+func (s *PeopleRecordService) insert(ctx context.Context, table string, person *models.Person) error {
+	batch, err := database.ClickHouseDB.PrepareBatch(ctx,
+		`INSERT INTO `+table+` (`+personColumns+`, `+provenanceColumns+`)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare person insert: %w", err)
+	}
+
+	err = batch.Append(
+		person.ID,
+		person.MasterID,
+		person.Mobile,
+		person.Name,
+		person.FName,
+		person.Address,
+		person.Alt,
+		person.Circle,
+		person.Email,
+		person.CreatedAt,
+		person.UpdatedAt,
+		person.Confidence,
+		person.QualityFlags,
+		person.SourceFile,
+		person.ImportJobID,
+		person.ImportedAt,
+		person.MobileRaw,
+		person.AltRaw,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to append person: %w", err)
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to insert person: %w", err)
+	}
+	return nil
+}
+
+// delete removes a person row with a lightweight DELETE
+func (s *PeopleRecordService) delete(ctx context.Context, table, id string) error {
+	if err := database.ClickHouseDB.Exec(ctx, `DELETE FROM `+table+` WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete person: %w", err)
+	}
+	return nil
+}
+
+// logEdit records a manual edit. Failures are logged, not returned, since the edit itself succeeded.
+func (s *PeopleRecordService) logEdit(personID, table, action string, before, after *models.Person, reason string, editedBy *uuid.UUID) {
+	// Untyped nils so a missing side is stored as NULL
+	var beforeJSON, afterJSON interface{}
+	if before != nil {
+		beforeJSON, _ = json.Marshal(before)
+	}
+	if after != nil {
+		afterJSON, _ = json.Marshal(after)
+	}
+
+	query := `INSERT INTO person_edits (person_id, people_table, action, before, after, reason, edited_by)
+			  VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)`
+	if _, err := database.PostgresDB.Exec(query, personID, table, action, beforeJSON, afterJSON, strings.TrimSpace(reason), editedBy); err != nil {
+		utils.LogError("Failed to record person edit", err)
+	}
+}
+
+// applyPersonUpdate copies the fields present in an update onto a person
+func applyPersonUpdate(person *models.Person, req *models.UpdatePersonRequest) {
+	fields := []struct {
+		value  *string
+		target *string
+	}{
+		{req.MasterID, &person.MasterID},
+		{req.Mobile, &person.Mobile},
+		{req.Name, &person.Name},
+		{req.FName, &person.FName},
+		{req.Address, &person.Address},
+		{req.Alt, &person.Alt},
+		{req.Circle, &person.Circle},
+		{req.Email, &person.Email},
+	}
+	for _, field := range fields {
+		if field.value != nil {
+			*field.target = strings.TrimSpace(*field.value)
+		}
+	}
+}
+
+// scorePerson recomputes the import-time quality fields of an edited row
+func scorePerson(person *models.Person) {
+	person.Confidence = utils.ConfidenceScore(person, person.UpdatedAt)
+	person.QualityFlags = utils.CheckConsistency(person)
+	if person.QualityFlags == nil {
+		person.QualityFlags = []string{}
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// peopleTableRefreshInterval is how often other instances pick up a switchover made elsewhere
+const peopleTableRefreshInterval = 30 * time.Second
+
+// requiredPeopleColumns are the columns searches, exports and imports read or write
+var requiredPeopleColumns = append(strings.Split(strings.ReplaceAll(personColumns+","+provenanceColumns, " ", ""), ","),
+	"pincode", "locality", "city", "state")
+
+// PeopleTableService manages which ClickHouse table holds the searchable people data, so a rebuilt
+// table can be loaded alongside the live one and cut over to without downtime
+type PeopleTableService struct{}
+
+func NewPeopleTableService() *PeopleTableService {
+	return &PeopleTableService{}
+}
+
+// LoadActiveTable applies the latest recorded switchover, falling back to the configured table
+func (s *PeopleTableService) LoadActiveTable() error {
+	var table string
+	query := `SELECT to_table FROM people_table_switchovers ORDER BY switched_at DESC LIMIT 1`
+	err := database.PostgresDB.Get(&table, query)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load active people table: %w", err)
+	}
+	if !database.ValidTableName(table) {
+		return fmt.Errorf("recorded people table has an invalid name: %s", table)
+	}
+
+	if table != database.PeopleTableName() {
+		utils.LogInfo(fmt.Sprintf("Using people table %s", database.QualifiedTable(table)))
+		database.SetPeopleTable(table)
+	}
+	return nil
+}
+
+// StartRefresher periodically reloads the active table so every instance follows a switchover
+func (s *PeopleTableService) StartRefresher() {
+	go func() {
+		ticker := time.NewTicker(peopleTableRefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.LoadActiveTable(); err != nil {
+				utils.LogError("Failed to refresh active people table", err)
+			}
+		}
+	}()
+}
+
+// GetStatus returns the active people table, the tables available to switch to and recent switchovers
+func (s *PeopleTableService) GetStatus() (*models.PeopleTableStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tables, err := s.listTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []models.PeopleTableSwitchover{}
+	query := `SELECT * FROM people_table_switchovers ORDER BY switched_at DESC LIMIT 20`
+	if err := database.PostgresDB.Select(&history, query); err != nil {
+		return nil, fmt.Errorf("failed to get switchover history: %w", err)
+	}
+
+	defaultTable := "people"
+	if config.Get() != nil {
+		defaultTable = config.Get().Database.ClickHouse.PeopleTable
+	}
+
+	return &models.PeopleTableStatus{
+		ActiveTable:  database.PeopleTableName(),
+		DefaultTable: defaultTable,
+		Tables:       tables,
+		History:      history,
+	}, nil
+}
+
+// CreateTable creates an empty table with the same columns, indexes and engine as the active table,
+// ready to be loaded by an import with a target table
+func (s *PeopleTableService) CreateTable(name string) (*models.PeopleTableInfo, error) {
+	if !database.ValidTableName(name) {
+		return nil, fmt.Errorf("invalid table name: %s", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := s.getTable(ctx, name); err == nil {
+		return nil, fmt.Errorf("table already exists: %s", name)
+	}
+
+	query := fmt.Sprintf("CREATE TABLE %s AS %s", database.QualifiedTable(name), database.PeopleTable())
+	if err := database.ClickHouseDB.Exec(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to create table %s: %w", name, err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Created people table %s from %s", database.QualifiedTable(name), database.PeopleTable()))
+	return s.getTable(ctx, name)
+}
+
+// ValidateTable checks that a table exists and has every people column, returning its details
+func (s *PeopleTableService) ValidateTable(name string) (*models.PeopleTableInfo, error) {
+	if !database.ValidTableName(name) {
+		return nil, fmt.Errorf("invalid table name: %s", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	table, err := s.getTable(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(table.MissingColumns) > 0 {
+		return nil, fmt.Errorf("table %s is missing columns: %s", name, strings.Join(table.MissingColumns, ", "))
+	}
+	return table, nil
+}
+
+// Switch makes another table the active people table. In-flight queries finish on the old table and
+// every query started afterwards uses the new one; the old table is kept for rollback.
+func (s *PeopleTableService) Switch(req *models.SwitchPeopleTableRequest, switchedBy uuid.UUID) (*models.PeopleTableSwitchover, error) {
+	table, err := s.ValidateTable(req.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	fromTable := database.PeopleTableName()
+	if table.Name == fromTable {
+		return nil, fmt.Errorf("table %s is already active", table.Name)
+	}
+	if table.TotalRows == 0 && !req.AllowEmpty {
+		return nil, fmt.Errorf("table %s is empty; set allow_empty to switch anyway", table.Name)
+	}
+
+	var switchover models.PeopleTableSwitchover
+	query := `INSERT INTO people_table_switchovers (from_table, to_table, row_count, switched_by)
+	          VALUES ($1, $2, $3, $4)
+	          RETURNING *`
+	if err := database.PostgresDB.Get(&switchover, query, fromTable, table.Name, int64(table.TotalRows), switchedBy); err != nil {
+		return nil, fmt.Errorf("failed to record switchover: %w", err)
+	}
+
+	database.SetPeopleTable(table.Name)
+	utils.LogInfo(fmt.Sprintf("Switched people table from %s to %s (%d rows)",
+		database.QualifiedTable(fromTable), database.QualifiedTable(table.Name), table.TotalRows))
+
+	return &switchover, nil
+}
+
+// listTables returns the tables in the people database that have a mobile column
+func (s *PeopleTableService) listTables(ctx context.Context) ([]models.PeopleTableInfo, error) {
+	var tables []models.PeopleTableInfo
+	query := `SELECT name, engine, ifNull(total_rows, 0) AS total_rows, ifNull(total_bytes, 0) AS total_bytes
+	          FROM system.tables
+	          WHERE database = ? AND engine LIKE '%MergeTree'
+	            AND name IN (SELECT table FROM system.columns WHERE database = ? AND name = 'mobile')
+	          ORDER BY name`
+	if err := database.ClickHouseDB.Select(ctx, &tables, query, database.PeopleDatabase, database.PeopleDatabase); err != nil {
+		return nil, fmt.Errorf("failed to list people tables: %w", err)
+	}
+
+	columns, err := s.tableColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := database.PeopleTableName()
+	for i := range tables {
+		tables[i].Active = tables[i].Name == active
+		tables[i].MissingColumns = missingPeopleColumns(columns[tables[i].Name])
+	}
+	return tables, nil
+}
+
+// getTable returns a single table from the people database
+func (s *PeopleTableService) getTable(ctx context.Context, name string) (*models.PeopleTableInfo, error) {
+	var table models.PeopleTableInfo
+	query := `SELECT name, engine, ifNull(total_rows, 0) AS total_rows, ifNull(total_bytes, 0) AS total_bytes
+	          FROM system.tables
+	          WHERE database = ? AND name = ?`
+	if err := database.ClickHouseDB.QueryRow(ctx, query, database.PeopleDatabase, name).ScanStruct(&table); err != nil {
+		return nil, fmt.Errorf("table not found: %s", name)
+	}
+
+	columns, err := s.tableColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	table.Active = table.Name == database.PeopleTableName()
+	table.MissingColumns = missingPeopleColumns(columns[table.Name])
+	return &table, nil
+}
+
+// tableColumns returns the column names of every table in the people database
+func (s *PeopleTableService) tableColumns(ctx context.Context) (map[string]map[string]bool, error) {
+	rows, err := database.ClickHouseDB.Query(ctx,
+		`SELECT table, name FROM system.columns WHERE database = ?`, database.PeopleDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list table columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("failed to read table columns: %w", err)
+		}
+		if columns[table] == nil {
+			columns[table] = make(map[string]bool)
+		}
+		columns[table][column] = true
+	}
+	return columns, rows.Err()
+}
+
+func missingPeopleColumns(columns map[string]bool) []string {
+	var missing []string
+	for _, column := range requiredPeopleColumns {
+		if !columns[column] {
+			missing = append(missing, column)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// Limits of a person graph
+const (
+	DefaultPersonGraphDepth    = 2
+	MaxPersonGraphDepth        = 3
+	DefaultPersonGraphMaxNodes = 50
+	MaxPersonGraphMaxNodes     = 200
+)
+
+// Attributes linking people in a person graph
+const (
+	graphLinkMasterID  = "master_id"
+	graphLinkEmail     = "email"
+	graphLinkMobile    = "mobile"
+	graphLinkAlt       = "alt"
+	graphLinkMobileAlt = "mobile_alt" // One's mobile is the other's alt number
+)
+
+// GetPersonGraph returns the people linked to a person by a shared master ID, mobile or alt number
+// or email, expanding depth hops out from them and stopping at maxNodes people. Users with restricted
+// search fields are only linked through the fields they may search. The people are masked with the
+// user's field visibility policy, as are the values labelling the edges.
+func (s *SearchService) GetPersonGraph(userID uuid.UUID, id, table string, depth, maxNodes int) (*models.PersonGraph, error) {
+	links, err := s.graphLinks(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := searchContext(context.Background(), userID)
+	defer cancel()
+	ctx = database.WithPeopleTable(ctx, table)
+
+	root, err := s.backend.GetPerson(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPersonNotFound, err)
+	}
+
+	graph := &models.PersonGraph{RootID: root.ID, Depth: depth, MaxNodes: maxNodes, Edges: []models.PersonGraphEdge{}}
+	people := []models.Person{*root}
+	depths := map[string]int{root.ID: 0}
+	frontier := []models.Person{*root}
+	queried := make(map[string]bool) // Attribute values already expanded, so cycles end
+
+	for hop := 1; hop <= depth && len(frontier) > 0 && !graph.Truncated; hop++ {
+		var numbers, masterIDs, emails []string
+		for _, person := range frontier {
+			for _, value := range graphValues(&person, links) {
+				key := value.attribute + ":" + value.value
+				if queried[key] {
+					continue
+				}
+				queried[key] = true
+				switch value.attribute {
+				case graphLinkMasterID:
+					masterIDs = append(masterIDs, value.value)
+				case graphLinkEmail:
+					emails = append(emails, value.value)
+				default:
+					numbers = append(numbers, numberValues(value.value)...)
+				}
+			}
+		}
+		numbers = slices.Compact(slices.Sorted(slices.Values(numbers)))
+
+		// The results can include everyone already in the graph, so one more than maxNodes tells whether
+		// new people were left out
+		linked, err := s.backend.FindLinked(ctx, numbers, masterIDs, emails, maxNodes+1)
+		if err != nil {
+			return nil, fmt.Errorf("linked people search failed: %w", err)
+		}
+
+		frontier = nil
+		for _, person := range linked {
+			if _, ok := depths[person.ID]; ok {
+				continue
+			}
+			if len(people) == maxNodes {
+				graph.Truncated = true
+				break
+			}
+			depths[person.ID] = hop
+			people = append(people, person)
+			frontier = append(frontier, person)
+		}
+	}
+
+	graph.Edges = append(graph.Edges, graphEdges(people, links)...)
+
+	s.MaskResults(userID, people)
+	masked := s.maskedGraphFields(userID)
+	masking := NewFieldMaskingService()
+	for i, edge := range graph.Edges {
+		field := edge.Attribute
+		if field == graphLinkMobileAlt {
+			field = graphLinkMobile
+		}
+		if masked[field] || (edge.Attribute == graphLinkMobileAlt && masked[graphLinkAlt]) {
+			graph.Edges[i].Value = masking.MaskValue(field, edge.Value)
+		}
+	}
+
+	for _, person := range people {
+		graph.Nodes = append(graph.Nodes, models.PersonGraphNode{ID: person.ID, Depth: depths[person.ID], Person: person})
+	}
+	return graph, nil
+}
+
+// graphLinks returns the attributes a user's graphs link people through: all of them, or the ones
+// among their allowed search fields
+func (s *SearchService) graphLinks(userID uuid.UUID) (map[string]bool, error) {
+	links := map[string]bool{graphLinkMasterID: true, graphLinkEmail: true, graphLinkMobile: true, graphLinkAlt: true}
+	allowed, err := s.allowedSearchFields(userID)
+	if err != nil || allowed == nil {
+		return links, err
+	}
+	linkable := false
+	for link := range links {
+		links[link] = allowed[link]
+		linkable = linkable || allowed[link]
+	}
+	if !linkable {
+		return nil, fmt.Errorf("%w: master_id, email, mobile or alt", ErrSearchFieldNotAllowed)
+	}
+	return links, nil
+}
+
+// maskedGraphFields returns the fields masked for a user, masking everything when the policy cannot be loaded
+func (s *SearchService) maskedGraphFields(userID uuid.UUID) map[string]bool {
+	fields, err := NewFieldMaskingService().GetMaskedFieldsForUser(userID)
+	if err != nil {
+		return maskableFields
+	}
+	masked := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		masked[field] = true
+	}
+	return masked
+}
+
+type graphValue struct {
+	attribute string
+	value     string
+}
+
+// graphValues returns the attribute values a person can be linked through. Mobile and alt numbers
+// are both looked up as numbers; master IDs that are masked or partial are skipped.
+func graphValues(person *models.Person, links map[string]bool) []graphValue {
+	var values []graphValue
+	if links[graphLinkMasterID] && utils.IsValidMasterID(person.MasterID) {
+		values = append(values, graphValue{graphLinkMasterID, person.MasterID})
+	}
+	if email := strings.ToLower(strings.TrimSpace(person.Email)); links[graphLinkEmail] && strings.Contains(email, "@") {
+		values = append(values, graphValue{graphLinkEmail, email})
+	}
+	if links[graphLinkMobile] && person.Mobile != "" {
+		values = append(values, graphValue{graphLinkMobile, utils.NormalizeMobile(person.Mobile)})
+	}
+	if links[graphLinkAlt] && person.Alt != "" {
+		values = append(values, graphValue{graphLinkAlt, utils.NormalizeMobile(person.Alt)})
+	}
+	return values
+}
+
+// graphEdges links every pair of people sharing an attribute value, one edge per attribute
+func graphEdges(people []models.Person, links map[string]bool) []models.PersonGraphEdge {
+	var edges []models.PersonGraphEdge
+	for i := range people {
+		for j := i + 1; j < len(people); j++ {
+			a, b := &people[i], &people[j]
+			if links[graphLinkMasterID] && a.MasterID == b.MasterID && utils.IsValidMasterID(a.MasterID) {
+				edges = append(edges, models.PersonGraphEdge{Source: a.ID, Target: b.ID, Attribute: graphLinkMasterID, Value: a.MasterID})
+			}
+			if email := strings.ToLower(strings.TrimSpace(a.Email)); links[graphLinkEmail] && strings.Contains(email, "@") &&
+				email == strings.ToLower(strings.TrimSpace(b.Email)) {
+				edges = append(edges, models.PersonGraphEdge{Source: a.ID, Target: b.ID, Attribute: graphLinkEmail, Value: email})
+			}
+			// Numbers are compared in their canonical form, so rows imported before numbers were normalized link too
+			aMobile, aAlt := utils.NormalizeMobile(a.Mobile), utils.NormalizeMobile(a.Alt)
+			bMobile, bAlt := utils.NormalizeMobile(b.Mobile), utils.NormalizeMobile(b.Alt)
+			if links[graphLinkMobile] && aMobile != "" && aMobile == bMobile {
+				edges = append(edges, models.PersonGraphEdge{Source: a.ID, Target: b.ID, Attribute: graphLinkMobile, Value: aMobile})
+			}
+			if links[graphLinkAlt] && aAlt != "" && aAlt == bAlt {
+				edges = append(edges, models.PersonGraphEdge{Source: a.ID, Target: b.ID, Attribute: graphLinkAlt, Value: aAlt})
+			}
+			if links[graphLinkMobile] && links[graphLinkAlt] {
+				if aMobile != "" && aMobile == bAlt {
+					edges = append(edges, models.PersonGraphEdge{Source: a.ID, Target: b.ID, Attribute: graphLinkMobileAlt, Value: aMobile})
+				}
+				if aAlt != "" && aAlt == bMobile {
+					edges = append(edges, models.PersonGraphEdge{Source: b.ID, Target: a.ID, Attribute: graphLinkMobileAlt, Value: bMobile})
+				}
+			}
+		}
+	}
+	return edges
+}
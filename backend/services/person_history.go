@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+)
+
+// Limits of the versions returned by GetPersonHistory
+const (
+	DefaultPersonHistoryLimit = 50
+	MaxPersonHistoryLimit     = 200
+)
+
+// GetPersonHistory returns the current version of a person row from a dataset table, or the active
+// people table when table is empty, and up to limit prior versions replaced by imports with
+// update_existing or by manual edits. A deleted row has no current version but keeps its history.
+// Every version is masked with the user's field visibility policy.
+func (s *SearchService) GetPersonHistory(userID uuid.UUID, id, table string, limit int) (*models.PersonHistory, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPersonNotFound
+	}
+
+	ctx, cancel := searchContext(context.Background(), userID)
+	defer cancel()
+	ctx = database.WithPeopleTable(ctx, table)
+
+	history := &models.PersonHistory{PersonID: id, Versions: []models.PersonVersion{}}
+	if current, err := s.backend.GetPerson(ctx, id); err == nil {
+		history.Current = current
+	}
+
+	query := `SELECT ` + personColumns + `, ` + provenanceColumns + `, people_table, change, superseded_at, superseded_by_job_id
+			  FROM ` + database.PeopleHistoryTable + `
+			  WHERE id = ?
+			  ORDER BY superseded_at DESC
+			  LIMIT ?`
+	rows, err := database.ClickHouseDB.Query(ctx, query, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get person history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version models.PersonVersion
+		p := &version.Person
+		if err := rows.Scan(&p.ID, &p.MasterID, &p.Mobile, &p.Name, &p.FName, &p.Address, &p.Alt, &p.Circle, &p.Email,
+			&p.CreatedAt, &p.UpdatedAt, &p.Confidence, &p.QualityFlags, &p.SourceFile, &p.ImportJobID, &p.ImportedAt,
+			&p.MobileRaw, &p.AltRaw, &version.PeopleTable, &version.Change, &version.SupersededAt, &version.SupersededByJobID); err != nil {
+			return nil, fmt.Errorf("failed to scan person version: %w", err)
+		}
+		history.Versions = append(history.Versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read person history: %w", err)
+	}
+
+	if history.Current == nil && len(history.Versions) == 0 {
+		return nil, ErrPersonNotFound
+	}
+
+	// Mask every version together, so the policy is loaded once
+	people := make([]models.Person, 0, len(history.Versions)+1)
+	for _, version := range history.Versions {
+		people = append(people, version.Person)
+	}
+	if history.Current != nil {
+		people = append(people, *history.Current)
+	}
+	s.MaskResults(userID, people)
+	for i := range history.Versions {
+		history.Versions[i].Person = people[i]
+	}
+	if history.Current != nil {
+		history.Current = &people[len(people)-1]
+	}
+
+	return history, nil
+}
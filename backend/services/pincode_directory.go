@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// pincodeDirectoryTable maps pincodes to address components; the people table's locality, city
+// and state columns are materialized from it with joinGet
+const pincodeDirectoryTable = "finone_search.pincode_directory"
+
+// addressComponentColumns are the people columns derived from the pincode directory
+var addressComponentColumns = []string{"locality", "city", "state"}
+
+var sixDigitPincode = regexp.MustCompile(`^\d{6}$`)
+
+// maxReportedPincodeErrors caps the skipped row messages returned by an import
+const maxReportedPincodeErrors = 100
+
+type PincodeDirectoryService struct{}
+
+func NewPincodeDirectoryService() *PincodeDirectoryService {
+	return &PincodeDirectoryService{}
+}
+
+// ImportCSV replaces the pincode directory with a CSV that has a header row naming its pincode, city
+// and state columns (locality is optional). Rows with an invalid pincode are skipped; a pincode listed
+// more than once keeps its last row.
+func (s *PincodeDirectoryService) ImportCSV(r io.Reader) (*models.PincodeImportResponse, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"pincode", "city", "state"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must include a %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		position, ok := columns[name]
+		if !ok || position >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[position])
+	}
+
+	response := &models.PincodeImportResponse{}
+	entries := make(map[string]models.AddressComponents)
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			s.skipRow(response, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+
+		pincode := field(record, "pincode")
+		if !sixDigitPincode.MatchString(pincode) {
+			s.skipRow(response, fmt.Sprintf("line %d: invalid pincode %q", line, pincode))
+			continue
+		}
+		entries[pincode] = models.AddressComponents{
+			Pincode:  pincode,
+			Locality: field(record, "locality"),
+			City:     field(record, "city"),
+			State:    field(record, "state"),
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("CSV contains no valid pincodes")
+	}
+
+	if err := s.replaceDirectory(entries); err != nil {
+		return nil, err
+	}
+
+	response.Entries = len(entries)
+	utils.LogInfo(fmt.Sprintf("Pincode directory replaced: %d entries, %d rows skipped", response.Entries, response.SkippedRows))
+	return response, nil
+}
+
+// replaceDirectory swaps the directory contents. Rows imported while it is being reloaded may get
+// empty address components; RematerializePeople recomputes them.
+func (s *PincodeDirectoryService) replaceDirectory(entries map[string]models.AddressComponents) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := database.ClickHouseDB.Exec(ctx, "TRUNCATE TABLE "+pincodeDirectoryTable); err != nil {
+		return fmt.Errorf("failed to clear pincode directory: %w", err)
+	}
+
+	batch, err := database.ClickHouseDB.PrepareBatch(ctx,
+		"INSERT INTO "+pincodeDirectoryTable+" (pincode, locality, city, state)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare pincode batch: %w", err)
+	}
+	for _, entry := range entries {
+		if err := batch.Append(entry.Pincode, entry.Locality, entry.City, entry.State); err != nil {
+			return fmt.Errorf("failed to append pincode %s: %w", entry.Pincode, err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to load pincode directory: %w", err)
+	}
+	return nil
+}
+
+// RematerializePeople starts mutations recomputing the address components of existing people rows
+// from the current directory
+func (s *PincodeDirectoryService) RematerializePeople() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, column := range addressComponentColumns {
+		query := fmt.Sprintf("ALTER TABLE %s MATERIALIZE COLUMN %s", database.PeopleTable(), column)
+		if err := database.ClickHouseDB.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to materialize column %s: %w", column, err)
+		}
+	}
+
+	utils.LogInfo("Started rematerializing address components on " + database.PeopleTable())
+	return nil
+}
+
+// GetStatus returns the number of pincodes in the directory
+func (s *PincodeDirectoryService) GetStatus() (*models.PincodeDirectoryStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entries uint64
+	if err := database.ClickHouseDB.QueryRow(ctx, "SELECT count() FROM "+pincodeDirectoryTable).Scan(&entries); err != nil {
+		return nil, fmt.Errorf("failed to count pincode directory: %w", err)
+	}
+
+	return &models.PincodeDirectoryStatus{
+		Entries: entries,
+		Fields:  addressComponentColumns,
+	}, nil
+}
+
+// skipRow counts a skipped row and keeps its message, up to maxReportedPincodeErrors
+func (s *PincodeDirectoryService) skipRow(response *models.PincodeImportResponse, message string) {
+	response.SkippedRows++
+	if len(response.Errors) < maxReportedPincodeErrors {
+		response.Errors = append(response.Errors, message)
+	}
+}
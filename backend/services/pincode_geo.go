@@ -0,0 +1,302 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+//go:embed data/pincode_geo.csv
+var bundledPincodeGeo string
+
+// maxNearbyRadiusKm caps nearby searches so the expanded pincode list stays small
+const maxNearbyRadiusKm = 100
+
+// maxNearbyPrefixes caps the explicit pincode prefixes of a nearby search
+const maxNearbyPrefixes = 50
+
+// ErrUnknownNearbyPincode is returned for a nearby search around a pincode missing from the pincode
+// locations, which would otherwise match nothing
+var ErrUnknownNearbyPincode = errors.New("pincode has no known location")
+
+// distanceBucketEdgesKm are the upper bounds of the distance buckets reported by nearby searches
+var distanceBucketEdgesKm = []float64{2, 5, 10, 25, 50, maxNearbyRadiusKm}
+
+type geoPoint struct {
+	lat, lon float64
+}
+
+// pincodeGeo is loaded on first use, from search.pincode_geo_file when set or the bundled dataset
+// otherwise, and kept until the pincode_locations cache is flushed
+var pincodeGeo struct {
+	mu     sync.Mutex
+	points map[string]geoPoint // nil until loaded
+}
+
+// pincodeLocations returns the coordinates of every known pincode
+func pincodeLocations() map[string]geoPoint {
+	pincodeGeo.mu.Lock()
+	defer pincodeGeo.mu.Unlock()
+
+	if pincodeGeo.points == nil {
+		pincodeGeo.points = loadPincodeLocations()
+	}
+	return pincodeGeo.points
+}
+
+func loadPincodeLocations() map[string]geoPoint {
+	if config.Get() != nil && config.Get().Search.PincodeGeoFile != "" {
+		path := config.Get().Search.PincodeGeoFile
+		file, err := os.Open(path)
+		var points map[string]geoPoint
+		if err == nil {
+			defer file.Close()
+			points, err = parsePincodeGeo(file)
+		}
+		if err == nil {
+			utils.LogInfo(fmt.Sprintf("Loaded %d pincode locations from %s", len(points), path))
+			return points
+		}
+		utils.LogError("Failed to load pincode locations from "+path+", using bundled data", err)
+	}
+
+	points, err := parsePincodeGeo(strings.NewReader(bundledPincodeGeo))
+	if err != nil {
+		utils.LogError("Failed to parse bundled pincode locations", err)
+		return map[string]geoPoint{}
+	}
+	utils.LogWarning(fmt.Sprintf("Using the %d bundled Delhi pincode locations; nearby searches around other pincodes are "+
+		"rejected until search.pincode_geo_file points at a national dataset", len(points)))
+	return points
+}
+
+// flushPincodeLocations drops the loaded pincode locations so the next nearby search reloads them,
+// returning how many there were
+func flushPincodeLocations() int {
+	pincodeGeo.mu.Lock()
+	defer pincodeGeo.mu.Unlock()
+
+	entries := len(pincodeGeo.points)
+	pincodeGeo.points = nil
+	return entries
+}
+
+// pincodeLocationStats returns the number of loaded pincode locations and an estimate of their size in bytes
+func pincodeLocationStats() (entries, approxBytes int) {
+	pincodeGeo.mu.Lock()
+	defer pincodeGeo.mu.Unlock()
+
+	// Six-digit key, string header and two float64 coordinates per pincode
+	return len(pincodeGeo.points), len(pincodeGeo.points) * (6 + 16 + 16)
+}
+
+// parsePincodeGeo reads a CSV with a pincode,latitude,longitude header; lines starting with # are skipped
+func parsePincodeGeo(r io.Reader) (map[string]geoPoint, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"pincode", "latitude", "longitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("header must include a %s column", required)
+		}
+	}
+
+	points := make(map[string]geoPoint)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		pincode := strings.TrimSpace(record[columns["pincode"]])
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(record[columns["latitude"]]), 64)
+		lon, lonErr := strconv.ParseFloat(strings.TrimSpace(record[columns["longitude"]]), 64)
+		if !sixDigitPincode.MatchString(pincode) || latErr != nil || lonErr != nil {
+			continue
+		}
+		points[pincode] = geoPoint{lat: lat, lon: lon}
+	}
+	return points, nil
+}
+
+// expandNearby resolves a nearby filter to the pincodes within its radius, nearest first, and
+// validates its explicit prefixes
+func expandNearby(near *models.NearbyFilter) ([]models.NearbyPincode, error) {
+	near.Pincode = strings.TrimSpace(near.Pincode)
+	for i, prefix := range near.Prefixes {
+		near.Prefixes[i] = strings.TrimSpace(prefix)
+		if !isDigits(near.Prefixes[i]) || len(near.Prefixes[i]) > 6 {
+			return nil, fmt.Errorf("invalid pincode prefix: %s", prefix)
+		}
+	}
+	if len(near.Prefixes) > maxNearbyPrefixes {
+		return nil, fmt.Errorf("at most %d pincode prefixes are allowed", maxNearbyPrefixes)
+	}
+
+	if near.Pincode == "" {
+		if len(near.Prefixes) == 0 {
+			return nil, fmt.Errorf("near requires a pincode or pincode prefixes")
+		}
+		return nil, nil
+	}
+	if near.RadiusKm <= 0 || near.RadiusKm > maxNearbyRadiusKm {
+		return nil, fmt.Errorf("radius_km must be between 0 and %d", maxNearbyRadiusKm)
+	}
+
+	locations := pincodeLocations()
+	center, ok := locations[near.Pincode]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not among the %d pincodes nearby searches can locate",
+			ErrUnknownNearbyPincode, near.Pincode, len(locations))
+	}
+
+	var nearby []models.NearbyPincode
+	for pincode, point := range locations {
+		distance := haversineKm(center, point)
+		if distance <= near.RadiusKm {
+			nearby = append(nearby, models.NearbyPincode{
+				Pincode:    pincode,
+				DistanceKm: math.Round(distance*10) / 10,
+				Bucket:     distanceBucket(distance),
+			})
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		if nearby[i].DistanceKm != nearby[j].DistanceKm {
+			return nearby[i].DistanceKm < nearby[j].DistanceKm
+		}
+		return nearby[i].Pincode < nearby[j].Pincode
+	})
+	return nearby, nil
+}
+
+// distanceBuckets groups the matches per nearby pincode into the distance buckets up to the radius
+func distanceBuckets(nearby []models.NearbyPincode, counts map[string]uint64, radiusKm float64) []models.DistanceBucket {
+	var buckets []models.DistanceBucket
+	lower := 0.0
+	for _, upper := range distanceBucketEdgesKm {
+		if lower >= radiusKm {
+			break
+		}
+		buckets = append(buckets, models.DistanceBucket{Label: bucketLabel(lower, upper), MinKm: lower, MaxKm: upper})
+		lower = upper
+	}
+
+	index := make(map[string]int, len(buckets))
+	for i, bucket := range buckets {
+		index[bucket.Label] = i
+	}
+	for _, pincode := range nearby {
+		bucket := &buckets[index[pincode.Bucket]]
+		bucket.Pincodes++
+		bucket.Count += counts[pincode.Pincode]
+	}
+	return buckets
+}
+
+// distanceBucket returns the label of the bucket a distance falls in
+func distanceBucket(distanceKm float64) string {
+	lower := 0.0
+	for _, upper := range distanceBucketEdgesKm {
+		if distanceKm <= upper {
+			return bucketLabel(lower, upper)
+		}
+		lower = upper
+	}
+	return bucketLabel(lower, maxNearbyRadiusKm)
+}
+
+func bucketLabel(lower, upper float64) string {
+	return fmt.Sprintf("%g-%gkm", lower, upper)
+}
+
+// haversineKm returns the great-circle distance between two points
+func haversineKm(a, b geoPoint) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(b.lat - a.lat)
+	dLon := toRad(b.lon - a.lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(a.lat))*math.Cos(toRad(b.lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// nearbyCondition returns the ClickHouse condition restricting a search to its nearby pincodes, or ""
+// when the request has no nearby filter. Pincodes and prefixes are validated digits, so they are inlined.
+func nearbyCondition(req *models.SearchRequest) string {
+	if req.Near == nil {
+		return ""
+	}
+
+	conditions := []string{"0"} // Matches nothing when no pincode lies within the radius
+	var pincodes []string
+	for _, pincode := range req.NearPincodes {
+		if sixDigitPincode.MatchString(pincode) {
+			pincodes = append(pincodes, pincode)
+		}
+	}
+	if len(pincodes) > 0 {
+		conditions = append(conditions, "pincode IN ('"+strings.Join(pincodes, "', '")+"')")
+	}
+	for _, prefix := range req.Near.Prefixes {
+		if isDigits(prefix) {
+			conditions = append(conditions, "startsWith(pincode, '"+prefix+"')")
+		}
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// matchesNearby applies a request's nearby filter to a pincode
+func matchesNearby(pincode string, req *models.SearchRequest) bool {
+	if req.Near == nil {
+		return true
+	}
+	for _, near := range req.NearPincodes {
+		if pincode == near {
+			return true
+		}
+	}
+	for _, prefix := range req.Near.Prefixes {
+		if pincode != "" && strings.HasPrefix(pincode, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
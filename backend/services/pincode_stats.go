@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+)
+
+// Limits of the top circles and name tokens in pincode statistics
+const (
+	DefaultPincodeStatsTop = 10
+	MaxPincodeStatsTop     = 50
+)
+
+// ErrInvalidPincode is returned for a pincode that is not six digits
+var ErrInvalidPincode = errors.New("pincode must be 6 digits")
+
+// GetPincodeStats summarizes the records of a pincode in a dataset table, or the active people table
+// when table is empty: how many there are, where the pincode directory places it, and the top circles
+// and name tokens. Users whose allowed search fields leave out pincode cannot see it, and a breakdown
+// of a field the user's field visibility policy masks is left out.
+func (s *SearchService) GetPincodeStats(userID uuid.UUID, pincode, table string, top int) (*models.PincodeStats, error) {
+	if !sixDigitPincode.MatchString(pincode) {
+		return nil, ErrInvalidPincode
+	}
+
+	allowed, err := s.allowedSearchFields(userID)
+	if err != nil {
+		return nil, err
+	}
+	if allowed != nil && !allowed["pincode"] {
+		return nil, fmt.Errorf("%w: pincode", ErrSearchFieldNotAllowed)
+	}
+
+	ctx, cancel := searchContext(context.Background(), userID)
+	defer cancel()
+	table = database.PeopleTableFor(database.WithPeopleTable(ctx, table))
+
+	stats := &models.PincodeStats{Pincode: pincode, Table: table}
+	query := `SELECT count(), uniqExact(master_id), any(locality), any(city), any(state) FROM ` + table + ` WHERE pincode = ?`
+	if err := database.ClickHouseDB.QueryRow(ctx, query, pincode).Scan(
+		&stats.TotalRecords, &stats.UniqueMasterIDs, &stats.Locality, &stats.City, &stats.State); err != nil {
+		return nil, fmt.Errorf("failed to count pincode records: %w", err)
+	}
+
+	masked := s.maskedGraphFields(userID)
+	if !masked["circle"] {
+		stats.TopCircles = []models.FacetCount{}
+		query := `SELECT upper(circle) AS value, count() AS count FROM ` + table + `
+		          WHERE pincode = ? AND circle != ''
+		          GROUP BY value
+		          ORDER BY count DESC, value
+		          LIMIT ?`
+		if err := database.ClickHouseDB.Select(ctx, &stats.TopCircles, query, pincode, top); err != nil {
+			return nil, fmt.Errorf("failed to count pincode circles: %w", err)
+		}
+	}
+	if !masked["name"] {
+		// Single letters are initials, which say little about coverage
+		stats.TopNameTokens = []models.FacetCount{}
+		query := `SELECT token AS value, count() AS count FROM ` + table + `
+		          ARRAY JOIN splitByWhitespace(upper(name)) AS token
+		          WHERE pincode = ? AND length(token) > 1
+		          GROUP BY value
+		          ORDER BY count DESC, value
+		          LIMIT ?`
+		if err := database.ClickHouseDB.Select(ctx, &stats.TopNameTokens, query, pincode, top); err != nil {
+			return nil, fmt.Errorf("failed to count pincode name tokens: %w", err)
+		}
+	}
+
+	return stats, nil
+}
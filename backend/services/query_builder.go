@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// queryPlanSettings encourage better planning of people queries
+const queryPlanSettings = " SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1"
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// QueryBuilder builds the WHERE clause of a people query. Searches, counts, facets, searches within
+// results and exports all take their conditions from it, so each of them matches the same rows.
+type QueryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// AddSearch adds the conditions of a search request: its field queries, else its query over its
+// fields, else its query over every searchable field, joined by the request's logic; then its
+// quality and nearby filters
+func (q *QueryBuilder) AddSearch(req *models.SearchRequest) *QueryBuilder {
+	var conditions []string
+	var args []interface{}
+	add := func(field, value, matchType string) {
+		if condition, conditionArgs, ok := fieldCondition(field, value, matchType); ok {
+			conditions = append(conditions, condition)
+			args = append(args, conditionArgs...)
+		}
+	}
+
+	if len(req.FieldQueries) > 0 {
+		// Sorted, so the same request always builds the same query
+		fields := make([]string, 0, len(req.FieldQueries))
+		for field := range req.FieldQueries {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			if value := strings.TrimSpace(req.FieldQueries[field]); value != "" {
+				add(field, value, req.MatchType)
+			}
+		}
+	} else {
+		for _, field := range req.Fields {
+			add(field, req.Query, req.MatchType)
+		}
+	}
+
+	// Default search across all fields if no specific fields provided
+	if len(conditions) == 0 {
+		for _, field := range searchableFields {
+			add(field, req.Query, req.MatchType)
+		}
+		q.add("("+strings.Join(conditions, " OR ")+")", args...)
+	} else if req.Logic == "AND" {
+		q.add("("+strings.Join(conditions, " AND ")+")", args...)
+	} else {
+		q.add("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+
+	if condition := utils.QualityFilterSQL(req.Quality); condition != "" {
+		q.add(condition)
+	}
+	if condition := nearbyCondition(req); condition != "" {
+		q.add(condition)
+	}
+	return q
+}
+
+// AddWithin adds the refinement of a search within results, which matches its query on any of its
+// fields, or on any searchable field when it names none
+func (q *QueryBuilder) AddWithin(req *models.SearchWithinRequest) *QueryBuilder {
+	fields := req.Fields
+	if len(fields) == 0 {
+		fields = searchableFields
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, field := range fields {
+		if condition, conditionArgs, ok := fieldCondition(field, req.Query, req.MatchType); ok {
+			conditions = append(conditions, condition)
+			args = append(args, conditionArgs...)
+		}
+	}
+	if len(conditions) == 0 {
+		q.add("0") // None of the fields can be searched
+		return q
+	}
+	q.add("("+strings.Join(conditions, " OR ")+")", args...)
+	return q
+}
+
+// add ANDs a condition and its arguments onto the clause
+func (q *QueryBuilder) add(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+// Where returns the WHERE clause, without the WHERE keyword, and its arguments
+func (q *QueryBuilder) Where() (string, []interface{}) {
+	if len(q.conditions) == 0 {
+		return "1", nil
+	}
+	return strings.Join(q.conditions, " AND "), q.args
+}
+
+// Select returns a query for one page of matching people from table
+func (q *QueryBuilder) Select(table, orderBy string, limit, offset int) (string, []interface{}) {
+	where, args := q.Where()
+	query := "SELECT " + personColumns + " FROM " + table + " WHERE " + where + " ORDER BY " + orderBy
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return query + queryPlanSettings, args
+}
+
+// SelectWithCount returns a query for one page of matching people from table, each row carrying the
+// number of matches as total_count. The count stops at countLimit matches; 0 counts them all.
+func (q *QueryBuilder) SelectWithCount(table, orderBy string, limit, offset, countLimit int) (string, []interface{}) {
+	where, args := q.Where()
+	matches := "SELECT " + personColumns + " FROM " + table + " WHERE " + where + " ORDER BY " + orderBy
+	if countLimit > 0 {
+		matches += fmt.Sprintf(" LIMIT %d", countLimit)
+	}
+	query := "SELECT *, count() OVER () AS total_count FROM (" + matches + ") ORDER BY " + orderBy
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return query + queryPlanSettings, args
+}
+
+// EstimateCount returns a query estimating the number of matching people in table from the matches
+// among one in sampleRate of its IDs
+func (q *QueryBuilder) EstimateCount(table string, sampleRate int) (string, []interface{}) {
+	where, args := q.Where()
+	return fmt.Sprintf("SELECT count() * %d FROM %s WHERE (%s) AND cityHash64(id) %% %d = 0",
+		sampleRate, table, where, sampleRate) + queryPlanSettings, args
+}
+
+// Count returns a query for the number of matching people in table
+func (q *QueryBuilder) Count(table string) (string, []interface{}) {
+	where, args := q.Where()
+	return "SELECT count() FROM " + table + " WHERE " + where + queryPlanSettings, args
+}
+
+// fieldCondition returns the condition matching value on a field and its arguments, or false when the
+// field cannot be searched or the value cannot match it. Full matches compare whole values and partial
+// matches substrings, except that a full-length mobile or alt number is always compared whole and a
+// pincode is matched on the 6-digit pincode column or, when only part of it is given, in addresses.
+func fieldCondition(field, value, matchType string) (string, []interface{}, bool) {
+	if !validSearchFields[field] {
+		return "", nil, false
+	}
+
+	switch field {
+	case "pincode":
+		digits := nonDigits.ReplaceAllString(strings.TrimSpace(value), "")
+		if len(digits) == 6 {
+			return "pincode = ?", []interface{}{digits}, true
+		}
+		if len(digits) >= 4 {
+			pattern := fmt.Sprintf("(^|[^0-9])%s([^0-9]|$)", regexp.QuoteMeta(digits))
+			return "(address ILIKE ? AND match(address, ?))", []interface{}{"%" + digits + "%", pattern}, true
+		}
+		return "", nil, false
+	case "mobile", "alt":
+		// A number matches however it was stored: 10 digits, or with a 91, +91 or 0 prefix
+		if variants := utils.MobileVariants(value); variants != nil {
+			condition, args := inCondition(field, variants)
+			return condition, args, true
+		}
+	case "circle":
+		// A circle known to the normalization map also matches its aliases, so DL finds rows imported
+		// as DELHI and rows imported before the alias existed
+		if variants := circleMap().Variants(value); len(variants) > 0 {
+			condition, args := textCondition(field, value, matchType)
+			inCond, inArgs := inCondition(normalizedCircleSQL, variants)
+			return "(" + condition + " OR " + inCond + ")", append(args, inArgs...), true
+		}
+	}
+
+	condition, args := textCondition(field, value, matchType)
+	return condition, args, true
+}
+
+// normalizedCircleSQL is the circle column in the form of utils.NormalizeCircleKey
+const normalizedCircleSQL = "arrayStringConcat(splitByWhitespace(upper(circle)), ' ')"
+
+// textCondition matches a field equal to the value for full matches, or containing it otherwise
+func textCondition(field, value, matchType string) (string, []interface{}) {
+	if matchType == "full" {
+		return field + " = ?", []interface{}{value}
+	}
+	return field + " ILIKE ?", []interface{}{"%" + value + "%"}
+}
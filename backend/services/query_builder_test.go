@@ -0,0 +1,344 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// useCircleAliases makes circleMap return a map of the given aliases for the rest of the test
+func useCircleAliases(t *testing.T, aliases map[string]string) {
+	t.Helper()
+	loadedCircleMap.mu.Lock()
+	previous, previousLoadedAt := loadedCircleMap.circles, loadedCircleMap.loadedAt
+	loadedCircleMap.circles, loadedCircleMap.loadedAt = utils.NewCircleMap(aliases), time.Now()
+	loadedCircleMap.mu.Unlock()
+
+	t.Cleanup(func() {
+		loadedCircleMap.mu.Lock()
+		loadedCircleMap.circles, loadedCircleMap.loadedAt = previous, previousLoadedAt
+		loadedCircleMap.mu.Unlock()
+	})
+}
+
+// sortedSearchFields returns every field a search accepts, in a stable order
+func sortedSearchFields() []string {
+	fields := make([]string, 0, len(validSearchFields))
+	for field := range validSearchFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+var matchTypes = []string{"", "partial", "full"}
+
+func TestFieldCondition(t *testing.T) {
+	useCircleAliases(t, map[string]string{"DL": "DELHI", "NEW DELHI": "DELHI"})
+
+	mobileVariants := []interface{}{"9876543210", "919876543210", "+919876543210", "09876543210"}
+	circleIn := normalizedCircleSQL + " IN (?,?,?)"
+
+	tests := []struct {
+		name          string
+		field         string
+		value         string
+		matchType     string
+		wantCondition string
+		wantArgs      []interface{}
+		wantOK        bool
+	}{
+		{"unknown field", "password", "x", "partial", "", nil, false},
+		{"empty field", "", "x", "full", "", nil, false},
+
+		{"name partial", "name", "Ravi", "partial", "name ILIKE ?", []interface{}{"%Ravi%"}, true},
+		{"name default match", "name", "Ravi", "", "name ILIKE ?", []interface{}{"%Ravi%"}, true},
+		{"name full", "name", "Ravi", "full", "name = ?", []interface{}{"Ravi"}, true},
+		{"fname partial", "fname", "Suresh", "partial", "fname ILIKE ?", []interface{}{"%Suresh%"}, true},
+		{"fname full", "fname", "Suresh", "full", "fname = ?", []interface{}{"Suresh"}, true},
+		{"address partial", "address", "Karol Bagh", "partial", "address ILIKE ?", []interface{}{"%Karol Bagh%"}, true},
+		{"address full", "address", "Karol Bagh", "full", "address = ?", []interface{}{"Karol Bagh"}, true},
+		{"email partial", "email", "gmail.com", "partial", "email ILIKE ?", []interface{}{"%gmail.com%"}, true},
+		{"email full", "email", "a@b.in", "full", "email = ?", []interface{}{"a@b.in"}, true},
+		{"master_id partial", "master_id", "1234", "partial", "master_id ILIKE ?", []interface{}{"%1234%"}, true},
+		{"master_id full", "master_id", "12345678", "full", "master_id = ?", []interface{}{"12345678"}, true},
+		{"locality partial", "locality", "Rohini", "partial", "locality ILIKE ?", []interface{}{"%Rohini%"}, true},
+		{"locality full", "locality", "Rohini", "full", "locality = ?", []interface{}{"Rohini"}, true},
+		{"city partial", "city", "Delhi", "partial", "city ILIKE ?", []interface{}{"%Delhi%"}, true},
+		{"city full", "city", "Delhi", "full", "city = ?", []interface{}{"Delhi"}, true},
+		{"state partial", "state", "Punjab", "partial", "state ILIKE ?", []interface{}{"%Punjab%"}, true},
+		{"state full", "state", "Punjab", "full", "state = ?", []interface{}{"Punjab"}, true},
+
+		{"mobile 10 digits partial", "mobile", "9876543210", "partial", "mobile IN (?,?,?,?)", mobileVariants, true},
+		{"mobile 10 digits full", "mobile", "9876543210", "full", "mobile IN (?,?,?,?)", mobileVariants, true},
+		{"mobile with +91 prefix", "mobile", "+91 98765 43210", "partial", "mobile IN (?,?,?,?)", mobileVariants, true},
+		{"mobile with 0 prefix", "mobile", "09876543210", "full", "mobile IN (?,?,?,?)", mobileVariants, true},
+		{"mobile part partial", "mobile", "98765", "partial", "mobile ILIKE ?", []interface{}{"%98765%"}, true},
+		{"mobile part full", "mobile", "98765", "full", "mobile = ?", []interface{}{"98765"}, true},
+		{"alt 10 digits partial", "alt", "9876543210", "partial", "alt IN (?,?,?,?)", mobileVariants, true},
+		{"alt with 91 prefix full", "alt", "919876543210", "full", "alt IN (?,?,?,?)", mobileVariants, true},
+		{"alt part partial", "alt", "4321", "partial", "alt ILIKE ?", []interface{}{"%4321%"}, true},
+		{"alt part full", "alt", "4321", "full", "alt = ?", []interface{}{"4321"}, true},
+
+		{"pincode 6 digits partial", "pincode", "110001", "partial", "pincode = ?", []interface{}{"110001"}, true},
+		{"pincode 6 digits full", "pincode", "110001", "full", "pincode = ?", []interface{}{"110001"}, true},
+		{"pincode with spaces", "pincode", " 110 001 ", "partial", "pincode = ?", []interface{}{"110001"}, true},
+		{"pincode 4 digits partial", "pincode", "1100", "partial", "(address ILIKE ? AND match(address, ?))",
+			[]interface{}{"%1100%", "(^|[^0-9])1100([^0-9]|$)"}, true},
+		{"pincode 5 digits full", "pincode", "11000", "full", "(address ILIKE ? AND match(address, ?))",
+			[]interface{}{"%11000%", "(^|[^0-9])11000([^0-9]|$)"}, true},
+		{"pincode 3 digits", "pincode", "110", "partial", "", nil, false},
+		{"pincode without digits", "pincode", "delhi", "full", "", nil, false},
+
+		{"circle alias partial", "circle", "dl", "partial", "(circle ILIKE ? OR " + circleIn + ")",
+			[]interface{}{"%dl%", "DELHI", "DL", "NEW DELHI"}, true},
+		{"circle alias full", "circle", "dl", "full", "(circle = ? OR " + circleIn + ")",
+			[]interface{}{"dl", "DELHI", "DL", "NEW DELHI"}, true},
+		{"circle canonical full", "circle", " delhi ", "full", "(circle = ? OR " + circleIn + ")",
+			[]interface{}{" delhi ", "DELHI", "DL", "NEW DELHI"}, true},
+		{"circle unknown partial", "circle", "Kerala", "partial", "circle ILIKE ?", []interface{}{"%Kerala%"}, true},
+		{"circle unknown full", "circle", "Kerala", "full", "circle = ?", []interface{}{"Kerala"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, args, ok := fieldCondition(tt.field, tt.value, tt.matchType)
+			if ok != tt.wantOK {
+				t.Fatalf("fieldCondition(%q, %q, %q) ok = %v, want %v", tt.field, tt.value, tt.matchType, ok, tt.wantOK)
+			}
+			if condition != tt.wantCondition {
+				t.Errorf("condition = %q, want %q", condition, tt.wantCondition)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+
+	// Every searchable field builds a condition for every match type
+	for _, field := range sortedSearchFields() {
+		for _, matchType := range matchTypes {
+			if _, _, ok := fieldCondition(field, "110001", matchType); !ok {
+				t.Errorf("fieldCondition(%q, %q, %q) built no condition", field, "110001", matchType)
+			}
+		}
+	}
+}
+
+func TestQueryBuilderAddSearch(t *testing.T) {
+	useCircleAliases(t, nil)
+
+	tests := []struct {
+		name      string
+		req       models.SearchRequest
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "fields joined with OR",
+			req:       models.SearchRequest{Query: "Ravi", Fields: []string{"name", "fname"}, Logic: "OR", MatchType: "partial"},
+			wantWhere: "(name ILIKE ? OR fname ILIKE ?)",
+			wantArgs:  []interface{}{"%Ravi%", "%Ravi%"},
+		},
+		{
+			name:      "fields joined with AND",
+			req:       models.SearchRequest{Query: "Ravi", Fields: []string{"name", "fname"}, Logic: "AND", MatchType: "full"},
+			wantWhere: "(name = ? AND fname = ?)",
+			wantArgs:  []interface{}{"Ravi", "Ravi"},
+		},
+		{
+			name:      "fields without logic joined with OR",
+			req:       models.SearchRequest{Query: "Ravi", Fields: []string{"email", "name"}},
+			wantWhere: "(email ILIKE ? OR name ILIKE ?)",
+			wantArgs:  []interface{}{"%Ravi%", "%Ravi%"},
+		},
+		{
+			name:      "unsearchable fields skipped",
+			req:       models.SearchRequest{Query: "Ravi", Fields: []string{"password", "name"}, Logic: "AND"},
+			wantWhere: "(name ILIKE ?)",
+			wantArgs:  []interface{}{"%Ravi%"},
+		},
+		{
+			name: "field queries in field order",
+			req: models.SearchRequest{
+				Query:        "ignored",
+				Fields:       []string{"email"},
+				FieldQueries: map[string]string{"name": "Ravi", "mobile": "9876543210", "address": " Rohini "},
+				Logic:        "AND",
+				MatchType:    "full",
+			},
+			wantWhere: "(address = ? AND mobile IN (?,?,?,?) AND name = ?)",
+			wantArgs:  []interface{}{"Rohini", "9876543210", "919876543210", "+919876543210", "09876543210", "Ravi"},
+		},
+		{
+			name: "blank field queries skipped",
+			req: models.SearchRequest{
+				FieldQueries: map[string]string{"name": "Ravi", "fname": "  "},
+				Logic:        "OR",
+			},
+			wantWhere: "(name ILIKE ?)",
+			wantArgs:  []interface{}{"%Ravi%"},
+		},
+		{
+			name:      "no searchable field searches every searchable field with OR",
+			req:       models.SearchRequest{Query: "Ravi", Fields: []string{"password"}, Logic: "AND", MatchType: "full"},
+			wantWhere: "(mobile = ? OR name = ? OR fname = ? OR address = ? OR alt = ? OR circle = ? OR email = ? OR master_id = ?)",
+			wantArgs:  []interface{}{"Ravi", "Ravi", "Ravi", "Ravi", "Ravi", "Ravi", "Ravi", "Ravi"},
+		},
+		{
+			name:      "quality filter",
+			req:       models.SearchRequest{Query: "Ravi", Fields: []string{"name"}, Quality: utils.QualityFilterClean},
+			wantWhere: "(name ILIKE ?) AND empty(quality_flags)",
+			wantArgs:  []interface{}{"%Ravi%"},
+		},
+		{
+			name: "nearby filter",
+			req: models.SearchRequest{
+				Query:        "Ravi",
+				Fields:       []string{"name"},
+				Near:         &models.NearbyFilter{Pincode: "110001", Prefixes: []string{"1220"}},
+				NearPincodes: []string{"110001", "bad", "110002"},
+			},
+			wantWhere: "(name ILIKE ?) AND (0 OR pincode IN ('110001', '110002') OR startsWith(pincode, '1220'))",
+			wantArgs:  []interface{}{"%Ravi%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := NewQueryBuilder().AddSearch(&tt.req).Where()
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderAddSearchCombinations(t *testing.T) {
+	useCircleAliases(t, nil)
+
+	// Every searchable field paired with name, under every logic and match type, builds the
+	// conditions of both fields joined by the request's logic
+	for _, field := range sortedSearchFields() {
+		for _, logic := range []string{"", "AND", "OR"} {
+			for _, matchType := range matchTypes {
+				req := &models.SearchRequest{Query: "110001", Fields: []string{field, "name"}, Logic: logic, MatchType: matchType}
+				where, args := NewQueryBuilder().AddSearch(req).Where()
+
+				condition, wantArgs, _ := fieldCondition(field, "110001", matchType)
+				nameCondition, nameArgs, _ := fieldCondition("name", "110001", matchType)
+				join := " OR "
+				if logic == "AND" {
+					join = " AND "
+				}
+				wantWhere := "(" + condition + join + nameCondition + ")"
+				wantArgs = append(wantArgs, nameArgs...)
+
+				if where != wantWhere {
+					t.Errorf("%s/%q/%q: where = %q, want %q", field, logic, matchType, where, wantWhere)
+				}
+				if !reflect.DeepEqual(args, wantArgs) {
+					t.Errorf("%s/%q/%q: args = %#v, want %#v", field, logic, matchType, args, wantArgs)
+				}
+			}
+		}
+	}
+}
+
+func TestQueryBuilderAddWithin(t *testing.T) {
+	useCircleAliases(t, nil)
+
+	tests := []struct {
+		name      string
+		req       models.SearchWithinRequest
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "fields joined with OR",
+			req:       models.SearchWithinRequest{Query: "Ravi", Fields: []string{"name", "fname"}, MatchType: "partial"},
+			wantWhere: "(name ILIKE ? OR fname ILIKE ?)",
+			wantArgs:  []interface{}{"%Ravi%", "%Ravi%"},
+		},
+		{
+			name:      "full match",
+			req:       models.SearchWithinRequest{Query: "9876543210", Fields: []string{"mobile", "email"}, MatchType: "full"},
+			wantWhere: "(mobile IN (?,?,?,?) OR email = ?)",
+			wantArgs:  []interface{}{"9876543210", "919876543210", "+919876543210", "09876543210", "9876543210"},
+		},
+		{
+			name:      "no fields searches every searchable field",
+			req:       models.SearchWithinRequest{Query: "Ravi", MatchType: "partial"},
+			wantWhere: "(mobile ILIKE ? OR name ILIKE ? OR fname ILIKE ? OR address ILIKE ? OR alt ILIKE ? OR circle ILIKE ? OR email ILIKE ? OR master_id ILIKE ?)",
+			wantArgs:  []interface{}{"%Ravi%", "%Ravi%", "%Ravi%", "%Ravi%", "%Ravi%", "%Ravi%", "%Ravi%", "%Ravi%"},
+		},
+		{
+			name:      "unsearchable fields match nothing",
+			req:       models.SearchWithinRequest{Query: "Ravi", Fields: []string{"password"}, MatchType: "full"},
+			wantWhere: "0",
+		},
+		{
+			name:      "short pincode matches nothing",
+			req:       models.SearchWithinRequest{Query: "11", Fields: []string{"pincode"}, MatchType: "partial"},
+			wantWhere: "0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, args := NewQueryBuilder().AddWithin(&tt.req).Where()
+			if where != tt.wantWhere {
+				t.Errorf("where = %q, want %q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+
+	// Every searchable field, under every match type, builds that field's condition
+	for _, field := range sortedSearchFields() {
+		for _, matchType := range matchTypes {
+			req := &models.SearchWithinRequest{Query: "110001", Fields: []string{field}, MatchType: matchType}
+			where, args := NewQueryBuilder().AddWithin(req).Where()
+
+			condition, wantArgs, _ := fieldCondition(field, "110001", matchType)
+			if want := "(" + condition + ")"; where != want {
+				t.Errorf("%s/%q: where = %q, want %q", field, matchType, where, want)
+			}
+			if !reflect.DeepEqual(args, wantArgs) {
+				t.Errorf("%s/%q: args = %#v, want %#v", field, matchType, args, wantArgs)
+			}
+		}
+	}
+}
+
+func TestQueryBuilderWhereCombinesAdds(t *testing.T) {
+	useCircleAliases(t, nil)
+
+	q := NewQueryBuilder()
+	if where, args := q.Where(); where != "1" || args != nil {
+		t.Fatalf("empty builder Where() = %q, %#v, want \"1\", nil", where, args)
+	}
+
+	q.AddSearch(&models.SearchRequest{Query: "Ravi", Fields: []string{"name"}, MatchType: "full"}).
+		AddWithin(&models.SearchWithinRequest{Query: "Delhi", Fields: []string{"city"}, MatchType: "partial"})
+	where, args := q.Where()
+	if want := "(name = ?) AND (city ILIKE ?)"; where != want {
+		t.Errorf("where = %q, want %q", where, want)
+	}
+	if want := []interface{}{"Ravi", "%Delhi%"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %#v, want %#v", args, want)
+	}
+	if strings.Count(where, "?") != len(args) {
+		t.Errorf("where has %d placeholders for %d args", strings.Count(where, "?"), len(args))
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// maxQuotaAdjustment caps each part of a single adjustment so a typo cannot lift a limit entirely
+const maxQuotaAdjustment = 100000
+
+// ErrInvalidQuotaAdjustment is returned for an adjustment without extras or a reason
+var ErrInvalidQuotaAdjustment = errors.New("invalid quota adjustment")
+
+type QuotaAdjustmentService struct{}
+
+func NewQuotaAdjustmentService() *QuotaAdjustmentService {
+	return &QuotaAdjustmentService{}
+}
+
+// AdjustQuota gives a user extra searches and exports for the current quota day without changing
+// their daily limits. Unused extras expire with the quota day.
+func (s *QuotaAdjustmentService) AdjustQuota(userID, adjustedBy uuid.UUID, req *models.QuotaAdjustmentRequest) (*models.QuotaAdjustment, error) {
+	if req.ExtraSearches < 0 || req.ExtraExports < 0 || req.ExtraSearches > maxQuotaAdjustment || req.ExtraExports > maxQuotaAdjustment {
+		return nil, fmt.Errorf("%w: extra_searches and extra_exports must be between 0 and %d", ErrInvalidQuotaAdjustment, maxQuotaAdjustment)
+	}
+	if req.ExtraSearches == 0 && req.ExtraExports == 0 {
+		return nil, fmt.Errorf("%w: extra_searches or extra_exports is required", ErrInvalidQuotaAdjustment)
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidQuotaAdjustment)
+	}
+
+	var adjustment models.QuotaAdjustment
+	query := `INSERT INTO quota_adjustments (user_id, date, extra_searches, extra_exports, reason, adjusted_by)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          RETURNING *`
+	err := database.PostgresDB.Get(&adjustment, query, userID, CurrentQuotaDate(), req.ExtraSearches, req.ExtraExports, reason, adjustedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to adjust quota: %w", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Adjusted quota of user %s for %s: %d extra searches, %d extra exports (%s)",
+		userID, CurrentQuotaDate(), req.ExtraSearches, req.ExtraExports, reason))
+	return &adjustment, nil
+}
+
+// GetTodayAdjustments returns the extra searches and exports a user was given for the current quota day
+func (s *QuotaAdjustmentService) GetTodayAdjustments(userID uuid.UUID) (int, int, error) {
+	var totals struct {
+		Searches int `db:"searches"`
+		Exports  int `db:"exports"`
+	}
+	query := `SELECT COALESCE(SUM(extra_searches), 0) AS searches, COALESCE(SUM(extra_exports), 0) AS exports
+	          FROM quota_adjustments WHERE user_id = $1 AND date = $2`
+	if err := database.PostgresDB.Get(&totals, query, userID, CurrentQuotaDate()); err != nil {
+		return 0, 0, fmt.Errorf("failed to get quota adjustments: %w", err)
+	}
+	return totals.Searches, totals.Exports, nil
+}
+
+// todayExtraSearches returns the searches a user may make today beyond max_searches_per_day: their
+// search credits plus the extra searches of their quota adjustments
+func todayExtraSearches(userID uuid.UUID) (int, error) {
+	credits, err := NewSearchCreditService().GetTodaySearchCredits(userID)
+	if err != nil {
+		return 0, err
+	}
+	adjusted, _, err := NewQuotaAdjustmentService().GetTodayAdjustments(userID)
+	if err != nil {
+		return 0, err
+	}
+	return credits + adjusted, nil
+}
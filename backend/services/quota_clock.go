@@ -0,0 +1,71 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/utils"
+)
+
+// fallbackQuotaLocation is used when the configured timezone cannot be loaded
+var fallbackQuotaLocation = time.FixedZone("IST", 5*3600+30*60)
+
+var (
+	quotaLocationMu    sync.Mutex
+	quotaLocationName  string
+	quotaLocationCache *time.Location
+)
+
+// QuotaLocation returns the timezone daily quotas reset in
+func QuotaLocation() *time.Location {
+	name := config.Get().Quota.ResetTimezone
+
+	quotaLocationMu.Lock()
+	defer quotaLocationMu.Unlock()
+
+	if quotaLocationCache != nil && quotaLocationName == name {
+		return quotaLocationCache
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Invalid quota reset timezone %q, falling back to IST", name), err)
+		location = fallbackQuotaLocation
+	}
+
+	quotaLocationName = name
+	quotaLocationCache = location
+	return location
+}
+
+// quotaResetOffset returns the configured reset time of day as an offset from midnight
+func quotaResetOffset() time.Duration {
+	resetTime, err := time.Parse("15:04", config.Get().Quota.ResetTime)
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Invalid quota reset time %q, falling back to midnight", config.Get().Quota.ResetTime), err)
+		return 0
+	}
+	return time.Duration(resetTime.Hour())*time.Hour + time.Duration(resetTime.Minute())*time.Minute
+}
+
+// CurrentQuotaDate returns the date (YYYY-MM-DD) of the quota day in progress. Before the reset
+// time of day, usage still counts towards the previous day.
+func CurrentQuotaDate() string {
+	now := time.Now().In(QuotaLocation())
+	return now.Add(-quotaResetOffset()).Format("2006-01-02")
+}
+
+// NextQuotaReset returns the next time daily quotas reset
+func NextQuotaReset() time.Time {
+	location := QuotaLocation()
+	now := time.Now().In(location)
+
+	offset := quotaResetOffset()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location).Add(offset)
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, location).Add(offset)
+	}
+	return next
+}
@@ -1,37 +1,83 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"time"
 
+	"finone-search-system/config"
 	"finone-search-system/database"
 	"finone-search-system/models"
+	"finone-search-system/utils"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
 type RegistrationService struct {
-	db *sqlx.DB
+	db                  *sqlx.DB
+	notificationService *NotificationService
+	webhookService      *WebhookService
 }
 
 func NewRegistrationService() *RegistrationService {
 	return &RegistrationService{
-		db: database.PostgresDB,
+		db:                  database.PostgresDB,
+		notificationService: NewNotificationService(),
+		webhookService:      NewWebhookService(),
 	}
 }
 
-// CreateRegistrationRequest creates a new user registration request
-func (s *RegistrationService) CreateRegistrationRequest(req models.CreateRegistrationRequest) (*models.UserRegistrationRequest, error) {
+var (
+	ErrRegistrationThrottled    = errors.New("too many registration requests, please try again later")
+	ErrRegistrationExists       = errors.New("registration request with this email already exists and is pending")
+	ErrInvalidVerificationToken = errors.New("verification link is invalid or has expired")
+)
+
+// CreateRegistrationRequest creates a new user registration request from a public submission. The
+// request must carry a valid CAPTCHA token when a provider is configured, and submissions are
+// throttled per IP address and per email. With registration.verify_email the request waits as
+// UNVERIFIED until the applicant follows the link emailed to them; only then is it PENDING review.
+func (s *RegistrationService) CreateRegistrationRequest(req models.CreateRegistrationRequest, ipAddress string) (*models.UserRegistrationRequest, error) {
+	cfg := config.Get().Registration
+
+	verifier, err := newCaptchaVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if verifier != nil {
+		if err := verifier.Verify(req.CaptchaToken, ipAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.checkThrottle(cfg, req.Email, ipAddress); err != nil {
+		return nil, err
+	}
+
+	// An unverified request whose link has expired no longer holds the email
+	_, err = s.db.Exec(`DELETE FROM user_registration_requests
+		WHERE email = $1 AND status = 'UNVERIFIED' AND verification_expires_at <= now()`, req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear expired registration request: %w", err)
+	}
+
 	// Check if email already exists in registration requests
 	var existingRequest models.UserRegistrationRequest
-	err := s.db.Get(&existingRequest, `
+	err = s.db.Get(&existingRequest, `
 		SELECT id, email, status FROM user_registration_requests
-		WHERE email = $1 AND status = 'PENDING'
+		WHERE email = $1 AND status IN ('UNVERIFIED', 'PENDING')
 	`, req.Email)
 
 	if err == nil {
-		return nil, fmt.Errorf("registration request with this email already exists and is pending")
+		if existingRequest.Status == "UNVERIFIED" {
+			return nil, fmt.Errorf("%w; check your email for the verification link", ErrRegistrationExists)
+		}
+		return nil, ErrRegistrationExists
 	}
 
 	// Check if user already exists
@@ -41,6 +87,13 @@ func (s *RegistrationService) CreateRegistrationRequest(req models.CreateRegistr
 		return nil, fmt.Errorf("user with this email already exists")
 	}
 
+	// Verification needs the email to reach the applicant
+	verify := cfg.VerifyEmail
+	if verify && !s.notificationService.IsEnabled(EventRegistrationVerify) {
+		utils.LogWarning("Registration email verification is on but registration_verify notifications are disabled; requests go straight to PENDING")
+		verify = false
+	}
+
 	// Create new registration request
 	registrationRequest := models.UserRegistrationRequest{
 		ID:                uuid.New(),
@@ -53,24 +106,123 @@ func (s *RegistrationService) CreateRegistrationRequest(req models.CreateRegistr
 		UpdatedAt:         time.Now(),
 	}
 
+	var token, tokenHash string
+	var verificationExpiresAt *time.Time
+	if verify {
+		if token, err = newVerificationToken(); err != nil {
+			return nil, err
+		}
+		tokenHash = hashVerificationToken(token)
+		expiresAt := time.Now().Add(cfg.VerificationExpiry)
+		verificationExpiresAt = &expiresAt
+		registrationRequest.Status = "UNVERIFIED"
+	}
+
 	query := `
 		INSERT INTO user_registration_requests
-		(id, name, email, phone_number, requested_searches, status, created_at, updated_at)
-		VALUES (:id, :name, :email, :phone_number, :requested_searches, :status, :created_at, :updated_at)
+		(id, name, email, phone_number, requested_searches, status, created_at, updated_at,
+		 ip_address, verification_token_hash, verification_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11)
 	`
 
-	_, err = s.db.NamedExec(query, registrationRequest)
+	_, err = s.db.Exec(query, registrationRequest.ID, registrationRequest.Name, registrationRequest.Email,
+		registrationRequest.PhoneNumber, registrationRequest.RequestedSearches, registrationRequest.Status,
+		registrationRequest.CreatedAt, registrationRequest.UpdatedAt, ipAddress, tokenHash, verificationExpiresAt)
+	if isUniqueViolation(err) {
+		return nil, ErrRegistrationExists
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create registration request: %w", err)
 	}
 
+	if verify {
+		s.notificationService.NotifyRegistrationVerify(&registrationRequest, token, *verificationExpiresAt)
+		return &registrationRequest, nil
+	}
+
+	s.notificationService.NotifyRegistrationReceived(&registrationRequest)
+	s.webhookService.Dispatch(WebhookEventRegistrationReceived, registrationRequest)
+
 	return &registrationRequest, nil
 }
 
-// GetRegistrationRequests gets paginated list of registration requests (admin only)
-func (s *RegistrationService) GetRegistrationRequests(page, limit int, status string) (*models.RegistrationRequestListResponse, error) {
-	offset := (page - 1) * limit
+// VerifyRegistrationEmail confirms the email of an UNVERIFIED registration request from the link
+// emailed to the applicant, making it PENDING review
+func (s *RegistrationService) VerifyRegistrationEmail(token string) (*models.UserRegistrationRequest, error) {
+	if token == "" {
+		return nil, ErrInvalidVerificationToken
+	}
+
+	var request models.UserRegistrationRequest
+	query := `
+		UPDATE user_registration_requests
+		SET status = 'PENDING', verified_at = now(), verification_token_hash = NULL
+		WHERE verification_token_hash = $1 AND status = 'UNVERIFIED' AND verification_expires_at > now()
+		RETURNING id, name, email, phone_number, requested_searches, status,
+		          admin_notes, verified_at, created_at, updated_at, reviewed_at, reviewed_by
+	`
+	err := s.db.Get(&request, query, hashVerificationToken(token))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify registration request: %w", err)
+	}
+
+	s.notificationService.NotifyRegistrationReceived(&request)
+	s.webhookService.Dispatch(WebhookEventRegistrationReceived, request)
+
+	return &request, nil
+}
+
+// checkThrottle limits how many registration requests an IP address may submit per hour and how many
+// may be submitted for an email per day
+func (s *RegistrationService) checkThrottle(cfg config.RegistrationConfig, email, ipAddress string) error {
+	if cfg.MaxPerIPPerHour > 0 && ipAddress != "" {
+		var count int
+		query := `SELECT COUNT(*) FROM user_registration_requests
+		          WHERE ip_address = $1 AND created_at > now() - INTERVAL '1 hour'`
+		if err := s.db.Get(&count, query, ipAddress); err != nil {
+			return fmt.Errorf("failed to count registration requests: %w", err)
+		}
+		if count >= cfg.MaxPerIPPerHour {
+			utils.LogInfo(fmt.Sprintf("Throttled registration from %s: %d requests in the last hour", ipAddress, count))
+			return ErrRegistrationThrottled
+		}
+	}
+
+	if cfg.MaxPerEmailPerDay > 0 {
+		var count int
+		query := `SELECT COUNT(*) FROM user_registration_requests
+		          WHERE email = $1 AND created_at > now() - INTERVAL '1 day'`
+		if err := s.db.Get(&count, query, email); err != nil {
+			return fmt.Errorf("failed to count registration requests: %w", err)
+		}
+		if count >= cfg.MaxPerEmailPerDay {
+			utils.LogInfo(fmt.Sprintf("Throttled registration for %s: %d requests in the last day", email, count))
+			return ErrRegistrationThrottled
+		}
+	}
+	return nil
+}
 
+// newVerificationToken returns a random token for an email verification link
+func newVerificationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate verification token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashVerificationToken returns the SHA-256 of a verification token, which is all that is stored
+func hashVerificationToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetRegistrationRequests gets paginated list of registration requests (admin only)
+func (s *RegistrationService) GetRegistrationRequests(params models.PageParams, status string) (*models.Paginated[models.UserRegistrationRequest], error) {
 	// Build WHERE clause
 	whereClause := ""
 	args := []interface{}{}
@@ -93,13 +245,13 @@ func (s *RegistrationService) GetRegistrationRequests(page, limit int, status st
 	// Get requests
 	query := `
 		SELECT r.id, r.name, r.email, r.phone_number, r.requested_searches, r.status,
-		       r.admin_notes, r.created_at, r.updated_at, r.reviewed_at, r.reviewed_by
+		       r.admin_notes, r.verified_at, r.created_at, r.updated_at, r.reviewed_at, r.reviewed_by
 		FROM user_registration_requests r
 	` + whereClause + `
 		ORDER BY r.created_at DESC
 		LIMIT $` + fmt.Sprintf("%d", argIndex) + ` OFFSET $` + fmt.Sprintf("%d", argIndex+1)
 
-	args = append(args, limit, offset)
+	args = append(args, params.Limit, params.Offset())
 
 	var requests []models.UserRegistrationRequest
 	err = s.db.Select(&requests, query, args...)
@@ -107,12 +259,7 @@ func (s *RegistrationService) GetRegistrationRequests(page, limit int, status st
 		return nil, fmt.Errorf("failed to get registration requests: %w", err)
 	}
 
-	return &models.RegistrationRequestListResponse{
-		Requests:   requests,
-		TotalCount: totalCount,
-		Page:       page,
-		Limit:      limit,
-	}, nil
+	return models.NewPaginated(requests, totalCount, params), nil
 }
 
 // GetRegistrationRequest gets a single registration request by ID
@@ -120,7 +267,7 @@ func (s *RegistrationService) GetRegistrationRequest(id uuid.UUID) (*models.User
 	var request models.UserRegistrationRequest
 	query := `
 		SELECT id, name, email, phone_number, requested_searches, status,
-		       admin_notes, created_at, updated_at, reviewed_at, reviewed_by
+		       admin_notes, verified_at, created_at, updated_at, reviewed_at, reviewed_by
 		FROM user_registration_requests
 		WHERE id = $1
 	`
@@ -159,8 +306,19 @@ func (s *RegistrationService) UpdateRegistrationRequest(id uuid.UUID, req models
 	}
 
 	// Return updated request
-	return s.GetRegistrationRequest(id)
-} // DeleteRegistrationRequest deletes a registration request (admin only)
+	updatedRequest, err := s.GetRegistrationRequest(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if updatedRequest.Status != "PENDING" {
+		s.notificationService.NotifyRegistrationReviewed(updatedRequest)
+	}
+
+	return updatedRequest, nil
+}
+
+// DeleteRegistrationRequest deletes a registration request (admin only)
 func (s *RegistrationService) DeleteRegistrationRequest(id uuid.UUID) error {
 	query := "DELETE FROM user_registration_requests WHERE id = $1"
 	result, err := s.db.Exec(query, id)
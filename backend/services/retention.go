@@ -0,0 +1,649 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// Tables the retention engine can act on
+const (
+	RetentionSearches          = "searches"
+	RetentionLogins            = "logins"
+	RetentionSystemLogs        = "system_logs"
+	RetentionSearchPerformance = "search_performance"
+	RetentionDailyUsage        = "daily_usage"
+	RetentionSessions          = "user_sessions"
+	RetentionExports           = "exports"
+	RetentionUploads           = "upload_sessions"
+)
+
+// Retention policy actions
+const (
+	RetentionDelete    = "delete"
+	RetentionArchive   = "archive"
+	RetentionAnonymize = "anonymize"
+	RetentionTTL       = "ttl"
+)
+
+// Where a retention policy is defined; later sources override earlier ones by name
+const (
+	RetentionSourceDefault  = "default"
+	RetentionSourceConfig   = "config"
+	RetentionSourceDatabase = "database"
+)
+
+// retentionBatchSize bounds each delete so a large purge does not hold long locks
+const retentionBatchSize = 10000
+
+var (
+	ErrUnknownRetentionTable   = errors.New("unknown retention table")
+	ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+	ErrInvalidRetentionPolicy  = errors.New("invalid retention policy")
+	ErrRetentionPolicyDisabled = errors.New("retention policy is disabled")
+	ErrPurgeRunning            = errors.New("a retention purge is already running")
+)
+
+// retentionTarget describes a table policies can act on. Table names, columns and criteria SQL come
+// from here, never from a policy, so policies stored in the database cannot inject SQL.
+type retentionTarget struct {
+	database   string
+	timeColumn string            // Age is measured from this column or expression
+	criteria   map[string]string // Named row filters besides all
+	actions    []string
+	fileColumn string        // Files named by this column are removed with their rows
+	fileDir    func() string // Directory of those files
+}
+
+// retentionTargets lists every table the retention engine knows
+var retentionTargets = map[string]retentionTarget{
+	RetentionSearches: {
+		database:   "postgres",
+		timeColumn: "search_time",
+		criteria: map[string]string{
+			"diagnostic": "is_diagnostic",
+			"anonymized": "anonymized_at IS NOT NULL",
+			"empty":      "result_count = 0",
+		},
+		actions: []string{RetentionDelete, RetentionArchive, RetentionAnonymize},
+	},
+	RetentionLogins: {
+		database:   "postgres",
+		timeColumn: "login_time",
+		actions:    []string{RetentionDelete, RetentionArchive},
+	},
+	RetentionSystemLogs: {
+		database:   "postgres",
+		timeColumn: "timestamp",
+		actions:    []string{RetentionDelete, RetentionArchive},
+	},
+	RetentionDailyUsage: {
+		database:   "postgres",
+		timeColumn: "date",
+		actions:    []string{RetentionDelete, RetentionArchive},
+	},
+	RetentionSessions: {
+		database:   "postgres",
+		timeColumn: "COALESCE(logged_out_at, expires_at)",
+		criteria: map[string]string{
+			"ended": "is_active = false OR logged_out_at IS NOT NULL OR expires_at < now()",
+		},
+		actions: []string{RetentionDelete},
+	},
+	RetentionExports: {
+		database:   "postgres",
+		timeColumn: "exported_at",
+		criteria: map[string]string{
+			"expired": "expires_at < now()",
+		},
+		actions:    []string{RetentionDelete, RetentionArchive},
+		fileColumn: "file_name",
+		fileDir:    func() string { return config.Get().Export.Dir },
+	},
+	RetentionUploads: {
+		database:   "postgres",
+		timeColumn: "updated_at",
+		criteria: map[string]string{
+			"unfinished": "status <> 'COMPLETED'",
+		},
+		actions:    []string{RetentionDelete},
+		fileColumn: "upload_file",
+		fileDir:    UploadDir,
+	},
+	RetentionSearchPerformance: {
+		database:   "clickhouse",
+		timeColumn: "timestamp",
+		actions:    []string{RetentionTTL},
+	},
+}
+
+var retentionPolicyNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// purgeMu keeps a manual run from overlapping the scheduled one
+var purgeMu sync.Mutex
+
+// RetentionService runs the retention policies that delete, archive or anonymize old rows
+type RetentionService struct{}
+
+func NewRetentionService() *RetentionService {
+	return &RetentionService{}
+}
+
+// GetStatus returns every retention policy, the tables they can target and the most recent runs
+func (s *RetentionService) GetStatus(limit int) (*models.RetentionStatusResponse, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	policies, err := s.Policies()
+	if err != nil {
+		return nil, err
+	}
+
+	purges := []models.RetentionPurge{}
+	query := `SELECT id, policy_name, table_name, action, retention_days, cutoff, rows_deleted, rows_archived, rows_anonymized,
+				status, error, triggered_by, started_at, finished_at
+			  FROM retention_purges
+			  ORDER BY started_at DESC
+			  LIMIT $1`
+	if err := database.PostgresDB.Select(&purges, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to get retention purges: %w", err)
+	}
+
+	return &models.RetentionStatusResponse{
+		Policies:     policies,
+		Targets:      s.Targets(),
+		NextPurgeAt:  NewSchedulerService().GetNextRetentionPurgeTime(),
+		RecentPurges: purges,
+	}, nil
+}
+
+// Targets lists the tables retention policies can act on
+func (s *RetentionService) Targets() []models.RetentionTarget {
+	targets := make([]models.RetentionTarget, 0, len(retentionTargets))
+	for name, target := range retentionTargets {
+		criteria := []string{"all"}
+		for criterion := range target.criteria {
+			criteria = append(criteria, criterion)
+		}
+		sort.Strings(criteria[1:])
+		targets = append(targets, models.RetentionTarget{
+			Table:      name,
+			Database:   target.database,
+			TimeColumn: target.timeColumn,
+			Criteria:   criteria,
+			Actions:    target.actions,
+		})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Table < targets[j].Table })
+	return targets
+}
+
+// defaultRetentionPolicies derives the built-in policies from the retention settings
+func defaultRetentionPolicies(retention *config.RetentionConfig) []models.RetentionPolicy {
+	return []models.RetentionPolicy{
+		{Name: "searches", Table: RetentionSearches, AgeDays: retention.SearchesDays, Action: RetentionDelete},
+		{Name: "searches_anonymize", Table: RetentionSearches, AgeDays: retention.AnonymizeSearchesDays, Action: RetentionAnonymize},
+		{Name: "logins", Table: RetentionLogins, AgeDays: retention.LoginsDays, Action: RetentionDelete},
+		{Name: "system_logs", Table: RetentionSystemLogs, AgeDays: retention.SystemLogsDays, Action: RetentionDelete},
+		{Name: "daily_usage", Table: RetentionDailyUsage, AgeDays: retention.DailyUsageDays, Action: RetentionDelete},
+		{Name: "user_sessions", Table: RetentionSessions, Criteria: "ended", AgeDays: retention.SessionsDays, Action: RetentionDelete},
+		{Name: "exports", Table: RetentionExports, AgeDays: retention.ExportsDays, Action: RetentionDelete},
+		{Name: "upload_sessions", Table: RetentionUploads, Criteria: "unfinished", AgeDays: retention.UploadsDays, Action: RetentionDelete},
+		{Name: "search_performance", Table: RetentionSearchPerformance, AgeDays: retention.SearchPerformanceDays, Action: RetentionTTL},
+	}
+}
+
+// Policies returns the effective retention policies: the built-in ones, overridden by name by
+// retention.policies in config.yaml and then by the policies saved through the admin API.
+// Invalid config.yaml policies are logged and skipped.
+func (s *RetentionService) Policies() ([]models.RetentionPolicy, error) {
+	byName := map[string]models.RetentionPolicy{}
+	for _, policy := range defaultRetentionPolicies(&config.Get().Retention) {
+		policy.Enabled = true
+		policy.Source = RetentionSourceDefault
+		byName[policy.Name] = policy
+	}
+
+	for _, declared := range config.Get().Retention.Policies {
+		policy := models.RetentionPolicy{
+			Name:     declared.Name,
+			Table:    declared.Table,
+			Criteria: declared.Criteria,
+			AgeDays:  declared.AgeDays,
+			Action:   declared.Action,
+			Enabled:  declared.Enabled == nil || *declared.Enabled,
+			Source:   RetentionSourceConfig,
+		}
+		if err := validateRetentionPolicy(&policy); err != nil {
+			utils.LogWarning(fmt.Sprintf("Ignoring retention policy %q from config: %v", declared.Name, err))
+			continue
+		}
+		byName[policy.Name] = policy
+	}
+
+	stored := []models.RetentionPolicy{}
+	query := `SELECT name, table_name, criteria, age_days, action, enabled, updated_by, updated_at FROM retention_policies`
+	if err := database.PostgresDB.Select(&stored, query); err != nil {
+		return nil, fmt.Errorf("failed to get retention policies: %w", err)
+	}
+	for _, policy := range stored {
+		policy.Source = RetentionSourceDatabase
+		if err := validateRetentionPolicy(&policy); err != nil {
+			utils.LogWarning(fmt.Sprintf("Ignoring stored retention policy %q: %v", policy.Name, err))
+			continue
+		}
+		byName[policy.Name] = policy
+	}
+
+	policies := make([]models.RetentionPolicy, 0, len(byName))
+	for _, policy := range byName {
+		if policy.Criteria == "" {
+			policy.Criteria = "all"
+		}
+		policy.Database = retentionTargets[policy.Table].database
+		policies = append(policies, policy)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].Name < policies[j].Name })
+	return policies, nil
+}
+
+// validateRetentionPolicy checks that a policy names a known table, one of its criteria and one of
+// its actions, filling in the default criteria
+func validateRetentionPolicy(policy *models.RetentionPolicy) error {
+	if !retentionPolicyNamePattern.MatchString(policy.Name) {
+		return fmt.Errorf("%w: name must be 1-64 lowercase letters, digits or underscores", ErrInvalidRetentionPolicy)
+	}
+	target, ok := retentionTargets[policy.Table]
+	if !ok {
+		return fmt.Errorf("%w: %w: %q", ErrInvalidRetentionPolicy, ErrUnknownRetentionTable, policy.Table)
+	}
+	if policy.Criteria == "" {
+		policy.Criteria = "all"
+	}
+	if _, ok := target.criteria[policy.Criteria]; !ok && policy.Criteria != "all" {
+		return fmt.Errorf("%w: %s has no criteria %q", ErrInvalidRetentionPolicy, policy.Table, policy.Criteria)
+	}
+	supported := false
+	for _, action := range target.actions {
+		supported = supported || action == policy.Action
+	}
+	if !supported {
+		return fmt.Errorf("%w: %s supports %s, not %q", ErrInvalidRetentionPolicy, policy.Table,
+			strings.Join(target.actions, ", "), policy.Action)
+	}
+	if policy.Action == RetentionTTL && policy.Criteria != "all" {
+		return fmt.Errorf("%w: a ttl policy applies to every row", ErrInvalidRetentionPolicy)
+	}
+	if policy.AgeDays < 0 {
+		return fmt.Errorf("%w: age_days cannot be negative", ErrInvalidRetentionPolicy)
+	}
+	return nil
+}
+
+// findRetentionPolicy returns the effective policy with the given name
+func (s *RetentionService) findRetentionPolicy(name string) (*models.RetentionPolicy, error) {
+	policies, err := s.Policies()
+	if err != nil {
+		return nil, err
+	}
+	for i := range policies {
+		if policies[i].Name == name {
+			return &policies[i], nil
+		}
+	}
+	return nil, ErrRetentionPolicyNotFound
+}
+
+// SavePolicy creates or replaces the stored policy with the given name. Fields left out of the
+// request keep the value of the current policy of that name, so a built-in policy can be disabled
+// or given another age without restating it.
+func (s *RetentionService) SavePolicy(name string, req *models.RetentionPolicyRequest, updatedBy *uuid.UUID) (*models.RetentionPolicy, error) {
+	policy := models.RetentionPolicy{Name: name, Enabled: true}
+	current, err := s.findRetentionPolicy(name)
+	if err != nil && !errors.Is(err, ErrRetentionPolicyNotFound) {
+		return nil, err
+	}
+	if current != nil {
+		policy = *current
+	}
+
+	if req.Table != nil {
+		policy.Table = *req.Table
+	}
+	if req.Criteria != nil {
+		policy.Criteria = *req.Criteria
+	}
+	if req.AgeDays != nil {
+		policy.AgeDays = *req.AgeDays
+	}
+	if req.Action != nil {
+		policy.Action = *req.Action
+	}
+	if req.Enabled != nil {
+		policy.Enabled = *req.Enabled
+	}
+	if err := validateRetentionPolicy(&policy); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO retention_policies (name, table_name, criteria, age_days, action, enabled, updated_by, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+			  ON CONFLICT (name) DO UPDATE
+			  SET table_name = EXCLUDED.table_name, criteria = EXCLUDED.criteria, age_days = EXCLUDED.age_days,
+				  action = EXCLUDED.action, enabled = EXCLUDED.enabled, updated_by = EXCLUDED.updated_by,
+				  updated_at = EXCLUDED.updated_at
+			  RETURNING updated_at`
+	var updatedAt time.Time
+	err = database.PostgresDB.QueryRow(query, policy.Name, policy.Table, policy.Criteria, policy.AgeDays,
+		policy.Action, policy.Enabled, updatedBy).Scan(&updatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save retention policy: %w", err)
+	}
+
+	policy.Database = retentionTargets[policy.Table].database
+	policy.Source = RetentionSourceDatabase
+	policy.UpdatedBy = updatedBy
+	policy.UpdatedAt = &updatedAt
+	utils.LogInfo(fmt.Sprintf("Saved retention policy %s: %s %s rows of %s older than %d days (enabled: %t)",
+		policy.Name, policy.Action, policy.Criteria, policy.Table, policy.AgeDays, policy.Enabled))
+	return &policy, nil
+}
+
+// DeletePolicy removes a stored policy; a built-in or config.yaml policy of the same name applies again
+func (s *RetentionService) DeletePolicy(name string) error {
+	result, err := database.PostgresDB.Exec(`DELETE FROM retention_policies WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention policy: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrRetentionPolicyNotFound
+	}
+	utils.LogInfo(fmt.Sprintf("Deleted stored retention policy %s", name))
+	return nil
+}
+
+// Purge runs every enabled policy, or only those on one table when table is set. Policies that keep
+// rows forever are skipped. ttl policies re-apply their ClickHouse TTL and record no run.
+func (s *RetentionService) Purge(table string, triggeredBy *uuid.UUID) ([]models.RetentionPurge, error) {
+	if table == "all" {
+		table = ""
+	}
+	if _, ok := retentionTargets[table]; table != "" && !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRetentionTable, table)
+	}
+
+	return s.runPolicies(func(policy models.RetentionPolicy) bool {
+		return table == "" || policy.Table == table
+	}, triggeredBy)
+}
+
+// AnonymizeSearches runs the enabled anonymize policies on searches, which rewrite old searches so they
+// keep only structural metadata. It returns no runs when none is enabled.
+func (s *RetentionService) AnonymizeSearches(triggeredBy *uuid.UUID) ([]models.RetentionPurge, error) {
+	return s.runPolicies(func(policy models.RetentionPolicy) bool {
+		return policy.Table == RetentionSearches && policy.Action == RetentionAnonymize
+	}, triggeredBy)
+}
+
+// RunPolicy runs one enabled policy now. It returns nil when the policy keeps rows forever or is a ttl policy.
+func (s *RetentionService) RunPolicy(name string, triggeredBy *uuid.UUID) (*models.RetentionPurge, error) {
+	policy, err := s.findRetentionPolicy(name)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, ErrRetentionPolicyDisabled
+	}
+
+	if !purgeMu.TryLock() {
+		return nil, ErrPurgeRunning
+	}
+	defer purgeMu.Unlock()
+
+	return s.runPolicy(*policy, triggeredBy)
+}
+
+// runPolicies runs the enabled policies selected by match, stopping at the first failure
+func (s *RetentionService) runPolicies(match func(models.RetentionPolicy) bool, triggeredBy *uuid.UUID) ([]models.RetentionPurge, error) {
+	policies, err := s.Policies()
+	if err != nil {
+		return nil, err
+	}
+
+	if !purgeMu.TryLock() {
+		return nil, ErrPurgeRunning
+	}
+	defer purgeMu.Unlock()
+
+	purges := []models.RetentionPurge{}
+	for _, policy := range policies {
+		if !policy.Enabled || !match(policy) {
+			continue
+		}
+		purge, err := s.runPolicy(policy, triggeredBy)
+		if err != nil {
+			if purge != nil {
+				purges = append(purges, *purge)
+			}
+			return purges, err
+		}
+		if purge != nil {
+			purges = append(purges, *purge)
+		}
+	}
+	return purges, nil
+}
+
+// runPolicy applies one policy and records the run. Policies that keep rows forever return nil.
+func (s *RetentionService) runPolicy(policy models.RetentionPolicy, triggeredBy *uuid.UUID) (*models.RetentionPurge, error) {
+	if policy.Action == RetentionTTL {
+		return nil, s.syncClickHouseTTL(policy.AgeDays)
+	}
+	if policy.AgeDays <= 0 {
+		return nil, nil
+	}
+
+	target := retentionTargets[policy.Table]
+	condition := "TRUE"
+	if policy.Criteria != "all" {
+		condition = target.criteria[policy.Criteria]
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -policy.AgeDays)
+	run, err := s.startRun(policy, cutoff, triggeredBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var runErr error
+	if policy.Action == RetentionAnonymize {
+		runErr = s.anonymizeRows(run, cutoff, condition)
+	} else {
+		runErr = s.removeRows(run, policy, target, cutoff, condition)
+	}
+
+	s.finishRun(run, runErr)
+	if runErr != nil {
+		return run, runErr
+	}
+	utils.LogInfo(fmt.Sprintf("🧹 Retention policy %s: %d deleted, %d archived, %d anonymized from %s older than %s (%d days)",
+		policy.Name, run.RowsDeleted, run.RowsArchived, run.RowsAnonymized, policy.Table,
+		cutoff.Format("2006-01-02"), policy.AgeDays))
+	return run, nil
+}
+
+// removeRows deletes or archives the matching rows in batches, removing the files they name
+func (s *RetentionService) removeRows(run *models.RetentionPurge, policy models.RetentionPolicy, target retentionTarget,
+	cutoff time.Time, condition string) error {
+	fileColumn := "NULL::text"
+	if target.fileColumn != "" {
+		fileColumn = target.fileColumn
+	}
+
+	// Table, column and criteria SQL come from retentionTargets, never from the policy
+	batch := fmt.Sprintf(`SELECT ctid FROM %s WHERE %s < $1 AND (%s) LIMIT $2`, policy.Table, target.timeColumn, condition)
+	args := []interface{}{cutoff, retentionBatchSize}
+	query := fmt.Sprintf(`DELETE FROM %s WHERE ctid IN (%s) RETURNING %s`, policy.Table, batch, fileColumn)
+	if policy.Action == RetentionArchive {
+		if target.fileColumn != "" {
+			fileColumn = fmt.Sprintf("row_data->>'%s'", target.fileColumn)
+		}
+		query = fmt.Sprintf(`WITH moved AS (
+								DELETE FROM %s WHERE ctid IN (%s) RETURNING *
+							)
+							INSERT INTO retention_archive (policy_name, table_name, row_data)
+							SELECT $3, $4, to_jsonb(moved) FROM moved
+							RETURNING %s`, policy.Table, batch, fileColumn)
+		args = append(args, policy.Name, policy.Table)
+	}
+
+	for {
+		files, removed, err := s.removeBatch(query, args)
+		if err != nil {
+			return fmt.Errorf("failed to %s rows from %s: %w", policy.Action, policy.Table, err)
+		}
+		if policy.Action == RetentionArchive {
+			run.RowsArchived += removed
+		} else {
+			run.RowsDeleted += removed
+		}
+		for _, file := range files {
+			path := filepath.Join(target.fileDir(), filepath.Base(file))
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				utils.LogError(fmt.Sprintf("Failed to remove %s file %s", policy.Table, path), err)
+			}
+		}
+		if removed < retentionBatchSize {
+			return nil
+		}
+	}
+}
+
+// removeBatch runs one delete or archive statement and returns the file names it returned and the
+// number of rows it removed
+func (s *RetentionService) removeBatch(query string, args []interface{}) ([]string, int64, error) {
+	rows, err := database.PostgresDB.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var files []string
+	var removed int64
+	for rows.Next() {
+		var file sql.NullString
+		if err := rows.Scan(&file); err != nil {
+			return nil, removed, err
+		}
+		removed++
+		if file.Valid && file.String != "" {
+			files = append(files, file.String)
+		}
+	}
+	return files, removed, rows.Err()
+}
+
+// startRun records the start of a policy run
+func (s *RetentionService) startRun(policy models.RetentionPolicy, cutoff time.Time, triggeredBy *uuid.UUID) (*models.RetentionPurge, error) {
+	run := models.RetentionPurge{
+		PolicyName:    &policy.Name,
+		TableName:     policy.Table,
+		Action:        policy.Action,
+		RetentionDays: policy.AgeDays,
+		Cutoff:        &cutoff,
+		Status:        "RUNNING",
+		TriggeredBy:   triggeredBy,
+	}
+	query := `INSERT INTO retention_purges (policy_name, table_name, action, retention_days, cutoff, triggered_by)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, started_at`
+	err := database.PostgresDB.QueryRow(query, policy.Name, policy.Table, policy.Action, policy.AgeDays, cutoff, triggeredBy).
+		Scan(&run.ID, &run.StartedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record retention run of %s: %w", policy.Name, err)
+	}
+	return &run, nil
+}
+
+// finishRun records the outcome of a policy run
+func (s *RetentionService) finishRun(run *models.RetentionPurge, runErr error) {
+	run.Status = "COMPLETED"
+	if runErr != nil {
+		run.Status = "FAILED"
+		message := runErr.Error()
+		run.Error = &message
+	}
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+
+	query := `UPDATE retention_purges
+			  SET rows_deleted = $2, rows_archived = $3, rows_anonymized = $4, status = $5, error = $6, finished_at = $7
+			  WHERE id = $1`
+	_, err := database.PostgresDB.Exec(query, run.ID, run.RowsDeleted, run.RowsArchived, run.RowsAnonymized,
+		run.Status, run.Error, finishedAt)
+	if err != nil {
+		utils.LogError("Failed to update retention run record", err)
+	}
+}
+
+// SyncClickHouseTTL applies the enabled ttl policy on search_performance, removing the TTL when there
+// is none or it keeps rows forever
+func (s *RetentionService) SyncClickHouseTTL() error {
+	policies, err := s.Policies()
+	if err != nil {
+		return err
+	}
+
+	days := 0
+	for _, policy := range policies {
+		if policy.Enabled && policy.Table == RetentionSearchPerformance && policy.Action == RetentionTTL {
+			days = policy.AgeDays
+		}
+	}
+	return s.syncClickHouseTTL(days)
+}
+
+// syncClickHouseTTL sets the TTL on search_performance to days, or removes it when days is 0. The
+// table is only altered when its TTL differs.
+func (s *RetentionService) syncClickHouseTTL(days int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var engineFull string
+	query := `SELECT engine_full FROM system.tables WHERE database = 'finone_search' AND name = 'search_performance'`
+	if err := database.ClickHouseDB.QueryRow(ctx, query).Scan(&engineFull); err != nil {
+		return fmt.Errorf("failed to read search_performance TTL: %w", err)
+	}
+
+	var alter string
+	switch {
+	case days <= 0 && strings.Contains(engineFull, " TTL "):
+		alter = `ALTER TABLE finone_search.search_performance REMOVE TTL`
+	case days > 0 && !strings.Contains(engineFull, fmt.Sprintf("toIntervalDay(%d)", days)):
+		alter = fmt.Sprintf("ALTER TABLE finone_search.search_performance MODIFY TTL timestamp + INTERVAL %d DAY", days)
+	default:
+		return nil
+	}
+
+	if err := database.ClickHouseDB.Exec(ctx, alter); err != nil {
+		return fmt.Errorf("failed to update search_performance TTL: %w", err)
+	}
+	utils.LogInfo(fmt.Sprintf("Set search_performance retention to %d days", days))
+	return nil
+}
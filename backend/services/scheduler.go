@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"finone-search-system/config"
 	"finone-search-system/database"
 	"finone-search-system/utils"
 	"fmt"
@@ -13,21 +15,22 @@ func NewSchedulerService() *SchedulerService {
 	return &SchedulerService{}
 }
 
-// StartDailyResetScheduler starts a background goroutine that resets search counts at 12 AM IST daily
+// StartDailyResetScheduler starts a background goroutine that resets search counts daily at the
+// configured reset time (12 AM IST by default)
 func (s *SchedulerService) StartDailyResetScheduler() {
 	utils.LogInfo("Starting daily search count reset scheduler...")
 
 	go func() {
 		for {
-			// Calculate next 12 AM IST
-			nextMidnight := s.getNextMidnightIST()
+			// Calculate next reset in the configured timezone
+			nextReset := s.getNextReset()
 
-			// Calculate duration until next midnight
-			duration := time.Until(nextMidnight)
+			// Calculate duration until next reset
+			duration := time.Until(nextReset)
 			utils.LogInfo(fmt.Sprintf("Next search count reset scheduled at: %s (in %v)",
-				nextMidnight.Format("2006-01-02 15:04:05 IST"), duration))
+				nextReset.Format("2006-01-02 15:04:05 MST"), duration))
 
-			// Sleep until midnight
+			// Sleep until the reset
 			time.Sleep(duration)
 
 			// Reset search counts
@@ -36,29 +39,17 @@ func (s *SchedulerService) StartDailyResetScheduler() {
 	}()
 }
 
-// getNextMidnightIST calculates the next 12:00 AM IST
-func (s *SchedulerService) getNextMidnightIST() time.Time {
-	// Get current time in IST (UTC + 5:30)
-	istLocation := time.FixedZone("IST", 5*3600+30*60)
-	now := time.Now().In(istLocation)
-
-	// Calculate next midnight in IST
-	nextMidnight := time.Date(
-		now.Year(), now.Month(), now.Day()+1,
-		0, 0, 0, 0, // 12:00:00 AM
-		istLocation,
-	)
-
-	return nextMidnight
+// getNextReset calculates the next configured reset time
+func (s *SchedulerService) getNextReset() time.Time {
+	return NextQuotaReset()
 }
 
 // resetDailySearchCounts resets all users' daily search counts to 0
 func (s *SchedulerService) resetDailySearchCounts() {
-	utils.LogInfo("🕛 Starting daily search count reset at 12 AM IST...")
+	utils.LogInfo("🕛 Starting daily search count reset...")
 
-	// Get current IST date
-	istLocation := time.FixedZone("IST", 5*3600+30*60)
-	today := time.Now().In(istLocation).Format("2006-01-02")
+	// Get the quota date that just started
+	today := CurrentQuotaDate()
 
 	// Option 1: Delete all daily_usage records for today
 	// This ensures clean start with 0 counts
@@ -84,14 +75,13 @@ func (s *SchedulerService) resetDailySearchCounts() {
 
 // resetDailySearchCountsAlternative - Alternative approach: Reset counts to 0 instead of deleting
 func (s *SchedulerService) resetDailySearchCountsAlternative() {
-	utils.LogInfo("🕛 Starting daily search count reset at 12 AM IST (alternative method)...")
+	utils.LogInfo("🕛 Starting daily search count reset (alternative method)...")
 
-	// Get current IST date
-	istLocation := time.FixedZone("IST", 5*3600+30*60)
-	today := time.Now().In(istLocation).Format("2006-01-02")
+	// Get the quota date that just started
+	today := CurrentQuotaDate()
 
 	// Update all existing records to 0
-	updateQuery := `UPDATE daily_usage SET search_count = 0, export_count = 0 WHERE date = $1`
+	updateQuery := `UPDATE daily_usage SET search_count = 0, export_count = 0, credits_used = 0, adjusted_searches_used = 0 WHERE date = $1`
 
 	result, err := database.PostgresDB.Exec(updateQuery, today)
 	if err != nil {
@@ -137,72 +127,128 @@ func (s *SchedulerService) ManualReset() error {
 
 // GetNextResetTime returns when the next reset will occur
 func (s *SchedulerService) GetNextResetTime() time.Time {
-	return s.getNextMidnightIST()
+	return s.getNextReset()
+}
+
+// GetResetSchedule returns the effective reset timezone and time of day
+func (s *SchedulerService) GetResetSchedule() (string, string) {
+	return QuotaLocation().String(), config.Get().Quota.ResetTime
 }
 
-// CleanupOldDailyUsage removes daily_usage records older than specified days
-func (s *SchedulerService) CleanupOldDailyUsage(daysToKeep int) error {
-	if daysToKeep <= 0 {
-		daysToKeep = 30 // Default: keep 30 days of history
+// StartRetentionPurge starts queueing a nightly run of every enabled retention policy
+func (s *SchedulerService) StartRetentionPurge() {
+	utils.LogInfo("Starting nightly retention policy scheduler...")
+
+	retentionService := NewRetentionService()
+	if err := retentionService.SyncClickHouseTTL(); err != nil {
+		utils.LogError("Failed to apply search_performance TTL", err)
 	}
 
-	// Get current IST date
-	istLocation := time.FixedZone("IST", 5*3600+30*60)
-	cutoffDate := time.Now().In(istLocation).AddDate(0, 0, -daysToKeep).Format("2006-01-02")
+	go func() {
+		for {
+			nextPurge := s.getNextRetentionPurge()
+			utils.LogInfo(fmt.Sprintf("Next retention purge scheduled at: %s",
+				nextPurge.Format("2006-01-02 15:04:05 MST")))
 
-	deleteQuery := `DELETE FROM daily_usage WHERE date < $1`
+			time.Sleep(time.Until(nextPurge))
 
-	result, err := database.PostgresDB.Exec(deleteQuery, cutoffDate)
-	if err != nil {
-		return fmt.Errorf("failed to cleanup old daily usage records: %w", err)
-	}
+			// Run on the job queue, so a purge cut short by a restart is picked up again
+			if _, err := NewJobQueueService().Enqueue(JobTypeRetentionPurge, struct{}{}, nil, 0); err != nil {
+				utils.LogError("Failed to queue retention purge", err)
+			}
+		}
+	}()
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err == nil {
-		utils.LogInfo(fmt.Sprintf("🧹 Cleaned up %d old daily_usage records (older than %s)",
-			rowsAffected, cutoffDate))
-	}
+// StartExportCleanup starts a periodic removal of expired export files
+func (s *SchedulerService) StartExportCleanup() {
+	interval := config.Get().Export.CleanupInterval
+	utils.LogInfo(fmt.Sprintf("Starting export cleanup every %v...", interval))
 
-	return nil
+	exportService := NewExportService()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			removed, err := exportService.CleanupExpired(context.Background())
+			if err != nil {
+				utils.LogError("Export cleanup failed", err)
+			}
+			if removed > 0 {
+				utils.LogInfo(fmt.Sprintf("🧹 Removed %d expired export files", removed))
+			}
+		}
+	}()
 }
 
-// StartWeeklyCleanup starts a weekly cleanup of old daily_usage records
-func (s *SchedulerService) StartWeeklyCleanup() {
-	utils.LogInfo("Starting weekly cleanup scheduler for old daily_usage records...")
+// StartTableMaintenance starts the nightly OPTIMIZE of the active people table, which merges the
+// parts imports leave behind during the configured off-peak window
+func (s *SchedulerService) StartTableMaintenance() {
+	if !config.Get().Maintenance.OptimizeEnabled {
+		utils.LogInfo("Nightly table maintenance is disabled")
+		return
+	}
+	utils.LogInfo("Starting nightly table maintenance scheduler...")
 
+	maintenanceService := NewTableMaintenanceService()
 	go func() {
-		// Run cleanup every Sunday at 1 AM IST
 		for {
-			nextSunday := s.getNextSunday1AM()
-			duration := time.Until(nextSunday)
+			nextRun := s.getNextTableMaintenance()
+			utils.LogInfo(fmt.Sprintf("Next table maintenance scheduled at: %s",
+				nextRun.Format("2006-01-02 15:04:05 MST")))
 
-			utils.LogInfo(fmt.Sprintf("Next weekly cleanup scheduled at: %s",
-				nextSunday.Format("2006-01-02 15:04:05 IST")))
+			time.Sleep(time.Until(nextRun))
 
-			time.Sleep(duration)
-
-			// Keep 90 days of history
-			s.CleanupOldDailyUsage(90)
+			deadline := time.Now().Add(config.Get().Maintenance.OptimizeWindow)
+			if _, err := maintenanceService.StartOptimize("", nil, deadline); err != nil {
+				utils.LogError("Scheduled table maintenance did not start", err)
+			}
 		}
 	}()
 }
 
-// getNextSunday1AM calculates next Sunday 1 AM IST
-func (s *SchedulerService) getNextSunday1AM() time.Time {
-	istLocation := time.FixedZone("IST", 5*3600+30*60)
-	now := time.Now().In(istLocation)
+// GetNextTableMaintenanceTime returns when the next nightly OPTIMIZE will run, or nil when it is disabled
+func (s *SchedulerService) GetNextTableMaintenanceTime() *time.Time {
+	if !config.Get().Maintenance.OptimizeEnabled {
+		return nil
+	}
+	next := s.getNextTableMaintenance()
+	return &next
+}
+
+// getNextTableMaintenance calculates the next configured maintenance time in the quota timezone
+func (s *SchedulerService) getNextTableMaintenance() time.Time {
+	location := QuotaLocation()
+	now := time.Now().In(location)
+
+	hour, minute := 1, 0
+	if start, err := time.Parse("15:04", config.Get().Maintenance.OptimizeTime); err == nil {
+		hour, minute = start.Hour(), start.Minute()
+	} else {
+		utils.LogError(fmt.Sprintf("Invalid maintenance optimize time %q, falling back to 01:00", config.Get().Maintenance.OptimizeTime), err)
+	}
 
-	// Find next Sunday
-	daysUntilSunday := (7 - int(now.Weekday())) % 7
-	if daysUntilSunday == 0 && (now.Hour() >= 1) {
-		daysUntilSunday = 7 // If it's Sunday and past 1 AM, go to next Sunday
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
 	}
+	return next
+}
+
+// GetNextRetentionPurgeTime returns when the next scheduled retention purge will run
+func (s *SchedulerService) GetNextRetentionPurgeTime() time.Time {
+	return s.getNextRetentionPurge()
+}
 
-	nextSunday := time.Date(
-		now.Year(), now.Month(), now.Day()+daysUntilSunday,
-		1, 0, 0, 0, // 1:00:00 AM
-		istLocation,
-	)
+// getNextRetentionPurge calculates the next 3 AM in the quota timezone, a quiet hour away from the
+// daily reset
+func (s *SchedulerService) getNextRetentionPurge() time.Time {
+	location := QuotaLocation()
+	now := time.Now().In(location)
 
-	return nextSunday
+	next := time.Date(now.Year(), now.Month(), now.Day(), 3, 0, 0, 0, location)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
 }
@@ -5,11 +5,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"finone-search-system/config"
 	"finone-search-system/database"
 	"finone-search-system/models"
 	"finone-search-system/utils"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -17,10 +19,14 @@ import (
 	"github.com/google/uuid"
 )
 
-type SearchService struct{}
+type SearchService struct {
+	backend SearchBackend
+}
 
 func NewSearchService() *SearchService {
-	return &SearchService{}
+	return &SearchService{
+		backend: newSearchBackend(),
+	}
 }
 
 // computeSearchFingerprint generates a stable fingerprint for a search request that ignores pagination
@@ -59,22 +65,91 @@ func (s *SearchService) computeSearchFingerprint(req *models.SearchRequest) stri
 	} else {
 		base.WriteString("0")
 	}
+	if req.Mode != "" && req.Mode != models.SearchModeAuto {
+		base.WriteString(";mode=")
+		base.WriteString(req.Mode)
+	}
 	base.WriteString(";q=")
 	base.WriteString(strings.TrimSpace(req.Query))
 	base.WriteString(";fields=")
 	base.WriteString(strings.Join(sortedFields, ","))
 	base.WriteString(";field_queries=")
 	base.WriteString(strings.Join(fqPairs, ","))
+	if quality := strings.ToLower(strings.TrimSpace(req.Quality)); quality != "" {
+		base.WriteString(";quality=")
+		base.WriteString(quality)
+	}
+	if req.DatasetID != "" {
+		base.WriteString(";dataset=")
+		base.WriteString(req.DatasetID)
+	}
+	if req.Near != nil {
+		base.WriteString(fmt.Sprintf(";near=%s/%g/%s", strings.TrimSpace(req.Near.Pincode), req.Near.RadiusKm, strings.Join(req.Near.Prefixes, ",")))
+	}
 
 	sum := sha256.Sum256([]byte(base.String()))
 	return hex.EncodeToString(sum[:])
 }
 
+// ApplyDefaults fills in the default pagination, logic and match type for a search request
+func (s *SearchService) ApplyDefaults(req *models.SearchRequest) {
+	if req.Limit == 0 {
+		req.Limit = 1000
+	}
+	if req.Limit > 10000 {
+		req.Limit = 10000 // Max limit from config
+	}
+	if req.Logic == "" {
+		req.Logic = "AND"
+	}
+	if req.MatchType == "" {
+		req.MatchType = "partial"
+	}
+	req.SortBy = strings.ToLower(strings.TrimSpace(req.SortBy))
+	req.Quality = strings.ToLower(strings.TrimSpace(req.Quality))
+	req.Mode = strings.ToLower(strings.TrimSpace(req.Mode))
+	if req.Mode == "" {
+		req.Mode = models.SearchModeAuto
+	}
+}
+
+// searchOrderBy returns the ORDER BY clause for a search. Results are ordered by mobile and name
+// unless the request sorts by confidence, which puts the highest quality records first.
+func searchOrderBy(req *models.SearchRequest) string {
+	if req.SortBy == "confidence" {
+		return "confidence DESC, mobile, name"
+	}
+	return "mobile, name"
+}
+
+// estimateQueryCost asks ClickHouse how many parts, rows and marks a query would read without running it
+func (s *SearchService) estimateQueryCost(ctx context.Context, query string, args ...interface{}) (*models.CostEstimate, error) {
+	rows, err := database.ClickHouseDB.Query(ctx, "EXPLAIN ESTIMATE "+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate query cost: %w", err)
+	}
+	defer rows.Close()
+
+	estimate := &models.CostEstimate{}
+	for rows.Next() {
+		var db, table string
+		var parts, rowCount, marks uint64
+		if err := rows.Scan(&db, &table, &parts, &rowCount, &marks); err != nil {
+			return nil, fmt.Errorf("failed to read query cost estimate: %w", err)
+		}
+		estimate.Parts += parts
+		estimate.Rows += rowCount
+		estimate.Marks += marks
+	}
+
+	return estimate, rows.Err()
+}
+
 // isDuplicateSearchToday checks if a search with the same fingerprint already exists today for the user
-func (s *SearchService) isDuplicateSearchToday(userID uuid.UUID, fingerprint string) (bool, error) {
+func (s *SearchService) isDuplicateSearchToday(ctx context.Context, userID uuid.UUID, fingerprint string) (bool, error) {
 	query := `SELECT 1 FROM searches WHERE user_id = $1 AND search_time::date = CURRENT_DATE AND search_query ->> 'fingerprint' = $2 LIMIT 1`
 	var tmp int
-	err := database.PostgresDB.Get(&tmp, query, userID, fingerprint)
+	err := database.PostgresDB.GetContext(ctx, &tmp, query, userID, fingerprint)
 	if err != nil {
 		// If no rows, sqlx returns an error; treat as not duplicate
 		return false, nil
@@ -82,73 +157,107 @@ func (s *SearchService) isDuplicateSearchToday(userID uuid.UUID, fingerprint str
 	return true, nil
 }
 
-// helper: build condition for a field/value considering virtual fields like pincode
-func (s *SearchService) buildFieldCondition(field string, value string, matchType string, args *[]interface{}) (string, bool) {
-	// Virtual field: pincode is extracted from address; prefer exact 6-digit equality on materialized column
-	if field == "pincode" {
-		clean := strings.TrimSpace(value)
-		if clean == "" {
-			return "", false
-		}
-		// Only digits
-		digits := regexp.MustCompile(`\D`).ReplaceAllString(clean, "")
-		if len(digits) == 6 {
-			*args = append(*args, digits)
-			return "pincode = ?", true
-		}
-		if len(digits) >= 4 {
-			// Partial pincode: fallback to address filtering to keep current behavior
-			c1 := "address ILIKE ?"
-			*args = append(*args, "%"+digits+"%")
-			pattern := fmt.Sprintf("(^|[^0-9])%s([^0-9]|$)", regexp.QuoteMeta(digits))
-			c2 := "match(address, ?)"
-			*args = append(*args, pattern)
-			return fmt.Sprintf("(%s AND %s)", c1, c2), true
-		}
-		return "", false
-	}
-
-	// Normal fields
-	if matchType == "full" {
-		return fmt.Sprintf("%s = ?", field), true
+// traceContext returns the base context for a search's queries. It keeps the request's trace span,
+// so queries show up under the request, but not its cancellation: an abandoned request still
+// finishes logging and counting the search.
+func traceContext(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
 	}
-	return fmt.Sprintf("%s ILIKE ?", field), true
+	return context.WithoutCancel(ctx)
 }
 
 // Search performs a search operation on the people data
 func (s *SearchService) Search(userID uuid.UUID, req *models.SearchRequest) (*models.SearchResponse, error) {
-	// Check if user has remaining search quota
+	// Debug traces are only requested by admins; the handler enforces that
+	tracer := newSearchTracer(req.Debug)
+
+	if err := s.ValidateMode(req); err != nil {
+		return nil, err
+	}
+
+	// Check if user has remaining search quota; admin diagnostic searches are exempt
 	authService := NewAuthService()
-	canSearch, err := authService.CheckSearchLimit(userID)
+	if req.Diagnostic {
+		tracer.decide("diagnostic search; quota not checked")
+	} else {
+		canSearch, err := authService.CheckSearchLimit(userID)
+		if err != nil {
+			utils.LogError("Failed to check search limit", err)
+			return nil, fmt.Errorf("failed to check search limit")
+		}
+		if !canSearch {
+			return nil, ErrSearchLimitExceeded
+		}
+		tracer.decide("quota check passed")
+	}
+
+	// Keep the search to the fields the user is allowed to search on
+	if err := s.enforceAllowedFields(userID, req); err != nil {
+		return nil, err
+	}
+
+	// Search the requested dataset, or the default people table
+	table, err := resolveDatasetTable(userID, &req.DatasetID)
 	if err != nil {
-		utils.LogError("Failed to check search limit", err)
-		return nil, fmt.Errorf("failed to check search limit")
+		return nil, err
 	}
-	if !canSearch {
-		return nil, fmt.Errorf("daily search limit exceeded")
+
+	// Expand a nearby filter to the pincodes within its radius
+	nearby, err := s.PrepareNearby(req)
+	if err != nil {
+		return nil, err
 	}
 
-	// Auto-detect mobile number searches and use enhanced mobile search
-	// Check if this is likely a mobile number search
-	if s.shouldUseEnhancedMobileSearch(req) {
-		utils.LogInfo("Detected mobile number pattern, using enhanced mobile search")
+	// Use enhanced mobile search when the mode asks for it, or in auto mode when the search looks like
+	// a mobile number search
+	if req.Mode == models.SearchModeStandard {
+		tracer.decide("standard mode requested; enhanced mobile search not considered")
+	}
+	if s.useEnhancedMobileSearch(req) {
+		if req.Mode == models.SearchModeEnhancedMobile {
+			utils.LogInfo("Enhanced mobile mode requested, using enhanced mobile search")
+		} else {
+			utils.LogInfo("Detected mobile number pattern, using enhanced mobile search")
+		}
 
 		// Extract the mobile number from the search
 		mobileNumber := s.extractMobileNumber(req)
 		if mobileNumber != "" {
 			enhancedReq := &models.EnhancedMobileSearchRequest{
-				MobileNumber: mobileNumber,
-				Limit:        req.Limit,
-				Offset:       req.Offset,
+				MobileNumber:   mobileNumber,
+				Limit:          req.Limit,
+				Offset:         req.Offset,
+				Diagnostic:     req.Diagnostic,
+				DatasetID:      req.DatasetID,
+				ClientID:       req.ClientID,
+				RequestID:      req.RequestID,
+				ImpersonatedBy: req.ImpersonatedBy,
+				Context:        req.Context,
 			}
 
+			enhancedStart := time.Now()
 			enhancedResponse, err := s.EnhancedMobileSearch(userID, enhancedReq)
+			tracer.timed("enhanced_mobile_search", enhancedStart)
+			if err != nil && req.Mode == models.SearchModeEnhancedMobile {
+				// The caller asked for enhanced mobile search, so it is not replaced by a different search
+				return nil, err
+			}
 			if err != nil {
 				utils.LogError("Enhanced mobile search failed, falling back to regular search", err)
 				// Fall back to regular search on error
+				tracer.decide("query looked like a mobile number but enhanced mobile search failed (%v); fell back to regular search", err)
 			} else {
 				// Convert enhanced response to regular response format
 				allResults := append(enhancedResponse.DirectMatches, enhancedResponse.MasterIDMatches...)
+				allResults = append(allResults, enhancedResponse.SecondHopMatches...)
+				if req.Mode == models.SearchModeEnhancedMobile {
+					tracer.decide("enhanced_mobile mode requested; answered by enhanced mobile search (%d direct, %d master ID matches)",
+						len(enhancedResponse.DirectMatches), len(enhancedResponse.MasterIDMatches))
+				} else {
+					tracer.decide("query looked like a mobile number; answered by enhanced mobile search (%d direct, %d master ID matches)",
+						len(enhancedResponse.DirectMatches), len(enhancedResponse.MasterIDMatches))
+				}
 
 				return &models.SearchResponse{
 					Results:       allResults,
@@ -156,6 +265,10 @@ func (s *SearchService) Search(userID uuid.UUID, req *models.SearchRequest) (*mo
 					ExecutionTime: enhancedResponse.ExecutionTime,
 					SearchID:      enhancedResponse.SearchID,
 					HasMore:       enhancedResponse.HasMore,
+					Debug:         tracer.finish(),
+					Quota:         enhancedResponse.Quota,
+					Columns:       req.Columns,
+					Mode:          models.SearchModeEnhancedMobile,
 				}, nil
 			}
 		}
@@ -164,27 +277,49 @@ func (s *SearchService) Search(userID uuid.UUID, req *models.SearchRequest) (*mo
 	startTime := time.Now()
 	searchID := uuid.New().String()
 
-	// Build the search query
-	query, args := s.buildSearchQuery(req)
-
-	utils.LogInfo(fmt.Sprintf("Executing search query: %s", query))
-
 	// Execute the search
-	var results []models.Person
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := searchContext(traceContext(req.Context), userID)
 	defer cancel()
-
-	err = database.ClickHouseDB.Select(ctx, &results, query, args...)
+	ctx = database.WithPeopleTable(ctx, table)
+
+	// Build the search query (logged with the performance metrics)
+	query, args := s.buildSearchQuery(ctx, req)
+	tracer.query(query, args)
+	tracer.explain(ctx, s, query, args)
+
+	// Facet counts run alongside the main query
+	var facetsCh chan map[string][]models.FacetCount
+	if len(req.Facets) > 0 {
+		facetsCh = make(chan map[string][]models.FacetCount, 1)
+		go func() {
+			facetsStart := time.Now()
+			facetsCh <- s.backend.Facets(ctx, req)
+			tracer.timed("facets", facetsStart)
+		}()
+	}
+
+	// Read the page with its total count for pagination
+	stepStart := time.Now()
+	results, totalCount, estimated, err := s.searchPage(ctx, req)
 	if err != nil {
 		utils.LogError("Search query failed", err)
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
+	tracer.timed("query", stepStart)
+	if estimated {
+		tracer.decide("at least %d matches; total_count is estimated from a sample", exactCountLimit())
+	}
 
-	// Get total count for pagination (without LIMIT/OFFSET)
-	totalCount, err := s.getTotalCount(req, ctx)
-	if err != nil {
-		utils.LogError("Failed to get total count", err)
-		totalCount = len(results) // Fallback to current page count
+	var facets map[string][]models.FacetCount
+	if facetsCh != nil {
+		facets = s.dropMaskedFacets(userID, <-facetsCh)
+	}
+
+	var nearbySummary *models.NearbySummary
+	if req.Near != nil {
+		stepStart = time.Now()
+		nearbySummary = s.summarizeNearby(ctx, req, nearby, results)
+		tracer.timed("nearby", stepStart)
 	}
 
 	executionTime := int(time.Since(startTime).Milliseconds())
@@ -194,443 +329,287 @@ func (s *SearchService) Search(userID uuid.UUID, req *models.SearchRequest) (*mo
 
 	// Duplicate detection (based on semantic query, ignoring pagination)
 	fingerprint := s.computeSearchFingerprint(req)
-	isDup, _ := s.isDuplicateSearchToday(userID, fingerprint)
+	isDup, _ := s.isDuplicateSearchToday(ctx, userID, fingerprint)
 
 	// Log the search (including fingerprint)
-	s.logSearch(userID, req, len(results), executionTime, searchID, fingerprint)
+	s.logSearch(ctx, userID, req, len(results), executionTime, searchID, fingerprint)
 
-	// Log performance metrics to ClickHouse
-	s.logSearchPerformance(searchID, userID.String(), query, executionTime, len(results))
+	// Log performance metrics to ClickHouse, capturing the query plan of a slow search
+	if s.isSlowSearch(executionTime) {
+		go s.logSlowSearchPerformance(traceContext(req.Context), searchID, userID.String(), req.RequestID, query, args, executionTime, len(results))
+	} else {
+		s.logSearchPerformance(ctx, searchID, userID.String(), req.RequestID, query, "", executionTime, len(results))
+	}
 
 	// Only increment user's daily search count if we found results and not a duplicate
-	if totalCount > 0 && !isDup {
+	var freeReason string
+	if req.Diagnostic {
+		utils.LogInfo("Diagnostic search, search count not incremented")
+		tracer.decide("diagnostic search; not counted against the daily quota")
+		freeReason = models.SearchFreeDiagnostic
+	} else if totalCount > 0 && !isDup {
 		if err := authService.IncrementSearchCount(userID); err != nil {
 			utils.LogError("Failed to increment search count", err)
 		}
+		tracer.decide("search counted against the daily quota")
 	} else if totalCount == 0 {
 		utils.LogInfo("No results found, search count not incremented")
+		tracer.decide("no results; search not counted against the daily quota")
+		freeReason = models.SearchFreeNoResults
 	} else if isDup {
 		utils.LogInfo("Duplicate search detected for today, search count not incremented")
+		tracer.decide("same search already made today (fingerprint %s); not counted against the daily quota", fingerprint[:12])
+		freeReason = models.SearchFreeDuplicate
 	}
+	tracer.decide("results are not cached; the query ran against %s", database.PeopleTableFor(ctx))
+
+	// Apply the user's field visibility policy before returning
+	stepStart = time.Now()
+	s.MaskResults(userID, results)
+	tracer.timed("masking", stepStart)
+	stepStart = time.Now()
+	highlightPeople(results, searchHighlightTerms(req))
+	tracer.timed("highlighting", stepStart)
 
 	return &models.SearchResponse{
 		Results:       results,
 		TotalCount:    totalCount,
+		Estimated:     estimated,
 		ExecutionTime: executionTime,
 		SearchID:      searchID,
 		HasMore:       hasMore,
+		Facets:        facets,
+		Nearby:        nearbySummary,
+		Debug:         tracer.finish(),
+		Quota:         models.NewSearchQuota(freeReason),
+		Columns:       req.Columns,
+		Mode:          models.SearchModeStandard,
 	}, nil
 }
 
-// buildSearchQuery constructs the SQL query based on search parameters
-func (s *SearchService) buildSearchQuery(req *models.SearchRequest) (string, []interface{}) {
-	baseQuery := `SELECT id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at
-	              FROM finone_search.people WHERE `
-
-	conditions := []string{}
-	args := []interface{}{}
-
-	// Check if we have field-specific queries (preferred method)
-	if len(req.FieldQueries) > 0 {
-		// Field-specific search: each field has its own query value
-		for field, value := range req.FieldQueries {
-			if !s.isValidField(field) {
-				continue
-			}
-
-			val := strings.TrimSpace(value)
-			if val == "" {
-				continue
-			}
-
-			var condition string
-			if field == "pincode" {
-				c, ok := s.buildFieldCondition("pincode", val, req.MatchType, &args)
-				if ok {
-					conditions = append(conditions, c)
-				}
-				continue
-			}
-
-			if req.MatchType == "full" {
-				condition = fmt.Sprintf("%s = ?", field)
-				args = append(args, val)
-			} else {
-				// Optimize numeric mobile/alt lookups when full-length number provided
-				if field == "mobile" || field == "alt" {
-					digits := regexp.MustCompile(`\D`).ReplaceAllString(val, "")
-					if len(digits) >= 10 && len(digits) <= 12 {
-						condition = fmt.Sprintf("%s = ?", field)
-						args = append(args, digits)
-					} else {
-						condition = fmt.Sprintf("%s ILIKE ?", field)
-						args = append(args, "%"+val+"%")
-					}
-				} else {
-					condition = fmt.Sprintf("%s ILIKE ?", field)
-					args = append(args, "%"+val+"%")
-				}
-			}
-			conditions = append(conditions, condition)
-		}
-	} else if len(req.Fields) > 0 {
-		// Legacy method: single query across multiple fields
-		for _, field := range req.Fields {
-			if !s.isValidField(field) {
-				continue
-			}
-
-			var condition string
-			if req.MatchType == "full" {
-				condition = fmt.Sprintf("%s = ?", field)
-				args = append(args, req.Query)
-			} else {
-				if field == "mobile" || field == "alt" {
-					digits := regexp.MustCompile(`\D`).ReplaceAllString(req.Query, "")
-					if len(digits) >= 10 && len(digits) <= 12 {
-						condition = fmt.Sprintf("%s = ?", field)
-						args = append(args, digits)
-					} else {
-						condition = fmt.Sprintf("%s ILIKE ?", field)
-						args = append(args, "%"+req.Query+"%")
-					}
-				} else {
-					condition = fmt.Sprintf("%s ILIKE ?", field)
-					args = append(args, "%"+req.Query+"%")
-				}
-			}
-			conditions = append(conditions, condition)
-		}
-	}
-
-	// Default search across all fields if no specific fields provided
-	if len(conditions) == 0 {
-		if req.MatchType == "full" {
-			condition := "(mobile = ? OR name = ? OR fname = ? OR address = ? OR alt = ? OR circle = ? OR email = ? OR master_id = ?)"
-			conditions = append(conditions, condition)
-			for i := 0; i < 8; i++ {
-				args = append(args, req.Query)
-			}
-		} else {
-			condition := "(mobile ILIKE ? OR name ILIKE ? OR fname ILIKE ? OR address ILIKE ? OR alt ILIKE ? OR circle ILIKE ? OR email ILIKE ? OR master_id ILIKE ?)"
-			conditions = append(conditions, condition)
-			queryWithWildcard := "%" + req.Query + "%"
-			for i := 0; i < 8; i++ {
-				args = append(args, queryWithWildcard)
-			}
-		}
-	}
-
-	// Join conditions with AND/OR logic
-	logicOperator := "OR"
-	if req.Logic == "AND" {
-		logicOperator = "AND"
+// PrepareNearby validates a request's nearby filter and stores the pincodes within its radius on the
+// request, so the expansion is saved with the search. Returns the pincodes with their distances.
+func (s *SearchService) PrepareNearby(req *models.SearchRequest) ([]models.NearbyPincode, error) {
+	if req.Near == nil {
+		req.NearPincodes = nil
+		return nil, nil
 	}
 
-	whereClause := "(" + strings.Join(conditions, " "+logicOperator+" ") + ")"
-	query := baseQuery + whereClause
-
-	// Add ordering for consistent results
-	query += " ORDER BY mobile, name"
-
-	// Add pagination
-	if req.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", req.Limit)
+	nearby, err := expandNearby(req.Near)
+	if err != nil {
+		return nil, err
 	}
-	if req.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", req.Offset)
+	req.NearPincodes = make([]string, 0, len(nearby))
+	for _, pincode := range nearby {
+		req.NearPincodes = append(req.NearPincodes, pincode.Pincode)
 	}
-
-	// Encourage better planning
-	query += " SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1"
-
-	// Debug logging
-	utils.LogInfo(fmt.Sprintf("Generated SQL query - Logic: %s, Operator: %s, Conditions: %d",
-		req.Logic, logicOperator, len(conditions)))
-	utils.LogInfo(fmt.Sprintf("SQL Query: %s", query))
-
-	return query, args
+	return nearby, nil
 }
 
-// getTotalCount gets the total count of matching records without pagination
-func (s *SearchService) getTotalCount(req *models.SearchRequest, ctx context.Context) (int, error) {
-	baseQuery := `SELECT count() FROM finone_search.people WHERE `
-
-	conditions := []string{}
-	args := []interface{}{}
-
-	// Check if we have field-specific queries (preferred method)
-	if len(req.FieldQueries) > 0 {
-		// Field-specific search: each field has its own query value
-		for field, value := range req.FieldQueries {
-			if !s.isValidField(field) {
-				continue
-			}
-
-			val := strings.TrimSpace(value)
-			if val == "" {
-				continue
-			}
-
-			if field == "pincode" {
-				c, ok := s.buildFieldCondition("pincode", val, req.MatchType, &args)
-				if ok {
-					conditions = append(conditions, c)
-				}
-				continue
-			}
-
-			var condition string
-			if req.MatchType == "full" {
-				condition = fmt.Sprintf("%s = ?", field)
-				args = append(args, val)
-			} else {
-				if field == "mobile" || field == "alt" {
-					digits := regexp.MustCompile(`\D`).ReplaceAllString(val, "")
-					if len(digits) >= 10 && len(digits) <= 12 {
-						condition = fmt.Sprintf("%s = ?", field)
-						args = append(args, digits)
-					} else {
-						condition = fmt.Sprintf("%s ILIKE ?", field)
-						args = append(args, "%"+val+"%")
-					}
-				} else {
-					condition = fmt.Sprintf("%s ILIKE ?", field)
-					args = append(args, "%"+val+"%")
-				}
-			}
-			conditions = append(conditions, condition)
-		}
-	} else if len(req.Fields) > 0 {
-		// Legacy method: single query across multiple fields
-		for _, field := range req.Fields {
-			if !s.isValidField(field) {
-				continue
-			}
-
-			var condition string
-			if req.MatchType == "full" {
-				condition = fmt.Sprintf("%s = ?", field)
-				args = append(args, req.Query)
-			} else {
-				if field == "mobile" || field == "alt" {
-					digits := regexp.MustCompile(`\D`).ReplaceAllString(req.Query, "")
-					if len(digits) >= 10 && len(digits) <= 12 {
-						condition = fmt.Sprintf("%s = ?", field)
-						args = append(args, digits)
-					} else {
-						condition = fmt.Sprintf("%s ILIKE ?", field)
-						args = append(args, "%"+req.Query+"%")
-					}
-				} else {
-					condition = fmt.Sprintf("%s ILIKE ?", field)
-					args = append(args, "%"+req.Query+"%")
-				}
-			}
-			conditions = append(conditions, condition)
+// summarizeNearby reports the distance of each returned record and, for a radius search, the number of
+// matches per distance bucket. Pincodes are read from the addresses before they are masked.
+func (s *SearchService) summarizeNearby(ctx context.Context, req *models.SearchRequest, nearby []models.NearbyPincode, results []models.Person) *models.NearbySummary {
+	summary := &models.NearbySummary{
+		Pincode:  req.Near.Pincode,
+		RadiusKm: req.Near.RadiusKm,
+		Prefixes: req.Near.Prefixes,
+		Pincodes: nearby,
+		Matches:  make([]models.NearbyMatch, 0, len(results)),
+	}
+	if summary.Pincodes == nil {
+		summary.Pincodes = []models.NearbyPincode{}
+	}
+
+	distances := make(map[string]models.NearbyPincode, len(nearby))
+	for _, pincode := range nearby {
+		distances[pincode.Pincode] = pincode
+	}
+	for _, person := range results {
+		match := models.NearbyMatch{PersonID: person.ID, Pincode: utils.AddressPincode(person.Address), DistanceKm: -1}
+		if pincode, ok := distances[match.Pincode]; ok {
+			match.DistanceKm = pincode.DistanceKm
+			match.Bucket = pincode.Bucket
 		}
+		summary.Matches = append(summary.Matches, match)
 	}
 
-	// Default search across all fields if no specific fields provided
-	if len(conditions) == 0 {
-		if req.MatchType == "full" {
-			condition := "(mobile = ? OR name = ? OR fname = ? OR address = ? OR alt = ? OR circle = ? OR email = ? OR master_id = ?)"
-			conditions = append(conditions, condition)
-			for i := 0; i < 8; i++ {
-				args = append(args, req.Query)
-			}
+	if req.Near.Pincode != "" {
+		counts, err := s.backend.CountByPincode(ctx, req)
+		if err != nil {
+			utils.LogError("Failed to count nearby matches by pincode", err)
 		} else {
-			condition := "(mobile ILIKE ? OR name ILIKE ? OR fname ILIKE ? OR address ILIKE ? OR alt ILIKE ? OR circle ILIKE ? OR email ILIKE ? OR master_id ILIKE ?)"
-			conditions = append(conditions, condition)
-			queryWithWildcard := "%" + req.Query + "%"
-			for i := 0; i < 8; i++ {
-				args = append(args, queryWithWildcard)
-			}
+			summary.Buckets = distanceBuckets(nearby, counts, req.Near.RadiusKm)
 		}
 	}
+	return summary
+}
 
-	// Join conditions with AND/OR logic
-	logicOperator := "OR"
-	if req.Logic == "AND" {
-		logicOperator = "AND"
+// buildSearchQuery constructs the SQL query for a page of a search's results
+func (s *SearchService) buildSearchQuery(ctx context.Context, req *models.SearchRequest) (string, []interface{}) {
+	builder := NewQueryBuilder().AddSearch(req)
+	var query string
+	var args []interface{}
+	if countInQuery() {
+		query, args = builder.SelectWithCount(database.PeopleTableFor(ctx), searchOrderBy(req), req.Limit, req.Offset, 0)
+	} else {
+		query, args = builder.Select(database.PeopleTableFor(ctx), searchOrderBy(req), req.Limit, req.Offset)
 	}
 
-	whereClause := "(" + strings.Join(conditions, " "+logicOperator+" ") + ")"
-	countQuery := baseQuery + whereClause + " SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1"
-
-	var totalCount uint64
-	err := database.ClickHouseDB.QueryRow(ctx, countQuery, args...).Scan(&totalCount)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get total count: %w", err)
-	}
+	utils.LogInfo(fmt.Sprintf("SQL Query: %s", query))
 
-	return int(totalCount), nil
+	return query, args
 }
 
-// getSearchWithinTotalCount gets the total count for search within operations
-func (s *SearchService) getSearchWithinTotalCount(originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest, ctx context.Context) (int, error) {
-	// Build the original query conditions for count
-	originalConditions := []string{}
-	args := []interface{}{}
-
-	// Handle original request fields and query
-	if len(originalReq.FieldQueries) > 0 {
-		// Field-specific search: each field has its own query value
-		for field, value := range originalReq.FieldQueries {
-			if !s.isValidField(field) {
-				continue
-			}
-			if strings.TrimSpace(value) == "" {
-				continue
-			}
-
-			var condition string
-			if originalReq.MatchType == "full" {
-				condition = fmt.Sprintf("%s = ?", field)
-				args = append(args, value)
-			} else {
-				condition = fmt.Sprintf("%s ILIKE ?", field)
-				args = append(args, "%"+value+"%")
-			}
-			originalConditions = append(originalConditions, condition)
-		}
-	} else if len(originalReq.Fields) > 0 {
-		// Legacy method: single query across multiple fields
-		for _, field := range originalReq.Fields {
-			if !s.isValidField(field) {
-				continue
-			}
-
-			var condition string
-			if originalReq.MatchType == "full" {
-				condition = fmt.Sprintf("%s = ?", field)
-				args = append(args, originalReq.Query)
-			} else {
-				condition = fmt.Sprintf("%s ILIKE ?", field)
-				args = append(args, "%"+originalReq.Query+"%")
-			}
-			originalConditions = append(originalConditions, condition)
-		}
-	} else {
-		// Default search across all text fields for original query
-		if originalReq.MatchType == "full" {
-			condition := "(mobile = ? OR name = ? OR fname = ? OR address = ? OR alt = ? OR circle = ? OR email = ? OR master_id = ?)"
-			originalConditions = append(originalConditions, condition)
-			for i := 0; i < 8; i++ {
-				args = append(args, originalReq.Query)
-			}
-		} else {
-			condition := "(mobile ILIKE ? OR name ILIKE ? OR fname ILIKE ? OR address ILIKE ? OR alt ILIKE ? OR circle ILIKE ? OR email ILIKE ? OR master_id ILIKE ?)"
-			originalConditions = append(originalConditions, condition)
-			queryWithWildcard := "%" + originalReq.Query + "%"
-			for i := 0; i < 8; i++ {
-				args = append(args, queryWithWildcard)
-			}
-		}
-	}
+// countInQuery reports whether searches count their matches in the page query, as search.count_mode
+// "window" (the default) asks
+func countInQuery() bool {
+	return config.Get() == nil || config.Get().Search.CountMode != "query"
+}
 
-	// Build the new search conditions for count
-	newConditions := []string{}
-	fields := withinReq.Fields
-	if len(fields) == 0 {
-		fields = []string{"mobile", "name", "fname", "address", "alt", "circle", "email", "master_id"}
+// exactCountLimit returns the number of matches from which a search's total count is estimated
+func exactCountLimit() int {
+	if config.Get() != nil && config.Get().Search.ExactCountLimit > 0 {
+		return config.Get().Search.ExactCountLimit
 	}
+	return 100000
+}
 
-	for _, field := range fields {
-		if !s.isValidField(field) {
-			continue
-		}
-
-		var condition string
-		if withinReq.MatchType == "full" {
-			condition = fmt.Sprintf("%s = ?", field)
-			args = append(args, withinReq.Query)
-		} else {
-			condition = fmt.Sprintf("%s ILIKE ?", field)
-			args = append(args, "%"+withinReq.Query+"%")
-		}
-		newConditions = append(newConditions, condition)
+// searchPage returns one page of a search, its total count and whether that count is estimated. By
+// default the page query counts the matches; with search.count_mode "query" a separate count query does,
+// and a failed count falls back to the page size.
+func (s *SearchService) searchPage(ctx context.Context, req *models.SearchRequest) ([]models.Person, int, bool, error) {
+	if countInQuery() {
+		return s.backend.SearchWithCount(ctx, req, exactCountLimit())
 	}
 
-	// Combine conditions with proper logic
-	originalLogic := "OR"
-	if originalReq.Logic == "AND" {
-		originalLogic = "AND"
+	results, err := s.backend.Search(ctx, req)
+	if err != nil {
+		return nil, 0, false, err
 	}
-
-	baseCountQuery := `SELECT count() FROM finone_search.people WHERE `
-
-	// Original conditions
-	originalWhere := "(" + strings.Join(originalConditions, " "+originalLogic+" ") + ")"
-
-	// New conditions (always OR for within search fields)
-	newWhere := "(" + strings.Join(newConditions, " OR ") + ")"
-
-	// Combine with AND (search within means both conditions must be true)
-	combinedWhere := originalWhere + " AND " + newWhere
-
-	countQuery := baseCountQuery + combinedWhere + " SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1"
-
-	var totalCount uint64
-	err := database.ClickHouseDB.QueryRow(ctx, countQuery, args...).Scan(&totalCount)
+	totalCount, err := s.backend.Count(ctx, req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get search within total count: %w", err)
+		utils.LogError("Failed to get total count", err)
+		totalCount = len(results) // Fallback to current page count
 	}
+	return results, totalCount, false, nil
+}
 
-	return int(totalCount), nil
+// validSearchFields are the fields searches can match on
+var validSearchFields = map[string]bool{
+	"mobile":    true,
+	"name":      true,
+	"fname":     true,
+	"address":   true,
+	"alt":       true,
+	"circle":    true,
+	"email":     true,
+	"master_id": true,
+	// virtual fields
+	"pincode":  true,
+	"locality": true, // derived from the pincode directory
+	"city":     true,
+	"state":    true,
 }
 
 // isValidField checks if the field is valid for searching
 func (s *SearchService) isValidField(field string) bool {
-	validFields := map[string]bool{
-		"mobile":    true,
-		"name":      true,
-		"fname":     true,
-		"address":   true,
-		"alt":       true,
-		"circle":    true,
-		"email":     true,
-		"master_id": true,
-		// virtual field
-		"pincode": true,
-	}
-	return validFields[field]
+	return validSearchFields[field]
 }
 
-// GetPersonByID retrieves a person by ID
-func (s *SearchService) GetPersonByID(id string) (*models.Person, error) {
-	var person models.Person
-	query := `SELECT id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at
-	          FROM finone_search.people WHERE id = ?`
-
+// GetPersonByID retrieves a person by ID from a dataset table, or the default people table when table is empty
+func (s *SearchService) GetPersonByID(id, table string) (*models.Person, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	ctx = database.WithPeopleTable(ctx, table)
 
-	err := database.ClickHouseDB.QueryRow(ctx, query, id).ScanStruct(&person)
+	person, err := s.backend.GetPerson(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("person not found: %w", err)
 	}
 
-	return &person, nil
+	return person, nil
+}
+
+// MaskResults applies the user's field visibility policy to people in place. If the policy
+// cannot be loaded every maskable field is hidden rather than leaking data.
+func (s *SearchService) MaskResults(userID uuid.UUID, people []models.Person) {
+	if len(people) == 0 {
+		return
+	}
+	NewFieldMaskingService().MaskPeople(people, maskedFieldsFor(userID))
+}
+
+// facetMaskedFields maps facets to the field whose masking hides their values. Address masking keeps
+// the pincode, so the pincode, city and state facets stay.
+var facetMaskedFields = map[string]string{
+	"circle": "circle",
+}
+
+// dropMaskedFacets removes the facets on fields masked for the user, whose grouped values would
+// otherwise reveal what the results hide
+func (s *SearchService) dropMaskedFacets(userID uuid.UUID, facets map[string][]models.FacetCount) map[string][]models.FacetCount {
+	if len(facets) == 0 {
+		return facets
+	}
+	for _, field := range maskedFieldsFor(userID) {
+		for facet, maskedField := range facetMaskedFields {
+			if maskedField == field {
+				delete(facets, facet)
+			}
+		}
+	}
+	return facets
+}
+
+// maskedFieldsFor returns the fields masked for a user, or every maskable field when their policy
+// cannot be loaded
+func maskedFieldsFor(userID uuid.UUID) []string {
+	fields, err := NewFieldMaskingService().GetMaskedFieldsForUser(userID)
+	if err != nil {
+		utils.LogError("Failed to load field visibility policy, masking all fields", err)
+		fields = make([]string, 0, len(maskableFields))
+		for field := range maskableFields {
+			fields = append(fields, field)
+		}
+	}
+	return fields
 }
 
-// GetSearchStats returns search statistics
-func (s *SearchService) GetSearchStats() (map[string]interface{}, error) {
+// GetSearchStats returns search statistics of a dataset, or of the default people table when dataset is nil
+func (s *SearchService) GetSearchStats(dataset *models.Dataset) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
 	// Total records count
-	var totalRecords uint64
-	countQuery := `SELECT count() FROM finone_search.people`
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err := database.ClickHouseDB.QueryRow(ctx, countQuery).Scan(&totalRecords)
+	if dataset != nil {
+		ctx = database.WithPeopleTable(ctx, database.QualifiedTable(dataset.TableName))
+	}
+	totalRecords, err := s.backend.CountAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total records: %w", err)
 	}
 
 	stats["total_records"] = totalRecords
 
+	// Dataset searches are told apart by the search log, since search_performance has no dataset
+	if dataset != nil {
+		var avgTime float64
+		var searchCount int64
+		query := `SELECT COALESCE(avg(execution_time_ms), 0), count(*)
+				  FROM searches
+				  WHERE dataset_id = $1 AND search_time >= now() - INTERVAL '1 day'`
+		if err := database.PostgresDB.QueryRow(query, dataset.ID).Scan(&avgTime, &searchCount); err != nil {
+			utils.LogError("Failed to get dataset search stats", err)
+		}
+		stats["dataset_id"] = dataset.ID
+		stats["dataset"] = dataset.Slug
+		stats["avg_search_time_ms"] = avgTime
+		stats["searches_last_24h"] = searchCount
+		return stats, nil
+	}
+
 	// Recent search performance
 	perfQuery := `SELECT avg(execution_time_ms), count()
 	              FROM finone_search.search_performance
@@ -652,7 +631,7 @@ func (s *SearchService) GetSearchStats() (map[string]interface{}, error) {
 }
 
 // logSearch logs a search operation to PostgreSQL, embedding the fingerprint into the stored JSON
-func (s *SearchService) logSearch(userID uuid.UUID, req *models.SearchRequest, resultCount, executionTime int, searchID, fingerprint string) {
+func (s *SearchService) logSearch(ctx context.Context, userID uuid.UUID, req *models.SearchRequest, resultCount, executionTime int, searchID, fingerprint string) {
 	// Marshal req then inject fingerprint in a deterministic way
 	raw, _ := json.Marshal(req)
 	var obj map[string]interface{}
@@ -660,35 +639,66 @@ func (s *SearchService) logSearch(userID uuid.UUID, req *models.SearchRequest, r
 	obj["fingerprint"] = fingerprint
 	queryData, _ := json.Marshal(obj)
 
-	query := `INSERT INTO searches (id, user_id, search_query, result_count, execution_time_ms)
-	          VALUES ($1, $2, $3, $4, $5)`
+	query := `INSERT INTO searches (id, user_id, search_query, result_count, execution_time_ms, client_id, is_diagnostic, request_id, dataset_id, impersonated_by)
+	          VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), NULLIF($9, '')::uuid, NULLIF($10, '')::uuid)`
 
-	_, err := database.PostgresDB.Exec(query, searchID, userID, queryData, resultCount, executionTime)
+	_, err := database.PostgresDB.ExecContext(ctx, query, searchID, userID, queryData, resultCount, executionTime, req.ClientID, req.Diagnostic, req.RequestID, req.DatasetID, req.ImpersonatedBy)
 	if err != nil {
 		utils.LogError("Failed to log search", err)
 	}
 }
 
-// logSearchPerformance logs search performance to ClickHouse
-func (s *SearchService) logSearchPerformance(queryID, userID, queryText string, executionTime, resultCount int) {
+// logSearchPerformance logs search performance to ClickHouse; queryID is the search ID and explainPlan
+// the captured plan of a slow search, if any
+func (s *SearchService) logSearchPerformance(ctx context.Context, queryID, userID, requestID, queryText, explainPlan string, executionTime, resultCount int) {
 	query := `INSERT INTO finone_search.search_performance
-	          (query_id, user_id, query_text, execution_time_ms, result_count)
-	          VALUES (?, ?, ?, ?, ?)`
+	          (query_id, user_id, request_id, query_text, explain_plan, execution_time_ms, result_count)
+	          VALUES (?, ?, ?, ?, ?, ?, ?)`
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	err := database.ClickHouseDB.Exec(ctx, query, queryID, userID, queryText, executionTime, resultCount)
+	err := database.ClickHouseDB.Exec(ctx, query, queryID, userID, requestID, queryText, explainPlan, executionTime, resultCount)
 
 	if err != nil {
 		utils.LogError("Failed to log search performance", err)
 	}
 }
 
+// isSlowSearch reports whether a ClickHouse search took longer than search.slow_query_threshold
+func (s *SearchService) isSlowSearch(executionTime int) bool {
+	threshold := config.Get().Search.SlowQueryThreshold
+	if _, ok := s.backend.(*clickHouseSearchBackend); !ok || threshold <= 0 {
+		return false
+	}
+	return time.Duration(executionTime)*time.Millisecond > threshold
+}
+
+// logSlowSearchPerformance runs EXPLAIN on a slow search's query and logs the plan with its performance
+// metrics. It runs in the background so the EXPLAIN does not delay the response further.
+func (s *SearchService) logSlowSearchPerformance(ctx context.Context, queryID, userID, requestID, query string, args []interface{}, executionTime, resultCount int) {
+	utils.LogWarning(fmt.Sprintf("Slow search %s took %dms, capturing its query plan", queryID, executionTime))
+
+	explainCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	plan, err := explainQuery(explainCtx, query, args)
+	cancel()
+	explainPlan := strings.Join(plan, "\n")
+	if err != nil {
+		utils.LogError("Failed to explain slow search", err)
+		explainPlan = "EXPLAIN failed: " + err.Error()
+	}
+
+	s.logSearchPerformance(ctx, queryID, userID, requestID, query, explainPlan, executionTime, resultCount)
+}
+
 // SearchWithin performs a search within previous search results
 func (s *SearchService) SearchWithin(userID uuid.UUID, req *models.SearchWithinRequest) (*models.SearchResponse, error) {
 	startTime := time.Now()
 
+	if err := s.enforceAllowedWithinFields(userID, req); err != nil {
+		return nil, err
+	}
+
 	// Parse the search_id string to UUID
 	originalSearchID, err := uuid.Parse(req.SearchID)
 	if err != nil {
@@ -702,44 +712,36 @@ func (s *SearchService) SearchWithin(userID uuid.UUID, req *models.SearchWithinR
 	if err != nil {
 		return nil, fmt.Errorf("original search not found: %w", err)
 	}
+	if originalSearch.AnonymizedAt != nil {
+		return nil, ErrSearchAnonymized
+	}
 
 	// Extract the original search parameters
-	var originalReq models.SearchRequest
-
-	// Handle the SearchQuery which is stored as interface{} in JSONB
-	var queryData []byte
-	switch v := originalSearch.SearchQuery.(type) {
-	case []byte:
-		queryData = v
-	case string:
-		queryData = []byte(v)
-	default:
-		// Try to marshal and then unmarshal
-		queryData, _ = json.Marshal(originalSearch.SearchQuery)
+	storedReq, err := storedSearchRequest(&originalSearch)
+	if err != nil {
+		return nil, err
 	}
+	originalReq := *storedReq
 
-	if err := json.Unmarshal(queryData, &originalReq); err != nil {
-		return nil, fmt.Errorf("failed to parse original search: %w", err)
+	// Refine within the original search's dataset, provided the user can still use it
+	table, err := resolveDatasetTable(userID, &originalReq.DatasetID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build a combined query that includes both original and new search criteria
-	combinedQuery := s.buildSearchWithinQuery(&originalReq, req)
-
-	utils.LogInfo(fmt.Sprintf("Executing search within query: %s", combinedQuery))
-
-	// Execute the refined search
-	var results []models.Person
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	// Execute the refined search, combining the original and new search criteria
+	ctx, cancel := searchContext(traceContext(req.Context), userID)
 	defer cancel()
+	ctx = database.WithPeopleTable(ctx, table)
 
-	err = database.ClickHouseDB.Select(ctx, &results, combinedQuery)
+	results, err := s.backend.SearchWithin(ctx, &originalReq, req)
 	if err != nil {
 		utils.LogError("Search within query failed", err)
 		return nil, fmt.Errorf("search within failed: %w", err)
 	}
 
 	// Get proper total count for SearchWithin using a separate count query
-	totalCount, err := s.getSearchWithinTotalCount(&originalReq, req, ctx)
+	totalCount, err := s.backend.CountWithin(ctx, &originalReq, req)
 	if err != nil {
 		utils.LogError("Failed to get search within total count", err)
 		totalCount = len(results) // Fallback to current page count
@@ -750,122 +752,55 @@ func (s *SearchService) SearchWithin(userID uuid.UUID, req *models.SearchWithinR
 
 	// Log the search within operation
 	searchWithinReq := models.SearchRequest{
-		Query:     fmt.Sprintf("WITHIN[%s]: %s", req.SearchID, req.Query),
-		Fields:    req.Fields,
-		MatchType: req.MatchType,
-		Limit:     req.Limit,
-		Offset:    req.Offset,
+		Query:          fmt.Sprintf("WITHIN[%s]: %s", req.SearchID, req.Query),
+		Fields:         req.Fields,
+		MatchType:      req.MatchType,
+		Limit:          req.Limit,
+		Offset:         req.Offset,
+		Diagnostic:     req.Diagnostic,
+		DatasetID:      originalReq.DatasetID,
+		ClientID:       req.ClientID,
+		RequestID:      req.RequestID,
+		ImpersonatedBy: req.ImpersonatedBy,
 	}
 	fingerprint := s.computeSearchFingerprint(&searchWithinReq)
-	isDup, _ := s.isDuplicateSearchToday(userID, fingerprint)
-	s.logSearch(userID, &searchWithinReq, len(results), executionTime, newSearchID, fingerprint)
+	isDup, _ := s.isDuplicateSearchToday(ctx, userID, fingerprint)
+	s.logSearch(ctx, userID, &searchWithinReq, len(results), executionTime, newSearchID, fingerprint)
+	s.logSearchPerformance(ctx, newSearchID, userID.String(), req.RequestID, searchWithinReq.Query, "", executionTime, len(results))
 
 	// Only increment search count if we found results (search within should count as a new search) and not duplicate
-	if totalCount > 0 && !isDup {
+	var freeReason string
+	if req.Diagnostic {
+		utils.LogInfo("Diagnostic search within, search count not incremented")
+		freeReason = models.SearchFreeDiagnostic
+	} else if totalCount > 0 && !isDup {
 		authService := NewAuthService()
 		if err := authService.IncrementSearchCount(userID); err != nil {
 			utils.LogError("Failed to increment search count for search within", err)
 		}
 	} else if totalCount == 0 {
 		utils.LogInfo("No results found in search within, search count not incremented")
+		freeReason = models.SearchFreeNoResults
 	} else if isDup {
 		utils.LogInfo("Duplicate search-within detected for today, search count not incremented")
+		freeReason = models.SearchFreeDuplicate
 	}
 
+	// Apply the user's field visibility policy before returning
+	s.MaskResults(userID, results)
+	highlightPeople(results, withinHighlightTerms(&originalReq, req))
+
 	return &models.SearchResponse{
 		Results:       results,
 		TotalCount:    totalCount,
 		ExecutionTime: executionTime,
 		SearchID:      newSearchID,
 		HasMore:       (req.Offset + len(results)) < totalCount,
+		Quota:         models.NewSearchQuota(freeReason),
+		Mode:          models.SearchModeStandard,
 	}, nil
 }
 
-// buildSearchWithinQuery builds a query that searches within previous results
-func (s *SearchService) buildSearchWithinQuery(originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) string {
-	// Build the original query conditions
-	originalConditions := []string{}
-
-	// Check if we have field-specific queries (preferred method)
-	if len(originalReq.FieldQueries) > 0 {
-		// Field-specific search: each field has its own query value
-		for field, value := range originalReq.FieldQueries {
-			if !s.isValidField(field) {
-				continue
-			}
-			if strings.TrimSpace(value) == "" {
-				continue
-			}
-			if originalReq.MatchType == "full" {
-				originalConditions = append(originalConditions, fmt.Sprintf("%s = '%s'", field, value))
-			} else {
-				originalConditions = append(originalConditions, fmt.Sprintf("%s ILIKE '%%%s%%'", field, value))
-			}
-		}
-	} else if len(originalReq.Fields) > 0 {
-		// Legacy method: single query across multiple fields
-		for _, field := range originalReq.Fields {
-			if !s.isValidField(field) {
-				continue
-			}
-			if originalReq.MatchType == "full" {
-				originalConditions = append(originalConditions, fmt.Sprintf("%s = '%s'", field, originalReq.Query))
-			} else {
-				originalConditions = append(originalConditions, fmt.Sprintf("%s ILIKE '%%%s%%'", field, originalReq.Query))
-			}
-		}
-	}
-
-	// Build the new search conditions
-	newConditions := []string{}
-	fields := withinReq.Fields
-	if len(fields) == 0 {
-		fields = []string{"mobile", "name", "fname", "address", "alt", "circle", "email", "master_id"}
-	}
-
-	for _, field := range fields {
-		if !s.isValidField(field) {
-			continue
-		}
-		if withinReq.MatchType == "full" {
-			newConditions = append(newConditions, fmt.Sprintf("%s = '%s'", field, withinReq.Query))
-		} else {
-			newConditions = append(newConditions, fmt.Sprintf("%s ILIKE '%%%s%%'", field, withinReq.Query))
-		}
-	}
-
-	// Combine both conditions
-	originalLogic := "OR"
-	if originalReq.Logic == "AND" {
-		originalLogic = "AND"
-	}
-
-	baseQuery := `SELECT id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at
-	              FROM finone_search.people WHERE `
-
-	// Original conditions
-	originalWhere := "(" + strings.Join(originalConditions, " "+originalLogic+" ") + ")"
-
-	// New conditions
-	newWhere := "(" + strings.Join(newConditions, " OR ") + ")"
-
-	// Combine with AND (search within means both conditions must be true)
-	combinedWhere := originalWhere + " AND " + newWhere
-
-	query := baseQuery + combinedWhere + " ORDER BY mobile, name"
-
-	if withinReq.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", withinReq.Limit)
-	}
-	if withinReq.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", withinReq.Offset)
-	}
-
-	query += " SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1"
-
-	return query
-}
-
 // isMobileNumber checks if a string looks like a mobile number (10-12 digits)
 func (s *SearchService) isMobileNumber(query string) bool {
 	// Remove any non-digit characters for validation
@@ -876,35 +811,7 @@ func (s *SearchService) isMobileNumber(query string) bool {
 
 // isValidMasterID checks if a master ID is valid and not a partial/masked ID
 func (s *SearchService) isValidMasterID(masterID string) bool {
-	if masterID == "" {
-		return false
-	}
-
-	// Filter out partial/masked master IDs that contain 'x' characters
-	// These are typically used to mask sensitive data and should not be used for searching
-	if strings.Contains(strings.ToLower(masterID), "x") {
-		return false
-	}
-
-	// Filter out master IDs that are too short (likely partial matches)
-	// Valid master IDs should typically be at least 8-10 characters long
-	if len(masterID) < 8 {
-		return false
-	}
-
-	// Check if it's all digits or contains valid suffix characters
-	// Remove any valid suffix characters (letters) from the end
-	baseID := regexp.MustCompile(`[A-Za-z]*$`).ReplaceAllString(masterID, "")
-
-	// The base part should be all digits
-	if !regexp.MustCompile(`^\d+$`).MatchString(baseID) {
-		return false
-	}
-
-	// If the original masterID is longer than baseID, it means it has a suffix
-	// This is allowed as per requirements (e.g., 718834427584M)
-
-	return true
+	return utils.IsValidMasterID(masterID)
 }
 
 // shouldUseEnhancedMobileSearch determines if the search should use enhanced mobile search
@@ -988,196 +895,200 @@ func (s *SearchService) extractMobileNumber(req *models.SearchRequest) string {
 // EnhancedMobileSearch performs an enhanced mobile number search
 // It searches for the mobile number and then finds all records with the same master_ids
 func (s *SearchService) EnhancedMobileSearch(userID uuid.UUID, req *models.EnhancedMobileSearchRequest) (*models.EnhancedMobileSearchResponse, error) {
-	// Check if user has remaining search quota
-	authService := NewAuthService()
-	canSearch, err := authService.CheckSearchLimit(userID)
+	return s.EnhancedSearch(userID, &models.EnhancedSearchRequest{
+		KeyType:        models.EnhancedKeyMobile,
+		Value:          req.MobileNumber,
+		Limit:          req.Limit,
+		Offset:         req.Offset,
+		Depth:          req.Depth,
+		Diagnostic:     req.Diagnostic,
+		DatasetID:      req.DatasetID,
+		ClientID:       req.ClientID,
+		RequestID:      req.RequestID,
+		Context:        req.Context,
+		ImpersonatedBy: req.ImpersonatedBy,
+	})
+}
+
+// EnhancedSearch finds the direct matches of a mobile number, alt number or email and then all
+// records with the same master_ids
+func (s *SearchService) EnhancedSearch(userID uuid.UUID, req *models.EnhancedSearchRequest) (*models.EnhancedMobileSearchResponse, error) {
+	key, err := enhancedSearchKey(req.KeyType, req.Value)
 	if err != nil {
-		utils.LogError("Failed to check search limit", err)
-		return nil, fmt.Errorf("failed to check search limit")
+		return nil, err
 	}
-	if !canSearch {
-		return nil, fmt.Errorf("daily search limit exceeded")
+
+	// Check if user has remaining search quota; admin diagnostic searches are exempt
+	authService := NewAuthService()
+	if !req.Diagnostic {
+		canSearch, err := authService.CheckSearchLimit(userID)
+		if err != nil {
+			utils.LogError("Failed to check search limit", err)
+			return nil, fmt.Errorf("failed to check search limit")
+		}
+		if !canSearch {
+			return nil, ErrSearchLimitExceeded
+		}
+	}
+	if err := s.enforceAllowedKeySearch(userID, key.Type); err != nil {
+		return nil, err
+	}
+	table, err := resolveDatasetTable(userID, &req.DatasetID)
+	if err != nil {
+		return nil, err
 	}
 
 	startTime := time.Now()
 	searchID := uuid.New().String()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // Longer timeout for complex query
+	ctx, cancel := searchContext(traceContext(req.Context), userID)
 	defer cancel()
+	ctx = database.WithPeopleTable(ctx, table)
 
-	// Clean the mobile number (remove any non-digit characters)
-	cleanedMobile := regexp.MustCompile(`\D`).ReplaceAllString(req.MobileNumber, "")
-
-	utils.LogInfo(fmt.Sprintf("Enhanced mobile search for: %s (cleaned: %s)", req.MobileNumber, cleanedMobile))
-
-	// Step 1: Find all direct mobile number matches (both exact and partial)
-	directMatchQuery := `
-		SELECT id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at
-		FROM finone_search.people
-		WHERE mobile = ? OR mobile ILIKE ? OR mobile ILIKE ? OR alt = ? OR alt ILIKE ? OR alt ILIKE ?
-		ORDER BY mobile, name
-		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
-	`
-
-	// Prepare variations of the mobile number for matching
-	mobileVariations := []interface{}{
-		cleanedMobile,       // Exact match
-		"%" + cleanedMobile, // Ends with
-		cleanedMobile + "%", // Starts with
-		cleanedMobile,       // Alt exact match
-		"%" + cleanedMobile, // Alt ends with
-		cleanedMobile + "%", // Alt starts with
-	}
+	utils.LogInfo(fmt.Sprintf("Enhanced %s search for: %s (cleaned: %s)", key.Type, req.Value, key.Value))
 
-	var directMatches []models.Person
-	err = database.ClickHouseDB.Select(ctx, &directMatches, directMatchQuery, mobileVariations...)
+	// Step 1: Find the master_ids of the direct matches of the key (numbers both exact and partial)
+	firstByMasterID, err := s.backend.FindKeyMasterIDs(ctx, key)
 	if err != nil {
-		utils.LogError("Direct mobile search failed", err)
-		return nil, fmt.Errorf("direct mobile search failed: %w", err)
+		utils.LogError("Direct "+key.Type+" search failed", err)
+		return nil, fmt.Errorf("direct %s search failed: %w", key.Type, err)
 	}
 
-	utils.LogInfo(fmt.Sprintf("Found %d direct matches for mobile: %s", len(directMatches), cleanedMobile))
-
-	// Step 2: Extract unique master_ids from direct matches
 	masterIDMap := make(map[string]bool)
-	for _, person := range directMatches {
-		if person.MasterID != "" && s.isValidMasterID(person.MasterID) {
-			masterIDMap[person.MasterID] = true
-		}
-	}
-
-	var masterIDMatches []models.Person
 	var uniqueMasterIDs []string
-
-	if len(masterIDMap) > 0 {
-		// Convert map to slice for query
-		for masterID := range masterIDMap {
+	for masterID := range firstByMasterID {
+		if s.isValidMasterID(masterID) {
+			masterIDMap[masterID] = true
 			uniqueMasterIDs = append(uniqueMasterIDs, masterID)
 		}
+	}
+	slices.Sort(uniqueMasterIDs)
+	hop := EnhancedHop{Key: key, MasterIDs: uniqueMasterIDs}
 
-		utils.LogInfo(fmt.Sprintf("Found %d unique master_ids, searching for related records", len(uniqueMasterIDs)))
+	// Step 2: Count the direct matches and the other records with these master_ids
+	totalDirectCount, totalMasterIDCount, err := s.backend.CountEnhancedMatches(ctx, hop)
+	if err != nil {
+		utils.LogError("Master ID search failed", err)
+		return nil, fmt.Errorf("master ID search failed: %w", err)
+	}
 
-		// Step 3: Find all records with these master_ids (excluding already found direct matches)
-		// Build dynamic IN clause for master_ids
-		placeholders := make([]string, len(uniqueMasterIDs))
-		masterIDArgs := make([]interface{}, len(uniqueMasterIDs))
-		for i, masterID := range uniqueMasterIDs {
-			placeholders[i] = "?"
-			masterIDArgs[i] = masterID
-		}
+	utils.LogInfo(fmt.Sprintf("Found %d direct matches for %s: %s and %d additional records with %d matching master_ids",
+		totalDirectCount, key.Type, key.Value, totalMasterIDCount, len(uniqueMasterIDs)))
 
-		masterIDQuery := fmt.Sprintf(`
-			SELECT id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at
-			FROM finone_search.people
-			WHERE master_id IN (%s)
-			AND id NOT IN (
-				SELECT id FROM finone_search.people
-				WHERE mobile = ? OR mobile ILIKE ? OR mobile ILIKE ? OR alt = ? OR alt ILIKE ? OR alt ILIKE ?
-			)
-			ORDER BY master_id, mobile, name
-			SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
-		`, strings.Join(placeholders, ","))
-
-		// Combine master_id args with mobile variations for exclusion
-		allArgs := append(masterIDArgs, mobileVariations...)
-
-		err = database.ClickHouseDB.Select(ctx, &masterIDMatches, masterIDQuery, allArgs...)
+	// Step 3: At depth 2, follow the alt numbers of those records to more master_ids
+	var secondHopMatches []models.Person
+	secondHopTruncated := false
+	if req.Depth >= 2 {
+		secondHopMatches, secondHopTruncated, err = s.expandSecondHop(ctx, hop, firstByMasterID, masterIDMap)
 		if err != nil {
-			utils.LogError("Master ID search failed", err)
-			return nil, fmt.Errorf("master ID search failed: %w", err)
+			utils.LogError("Second hop search failed", err)
+			return nil, err
 		}
-
-		utils.LogInfo(fmt.Sprintf("Found %d additional records with matching master_ids", len(masterIDMatches)))
+		for _, person := range secondHopMatches {
+			if masterIDMap[person.MasterID] && !slices.Contains(uniqueMasterIDs, person.MasterID) {
+				uniqueMasterIDs = append(uniqueMasterIDs, person.MasterID)
+			}
+		}
+		utils.LogInfo(fmt.Sprintf("Found %d records linked through alt numbers", len(secondHopMatches)))
 	}
 
-	// Step 4: Get total counts for pagination
-	totalDirectCount := len(directMatches)
-	totalMasterIDCount := len(masterIDMatches)
-	totalCount := totalDirectCount + totalMasterIDCount
-
-	// Step 5: Apply pagination to combined results
-	var finalDirectMatches, finalMasterIDMatches []models.Person
+	// Step 4: Read only the requested page; direct and master ID matches are paged by the backend and
+	// the capped second hop follows them
+	firstHopCount := totalDirectCount + totalMasterIDCount
+	totalCount := firstHopCount + len(secondHopMatches)
+	if req.Limit <= 0 {
+		req.Limit = 1000
+	}
 
-	if req.Limit > 0 {
-		// Apply pagination logic
-		if req.Offset < totalDirectCount {
-			// We're still in the direct matches range
-			endIndex := req.Offset + req.Limit
-			if endIndex > totalDirectCount {
-				endIndex = totalDirectCount
-			}
-			finalDirectMatches = directMatches[req.Offset:endIndex]
-
-			// If we have remaining limit, get from master ID matches
-			remainingLimit := req.Limit - len(finalDirectMatches)
-			if remainingLimit > 0 && len(masterIDMatches) > 0 {
-				masterEndIndex := remainingLimit
-				if masterEndIndex > len(masterIDMatches) {
-					masterEndIndex = len(masterIDMatches)
-				}
-				finalMasterIDMatches = masterIDMatches[0:masterEndIndex]
-			}
-		} else {
-			// We're in the master ID matches range
-			masterOffset := req.Offset - totalDirectCount
-			if masterOffset < len(masterIDMatches) {
-				endIndex := masterOffset + req.Limit
-				if endIndex > len(masterIDMatches) {
-					endIndex = len(masterIDMatches)
-				}
-				finalMasterIDMatches = masterIDMatches[masterOffset:endIndex]
-			}
+	var finalDirectMatches, finalMasterIDMatches, finalSecondHopMatches []models.Person
+	if req.Offset < firstHopCount {
+		page, err := s.backend.FindEnhancedMatches(ctx, hop, req.Offset, req.Limit)
+		if err != nil {
+			utils.LogError("Enhanced match page failed", err)
+			return nil, fmt.Errorf("master ID search failed: %w", err)
+		}
+		finalDirectMatches, finalMasterIDMatches = linkFirstHop(key, firstByMasterID, page)
+	}
+	if remaining := req.Limit - len(finalDirectMatches) - len(finalMasterIDMatches); remaining > 0 {
+		from := max(req.Offset-firstHopCount, 0)
+		if to := min(from+remaining, len(secondHopMatches)); from < to {
+			finalSecondHopMatches = secondHopMatches[from:to]
 		}
-	} else {
-		// No pagination, return all results
-		finalDirectMatches = directMatches
-		finalMasterIDMatches = masterIDMatches
 	}
 
 	executionTime := int(time.Since(startTime).Milliseconds())
-	hasMore := (req.Offset + len(finalDirectMatches) + len(finalMasterIDMatches)) < totalCount
+	hasMore := (req.Offset + len(finalDirectMatches) + len(finalMasterIDMatches) + len(finalSecondHopMatches)) < totalCount
 
 	// Log the search
+	query := fmt.Sprintf("ENHANCED_%s: %s", strings.ToUpper(key.Type), req.Value)
+	if req.Depth >= 2 {
+		query += " (depth 2)"
+	}
+	fields := []string{key.Type}
+	if key.Type == models.EnhancedKeyMobile {
+		fields = []string{"mobile", "alt"}
+	}
 	searchReq := &models.SearchRequest{
-		Query:          fmt.Sprintf("ENHANCED_MOBILE: %s", req.MobileNumber),
-		Fields:         []string{"mobile", "alt"},
+		Query:          query,
+		Fields:         fields,
 		Logic:          "OR",
 		MatchType:      "partial",
 		Limit:          req.Limit,
 		Offset:         req.Offset,
 		EnhancedMobile: true,
+		Diagnostic:     req.Diagnostic,
+		DatasetID:      req.DatasetID,
+		ClientID:       req.ClientID,
+		RequestID:      req.RequestID,
+		ImpersonatedBy: req.ImpersonatedBy,
 	}
 	fingerprint := s.computeSearchFingerprint(searchReq)
-	isDup, _ := s.isDuplicateSearchToday(userID, fingerprint)
-	s.logSearch(userID, searchReq, totalCount, executionTime, searchID, fingerprint)
+	isDup, _ := s.isDuplicateSearchToday(ctx, userID, fingerprint)
+	s.logSearch(ctx, userID, searchReq, totalCount, executionTime, searchID, fingerprint)
 
 	// Log performance metrics
-	queryText := fmt.Sprintf("Enhanced mobile search: %s (found %d master_ids)", cleanedMobile, len(uniqueMasterIDs))
-	s.logSearchPerformance(searchID, userID.String(), queryText, executionTime, totalCount)
+	queryText := fmt.Sprintf("Enhanced %s search: %s (found %d master_ids)", key.Type, key.Value, len(uniqueMasterIDs))
+	s.logSearchPerformance(ctx, searchID, userID.String(), req.RequestID, queryText, "", executionTime, totalCount)
 
 	// Only increment user's daily search count if we found results and not duplicate
-	if totalCount > 0 && !isDup {
+	var freeReason string
+	if req.Diagnostic {
+		utils.LogInfo("Diagnostic enhanced mobile search, search count not incremented")
+		freeReason = models.SearchFreeDiagnostic
+	} else if totalCount > 0 && !isDup {
 		if err := authService.IncrementSearchCount(userID); err != nil {
 			utils.LogError("Failed to increment search count", err)
 		}
 	} else if totalCount == 0 {
 		utils.LogInfo("No results found in enhanced mobile search, search count not incremented")
+		freeReason = models.SearchFreeNoResults
 	} else if isDup {
 		utils.LogInfo("Duplicate enhanced-mobile search detected for today, search count not incremented")
+		freeReason = models.SearchFreeDuplicate
 	}
 
 	utils.LogInfo(fmt.Sprintf("Enhanced mobile search completed in %dms. Direct: %d, Master ID: %d, Total: %d",
 		executionTime, len(finalDirectMatches), len(finalMasterIDMatches), totalCount))
 
+	// Apply the user's field visibility policy before returning
+	s.MaskResults(userID, finalDirectMatches)
+	s.MaskResults(userID, finalMasterIDMatches)
+	s.MaskResults(userID, finalSecondHopMatches)
+
 	return &models.EnhancedMobileSearchResponse{
+		KeyType:              key.Type,
 		DirectMatches:        finalDirectMatches,
 		MasterIDMatches:      finalMasterIDMatches,
+		SecondHopMatches:     finalSecondHopMatches,
 		TotalDirectMatches:   totalDirectCount,
 		TotalMasterIDMatches: totalMasterIDCount,
+		TotalSecondHop:       len(secondHopMatches),
+		SecondHopTruncated:   secondHopTruncated,
 		TotalCount:           totalCount,
 		ExecutionTime:        executionTime,
 		SearchID:             searchID,
 		HasMore:              hasMore,
 		MasterIDs:            uniqueMasterIDs,
+		Quota:                models.NewSearchQuota(freeReason),
 	}, nil
 }
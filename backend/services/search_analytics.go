@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Search volume intervals
+const (
+	AnalyticsIntervalHour = "hour"
+	AnalyticsIntervalDay  = "day"
+)
+
+// searchTermExpr is the term a logged search looked for: its query text, or its field queries as
+// field:value pairs when it had no query text. Searches within results are left out.
+const searchTermExpr = `COALESCE(
+	NULLIF(lower(btrim(search_query->>'query')), ''),
+	(SELECT string_agg(key || ':' || lower(btrim(value)), ' ' ORDER BY key)
+	 FROM jsonb_each_text(CASE WHEN jsonb_typeof(search_query->'field_queries') = 'object' THEN search_query->'field_queries' ELSE '{}' END)
+	 WHERE btrim(value) <> ''))`
+
+// searchFieldsExpr is the comma-separated sorted fields a logged search used, from its fields and the
+// fields of its field queries; anonymized searches keep both. Empty means every field.
+const searchFieldsExpr = `(SELECT COALESCE(string_agg(DISTINCT field, ',' ORDER BY field), '') FROM (
+	SELECT key AS field FROM jsonb_each_text(CASE WHEN jsonb_typeof(search_query->'field_queries') = 'object' THEN search_query->'field_queries' ELSE '{}' END)
+	WHERE btrim(value) <> ''
+	UNION ALL
+	SELECT jsonb_array_elements_text(CASE WHEN jsonb_typeof(search_query->'field_query_fields') = 'array' THEN search_query->'field_query_fields' ELSE '[]' END)
+	UNION ALL
+	SELECT jsonb_array_elements_text(CASE WHEN jsonb_typeof(search_query->'fields') = 'array' THEN search_query->'fields' ELSE '[]' END)
+) fields)`
+
+// analyticsSearchesCondition selects the non-diagnostic searches logged in a period ($1 to $2)
+const analyticsSearchesCondition = `search_time >= $1 AND search_time < $2 AND NOT is_diagnostic`
+
+type SearchAnalyticsService struct{}
+
+func NewSearchAnalyticsService() *SearchAnalyticsService {
+	return &SearchAnalyticsService{}
+}
+
+// GetSearchPerformance reports latency percentiles, search volume per hour or day, the slowest
+// searches and per-user latency between from and to, from the ClickHouse performance log. limit
+// bounds the slowest searches and users listed.
+func (s *SearchAnalyticsService) GetSearchPerformance(ctx context.Context, from, to time.Time, interval string, limit int) (*models.SearchPerformanceAnalytics, error) {
+	bucket := "toStartOfHour(timestamp)"
+	if interval == AnalyticsIntervalDay {
+		bucket = "toStartOfDay(timestamp)"
+	}
+
+	analytics := &models.SearchPerformanceAnalytics{
+		From:     from,
+		To:       to,
+		Interval: interval,
+		Volume:   []models.SearchVolumeBucket{},
+		Slowest:  []models.SlowSearch{},
+		ByUser:   []models.UserSearchLatency{},
+	}
+
+	var searches uint64
+	var avg float64
+	var quantiles []float64
+	var maxMs uint32
+	query := `SELECT count(), avg(execution_time_ms), quantiles(0.5, 0.95, 0.99)(execution_time_ms), max(execution_time_ms)
+			  FROM finone_search.search_performance
+			  WHERE timestamp >= ? AND timestamp < ?`
+	if err := database.ClickHouseDB.QueryRow(ctx, query, from, to).Scan(&searches, &avg, &quantiles, &maxMs); err != nil {
+		return nil, fmt.Errorf("failed to get search latency: %w", err)
+	}
+	// Aggregates over no rows are NaN, which JSON cannot carry
+	if searches == 0 {
+		return analytics, nil
+	}
+	analytics.Latency = models.SearchLatency{
+		Searches: int64(searches),
+		AvgMs:    avg,
+		P50Ms:    quantiles[0],
+		P95Ms:    quantiles[1],
+		P99Ms:    quantiles[2],
+		MaxMs:    maxMs,
+	}
+
+	query = `SELECT ` + bucket + ` AS bucket, count() AS searches, avg(execution_time_ms) AS avg_ms,
+			        quantile(0.95)(execution_time_ms) AS p95_ms
+			 FROM finone_search.search_performance
+			 WHERE timestamp >= ? AND timestamp < ?
+			 GROUP BY bucket
+			 ORDER BY bucket`
+	if err := database.ClickHouseDB.Select(ctx, &analytics.Volume, query, from, to); err != nil {
+		return nil, fmt.Errorf("failed to get search volume: %w", err)
+	}
+
+	query = `SELECT query_id, user_id, request_id, query_text, execution_time_ms, result_count, timestamp
+			 FROM finone_search.search_performance
+			 WHERE timestamp >= ? AND timestamp < ?
+			 ORDER BY execution_time_ms DESC
+			 LIMIT ?`
+	if err := database.ClickHouseDB.Select(ctx, &analytics.Slowest, query, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to get slowest searches: %w", err)
+	}
+
+	query = `SELECT user_id, count() AS searches, avg(execution_time_ms) AS avg_ms,
+			        quantile(0.5)(execution_time_ms) AS p50_ms, quantile(0.95)(execution_time_ms) AS p95_ms,
+			        max(execution_time_ms) AS max_ms
+			 FROM finone_search.search_performance
+			 WHERE timestamp >= ? AND timestamp < ?
+			 GROUP BY user_id
+			 ORDER BY p95_ms DESC
+			 LIMIT ?`
+	if err := database.ClickHouseDB.Select(ctx, &analytics.ByUser, query, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to get per-user search latency: %w", err)
+	}
+	if err := s.addUserNames(analytics.ByUser); err != nil {
+		return nil, err
+	}
+
+	return analytics, nil
+}
+
+// GetSlowQueries lists the searches between from and to whose query plan was captured for being
+// slower than search.slow_query_threshold, most recent first
+func (s *SearchAnalyticsService) GetSlowQueries(ctx context.Context, from, to time.Time, limit int) (*models.SlowQueryReport, error) {
+	report := &models.SlowQueryReport{
+		From:        from,
+		To:          to,
+		ThresholdMs: config.Get().Search.SlowQueryThreshold.Milliseconds(),
+		Queries:     []models.SlowQuery{},
+	}
+
+	query := `SELECT query_id, user_id, request_id, query_text, explain_plan, execution_time_ms, result_count, timestamp
+			  FROM finone_search.search_performance
+			  WHERE timestamp >= ? AND timestamp < ? AND explain_plan != ''
+			  ORDER BY timestamp DESC
+			  LIMIT ?`
+	if err := database.ClickHouseDB.Select(ctx, &report.Queries, query, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to get slow queries: %w", err)
+	}
+	for i := range report.Queries {
+		report.Queries[i].Plan = strings.Split(report.Queries[i].ExplainPlan, "\n")
+	}
+
+	return report, nil
+}
+
+// addUserNames fills in the names and emails of the users in a per-user breakdown
+func (s *SearchAnalyticsService) addUserNames(users []models.UserSearchLatency) error {
+	ids := pq.StringArray{}
+	for _, user := range users {
+		if _, err := uuid.Parse(user.UserID); err == nil {
+			ids = append(ids, user.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		ID    string `db:"id"`
+		Name  string `db:"name"`
+		Email string `db:"email"`
+	}
+	if err := database.PostgresDB.Select(&rows, `SELECT id, name, email FROM users WHERE id = ANY($1::uuid[])`, ids); err != nil {
+		return fmt.Errorf("failed to get user names: %w", err)
+	}
+
+	names := make(map[string]int, len(rows))
+	for i, row := range rows {
+		names[row.ID] = i
+	}
+	for i := range users {
+		if j, ok := names[users[i].UserID]; ok {
+			users[i].UserName = rows[j].Name
+			users[i].UserEmail = rows[j].Email
+		}
+	}
+	return nil
+}
+
+// GetTopQueries reports the most searched terms and field combinations between from and to from the
+// search log. Terms of anonymized searches are gone, so they only count toward field combinations.
+func (s *SearchAnalyticsService) GetTopQueries(from, to time.Time, limit int) (*models.TopQueriesReport, error) {
+	report := &models.TopQueriesReport{From: from, To: to, Terms: []models.QueryTermStat{}, FieldCombinations: []models.FieldCombinationStat{}}
+
+	query := `SELECT term, count(*) AS searches, count(DISTINCT user_id) AS users,
+	                 count(*) FILTER (WHERE result_count = 0) AS zero_result_searches, max(search_time) AS last_searched_at
+	          FROM (SELECT ` + searchTermExpr + ` AS term, user_id, result_count, search_time
+	                FROM searches
+	                WHERE ` + analyticsSearchesCondition + ` AND anonymized_at IS NULL
+	                  AND COALESCE(search_query->>'query', '') NOT LIKE 'WITHIN[%') terms
+	          WHERE term IS NOT NULL
+	          GROUP BY term
+	          ORDER BY searches DESC, term
+	          LIMIT $3`
+	if err := database.PostgresDB.Select(&report.Terms, query, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to get top search terms: %w", err)
+	}
+
+	query = `SELECT ` + searchFieldsExpr + ` AS fields, count(*) AS searches,
+	                count(*) FILTER (WHERE result_count = 0) AS zero_result_searches
+	         FROM searches
+	         WHERE ` + analyticsSearchesCondition + `
+	         GROUP BY 1
+	         ORDER BY searches DESC, fields
+	         LIMIT $3`
+	if err := database.PostgresDB.Select(&report.FieldCombinations, query, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to get top field combinations: %w", err)
+	}
+	for i := range report.FieldCombinations {
+		report.FieldCombinations[i].Fields = splitFieldList(report.FieldCombinations[i].FieldList)
+	}
+
+	return report, nil
+}
+
+// GetZeroResultQueries reports the searches between from and to that found nothing, grouped by term
+// and fields with the most repeated first
+func (s *SearchAnalyticsService) GetZeroResultQueries(from, to time.Time, limit int) (*models.ZeroResultReport, error) {
+	report := &models.ZeroResultReport{From: from, To: to, Queries: []models.ZeroResultQuery{}}
+
+	query := `SELECT count(*) AS searches, count(*) FILTER (WHERE result_count = 0) AS zero_result_searches
+	          FROM searches
+	          WHERE ` + analyticsSearchesCondition
+	if err := database.PostgresDB.Get(report, query, from, to); err != nil {
+		return nil, fmt.Errorf("failed to count searches: %w", err)
+	}
+
+	query = `SELECT term, fields, count(*) AS searches, count(DISTINCT user_id) AS users, max(search_time) AS last_searched_at
+	         FROM (SELECT ` + searchTermExpr + ` AS term, ` + searchFieldsExpr + ` AS fields, user_id, search_time
+	               FROM searches
+	               WHERE ` + analyticsSearchesCondition + ` AND result_count = 0 AND anonymized_at IS NULL
+	                 AND COALESCE(search_query->>'query', '') NOT LIKE 'WITHIN[%') zero_results
+	         WHERE term IS NOT NULL
+	         GROUP BY term, fields
+	         ORDER BY searches DESC, last_searched_at DESC
+	         LIMIT $3`
+	if err := database.PostgresDB.Select(&report.Queries, query, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to get zero result searches: %w", err)
+	}
+	for i := range report.Queries {
+		report.Queries[i].Fields = splitFieldList(report.Queries[i].FieldList)
+	}
+
+	return report, nil
+}
+
+func splitFieldList(list string) []string {
+	if list == "" {
+		return []string{}
+	}
+	return strings.Split(list, ",")
+}
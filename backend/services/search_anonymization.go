@@ -0,0 +1,104 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+)
+
+// anonymizeBatchSize is how many searches are rewritten per transaction
+const anonymizeBatchSize = 1000
+
+// ErrSearchAnonymized is returned when a search is reused after its searched values were dropped
+var ErrSearchAnonymized = errors.New("search is too old to reuse: its search values have been anonymized")
+
+// anonymizeRows rewrites the searches matching condition and older than cutoff so they keep only
+// structural metadata, counting them on the run
+func (s *RetentionService) anonymizeRows(run *models.RetentionPurge, cutoff time.Time, condition string) error {
+	for {
+		count, err := s.anonymizeBatch(cutoff, condition)
+		if err != nil {
+			return err
+		}
+		run.RowsAnonymized += int64(count)
+		if count < anonymizeBatchSize {
+			return nil
+		}
+	}
+}
+
+// anonymizeBatch rewrites the oldest searches not yet anonymized and returns how many it rewrote
+func (s *RetentionService) anonymizeBatch(cutoff time.Time, condition string) (int, error) {
+	tx, err := database.PostgresDB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start anonymization: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rows []struct {
+		ID          uuid.UUID `db:"id"`
+		SearchQuery []byte    `db:"search_query"`
+	}
+	// condition comes from retentionTargets, never from the policy
+	selectQuery := fmt.Sprintf(`SELECT id, search_query FROM searches
+					WHERE anonymized_at IS NULL AND search_time < $1 AND (%s)
+					ORDER BY search_time
+					LIMIT $2
+					FOR UPDATE SKIP LOCKED`, condition)
+	if err := tx.Select(&rows, selectQuery, cutoff, anonymizeBatchSize); err != nil {
+		return 0, fmt.Errorf("failed to load searches to anonymize: %w", err)
+	}
+
+	updateQuery := `UPDATE searches SET search_query = $2, anonymized_at = now() WHERE id = $1`
+	for _, row := range rows {
+		if _, err := tx.Exec(updateQuery, row.ID, anonymizeSearchQuery(row.SearchQuery)); err != nil {
+			return 0, fmt.Errorf("failed to anonymize search %s: %w", row.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit anonymization: %w", err)
+	}
+	return len(rows), nil
+}
+
+// anonymizeSearchQuery reduces a logged search to the fields and options it used. Queries that cannot
+// be parsed are still replaced, so no searched value survives.
+func anonymizeSearchQuery(raw []byte) []byte {
+	anonymized := models.AnonymizedSearchQuery{Anonymized: true, Fields: []string{}}
+
+	var req models.SearchRequest
+	if err := json.Unmarshal(raw, &req); err == nil {
+		if req.Fields != nil {
+			anonymized.Fields = req.Fields
+		}
+		for field, value := range req.FieldQueries {
+			if value != "" {
+				anonymized.FieldQueryFields = append(anonymized.FieldQueryFields, field)
+			}
+		}
+		sort.Strings(anonymized.FieldQueryFields)
+		anonymized.Logic = req.Logic
+		anonymized.MatchType = req.MatchType
+		anonymized.Limit = req.Limit
+		anonymized.Offset = req.Offset
+		anonymized.SearchWithin = req.SearchWithin || strings.HasPrefix(req.Query, "WITHIN[")
+		anonymized.EnhancedMobile = req.EnhancedMobile
+		anonymized.Facets = req.Facets
+		anonymized.SortBy = req.SortBy
+		anonymized.Quality = req.Quality
+		anonymized.Near = req.Near != nil
+		anonymized.Diagnostic = req.Diagnostic
+	}
+
+	data, _ := json.Marshal(anonymized)
+	return data
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"finone-search-system/config"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// SearchBackend executes people lookups for the search service. Quota checks, search logging,
+// duplicate detection and field masking stay in SearchService, so a backend only finds records.
+type SearchBackend interface {
+	// Search returns one page (req.Limit/req.Offset) of people matching a search request
+	Search(ctx context.Context, req *models.SearchRequest) ([]models.Person, error)
+	// Count returns the number of people matching a search request, ignoring pagination
+	Count(ctx context.Context, req *models.SearchRequest) (int, error)
+	// SearchWithCount returns one page of people matching a search request with the number of matches,
+	// counted by the same query where possible. Counts reaching exactLimit may be estimated, which the
+	// returned flag reports; 0 always counts exactly.
+	SearchWithCount(ctx context.Context, req *models.SearchRequest, exactLimit int) ([]models.Person, int, bool, error)
+	// Facets returns the top values of each facet in req.Facets among the matching people
+	Facets(ctx context.Context, req *models.SearchRequest) map[string][]models.FacetCount
+	// SearchWithin returns one page of people matching both a previous search and a refinement
+	SearchWithin(ctx context.Context, originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) ([]models.Person, error)
+	// CountWithin returns the number of people matching both a previous search and a refinement
+	CountWithin(ctx context.Context, originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) (int, error)
+	// FindKeyMasterIDs returns the master IDs of the direct matches of an enhanced search key (see
+	// models.EnhancedKeyMobile), each with the ID of its first direct match in enhanced match order
+	FindKeyMasterIDs(ctx context.Context, key models.EnhancedSearchKey) (map[string]string, error)
+	// CountEnhancedMatches returns the number of direct and master ID matches of an enhanced search hop
+	CountEnhancedMatches(ctx context.Context, hop EnhancedHop) (direct, byMasterID int, err error)
+	// FindEnhancedMatches returns one page of the direct matches of a hop followed by its master ID
+	// matches, in enhanced match order
+	FindEnhancedMatches(ctx context.Context, hop EnhancedHop, offset, limit int) ([]EnhancedMatch, error)
+	// FindEnhancedAltCarriers returns, in enhanced match order, up to limit matches of a hop with a
+	// mobile-length alt number, the first one of each number
+	FindEnhancedAltCarriers(ctx context.Context, hop EnhancedHop, limit int) ([]EnhancedMatch, error)
+	// FindByKey returns up to limit people matching an enhanced search key that are not matches of exclude
+	FindByKey(ctx context.Context, key models.EnhancedSearchKey, exclude EnhancedHop, limit int) ([]models.Person, error)
+	// FindByMasterIDs returns up to limit people with any of the master IDs that are not matches of exclude
+	FindByMasterIDs(ctx context.Context, masterIDs []string, exclude EnhancedHop, limit int) ([]models.Person, error)
+	// GetPerson returns a single person by ID
+	GetPerson(ctx context.Context, id string) (*models.Person, error)
+	// FindLinked returns up to limit people whose mobile or alt is one of numbers, whose master ID is one
+	// of masterIDs or whose email is one of emails (lower case), all matched exactly
+	FindLinked(ctx context.Context, numbers, masterIDs, emails []string, limit int) ([]models.Person, error)
+	// CountByPincode returns the number of people matching a search request per pincode
+	CountByPincode(ctx context.Context, req *models.SearchRequest) (map[string]uint64, error)
+	// CountAll returns the total number of people
+	CountAll(ctx context.Context) (uint64, error)
+}
+
+// EnhancedHop is the first hop of an enhanced search: the direct matches of Key, then the other people
+// with one of MasterIDs
+type EnhancedHop struct {
+	Key       models.EnhancedSearchKey
+	MasterIDs []string
+}
+
+// Classes of enhanced search matches, in the order they are paged
+const (
+	EnhancedMatchDirect   uint8 = 0
+	EnhancedMatchMasterID uint8 = 1
+)
+
+// EnhancedMatch is a person found by the first hop of an enhanced search. Matches are ordered by class,
+// then master ID matches by master ID, then by mobile, name and ID, so pages never overlap.
+type EnhancedMatch struct {
+	models.Person
+	MatchClass uint8 `ch:"match_class"`
+}
+
+const defaultSearchBackend = "clickhouse"
+
+var (
+	searchBackendsMu sync.RWMutex
+	searchBackends   = map[string]func() SearchBackend{
+		defaultSearchBackend: newClickHouseSearchBackend,
+	}
+)
+
+// RegisterSearchBackend makes a search backend selectable by name through search.backend
+func RegisterSearchBackend(name string, factory func() SearchBackend) {
+	searchBackendsMu.Lock()
+	defer searchBackendsMu.Unlock()
+	searchBackends[name] = factory
+}
+
+// newSearchBackend returns the configured search backend, falling back to ClickHouse when the
+// configured name is unknown
+func newSearchBackend() SearchBackend {
+	name := defaultSearchBackend
+	if config.Get() != nil && config.Get().Search.Backend != "" {
+		name = config.Get().Search.Backend
+	}
+
+	searchBackendsMu.RLock()
+	factory, ok := searchBackends[name]
+	if !ok {
+		factory = searchBackends[defaultSearchBackend]
+	}
+	searchBackendsMu.RUnlock()
+
+	if !ok {
+		utils.LogWarning(fmt.Sprintf("Unknown search backend %q, using %s", name, defaultSearchBackend))
+	}
+	return factory()
+}
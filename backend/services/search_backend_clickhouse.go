@@ -0,0 +1,479 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+const personColumns = "id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at, confidence, quality_flags"
+
+// provenanceColumns record which import a row came from; searches do not read them
+const provenanceColumns = "source_file, import_job_id, imported_at, mobile_raw, alt_raw"
+
+// clickHouseSearchBackend searches the finone_search.people table in ClickHouse (default backend), or
+// the dataset table carried by the query context. Its queries take their conditions from QueryBuilder.
+type clickHouseSearchBackend struct{}
+
+func newClickHouseSearchBackend() SearchBackend {
+	return &clickHouseSearchBackend{}
+}
+
+// Search returns one page of people matching a search request
+func (b *clickHouseSearchBackend) Search(ctx context.Context, req *models.SearchRequest) ([]models.Person, error) {
+	query, args := NewQueryBuilder().AddSearch(req).Select(database.PeopleTableFor(ctx), searchOrderBy(req), req.Limit, req.Offset)
+
+	utils.LogInfo(fmt.Sprintf("Executing search query: %s", query))
+
+	var results []models.Person
+	if err := database.ClickHouseDB.Select(ctx, &results, query, args...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Count gets the total count of matching records without pagination
+func (b *clickHouseSearchBackend) Count(ctx context.Context, req *models.SearchRequest) (int, error) {
+	countQuery, args := NewQueryBuilder().AddSearch(req).Count(database.PeopleTableFor(ctx))
+
+	var totalCount uint64
+	err := database.ClickHouseDB.QueryRow(ctx, countQuery, args...).Scan(&totalCount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	return int(totalCount), nil
+}
+
+// countSampleRate is the share of IDs, one in this many, whose matches estimate a very large count
+const countSampleRate = 100
+
+// countedPerson is a person row of a page query that also carries the number of matches
+type countedPerson struct {
+	models.Person
+	TotalCount uint64 `ch:"total_count"`
+}
+
+// SearchWithCount reads the page and the number of matches in one pass with count() OVER (). The window
+// covers at most exactLimit matches (or the page's end, if further), so a very large result set is not
+// buffered; when the window fills, the count is estimated from a sample of IDs instead.
+func (b *clickHouseSearchBackend) SearchWithCount(ctx context.Context, req *models.SearchRequest, exactLimit int) ([]models.Person, int, bool, error) {
+	table := database.PeopleTableFor(ctx)
+	countLimit := 0
+	if exactLimit > 0 && req.Limit > 0 {
+		countLimit = max(exactLimit, req.Offset+req.Limit) + 1
+	}
+	query, args := NewQueryBuilder().AddSearch(req).SelectWithCount(table, searchOrderBy(req), req.Limit, req.Offset, countLimit)
+
+	utils.LogInfo(fmt.Sprintf("Executing search query: %s", query))
+
+	var rows []countedPerson
+	if err := database.ClickHouseDB.Select(ctx, &rows, query, args...); err != nil {
+		return nil, 0, false, err
+	}
+	if len(rows) == 0 {
+		// A page past the last match carries no count
+		if req.Offset == 0 {
+			return nil, 0, false, nil
+		}
+		totalCount, err := b.Count(ctx, req)
+		return nil, totalCount, false, err
+	}
+
+	results := make([]models.Person, len(rows))
+	for i := range rows {
+		results[i] = rows[i].Person
+	}
+	totalCount := int(rows[0].TotalCount)
+	if countLimit == 0 || totalCount < countLimit {
+		return results, totalCount, false, nil
+	}
+
+	estimateQuery, estimateArgs := NewQueryBuilder().AddSearch(req).EstimateCount(table, countSampleRate)
+	var estimate uint64
+	if err := database.ClickHouseDB.QueryRow(ctx, estimateQuery, estimateArgs...).Scan(&estimate); err != nil {
+		utils.LogError("Failed to estimate search total count", err)
+		return results, totalCount, true, nil
+	}
+	return results, max(int(estimate), totalCount), true, nil
+}
+
+// facetColumns maps the supported facet names to the ClickHouse columns they group by
+var facetColumns = map[string]string{
+	"circle":  "circle",
+	"pincode": "pincode",
+	"city":    "city",
+	"state":   "state",
+}
+
+// Facets runs one GROUP BY count query per requested facet using the search's WHERE clause
+// and returns the top values for each. Failed or unknown facets are skipped.
+func (b *clickHouseSearchBackend) Facets(ctx context.Context, req *models.SearchRequest) map[string][]models.FacetCount {
+	limit := req.FacetLimit
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	whereClause, args := NewQueryBuilder().AddSearch(req).Where()
+
+	facets := make(map[string][]models.FacetCount)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, facet := range req.Facets {
+		column, ok := facetColumns[strings.ToLower(strings.TrimSpace(facet))]
+		if !ok {
+			utils.LogWarning(fmt.Sprintf("Ignoring unsupported facet: %s", facet))
+			continue
+		}
+
+		wg.Add(1)
+		go func(name, column string) {
+			defer wg.Done()
+
+			query := fmt.Sprintf(`SELECT %s AS value, count() AS count
+				FROM `+database.PeopleTableFor(ctx)+`
+				WHERE %s AND %s != ''
+				GROUP BY value
+				ORDER BY count DESC
+				LIMIT %d
+				SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1`,
+				column, whereClause, column, limit)
+
+			var counts []models.FacetCount
+			if err := database.ClickHouseDB.Select(ctx, &counts, query, args...); err != nil {
+				utils.LogError(fmt.Sprintf("Facet query failed for %s", name), err)
+				return
+			}
+
+			mu.Lock()
+			facets[name] = counts
+			mu.Unlock()
+		}(column, column)
+	}
+
+	wg.Wait()
+	return facets
+}
+
+// SearchWithin returns one page of people matching both a previous search and a refinement
+func (b *clickHouseSearchBackend) SearchWithin(ctx context.Context, originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) ([]models.Person, error) {
+	query, args := NewQueryBuilder().AddSearch(originalReq).AddWithin(withinReq).
+		Select(database.PeopleTableFor(ctx), searchOrderBy(originalReq), withinReq.Limit, withinReq.Offset)
+
+	utils.LogInfo(fmt.Sprintf("Executing search within query: %s", query))
+
+	var results []models.Person
+	if err := database.ClickHouseDB.Select(ctx, &results, query, args...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// CountWithin gets the total count for search within operations
+func (b *clickHouseSearchBackend) CountWithin(ctx context.Context, originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) (int, error) {
+	countQuery, args := NewQueryBuilder().AddSearch(originalReq).AddWithin(withinReq).Count(database.PeopleTableFor(ctx))
+
+	var totalCount uint64
+	err := database.ClickHouseDB.QueryRow(ctx, countQuery, args...).Scan(&totalCount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get search within total count: %w", err)
+	}
+
+	return int(totalCount), nil
+}
+
+// numberValues returns the stored forms of a number: the variants of a 10 digit number, or the number
+// alone when it is not one
+func numberValues(number string) []string {
+	if variants := utils.MobileVariants(number); variants != nil {
+		return variants
+	}
+	return []string{number}
+}
+
+// keyCondition returns the condition matching an enhanced search key, with its arguments. A mobile key
+// matches the mobile or alt number and an alt key the alt number, in any of the number's stored forms.
+func keyCondition(key models.EnhancedSearchKey) (string, []interface{}) {
+	switch key.Type {
+	case models.EnhancedKeyAlt:
+		return inCondition("alt", numberValues(key.Value))
+	case models.EnhancedKeyEmail:
+		return "lower(trimBoth(email)) = ? OR " + utils.NormalizedEmailSQL() + " = ?",
+			[]interface{}{key.Value, utils.NormalizeEmail(key.Value)}
+	default:
+		mobileCond, args := inCondition("mobile", numberValues(key.Value))
+		altCond, altArgs := inCondition("alt", numberValues(key.Value))
+		return mobileCond + " OR " + altCond, append(args, altArgs...)
+	}
+}
+
+// inCondition returns "column IN (?, ...)" for the values, with its arguments
+func inCondition(column string, values []string) (string, []interface{}) {
+	placeholders := make([]string, len(values))
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		placeholders[i] = "?"
+		args[i] = value
+	}
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ",")), args
+}
+
+// hopConditions returns the conditions of the direct and master ID matches of an enhanced search hop,
+// with their arguments. The master ID condition is empty when the hop has no master IDs.
+func hopConditions(hop EnhancedHop) (string, []interface{}, string, []interface{}) {
+	keyCond, keyArgs := keyCondition(hop.Key)
+	direct := "(" + keyCond + ")"
+	if len(hop.MasterIDs) == 0 {
+		return direct, keyArgs, "", nil
+	}
+	inCond, inArgs := inCondition("master_id", hop.MasterIDs)
+	return direct, keyArgs, inCond + " AND NOT " + direct, append(inArgs, keyArgs...)
+}
+
+// hopExclusion returns the condition excluding the matches of an enhanced search hop
+func hopExclusion(hop EnhancedHop) (string, []interface{}) {
+	direct, args, byMasterID, masterIDArgs := hopConditions(hop)
+	if byMasterID == "" {
+		return "NOT " + direct, args
+	}
+	return "NOT " + direct + " AND NOT (" + byMasterID + ")", append(args, masterIDArgs...)
+}
+
+// enhancedMatchOrder pages direct matches before master ID matches; within each class the order is
+// total, so pages are stable
+const enhancedMatchOrder = "match_class, if(match_class = 0, '', master_id), mobile, name, id"
+
+// enhancedMatchesQuery returns a query selecting the direct and master ID matches of a hop, each with its
+// match_class, as one UNION ALL
+func enhancedMatchesQuery(ctx context.Context, hop EnhancedHop) (string, []interface{}) {
+	table := database.PeopleTableFor(ctx)
+	direct, args, byMasterID, masterIDArgs := hopConditions(hop)
+	query := fmt.Sprintf("SELECT %s, toUInt8(%d) AS match_class FROM %s WHERE %s",
+		personColumns, EnhancedMatchDirect, table, direct)
+	if byMasterID != "" {
+		query += fmt.Sprintf(" UNION ALL SELECT %s, toUInt8(%d) AS match_class FROM %s WHERE %s",
+			personColumns, EnhancedMatchMasterID, table, byMasterID)
+		args = append(args, masterIDArgs...)
+	}
+	return query, args
+}
+
+// FindKeyMasterIDs returns the master IDs of the records matching an enhanced search key: by default
+// those whose mobile or alt number is the number in any of its stored forms
+func (b *clickHouseSearchBackend) FindKeyMasterIDs(ctx context.Context, key models.EnhancedSearchKey) (map[string]string, error) {
+	condition, args := keyCondition(key)
+	query := `
+		SELECT master_id, argMin(id, (mobile, name, id)) AS first_id
+		FROM ` + database.PeopleTableFor(ctx) + `
+		WHERE (` + condition + `) AND master_id != ''
+		GROUP BY master_id
+		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
+	`
+
+	rows, err := database.ClickHouseDB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	firstByMasterID := make(map[string]string)
+	for rows.Next() {
+		var masterID, firstID string
+		if err := rows.Scan(&masterID, &firstID); err != nil {
+			return nil, err
+		}
+		firstByMasterID[masterID] = firstID
+	}
+	return firstByMasterID, rows.Err()
+}
+
+// CountEnhancedMatches counts both classes of matches of a hop in one pass over the table
+func (b *clickHouseSearchBackend) CountEnhancedMatches(ctx context.Context, hop EnhancedHop) (int, int, error) {
+	direct, args, byMasterID, masterIDArgs := hopConditions(hop)
+	where, whereArgs := direct, args
+	if byMasterID == "" {
+		byMasterID = "0"
+	} else {
+		inCond, inArgs := inCondition("master_id", hop.MasterIDs)
+		where += " OR " + inCond
+		whereArgs = append(append([]interface{}{}, args...), inArgs...)
+	}
+	query := `
+		SELECT countIf(` + direct + `), countIf(` + byMasterID + `)
+		FROM ` + database.PeopleTableFor(ctx) + `
+		WHERE ` + where + `
+		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
+	`
+	countArgs := append(append(append([]interface{}{}, args...), masterIDArgs...), whereArgs...)
+
+	var directCount, masterIDCount uint64
+	if err := database.ClickHouseDB.QueryRow(ctx, query, countArgs...).Scan(&directCount, &masterIDCount); err != nil {
+		return 0, 0, fmt.Errorf("failed to count enhanced matches: %w", err)
+	}
+	return int(directCount), int(masterIDCount), nil
+}
+
+// FindEnhancedMatches pages the UNION of both classes of matches, so only one page is read back
+func (b *clickHouseSearchBackend) FindEnhancedMatches(ctx context.Context, hop EnhancedHop, offset, limit int) ([]EnhancedMatch, error) {
+	matches, args := enhancedMatchesQuery(ctx, hop)
+	query := `
+		SELECT * FROM (` + matches + `)
+		ORDER BY ` + enhancedMatchOrder + `
+		LIMIT ? OFFSET ?
+		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
+	`
+	args = append(args, limit, offset)
+
+	var page []EnhancedMatch
+	if err := database.ClickHouseDB.Select(ctx, &page, query, args...); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// FindEnhancedAltCarriers returns the first match of a hop carrying each alt number of 10-12 digits
+func (b *clickHouseSearchBackend) FindEnhancedAltCarriers(ctx context.Context, hop EnhancedHop, limit int) ([]EnhancedMatch, error) {
+	matches, args := enhancedMatchesQuery(ctx, hop)
+	query := `
+		SELECT * FROM (` + matches + `)
+		WHERE length(replaceRegexpAll(alt, '\\D', '')) BETWEEN 10 AND 12
+		ORDER BY ` + enhancedMatchOrder + `
+		LIMIT 1 BY replaceRegexpAll(alt, '\\D', '')
+		LIMIT ?
+		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
+	`
+	args = append(args, limit)
+
+	var carriers []EnhancedMatch
+	if err := database.ClickHouseDB.Select(ctx, &carriers, query, args...); err != nil {
+		return nil, err
+	}
+	return carriers, nil
+}
+
+// FindByKey returns up to limit records matching an enhanced search key outside a hop
+func (b *clickHouseSearchBackend) FindByKey(ctx context.Context, key models.EnhancedSearchKey, exclude EnhancedHop, limit int) ([]models.Person, error) {
+	condition, args := keyCondition(key)
+	excludeCondition, excludeArgs := hopExclusion(exclude)
+	query := `
+		SELECT ` + personColumns + `
+		FROM ` + database.PeopleTableFor(ctx) + `
+		WHERE (` + condition + `) AND ` + excludeCondition + `
+		ORDER BY mobile, name, id
+		LIMIT ?
+		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
+	`
+	args = append(append(args, excludeArgs...), limit)
+
+	var people []models.Person
+	if err := database.ClickHouseDB.Select(ctx, &people, query, args...); err != nil {
+		return nil, err
+	}
+	return people, nil
+}
+
+// FindByMasterIDs returns up to limit records with one of the master IDs outside a hop
+func (b *clickHouseSearchBackend) FindByMasterIDs(ctx context.Context, masterIDs []string, exclude EnhancedHop, limit int) ([]models.Person, error) {
+	condition, args := inCondition("master_id", masterIDs)
+	excludeCondition, excludeArgs := hopExclusion(exclude)
+	query := `
+		SELECT ` + personColumns + `
+		FROM ` + database.PeopleTableFor(ctx) + `
+		WHERE ` + condition + ` AND ` + excludeCondition + `
+		ORDER BY master_id, mobile, name, id
+		LIMIT ?
+		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
+	`
+	args = append(append(args, excludeArgs...), limit)
+
+	var people []models.Person
+	if err := database.ClickHouseDB.Select(ctx, &people, query, args...); err != nil {
+		return nil, err
+	}
+	return people, nil
+}
+
+// FindLinked returns people sharing a number, master ID or email with the given values
+func (b *clickHouseSearchBackend) FindLinked(ctx context.Context, numbers, masterIDs, emails []string, limit int) ([]models.Person, error) {
+	var conditions []string
+	var args []interface{}
+	in := func(column string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			placeholders[i] = "?"
+			args = append(args, value)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ",")))
+	}
+	in("mobile", numbers)
+	in("alt", numbers)
+	in("master_id", masterIDs)
+	in("lower(email)", emails)
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	query := `
+		SELECT ` + personColumns + `
+		FROM ` + database.PeopleTableFor(ctx) + `
+		WHERE ` + strings.Join(conditions, " OR ") + `
+		ORDER BY id
+		LIMIT ?
+		SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1
+	`
+	args = append(args, limit)
+
+	var people []models.Person
+	if err := database.ClickHouseDB.Select(ctx, &people, query, args...); err != nil {
+		return nil, err
+	}
+	return people, nil
+}
+
+// GetPerson retrieves a person by ID
+func (b *clickHouseSearchBackend) GetPerson(ctx context.Context, id string) (*models.Person, error) {
+	var person models.Person
+	query := `SELECT ` + personColumns + ` FROM ` + database.PeopleTableFor(ctx) + ` WHERE id = ?`
+
+	if err := database.ClickHouseDB.QueryRow(ctx, query, id).ScanStruct(&person); err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// CountByPincode gets the number of matching records per pincode
+func (b *clickHouseSearchBackend) CountByPincode(ctx context.Context, req *models.SearchRequest) (map[string]uint64, error) {
+	whereClause, args := NewQueryBuilder().AddSearch(req).Where()
+	query := "SELECT pincode AS value, count() AS count FROM " + database.PeopleTableFor(ctx) + " WHERE " + whereClause +
+		" GROUP BY value SETTINGS optimize_move_to_prewhere=1, allow_experimental_analyzer=1"
+
+	var rows []models.FacetCount
+	if err := database.ClickHouseDB.Select(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to count by pincode: %w", err)
+	}
+
+	counts := make(map[string]uint64, len(rows))
+	for _, row := range rows {
+		counts[row.Value] = row.Count
+	}
+	return counts, nil
+}
+
+// CountAll returns the total number of records
+func (b *clickHouseSearchBackend) CountAll(ctx context.Context) (uint64, error) {
+	var total uint64
+	err := database.ClickHouseDB.QueryRow(ctx, `SELECT count() FROM `+database.PeopleTableFor(ctx)).Scan(&total)
+	return total, err
+}
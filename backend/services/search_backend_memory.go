@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"finone-search-system/models"
+	"finone-search-system/utils"
+)
+
+// MemorySearchBackend searches an in-memory slice of people. It follows the ClickHouse backend's
+// matching rules closely enough for tests and small demo deployments, e.g.
+//
+//	services.RegisterSearchBackend("memory", func() services.SearchBackend {
+//		return services.NewMemorySearchBackend(people)
+//	})
+type MemorySearchBackend struct {
+	mu     sync.RWMutex
+	people []models.Person
+}
+
+func NewMemorySearchBackend(people []models.Person) *MemorySearchBackend {
+	return &MemorySearchBackend{people: people}
+}
+
+// Add stores more people in the backend
+func (b *MemorySearchBackend) Add(people ...models.Person) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.people = append(b.people, people...)
+}
+
+func (b *MemorySearchBackend) Search(ctx context.Context, req *models.SearchRequest) ([]models.Person, error) {
+	matches := b.filter(func(p *models.Person) bool { return matchesSearch(p, req) })
+	return paginatePeople(sortPeople(matches, req), req.Limit, req.Offset), nil
+}
+
+func (b *MemorySearchBackend) Count(ctx context.Context, req *models.SearchRequest) (int, error) {
+	return len(b.filter(func(p *models.Person) bool { return matchesSearch(p, req) })), nil
+}
+
+func (b *MemorySearchBackend) Facets(ctx context.Context, req *models.SearchRequest) map[string][]models.FacetCount {
+	limit := req.FacetLimit
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	matches := b.filter(func(p *models.Person) bool { return matchesSearch(p, req) })
+	facets := make(map[string][]models.FacetCount)
+	for _, facet := range req.Facets {
+		name := strings.ToLower(strings.TrimSpace(facet))
+		if name != "circle" && name != "pincode" {
+			continue
+		}
+
+		counts := map[string]uint64{}
+		for i := range matches {
+			if value := personField(&matches[i], name); value != "" {
+				counts[value]++
+			}
+		}
+
+		values := make([]models.FacetCount, 0, len(counts))
+		for value, count := range counts {
+			values = append(values, models.FacetCount{Value: value, Count: count})
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if values[i].Count != values[j].Count {
+				return values[i].Count > values[j].Count
+			}
+			return values[i].Value < values[j].Value
+		})
+		if len(values) > limit {
+			values = values[:limit]
+		}
+		facets[name] = values
+	}
+	return facets
+}
+
+// SearchWithCount always counts exactly; the memory backend holds few enough people
+func (b *MemorySearchBackend) SearchWithCount(ctx context.Context, req *models.SearchRequest, exactLimit int) ([]models.Person, int, bool, error) {
+	results, err := b.Search(ctx, req)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	totalCount, err := b.Count(ctx, req)
+	return results, totalCount, false, err
+}
+
+func (b *MemorySearchBackend) SearchWithin(ctx context.Context, originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) ([]models.Person, error) {
+	matches := b.filter(func(p *models.Person) bool { return matchesSearchWithin(p, originalReq, withinReq) })
+	return paginatePeople(sortPeople(matches, originalReq), withinReq.Limit, withinReq.Offset), nil
+}
+
+func (b *MemorySearchBackend) CountWithin(ctx context.Context, originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) (int, error) {
+	return len(b.filter(func(p *models.Person) bool { return matchesSearchWithin(p, originalReq, withinReq) })), nil
+}
+
+func (b *MemorySearchBackend) FindKeyMasterIDs(ctx context.Context, key models.EnhancedSearchKey) (map[string]string, error) {
+	firstByMasterID := make(map[string]string)
+	for _, match := range b.enhancedMatches(EnhancedHop{Key: key}) {
+		if _, ok := firstByMasterID[match.MasterID]; !ok && match.MasterID != "" {
+			firstByMasterID[match.MasterID] = match.ID
+		}
+	}
+	return firstByMasterID, nil
+}
+
+func (b *MemorySearchBackend) CountEnhancedMatches(ctx context.Context, hop EnhancedHop) (int, int, error) {
+	direct, byMasterID := 0, 0
+	for _, match := range b.enhancedMatches(hop) {
+		if match.MatchClass == EnhancedMatchDirect {
+			direct++
+		} else {
+			byMasterID++
+		}
+	}
+	return direct, byMasterID, nil
+}
+
+func (b *MemorySearchBackend) FindEnhancedMatches(ctx context.Context, hop EnhancedHop, offset, limit int) ([]EnhancedMatch, error) {
+	matches := b.enhancedMatches(hop)
+	if offset >= len(matches) {
+		return nil, nil
+	}
+	return matches[offset:min(offset+limit, len(matches))], nil
+}
+
+func (b *MemorySearchBackend) FindEnhancedAltCarriers(ctx context.Context, hop EnhancedHop, limit int) ([]EnhancedMatch, error) {
+	var carriers []EnhancedMatch
+	seen := make(map[string]bool)
+	for _, match := range b.enhancedMatches(hop) {
+		alt := nonDigits.ReplaceAllString(match.Alt, "")
+		if len(carriers) == limit {
+			break
+		}
+		if len(alt) < 10 || len(alt) > 12 || seen[alt] {
+			continue
+		}
+		seen[alt] = true
+		carriers = append(carriers, match)
+	}
+	return carriers, nil
+}
+
+func (b *MemorySearchBackend) FindByKey(ctx context.Context, key models.EnhancedSearchKey, exclude EnhancedHop, limit int) ([]models.Person, error) {
+	matches := b.filter(func(p *models.Person) bool { return matchesKey(p, key) && !matchesHop(p, exclude) })
+	sortEnhancedPeople(matches, false)
+	return paginatePeople(matches, limit, 0), nil
+}
+
+func (b *MemorySearchBackend) FindByMasterIDs(ctx context.Context, masterIDs []string, exclude EnhancedHop, limit int) ([]models.Person, error) {
+	matches := b.filter(func(p *models.Person) bool {
+		return slices.Contains(masterIDs, p.MasterID) && !matchesHop(p, exclude)
+	})
+	sortEnhancedPeople(matches, true)
+	return paginatePeople(matches, limit, 0), nil
+}
+
+// enhancedMatches returns all matches of a hop in the order the ClickHouse backend pages them
+func (b *MemorySearchBackend) enhancedMatches(hop EnhancedHop) []EnhancedMatch {
+	direct := b.filter(func(p *models.Person) bool { return matchesKey(p, hop.Key) })
+	byMasterID := b.filter(func(p *models.Person) bool {
+		return slices.Contains(hop.MasterIDs, p.MasterID) && !matchesKey(p, hop.Key)
+	})
+	sortEnhancedPeople(direct, false)
+	sortEnhancedPeople(byMasterID, true)
+
+	matches := make([]EnhancedMatch, 0, len(direct)+len(byMasterID))
+	for _, person := range direct {
+		matches = append(matches, EnhancedMatch{Person: person, MatchClass: EnhancedMatchDirect})
+	}
+	for _, person := range byMasterID {
+		matches = append(matches, EnhancedMatch{Person: person, MatchClass: EnhancedMatchMasterID})
+	}
+	return matches
+}
+
+func (b *MemorySearchBackend) FindLinked(ctx context.Context, numbers, masterIDs, emails []string, limit int) ([]models.Person, error) {
+	matches := b.filter(func(p *models.Person) bool {
+		return (p.Mobile != "" && slices.Contains(numbers, p.Mobile)) || (p.Alt != "" && slices.Contains(numbers, p.Alt)) ||
+			(p.MasterID != "" && slices.Contains(masterIDs, p.MasterID)) || (p.Email != "" && slices.Contains(emails, strings.ToLower(p.Email)))
+	})
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+func (b *MemorySearchBackend) GetPerson(ctx context.Context, id string) (*models.Person, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for i := range b.people {
+		if b.people[i].ID == id {
+			person := b.people[i]
+			return &person, nil
+		}
+	}
+	return nil, fmt.Errorf("no person with ID %s", id)
+}
+
+func (b *MemorySearchBackend) CountByPincode(ctx context.Context, req *models.SearchRequest) (map[string]uint64, error) {
+	counts := make(map[string]uint64)
+	matches := b.filter(func(p *models.Person) bool { return matchesSearch(p, req) })
+	for i := range matches {
+		counts[personField(&matches[i], "pincode")]++
+	}
+	return counts, nil
+}
+
+func (b *MemorySearchBackend) CountAll(ctx context.Context) (uint64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return uint64(len(b.people)), nil
+}
+
+// filter returns copies of the matching people ordered by mobile and name, like the ClickHouse queries
+func (b *MemorySearchBackend) filter(match func(p *models.Person) bool) []models.Person {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var matches []models.Person
+	for i := range b.people {
+		if match(&b.people[i]) {
+			matches = append(matches, b.people[i])
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Mobile != matches[j].Mobile {
+			return matches[i].Mobile < matches[j].Mobile
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+// sortPeople applies the request's sort order to people already ordered by mobile and name
+func sortPeople(people []models.Person, req *models.SearchRequest) []models.Person {
+	if req.SortBy == "confidence" {
+		sort.SliceStable(people, func(i, j int) bool { return people[i].Confidence > people[j].Confidence })
+	}
+	return people
+}
+
+// searchableFields are the columns searched when a request names no fields
+var searchableFields = []string{"mobile", "name", "fname", "address", "alt", "circle", "email", "master_id"}
+
+// matchesSearch applies a search request's field queries, fields and logic to a person
+func matchesSearch(p *models.Person, req *models.SearchRequest) bool {
+	if !utils.MatchesQualityFilter(p.QualityFlags, req.Quality) || !matchesNearby(personField(p, "pincode"), req) {
+		return false
+	}
+
+	var results []bool
+	if len(req.FieldQueries) > 0 {
+		for field, value := range req.FieldQueries {
+			value = strings.TrimSpace(value)
+			if value == "" || !isSearchableField(field) {
+				continue
+			}
+			results = append(results, matchesField(p, field, value, req.MatchType))
+		}
+	} else {
+		for _, field := range req.Fields {
+			if isSearchableField(field) {
+				results = append(results, matchesField(p, field, req.Query, req.MatchType))
+			}
+		}
+	}
+
+	// Default search across all fields if no specific fields provided
+	if len(results) == 0 {
+		return matchesAnyField(p, searchableFields, req.Query, req.MatchType)
+	}
+
+	if req.Logic == "AND" {
+		for _, ok := range results {
+			if !ok {
+				return false
+			}
+		}
+		return true
+	}
+	for _, ok := range results {
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesSearchWithin requires a person to match the original search and any refinement field
+func matchesSearchWithin(p *models.Person, originalReq *models.SearchRequest, withinReq *models.SearchWithinRequest) bool {
+	fields := withinReq.Fields
+	if len(fields) == 0 {
+		fields = searchableFields
+	}
+	return matchesSearch(p, originalReq) && matchesAnyField(p, fields, withinReq.Query, withinReq.MatchType)
+}
+
+func matchesAnyField(p *models.Person, fields []string, query, matchType string) bool {
+	for _, field := range fields {
+		if isSearchableField(field) && matchesField(p, field, query, matchType) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesField matches a person's field like fieldCondition, including the aliases of a circle
+func matchesField(p *models.Person, field, query, matchType string) bool {
+	if matchesValue(personField(p, field), query, matchType) {
+		return true
+	}
+	if (field == "mobile" || field == "alt") && utils.MobileVariants(query) != nil {
+		return slices.Contains(utils.MobileVariants(query), personField(p, field))
+	}
+	if field == "circle" && p.Circle != "" {
+		return slices.Contains(circleMap().Variants(query), utils.NormalizeCircleKey(p.Circle))
+	}
+	return false
+}
+
+// matchesValue compares case-insensitively: equality for full matches, substring otherwise
+func matchesValue(value, query, matchType string) bool {
+	if matchType == "full" {
+		return strings.EqualFold(value, query)
+	}
+	return strings.Contains(strings.ToLower(value), strings.ToLower(query))
+}
+
+// matchesHop reports whether a person is a direct or master ID match of an enhanced search hop
+func matchesHop(p *models.Person, hop EnhancedHop) bool {
+	return matchesKey(p, hop.Key) || slices.Contains(hop.MasterIDs, p.MasterID)
+}
+
+// sortEnhancedPeople sorts people by mobile, name and ID, after master ID when byMasterID is set
+func sortEnhancedPeople(people []models.Person, byMasterID bool) {
+	sort.SliceStable(people, func(i, j int) bool {
+		a, b := people[i], people[j]
+		if byMasterID && a.MasterID != b.MasterID {
+			return a.MasterID < b.MasterID
+		}
+		if a.Mobile != b.Mobile {
+			return a.Mobile < b.Mobile
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+}
+
+func matchesKey(p *models.Person, key models.EnhancedSearchKey) bool {
+	numbers := []string{p.Mobile, p.Alt}
+	switch key.Type {
+	case models.EnhancedKeyEmail:
+		email := strings.ToLower(strings.TrimSpace(p.Email))
+		return email != "" && (email == key.Value || utils.NormalizeEmail(email) == utils.NormalizeEmail(key.Value))
+	case models.EnhancedKeyAlt:
+		numbers = numbers[1:]
+	}
+	for _, number := range numbers {
+		if number != "" && slices.Contains(numberValues(key.Value), number) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSearchableField(field string) bool {
+	switch field {
+	case "pincode", "locality", "city", "state":
+		return true
+	}
+	for _, searchable := range searchableFields {
+		if field == searchable {
+			return true
+		}
+	}
+	return false
+}
+
+// personField returns a searchable field of a person; pincode is extracted from the address. The memory
+// backend has no pincode directory, so locality, city and state are always empty.
+func personField(p *models.Person, field string) string {
+	switch field {
+	case "mobile":
+		return p.Mobile
+	case "alt":
+		return p.Alt
+	case "master_id":
+		return p.MasterID
+	case "name":
+		return p.Name
+	case "fname":
+		return p.FName
+	case "address":
+		return p.Address
+	case "email":
+		return p.Email
+	case "circle":
+		return p.Circle
+	case "pincode":
+		if match := pincodePattern.FindStringSubmatch(p.Address); match != nil {
+			return match[2]
+		}
+	}
+	return ""
+}
+
+func paginatePeople(people []models.Person, limit, offset int) []models.Person {
+	if offset >= len(people) {
+		return []models.Person{}
+	}
+	people = people[offset:]
+	if limit > 0 && limit < len(people) {
+		people = people[:limit]
+	}
+	return people
+}
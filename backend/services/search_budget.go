@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// defaultSearchTimeout bounds searches when no budget is configured
+const defaultSearchTimeout = 30 * time.Second
+
+// searchTimeoutGrace keeps a search's context open a little past its ClickHouse time limit, so the
+// server reports the limit and the search log and quota writes still run
+const searchTimeoutGrace = 5 * time.Second
+
+// searchBudget returns the query budget of a user's searches: search.budgets for their user type, else
+// the default budget
+func searchBudget(userID uuid.UUID) database.QueryBudget {
+	cfg := config.Get()
+	if cfg == nil {
+		return database.QueryBudget{Timeout: defaultSearchTimeout}
+	}
+
+	var userType string
+	if err := database.PostgresDB.Get(&userType, `SELECT COALESCE(user_type, '') FROM users WHERE id = $1`, userID); err != nil {
+		utils.LogError("Failed to get user type for search budget", err)
+	}
+	budget, ok := cfg.Search.Budgets[userType]
+	if !ok {
+		budget = cfg.Search.Budgets["default"]
+	}
+	if budget.Timeout <= 0 {
+		budget.Timeout = defaultSearchTimeout
+	}
+	return database.QueryBudget{
+		Timeout:        budget.Timeout,
+		MaxRowsToRead:  budget.MaxRowsToRead,
+		MaxResultBytes: budget.MaxResultBytes,
+	}
+}
+
+// searchContext returns the context of a user's search: its ClickHouse queries carry the user's query
+// budget and it ends shortly after the budget's timeout
+func searchContext(parent context.Context, userID uuid.UUID) (context.Context, context.CancelFunc) {
+	budget := searchBudget(userID)
+	ctx, cancel := context.WithTimeout(parent, budget.Timeout+searchTimeoutGrace)
+	return database.WithQueryBudget(ctx, budget), cancel
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the correlation ID of an API call; clients may send their own, otherwise
+// one is generated. It is echoed in every response and stored with each search.
+const RequestIDHeader = "X-Request-Id"
+
+// SearchIDHeader returns the search ID of a search response, matching search_id in the body
+const SearchIDHeader = "X-Search-Id"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// ErrSearchNotFound is returned when a search ID is not in the search log
+var ErrSearchNotFound = errors.New("search not found")
+
+// NormalizeRequestID returns the client's request ID when it is usable, or a new one
+func NormalizeRequestID(raw string) string {
+	requestID := strings.TrimSpace(raw)
+	if !requestIDPattern.MatchString(requestID) {
+		return uuid.New().String()
+	}
+	return requestID
+}
+
+// searchCorrelationColumns are the searches columns read into models.CorrelatedSearch
+const searchCorrelationColumns = `s.id, s.user_id, u.email AS user_email, s.search_query, s.search_time, s.result_count,
+	s.execution_time_ms, s.client_id, s.request_id, s.is_diagnostic, s.anonymized_at`
+
+// SearchCorrelationService looks up everything recorded about a search across PostgreSQL and ClickHouse
+type SearchCorrelationService struct{}
+
+func NewSearchCorrelationService() *SearchCorrelationService {
+	return &SearchCorrelationService{}
+}
+
+// GetSearchCorrelation returns the search log row for a search ID together with its request's other
+// searches, its performance log rows, searches within it and its exports
+func (s *SearchCorrelationService) GetSearchCorrelation(searchID string) (*models.SearchCorrelation, error) {
+	id, err := uuid.Parse(searchID)
+	if err != nil {
+		return nil, ErrSearchNotFound
+	}
+
+	correlation := &models.SearchCorrelation{
+		SearchID:        id.String(),
+		Performance:     []models.SearchPerformanceEntry{},
+		RelatedSearches: []models.CorrelatedSearch{},
+		Refinements:     []models.CorrelatedSearch{},
+		Exports:         []models.Export{},
+	}
+
+	query := `SELECT ` + searchCorrelationColumns + ` FROM searches s JOIN users u ON u.id = s.user_id WHERE s.id = $1`
+	if err := database.PostgresDB.Get(&correlation.Search, query, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSearchNotFound
+		}
+		return nil, fmt.Errorf("failed to get search: %w", err)
+	}
+	if correlation.Search.RequestID != nil {
+		correlation.RequestID = *correlation.Search.RequestID
+
+		query = `SELECT ` + searchCorrelationColumns + ` FROM searches s JOIN users u ON u.id = s.user_id
+				 WHERE s.request_id = $1 AND s.id != $2
+				 ORDER BY s.search_time`
+		if err := database.PostgresDB.Select(&correlation.RelatedSearches, query, correlation.RequestID, id); err != nil {
+			return nil, fmt.Errorf("failed to get related searches: %w", err)
+		}
+	}
+
+	query = `SELECT ` + searchCorrelationColumns + ` FROM searches s JOIN users u ON u.id = s.user_id
+			 WHERE s.user_id = $1 AND s.search_time >= $2 AND s.search_query ->> 'query' LIKE $3
+			 ORDER BY s.search_time
+			 LIMIT 100`
+	withinPrefix := "WITHIN[" + id.String() + "]%"
+	if err := database.PostgresDB.Select(&correlation.Refinements, query, correlation.Search.UserID, correlation.Search.SearchTime, withinPrefix); err != nil {
+		return nil, fmt.Errorf("failed to get searches within: %w", err)
+	}
+
+	query = `SELECT * FROM exports WHERE search_id = $1 ORDER BY exported_at`
+	if err := database.PostgresDB.Select(&correlation.Exports, query, id); err != nil {
+		return nil, fmt.Errorf("failed to get exports: %w", err)
+	}
+
+	correlation.Performance = s.performanceEntries(id.String(), correlation.RequestID, correlation.Search.SearchTime)
+	return correlation, nil
+}
+
+// performanceEntries returns the search_performance rows of a search or its request. ClickHouse being
+// unavailable or the rows having expired leaves the list empty rather than failing the lookup.
+func (s *SearchCorrelationService) performanceEntries(searchID, requestID string, searchTime time.Time) []models.SearchPerformanceEntry {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The table is ordered by timestamp, so bound the scan to a day either side of the search. The
+	// margin is wide because searches.search_time and the ClickHouse server may use different time zones.
+	query := `SELECT query_id, request_id, query_text, execution_time_ms, result_count, timestamp
+			  FROM finone_search.search_performance
+			  WHERE timestamp BETWEEN ? AND ?
+			    AND (query_id = ? OR (? != '' AND request_id = ?))
+			  ORDER BY timestamp`
+	entries := []models.SearchPerformanceEntry{}
+	err := database.ClickHouseDB.Select(ctx, &entries, query,
+		searchTime.Add(-24*time.Hour), searchTime.Add(24*time.Hour), searchID, requestID, requestID)
+	if err != nil {
+		utils.LogError("Failed to get search performance entries", err)
+		return []models.SearchPerformanceEntry{}
+	}
+	return entries
+}
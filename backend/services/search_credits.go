@@ -0,0 +1,96 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// maxSearchCreditsPerGrant caps a single grant so a typo cannot hand out an unlimited quota
+const maxSearchCreditsPerGrant = 100000
+
+type SearchCreditService struct{}
+
+func NewSearchCreditService() *SearchCreditService {
+	return &SearchCreditService{}
+}
+
+// GrantSearchCredits gives a user extra searches for the current quota day without changing their
+// max_searches_per_day. Unused credits expire with the quota day.
+func (s *SearchCreditService) GrantSearchCredits(userID, grantedBy uuid.UUID, req *models.GrantSearchCreditsRequest) (*models.SearchCredit, error) {
+	if req.Credits < 1 || req.Credits > maxSearchCreditsPerGrant {
+		return nil, fmt.Errorf("credits must be between 1 and %d", maxSearchCreditsPerGrant)
+	}
+
+	var reason *string
+	if trimmed := strings.TrimSpace(req.Reason); trimmed != "" {
+		reason = &trimmed
+	}
+
+	var credit models.SearchCredit
+	query := `INSERT INTO search_credits (user_id, date, credits, reason, granted_by)
+	          VALUES ($1, $2, $3, $4, $5)
+	          RETURNING *`
+	if err := database.PostgresDB.Get(&credit, query, userID, CurrentQuotaDate(), req.Credits, reason, grantedBy); err != nil {
+		return nil, fmt.Errorf("failed to grant search credits: %w", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Granted %d search credits to user %s for %s", req.Credits, userID, CurrentQuotaDate()))
+	return &credit, nil
+}
+
+// GetTodaySearchCredits returns the total search credits granted to a user for the current quota day
+func (s *SearchCreditService) GetTodaySearchCredits(userID uuid.UUID) (int, error) {
+	var credits int
+	query := `SELECT COALESCE(SUM(credits), 0) FROM search_credits WHERE user_id = $1 AND date = $2`
+	if err := database.PostgresDB.Get(&credits, query, userID, CurrentQuotaDate()); err != nil {
+		return 0, fmt.Errorf("failed to get search credits: %w", err)
+	}
+	return credits, nil
+}
+
+// GetSearchCredits returns a user's credit grants and consumption for the current quota day
+func (s *SearchCreditService) GetSearchCredits(userID uuid.UUID) (*models.SearchCreditsResponse, error) {
+	today := CurrentQuotaDate()
+
+	var maxSearches int
+	if err := database.PostgresDB.Get(&maxSearches, `SELECT max_searches_per_day FROM users WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	grants := []models.SearchCredit{}
+	query := `SELECT * FROM search_credits WHERE user_id = $1 AND date = $2 ORDER BY created_at`
+	if err := database.PostgresDB.Select(&grants, query, userID, today); err != nil {
+		return nil, fmt.Errorf("failed to get search credits: %w", err)
+	}
+
+	var used int
+	usedQuery := `SELECT COALESCE(MAX(credits_used), 0) FROM daily_usage WHERE user_id = $1 AND date = $2`
+	if err := database.PostgresDB.Get(&used, usedQuery, userID, today); err != nil {
+		return nil, fmt.Errorf("failed to get search credit usage: %w", err)
+	}
+
+	granted := 0
+	for _, grant := range grants {
+		granted += grant.Credits
+	}
+	remaining := granted - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &models.SearchCreditsResponse{
+		UserID:            userID,
+		Date:              today,
+		MaxSearchesPerDay: maxSearches,
+		Granted:           granted,
+		Used:              used,
+		Remaining:         remaining,
+		Grants:            grants,
+	}, nil
+}
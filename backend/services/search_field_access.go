@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrSearchFieldNotAllowed is returned when a search uses a field outside the user's allowed search fields
+var ErrSearchFieldNotAllowed = errors.New("search field not allowed")
+
+// ErrInvalidSearchField is returned when allowed search fields name a field that cannot be searched
+var ErrInvalidSearchField = errors.New("invalid search field")
+
+// normalizeSearchFields lower-cases, de-duplicates and validates a list of allowed search fields
+func normalizeSearchFields(fields []string) (pq.StringArray, error) {
+	seen := make(map[string]bool, len(fields))
+	normalized := pq.StringArray{}
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !validSearchFields[field] {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSearchField, field)
+		}
+		if !seen[field] {
+			seen[field] = true
+			normalized = append(normalized, field)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// allowedSearchFields returns the fields a user may search on, or nil when every field is allowed
+func (s *SearchService) allowedSearchFields(userID uuid.UUID) (map[string]bool, error) {
+	var fields pq.StringArray
+	query := `SELECT allowed_search_fields FROM users WHERE id = $1`
+	if err := database.PostgresDB.Get(&fields, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get allowed search fields: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	allowed := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		allowed[field] = true
+	}
+	return allowed, nil
+}
+
+// enforceAllowedFields restricts a search to the user's allowed fields. Field-specific queries on a
+// disallowed field are rejected, since dropping one would widen an AND search; disallowed fields
+// are stripped from the shared-query field list. A search that names no usable field, which would
+// otherwise run across every field, runs across the allowed fields instead.
+func (s *SearchService) enforceAllowedFields(userID uuid.UUID, req *models.SearchRequest) error {
+	allowed, err := s.allowedSearchFields(userID)
+	if err != nil || allowed == nil {
+		return err
+	}
+
+	if req.Near != nil && !allowed["pincode"] {
+		return fmt.Errorf("%w: pincode", ErrSearchFieldNotAllowed)
+	}
+
+	fieldQueries := 0
+	for field, value := range req.FieldQueries {
+		if strings.TrimSpace(value) == "" || !s.isValidField(field) {
+			continue
+		}
+		if !allowed[field] {
+			return fmt.Errorf("%w: %s", ErrSearchFieldNotAllowed, field)
+		}
+		fieldQueries++
+	}
+	if fieldQueries > 0 {
+		return nil
+	}
+	// Only blank or unknown field queries: they would fall through to the all-fields search
+	req.FieldQueries = nil
+
+	var fields []string
+	for _, field := range req.Fields {
+		if s.isValidField(field) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		// Match the all-fields search, which matches any field
+		req.Logic = "OR"
+	}
+	req.Fields, err = stripDisallowedFields(fields, allowed)
+	return err
+}
+
+// enforceAllowedWithinFields restricts a search within previous results to the user's allowed fields
+func (s *SearchService) enforceAllowedWithinFields(userID uuid.UUID, req *models.SearchWithinRequest) error {
+	allowed, err := s.allowedSearchFields(userID)
+	if err != nil || allowed == nil {
+		return err
+	}
+
+	fields, err := stripDisallowedFields(req.Fields, allowed)
+	if err != nil {
+		return err
+	}
+	req.Fields = fields
+	return nil
+}
+
+// enforceAllowedKeySearch rejects enhanced searches for users who may not search by the key's field
+func (s *SearchService) enforceAllowedKeySearch(userID uuid.UUID, keyType string) error {
+	allowed, err := s.allowedSearchFields(userID)
+	if err != nil || allowed == nil {
+		return err
+	}
+	if !allowed[keyType] {
+		return fmt.Errorf("%w: %s", ErrSearchFieldNotAllowed, keyType)
+	}
+	return nil
+}
+
+// stripDisallowedFields keeps the allowed fields of a field list. An empty list, which searches
+// every field, becomes the allowed fields; a list with no allowed field is rejected.
+func stripDisallowedFields(fields []string, allowed map[string]bool) ([]string, error) {
+	if len(fields) == 0 {
+		all := make([]string, 0, len(allowed))
+		for field := range allowed {
+			all = append(all, field)
+		}
+		sort.Strings(all)
+		return all, nil
+	}
+
+	var kept, dropped []string
+	for _, field := range fields {
+		if allowed[field] {
+			kept = append(kept, field)
+		} else {
+			dropped = append(dropped, field)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSearchFieldNotAllowed, strings.Join(dropped, ", "))
+	}
+	return kept, nil
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"finone-search-system/models"
+)
+
+// ErrInvalidSearchMode is returned when a search names an unknown mode or one it cannot run in
+var ErrInvalidSearchMode = errors.New("invalid search mode")
+
+// ValidateMode checks the mode of a search. An enhanced_mobile search needs a mobile number as its
+// query or as its mobile or alt field query, and cannot be restricted to nearby pincodes.
+func (s *SearchService) ValidateMode(req *models.SearchRequest) error {
+	switch req.Mode {
+	case "", models.SearchModeAuto, models.SearchModeStandard:
+		return nil
+	case models.SearchModeEnhancedMobile:
+		if req.Near != nil {
+			return fmt.Errorf("%w: enhanced_mobile searches cannot be restricted to nearby pincodes", ErrInvalidSearchMode)
+		}
+		if s.extractMobileNumber(req) == "" {
+			return fmt.Errorf("%w: enhanced_mobile searches need a mobile number of 10 to 12 digits", ErrInvalidSearchMode)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %q; use auto, standard or enhanced_mobile", ErrInvalidSearchMode, req.Mode)
+}
+
+// useEnhancedMobileSearch reports whether a search runs as an enhanced mobile search: always in
+// enhanced_mobile mode, never in standard mode, and in auto mode when it looks like a mobile number search
+func (s *SearchService) useEnhancedMobileSearch(req *models.SearchRequest) bool {
+	switch req.Mode {
+	case models.SearchModeEnhancedMobile:
+		return true
+	case models.SearchModeStandard:
+		return false
+	}
+	return req.Near == nil && s.shouldUseEnhancedMobileSearch(req)
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// ErrSearchNotReplayable is returned for logged searches whose stored query cannot be run again as a
+// regular search, i.e. enhanced mobile searches and searches within results
+var ErrSearchNotReplayable = errors.New("search cannot be replayed; only regular searches can be")
+
+// ReplaySearch re-runs a logged search for another page of its results. Users may replay their own
+// searches; canViewAll lets admins replay anyone's. Replaying a search already made today does not use
+// quota. Replaying an older one counts as making it again, after which its other pages are free too.
+func (s *SearchService) ReplaySearch(userID uuid.UUID, canViewAll bool, searchID uuid.UUID, offset, limit int) (*models.SearchResponse, error) {
+	var search models.Search
+	err := database.PostgresDB.Get(&search, `SELECT * FROM searches WHERE id = $1`, searchID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && search.UserID != userID && !canViewAll) {
+		return nil, ErrSearchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search: %w", err)
+	}
+	if search.AnonymizedAt != nil {
+		return nil, ErrSearchAnonymized
+	}
+
+	req, err := storedSearchRequest(&search)
+	if err != nil {
+		return nil, err
+	}
+	if req.EnhancedMobile || strings.HasPrefix(req.Query, "WITHIN[") {
+		return nil, ErrSearchNotReplayable
+	}
+	s.ApplyDefaults(req)
+	if limit > 0 {
+		req.Limit = limit
+		s.ApplyDefaults(req)
+	}
+	req.Offset = offset
+	req.Facets = nil
+	req.Debug = false
+
+	// Only the owner's replays of their own searches count; admins looking at a search and replays of
+	// diagnostic searches do not
+	authService := NewAuthService()
+	fingerprint := s.computeSearchFingerprint(req)
+	counted := false
+	freeReason := models.SearchFreeReplay
+	if search.UserID == userID && !search.IsDiagnostic {
+		freeReason = models.SearchFreeDuplicate
+		isDup, _ := s.isDuplicateSearchToday(context.Background(), userID, fingerprint)
+		if !isDup {
+			canSearch, err := authService.CheckSearchLimit(userID)
+			if err != nil {
+				utils.LogError("Failed to check search limit", err)
+				return nil, fmt.Errorf("failed to check search limit")
+			}
+			if !canSearch {
+				return nil, ErrSearchLimitExceeded
+			}
+			counted = true
+		}
+	}
+
+	table, err := resolveDatasetTable(userID, &req.DatasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	ctx, cancel := searchContext(context.Background(), userID)
+	defer cancel()
+	ctx = database.WithPeopleTable(ctx, table)
+
+	results, totalCount, estimated, err := s.searchPage(ctx, req)
+	if err != nil {
+		utils.LogError("Search replay query failed", err)
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	executionTime := int(time.Since(startTime).Milliseconds())
+
+	// An older search run again is logged for today, so its later pages find the fingerprint
+	if counted {
+		s.logSearch(ctx, userID, req, len(results), executionTime, uuid.New().String(), fingerprint)
+		freeReason = models.SearchFreeNoResults
+		if totalCount > 0 {
+			if err := authService.IncrementSearchCount(userID); err != nil {
+				utils.LogError("Failed to increment search count", err)
+			}
+			freeReason = ""
+		}
+	}
+
+	s.MaskResults(userID, results)
+	highlightPeople(results, searchHighlightTerms(req))
+
+	return &models.SearchResponse{
+		Results:       results,
+		TotalCount:    totalCount,
+		Estimated:     estimated,
+		ExecutionTime: executionTime,
+		SearchID:      searchID.String(),
+		HasMore:       (req.Offset + len(results)) < totalCount,
+		Quota:         models.NewSearchQuota(freeReason),
+		Columns:       req.Columns,
+		Mode:          models.SearchModeStandard,
+	}, nil
+}
+
+// storedSearchRequest decodes the request saved with a logged search
+func storedSearchRequest(search *models.Search) (*models.SearchRequest, error) {
+	// SearchQuery is JSONB, scanned as raw bytes or text
+	var queryData []byte
+	switch v := search.SearchQuery.(type) {
+	case []byte:
+		queryData = v
+	case string:
+		queryData = []byte(v)
+	default:
+		queryData, _ = json.Marshal(search.SearchQuery)
+	}
+
+	var req models.SearchRequest
+	if err := json.Unmarshal(queryData, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse stored search: %w", err)
+	}
+	return &req, nil
+}
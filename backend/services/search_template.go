@@ -0,0 +1,249 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrSearchTemplateNotFound = errors.New("search template not found")
+	ErrSearchTemplateExists   = errors.New("a search template with this name already exists")
+	ErrInvalidSearchTemplate  = errors.New("invalid search template")
+)
+
+// SearchTemplateService manages search templates: the fields, logic, match type and output columns
+// of a standard search, which users run with just the values to search for
+type SearchTemplateService struct{}
+
+func NewSearchTemplateService() *SearchTemplateService {
+	return &SearchTemplateService{}
+}
+
+// ListTemplates returns the search templates by name, only the active ones unless includeInactive
+func (s *SearchTemplateService) ListTemplates(includeInactive bool) ([]models.SearchTemplate, error) {
+	templates := []models.SearchTemplate{}
+	query := `SELECT * FROM search_templates WHERE is_active OR $1 ORDER BY name`
+	if err := database.PostgresDB.Select(&templates, query, includeInactive); err != nil {
+		return nil, fmt.Errorf("failed to list search templates: %w", err)
+	}
+	return templates, nil
+}
+
+// GetTemplate returns a search template by ID, active or not
+func (s *SearchTemplateService) GetTemplate(id string) (*models.SearchTemplate, error) {
+	templateID, err := uuid.Parse(strings.TrimSpace(id))
+	if err != nil {
+		return nil, ErrSearchTemplateNotFound
+	}
+
+	var template models.SearchTemplate
+	err = database.PostgresDB.Get(&template, `SELECT * FROM search_templates WHERE id = $1`, templateID)
+	if err == sql.ErrNoRows {
+		return nil, ErrSearchTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get search template: %w", err)
+	}
+	return &template, nil
+}
+
+// CreateTemplate creates a search template
+func (s *SearchTemplateService) CreateTemplate(req *models.CreateSearchTemplateRequest, createdBy uuid.UUID) (*models.SearchTemplate, error) {
+	template := &models.SearchTemplate{
+		Name:          strings.TrimSpace(req.Name),
+		Fields:        req.Fields,
+		Logic:         req.Logic,
+		MatchType:     req.MatchType,
+		OutputColumns: req.OutputColumns,
+	}
+	if description := strings.TrimSpace(req.Description); description != "" {
+		template.Description = &description
+	}
+	if err := normalizeSearchTemplate(template); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO search_templates (name, description, fields, logic, match_type, output_columns, created_by)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  RETURNING *`
+	err := database.PostgresDB.Get(template, query, template.Name, template.Description, template.Fields,
+		template.Logic, template.MatchType, template.OutputColumns, createdBy)
+	if isUniqueViolation(err) {
+		return nil, ErrSearchTemplateExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search template: %w", err)
+	}
+
+	utils.LogInfo(fmt.Sprintf("Created search template %s on fields %v", template.Name, []string(template.Fields)))
+	return template, nil
+}
+
+// UpdateTemplate changes a search template's settings or whether it can be used
+func (s *SearchTemplateService) UpdateTemplate(id string, req *models.UpdateSearchTemplateRequest) (*models.SearchTemplate, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		template.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		description := strings.TrimSpace(*req.Description)
+		template.Description = &description
+		if description == "" {
+			template.Description = nil
+		}
+	}
+	if req.Fields != nil {
+		template.Fields = *req.Fields
+	}
+	if req.Logic != nil {
+		template.Logic = *req.Logic
+	}
+	if req.MatchType != nil {
+		template.MatchType = *req.MatchType
+	}
+	if req.OutputColumns != nil {
+		template.OutputColumns = *req.OutputColumns
+	}
+	if req.IsActive != nil {
+		template.IsActive = *req.IsActive
+	}
+	if err := normalizeSearchTemplate(template); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE search_templates
+			  SET name = $2, description = $3, fields = $4, logic = $5, match_type = $6, output_columns = $7,
+				  is_active = $8, updated_at = now()
+			  WHERE id = $1
+			  RETURNING *`
+	err = database.PostgresDB.Get(template, query, template.ID, template.Name, template.Description, template.Fields,
+		template.Logic, template.MatchType, template.OutputColumns, template.IsActive)
+	if isUniqueViolation(err) {
+		return nil, ErrSearchTemplateExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update search template: %w", err)
+	}
+	return template, nil
+}
+
+// DeleteTemplate deletes a search template. Searches already made through it can still be replayed.
+func (s *SearchTemplateService) DeleteTemplate(id string) error {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return err
+	}
+	if _, err := database.PostgresDB.Exec(`DELETE FROM search_templates WHERE id = $1`, template.ID); err != nil {
+		return fmt.Errorf("failed to delete search template: %w", err)
+	}
+	utils.LogInfo(fmt.Sprintf("Deleted search template %s", template.Name))
+	return nil
+}
+
+// BuildRequest turns a run of an active template into a search request. Values may only be given for
+// the template's fields and at least one must be non-empty; the fields without one are not searched.
+func (s *SearchTemplateService) BuildRequest(id string, run *models.RunSearchTemplateRequest) (*models.SearchRequest, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if !template.IsActive {
+		return nil, ErrSearchTemplateNotFound
+	}
+
+	req := &models.SearchRequest{
+		FieldQueries: map[string]string{},
+		Logic:        template.Logic,
+		MatchType:    template.MatchType,
+		Limit:        run.Limit,
+		Offset:       run.Offset,
+		DatasetID:    run.DatasetID,
+		Diagnostic:   run.Diagnostic,
+		Columns:      template.OutputColumns,
+		TemplateID:   template.ID.String(),
+	}
+	var values []string
+	for field, value := range run.Values {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !slices.Contains(template.Fields, field) {
+			return nil, fmt.Errorf("%w: %s is not a field of template %s; its fields are %s",
+				ErrInvalidSearchTemplate, field, template.Name, strings.Join(template.Fields, ", "))
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			req.FieldQueries[field] = value
+		}
+	}
+	// Fields and Query follow the template's field order, so the same values make the same search
+	for _, field := range template.Fields {
+		if value, ok := req.FieldQueries[field]; ok {
+			req.Fields = append(req.Fields, field)
+			values = append(values, value)
+		}
+	}
+	if len(req.Fields) == 0 {
+		return nil, fmt.Errorf("%w: a value is required for at least one of %s",
+			ErrInvalidSearchTemplate, strings.Join(template.Fields, ", "))
+	}
+	req.Query = strings.Join(values, " ")
+	return req, nil
+}
+
+// normalizeSearchTemplate validates a template's settings, filling in the default logic and match type
+func normalizeSearchTemplate(template *models.SearchTemplate) error {
+	if template.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidSearchTemplate)
+	}
+
+	fields := pq.StringArray{}
+	for _, field := range template.Fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !validSearchFields[field] {
+			return fmt.Errorf("%w: %q is not a search field", ErrInvalidSearchTemplate, field)
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: at least one field is required", ErrInvalidSearchTemplate)
+	}
+	template.Fields = fields
+
+	template.Logic = strings.ToUpper(strings.TrimSpace(template.Logic))
+	switch template.Logic {
+	case "":
+		template.Logic = "AND"
+	case "AND", "OR":
+	default:
+		return fmt.Errorf("%w: logic must be AND or OR", ErrInvalidSearchTemplate)
+	}
+	template.MatchType = strings.ToLower(strings.TrimSpace(template.MatchType))
+	switch template.MatchType {
+	case "":
+		template.MatchType = "partial"
+	case "partial", "full":
+	default:
+		return fmt.Errorf("%w: match_type must be partial or full", ErrInvalidSearchTemplate)
+	}
+
+	columns, err := models.NormalizePersonColumns(template.OutputColumns)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSearchTemplate, err)
+	}
+	template.OutputColumns = columns
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+)
+
+// searchTracer collects the debug trace of a single search. A nil tracer records nothing, so the
+// search path can call it unconditionally.
+type searchTracer struct {
+	mu    sync.Mutex // Facets are timed from their own goroutine
+	trace *models.SearchTrace
+	start time.Time
+}
+
+func newSearchTracer(enabled bool) *searchTracer {
+	if !enabled {
+		return nil
+	}
+	return &searchTracer{
+		trace: &models.SearchTrace{Parameters: []string{}, Decisions: []string{}, Timings: []models.TraceTiming{}},
+		start: time.Now(),
+	}
+}
+
+// timed records how long a step took since start
+func (t *searchTracer) timed(step string, start time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.Timings = append(t.trace.Timings, models.TraceTiming{
+		Step:       step,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	})
+}
+
+// decide records a decision the search made, e.g. skipping the quota increment for a duplicate
+func (t *searchTracer) decide(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.Decisions = append(t.trace.Decisions, fmt.Sprintf(format, args...))
+}
+
+// query records the generated SQL with its bound parameters redacted
+func (t *searchTracer) query(query string, args []interface{}) {
+	if t == nil {
+		return
+	}
+	t.trace.SQL = query
+	for _, arg := range args {
+		t.trace.Parameters = append(t.trace.Parameters, redactParameter(arg))
+	}
+}
+
+// explain records the indexes and estimated cost ClickHouse reports for the query. Failures are
+// recorded in the trace rather than failing the search.
+func (t *searchTracer) explain(ctx context.Context, s *SearchService, query string, args []interface{}) {
+	if t == nil {
+		return
+	}
+	if _, ok := s.backend.(*clickHouseSearchBackend); !ok {
+		t.decide("explain skipped: the search backend is not ClickHouse")
+		return
+	}
+	start := time.Now()
+	defer t.timed("explain", start)
+
+	plan, err := explainQuery(ctx, query, args)
+	if err != nil {
+		t.decide("explain failed: %v", err)
+		return
+	}
+	t.trace.Plan = plan
+	for _, line := range plan {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "Name: "); ok {
+			t.trace.Indexes = append(t.trace.Indexes, name)
+		}
+	}
+
+	if estimate, err := s.estimateQueryCost(ctx, query, args...); err == nil {
+		t.trace.CostEstimate = estimate
+	}
+}
+
+// explainQuery returns the lines of the plan ClickHouse reports for a query, with the indexes it uses
+func explainQuery(ctx context.Context, query string, args []interface{}) ([]string, error) {
+	rows, err := database.ClickHouseDB.Query(ctx, "EXPLAIN indexes = 1 "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plan []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		plan = append(plan, line)
+	}
+	return plan, rows.Err()
+}
+
+// finish returns the trace with the total time spent
+func (t *searchTracer) finish() *models.SearchTrace {
+	if t == nil {
+		return nil
+	}
+	t.timed("total", t.start)
+	return t.trace
+}
+
+// redactParameter describes a bound parameter without revealing the searched value: strings keep
+// their LIKE wildcards and length, other values only their type
+func redactParameter(arg interface{}) string {
+	value, ok := arg.(string)
+	if !ok {
+		return fmt.Sprintf("<%T>", arg)
+	}
+
+	prefix, suffix := "", ""
+	if strings.HasPrefix(value, "%") {
+		prefix, value = "%", value[1:]
+	}
+	if strings.HasSuffix(value, "%") {
+		suffix, value = "%", value[:len(value)-1]
+	}
+	return fmt.Sprintf("%s<redacted:%d chars>%s", prefix, len([]rune(value)), suffix)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// sessionActivityInterval throttles last_activity_at writes to one per session per interval, so the
+// idle timeout is accurate to about this much
+const sessionActivityInterval = time.Minute
+
+// sessionRotationGrace is how long a token keeps working after an active session was given a fresh
+// one, so requests already in flight with the old token still succeed
+const sessionRotationGrace = time.Minute
+
+// activityThrottle remembers when each session's activity was last written
+type activityThrottle struct {
+	mu        sync.Mutex
+	lastWrite map[string]time.Time // token hash -> last write
+}
+
+// sessionActivity is shared by every AuthService since services are created per request
+var sessionActivity = &activityThrottle{lastWrite: make(map[string]time.Time)}
+
+// due reports whether a session's activity should be written now, and if so records the write
+func (t *activityThrottle) due(tokenHash string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.lastWrite[tokenHash]; ok && now.Sub(last) < sessionActivityInterval {
+		return false
+	}
+	t.lastWrite[tokenHash] = now
+
+	// Drop stale entries so the map cannot grow without bound
+	if len(t.lastWrite)%1000 == 0 {
+		for hash, last := range t.lastWrite {
+			if now.Sub(last) > sessionActivityInterval {
+				delete(t.lastWrite, hash)
+			}
+		}
+	}
+	return true
+}
+
+// sessionIdle reports whether a session has gone without a request for longer than the idle timeout
+func sessionIdle(session *models.UserSession, now time.Time) bool {
+	if config.Get() == nil || config.Get().JWT.IdleTimeout <= 0 {
+		return false
+	}
+	lastActivity := session.CreatedAt
+	if session.LastActivityAt != nil {
+		lastActivity = *session.LastActivityAt
+	}
+	return now.Sub(lastActivity) > config.Get().JWT.IdleTimeout
+}
+
+// expireIdleSession invalidates a session that was found idle during validation
+func (s *AuthService) expireIdleSession(sessionID uuid.UUID) {
+	query := `UPDATE user_sessions SET is_active = false, logged_out_at = now() WHERE id = $1 AND is_active = true`
+	if _, err := database.PostgresDB.Exec(query, sessionID); err != nil {
+		utils.LogError("Failed to expire idle session", err)
+	}
+}
+
+// expireIdleSessions invalidates every session idle for longer than the idle timeout
+func (s *AuthService) expireIdleSessions() error {
+	if config.Get() == nil || config.Get().JWT.IdleTimeout <= 0 {
+		return nil
+	}
+
+	query := `UPDATE user_sessions
+			  SET is_active = false, logged_out_at = now()
+			  WHERE is_active = true AND COALESCE(last_activity_at, created_at) < now() - make_interval(secs => $1)`
+	result, err := database.PostgresDB.Exec(query, config.Get().JWT.IdleTimeout.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to expire idle sessions: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+		utils.LogInfo(fmt.Sprintf("Expired %d idle sessions", rows))
+	}
+	return nil
+}
+
+// RecordActivity notes that a session made a request, at most once per sessionActivityInterval.
+// With jwt.extend_active, a session past half its lifetime gets a fresh token and session, which is
+// returned; the old token stops working after sessionRotationGrace.
+func (s *AuthService) RecordActivity(tokenString string, user *models.User) (string, error) {
+	tokenHash := s.hashToken(tokenString)
+	if !sessionActivity.due(tokenHash, time.Now()) {
+		return "", nil
+	}
+
+	var session models.UserSession
+	query := `UPDATE user_sessions
+			  SET last_activity_at = now()
+			  WHERE session_token = $1 AND is_active = true
+			  RETURNING id, user_id, expires_at, ip_address, user_agent, impersonated_by, country, city, asn, as_org`
+	err := database.PostgresDB.Get(&session, query, tokenHash)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to record session activity: %w", err)
+	}
+
+	// Impersonation sessions end when they expire
+	cfg := config.Get()
+	if session.ImpersonatedBy != nil || !cfg.JWT.ExtendActive || time.Until(session.ExpiresAt) > cfg.JWT.Expiry/2 {
+		return "", nil
+	}
+	return s.rotateSession(&session, tokenHash, user)
+}
+
+// rotateSession replaces an active session with a new one carrying a fresh token
+func (s *AuthService) rotateSession(session *models.UserSession, tokenHash string, user *models.User) (string, error) {
+	token, expiresAt, err := s.generateJWT(user.ID.String(), user.Email, user.Role)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	client := LoginClient{IPAddress: session.IPAddress, UserAgent: session.UserAgent, Location: utils.GeoLocation{
+		Country: stringValue(session.Country),
+		City:    stringValue(session.City),
+		ASOrg:   stringValue(session.ASOrg),
+	}}
+	if session.ASN != nil {
+		client.Location.ASN = uint(*session.ASN)
+	}
+	if _, err := s.createSession(user.ID, token, expiresAt, client); err != nil {
+		return "", err
+	}
+
+	query := `UPDATE user_sessions SET expires_at = LEAST(expires_at, $2) WHERE id = $1`
+	if _, err := database.PostgresDB.Exec(query, session.ID, time.Now().Add(sessionRotationGrace)); err != nil {
+		return "", fmt.Errorf("failed to retire rotated session: %w", err)
+	}
+	sessionCache.invalidateToken(tokenHash)
+
+	utils.LogInfo(fmt.Sprintf("Extended active session %s for user %s", session.ID, user.ID))
+	return token, nil
+}
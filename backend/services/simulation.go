@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidSimulation is returned when a simulation request is incomplete or malformed
+var ErrInvalidSimulation = errors.New("invalid simulation")
+
+type SimulationService struct {
+	authService   *AuthService
+	searchService *SearchService
+}
+
+func NewSimulationService() *SimulationService {
+	return &SimulationService{
+		authService:   NewAuthService(),
+		searchService: NewSearchService(),
+	}
+}
+
+// Simulate evaluates whether a hypothetical search or export would be allowed for a user.
+// Nothing is executed, logged or counted against the user's quota.
+func (s *SimulationService) Simulate(req *models.SimulationRequest) (*models.SimulationResponse, error) {
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid user ID", ErrInvalidSimulation)
+	}
+
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	if action == "" {
+		action = "search"
+	}
+	if action != "search" && action != "export" {
+		return nil, fmt.Errorf("%w: action must be either 'search' or 'export'", ErrInvalidSimulation)
+	}
+
+	// Load the user regardless of active state so the simulation can explain why access is denied
+	var user models.User
+	err = database.PostgresDB.Get(&user, `SELECT * FROM users WHERE id = $1`, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	usage, err := s.authService.GetTodayUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	extraSearches, err := todayExtraSearches(userID)
+	if err != nil {
+		return nil, err
+	}
+	_, extraExports, err := NewQuotaAdjustmentService().GetTodayAdjustments(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.SimulationResponse{
+		UserID:        userID.String(),
+		Action:        action,
+		SearchQuota:   newQuotaStatus(usage.SearchCount, user.MaxSearchesPerDay+extraSearches),
+		ExportQuota:   newQuotaStatus(usage.ExportCount, user.MaxExportsPerDay+extraExports),
+		ConsumesQuota: true,
+		QuotaExempt:   user.QuotaExempt,
+	}
+
+	addSimulationCheck(response, "account_active", user.IsActive, "User account is active", "User account is deactivated")
+
+	expired := user.ExpiresAt != nil && user.ExpiresAt.Before(time.Now())
+	expiryDetail := "User account does not expire"
+	if user.ExpiresAt != nil {
+		expiryDetail = fmt.Sprintf("User account expires at %s", user.ExpiresAt.Format(time.RFC3339))
+	}
+	addSimulationCheck(response, "account_not_expired", !expired, expiryDetail, "User account has expired")
+
+	switch action {
+	case "search":
+		if req.Search == nil {
+			return nil, fmt.Errorf("%w: search payload is required for a search simulation", ErrInvalidSimulation)
+		}
+		s.simulateSearch(response, userID, req.Search)
+	case "export":
+		if req.Export == nil {
+			return nil, fmt.Errorf("%w: export payload is required for an export simulation", ErrInvalidSimulation)
+		}
+		if err := s.simulateExport(response, userID, req.Export); err != nil {
+			return nil, err
+		}
+	}
+
+	response.Allowed = true
+	for _, check := range response.Checks {
+		if !check.Passed {
+			response.Allowed = false
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// simulateSearch evaluates field permissions, quota and query cost for a hypothetical search
+func (s *SimulationService) simulateSearch(response *models.SimulationResponse, userID uuid.UUID, req *models.SearchRequest) {
+	s.searchService.ApplyDefaults(req)
+
+	// The search service silently skips unknown fields, so report them without failing the check
+	ignored := s.unknownFields(req)
+	fieldDetail := "All requested fields are searchable"
+	if len(ignored) > 0 {
+		fieldDetail = fmt.Sprintf("Unknown fields will be ignored: %s", strings.Join(ignored, ", "))
+	}
+	addSimulationCheck(response, "field_permissions", true, fieldDetail, "")
+
+	// Quota is checked before duplicate detection, so even a duplicate search needs remaining quota
+	if response.QuotaExempt {
+		addSimulationCheck(response, "search_quota", true, "User is exempt from the daily search limit", "")
+		response.ConsumesQuota = false
+	} else {
+		addSimulationCheck(response, "search_quota", response.SearchQuota.Remaining > 0,
+			fmt.Sprintf("%d of %d searches remaining today", response.SearchQuota.Remaining, response.SearchQuota.Limit),
+			"Daily search limit exceeded")
+	}
+
+	fingerprint := s.searchService.computeSearchFingerprint(req)
+	if isDup, _ := s.searchService.isDuplicateSearchToday(context.Background(), userID, fingerprint); isDup {
+		response.ConsumesQuota = false
+	}
+
+	if s.searchService.shouldUseEnhancedMobileSearch(req) && s.searchService.extractMobileNumber(req) != "" {
+		response.SearchMode = "enhanced_mobile"
+		return
+	}
+	response.SearchMode = "standard"
+
+	query, args := s.searchService.buildSearchQuery(s.simulateDataset(response, userID, req), req)
+	response.GeneratedQuery = query
+	response.EstimatedCost = s.estimate(query, args)
+}
+
+// simulateExport evaluates export quota, format and source data for a hypothetical export
+func (s *SimulationService) simulateExport(response *models.SimulationResponse, userID uuid.UUID, req *models.ExportRequest) error {
+	// Exports are never free, unlike duplicate searches
+	addSimulationCheck(response, "export_quota", response.ExportQuota.Remaining > 0,
+		fmt.Sprintf("%d of %d exports remaining today", response.ExportQuota.Remaining, response.ExportQuota.Limit),
+		"Daily export limit exceeded")
+
+	format := strings.ToLower(strings.TrimSpace(req.Format))
+	if format == "" {
+		format = "csv"
+	}
+	_, supported := exportFormats[format]
+	addSimulationCheck(response, "export_format", supported,
+		fmt.Sprintf("Export format %s is supported", format),
+		fmt.Sprintf("Unsupported export format: %s", format))
+
+	switch {
+	case req.SearchID != nil:
+		searchID, err := uuid.Parse(*req.SearchID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid search ID", ErrInvalidSimulation)
+		}
+
+		var resultCount int
+		err = database.PostgresDB.Get(&resultCount,
+			`SELECT result_count FROM searches WHERE id = $1 AND user_id = $2`, searchID, userID)
+		addSimulationCheck(response, "source_search", err == nil,
+			fmt.Sprintf("Search %s belongs to the user (%d results)", searchID, resultCount),
+			"Search not found for this user")
+		if err == nil {
+			rows := uint64(resultCount)
+			if maxRows := config.Get().Limits.MaxRowsPerSearch; maxRows > 0 && resultCount > maxRows {
+				rows = uint64(maxRows)
+			}
+			response.EstimatedCost = &models.CostEstimate{Rows: rows}
+		}
+	case req.Query != nil:
+		s.searchService.ApplyDefaults(req.Query)
+		query, args := s.searchService.buildSearchQuery(s.simulateDataset(response, userID, req.Query), req.Query)
+		response.GeneratedQuery = query
+		response.EstimatedCost = s.estimate(query, args)
+	default:
+		return fmt.Errorf("%w: export simulation requires either search_id or query", ErrInvalidSimulation)
+	}
+
+	return nil
+}
+
+// simulateDataset checks the user may use the dataset a request names and returns a context that
+// builds its query against the dataset's table
+func (s *SimulationService) simulateDataset(response *models.SimulationResponse, userID uuid.UUID, req *models.SearchRequest) context.Context {
+	ctx := context.Background()
+	if req.DatasetID == "" {
+		return ctx
+	}
+
+	dataset, err := NewDatasetService().ResolveDataset(userID, req.DatasetID)
+	if err != nil {
+		addSimulationCheck(response, "dataset_access", false, "", err.Error())
+		return ctx
+	}
+	addSimulationCheck(response, "dataset_access", true, fmt.Sprintf("User may use dataset %s", dataset.Slug), "")
+	return database.WithPeopleTable(ctx, database.QualifiedTable(dataset.TableName))
+}
+
+// estimate returns the ClickHouse cost estimate for a query, or nil if it cannot be computed
+func (s *SimulationService) estimate(query string, args []interface{}) *models.CostEstimate {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	estimate, err := s.searchService.estimateQueryCost(ctx, query, args...)
+	if err != nil {
+		utils.LogError("Failed to estimate simulated query cost", err)
+		return nil
+	}
+	return estimate
+}
+
+// unknownFields returns the requested fields the search service would ignore
+func (s *SimulationService) unknownFields(req *models.SearchRequest) []string {
+	seen := map[string]bool{}
+	for _, field := range req.Fields {
+		if !s.searchService.isValidField(field) {
+			seen[field] = true
+		}
+	}
+	for field := range req.FieldQueries {
+		if !s.searchService.isValidField(field) {
+			seen[field] = true
+		}
+	}
+
+	ignored := make([]string, 0, len(seen))
+	for field := range seen {
+		ignored = append(ignored, field)
+	}
+	sort.Strings(ignored)
+	return ignored
+}
+
+// addSimulationCheck records a check, choosing the detail message based on its outcome
+func addSimulationCheck(response *models.SimulationResponse, name string, passed bool, passDetail, failDetail string) {
+	detail := passDetail
+	if !passed {
+		detail = failDetail
+	}
+	response.Checks = append(response.Checks, models.SimulationCheck{Name: name, Passed: passed, Detail: detail})
+}
+
+func newQuotaStatus(used, limit int) models.QuotaStatus {
+	remaining := limit - used
+	if remaining < 0 {
+		remaining = 0
+	}
+	return models.QuotaStatus{Used: used, Limit: limit, Remaining: remaining}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"finone-search-system/database"
+	"finone-search-system/models"
+)
+
+// StorageService reports on the database connection pools. Pools are per instance, so the stats
+// describe only the instance that served the request.
+type StorageService struct{}
+
+func NewStorageService() *StorageService {
+	return &StorageService{}
+}
+
+// GetPoolStats returns the current PostgreSQL and ClickHouse pool usage
+func (s *StorageService) GetPoolStats() *models.StorageStatsResponse {
+	stats := &models.StorageStatsResponse{}
+
+	if database.PostgresDB != nil {
+		pg := database.PostgresDB.Stats()
+		stats.Postgres = models.PostgresPoolStats{
+			MaxOpenConns:      pg.MaxOpenConnections,
+			OpenConns:         pg.OpenConnections,
+			InUse:             pg.InUse,
+			Idle:              pg.Idle,
+			WaitCount:         pg.WaitCount,
+			WaitDurationMs:    pg.WaitDuration.Milliseconds(),
+			MaxIdleClosed:     pg.MaxIdleClosed,
+			MaxIdleTimeClosed: pg.MaxIdleTimeClosed,
+			MaxLifetimeClosed: pg.MaxLifetimeClosed,
+		}
+	}
+
+	if database.ClickHouseDB != nil {
+		ch := database.ClickHouseDB.Stats()
+		stats.ClickHouse = models.ClickHousePoolStats{
+			MaxOpenConns: ch.MaxOpenConns,
+			MaxIdleConns: ch.MaxIdleConns,
+			OpenConns:    ch.Open,
+			Idle:         ch.Idle,
+			CircuitOpen:  database.ClickHouseCircuitOpen(),
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// systemMaintenanceTTL is how long an instance uses the loaded maintenance switch before reloading it,
+// so turning maintenance mode on or off through another instance reaches this one
+const systemMaintenanceTTL = 10 * time.Second
+
+// defaultMaintenanceMessage is shown to users while maintenance mode is on without a message of its own
+const defaultMaintenanceMessage = "The service is down for maintenance, please try again later"
+
+// maxMaintenanceMessageLength is the longest maintenance message accepted
+const maxMaintenanceMessageLength = 500
+
+// ErrInvalidMaintenanceMessage is returned when a maintenance message is too long
+var ErrInvalidMaintenanceMessage = fmt.Errorf("maintenance message must be at most %d characters", maxMaintenanceMessageLength)
+
+// loadedSystemMaintenance holds the maintenance switch until it expires or is changed through this instance
+var loadedSystemMaintenance struct {
+	mu          sync.Mutex
+	maintenance *models.SystemMaintenance // nil until loaded
+	loadedAt    time.Time
+}
+
+// SystemMaintenanceService manages maintenance mode, the persisted switch that shuts users out while
+// admins run large imports and migrations
+type SystemMaintenanceService struct{}
+
+func NewSystemMaintenanceService() *SystemMaintenanceService {
+	return &SystemMaintenanceService{}
+}
+
+// Get returns the maintenance switch as stored
+func (s *SystemMaintenanceService) Get() (*models.SystemMaintenance, error) {
+	var maintenance models.SystemMaintenance
+	query := `SELECT enabled, message, updated_by, updated_at FROM system_maintenance`
+	if err := database.PostgresDB.Get(&maintenance, query); err != nil {
+		return nil, fmt.Errorf("failed to get maintenance mode: %w", err)
+	}
+	return &maintenance, nil
+}
+
+// Set turns maintenance mode on or off. The message is kept while maintenance mode is off, so it
+// can be turned back on with the same message.
+func (s *SystemMaintenanceService) Set(req *models.SystemMaintenanceRequest, updatedBy uuid.UUID) (*models.SystemMaintenance, error) {
+	message := strings.TrimSpace(req.Message)
+	if len(message) > maxMaintenanceMessageLength {
+		return nil, ErrInvalidMaintenanceMessage
+	}
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+
+	var saved models.SystemMaintenance
+	query := `INSERT INTO system_maintenance (id, enabled, message, updated_by)
+	          VALUES (TRUE, $1, $2, $3)
+	          ON CONFLICT (id) DO UPDATE SET enabled = EXCLUDED.enabled, message = EXCLUDED.message,
+	              updated_by = EXCLUDED.updated_by, updated_at = now()
+	          RETURNING enabled, message, updated_by, updated_at`
+	if err := database.PostgresDB.Get(&saved, query, *req.Enabled, message, updatedBy); err != nil {
+		return nil, fmt.Errorf("failed to save maintenance mode: %w", err)
+	}
+
+	loadedSystemMaintenance.mu.Lock()
+	loadedSystemMaintenance.maintenance = &saved
+	loadedSystemMaintenance.loadedAt = time.Now()
+	loadedSystemMaintenance.mu.Unlock()
+
+	if saved.Enabled {
+		utils.LogWarning(fmt.Sprintf("Maintenance mode turned on by %s: %s", updatedBy, saved.Message))
+	} else {
+		utils.LogInfo(fmt.Sprintf("Maintenance mode turned off by %s", updatedBy))
+	}
+	return &saved, nil
+}
+
+// MaintenanceMessage reports whether maintenance mode is on and the message users are shown,
+// loading the switch when it is missing or expired. If it cannot be loaded the previous state is
+// kept, or maintenance mode taken to be off, so an unreachable database does not shut users out.
+func MaintenanceMessage() (string, bool) {
+	loadedSystemMaintenance.mu.Lock()
+	defer loadedSystemMaintenance.mu.Unlock()
+
+	if loadedSystemMaintenance.maintenance == nil || time.Since(loadedSystemMaintenance.loadedAt) >= systemMaintenanceTTL {
+		if database.PostgresDB == nil {
+			return "", false
+		}
+		maintenance, err := NewSystemMaintenanceService().Get()
+		if err != nil {
+			utils.LogError("Failed to load maintenance mode", err)
+		} else {
+			loadedSystemMaintenance.maintenance = maintenance
+		}
+		loadedSystemMaintenance.loadedAt = time.Now() // Keep the stale state for another TTL rather than retry on every request
+	}
+
+	maintenance := loadedSystemMaintenance.maintenance
+	if maintenance == nil || !maintenance.Enabled {
+		return "", false
+	}
+	if maintenance.Message == "" {
+		return defaultMaintenanceMessage, true
+	}
+	return maintenance.Message, true
+}
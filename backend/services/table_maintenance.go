@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+// Table maintenance run statuses
+const (
+	MaintenanceStatusRunning   = "running"
+	MaintenanceStatusCompleted = "completed"
+	MaintenanceStatusStopped   = "stopped"
+	MaintenanceStatusFailed    = "failed"
+)
+
+// optimizePartitionTimeout bounds the merge of a single partition
+const optimizePartitionTimeout = 6 * time.Hour
+
+// importStaleAfter is how long an import recorded as started counts as running on another instance;
+// older ones are assumed to have died with their instance
+const importStaleAfter = 12 * time.Hour
+
+var (
+	ErrMaintenanceRunning = errors.New("table maintenance (OPTIMIZE) is running; retry once it finishes")
+	ErrImportRunning      = errors.New("an import is running; table maintenance waits until it finishes")
+	ErrInvalidTable       = errors.New("invalid table name")
+)
+
+// maintenanceGate keeps OPTIMIZE runs and imports of this instance apart: imports share it, while an
+// OPTIMIZE run needs it alone
+type maintenanceGate struct {
+	mu      sync.Mutex
+	imports int
+	running bool
+	run     *models.TableMaintenanceRun // Current or last run
+}
+
+var tableMaintenance maintenanceGate
+
+// BeginImport registers an import that is about to insert rows, failing while an OPTIMIZE run is in
+// progress. Call the returned function once the import is done.
+func BeginImport() (func(), error) {
+	tableMaintenance.mu.Lock()
+	defer tableMaintenance.mu.Unlock()
+
+	if tableMaintenance.running {
+		return nil, ErrMaintenanceRunning
+	}
+	tableMaintenance.imports++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			tableMaintenance.mu.Lock()
+			tableMaintenance.imports--
+			tableMaintenance.mu.Unlock()
+		})
+	}, nil
+}
+
+type TableMaintenanceService struct{}
+
+func NewTableMaintenanceService() *TableMaintenanceService {
+	return &TableMaintenanceService{}
+}
+
+// optimizePartition is a partition of a people table with more than one active part
+type optimizePartition struct {
+	PartitionID string `ch:"partition_id"`
+	Partition   string `ch:"partition"`
+	Parts       uint64 `ch:"parts"`
+	Rows        uint64 `ch:"rows"`
+}
+
+// StartOptimize starts merging the parts of a people table in the background, one partition at a
+// time with OPTIMIZE ... PARTITION ID ... FINAL. Partitions already in a single part are skipped.
+// No partition is started after deadline, unless it is zero; the rest are merged by the next run.
+// Runs refuse to start while an import is running on this or, as far as the import audit shows,
+// another instance, and imports are refused until the run ends.
+func (s *TableMaintenanceService) StartOptimize(table string, triggeredBy *uuid.UUID, deadline time.Time) (*models.TableMaintenanceRun, error) {
+	if table == "" {
+		table = database.PeopleTableName()
+	}
+	if !database.ValidTableName(table) {
+		return nil, ErrInvalidTable
+	}
+
+	tableMaintenance.mu.Lock()
+	if tableMaintenance.running {
+		tableMaintenance.mu.Unlock()
+		return nil, ErrMaintenanceRunning
+	}
+	if tableMaintenance.imports > 0 {
+		tableMaintenance.mu.Unlock()
+		return nil, ErrImportRunning
+	}
+	tableMaintenance.running = true
+	tableMaintenance.mu.Unlock()
+
+	run, partitions, err := s.prepareOptimize(table, triggeredBy, deadline)
+	if err != nil {
+		tableMaintenance.mu.Lock()
+		tableMaintenance.running = false
+		tableMaintenance.mu.Unlock()
+		return nil, err
+	}
+
+	tableMaintenance.mu.Lock()
+	tableMaintenance.run = run
+	snapshot := *run
+	tableMaintenance.mu.Unlock()
+
+	go s.runOptimize(run, partitions, deadline)
+	return &snapshot, nil
+}
+
+// prepareOptimize checks for imports on other instances and lists the partitions to merge
+func (s *TableMaintenanceService) prepareOptimize(table string, triggeredBy *uuid.UUID, deadline time.Time) (*models.TableMaintenanceRun, []optimizePartition, error) {
+	var importsRunning int
+	err := database.PostgresDB.Get(&importsRunning,
+		`SELECT COUNT(*) FROM csv_import_audit WHERE status = 'STARTED' AND started_at > $1`,
+		time.Now().Add(-importStaleAfter))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to check running imports: %w", err)
+	}
+	if importsRunning > 0 {
+		return nil, nil, ErrImportRunning
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var partitions []optimizePartition
+	query := `SELECT partition_id, any(partition) AS partition, toUInt64(count()) AS parts, toUInt64(sum(rows)) AS rows
+	          FROM system.parts
+	          WHERE database = ? AND table = ? AND active
+	          GROUP BY partition_id
+	          HAVING parts > 1
+	          ORDER BY partition_id`
+	if err := database.ClickHouseDB.Select(ctx, &partitions, query, database.PeopleDatabase, table); err != nil {
+		return nil, nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	return &models.TableMaintenanceRun{
+		Table:       table,
+		Status:      MaintenanceStatusRunning,
+		Scheduled:   !deadline.IsZero(),
+		TriggeredBy: triggeredBy,
+		Partitions:  len(partitions),
+		StartedAt:   time.Now(),
+	}, partitions, nil
+}
+
+// runOptimize merges each partition in turn, logging progress, and releases the gate when done
+func (s *TableMaintenanceService) runOptimize(run *models.TableMaintenanceRun, partitions []optimizePartition, deadline time.Time) {
+	status := MaintenanceStatusCompleted
+	var runErr error
+
+	utils.LogInfo(fmt.Sprintf("Starting OPTIMIZE of %s: %d partitions with more than one part",
+		database.QualifiedTable(run.Table), len(partitions)))
+
+	for i, partition := range partitions {
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			status = MaintenanceStatusStopped
+			utils.LogInfo(fmt.Sprintf("OPTIMIZE of %s stopped at the end of its window after %d of %d partitions",
+				run.Table, i, len(partitions)))
+			break
+		}
+
+		tableMaintenance.mu.Lock()
+		run.CurrentPartition = partition.Partition
+		tableMaintenance.mu.Unlock()
+
+		start := time.Now()
+		if err := s.optimizePartition(run.Table, partition.PartitionID); err != nil {
+			status = MaintenanceStatusFailed
+			runErr = fmt.Errorf("partition %s: %w", partition.Partition, err)
+			break
+		}
+		utils.LogInfo(fmt.Sprintf("OPTIMIZE %s partition %s (%d/%d): merged %d parts, %d rows in %v",
+			run.Table, partition.Partition, i+1, len(partitions), partition.Parts, partition.Rows,
+			time.Since(start).Round(time.Second)))
+
+		tableMaintenance.mu.Lock()
+		run.PartitionsDone++
+		tableMaintenance.mu.Unlock()
+	}
+
+	if runErr != nil {
+		utils.LogError(fmt.Sprintf("OPTIMIZE of %s failed", run.Table), runErr)
+	} else if status == MaintenanceStatusCompleted {
+		utils.LogInfo(fmt.Sprintf("OPTIMIZE of %s completed: %d partitions merged", run.Table, run.PartitionsDone))
+	}
+
+	completedAt := time.Now()
+	tableMaintenance.mu.Lock()
+	run.Status = status
+	run.CurrentPartition = ""
+	run.CompletedAt = &completedAt
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+	tableMaintenance.running = false
+	tableMaintenance.mu.Unlock()
+}
+
+// optimizePartition merges one partition of a table into a single part
+func (s *TableMaintenanceService) optimizePartition(table, partitionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), optimizePartitionTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf("OPTIMIZE TABLE %s PARTITION ID '%s' FINAL",
+		database.QualifiedTable(table), strings.ReplaceAll(partitionID, "'", "\\'"))
+	return database.ClickHouseDB.Exec(ctx, query)
+}
+
+// GetStatus returns the current or last OPTIMIZE run of this instance
+func (s *TableMaintenanceService) GetStatus() (bool, *models.TableMaintenanceRun) {
+	tableMaintenance.mu.Lock()
+	defer tableMaintenance.mu.Unlock()
+
+	if tableMaintenance.run == nil {
+		return tableMaintenance.running, nil
+	}
+	snapshot := *tableMaintenance.run
+	return tableMaintenance.running, &snapshot
+}
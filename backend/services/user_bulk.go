@@ -0,0 +1,280 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// maxBulkUsers caps one bulk request; each user's password is hashed with bcrypt
+const maxBulkUsers = 500
+
+// generatedPasswordAlphabet leaves out characters that are easy to misread, like 0/O and 1/l
+const generatedPasswordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+
+const generatedPasswordLength = 12
+
+// emailTakenMessage reports a row whose email belongs to an existing user
+const emailTakenMessage = "a user with this email already exists"
+
+var (
+	ErrBulkUsersEmpty   = errors.New("no users to create")
+	ErrBulkUsersTooMany = fmt.Errorf("at most %d users can be created at once", maxBulkUsers)
+)
+
+// BulkUsersError is returned when rows of a bulk request are invalid; no user is created
+type BulkUsersError struct {
+	Rows []models.BulkUserError
+}
+
+func (e *BulkUsersError) Error() string {
+	return fmt.Sprintf("%d of the users are invalid", len(e.Rows))
+}
+
+// BulkCreateUsers creates every user of a request in one transaction, so either all of them are
+// created or none are. Rows are validated first and all invalid rows are reported together in a
+// BulkUsersError, including emails that are repeated or already taken. Users without a password get
+// a generated one, returned in the response. With SendWelcomeEmail each user is emailed their login
+// details once the transaction has committed.
+func (s *AuthService) BulkCreateUsers(req *models.BulkCreateUsersRequest) (*models.BulkCreateUsersResponse, error) {
+	if len(req.Users) == 0 {
+		return nil, ErrBulkUsersEmpty
+	}
+	if len(req.Users) > maxBulkUsers {
+		return nil, ErrBulkUsersTooMany
+	}
+
+	rowErrors, err := validateBulkUsers(req.Users)
+	if err != nil {
+		return nil, err
+	}
+	if len(rowErrors) > 0 {
+		return nil, &BulkUsersError{Rows: rowErrors}
+	}
+
+	response := &models.BulkCreateUsersResponse{Users: make([]models.BulkCreatedUser, 0, len(req.Users))}
+	passwords := make([]string, len(req.Users))
+	users := make([]*models.User, len(req.Users))
+	for i := range req.Users {
+		row := &req.Users[i]
+		created := models.BulkCreatedUser{Row: i + 1}
+		if row.Password == "" {
+			if row.Password, err = generatePassword(); err != nil {
+				return nil, err
+			}
+			created.Password = row.Password
+		}
+		passwords[i] = row.Password
+
+		if users[i], err = s.newUser(row); err != nil {
+			return nil, &BulkUsersError{Rows: []models.BulkUserError{{Row: i + 1, Email: row.Email, Error: err.Error()}}}
+		}
+		response.Users = append(response.Users, created)
+	}
+
+	err = database.WithTransaction(func(tx *sqlx.Tx) error {
+		for i, user := range users {
+			if err := insertUser(tx, user); err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := NewNotificationService()
+	for i, user := range users {
+		user.PasswordHash = ""
+		response.Users[i].User = *user
+		if req.SendWelcomeEmail {
+			notifications.NotifyUserCreated(user, passwords[i])
+		}
+	}
+	response.Created = len(users)
+	response.EmailsQueued = req.SendWelcomeEmail && notifications.IsEnabled(EventUserCreated)
+
+	utils.LogInfo(fmt.Sprintf("Bulk created %d users", response.Created))
+	return response, nil
+}
+
+// validateBulkUsers checks the rows of a bulk request, reporting each invalid row
+func validateBulkUsers(rows []models.CreateUserRequest) ([]models.BulkUserError, error) {
+	var rowErrors []models.BulkUserError
+	reject := func(i int, email, message string) {
+		rowErrors = append(rowErrors, models.BulkUserError{Row: i + 1, Email: email, Error: message})
+	}
+
+	seen := make(map[string]int, len(rows))
+	emails := make([]string, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		row.Name = strings.TrimSpace(row.Name)
+		row.Email = strings.TrimSpace(row.Email)
+		row.UserType = strings.ToUpper(strings.TrimSpace(row.UserType))
+		row.Role = strings.ToUpper(strings.TrimSpace(row.Role))
+
+		switch {
+		case row.Name == "":
+			reject(i, row.Email, "name is required")
+			continue
+		case row.Email == "":
+			reject(i, "", "email is required")
+			continue
+		case row.UserType != "DEMO" && row.UserType != "PERMANENT":
+			reject(i, row.Email, "user_type must be DEMO or PERMANENT")
+			continue
+		case row.Role != "" && row.Role != "USER" && row.Role != "ADMIN":
+			reject(i, row.Email, "role must be USER or ADMIN")
+			continue
+		case row.Password != "" && len(row.Password) < 6:
+			reject(i, row.Email, "password must be at least 6 characters")
+			continue
+		case row.MaxSearchesPerDay < 0 || row.MaxExportsPerDay < 0:
+			reject(i, row.Email, "limits cannot be negative")
+			continue
+		}
+		if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email {
+			reject(i, row.Email, "invalid email address")
+			continue
+		}
+		if _, err := normalizeSearchFields(row.AllowedSearchFields); err != nil {
+			reject(i, row.Email, err.Error())
+			continue
+		}
+
+		key := strings.ToLower(row.Email)
+		if first, ok := seen[key]; ok {
+			reject(i, row.Email, fmt.Sprintf("email is repeated from row %d", first))
+			continue
+		}
+		seen[key] = i + 1
+		emails = append(emails, key)
+	}
+
+	var taken []string
+	query := `SELECT LOWER(email) FROM users WHERE LOWER(email) = ANY($1)`
+	if err := database.PostgresDB.Select(&taken, query, pq.Array(emails)); err != nil {
+		return nil, fmt.Errorf("failed to check existing users: %w", err)
+	}
+	for _, email := range taken {
+		row := seen[email]
+		reject(row-1, rows[row-1].Email, emailTakenMessage)
+	}
+	sort.Slice(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+
+	return rowErrors, nil
+}
+
+// ParseBulkUsersCSV reads the users of a bulk request from a CSV with a header row. name, email and
+// user_type columns are required; password, role, expires_at (RFC 3339 or YYYY-MM-DD),
+// max_searches_per_day, max_exports_per_day, allowed_search_fields (separated by ";") and quota_exempt
+// are optional.
+func ParseBulkUsersCSV(r io.Reader) ([]models.CreateUserRequest, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "email", "user_type"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header must include a %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		position, ok := columns[name]
+		if !ok || position >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[position])
+	}
+
+	var users []models.CreateUserRequest
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if len(users) == maxBulkUsers {
+			return nil, ErrBulkUsersTooMany
+		}
+
+		user := models.CreateUserRequest{
+			Name:     field(record, "name"),
+			Email:    field(record, "email"),
+			Password: field(record, "password"),
+			UserType: field(record, "user_type"),
+			Role:     field(record, "role"),
+		}
+		if value := field(record, "expires_at"); value != "" {
+			expiresAt, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				if expiresAt, err = time.Parse("2006-01-02", value); err != nil {
+					return nil, fmt.Errorf("row %d: invalid expires_at %q", row, value)
+				}
+			}
+			user.ExpiresAt = &expiresAt
+		}
+		for name, limit := range map[string]*int{
+			"max_searches_per_day": &user.MaxSearchesPerDay,
+			"max_exports_per_day":  &user.MaxExportsPerDay,
+		} {
+			if value := field(record, name); value != "" {
+				if *limit, err = strconv.Atoi(value); err != nil {
+					return nil, fmt.Errorf("row %d: invalid %s %q", row, name, value)
+				}
+			}
+		}
+		if value := field(record, "allowed_search_fields"); value != "" {
+			user.AllowedSearchFields = strings.Split(value, ";")
+		}
+		if value := field(record, "quota_exempt"); value != "" {
+			if user.QuotaExempt, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("row %d: invalid quota_exempt %q", row, value)
+			}
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// generatePassword returns a random password for a user created without one
+func generatePassword() (string, error) {
+	alphabetSize := big.NewInt(int64(len(generatedPasswordAlphabet)))
+	password := make([]byte, generatedPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		password[i] = generatedPasswordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
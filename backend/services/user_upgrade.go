@@ -0,0 +1,139 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrUserNotDemo    = errors.New("only DEMO users can be upgraded")
+	ErrInvalidUpgrade = errors.New("invalid upgrade")
+)
+
+// UpgradeUser turns a DEMO user into a PERMANENT one in a single transaction: the type changes,
+// the expiry is cleared, the new daily limits apply and, unless history is preserved, the searches
+// made during the demo are removed. Existing sessions stay signed in and pick up the new account
+// on their next request.
+func (s *AuthService) UpgradeUser(userID uuid.UUID, req *models.UpgradeUserRequest, upgradedBy *uuid.UUID) (*models.UpgradeUserResponse, error) {
+	maxSearches := config.Get().Limits.MaxSearchesPerDay
+	if req.MaxSearchesPerDay != nil {
+		maxSearches = *req.MaxSearchesPerDay
+	}
+	maxExports := config.Get().Limits.MaxExportsPerDay
+	if req.MaxExportsPerDay != nil {
+		maxExports = *req.MaxExportsPerDay
+	}
+	if maxSearches < 1 || maxExports < 0 {
+		return nil, fmt.Errorf("%w: max_searches_per_day must be at least 1 and max_exports_per_day at least 0", ErrInvalidUpgrade)
+	}
+	preserveHistory := req.PreserveHistory == nil || *req.PreserveHistory
+
+	tx, err := database.PostgresDB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start upgrade: %w", err)
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	if err := tx.Get(&user, `SELECT * FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if user.UserType != "DEMO" {
+		return nil, ErrUserNotDemo
+	}
+
+	upgrade := models.UserUpgrade{
+		UserID:                    userID,
+		PreviousUserType:          user.UserType,
+		NewUserType:               "PERMANENT",
+		PreviousExpiresAt:         user.ExpiresAt,
+		PreviousMaxSearchesPerDay: user.MaxSearchesPerDay,
+		PreviousMaxExportsPerDay:  user.MaxExportsPerDay,
+		MaxSearchesPerDay:         maxSearches,
+		MaxExportsPerDay:          maxExports,
+		HistoryPreserved:          preserveHistory,
+		UpgradedBy:                upgradedBy,
+	}
+	if notes := strings.TrimSpace(req.Notes); notes != "" {
+		upgrade.Notes = &notes
+	}
+
+	updateQuery := `UPDATE users
+					SET user_type = 'PERMANENT', expires_at = NULL, is_active = true,
+						max_searches_per_day = $2, max_exports_per_day = $3, updated_at = now()
+					WHERE id = $1`
+	if _, err := tx.Exec(updateQuery, userID, maxSearches, maxExports); err != nil {
+		return nil, fmt.Errorf("failed to upgrade user: %w", err)
+	}
+
+	if !preserveHistory {
+		// Exports keep their files; their search_id is cleared by the foreign key
+		result, err := tx.Exec(`DELETE FROM searches WHERE user_id = $1`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove demo search history: %w", err)
+		}
+		removed, _ := result.RowsAffected()
+		upgrade.SearchesRemoved = int(removed)
+
+		// Today's usage was counted against the demo quota
+		if _, err := tx.Exec(`DELETE FROM daily_usage WHERE user_id = $1 AND date = $2`, userID, CurrentQuotaDate()); err != nil {
+			return nil, fmt.Errorf("failed to reset daily usage: %w", err)
+		}
+	}
+
+	insertQuery := `INSERT INTO user_upgrades
+					(user_id, previous_user_type, new_user_type, previous_expires_at,
+					 previous_max_searches_per_day, previous_max_exports_per_day,
+					 max_searches_per_day, max_exports_per_day, history_preserved,
+					 searches_removed, notes, upgraded_by)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+					RETURNING id, upgraded_at`
+	err = tx.QueryRow(insertQuery,
+		upgrade.UserID, upgrade.PreviousUserType, upgrade.NewUserType, upgrade.PreviousExpiresAt,
+		upgrade.PreviousMaxSearchesPerDay, upgrade.PreviousMaxExportsPerDay,
+		upgrade.MaxSearchesPerDay, upgrade.MaxExportsPerDay, upgrade.HistoryPreserved,
+		upgrade.SearchesRemoved, upgrade.Notes, upgrade.UpgradedBy).Scan(&upgrade.ID, &upgrade.UpgradedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record upgrade: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit upgrade: %w", err)
+	}
+
+	// Cached sessions carry the old type, expiry and limits
+	sessionCache.invalidateUser(userID)
+
+	upgraded, err := s.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.LogInfo(fmt.Sprintf("Upgraded user %s from DEMO to PERMANENT (history preserved: %t, %d searches removed)",
+		upgraded.Email, preserveHistory, upgrade.SearchesRemoved))
+	NewNotificationService().NotifyUserUpgraded(upgraded)
+	return &models.UpgradeUserResponse{User: upgraded, Upgrade: &upgrade}, nil
+}
+
+// GetUserUpgrades returns the upgrades of a user, newest first
+func (s *AuthService) GetUserUpgrades(userID uuid.UUID) ([]models.UserUpgrade, error) {
+	upgrades := []models.UserUpgrade{}
+	query := `SELECT * FROM user_upgrades WHERE user_id = $1 ORDER BY upgraded_at DESC`
+	if err := database.PostgresDB.Select(&upgrades, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user upgrades: %w", err)
+	}
+	return upgrades, nil
+}
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"finone-search-system/config"
+	"finone-search-system/database"
+	"finone-search-system/models"
+	"finone-search-system/utils"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Webhook events
+const (
+	WebhookEventRegistrationReceived = "registration.received"
+	WebhookEventImportCompleted      = "import.completed"
+	WebhookEventExportCompleted      = "export.completed"
+	WebhookEventQuotaExceeded        = "quota.exceeded"
+	WebhookEventLoginNewDevice       = "login.new_device"
+)
+
+// webhookEvents lists the events a webhook can subscribe to; "*" subscribes to all of them
+var webhookEvents = map[string]bool{
+	WebhookEventRegistrationReceived: true,
+	WebhookEventImportCompleted:      true,
+	WebhookEventExportCompleted:      true,
+	WebhookEventQuotaExceeded:        true,
+	WebhookEventLoginNewDevice:       true,
+	"*":                              true,
+}
+
+// quotaExceededReported remembers which users already triggered a quota.exceeded event for a
+// quota day, so repeated rejected requests send a single event
+var quotaExceededReported sync.Map
+
+type WebhookService struct {
+	db         *sqlx.DB
+	httpClient *http.Client
+}
+
+func NewWebhookService() *WebhookService {
+	return &WebhookService{
+		db:         database.PostgresDB,
+		httpClient: &http.Client{},
+	}
+}
+
+// GetWebhooks returns all registered webhooks (admin only)
+func (s *WebhookService) GetWebhooks() ([]models.Webhook, error) {
+	webhooks := []models.Webhook{}
+	if err := s.db.Select(&webhooks, `SELECT * FROM webhooks ORDER BY created_at DESC`); err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a single webhook
+func (s *WebhookService) GetWebhook(id uuid.UUID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := s.db.Get(&webhook, `SELECT * FROM webhooks WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("webhook not found")
+	}
+	return &webhook, nil
+}
+
+// CreateWebhook registers a webhook, generating a signing secret when none is given (admin only)
+func (s *WebhookService) CreateWebhook(req *models.CreateWebhookRequest, adminID uuid.UUID) (*models.CreateWebhookResponse, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	var webhook models.Webhook
+	query := `INSERT INTO webhooks (url, secret, events, created_by)
+	          VALUES ($1, $2, $3, $4)
+	          RETURNING *`
+	if err := s.db.Get(&webhook, query, req.URL, secret, pq.StringArray(events), adminID); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return &models.CreateWebhookResponse{Webhook: webhook, Secret: secret}, nil
+}
+
+// UpdateWebhook changes a webhook's URL, secret, events or active state (admin only)
+func (s *WebhookService) UpdateWebhook(id uuid.UUID, req *models.UpdateWebhookRequest) (*models.Webhook, error) {
+	updates := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		updates = append(updates, fmt.Sprintf("url = $%d", argIndex))
+		args = append(args, *req.URL)
+		argIndex++
+	}
+	if req.Secret != nil {
+		if *req.Secret == "" {
+			return nil, fmt.Errorf("secret cannot be empty")
+		}
+		updates = append(updates, fmt.Sprintf("secret = $%d", argIndex))
+		args = append(args, *req.Secret)
+		argIndex++
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, fmt.Sprintf("events = $%d", argIndex))
+		args = append(args, pq.StringArray(events))
+		argIndex++
+	}
+	if req.IsActive != nil {
+		updates = append(updates, fmt.Sprintf("is_active = $%d", argIndex))
+		args = append(args, *req.IsActive)
+		argIndex++
+	}
+
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("no fields to update")
+	}
+
+	updates = append(updates, "updated_at = now()")
+	args = append(args, id)
+	query := fmt.Sprintf(`UPDATE webhooks SET %s WHERE id = $%d RETURNING *`, strings.Join(updates, ", "), argIndex)
+
+	var webhook models.Webhook
+	if err := s.db.Get(&webhook, query, args...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery history (admin only)
+func (s *WebhookService) DeleteWebhook(id uuid.UUID) error {
+	result, err := s.db.Exec(`DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+	return nil
+}
+
+// GetDeliveries returns the most recent deliveries of a webhook (admin only)
+func (s *WebhookService) GetDeliveries(webhookID uuid.UUID, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := []models.WebhookDelivery{}
+	query := `SELECT * FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC LIMIT $2`
+	if err := s.db.Select(&deliveries, query, webhookID, limit); err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RetryDelivery sends a pending or failed delivery again with a fresh set of attempts (admin only)
+func (s *WebhookService) RetryDelivery(webhookID, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery models.WebhookDelivery
+	query := `UPDATE webhook_deliveries SET status = 'PENDING', updated_at = now()
+	          WHERE id = $1 AND webhook_id = $2 AND status != 'DELIVERED'
+	          RETURNING *`
+	if err := s.db.Get(&delivery, query, deliveryID, webhookID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("delivery not found or already delivered")
+		}
+		return nil, fmt.Errorf("failed to retry delivery: %w", err)
+	}
+
+	go s.deliver(webhook, &delivery)
+	return &delivery, nil
+}
+
+// ResumePendingDeliveries restarts deliveries interrupted by a shutdown
+func (s *WebhookService) ResumePendingDeliveries() {
+	var deliveries []models.WebhookDelivery
+	if err := s.db.Select(&deliveries, `SELECT * FROM webhook_deliveries WHERE status = 'PENDING'`); err != nil {
+		utils.LogError("Failed to load pending webhook deliveries", err)
+		return
+	}
+
+	for i := range deliveries {
+		webhook, err := s.GetWebhook(deliveries[i].WebhookID)
+		if err != nil || !webhook.IsActive {
+			continue
+		}
+		go s.deliver(webhook, &deliveries[i])
+	}
+
+	if len(deliveries) > 0 {
+		utils.LogInfo(fmt.Sprintf("Resumed %d pending webhook deliveries", len(deliveries)))
+	}
+}
+
+// Dispatch sends an event to every active webhook subscribed to it. It returns immediately;
+// deliveries are recorded and retried in the background.
+func (s *WebhookService) Dispatch(event string, data interface{}) {
+	go func() {
+		var webhooks []models.Webhook
+		query := `SELECT * FROM webhooks WHERE is_active = true AND ($1 = ANY(events) OR '*' = ANY(events))`
+		if err := s.db.Select(&webhooks, query, event); err != nil {
+			utils.LogError("Failed to load webhooks for event "+event, err)
+			return
+		}
+
+		for i := range webhooks {
+			delivery, err := s.createDelivery(webhooks[i].ID, event, data)
+			if err != nil {
+				utils.LogError("Failed to record webhook delivery", err)
+				continue
+			}
+			go s.deliver(&webhooks[i], delivery)
+		}
+	}()
+}
+
+// DispatchQuotaExceeded sends a quota.exceeded event the first time a user hits a daily quota
+func (s *WebhookService) DispatchQuotaExceeded(userID uuid.UUID, kind string, limit int) {
+	date := CurrentQuotaDate()
+	key := userID.String() + ":" + kind
+	if previous, loaded := quotaExceededReported.Swap(key, date); loaded && previous == date {
+		return
+	}
+
+	s.Dispatch(WebhookEventQuotaExceeded, map[string]interface{}{
+		"user_id": userID,
+		"quota":   kind,
+		"limit":   limit,
+		"date":    date,
+	})
+}
+
+// createDelivery records a pending delivery with the payload that will be posted for it
+func (s *WebhookService) createDelivery(webhookID uuid.UUID, event string, data interface{}) (*models.WebhookDelivery, error) {
+	id := uuid.New()
+	payload, err := json.Marshal(models.WebhookEvent{
+		ID:        id.String(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	var delivery models.WebhookDelivery
+	query := `INSERT INTO webhook_deliveries (id, webhook_id, event, payload)
+	          VALUES ($1, $2, $3, $4)
+	          RETURNING *`
+	if err := s.db.Get(&delivery, query, id, webhookID, event, payload); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// deliver posts a delivery until it succeeds or runs out of attempts, recording every attempt
+func (s *WebhookService) deliver(webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	maxAttempts := config.Get().Webhooks.MaxAttempts
+	backoff := config.Get().Webhooks.RetryBackoff
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		statusCode, err := s.post(webhook, delivery)
+		if err == nil {
+			s.recordAttempt(webhook.ID, delivery.ID, "DELIVERED", statusCode, nil)
+			return
+		}
+
+		status := "PENDING"
+		if attempt == maxAttempts {
+			status = "FAILED"
+		}
+		message := err.Error()
+		s.recordAttempt(webhook.ID, delivery.ID, status, statusCode, &message)
+
+		if attempt == maxAttempts {
+			utils.LogWarning(fmt.Sprintf("Webhook delivery %s to %s failed after %d attempts: %v", delivery.ID, webhook.URL, attempt, err))
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends a delivery's payload once. The body is signed with HMAC-SHA256 over
+// "<timestamp>.<body>" using the webhook secret, so receivers can verify and reject replays.
+func (s *WebhookService) post(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(webhook.Secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(delivery.Payload)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.Get().Webhooks.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FinOne-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID.String())
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordAttempt stores the outcome of a delivery attempt on the delivery and the webhook
+func (s *WebhookService) recordAttempt(webhookID, deliveryID uuid.UUID, status string, statusCode int, lastError *string) {
+	var responseStatus *int
+	if statusCode > 0 {
+		responseStatus = &statusCode
+	}
+
+	query := `UPDATE webhook_deliveries
+	          SET status = $1, attempts = attempts + 1, response_status = $2, last_error = $3, updated_at = now(),
+	              delivered_at = CASE WHEN $1 = 'DELIVERED' THEN now() ELSE delivered_at END
+	          WHERE id = $4`
+	if _, err := s.db.Exec(query, status, responseStatus, lastError, deliveryID); err != nil {
+		utils.LogError("Failed to record webhook delivery attempt", err)
+	}
+
+	if status == "PENDING" {
+		return
+	}
+	query = `UPDATE webhooks SET last_delivery_status = $1, last_delivery_at = now() WHERE id = $2`
+	if _, err := s.db.Exec(query, status, webhookID); err != nil {
+		utils.LogError("Failed to record webhook delivery status", err)
+	}
+}
+
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("at least one event is required")
+	}
+
+	normalized := make([]string, 0, len(events))
+	seen := map[string]bool{}
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !webhookEvents[event] {
+			return nil, fmt.Errorf("unknown webhook event: %s", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized, nil
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+
+	"finone-search-system/models"
+)
+
+// maxTrackedUnmappedCircles caps the distinct unmapped circle values an import counts
+const maxTrackedUnmappedCircles = 1000
+
+// maxReportedUnmappedCircles caps the unmapped circle values returned in an import response
+const maxReportedUnmappedCircles = 100
+
+// CircleMap normalizes circle values, e.g. "DL" and "Delhi NCR" to "DELHI". Aliases and circles are
+// compared in the form NormalizeCircleKey returns.
+type CircleMap struct {
+	aliases map[string]string   // Alias to circle
+	circles map[string][]string // Circle to its aliases
+}
+
+// NewCircleMap builds a circle map from aliases keyed by the alias
+func NewCircleMap(aliases map[string]string) *CircleMap {
+	m := &CircleMap{aliases: make(map[string]string, len(aliases)), circles: make(map[string][]string)}
+	for alias, circle := range aliases {
+		alias, circle = NormalizeCircleKey(alias), NormalizeCircleKey(circle)
+		m.aliases[alias] = circle
+		m.circles[circle] = append(m.circles[circle], alias)
+	}
+	for _, list := range m.circles {
+		sort.Strings(list)
+	}
+	return m
+}
+
+// NormalizeCircleKey upper-cases a circle value and collapses its whitespace
+func NormalizeCircleKey(value string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(value)), " ")
+}
+
+// Len returns the number of aliases in the map
+func (m *CircleMap) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.aliases)
+}
+
+// Normalize returns the circle a value stands for and whether the map knows the value, as an alias
+// or as a circle. Unknown values are returned unchanged.
+func (m *CircleMap) Normalize(value string) (string, bool) {
+	if m == nil {
+		return value, false
+	}
+	key := NormalizeCircleKey(value)
+	if circle, ok := m.aliases[key]; ok {
+		return circle, true
+	}
+	if _, ok := m.circles[key]; ok {
+		return key, true
+	}
+	return value, false
+}
+
+// Variants returns the circle a value stands for followed by every alias of that circle, or nil when
+// the map does not know the value
+func (m *CircleMap) Variants(value string) []string {
+	circle, ok := m.Normalize(value)
+	if !ok {
+		return nil
+	}
+	return append([]string{circle}, m.circles[circle]...)
+}
+
+// SetCircleMap normalizes the circle of every imported row with m. Values m does not know are
+// imported as they are and counted in the response's unmapped_circles.
+func (cp *CSVProcessor) SetCircleMap(m *CircleMap) {
+	cp.circles = m
+	cp.unmappedCircles = make(map[string]int64)
+}
+
+// normalizeCircle replaces a person's circle with the circle it stands for, counting unknown values
+func (cp *CSVProcessor) normalizeCircle(person *models.Person) {
+	if cp.circles == nil || person.Circle == "" {
+		return
+	}
+	circle, ok := cp.circles.Normalize(person.Circle)
+	if ok {
+		person.Circle = circle
+		return
+	}
+
+	key := NormalizeCircleKey(person.Circle)
+	if _, tracked := cp.unmappedCircles[key]; tracked || len(cp.unmappedCircles) < maxTrackedUnmappedCircles {
+		cp.unmappedCircles[key]++
+	}
+}
+
+// unmappedCircleCounts returns the most frequent unmapped circle values seen so far
+func (cp *CSVProcessor) unmappedCircleCounts() []models.FacetCount {
+	if len(cp.unmappedCircles) == 0 {
+		return nil
+	}
+
+	counts := make([]models.FacetCount, 0, len(cp.unmappedCircles))
+	for value, count := range cp.unmappedCircles {
+		counts = append(counts, models.FacetCount{Value: value, Count: uint64(count)})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Value < counts[j].Value
+	})
+	if len(counts) > maxReportedUnmappedCircles {
+		counts = counts[:maxReportedUnmappedCircles]
+	}
+	return counts
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"finone-search-system/models"
+)
+
+// Confidence score weights, adding up to 100. Keep in sync with the backfill in
+// migrations/clickhouse/004_people_confidence.up.sql.
+const (
+	confidenceCompletenessWeight = 40 // Shared evenly by the optional fields that are filled in
+	confidenceMobileWeight       = 25 // Mobile is a valid Indian mobile number
+	confidenceMasterIDWeight     = 20 // Master ID is complete and not masked
+	confidenceRecencyWeight      = 15 // Full within a year of the last update, half within three years
+)
+
+var (
+	nonDigitPattern       = regexp.MustCompile(`\D`)
+	indianMobilePattern   = regexp.MustCompile(`^[6-9]\d{9}$`)
+	masterIDSuffixPattern = regexp.MustCompile(`[A-Za-z]*$`)
+	masterIDDigitsPattern = regexp.MustCompile(`^\d+$`)
+)
+
+// ConfidenceScore rates the quality of a person record from 0 to 100 based on how complete it is,
+// whether its mobile and master ID are valid and how recently it was updated
+func ConfidenceScore(person *models.Person, now time.Time) uint8 {
+	optional := []string{person.Name, person.FName, person.Address, person.Alt, person.Circle, person.Email}
+	filled := 0
+	for _, value := range optional {
+		if strings.TrimSpace(value) != "" {
+			filled++
+		}
+	}
+	score := confidenceCompletenessWeight * filled / len(optional)
+
+	if IsValidMobile(person.Mobile) {
+		score += confidenceMobileWeight
+	}
+	if IsValidMasterID(person.MasterID) {
+		score += confidenceMasterIDWeight
+	}
+
+	switch {
+	case person.UpdatedAt.IsZero():
+	case !person.UpdatedAt.Before(now.AddDate(-1, 0, 0)):
+		score += confidenceRecencyWeight
+	case !person.UpdatedAt.Before(now.AddDate(-3, 0, 0)):
+		score += confidenceRecencyWeight / 2
+	}
+
+	return uint8(score)
+}
+
+// IsValidMobile checks if a number is a 10 digit Indian mobile number, optionally prefixed with 91 or 0
+func IsValidMobile(mobile string) bool {
+	return indianMobilePattern.MatchString(mobileDigits(mobile))
+}
+
+// IsValidMasterID checks if a master ID is valid and not a partial/masked ID
+func IsValidMasterID(masterID string) bool {
+	if masterID == "" {
+		return false
+	}
+
+	// Filter out partial/masked master IDs that contain 'x' characters
+	// These are typically used to mask sensitive data and should not be used for searching
+	if strings.Contains(strings.ToLower(masterID), "x") {
+		return false
+	}
+
+	// Filter out master IDs that are too short (likely partial matches)
+	// Valid master IDs should typically be at least 8-10 characters long
+	if len(masterID) < 8 {
+		return false
+	}
+
+	// Remove any valid suffix characters (letters) from the end, e.g. 718834427584M.
+	// The base part should be all digits
+	baseID := masterIDSuffixPattern.ReplaceAllString(masterID, "")
+	return masterIDDigitsPattern.MatchString(baseID)
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"finone-search-system/models"
+)
+
+// Quality flags stored in the people table's quality_flags column for internally inconsistent rows
+const (
+	QualityFlagPincodeCircleMismatch = "pincode_circle_mismatch" // Address pincode lies outside the circle's states
+	QualityFlagAltEqualsMobile       = "alt_equals_mobile"       // Alternate number repeats the mobile number
+	QualityFlagInvalidEmail          = "invalid_email"           // Email is obviously malformed
+)
+
+// QualityFlags lists every flag the consistency checks can raise
+var QualityFlags = []string{QualityFlagPincodeCircleMismatch, QualityFlagAltEqualsMobile, QualityFlagInvalidEmail}
+
+// Quality filters accepted by searches besides a single flag name
+const (
+	QualityFilterClean   = "clean"   // Rows without any flag
+	QualityFilterFlagged = "flagged" // Rows with at least one flag
+)
+
+var (
+	digitRunPattern      = regexp.MustCompile(`\d+`)
+	nonLetterPattern     = regexp.MustCompile(`[^A-Z]`)
+	plausibleEmailRegexp = `^[^@\s]+@[^@\s]+\.[^@\s]+$`
+	plausibleEmail       = regexp.MustCompile(plausibleEmailRegexp)
+)
+
+// circlePincodePrefixes maps telecom circles, keyed by their upper-cased letters, to the first two
+// pincode digits of the states they cover. Circles that are not listed are not checked.
+var circlePincodePrefixes = map[string][]string{
+	"DELHI":           {"11", "12", "20"}, // Includes the NCR towns of Haryana and UP
+	"DELHINCR":        {"11", "12", "20"},
+	"MUMBAI":          {"40", "41", "42"},
+	"MAHARASHTRA":     {"40", "41", "42", "43", "44"},
+	"KOLKATA":         {"70", "71", "74"},
+	"WESTBENGAL":      {"70", "71", "72", "73", "74"},
+	"WB":              {"70", "71", "72", "73", "74"},
+	"GUJARAT":         {"36", "37", "38", "39"},
+	"ANDHRAPRADESH":   {"50", "51", "52", "53"},
+	"AP":              {"50", "51", "52", "53"},
+	"TELANGANA":       {"50", "51", "52", "53"},
+	"KARNATAKA":       {"56", "57", "58", "59"},
+	"TAMILNADU":       {"60", "61", "62", "63", "64"},
+	"TN":              {"60", "61", "62", "63", "64"},
+	"CHENNAI":         {"60", "61", "62", "63", "64"},
+	"KERALA":          {"67", "68", "69"},
+	"PUNJAB":          {"14", "15", "16"},
+	"HARYANA":         {"12", "13"},
+	"UPEAST":          {"20", "21", "22", "23", "24", "25", "26", "27", "28"},
+	"UPWEST":          {"20", "21", "22", "23", "24", "25", "26", "27", "28"},
+	"UTTARPRADESH":    {"20", "21", "22", "23", "24", "25", "26", "27", "28"},
+	"UP":              {"20", "21", "22", "23", "24", "25", "26", "27", "28"},
+	"RAJASTHAN":       {"30", "31", "32", "33", "34"},
+	"MADHYAPRADESH":   {"45", "46", "47", "48", "49"},
+	"MP":              {"45", "46", "47", "48", "49"},
+	"HIMACHALPRADESH": {"17"},
+	"HP":              {"17"},
+	"BIHAR":           {"80", "81", "82", "83", "84", "85"},
+	"ODISHA":          {"75", "76", "77"},
+	"ORISSA":          {"75", "76", "77"},
+	"ASSAM":           {"78"},
+	"NORTHEAST":       {"78", "79"},
+	"NE":              {"78", "79"},
+	"JAMMUKASHMIR":    {"18", "19"},
+	"JK":              {"18", "19"},
+}
+
+// CheckConsistency returns the quality flags raised by a person record. Empty fields are never
+// flagged; missing data is reflected in the confidence score instead.
+func CheckConsistency(person *models.Person) []string {
+	flags := []string{}
+
+	if pincode := AddressPincode(person.Address); pincode != "" {
+		if prefixes, ok := circlePincodePrefixes[circleKey(person.Circle)]; ok && !hasPrefix(prefixes, pincode[:2]) {
+			flags = append(flags, QualityFlagPincodeCircleMismatch)
+		}
+	}
+
+	if alt := mobileDigits(person.Alt); alt != "" && alt == mobileDigits(person.Mobile) {
+		flags = append(flags, QualityFlagAltEqualsMobile)
+	}
+
+	if email := strings.TrimSpace(person.Email); email != "" && !plausibleEmail.MatchString(email) {
+		flags = append(flags, QualityFlagInvalidEmail)
+	}
+
+	return flags
+}
+
+// AddressPincode returns the first run of exactly six digits in an address, matching the people
+// table's materialized pincode column
+func AddressPincode(address string) string {
+	for _, digits := range digitRunPattern.FindAllString(address, -1) {
+		if len(digits) == 6 {
+			return digits
+		}
+	}
+	return ""
+}
+
+// IsValidQualityFilter reports whether a search quality filter is empty, clean, flagged or a known flag
+func IsValidQualityFilter(filter string) bool {
+	switch filter {
+	case "", QualityFilterClean, QualityFilterFlagged:
+		return true
+	}
+	return isQualityFlag(filter)
+}
+
+// MatchesQualityFilter applies a search quality filter to a row's flags
+func MatchesQualityFilter(flags []string, filter string) bool {
+	switch filter {
+	case "":
+		return true
+	case QualityFilterClean:
+		return len(flags) == 0
+	case QualityFilterFlagged:
+		return len(flags) > 0
+	}
+	for _, flag := range flags {
+		if flag == filter {
+			return true
+		}
+	}
+	return false
+}
+
+// QualityFilterSQL returns the ClickHouse condition for a search quality filter, or "" for no filter.
+// Only validated filters are inlined, so the result is safe to embed in a query.
+func QualityFilterSQL(filter string) string {
+	switch filter {
+	case QualityFilterClean:
+		return "empty(quality_flags)"
+	case QualityFilterFlagged:
+		return "notEmpty(quality_flags)"
+	}
+	if isQualityFlag(filter) {
+		return fmt.Sprintf("has(quality_flags, '%s')", filter)
+	}
+	return ""
+}
+
+// QualityFlagsSQL returns a ClickHouse expression computing the same flags as CheckConsistency, used
+// to revalidate rows already in the people table
+func QualityFlagsSQL() string {
+	const digits = `replaceRegexpOne(replaceRegexpAll(%s, '\\D', ''), '^(?:91|0)(\\d{10})$', '\\1')`
+
+	circles := make([]string, 0, len(circlePincodePrefixes))
+	for circle := range circlePincodePrefixes {
+		circles = append(circles, circle)
+	}
+	sort.Strings(circles)
+
+	var mismatch strings.Builder
+	mismatch.WriteString("multiIf(pincode = '', 0")
+	for _, circle := range circles {
+		fmt.Fprintf(&mismatch, ", upper(replaceRegexpAll(circle, '[^A-Za-z]', '')) = '%s', substring(pincode, 1, 2) NOT IN ('%s')",
+			circle, strings.Join(circlePincodePrefixes[circle], "', '"))
+	}
+	mismatch.WriteString(", 0)")
+
+	altDigits := fmt.Sprintf(digits, "alt")
+	return fmt.Sprintf("arrayFilter(x -> x != '', [if(%s, '%s', ''), if(%s != '' AND %s = %s, '%s', ''), if(trim(email) != '' AND NOT match(trim(email), '%s'), '%s', '')])",
+		mismatch.String(), QualityFlagPincodeCircleMismatch,
+		altDigits, altDigits, fmt.Sprintf(digits, "mobile"), QualityFlagAltEqualsMobile,
+		strings.ReplaceAll(plausibleEmailRegexp, `\`, `\\`), QualityFlagInvalidEmail)
+}
+
+func isQualityFlag(value string) bool {
+	for _, flag := range QualityFlags {
+		if flag == value {
+			return true
+		}
+	}
+	return false
+}
+
+func circleKey(circle string) string {
+	return nonLetterPattern.ReplaceAllString(strings.ToUpper(circle), "")
+}
+
+func hasPrefix(prefixes []string, prefix string) bool {
+	for _, p := range prefixes {
+		if p == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+var emailTag = regexp.MustCompile(`\+[^@]*@`)
+
+// NormalizeEmail lower-cases an email and drops a +tag from its local part, so the addresses of one
+// mailbox compare equal
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if loc := emailTag.FindStringIndex(email); loc != nil {
+		email = email[:loc[0]] + "@" + email[loc[1]:]
+	}
+	return email
+}
+
+// NormalizedEmailSQL returns the ClickHouse expression normalizing the email column like NormalizeEmail
+func NormalizedEmailSQL() string {
+	return `replaceRegexpOne(lower(trimBoth(email)), '\\+[^@]*@', '@')`
+}
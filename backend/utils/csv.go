@@ -4,12 +4,14 @@ import (
 	"bufio"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
 	"time"
 
+	"finone-search-system/config"
 	"finone-search-system/database"
 	"finone-search-system/models"
 
@@ -21,8 +23,45 @@ type CSVProcessor struct {
 	batchSize int
 	tempDir   string
 	fieldMap  map[string]int
+	minFields int       // Columns a record needs to cover every mapped field
+	format    CSVFormat // Delimiter and encoding, detected when unset, see SetFormat
+	customMap bool      // The field map was set with SetFieldMap rather than defaulted
+
+	workbook bool   // The source is an Excel workbook, see SetWorkbook
+	sheet    string // Sheet of the workbook to import; the first sheet when empty
+	table    string // Fully qualified ClickHouse table the rows are inserted into
+
+	jobID      string // Stamped on every row as import_job_id, so the import can be rolled back
+	sourceFile string
+
+	updateExisting bool // Replace rows with the same master ID and mobile, see SetUpdateExisting
+	delta          bool // Insert new rows and stage changed ones for review, see SetDelta
+
+	circles         *CircleMap       // Normalizes circle values, see SetCircleMap
+	unmappedCircles map[string]int64 // Circle values the map does not know, with their row counts
+
+	// Rows that could not be imported are written to the job's error file, see recordErrorRows
+	sourceHeader    []string
+	errorFile       *os.File
+	errorWriter     *csv.Writer
+	errorFileFailed bool
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	progress ImportProgressFunc
 }
 
+// ImportProgressFunc is called as an import runs with the response so far and the bytes of the
+// source read
+type ImportProgressFunc func(response *models.CSVImportResponse, bytesRead int64)
+
+// progressRows is how often, in rows read, progress is reported between batches
+const progressRows = 10000
+
+// maxReportedErrors caps the error messages returned in an import response
+const maxReportedErrors = 100
+
 // NewCSVProcessor creates a new CSV processor instance
 func NewCSVProcessor(batchSize int, tempDir string) *CSVProcessor {
 	// Default field mapping based on your plan
@@ -41,11 +80,103 @@ func NewCSVProcessor(batchSize int, tempDir string) *CSVProcessor {
 		batchSize: batchSize,
 		tempDir:   tempDir,
 		fieldMap:  defaultFieldMap,
+		minFields: 8,
+		table:     database.PeopleTable(),
+		jobID:     uuid.New().String(),
+
+		maxRetries:   config.Get().CSV.MaxRetries,
+		retryBackoff: config.Get().CSV.RetryBackoff,
+	}
+}
+
+// SetTargetTable imports into another people table in the finone_search database, e.g. a rebuilt
+// table that is switched over to once loaded. The table must already exist with the people columns.
+func (cp *CSVProcessor) SetTargetTable(name string) error {
+	if !database.ValidTableName(name) {
+		return fmt.Errorf("invalid table name: %s", name)
+	}
+	cp.table = database.QualifiedTable(name)
+	return nil
+}
+
+// TargetTable returns the fully qualified table the processor inserts into
+func (cp *CSVProcessor) TargetTable() string {
+	return cp.table
+}
+
+// SetSource sets the job ID and source file recorded on every imported row, e.g. the ID of the
+// import's audit record and the file it read
+func (cp *CSVProcessor) SetSource(jobID, sourceFile string) {
+	cp.jobID = jobID
+	cp.sourceFile = sourceFile
+}
+
+// SetProgressFunc reports the progress of the import to fn after every batch and every progressRows rows
+func (cp *CSVProcessor) SetProgressFunc(fn ImportProgressFunc) {
+	cp.progress = fn
+}
+
+// JobID returns the job ID stamped on the imported rows
+func (cp *CSVProcessor) JobID() string {
+	return cp.jobID
+}
+
+// SetFormat sets the delimiter (comma, tab or pipe) and encoding (UTF-8, ISO-8859-1 or
+// Windows-1252) the source is read with. Values left empty or set to "auto" are detected from the
+// start of the source.
+func (cp *CSVProcessor) SetFormat(delimiter, encoding string) error {
+	format, err := ParseCSVFormat(delimiter, encoding)
+	if err != nil {
+		return err
+	}
+	cp.format = format
+	return nil
+}
+
+// SetWorkbook reads the source file as an Excel (.xlsx) workbook instead of CSV, importing the named
+// sheet or the first sheet when sheet is empty. The first row of the sheet is its header, and unless
+// a field map is set, columns are mapped to fields by their header names.
+func (cp *CSVProcessor) SetWorkbook(sheet string) {
+	cp.workbook = true
+	cp.sheet = sheet
+}
+
+// SetFieldMap overrides the default column layout, e.g. with a map suggested by ProfileCSV.
+// Fields left out of the map are imported as empty values.
+func (cp *CSVProcessor) SetFieldMap(fieldMap map[string]int) error {
+	if len(fieldMap) == 0 {
+		return fmt.Errorf("field map is empty")
+	}
+
+	for field, position := range fieldMap {
+		if _, ok := importFieldTypes[field]; !ok {
+			return fmt.Errorf("unknown field in field map: %s", field)
+		}
+		if position < 0 {
+			return fmt.Errorf("invalid column position for field %s: %d", field, position)
+		}
 	}
+
+	if _, ok := fieldMap["mobile"]; !ok {
+		return fmt.Errorf("field map must include mobile")
+	}
+
+	cp.fieldMap = fieldMap
+	cp.customMap = true
+	cp.minFields = 0
+	for _, position := range fieldMap {
+		if position+1 > cp.minFields {
+			cp.minFields = position + 1
+		}
+	}
+	return nil
 }
 
-// ProcessCSVFile processes a large CSV file in batches
+// ProcessCSVFile processes a large CSV file, or a workbook set with SetWorkbook, in batches
 func (cp *CSVProcessor) ProcessCSVFile(filePath string, hasHeader bool) (*models.CSVImportResponse, error) {
+	if cp.workbook {
+		return cp.processWorkbook(filePath)
+	}
 	LogInfo(fmt.Sprintf("Starting CSV processing for file: %s", filePath))
 
 	file, err := os.Open(filePath)
@@ -54,27 +185,123 @@ func (cp *CSVProcessor) ProcessCSVFile(filePath string, hasHeader bool) (*models
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.Comma = ','
-	reader.LazyQuotes = true
+	return cp.ProcessCSVReader(file, hasHeader)
+}
+
+// ProcessCSVReader processes a CSV stream in batches, e.g. a remote object read without staging it on disk
+func (cp *CSVProcessor) ProcessCSVReader(r io.Reader, hasHeader bool) (*models.CSVImportResponse, error) {
+	if cp.workbook {
+		return nil, fmt.Errorf("workbooks can only be imported from a file")
+	}
+
+	source := &byteCounter{r: r}
+	reader, format, err := NewCSVReader(source, cp.format)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.CSVImportResponse{
+		JobID:     cp.jobID,
+		Status:    "processing",
+		Table:     cp.table,
+		Delimiter: DelimiterName(format.Delimiter),
+		Encoding:  format.Encoding,
+		StartTime: time.Now(),
+	}
+	return cp.processRecords(reader, response, hasHeader, func() int64 { return source.n })
+}
+
+// processWorkbook imports a sheet of an Excel workbook
+func (cp *CSVProcessor) processWorkbook(filePath string) (*models.CSVImportResponse, error) {
+	LogInfo(fmt.Sprintf("Starting workbook processing for file: %s", filePath))
+
+	sheet, err := cp.openWorkbook(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer sheet.Close()
 
 	response := &models.CSVImportResponse{
-		JobID:     uuid.New().String(),
+		JobID:     cp.jobID,
 		Status:    "processing",
+		Table:     cp.table,
+		Sheet:     sheet.name,
 		StartTime: time.Now(),
 	}
+	return cp.processRecords(sheet, response, false, sheet.BytesRead)
+}
+
+// openWorkbook opens the sheet to import and reads its header row, mapping columns to fields by their
+// header names unless a field map was set
+func (cp *CSVProcessor) openWorkbook(filePath string) (*xlsxSheet, error) {
+	sheet, err := openXLSXSheet(filePath, cp.sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := sheet.Read()
+	if err != nil {
+		sheet.Close()
+		if err == io.EOF {
+			return nil, fmt.Errorf("sheet %q is empty", sheet.name)
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	cp.sourceHeader = header
+
+	if !cp.customMap {
+		fieldMap, err := HeaderFieldMap(header)
+		if err == nil {
+			err = cp.SetFieldMap(fieldMap)
+		}
+		if err != nil {
+			sheet.Close()
+			return nil, err
+		}
+	}
+	return sheet, nil
+}
+
+// recordReader reads the records of an import source: a csv.Reader or a workbook sheet
+type recordReader interface {
+	Read() ([]string, error)
+	FieldPos(field int) (line, column int)
+}
+
+// processRecords imports the records of a source in batches. bytesRead reports how much of the
+// source has been read, for progress.
+func (cp *CSVProcessor) processRecords(reader recordReader, response *models.CSVImportResponse, hasHeader bool, bytesRead func() int64) (*models.CSVImportResponse, error) {
+	importedAt := response.StartTime.Truncate(time.Second)
+	if cp.delta {
+		response.Delta = &models.DeltaImportStats{}
+	}
 
 	var batch []models.Person
+	var rows []sourceRow // The source records of the batch
 	lineCount := 0
 	errorCount := 0
+	reportProgress := func() {
+		if cp.progress != nil {
+			response.ErrorRows = errorCount
+			cp.progress(response, bytesRead())
+		}
+	}
 
 	// Skip header if present
 	if hasHeader {
-		if _, err := reader.Read(); err != nil {
+		header, err := reader.Read()
+		if err != nil {
 			return nil, fmt.Errorf("failed to read header: %w", err)
 		}
+		cp.sourceHeader = header
 	}
 
+	// A rerun of the job starts a new error file
+	if err := os.Remove(ImportErrorFilePath(cp.jobID)); err != nil && !os.IsNotExist(err) {
+		LogWarning(fmt.Sprintf("Failed to remove the previous error file of import %s: %v", cp.jobID, err))
+	}
+	defer cp.closeErrorFile()
+
 	for {
 		record, err := reader.Read()
 		if err == io.EOF {
@@ -83,28 +310,41 @@ func (cp *CSVProcessor) ProcessCSVFile(filePath string, hasHeader bool) (*models
 		if err != nil {
 			errorCount++
 			LogError("Failed to read CSV record", err)
+			line := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+			}
+			cp.recordErrorRows([]sourceRow{{line: line, record: record}}, err.Error())
 			continue
 		}
+		line, _ := reader.FieldPos(0)
 
 		person, err := cp.recordToPerson(record)
 		if err != nil {
 			errorCount++
 			LogError("Failed to convert record to person", err)
+			cp.recordErrorRows([]sourceRow{{line: line, record: record}}, err.Error())
 			continue
 		}
+		person.SourceFile = cp.sourceFile
+		person.ImportJobID = cp.jobID
+		person.ImportedAt = &importedAt
 
 		batch = append(batch, *person)
+		rows = append(rows, sourceRow{line: line, record: record})
 		lineCount++
 
 		// Process batch when it reaches the batch size
 		if len(batch) >= cp.batchSize {
-			if err := cp.insertBatch(batch); err != nil {
-				LogError("Failed to insert batch", err)
-				errorCount += len(batch)
-			} else {
-				response.ProcessedRows += len(batch)
-			}
+			failed := cp.importBatch(batch, rows, response)
+			errorCount += failed
+			response.ProcessedRows += len(batch) - failed
 			batch = batch[:0] // Clear the batch
+			rows = rows[:0]
+			reportProgress()
+		} else if lineCount%progressRows == 0 {
+			reportProgress()
 		}
 
 		// Log progress every 50,000 rows
@@ -115,49 +355,126 @@ func (cp *CSVProcessor) ProcessCSVFile(filePath string, hasHeader bool) (*models
 
 	// Process remaining records in the final batch
 	if len(batch) > 0 {
-		if err := cp.insertBatch(batch); err != nil {
-			LogError("Failed to insert final batch", err)
-			errorCount += len(batch)
-		} else {
-			response.ProcessedRows += len(batch)
-		}
+		failed := cp.importBatch(batch, rows, response)
+		errorCount += failed
+		response.ProcessedRows += len(batch) - failed
 	}
+	response.ErrorFile = cp.closeErrorFile()
 
 	endTime := time.Now()
 	response.EndTime = &endTime
 	response.TotalRows = lineCount
 	response.ErrorRows = errorCount
+	response.UnmappedCircles = cp.unmappedCircleCounts()
 	response.Status = "completed"
 
-	LogInfo(fmt.Sprintf("CSV processing completed. Total: %d, Processed: %d, Errors: %d",
-		response.TotalRows, response.ProcessedRows, response.ErrorRows))
+	LogInfo(fmt.Sprintf("CSV processing completed. Total: %d, Processed: %d, Updated: %d, Errors: %d",
+		response.TotalRows, response.ProcessedRows, response.UpdatedRows, response.ErrorRows))
 
 	return response, nil
 }
 
 // recordToPerson converts a CSV record to a Person model
 func (cp *CSVProcessor) recordToPerson(record []string) (*models.Person, error) {
-	if len(record) < 8 {
+	if len(record) < cp.minFields {
 		return nil, fmt.Errorf("record has insufficient fields: %d", len(record))
 	}
 
+	mobile, alt := cp.field(record, "mobile"), cp.field(record, "alt")
 	person := &models.Person{
 		ID:        uuid.New().String(),
-		Mobile:    strings.TrimSpace(record[cp.fieldMap["mobile"]]),
-		Name:      strings.TrimSpace(record[cp.fieldMap["name"]]),
-		FName:     strings.TrimSpace(record[cp.fieldMap["fname"]]),
-		Address:   strings.TrimSpace(record[cp.fieldMap["address"]]),
-		Alt:       strings.TrimSpace(record[cp.fieldMap["alt"]]),
-		Circle:    strings.TrimSpace(record[cp.fieldMap["circle"]]),
-		MasterID:  strings.TrimSpace(record[cp.fieldMap["id"]]),
-		Email:     strings.TrimSpace(record[cp.fieldMap["email"]]),
+		Mobile:    NormalizeMobile(mobile),
+		MobileRaw: mobile,
+		Name:      cp.field(record, "name"),
+		FName:     cp.field(record, "fname"),
+		Address:   cp.field(record, "address"),
+		Alt:       NormalizeMobile(alt),
+		AltRaw:    alt,
+		Circle:    cp.field(record, "circle"),
+		MasterID:  cp.field(record, "id"),
+		Email:     cp.field(record, "email"),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	cp.normalizeCircle(person)
+	person.Confidence = ConfidenceScore(person, person.UpdatedAt)
+	person.QualityFlags = CheckConsistency(person)
 
 	return person, nil
 }
 
+// field returns the trimmed value of a mapped field, or an empty string if the field is not mapped
+func (cp *CSVProcessor) field(record []string, name string) string {
+	position, ok := cp.fieldMap[name]
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(record[position])
+}
+
+// insertBatchResilient inserts a batch, retrying transient failures with backoff. A batch that
+// ClickHouse rejects is split in half repeatedly to isolate the poison rows, so only rows that
+// are rejected on their own are counted as errors and written to the error file with their source
+// rows. Returns the number of rows not inserted.
+func (cp *CSVProcessor) insertBatchResilient(batch []models.Person, rows []sourceRow, response *models.CSVImportResponse) int {
+	err := cp.insertWithRetry(batch)
+	if err == nil {
+		return 0
+	}
+
+	// Retries are exhausted: the rows are fine but ClickHouse is not, so splitting cannot help
+	if database.IsTransientError(err) {
+		LogError(fmt.Sprintf("Failed to insert batch of %d rows after %d retries", len(batch), cp.maxRetries), err)
+		cp.addError(response, fmt.Sprintf("batch of %d rows failed: %v", len(batch), err))
+		cp.recordErrorRows(rows, fmt.Sprintf("batch failed: %v", err))
+		return len(batch)
+	}
+
+	if len(batch) == 1 {
+		LogError("Row rejected by ClickHouse (mobile: "+batch[0].Mobile+")", err)
+		cp.addError(response, fmt.Sprintf("row rejected (mobile %s): %v", batch[0].Mobile, err))
+		cp.recordErrorRows(rows, fmt.Sprintf("row rejected: %v", err))
+		return 1
+	}
+
+	mid := len(batch) / 2
+	return cp.insertBatchResilient(batch[:mid], rows[:mid], response) + cp.insertBatchResilient(batch[mid:], rows[mid:], response)
+}
+
+// insertWithRetry inserts a batch, retrying with exponential backoff while errors are transient
+func (cp *CSVProcessor) insertWithRetry(batch []models.Person) error {
+	return cp.withRetry(len(batch), func() error { return cp.insertBatch(batch) })
+}
+
+// withRetry runs a write of a batch of rows, retrying with exponential backoff while errors are transient
+func (cp *CSVProcessor) withRetry(rows int, write func() error) error {
+	backoff := cp.retryBackoff
+
+	var err error
+	for attempt := 0; attempt <= cp.maxRetries; attempt++ {
+		if attempt > 0 {
+			LogWarning(fmt.Sprintf("Retrying batch of %d rows (attempt %d/%d) after transient error: %v",
+				rows, attempt, cp.maxRetries, err))
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		err = write()
+		if err == nil || !database.IsTransientError(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// addError records an error message on the import response, up to maxReportedErrors
+func (cp *CSVProcessor) addError(response *models.CSVImportResponse, message string) {
+	if len(response.Errors) < maxReportedErrors {
+		response.Errors = append(response.Errors, message)
+	}
+}
+
 // insertBatch inserts a batch of people into ClickHouse
 func (cp *CSVProcessor) insertBatch(batch []models.Person) error {
 	if len(batch) == 0 {
@@ -168,8 +485,9 @@ func (cp *CSVProcessor) insertBatch(batch []models.Person) error {
 
 	// Prepare batch insert statement
 	batchInsert, err := database.ClickHouseDB.PrepareBatch(ctx,
-		`INSERT INTO finone_search.people
-		(id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at)`)
+		`INSERT INTO `+cp.table+`
+		(id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at, confidence, quality_flags,
+		 source_file, import_job_id, imported_at, mobile_raw, alt_raw)`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare batch: %w", err)
 	}
@@ -188,6 +506,13 @@ func (cp *CSVProcessor) insertBatch(batch []models.Person) error {
 			person.Email,
 			person.CreatedAt,
 			person.UpdatedAt,
+			person.Confidence,
+			person.QualityFlags,
+			person.SourceFile,
+			person.ImportJobID,
+			person.ImportedAt,
+			person.MobileRaw,
+			person.AltRaw,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to append to batch: %w", err)
@@ -270,3 +595,15 @@ func FormatFileSize(bytes int64) string {
 	}
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
+
+// byteCounter counts the bytes read through it
+type byteCounter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *byteCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
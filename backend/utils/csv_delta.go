@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+)
+
+// SetDelta makes the import a delta import: rows whose mobile and master ID match no existing row are
+// inserted, rows identical to the existing row are skipped, and changed rows are staged in the people
+// staging table for review instead of being written. The response breaks the rows down in Delta.
+func (cp *CSVProcessor) SetDelta(delta bool) {
+	cp.delta = delta
+}
+
+// deltaBatch inserts the new rows of a batch and stages its changed rows. Returns the number of rows
+// neither inserted, staged nor skipped as unchanged.
+func (cp *CSVProcessor) deltaBatch(batch []models.Person, rows []sourceRow, response *models.CSVImportResponse) int {
+	existing, err := cp.existingRows(batch)
+	if err != nil {
+		// Without the lookup every row would look new, so the batch is not imported
+		LogError(fmt.Sprintf("Failed to look up existing rows for a batch of %d rows", len(batch)), err)
+		cp.addError(response, fmt.Sprintf("batch of %d rows failed: %v", len(batch), err))
+		cp.recordErrorRows(rows, fmt.Sprintf("failed to look up existing rows: %v", err))
+		return len(batch)
+	}
+
+	var added, changed []models.Person
+	var addedRows, changedRows []sourceRow
+	var changedFields [][]string
+	for i, person := range batch {
+		current, ok := existing[deltaKey(person.MasterID, person.Mobile)]
+		if !ok {
+			added = append(added, person)
+			addedRows = append(addedRows, rows[i])
+			continue
+		}
+
+		fields := cp.changedFields(current, person)
+		if len(fields) == 0 {
+			response.Delta.Unchanged++
+			continue
+		}
+		person.ID = current.ID
+		person.CreatedAt = current.CreatedAt
+		changed = append(changed, person)
+		changedRows = append(changedRows, rows[i])
+		changedFields = append(changedFields, fields)
+	}
+
+	failed := 0
+	if len(added) > 0 {
+		failed = cp.insertBatchResilient(added, addedRows, response)
+		response.Delta.Added += len(added) - failed
+	}
+
+	if len(changed) > 0 {
+		if err := cp.withRetry(len(changed), func() error { return cp.stageBatch(changed, changedFields) }); err != nil {
+			LogError(fmt.Sprintf("Failed to stage %d changed rows", len(changed)), err)
+			cp.addError(response, fmt.Sprintf("failed to stage %d changed rows: %v", len(changed), err))
+			cp.recordErrorRows(changedRows, fmt.Sprintf("failed to stage changed row: %v", err))
+			return failed + len(changed)
+		}
+		response.Delta.Changed += len(changed)
+	}
+	return failed
+}
+
+// deltaKey identifies a row by master ID and canonical mobile
+func deltaKey(masterID, mobile string) string {
+	return masterID + "\x00" + NormalizeMobile(mobile)
+}
+
+// existingRows looks up the rows of the table with the master ID and mobile of each batch row, by
+// deltaKey. Mobiles are matched in every variant, so rows imported before numbers were normalized are
+// found too. Where several rows share a key the first one found is used.
+func (cp *CSVProcessor) existingRows(batch []models.Person) (map[string]models.Person, error) {
+	keys := make(map[string]bool, len(batch))
+	var masterIDs []string
+	var mobiles [][]string // The variants of each key's mobile
+	for _, person := range batch {
+		key := deltaKey(person.MasterID, person.Mobile)
+		if keys[key] {
+			continue
+		}
+		keys[key] = true
+		masterIDs = append(masterIDs, person.MasterID)
+		if variants := MobileVariants(person.Mobile); variants != nil {
+			mobiles = append(mobiles, variants)
+		} else {
+			mobiles = append(mobiles, []string{person.Mobile})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	existing := make(map[string]models.Person)
+	for start := 0; start < len(masterIDs); start += updateLookupKeys {
+		end := min(start+updateLookupKeys, len(masterIDs))
+		var chunkMobiles []string
+		for _, variants := range mobiles[start:end] {
+			chunkMobiles = append(chunkMobiles, variants...)
+		}
+
+		// Matching master IDs and mobiles separately can return pairs from different rows, which are
+		// filtered out by the key lookup
+		masterIDList, args := database.InList(masterIDs[start:end])
+		mobileList, mobileArgs := database.InList(chunkMobiles)
+		args = append(append(args, mobileArgs...), cp.jobID)
+		rows, err := database.ClickHouseDB.Query(ctx,
+			`SELECT toString(id), master_id, mobile, name, fname, address, alt, circle, email, created_at FROM `+cp.table+`
+			 WHERE master_id IN `+masterIDList+` AND mobile IN `+mobileList+` AND import_job_id != ?`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up existing rows: %w", err)
+		}
+		for rows.Next() {
+			var p models.Person
+			if err := rows.Scan(&p.ID, &p.MasterID, &p.Mobile, &p.Name, &p.FName, &p.Address, &p.Alt, &p.Circle, &p.Email, &p.CreatedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan existing row: %w", err)
+			}
+			key := deltaKey(p.MasterID, p.Mobile)
+			if _, seen := existing[key]; keys[key] && !seen {
+				existing[key] = p
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing rows: %w", err)
+		}
+	}
+	return existing, nil
+}
+
+// changedFields lists the fields in which an incoming row differs from the existing row. Alt numbers
+// and circles are compared in their normalized forms, so values written differently do not count as
+// changes.
+func (cp *CSVProcessor) changedFields(current, incoming models.Person) []string {
+	var fields []string
+	compare := func(field, a, b string) {
+		if a != b {
+			fields = append(fields, field)
+		}
+	}
+	compare("name", current.Name, incoming.Name)
+	compare("fname", current.FName, incoming.FName)
+	compare("address", current.Address, incoming.Address)
+	compare("alt", NormalizeMobile(current.Alt), NormalizeMobile(incoming.Alt))
+	currentCircle, _ := cp.circles.Normalize(current.Circle)
+	compare("circle", currentCircle, incoming.Circle)
+	compare("email", current.Email, incoming.Email)
+	return fields
+}
+
+// stageBatch writes changed rows to the staging table with the fields that changed
+func (cp *CSVProcessor) stageBatch(batch []models.Person, changedFields [][]string) error {
+	ctx := context.Background()
+
+	batchInsert, err := database.ClickHouseDB.PrepareBatch(ctx,
+		`INSERT INTO `+database.PeopleStagingTable+`
+		(id, master_id, mobile, name, fname, address, alt, circle, email, created_at, updated_at, confidence, quality_flags,
+		 source_file, import_job_id, imported_at, mobile_raw, alt_raw, people_table, changed_fields)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare staging batch: %w", err)
+	}
+
+	for i, person := range batch {
+		err := batchInsert.Append(
+			person.ID,
+			person.MasterID,
+			person.Mobile,
+			person.Name,
+			person.FName,
+			person.Address,
+			person.Alt,
+			person.Circle,
+			person.Email,
+			person.CreatedAt,
+			person.UpdatedAt,
+			person.Confidence,
+			person.QualityFlags,
+			person.SourceFile,
+			person.ImportJobID,
+			person.ImportedAt,
+			person.MobileRaw,
+			person.AltRaw,
+			cp.table,
+			changedFields[i],
+		)
+		if err != nil {
+			return fmt.Errorf("failed to append to staging batch: %w", err)
+		}
+	}
+
+	return batchInsert.Send()
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"finone-search-system/config"
+)
+
+// sourceRow is a CSV record and the line it starts on. The rows of a batch are kept alongside it, so
+// rows that fail to insert can be written to the import's error file as they appeared in the source.
+type sourceRow struct {
+	line   int
+	record []string
+}
+
+// ImportErrorFilePath returns where the error file of an import job is written
+func ImportErrorFilePath(jobID string) string {
+	return filepath.Join(config.Get().CSV.ErrorDir, jobID+".errors.csv")
+}
+
+// recordErrorRows writes rows the import could not load to its error file with the reason, creating
+// the file on the first failed row so imports without errors leave none behind. A file that cannot be
+// written is logged once and the import carries on without it.
+func (cp *CSVProcessor) recordErrorRows(rows []sourceRow, reason string) {
+	if cp.errorFileFailed || len(rows) == 0 {
+		return
+	}
+
+	if cp.errorWriter == nil {
+		if err := cp.createErrorFile(); err != nil {
+			LogError(fmt.Sprintf("Failed to create the error file of import %s", cp.jobID), err)
+			cp.errorFileFailed = true
+			return
+		}
+	}
+
+	for _, row := range rows {
+		line := ""
+		if row.line > 0 {
+			line = fmt.Sprint(row.line)
+		}
+		if err := cp.errorWriter.Write(append([]string{line, reason}, row.record...)); err != nil {
+			LogError(fmt.Sprintf("Failed to write the error file of import %s", cp.jobID), err)
+			cp.errorFileFailed = true
+			return
+		}
+	}
+}
+
+// createErrorFile creates the error file with a header of the line, the error and the source columns:
+// the source's own header if it had one, or else the mapped field names
+func (cp *CSVProcessor) createErrorFile() error {
+	path := ImportErrorFilePath(cp.jobID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	columns := cp.sourceHeader
+	if columns == nil {
+		columns = make([]string, cp.minFields)
+		for i := range columns {
+			columns[i] = fmt.Sprintf("column_%d", i+1)
+		}
+		for field, position := range cp.fieldMap {
+			columns[position] = field
+		}
+	}
+
+	cp.errorFile = file
+	cp.errorWriter = csv.NewWriter(file)
+	return cp.errorWriter.Write(append([]string{"line", "error"}, columns...))
+}
+
+// closeErrorFile flushes and closes the error file, if the import wrote one, and reports whether the
+// file holds every failed row
+func (cp *CSVProcessor) closeErrorFile() bool {
+	if cp.errorFile == nil {
+		return false
+	}
+
+	cp.errorWriter.Flush()
+	if err := cp.errorWriter.Error(); err != nil && !cp.errorFileFailed {
+		LogError(fmt.Sprintf("Failed to write the error file of import %s", cp.jobID), err)
+		cp.errorFileFailed = true
+	}
+	if err := cp.errorFile.Close(); err != nil && !cp.errorFileFailed {
+		LogError(fmt.Sprintf("Failed to close the error file of import %s", cp.jobID), err)
+		cp.errorFileFailed = true
+	}
+	cp.errorFile, cp.errorWriter = nil, nil
+	return !cp.errorFileFailed
+}
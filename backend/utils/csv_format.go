@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Encodings a CSV import can be read in
+const (
+	EncodingUTF8        = "UTF-8"
+	EncodingISO88591    = "ISO-8859-1"
+	EncodingWindows1252 = "Windows-1252"
+)
+
+// formatSampleSize is how much of the start of a source is read to detect its delimiter and encoding
+const formatSampleSize = 64 * 1024
+
+// formatSampleLines is how many lines of the sample the delimiter is detected from
+const formatSampleLines = 20
+
+// csvDelimiters are the delimiters an import accepts, in the order a tie is settled
+var csvDelimiters = []rune{',', '\t', '|'}
+
+var delimiterNames = map[string]rune{
+	",": ',', "comma": ',',
+	"\t": '\t', `\t`: '\t', "tab": '\t',
+	"|": '|', "pipe": '|',
+}
+
+var encodingNames = map[string]string{
+	"utf-8": EncodingUTF8, "utf8": EncodingUTF8,
+	"iso-8859-1": EncodingISO88591, "iso8859-1": EncodingISO88591, "latin1": EncodingISO88591, "latin-1": EncodingISO88591,
+	"windows-1252": EncodingWindows1252, "cp1252": EncodingWindows1252,
+}
+
+// CSVFormat is the delimiter and encoding of a CSV source. A zero delimiter or an empty encoding is
+// detected from the start of the source when it is read.
+type CSVFormat struct {
+	Delimiter rune
+	Encoding  string
+}
+
+// ParseCSVFormat validates a delimiter (comma, tab or pipe, by name or character) and an encoding
+// (UTF-8, ISO-8859-1 or Windows-1252). Empty values and "auto" are left to detection.
+func ParseCSVFormat(delimiter, encoding string) (CSVFormat, error) {
+	var format CSVFormat
+
+	if delimiter != "" && !strings.EqualFold(delimiter, "auto") {
+		r, ok := delimiterNames[strings.ToLower(delimiter)]
+		if !ok {
+			return format, fmt.Errorf("unsupported delimiter %q: use comma, tab or pipe", delimiter)
+		}
+		format.Delimiter = r
+	}
+
+	if encoding != "" && !strings.EqualFold(encoding, "auto") {
+		name, ok := encodingNames[strings.ToLower(encoding)]
+		if !ok {
+			return format, fmt.Errorf("unsupported encoding %q: use UTF-8, ISO-8859-1 or Windows-1252", encoding)
+		}
+		format.Encoding = name
+	}
+
+	return format, nil
+}
+
+// DelimiterName returns the name a delimiter is reported by: comma, tab or pipe
+func DelimiterName(delimiter rune) string {
+	switch delimiter {
+	case '\t':
+		return "tab"
+	case '|':
+		return "pipe"
+	default:
+		return "comma"
+	}
+}
+
+// NewCSVReader returns a CSV reader over r in the given format, detecting the delimiter and encoding
+// that are not set from the first 64 KB, together with the format it reads. Sources that are not
+// UTF-8 are transcoded to UTF-8 as they are read, and a UTF-8 byte order mark is skipped.
+func NewCSVReader(r io.Reader, format CSVFormat) (*csv.Reader, CSVFormat, error) {
+	buffered := bufio.NewReaderSize(r, formatSampleSize)
+	sample, err := buffered.Peek(formatSampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, format, fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	if format.Encoding == "" {
+		format.Encoding = detectEncoding(sample)
+	}
+	if format.Delimiter == 0 {
+		format.Delimiter = detectDelimiter(sample)
+	}
+
+	var source io.Reader = buffered
+	switch format.Encoding {
+	case EncodingISO88591:
+		source = charmap.ISO8859_1.NewDecoder().Reader(buffered)
+	case EncodingWindows1252:
+		source = charmap.Windows1252.NewDecoder().Reader(buffered)
+	default:
+		if bytes.HasPrefix(sample, []byte("\xef\xbb\xbf")) {
+			buffered.Discard(3)
+		}
+	}
+
+	reader := csv.NewReader(source)
+	reader.Comma = format.Delimiter
+	reader.LazyQuotes = true
+	return reader, format, nil
+}
+
+// detectEncoding reports UTF-8 for a sample that is valid UTF-8, ignoring a rune cut off at its end,
+// and Windows-1252 otherwise, which reads ISO-8859-1 text the same apart from its control characters
+func detectEncoding(sample []byte) string {
+	end := len(sample)
+	for i := 1; i < utf8.UTFMax && i <= len(sample); i++ {
+		if utf8.RuneStart(sample[len(sample)-i]) {
+			if !utf8.FullRune(sample[len(sample)-i:]) {
+				end = len(sample) - i
+			}
+			break
+		}
+	}
+	if utf8.Valid(sample[:end]) {
+		return EncodingUTF8
+	}
+	return EncodingWindows1252
+}
+
+// detectDelimiter picks the delimiter found most often on every line of the sample, ignoring
+// delimiters inside quotes and a last line that may be cut off. Comma wins ties and samples
+// with none of them.
+func detectDelimiter(sample []byte) rune {
+	lines := strings.Split(string(sample), "\n")
+	if len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > formatSampleLines {
+		lines = lines[:formatSampleLines]
+	}
+
+	best, bestCount := ',', 0
+	for _, delimiter := range csvDelimiters {
+		count := -1
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if n := countUnquoted(line, delimiter); count < 0 || n < count {
+				count = n
+			}
+		}
+		if count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+// countUnquoted counts a delimiter on a line outside double quoted fields
+func countUnquoted(line string, delimiter rune) int {
+	count := 0
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == delimiter && !quoted:
+			count++
+		}
+	}
+	return count
+}
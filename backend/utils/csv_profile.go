@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"finone-search-system/models"
+)
+
+// ImportFields lists the person fields a CSV column can be mapped to, in assignment priority order
+var ImportFields = []string{"mobile", "email", "address", "name", "id", "circle", "alt", "fname"}
+
+// importFieldTypes maps each import field to the value type it is detected by
+var importFieldTypes = map[string]string{
+	"mobile":  "mobile",
+	"alt":     "mobile",
+	"name":    "name",
+	"fname":   "name",
+	"address": "address",
+	"circle":  "circle",
+	"id":      "id",
+	"email":   "email",
+}
+
+// headerHints maps normalized header names to the field they usually hold
+var headerHints = map[string]string{
+	"mobile": "mobile", "mobileno": "mobile", "phone": "mobile", "phoneno": "mobile", "msisdn": "mobile", "contact": "mobile",
+	"alt": "alt", "altmobile": "alt", "alternate": "alt", "alternatemobile": "alt", "altphone": "alt",
+	"name": "name", "fullname": "name", "customername": "name",
+	"fname": "fname", "father": "fname", "fathername": "fname", "fathersname": "fname",
+	"address": "address", "addr": "address", "fulladdress": "address",
+	"circle": "circle", "region": "circle", "state": "circle",
+	"id": "id", "masterid": "id", "customerid": "id",
+	"email": "email", "emailid": "email", "mail": "email",
+}
+
+var (
+	mobilePattern = regexp.MustCompile(`^(\+?91|0)?[6-9]\d{9}$`)
+	emailPattern  = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	namePattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z .']{0,59}$`)
+	idPattern     = regexp.MustCompile(`^[A-Za-z0-9_-]*\d[A-Za-z0-9_-]*$`)
+	headerCleaner = regexp.MustCompile(`[^a-z]`)
+)
+
+const (
+	profileSampleValues   = 5
+	profileDistinctCap    = 10000
+	minSuggestionScore    = 0.5
+	headerSuggestionBonus = 0.5
+)
+
+// ProfileCSV samples up to sampleSize rows of a CSV stream, infers which columns look like
+// mobiles, names, emails and addresses, and suggests a field map for the import. The delimiter and
+// encoding are detected unless set in format.
+func ProfileCSV(r io.Reader, hasHeader bool, sampleSize int, format CSVFormat) (*models.CSVProfile, error) {
+	reader, format, err := NewCSVReader(r, format)
+	if err != nil {
+		return nil, err
+	}
+	reader.FieldsPerRecord = -1
+
+	profile := &models.CSVProfile{
+		HasHeader:         hasHeader,
+		Delimiter:         DelimiterName(format.Delimiter),
+		Encoding:          format.Encoding,
+		SuggestedFieldMap: make(map[string]int),
+	}
+
+	var headers []string
+	if hasHeader {
+		record, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		headers = record
+	}
+
+	var rows [][]string
+	for len(rows) < sampleSize {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		rows = append(rows, record)
+		if len(record) > profile.ColumnCount {
+			profile.ColumnCount = len(record)
+		}
+	}
+	if len(headers) > profile.ColumnCount {
+		profile.ColumnCount = len(headers)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV file has no data rows to profile")
+	}
+	profile.SampledRows = len(rows)
+
+	for col := 0; col < profile.ColumnCount; col++ {
+		column := profileColumn(rows, col)
+		if col < len(headers) {
+			column.Header = strings.TrimSpace(headers[col])
+		}
+		profile.Columns = append(profile.Columns, column)
+	}
+
+	suggestFieldMap(profile)
+	return profile, nil
+}
+
+// HeaderFieldMap maps columns to import fields by their header names, e.g. "Mobile No" to mobile,
+// taking the first column for each field. It fails when no column maps to mobile.
+func HeaderFieldMap(headers []string) (map[string]int, error) {
+	fieldMap := make(map[string]int)
+	for i, header := range headers {
+		field, ok := headerHints[headerCleaner.ReplaceAllString(strings.ToLower(header), "")]
+		if !ok {
+			continue
+		}
+		if _, taken := fieldMap[field]; !taken {
+			fieldMap[field] = i
+		}
+	}
+	if _, ok := fieldMap["mobile"]; !ok {
+		return nil, fmt.Errorf("no column header maps to mobile; headers: %s", strings.Join(headers, ", "))
+	}
+	return fieldMap, nil
+}
+
+// profileColumn computes statistics and type scores for a single column of the sample
+func profileColumn(rows [][]string, col int) models.CSVColumnProfile {
+	column := models.CSVColumnProfile{
+		Index:        col,
+		SampleValues: []string{},
+		TypeScores:   make(map[string]float64),
+	}
+
+	distinct := make(map[string]bool)
+	counts := make(map[string]int)
+	totalLength := 0
+
+	for _, row := range rows {
+		if col >= len(row) {
+			continue
+		}
+		value := strings.TrimSpace(row[col])
+		if value == "" {
+			continue
+		}
+
+		column.FilledCount++
+		totalLength += len(value)
+		if len(distinct) < profileDistinctCap {
+			distinct[value] = true
+		}
+		if len(column.SampleValues) < profileSampleValues {
+			column.SampleValues = append(column.SampleValues, value)
+		}
+
+		for _, valueType := range detectValueTypes(value) {
+			counts[valueType]++
+		}
+	}
+
+	column.DistinctCount = len(distinct)
+	if column.FilledCount == 0 {
+		return column
+	}
+	column.AvgLength = float64(totalLength) / float64(column.FilledCount)
+
+	// Scores are relative to every sampled row so sparsely filled columns rank lower
+	for valueType, count := range counts {
+		column.TypeScores[valueType] = float64(count) / float64(len(rows))
+	}
+
+	// Circles are short names repeated across many rows
+	if name := column.TypeScores["name"]; name > 0 && column.FilledCount >= 20 &&
+		float64(column.DistinctCount)/float64(column.FilledCount) <= 0.1 && column.AvgLength <= 30 {
+		column.TypeScores["circle"] = name
+	}
+
+	return column
+}
+
+// detectValueTypes returns the value types a single cell looks like
+func detectValueTypes(value string) []string {
+	var types []string
+
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(value)
+	if mobilePattern.MatchString(digits) {
+		types = append(types, "mobile")
+	} else if idPattern.MatchString(value) {
+		types = append(types, "id")
+	}
+
+	if emailPattern.MatchString(value) {
+		types = append(types, "email")
+	}
+
+	if namePattern.MatchString(value) && len(strings.Fields(value)) <= 5 {
+		types = append(types, "name")
+	}
+
+	if len(value) >= 15 && strings.Contains(value, " ") && strings.ContainsAny(value, ",0123456789") {
+		types = append(types, "address")
+	}
+
+	return types
+}
+
+// suggestFieldMap greedily assigns each field to its best scoring column. Header names that match
+// a known field add a bonus, and primary fields (mobile, name) win ties over alt and fname.
+func suggestFieldMap(profile *models.CSVProfile) {
+	type candidate struct {
+		field    string
+		priority int
+		column   int
+		score    float64
+	}
+
+	var candidates []candidate
+	for priority, field := range ImportFields {
+		for _, column := range profile.Columns {
+			score := column.TypeScores[importFieldTypes[field]]
+			// Low cardinality name-like columns are far more likely to be circles than people's names
+			if importFieldTypes[field] == "name" && column.TypeScores["circle"] > 0 {
+				score /= 2
+			}
+			if headerHints[headerCleaner.ReplaceAllString(strings.ToLower(column.Header), "")] == field {
+				score += headerSuggestionBonus
+			}
+			if score >= minSuggestionScore {
+				candidates = append(candidates, candidate{field, priority, column.Index, score})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority < candidates[j].priority
+		}
+		return candidates[i].column < candidates[j].column
+	})
+
+	usedColumns := make(map[int]bool)
+	for _, c := range candidates {
+		if _, assigned := profile.SuggestedFieldMap[c.field]; assigned || usedColumns[c.column] {
+			continue
+		}
+		profile.SuggestedFieldMap[c.field] = c.column
+		profile.Columns[c.column].SuggestedField = c.field
+		usedColumns[c.column] = true
+	}
+
+	for _, field := range ImportFields {
+		if _, assigned := profile.SuggestedFieldMap[field]; !assigned {
+			profile.UnmappedFields = append(profile.UnmappedFields, field)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"finone-search-system/database"
+	"finone-search-system/models"
+)
+
+// updateLookupKeys is how many master ID and mobile pairs are looked up per query when an import
+// updates existing rows
+const updateLookupKeys = 1000
+
+// SetUpdateExisting makes the import replace rows that have the same master ID and mobile as an
+// imported row instead of adding a second row. The imported row keeps the ID and creation time of
+// the row it replaces, and the replaced row is kept in the people history. Rows without a master ID
+// are always added.
+func (cp *CSVProcessor) SetUpdateExisting(update bool) {
+	cp.updateExisting = update
+}
+
+// importBatch inserts a batch, first adopting the IDs of the rows it updates when updateExisting is
+// set and then archiving and removing those rows. A delta import is handed to deltaBatch. Returns the
+// number of rows not inserted.
+func (cp *CSVProcessor) importBatch(batch []models.Person, rows []sourceRow, response *models.CSVImportResponse) int {
+	if cp.delta {
+		return cp.deltaBatch(batch, rows, response)
+	}
+	if !cp.updateExisting {
+		return cp.insertBatchResilient(batch, rows, response)
+	}
+
+	replaced, err := cp.adoptExistingIDs(batch)
+	if err != nil {
+		// Without the lookup the rows would be added as duplicates, so the batch is not inserted
+		LogError(fmt.Sprintf("Failed to look up existing rows for a batch of %d rows", len(batch)), err)
+		cp.addError(response, fmt.Sprintf("batch of %d rows failed: %v", len(batch), err))
+		cp.recordErrorRows(rows, fmt.Sprintf("failed to look up existing rows: %v", err))
+		return len(batch)
+	}
+
+	failed := cp.insertBatchResilient(batch, rows, response)
+	if len(replaced) == 0 || failed == len(batch) {
+		return failed
+	}
+
+	// Only rows whose replacement was inserted are superseded
+	if failed > 0 {
+		inserted, err := cp.insertedIDs(replaced)
+		if err != nil {
+			LogError("Failed to check which updated rows were inserted", err)
+			cp.addError(response, fmt.Sprintf("failed to replace %d updated rows: %v", len(replaced), err))
+			return failed
+		}
+		replaced = inserted
+	}
+
+	if err := cp.supersede(replaced); err != nil {
+		LogError(fmt.Sprintf("Failed to replace %d updated rows", len(replaced)), err)
+		cp.addError(response, fmt.Sprintf("failed to replace %d updated rows: %v", len(replaced), err))
+		return failed
+	}
+	response.UpdatedRows += len(replaced)
+	return failed
+}
+
+// adoptExistingIDs gives each row of the batch that matches an existing row by master ID and mobile
+// the ID and creation time of that row, and returns the adopted IDs. Only the first row of the batch
+// with a given master ID and mobile updates the existing row.
+func (cp *CSVProcessor) adoptExistingIDs(batch []models.Person) ([]string, error) {
+	pending := make(map[string]int) // master ID and mobile to the batch row that updates them
+	var keys []string
+	for i, person := range batch {
+		if person.MasterID == "" {
+			continue
+		}
+		key := person.MasterID + "\x00" + person.Mobile
+		if _, ok := pending[key]; !ok {
+			pending[key] = i
+			keys = append(keys, key)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var adopted []string
+	for start := 0; start < len(keys); start += updateLookupKeys {
+		end := min(start+updateLookupKeys, len(keys))
+		masterIDs := make([]string, 0, end-start)
+		mobiles := make([]string, 0, end-start)
+		for _, key := range keys[start:end] {
+			person := batch[pending[key]]
+			masterIDs = append(masterIDs, person.MasterID)
+			mobiles = append(mobiles, person.Mobile)
+		}
+
+		// Matching master IDs and mobiles separately can return pairs from different rows, which are
+		// filtered out by the pending lookup
+		masterIDList, args := database.InList(masterIDs)
+		mobileList, mobileArgs := database.InList(mobiles)
+		args = append(append(args, mobileArgs...), cp.jobID)
+		rows, err := database.ClickHouseDB.Query(ctx,
+			`SELECT toString(id), master_id, mobile, created_at FROM `+cp.table+`
+			 WHERE master_id IN `+masterIDList+` AND mobile IN `+mobileList+` AND import_job_id != ?`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up existing rows: %w", err)
+		}
+		for rows.Next() {
+			var id, masterID, mobile string
+			var createdAt time.Time
+			if err := rows.Scan(&id, &masterID, &mobile, &createdAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan existing row: %w", err)
+			}
+			key := masterID + "\x00" + mobile
+			i, ok := pending[key]
+			if !ok {
+				continue
+			}
+			delete(pending, key)
+			batch[i].ID = id
+			batch[i].CreatedAt = createdAt
+			adopted = append(adopted, id)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read existing rows: %w", err)
+		}
+	}
+	return adopted, nil
+}
+
+// insertedIDs returns which of ids this import has inserted rows for
+func (cp *CSVProcessor) insertedIDs(ids []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	idList, args := database.InList(ids)
+	rows, err := database.ClickHouseDB.Query(ctx,
+		`SELECT DISTINCT toString(id) FROM `+cp.table+` WHERE id IN `+idList+` AND import_job_id = ?`, append(args, cp.jobID)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up inserted rows: %w", err)
+	}
+	defer rows.Close()
+
+	var inserted []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan inserted row: %w", err)
+		}
+		inserted = append(inserted, id)
+	}
+	return inserted, rows.Err()
+}
+
+// supersede archives the previous versions of the updated rows and removes them from the table,
+// leaving the rows this import inserted with the same IDs
+func (cp *CSVProcessor) supersede(ids []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := database.ArchivePeopleRows(ctx, cp.table, ids, models.PersonChangeImport, cp.jobID, cp.jobID); err != nil {
+		return err
+	}
+	idList, args := database.InList(ids)
+	if err := database.ClickHouseDB.Exec(ctx, `DELETE FROM `+cp.table+` WHERE id IN `+idList+` AND import_job_id != ?`, append(args, cp.jobID)...); err != nil {
+		return fmt.Errorf("failed to delete updated rows: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"finone-search-system/models"
+)
+
+// validationDistinctCap bounds the values tracked per field for duplicate detection, so a dry run of
+// a very large file keeps a fixed memory footprint
+const validationDistinctCap = 2000000
+
+// validationMalformedSamples is how many malformed values are reported per field
+const validationMalformedSamples = 5
+
+// fieldLengthLimits is the longest value expected in each field; longer values are reported as outliers
+var fieldLengthLimits = map[string]int{
+	"mobile":  13,
+	"alt":     13,
+	"name":    100,
+	"fname":   100,
+	"address": 500,
+	"circle":  50,
+	"id":      40,
+	"email":   254,
+}
+
+// fieldValidation accumulates the statistics of one mapped field during a dry run
+type fieldValidation struct {
+	report      models.CSVFieldValidation
+	totalLength int64
+	seen        map[uint64]struct{}
+}
+
+// DryRun reads a CSV file, or a workbook set with SetWorkbook, the way ProcessCSVFile would, with the
+// same field map, and reports what it would load without inserting anything. sampleRows stops after
+// that many data rows; 0 reads the whole file.
+func (cp *CSVProcessor) DryRun(filePath string, hasHeader bool, sampleRows int) (*models.CSVValidationReport, error) {
+	if cp.workbook {
+		LogInfo(fmt.Sprintf("Starting workbook dry run for file: %s", filePath))
+		sheet, err := cp.openWorkbook(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer sheet.Close()
+		return cp.dryRunRecords(sheet, &models.CSVValidationReport{Sheet: sheet.name}, false, sampleRows)
+	}
+	LogInfo(fmt.Sprintf("Starting CSV dry run for file: %s", filePath))
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	return cp.DryRunReader(file, hasHeader, sampleRows)
+}
+
+// DryRunReader validates a CSV stream like DryRun
+func (cp *CSVProcessor) DryRunReader(r io.Reader, hasHeader bool, sampleRows int) (*models.CSVValidationReport, error) {
+	if cp.workbook {
+		return nil, fmt.Errorf("workbooks can only be validated from a file")
+	}
+
+	reader, format, err := NewCSVReader(r, cp.format)
+	if err != nil {
+		return nil, err
+	}
+	report := &models.CSVValidationReport{
+		Delimiter: DelimiterName(format.Delimiter),
+		Encoding:  format.Encoding,
+	}
+	return cp.dryRunRecords(reader, report, hasHeader, sampleRows)
+}
+
+// dryRunRecords validates the records of a source, filling in report
+func (cp *CSVProcessor) dryRunRecords(reader recordReader, report *models.CSVValidationReport, hasHeader bool, sampleRows int) (*models.CSVValidationReport, error) {
+	start := time.Now()
+	if hasHeader {
+		if _, err := reader.Read(); err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+	}
+
+	report.DryRun = true
+	report.Table = cp.table
+	report.FieldMap = cp.fieldMap
+	report.QualityFlags = make(map[string]int64)
+
+	fields := make(map[string]*fieldValidation, len(cp.fieldMap))
+	for field, column := range cp.fieldMap {
+		fields[field] = &fieldValidation{
+			report: models.CSVFieldValidation{
+				Field:             field,
+				Column:            column,
+				MaxExpectedLength: fieldLengthLimits[field],
+			},
+			seen: make(map[uint64]struct{}),
+		}
+	}
+
+	var confidenceTotal int64
+	for sampleRows <= 0 || report.TotalRows < int64(sampleRows) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		report.TotalRows++
+		if err != nil {
+			report.ErrorRows++
+			cp.addValidationError(report, fmt.Sprintf("row %d: %v", report.TotalRows, err))
+			continue
+		}
+
+		person, err := cp.recordToPerson(record)
+		if err != nil {
+			report.ErrorRows++
+			cp.addValidationError(report, fmt.Sprintf("row %d: %v", report.TotalRows, err))
+			continue
+		}
+		report.ValidRows++
+
+		for field, stats := range fields {
+			stats.observe(field, cp.field(record, field))
+		}
+		confidenceTotal += int64(person.Confidence)
+		for _, flag := range person.QualityFlags {
+			report.QualityFlags[flag]++
+		}
+
+		if report.TotalRows%1000000 == 0 {
+			LogInfo(fmt.Sprintf("Dry run validated %d rows", report.TotalRows))
+		}
+	}
+
+	if sampleRows > 0 && report.TotalRows >= int64(sampleRows) {
+		if _, err := reader.Read(); err != io.EOF {
+			report.Sampled = true
+		}
+	}
+
+	for _, stats := range fields {
+		if stats.report.FilledCount > 0 {
+			stats.report.AvgLength = float64(stats.totalLength) / float64(stats.report.FilledCount)
+			stats.report.DuplicateRatio = float64(stats.report.DuplicateCount) / float64(stats.report.FilledCount)
+		}
+		report.Fields = append(report.Fields, stats.report)
+	}
+	sort.Slice(report.Fields, func(i, j int) bool { return report.Fields[i].Column < report.Fields[j].Column })
+
+	if report.ValidRows > 0 {
+		report.AvgConfidence = float64(confidenceTotal) / float64(report.ValidRows)
+	}
+	report.UnmappedCircles = cp.unmappedCircleCounts()
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+
+	LogInfo(fmt.Sprintf("CSV dry run completed. Total: %d, Valid: %d, Errors: %d",
+		report.TotalRows, report.ValidRows, report.ErrorRows))
+	return report, nil
+}
+
+// observe adds one value of a field to its statistics
+func (v *fieldValidation) observe(field, value string) {
+	stats := &v.report
+	if value == "" {
+		stats.EmptyCount++
+		return
+	}
+
+	stats.FilledCount++
+	length := len(value)
+	v.totalLength += int64(length)
+	if stats.FilledCount == 1 || length < stats.MinLength {
+		stats.MinLength = length
+	}
+	if length > stats.MaxLength {
+		stats.MaxLength = length
+	}
+	if limit := fieldLengthLimits[field]; limit > 0 && length > limit {
+		stats.LongCount++
+	}
+
+	if isMalformed(field, value) {
+		stats.MalformedCount++
+		if len(stats.MalformedSamples) < validationMalformedSamples {
+			stats.MalformedSamples = append(stats.MalformedSamples, value)
+		}
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	key := hash.Sum64()
+	if _, ok := v.seen[key]; ok {
+		stats.DuplicateCount++
+	} else if len(v.seen) < validationDistinctCap {
+		v.seen[key] = struct{}{}
+	} else {
+		stats.DistinctCapped = true
+	}
+}
+
+// isMalformed reports whether a filled mobile or email value fails its format check. Other fields
+// have no fixed format.
+func isMalformed(field, value string) bool {
+	switch importFieldTypes[field] {
+	case "mobile":
+		return !mobilePattern.MatchString(strings.NewReplacer(" ", "", "-", "").Replace(value))
+	case "email":
+		return !emailPattern.MatchString(value)
+	}
+	return false
+}
+
+// addValidationError records an error message on the dry-run report, up to maxReportedErrors
+func (cp *CSVProcessor) addValidationError(report *models.CSVValidationReport, message string) {
+	if len(report.Errors) < maxReportedErrors {
+		report.Errors = append(report.Errors, message)
+	}
+}
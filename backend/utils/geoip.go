@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"finone-search-system/config"
+)
+
+// GeoLocation is where an IP address is, as far as the GeoIP databases know; empty fields are unknown
+type GeoLocation struct {
+	Country string `json:"country,omitempty"` // ISO country code
+	City    string `json:"city,omitempty"`
+	ASN     uint   `json:"asn,omitempty"`    // Autonomous system number of the network
+	ASOrg   string `json:"as_org,omitempty"` // Organization that owns the network, e.g. an ISP or cloud provider
+}
+
+type geoIPDatabases struct {
+	city *MaxMindDB // GeoLite2/GeoIP2 City or Country
+	asn  *MaxMindDB // GeoLite2/GeoIP2 ASN
+}
+
+var geoIP atomic.Pointer[geoIPDatabases]
+
+// InitGeoIP loads the MaxMind databases named in the configuration, replacing any loaded before.
+// Without either path configured, lookups find nothing.
+func InitGeoIP(cfg config.GeoIPConfig) error {
+	dbs := &geoIPDatabases{}
+	var err error
+	if cfg.CityDB != "" {
+		if dbs.city, err = OpenMaxMindDB(cfg.CityDB); err != nil {
+			return fmt.Errorf("failed to open GeoIP city database %s: %w", cfg.CityDB, err)
+		}
+		LogInfo(fmt.Sprintf("Loaded GeoIP database %s (%s)", cfg.CityDB, dbs.city.DatabaseType))
+	}
+	if cfg.ASNDB != "" {
+		if dbs.asn, err = OpenMaxMindDB(cfg.ASNDB); err != nil {
+			return fmt.Errorf("failed to open GeoIP ASN database %s: %w", cfg.ASNDB, err)
+		}
+		LogInfo(fmt.Sprintf("Loaded GeoIP database %s (%s)", cfg.ASNDB, dbs.asn.DatabaseType))
+	}
+	geoIP.Store(dbs)
+	return nil
+}
+
+// LookupGeoIP locates an IP address. Private and loopback addresses, and addresses missing from the
+// databases, have no location.
+func LookupGeoIP(address string) GeoLocation {
+	var location GeoLocation
+	dbs := geoIP.Load()
+	ip := net.ParseIP(strings.TrimSpace(address))
+	if dbs == nil || ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+		return location
+	}
+
+	if dbs.city != nil {
+		record, err := dbs.city.Lookup(ip)
+		if err != nil {
+			LogError("GeoIP city lookup failed", err)
+		}
+		if fields, ok := record.(map[string]interface{}); ok {
+			location.Country = geoIPString(fields, "country", "iso_code")
+			if location.Country == "" {
+				location.Country = geoIPString(fields, "registered_country", "iso_code")
+			}
+			location.City = geoIPString(fields, "city", "names", "en")
+		}
+	}
+	if dbs.asn != nil {
+		record, err := dbs.asn.Lookup(ip)
+		if err != nil {
+			LogError("GeoIP ASN lookup failed", err)
+		}
+		if fields, ok := record.(map[string]interface{}); ok {
+			location.ASN = uint(maxMindUint(fields["autonomous_system_number"]))
+			location.ASOrg, _ = fields["autonomous_system_organization"].(string)
+		}
+	}
+	return location
+}
+
+// geoIPString follows a path of map keys through a decoded record to a string
+func geoIPString(fields map[string]interface{}, path ...string) string {
+	var value interface{} = fields
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	s, _ := value.(string)
+	return s
+}
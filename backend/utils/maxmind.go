@@ -0,0 +1,319 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+	"os"
+)
+
+// maxMindMetadataMarker precedes the metadata at the end of a MaxMind DB file
+var maxMindMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// maxMindDataSeparator is the size of the zero bytes between the search tree and the data section
+const maxMindDataSeparator = 16
+
+// MaxMind DB data types
+const (
+	mmdbExtended  = 0
+	mmdbPointer   = 1
+	mmdbString    = 2
+	mmdbDouble    = 3
+	mmdbBytes     = 4
+	mmdbUint16    = 5
+	mmdbUint32    = 6
+	mmdbMap       = 7
+	mmdbInt32     = 8
+	mmdbUint64    = 9
+	mmdbUint128   = 10
+	mmdbArray     = 11
+	mmdbContainer = 12
+	mmdbEndMarker = 13
+	mmdbBoolean   = 14
+	mmdbFloat     = 15
+)
+
+var errMaxMindCorrupt = errors.New("invalid MaxMind DB data")
+
+// MaxMindDB reads a MaxMind DB (.mmdb) file such as GeoLite2-City or GeoLite2-ASN. The whole file is
+// held in memory and lookups do not allocate beyond the decoded record.
+type MaxMindDB struct {
+	DatabaseType string
+
+	buffer     []byte
+	data       []byte // Data section
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	ipv4Start  uint // Node IPv4 addresses start from in an IPv6 tree
+}
+
+// OpenMaxMindDB loads a MaxMind DB file
+func OpenMaxMindDB(path string) (*MaxMindDB, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMaxMindDB(buffer)
+}
+
+// NewMaxMindDB reads a MaxMind DB from the contents of a file
+func NewMaxMindDB(buffer []byte) (*MaxMindDB, error) {
+	start := bytes.LastIndex(buffer, maxMindMetadataMarker)
+	if start < 0 {
+		return nil, fmt.Errorf("not a MaxMind DB file: metadata not found")
+	}
+	metadataValue, _, err := decodeMaxMind(buffer[start+len(maxMindMetadataMarker):], 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MaxMind DB metadata: %w", err)
+	}
+	metadata, ok := metadataValue.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to read MaxMind DB metadata: %w", errMaxMindCorrupt)
+	}
+
+	db := &MaxMindDB{buffer: buffer}
+	db.DatabaseType, _ = metadata["database_type"].(string)
+	db.nodeCount = uint(maxMindUint(metadata["node_count"]))
+	db.recordSize = uint(maxMindUint(metadata["record_size"]))
+	db.ipVersion = uint(maxMindUint(metadata["ip_version"]))
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported MaxMind DB record size %d", db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported MaxMind DB IP version %d", db.ipVersion)
+	}
+
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+maxMindDataSeparator > uint(start) {
+		return nil, fmt.Errorf("failed to read MaxMind DB search tree: %w", errMaxMindCorrupt)
+	}
+	db.data = buffer[treeSize+maxMindDataSeparator : start]
+
+	// IPv4 addresses are looked up under ::/96 of an IPv6 tree
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			if node, err = db.readNode(node, 0); err != nil {
+				return nil, err
+			}
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// Lookup returns the record of the network containing ip, or nil when the database has none
+func (db *MaxMindDB) Lookup(ip net.IP) (interface{}, error) {
+	node, bitCount := uint(0), 128
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bitCount = ip4, 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return nil, fmt.Errorf("IPv6 address %s looked up in an IPv4 database", ip)
+	}
+	if len(ip)*8 != bitCount {
+		return nil, fmt.Errorf("invalid IP address")
+	}
+
+	var err error
+	for i := 0; i < bitCount && node < db.nodeCount; i++ {
+		bit := uint(ip[i>>3]>>(7-uint(i)%8)) & 1
+		if node, err = db.readNode(node, bit); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case node == db.nodeCount:
+		return nil, nil
+	case node < db.nodeCount:
+		return nil, errMaxMindCorrupt
+	}
+
+	offset := node - db.nodeCount - maxMindDataSeparator
+	if offset >= uint(len(db.data)) {
+		return nil, errMaxMindCorrupt
+	}
+	value, _, err := decodeMaxMind(db.data, offset, 0)
+	return value, err
+}
+
+// readNode returns the left (bit 0) or right (bit 1) record of a search tree node
+func (db *MaxMindDB) readNode(node, bit uint) (uint, error) {
+	base := node * db.recordSize / 4
+	if base+db.recordSize/4 > uint(len(db.buffer)) {
+		return 0, errMaxMindCorrupt
+	}
+	b := db.buffer[base:]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:])), nil
+	}
+}
+
+// decodeMaxMind decodes the value at offset in a data section and returns it with the offset that
+// follows it. Maps decode to map[string]interface{}, arrays to []interface{}, integers to uint64 or
+// int64 (uint128 to *big.Int) and floats to float64.
+func decodeMaxMind(data []byte, offset uint, depth int) (interface{}, uint, error) {
+	if depth > 64 {
+		return nil, 0, errMaxMindCorrupt
+	}
+	if offset >= uint(len(data)) {
+		return nil, 0, errMaxMindCorrupt
+	}
+	ctrl := data[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == mmdbPointer {
+		pointerSize := uint(ctrl>>3)&0x3 + 1
+		if offset+pointerSize > uint(len(data)) {
+			return nil, 0, errMaxMindCorrupt
+		}
+		b := data[offset : offset+pointerSize]
+		prefix := uint(ctrl & 0x7)
+		var target uint
+		switch pointerSize {
+		case 1:
+			target = prefix<<8 | uint(b[0])
+		case 2:
+			target = (prefix<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 3:
+			target = (prefix<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		default:
+			target = uint(binary.BigEndian.Uint32(b))
+		}
+		value, _, err := decodeMaxMind(data, target, depth+1)
+		return value, offset + pointerSize, err
+	}
+
+	if kind == mmdbExtended {
+		if offset >= uint(len(data)) {
+			return nil, 0, errMaxMindCorrupt
+		}
+		kind = 7 + uint(data[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(data)) {
+			return nil, 0, errMaxMindCorrupt
+		}
+		n := uint(0)
+		for _, b := range data[offset : offset+extra] {
+			n = n<<8 | uint(b)
+		}
+		switch size {
+		case 29:
+			size = 29 + n
+		case 30:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+		offset += extra
+	}
+
+	switch kind {
+	case mmdbMap:
+		result := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := decodeMaxMind(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, 0, errMaxMindCorrupt
+			}
+			value, next, err := decodeMaxMind(data, next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			result[keyString] = value
+			offset = next
+		}
+		return result, offset, nil
+	case mmdbArray:
+		result := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			value, next, err := decodeMaxMind(data, offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			result = append(result, value)
+			offset = next
+		}
+		return result, offset, nil
+	case mmdbBoolean:
+		return size != 0, offset, nil
+	case mmdbEndMarker, mmdbContainer:
+		return nil, offset, nil
+	}
+
+	if offset+size > uint(len(data)) {
+		return nil, 0, errMaxMindCorrupt
+	}
+	b := data[offset : offset+size]
+	offset += size
+	switch kind {
+	case mmdbString:
+		return string(b), offset, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), offset, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errMaxMindCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), offset, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errMaxMindCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), offset, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		if size > 8 {
+			return nil, 0, errMaxMindCorrupt
+		}
+		n := uint64(0)
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, offset, nil
+	case mmdbInt32:
+		if size > 4 {
+			return nil, 0, errMaxMindCorrupt
+		}
+		n := uint32(0)
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), offset, nil
+	case mmdbUint128:
+		return new(big.Int).SetBytes(b), offset, nil
+	default:
+		return nil, 0, errMaxMindCorrupt
+	}
+}
+
+// maxMindUint reads an unsigned integer from a decoded record, or 0
+func maxMindUint(value interface{}) uint64 {
+	n, _ := value.(uint64)
+	return n
+}
@@ -0,0 +1,35 @@
+package utils
+
+import "strings"
+
+// NormalizeMobile returns the canonical 10 digit form of a phone number written with a 91, +91 or 0
+// prefix, spaces or dashes. A value that is not 10 digits once the prefix is dropped is returned
+// trimmed but otherwise unchanged, so it is not mistaken for a different number.
+func NormalizeMobile(mobile string) string {
+	if digits := mobileDigits(mobile); len(digits) == 10 {
+		return digits
+	}
+	return strings.TrimSpace(mobile)
+}
+
+// MobileVariants returns the forms a number is stored in: the canonical 10 digits, which imports store
+// since numbers are normalized, and the 91, +91 and 0 prefixed forms rows imported before that may have.
+// Returns nil when the value is not a 10 digit number.
+func MobileVariants(mobile string) []string {
+	digits := mobileDigits(mobile)
+	if len(digits) != 10 {
+		return nil
+	}
+	return []string{digits, "91" + digits, "+91" + digits, "0" + digits}
+}
+
+// mobileDigits returns the digits of a phone number without a 91 or 0 prefix on a 10 digit number
+func mobileDigits(mobile string) string {
+	digits := nonDigitPattern.ReplaceAllString(mobile, "")
+	if len(digits) == 12 && strings.HasPrefix(digits, "91") {
+		digits = digits[2:]
+	} else if len(digits) == 11 && strings.HasPrefix(digits, "0") {
+		digits = digits[1:]
+	}
+	return digits
+}
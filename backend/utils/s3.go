@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"finone-search-system/config"
+)
+
+// s3UnsignedPayload signs requests without hashing their body, which S3 accepts over HTTPS
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3Client reads and writes S3 objects with AWS Signature Version 4, which is all the server needs
+// and avoids pulling in the AWS SDK. Without an access key, requests are sent unsigned for public buckets.
+type S3Client struct {
+	cfg        config.S3Config
+	httpClient *http.Client
+}
+
+// NewS3Client creates a client with the given settings, sending requests through httpClient
+func NewS3Client(cfg config.S3Config, httpClient *http.Client) *S3Client {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3Client{cfg: cfg, httpClient: httpClient}
+}
+
+// ParseS3URL splits an s3://bucket/key URL into its bucket and key
+func ParseS3URL(rawURL string) (string, string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme != "s3" {
+		return "", "", fmt.Errorf("not an s3:// URL")
+	}
+	key := strings.TrimPrefix(parsed.Path, "/")
+	if parsed.Host == "" || key == "" {
+		return "", "", fmt.Errorf("s3 URL must name a bucket and a key: s3://bucket/key")
+	}
+	return parsed.Host, key, nil
+}
+
+// GetObject opens an object for streaming. The caller closes the body.
+func (c *S3Client) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, int64, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, bucket, key, nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, 0, fmt.Errorf("failed to get s3://%s/%s: %s", bucket, key, s3ErrorMessage(resp))
+	}
+	return resp.Body, resp.ContentLength, nil
+}
+
+// PutObject uploads an object of a known size in one request, which S3 accepts up to 5 GB
+func (c *S3Client) PutObject(ctx context.Context, bucket, key string, body io.Reader, size int64, contentType string) error {
+	req, err := c.newRequest(ctx, http.MethodPut, bucket, key, body, func(req *http.Request) {
+		req.ContentLength = size
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to put s3://%s/%s: %w", bucket, key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to put s3://%s/%s: %s", bucket, key, s3ErrorMessage(resp))
+	}
+	return nil
+}
+
+// DeleteObject removes an object; removing one that does not exist is not an error
+func (c *S3Client) DeleteObject(ctx context.Context, bucket, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete s3://%s/%s: %w", bucket, key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("failed to delete s3://%s/%s: %s", bucket, key, s3ErrorMessage(resp))
+	}
+	return nil
+}
+
+// PresignGetObject returns a URL that downloads an object without credentials until it expires.
+// Signature Version 4 allows at most 7 days. downloadName, when set, is the file name browsers save
+// the object as.
+func (c *S3Client) PresignGetObject(bucket, key string, expires time.Duration, downloadName string) (string, error) {
+	if c.cfg.AccessKeyID == "" {
+		return "", fmt.Errorf("s3 credentials are required to presign URLs")
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return "", fmt.Errorf("presigned URLs must expire within 7 days")
+	}
+
+	endpoint, err := c.objectURL(bucket, key)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	scope := now.Format("20060102") + "/" + c.cfg.Region + "/s3/aws4_request"
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", c.cfg.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if c.cfg.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.cfg.SessionToken)
+	}
+	if downloadName != "" {
+		query.Set("response-content-disposition", fmt.Sprintf(`attachment; filename="%s"`, downloadName))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		endpoint.EscapedPath(),
+		s3EncodeQuery(query),
+		"host:" + endpoint.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+	signature := c.signature(now, scope, canonicalRequest)
+
+	endpoint.RawQuery = s3EncodeQuery(query) + "&X-Amz-Signature=" + signature
+	return endpoint.String(), nil
+}
+
+// newRequest builds a signed request for an object. prepare, when set, adjusts the request before
+// it is signed.
+func (c *S3Client) newRequest(ctx context.Context, method, bucket, key string, body io.Reader, prepare func(*http.Request)) (*http.Request, error) {
+	endpoint, err := c.objectURL(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	if prepare != nil {
+		prepare(req)
+	}
+	if c.cfg.AccessKeyID != "" {
+		c.sign(req, time.Now().UTC())
+	}
+	return req, nil
+}
+
+// objectURL returns the URL of an object, virtual-hosted unless path style is configured
+func (c *S3Client) objectURL(bucket, key string) (*url.URL, error) {
+	endpoint := c.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.cfg.Region)
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %s", endpoint)
+	}
+
+	escapedKey := s3EscapePath(key)
+	if c.cfg.PathStyle {
+		base.Path = "/" + bucket + "/" + key
+		base.RawPath = "/" + bucket + "/" + escapedKey
+	} else {
+		base.Host = bucket + "." + base.Host
+		base.Path = "/" + key
+		base.RawPath = "/" + escapedKey
+	}
+	return base, nil
+}
+
+// sign adds a Signature Version 4 Authorization header to a request
+func (c *S3Client) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", s3UnsignedPayload)
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if c.cfg.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", c.cfg.SessionToken)
+		signed = append(signed, "x-amz-security-token")
+	}
+
+	var headers strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		s3EncodeQuery(req.URL.Query()),
+		headers.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + c.cfg.Region + "/s3/aws4_request"
+	signature := c.signature(now, scope, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// signature signs a canonical request with a key derived from the secret key, date and region
+func (c *S3Client) signature(now time.Time, scope, canonicalRequest string) string {
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.cfg.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, c.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// s3EncodeQuery encodes query parameters sorted by name, with spaces as %20 as Signature Version 4 requires
+func s3EncodeQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// s3EscapePath escapes an object key the way Signature Version 4 canonicalizes it: every byte but
+// letters, digits, "-", "_", ".", "~" and the "/" separators is percent-encoded
+func s3EscapePath(key string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(key) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~', b == '/':
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+// s3ErrorMessage returns the status and the start of an S3 error response
+func s3ErrorMessage(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if message := strings.TrimSpace(string(body)); message != "" {
+		return resp.Status + ": " + message
+	}
+	return resp.Status
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
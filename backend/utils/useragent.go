@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Device types reported by ParseUserAgent
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// DeviceInfo is the browser, operating system and kind of device a User-Agent header describes
+type DeviceInfo struct {
+	Browser    string `json:"browser"`
+	OS         string `json:"os"`
+	DeviceType string `json:"device_type"`
+}
+
+// userAgentBrowsers are matched in order, since most browsers also claim to be Chrome or Safari
+var userAgentBrowsers = []struct{ token, name string }{
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"YaBrowser/", "Yandex"},
+	{"UCBrowser/", "UC Browser"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Version/", "Safari"},
+	{"PostmanRuntime/", "Postman"},
+	{"curl/", "curl"},
+	{"Wget/", "Wget"},
+	{"okhttp/", "OkHttp"},
+	{"python-requests/", "Python Requests"},
+	{"Go-http-client/", "Go HTTP client"},
+}
+
+var userAgentSystems = []struct{ token, name string }{
+	{"Windows", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"iPod", "iOS"},
+	{"CrOS", "ChromeOS"},
+	{"Android", "Android"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Linux", "Linux"},
+}
+
+var userAgentBots = []string{"bot", "crawler", "spider", "slurp", "headless"}
+
+// ParseUserAgent reads the browser, operating system and device type from a User-Agent header.
+// Only names are kept, not versions, so a browser update does not look like a new device. Parts it
+// does not recognise are "Other".
+func ParseUserAgent(userAgent string) DeviceInfo {
+	info := DeviceInfo{Browser: "Other", OS: "Other", DeviceType: DeviceOther}
+	if strings.TrimSpace(userAgent) == "" {
+		return info
+	}
+
+	for _, browser := range userAgentBrowsers {
+		if strings.Contains(userAgent, browser.token) {
+			info.Browser = browser.name
+			break
+		}
+	}
+	for _, system := range userAgentSystems {
+		if strings.Contains(userAgent, system.token) {
+			info.OS = system.name
+			break
+		}
+	}
+
+	lower := strings.ToLower(userAgent)
+	switch {
+	case containsAny(lower, userAgentBots):
+		info.DeviceType = DeviceBot
+	case info.OS == "iPadOS" || (info.OS == "Android" && !strings.Contains(userAgent, "Mobile")) || strings.Contains(lower, "tablet"):
+		info.DeviceType = DeviceTablet
+	case info.OS == "iOS" || info.OS == "Android" || strings.Contains(userAgent, "Mobile"):
+		info.DeviceType = DeviceMobile
+	case info.OS == "Windows" || info.OS == "macOS" || info.OS == "Linux" || info.OS == "ChromeOS":
+		info.DeviceType = DeviceDesktop
+	}
+	return info
+}
+
+// Fingerprint identifies the device among an account's devices. Different machines with the same
+// browser and system share a fingerprint; it tells devices apart, it does not track them.
+func (d DeviceInfo) Fingerprint() string {
+	sum := sha256.Sum256([]byte(d.Browser + "|" + d.OS + "|" + d.DeviceType))
+	return hex.EncodeToString(sum[:8])
+}
+
+// String describes the device for people, e.g. "Chrome on Windows (desktop)"
+func (d DeviceInfo) String() string {
+	return d.Browser + " on " + d.OS + " (" + d.DeviceType + ")"
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
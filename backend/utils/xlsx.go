@@ -0,0 +1,363 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// xlsxSheet streams the rows of one worksheet of an Excel workbook as records, so a workbook runs
+// through the same import pipeline as a CSV file. Only the values of cells are read; formatting,
+// formulas and dates (which Excel stores as numbers) are not interpreted.
+type xlsxSheet struct {
+	zr      *zip.ReadCloser
+	entry   io.ReadCloser
+	counter *byteCounter
+	decoder *xml.Decoder
+	name    string
+	shared  []string // The workbook's shared strings, which text cells refer to by index
+	row     int      // Number of the row last read
+	width   int      // Cells in the header row; shorter rows are padded to it
+
+	compressed, uncompressed uint64
+}
+
+// IsWorkbookFile reports whether a file name has the .xlsx extension of an Excel workbook
+func IsWorkbookFile(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".xlsx")
+}
+
+// openXLSXSheet opens a worksheet of a workbook by name, or its first sheet when name is empty
+func openXLSXSheet(filePath, name string) (*xlsxSheet, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workbook: %w", err)
+	}
+
+	sheet, err := newXLSXSheet(zr, name)
+	if err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return sheet, nil
+}
+
+func newXLSXSheet(zr *zip.ReadCloser, name string) (*xlsxSheet, error) {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name  string     `xml:"name,attr"`
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXLSXPart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("workbook has no sheets")
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXLSXPart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+
+	var sheetName, relID string
+	var names []string
+	for _, s := range workbook.Sheets {
+		names = append(names, s.Name)
+		if name != "" && s.Name != name {
+			continue
+		}
+		sheetName = s.Name
+		for _, attr := range s.Attrs {
+			// The relationships namespace differs between transitional and strict workbooks
+			if attr.Name.Local == "id" && strings.HasSuffix(attr.Name.Space, "relationships") {
+				relID = attr.Value
+			}
+		}
+		break
+	}
+	if sheetName == "" {
+		return nil, fmt.Errorf("workbook has no sheet named %q; sheets: %s", name, strings.Join(names, ", "))
+	}
+
+	var target string
+	for _, rel := range rels.Relationships {
+		if rel.ID == relID {
+			target = rel.Target
+		}
+	}
+	if target == "" {
+		return nil, fmt.Errorf("sheet %q has no worksheet part", sheetName)
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+	f, ok := files[target]
+	if !ok {
+		return nil, fmt.Errorf("worksheet part %s of sheet %q is missing", target, sheetName)
+	}
+
+	shared, err := readSharedStrings(files["xl/sharedStrings.xml"])
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sheet %q: %w", sheetName, err)
+	}
+	counter := &byteCounter{r: entry}
+	return &xlsxSheet{
+		zr:           zr,
+		entry:        entry,
+		counter:      counter,
+		decoder:      xml.NewDecoder(counter),
+		name:         sheetName,
+		shared:       shared,
+		compressed:   f.CompressedSize64,
+		uncompressed: f.UncompressedSize64,
+	}, nil
+}
+
+// decodeXLSXPart unmarshals one XML part of a workbook
+func decodeXLSXPart(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return fmt.Errorf("not an Excel workbook: %s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// readSharedStrings reads the shared string table, joining the runs of rich text strings and leaving
+// out phonetic guides. A workbook without text cells has no table.
+func readSharedStrings(f *zip.File) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared strings: %w", err)
+	}
+	defer rc.Close()
+
+	var shared []string
+	var value strings.Builder
+	inText, inPhonetic := false, false
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				value.Reset()
+			case "t":
+				inText = !inPhonetic
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				shared = append(shared, value.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+// Read returns the cell values of the next row that has any, placed by their column so skipped
+// empty cells read as empty values. The first row read sets the width rows are padded to.
+func (s *xlsxSheet) Read() ([]string, error) {
+	for {
+		record, err := s.readRow()
+		if err != nil {
+			return nil, err
+		}
+		if s.width == 0 {
+			s.width = len(record)
+		}
+		for len(record) < s.width {
+			record = append(record, "")
+		}
+		for _, value := range record {
+			if value != "" {
+				return record, nil
+			}
+		}
+	}
+}
+
+// FieldPos returns the number of the row last read as its line, for error reports
+func (s *xlsxSheet) FieldPos(field int) (int, int) {
+	return s.row, field + 1
+}
+
+// BytesRead estimates the bytes of the workbook file read so far from the share of the sheet read
+func (s *xlsxSheet) BytesRead() int64 {
+	if s.uncompressed == 0 {
+		return 0
+	}
+	return int64(float64(s.counter.n) / float64(s.uncompressed) * float64(s.compressed))
+}
+
+// Close closes the sheet and the workbook
+func (s *xlsxSheet) Close() error {
+	s.entry.Close()
+	return s.zr.Close()
+}
+
+// readRow reads the next row element of the sheet data
+func (s *xlsxSheet) readRow() ([]string, error) {
+	var record []string
+	inRow := false
+	var cellType, cellRef string
+	var value strings.Builder
+	inValue := false
+
+	for {
+		token, err := s.decoder.Token()
+		if err == io.EOF {
+			if inRow {
+				return nil, fmt.Errorf("sheet %q ends inside row %d", s.name, s.row)
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sheet %q: %w", s.name, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				inRow = true
+				s.row++
+				if r := xmlAttr(t, "r"); r != "" {
+					if n, err := strconv.Atoi(r); err == nil {
+						s.row = n
+					}
+				}
+			case "c":
+				cellType, cellRef = xmlAttr(t, "t"), xmlAttr(t, "r")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "row":
+				return record, nil
+			case "c":
+				column := len(record)
+				if index, ok := xlsxColumnIndex(cellRef); ok {
+					column = index
+				}
+				for len(record) < column {
+					record = append(record, "")
+				}
+				if column == len(record) {
+					record = append(record, s.cellValue(cellType, value.String()))
+				}
+			case "v", "t":
+				inValue = false
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+// cellValue returns the text of a cell from its type and raw value
+func (s *xlsxSheet) cellValue(cellType, raw string) string {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || index < 0 || index >= len(s.shared) {
+			return ""
+		}
+		return s.shared[index]
+	case "b":
+		if raw == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "", "n":
+		// Long numbers such as mobiles may be stored in exponent form
+		if strings.ContainsAny(raw, "eE") {
+			if f, err := strconv.ParseFloat(raw, 64); err == nil {
+				return strconv.FormatFloat(f, 'f', -1, 64)
+			}
+		}
+		return raw
+	default: // inlineStr, str (formula results), e (errors), d (ISO dates)
+		return raw
+	}
+}
+
+// xlsxColumnIndex returns the zero-based column of a cell reference such as "C12"
+func xlsxColumnIndex(ref string) (int, bool) {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A') + 1
+		letters++
+	}
+	if letters == 0 {
+		return 0, false
+	}
+	return index - 1, true
+}
+
+// xmlAttr returns the value of an unqualified attribute of an element
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name && attr.Name.Space == "" {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"hash"
+	"io"
+	"time"
+)
+
+// WinZip AES (AE-1) parameters for 256-bit keys: entries are stored with method 99 and an extra field
+// naming the real compression method, and hold a salt, a password verifier, the deflated data
+// encrypted with AES-CTR and a truncated HMAC-SHA1 of the encrypted data
+const (
+	zipAESMethod       = 99
+	zipAESExtraID      = 0x9901
+	zipAESVersion      = 1 // AE-1 keeps the CRC of the plain data
+	zipAESStrength     = 3 // AES-256
+	zipAESKeyLength    = 32
+	zipAESSaltLength   = 16
+	zipAESVerifyLength = 2
+	zipAESMACLength    = 10
+	zipAESIterations   = 1000
+)
+
+// WriteEncryptedZip writes a ZIP archive with one entry, the contents of src, deflated and encrypted
+// with WinZip AES-256 under password. 7-Zip, WinZip, WinRAR and libarchive open it; the legacy ZIP
+// encryption most tools also offer is not used because it is easily broken.
+func WriteEncryptedZip(dst io.Writer, name string, modified time.Time, src io.Reader, password string) error {
+	zw := zip.NewWriter(dst)
+	zw.RegisterCompressor(zipAESMethod, func(w io.Writer) (io.WriteCloser, error) {
+		return newZipAESWriter(w, password)
+	})
+
+	extra := make([]byte, 11)
+	binary.LittleEndian.PutUint16(extra[0:], zipAESExtraID)
+	binary.LittleEndian.PutUint16(extra[2:], 7)
+	binary.LittleEndian.PutUint16(extra[4:], zipAESVersion)
+	copy(extra[6:], "AE")
+	extra[8] = zipAESStrength
+	binary.LittleEndian.PutUint16(extra[9:], zip.Deflate)
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zipAESMethod,
+		Modified: modified,
+		Flags:    0x1, // Encrypted
+		Extra:    extra,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(entry, src); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// zipAESWriter deflates, encrypts and authenticates an entry's data as it is written
+type zipAESWriter struct {
+	dst     io.Writer
+	header  []byte // Salt and password verifier, written ahead of the first encrypted bytes
+	deflate *flate.Writer
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int // Bytes of stream already used
+	mac     hash.Hash
+}
+
+func newZipAESWriter(dst io.Writer, password string) (*zipAESWriter, error) {
+	salt := make([]byte, zipAESSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	keys, err := pbkdf2.Key(sha1.New, password, salt, zipAESIterations, 2*zipAESKeyLength+zipAESVerifyLength)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(keys[:zipAESKeyLength])
+	if err != nil {
+		return nil, err
+	}
+
+	w := &zipAESWriter{
+		dst:    dst,
+		header: append(salt, keys[2*zipAESKeyLength:]...),
+		block:  block,
+		used:   aes.BlockSize,
+		mac:    hmac.New(sha1.New, keys[zipAESKeyLength:2*zipAESKeyLength]),
+	}
+	w.deflate, err = flate.NewWriter(writerFunc(w.encrypt), flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *zipAESWriter) Write(p []byte) (int, error) {
+	return w.deflate.Write(p)
+}
+
+// Close flushes the deflated data and appends the authentication code
+func (w *zipAESWriter) Close() error {
+	if err := w.deflate.Close(); err != nil {
+		return err
+	}
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	_, err := w.dst.Write(w.mac.Sum(nil)[:zipAESMACLength])
+	return err
+}
+
+// encrypt encrypts deflated data in CTR mode with WinZip's little-endian counter, which starts at 1
+func (w *zipAESWriter) encrypt(p []byte) (int, error) {
+	if err := w.writeHeader(); err != nil {
+		return 0, err
+	}
+
+	out := make([]byte, len(p))
+	for i := range p {
+		if w.used == aes.BlockSize {
+			for j := range w.counter {
+				w.counter[j]++
+				if w.counter[j] != 0 {
+					break
+				}
+			}
+			w.block.Encrypt(w.stream[:], w.counter[:])
+			w.used = 0
+		}
+		out[i] = p[i] ^ w.stream[w.used]
+		w.used++
+	}
+
+	w.mac.Write(out)
+	if _, err := w.dst.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *zipAESWriter) writeHeader() error {
+	if w.header == nil {
+		return nil
+	}
+	header := w.header
+	w.header = nil
+	_, err := w.dst.Write(header)
+	return err
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
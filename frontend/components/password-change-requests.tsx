@@ -51,8 +51,8 @@ export default function PasswordChangeRequests({
         statusFilter || undefined
       );
       // Ensure we always have an array
-      setRequests(response.requests || []);
-      setTotalCount(response.total_count || 0);
+      setRequests(response.items || []);
+      setTotalCount(response.total || 0);
     } catch (err: any) {
       setError(err.message || "Failed to fetch password change requests");
       // Set empty array on error to prevent null issues
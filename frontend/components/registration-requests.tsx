@@ -50,8 +50,8 @@ export default function RegistrationRequests({
         pageSize,
         statusFilter || undefined
       );
-      setRequests(response.requests);
-      setTotalCount(response.total_count);
+      setRequests(response.items);
+      setTotalCount(response.total);
     } catch (err: any) {
       setError(err.message || "Failed to fetch registration requests");
     } finally {
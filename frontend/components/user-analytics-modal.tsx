@@ -53,7 +53,7 @@ export default function UserAnalyticsModal({
       setLoadingSearches(true);
       setSearchError("");
       const response = await getUserSearchHistory(analytics.user_id, 15);
-      setRecentSearches(response.searches);
+      setRecentSearches(response.items);
     } catch (error: any) {
       setSearchError(error.message || "Failed to load search history");
     } finally {
@@ -170,7 +170,7 @@ export default function UserManagement() {
   };
 
   // Calculate stats
-  const totalUsers = userList?.total_count || 0;
+  const totalUsers = userList?.total || 0;
   const activeUsers = users.filter((u) => u.is_active).length;
   const inactiveUsers = users.filter((u) => !u.is_active).length;
   const adminUsers = users.filter((u) => u.role === "ADMIN").length;
@@ -358,11 +358,11 @@ export default function UserManagement() {
           />
 
           {/* Pagination */}
-          {userList && userList.total_count > 20 && (
+          {userList && userList.total > 20 && (
             <div className="flex justify-between items-center mt-6">
               <div className="text-sm text-gray-700">
                 Page {userList.page} of{" "}
-                {Math.ceil(userList.total_count / userList.limit)}
+                {Math.ceil(userList.total / userList.limit)}
               </div>
               <div className="flex space-x-2">
                 <Button
@@ -379,7 +379,7 @@ export default function UserManagement() {
                   onClick={() => handlePageChange(userList.page + 1)}
                   disabled={
                     userList.page >=
-                      Math.ceil(userList.total_count / userList.limit) ||
+                      Math.ceil(userList.total / userList.limit) ||
                     loading
                   }
                 >
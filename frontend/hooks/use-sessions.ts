@@ -32,7 +32,7 @@ export const useSessions = (): UseSessionsReturn => {
       setLoading(true);
       setError(null);
       const response = await getAllSessions();
-      setSessions(response.sessions);
+      setSessions(response.items);
     } catch (err: any) {
       setError(err.message || "Failed to load sessions");
     } finally {
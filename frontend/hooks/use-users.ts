@@ -73,7 +73,7 @@ export const useUsers = (): UseUsersReturn => {
         setError(null);
         const response = await getUsers(page, limit);
         setUserList(response);
-        setUsers(response.users);
+        setUsers(response.items);
       } catch (err: any) {
         setError(err.message || "Failed to load users");
       } finally {
@@ -142,7 +142,7 @@ export const useUsers = (): UseUsersReturn => {
             prev
               ? {
                   ...prev,
-                  users: prev.users.map((user) =>
+                  items: prev.items.map((user) =>
                     user.id === userId ? updatedUser : user
                   ),
                 }
@@ -178,8 +178,8 @@ export const useUsers = (): UseUsersReturn => {
             prev
               ? {
                   ...prev,
-                  users: prev.users.filter((user) => user.id !== userId),
-                  total_count: prev.total_count - 1,
+                  items: prev.items.filter((user) => user.id !== userId),
+                  total: prev.total - 1,
                 }
               : null
           );
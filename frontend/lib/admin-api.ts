@@ -2,7 +2,7 @@
  * Admin API functions for user management
  */
 
-import { ApiError } from "./api";
+import { ApiError, Paginated } from "./api";
 
 const BACKEND_URL =
   process.env.NEXT_PUBLIC_BACKEND_URL || "http://localhost:8082";
@@ -44,12 +44,7 @@ export interface UpdateUserRequest {
   max_exports_per_day?: number;
 }
 
-export interface UserListResponse {
-  users: User[];
-  total_count: number;
-  page: number;
-  limit: number;
-}
+export type UserListResponse = Paginated<User>;
 
 export interface UserAnalytics {
   user_id: string;
@@ -75,9 +70,7 @@ export interface RecentSearch {
   execution_time_ms: number;
 }
 
-export interface UserSearchHistoryResponse {
-  searches: RecentSearch[];
-}
+export type UserSearchHistoryResponse = Paginated<RecentSearch>;
 
 export interface UserRegistrationRequest {
   id: string;
@@ -93,12 +86,7 @@ export interface UserRegistrationRequest {
   reviewed_by?: string;
 }
 
-export interface RegistrationRequestListResponse {
-  requests: UserRegistrationRequest[];
-  total_count: number;
-  page: number;
-  limit: number;
-}
+export type RegistrationRequestListResponse = Paginated<UserRegistrationRequest>;
 
 export interface UpdateRegistrationRequest {
   status: "APPROVED" | "REJECTED";
@@ -119,16 +107,14 @@ export interface UserPasswordChangeRequest {
   reviewed_by?: string;
 }
 
-export interface PasswordChangeRequestListResponse {
-  requests: UserPasswordChangeRequest[];
-  total_count: number;
-  page: number;
-  limit: number;
-}
+export type PasswordChangeRequestListResponse =
+  Paginated<UserPasswordChangeRequest>;
 
 export interface UpdatePasswordChangeRequest {
   status: "APPROVED" | "REJECTED";
   admin_notes?: string;
+  new_password?: string; // Generated by the server when approving without one
+  email_credentials?: boolean;
 }
 
 /**
@@ -3,7 +3,7 @@
  * Handles admin-specific authentication and session management
  */
 
-import { ApiError, UserProfile } from "./api";
+import { ApiError, Paginated, UserProfile } from "./api";
 
 const BACKEND_URL =
   process.env.NEXT_PUBLIC_BACKEND_URL || "http://localhost:8082";
@@ -27,9 +27,7 @@ export interface SessionInfo {
   is_active: boolean;
 }
 
-export interface SessionsResponse {
-  sessions: SessionInfo[];
-}
+export type SessionsResponse = Paginated<SessionInfo>;
 
 /**
  * Admin login - separate from regular user login
@@ -108,7 +106,7 @@ export const getAllSessions = async (): Promise<SessionsResponse> => {
   const token = localStorage.getItem("admin_token");
   if (!token) throw new ApiError(401, "No admin token found");
 
-  const response = await fetch(`${BACKEND_URL}/api/v1/admin/sessions`, {
+  const response = await fetch(`${BACKEND_URL}/api/v1/admin/sessions?limit=100`, {
     headers: { Authorization: `Bearer ${token}` },
   });
 
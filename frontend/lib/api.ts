@@ -7,6 +7,16 @@ export interface ApiResponse<T = any> {
   message?: string;
 }
 
+// Response of every paged list endpoint
+export interface Paginated<T> {
+  items: T[];
+  total: number;
+  page: number;
+  limit: number;
+  has_more: boolean;
+  next_cursor?: string;
+}
+
 export interface SearchRequest {
   query: string;
   fields?: string[];
@@ -16,6 +26,7 @@ export interface SearchRequest {
   limit?: number;
   offset?: number;
   search_within?: boolean;
+  mode?: "auto" | "standard" | "enhanced_mobile";
 }
 
 export interface SearchResponse {
@@ -24,6 +35,7 @@ export interface SearchResponse {
   execution_time_ms: number;
   search_id: string;
   has_more: boolean;
+  mode?: "standard" | "enhanced_mobile"; // Mode the search ran in
   message?: string; // Added for no results message
 }
 
@@ -263,12 +275,8 @@ export interface UserPasswordChangeRequest {
   reviewed_by?: string;
 }
 
-export interface PasswordChangeRequestListResponse {
-  requests: UserPasswordChangeRequest[];
-  total_count: number;
-  page: number;
-  limit: number;
-}
+export type PasswordChangeRequestListResponse =
+  Paginated<UserPasswordChangeRequest>;
 
 // Password change request API functions
 export async function createPasswordChangeRequest(
@@ -286,5 +294,5 @@ export async function getUserPasswordChangeRequests(): Promise<
   const response: PasswordChangeRequestListResponse = await apiCall(
     "/api/v1/password-change-requests/my"
   );
-  return response.requests || [];
+  return response.items || [];
 }